@@ -0,0 +1,102 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionAllSynced is True when the last sync of every managed object succeeded
+	ConditionAllSynced = "AllSynced"
+
+	// ConditionPiholeReachable is True when the last Pi-hole API call got through
+	ConditionPiholeReachable = "PiholeReachable"
+)
+
+// PiholeSyncSpec is empty; the operator owns the whole object
+type PiholeSyncSpec struct{}
+
+// ObjectSyncStatus is the operator's view of one managed object
+type ObjectSyncStatus struct {
+	// Kind of the object, e.g. Ingress or DomainMapping
+	Kind string `json:"kind"`
+
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// Domains are the record keys owned by the object: the bare domain for its A record
+	// and domain/AAAA for its AAAA record
+	// +optional
+	Domains []string `json:"domains,omitempty"`
+
+	// LastSyncTime is when the object's records were last brought in line with Pi-hole
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// LastError is the error of the last failed sync, cleared by a successful one
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// Expired are the record keys removed because pihole.io/expire-after elapsed, and
+	// not created again until the annotation changes
+	// +optional
+	Expired []string `json:"expired,omitempty"`
+}
+
+// PiholeSyncStatus defines the observed state of PiholeSync
+type PiholeSyncStatus struct {
+	// Conditions summarize overall health: AllSynced and PiholeReachable
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Objects lists every managed object, sorted by kind, namespace and name
+	// +optional
+	Objects []ObjectSyncStatus `json:"objects,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.conditions[?(@.type=="AllSynced")].status`
+// +kubebuilder:printcolumn:name="Reachable",type=string,JSONPath=`.status.conditions[?(@.type=="PiholeReachable")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PiholeSync reports the sync state of every object the operator manages. It is
+// written by the operator and only read by users.
+type PiholeSync struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PiholeSyncSpec   `json:"spec,omitempty"`
+	Status PiholeSyncStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PiholeSyncList contains a list of PiholeSync
+type PiholeSyncList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PiholeSync `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PiholeSync{}, &PiholeSyncList{})
+}
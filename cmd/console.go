@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ANSI colors used by the console handler
+const (
+	colorReset  = "\x1b[0m"
+	colorFaint  = "\x1b[2m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorGray   = "\x1b[90m"
+)
+
+// consoleHandler is a slog.Handler for reading logs in a terminal: one compact line
+// per record with the time, a colored level, the message and key=value pairs.
+type consoleHandler struct {
+	w     io.Writer
+	level slog.Leveler
+	mu    *sync.Mutex
+
+	// attrs holds the pairs added by WithAttrs, already formatted; prefix is the
+	// group path applied to later keys
+	attrs  []byte
+	prefix string
+}
+
+// newConsoleHandler returns a console handler writing to w; only opts.Level is used
+func newConsoleHandler(w io.Writer, opts *slog.HandlerOptions) *consoleHandler {
+	h := &consoleHandler{w: w, level: slog.LevelInfo, mu: &sync.Mutex{}}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 256)
+	if !r.Time.IsZero() {
+		buf = append(buf, colorFaint...)
+		buf = r.Time.AppendFormat(buf, "15:04:05.000")
+		buf = append(buf, colorReset...)
+		buf = append(buf, ' ')
+	}
+	buf = appendLevel(buf, r.Level)
+	buf = append(buf, ' ')
+	buf = append(buf, r.Message...)
+	buf = append(buf, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = appendAttr(buf, h.prefix, a)
+		return true
+	})
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf)
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append([]byte(nil), h.attrs...)
+	for _, a := range attrs {
+		next.attrs = appendAttr(next.attrs, h.prefix, a)
+	}
+	return &next
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+// appendLevel appends the three-letter level in its color
+func appendLevel(buf []byte, level slog.Level) []byte {
+	color, label := colorGreen, "INF"
+	switch {
+	case level < slog.LevelInfo:
+		color, label = colorGray, "DBG"
+	case level >= slog.LevelError:
+		color, label = colorRed, "ERR"
+	case level >= slog.LevelWarn:
+		color, label = colorYellow, "WRN"
+	}
+	buf = append(buf, color...)
+	buf = append(buf, label...)
+	return append(buf, colorReset...)
+}
+
+// appendAttr appends " key=value", flattening groups into dotted keys and quoting
+// values that would otherwise be ambiguous
+func appendAttr(buf []byte, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			buf = appendAttr(buf, prefix, ga)
+		}
+		return buf
+	}
+
+	buf = append(buf, ' ')
+	buf = append(buf, colorFaint...)
+	buf = append(buf, prefix...)
+	buf = append(buf, a.Key...)
+	buf = append(buf, '=')
+	buf = append(buf, colorReset...)
+	value := a.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.AppendQuote(buf, value)
+	}
+	return append(buf, value...)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(newConsoleHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	logger.Debug("hidden")
+	logger.With("ingress", "default/app").WithGroup("pihole").
+		Warn("dns record conflict", "host", "app.local", "reason", "owned elsewhere", "error", errors.New("exists"))
+
+	line := buf.String()
+	if strings.Contains(line, "hidden") {
+		t.Errorf("debug line written below the level: %q", line)
+	}
+	plain := strings.NewReplacer(colorReset, "", colorFaint, "", colorYellow, "").Replace(line)
+	if strings.Count(plain, "\n") != 1 {
+		t.Fatalf("output = %q, want one line", plain)
+	}
+	want := ` WRN dns record conflict ingress=default/app pihole.host=app.local pihole.reason="owned elsewhere" pihole.error=exists` + "\n"
+	if !strings.HasSuffix(plain, want) {
+		t.Errorf("line = %q, want suffix %q", plain, want)
+	}
+	if !strings.Contains(line, colorYellow+"WRN") {
+		t.Errorf("line = %q, want the level in yellow", line)
+	}
+}
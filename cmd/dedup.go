@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dedupHandler is a slog.Handler that logs a repeating error once per window. Errors
+// with the same signature — level, message and the record's own attributes, but not
+// those added with WithAttrs, such as the object being reconciled — are dropped after
+// the first until the window closes, when the first is logged again with a
+// suppressed_count field if any were dropped. A different error is logged at once, so
+// when Pi-hole is down every object's "pihole api error" is logged once per window
+// instead of once per object and retry. Records below error level pass through.
+type dedupHandler struct {
+	next   slog.Handler
+	window time.Duration
+	state  *dedupState
+
+	// groups is the group path applied to later attributes, part of the signature
+	groups string
+}
+
+// dedupState is shared by a handler and those derived from it
+type dedupState struct {
+	mu      sync.Mutex
+	windows map[string]int
+}
+
+// newDedupHandler wraps next; a window of 0 returns next unchanged
+func newDedupHandler(next slog.Handler, window time.Duration) slog.Handler {
+	if window <= 0 {
+		return next
+	}
+	return &dedupHandler{next: next, window: window, state: &dedupState{windows: map[string]int{}}}
+}
+
+func (h *dedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *dedupHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelError {
+		return h.next.Handle(ctx, r)
+	}
+
+	sig := h.signature(r)
+	h.state.mu.Lock()
+	if _, open := h.state.windows[sig]; open {
+		h.state.windows[sig]++
+		h.state.mu.Unlock()
+		return nil
+	}
+	h.state.windows[sig] = 0
+	h.state.mu.Unlock()
+
+	first := r.Clone()
+	time.AfterFunc(h.window, func() { h.close(sig, first) })
+	return h.next.Handle(ctx, r)
+}
+
+// close ends the window of sig, logging first again with the number of records
+// dropped during it, if any
+func (h *dedupHandler) close(sig string, first slog.Record) {
+	h.state.mu.Lock()
+	suppressed := h.state.windows[sig]
+	delete(h.state.windows, sig)
+	h.state.mu.Unlock()
+	if suppressed == 0 {
+		return
+	}
+
+	summary := slog.NewRecord(time.Now(), first.Level, first.Message, first.PC)
+	first.Attrs(func(a slog.Attr) bool {
+		summary.AddAttrs(a)
+		return true
+	})
+	summary.AddAttrs(slog.Int("suppressed_count", suppressed))
+	_ = h.next.Handle(context.Background(), summary)
+}
+
+// signature identifies records that repeat one another
+func (h *dedupHandler) signature(r slog.Record) string {
+	var b strings.Builder
+	b.WriteString(r.Level.String())
+	b.WriteByte(0)
+	b.WriteString(r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		b.WriteByte(0)
+		b.WriteString(h.groups)
+		b.WriteString(a.Key)
+		b.WriteByte('=')
+		b.WriteString(a.Value.Resolve().String())
+		return true
+	})
+	return b.String()
+}
+
+func (h *dedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.next = h.next.WithAttrs(attrs)
+	return &next
+}
+
+func (h *dedupHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.next = h.next.WithGroup(name)
+	next.groups = h.groups + name + "."
+	return &next
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jsonLines parses the JSON log lines written to b so far
+func jsonLines(t *testing.T, b *lockedBuffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("log line does not parse: %v\n%s", err, line)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestDedupHandler(t *testing.T) {
+	out := &lockedBuffer{}
+	logger := slog.New(newDedupHandler(slog.NewJSONHandler(out, nil), 100*time.Millisecond))
+	down := errors.New("dial tcp 192.168.1.2:80: connection refused")
+
+	for _, name := range []string{"default/a", "default/b", "default/c"} {
+		logger.With("ingress", name).Error("pihole api error", "operation", "list", "error", down)
+	}
+	logger.With("ingress", "default/a").Error("pihole api error", "operation", "list",
+		"error", errors.New("401 unauthorized"))
+	logger.Info("reconcile started")
+	logger.Info("reconcile started")
+
+	lines := jsonLines(t, out)
+	if len(lines) != 4 {
+		t.Fatalf("logged %d lines during the window, want the first error, the changed error and both infos:\n%v",
+			len(lines), lines)
+	}
+	if lines[0]["ingress"] != "default/a" || lines[1]["error"] != "401 unauthorized" {
+		t.Errorf("lines = %v, want the first occurrence and the changed error", lines[:2])
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	lines = jsonLines(t, out)
+	if len(lines) != 5 {
+		t.Fatalf("logged %d lines after the window, want one summary more:\n%v", len(lines), lines)
+	}
+	summary := lines[4]
+	if summary["msg"] != "pihole api error" || summary["error"] != down.Error() || summary["suppressed_count"] != 2.0 {
+		t.Errorf("summary = %v, want the first error with suppressed_count 2", summary)
+	}
+
+	// After the window the error is logged at once again
+	logger.Error("pihole api error", "operation", "list", "error", down)
+	if n := len(jsonLines(t, out)); n != 6 {
+		t.Errorf("logged %d lines, want the error logged again after its window", n)
+	}
+}
+
+func TestDedupHandlerDisabled(t *testing.T) {
+	next := slog.NewJSONHandler(&lockedBuffer{}, nil)
+	if h := newDedupHandler(next, 0); h != slog.Handler(next) {
+		t.Errorf("newDedupHandler(0) = %T, want the wrapped handler unchanged", h)
+	}
+}
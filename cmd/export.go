@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/api/v1alpha1"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// exportFormats are the formats records export writes
+var exportFormats = []string{"json", "hosts", "csv"}
+
+// exportRecord is one managed record as exported
+type exportRecord struct {
+	Domain string `json:"domain"`
+	Type   string `json:"type"`
+	IP     string `json:"ip"`
+	Owner  string `json:"owner"`
+
+	// LastSync is when the owner last synced, from the PiholeSync status; zero if unknown
+	LastSync time.Time `json:"lastSync,omitzero"`
+}
+
+// exportRecords reads every managed record, sorted by domain, type and owner. The IP
+// is the one Pi-hole holds, or the target the object asks for when Pi-hole doesn't hold
+// the record or, with offline, isn't contacted.
+func exportRecords(ctx context.Context, cfg *config.Config, restConfig *rest.Config, offline bool) ([]exportRecord, error) {
+	reader, sources, err := recordSources(cfg, restConfig)
+	if err != nil {
+		return nil, err
+	}
+	var owned []controller.OwnedRecord
+	for _, src := range sources {
+		records, err := src.OwnedRecords(ctx)
+		if err != nil {
+			return nil, err
+		}
+		owned = append(owned, records...)
+	}
+
+	snapshot := map[string]string{}
+	if !offline {
+		for _, inst := range cfg.Instances() {
+			records, err := listInstance(ctx, inst, reader)
+			if err != nil {
+				return nil, fmt.Errorf("pihole %s: %w", instanceName(inst), err)
+			}
+			for _, r := range records {
+				snapshot[r.Key()] = r.IP
+			}
+		}
+	}
+	synced, err := lastSyncTimes(ctx, reader, cfg.StatusResource)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]exportRecord, 0, len(owned))
+	for _, rec := range owned {
+		ip := rec.TargetIP
+		if held, ok := snapshot[pihole.RecordKey(rec.Domain, rec.Type)]; ok {
+			ip = held
+		}
+		records = append(records, exportRecord{
+			Domain:   rec.Domain,
+			Type:     rec.Type,
+			IP:       ip,
+			Owner:    rec.Owner,
+			LastSync: synced[rec.Owner],
+		})
+	}
+	// Each source's records are sorted; the sources are merged here
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Owner < b.Owner
+	})
+	return records, nil
+}
+
+// lastSyncTimes returns when each owner last synced according to the named PiholeSync.
+// It returns nothing when the status is disabled, not written yet or its CRD is missing.
+func lastSyncTimes(ctx context.Context, reader client.Reader, name string) (map[string]time.Time, error) {
+	times := map[string]time.Time{}
+	if name == "" {
+		return times, nil
+	}
+	var status v1alpha1.PiholeSync
+	if err := reader.Get(ctx, types.NamespacedName{Name: name}, &status); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return times, nil
+		}
+		return nil, fmt.Errorf("reading PiholeSync %s: %w", name, err)
+	}
+	for _, obj := range status.Status.Objects {
+		if obj.LastSyncTime != nil {
+			owner := obj.Kind + " " + types.NamespacedName{Namespace: obj.Namespace, Name: obj.Name}.String()
+			times[owner] = obj.LastSyncTime.UTC()
+		}
+	}
+	return times, nil
+}
+
+// writeExport prints records as JSON, CSV or a hosts file of "IP domain" lines, which
+// Pi-hole imports as local DNS records. A record shared by several owners is listed
+// once in the hosts file.
+func writeExport(w io.Writer, records []exportRecord, format string) error {
+	switch format {
+	case "hosts":
+		seen := map[string]bool{}
+		for _, rec := range records {
+			line := rec.IP + " " + rec.Domain
+			if rec.IP == "" || seen[line] {
+				continue
+			}
+			seen[line] = true
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"domain", "type", "ip", "owner", "last_sync"})
+		for _, rec := range records {
+			lastSync := ""
+			if !rec.LastSync.IsZero() {
+				lastSync = rec.LastSync.Format(time.RFC3339)
+			}
+			_ = cw.Write([]string{rec.Domain, rec.Type, rec.IP, rec.Owner, lastSync})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+}
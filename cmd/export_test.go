@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/api/v1alpha1"
+)
+
+func TestWriteExport(t *testing.T) {
+	synced := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []exportRecord{
+		{Domain: "app.local", Type: "A", IP: "192.168.1.100", Owner: "Ingress default/app", LastSync: synced},
+		{Domain: "app.local", Type: "A", IP: "192.168.1.100", Owner: "Ingress default/app-v2"},
+		{Domain: "app.local", Type: "AAAA", IP: "fd00::10", Owner: "Ingress default/app", LastSync: synced},
+		{Domain: "pending.local", Type: "A", Owner: "Ingress default/pending"},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "hosts",
+			want:   "192.168.1.100 app.local\nfd00::10 app.local\n",
+		},
+		{
+			format: "csv",
+			want: "domain,type,ip,owner,last_sync\n" +
+				"app.local,A,192.168.1.100,Ingress default/app,2024-01-01T12:00:00Z\n" +
+				"app.local,A,192.168.1.100,Ingress default/app-v2,\n" +
+				"app.local,AAAA,fd00::10,Ingress default/app,2024-01-01T12:00:00Z\n" +
+				"pending.local,A,,Ingress default/pending,\n",
+		},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		if err := writeExport(&out, records, tt.format); err != nil {
+			t.Fatalf("writeExport(%s) unexpected error: %v", tt.format, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("writeExport(%s) =\n%s\nwant\n%s", tt.format, out.String(), tt.want)
+		}
+	}
+
+	var out strings.Builder
+	if err := writeExport(&out, records, "json"); err != nil {
+		t.Fatalf("writeExport(json) unexpected error: %v", err)
+	}
+	var decoded []exportRecord
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatalf("json output does not parse: %v\n%s", err, out.String())
+	}
+	if len(decoded) != len(records) || decoded[0] != records[0] || decoded[1] != records[1] {
+		t.Errorf("json records = %+v, want %+v", decoded, records)
+	}
+	if strings.Contains(out.String(), `"lastSync": "0001`) {
+		t.Errorf("json output holds a zero lastSync:\n%s", out.String())
+	}
+}
+
+func TestLastSyncTimes(t *testing.T) {
+	synced := metav1.NewTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	status := &v1alpha1.PiholeSync{ObjectMeta: metav1.ObjectMeta{Name: "pihole-sync"}}
+	status.Status.Objects = []v1alpha1.ObjectSyncStatus{
+		{Kind: "Ingress", Namespace: "default", Name: "app", LastSyncTime: &synced},
+		{Kind: "Ingress", Namespace: "default", Name: "failing"},
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(status).Build()
+
+	times, err := lastSyncTimes(context.Background(), reader, "pihole-sync")
+	if err != nil {
+		t.Fatalf("lastSyncTimes() unexpected error: %v", err)
+	}
+	if len(times) != 1 || !times["Ingress default/app"].Equal(synced.Time) {
+		t.Errorf("lastSyncTimes() = %v, want only Ingress default/app", times)
+	}
+
+	// A status not written yet, or disabled, gives no times
+	for _, name := range []string{"missing", ""} {
+		if times, err := lastSyncTimes(context.Background(), reader, name); err != nil || len(times) != 0 {
+			t.Errorf("lastSyncTimes(%q) = %v, %v, want no times", name, times, err)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// importOptions are the flags of the records import subcommand
+type importOptions struct {
+	file      string
+	adopt     bool
+	owner     string
+	overwrite bool
+	dryRun    bool
+}
+
+// validate returns why the flags are invalid, or "" when they are not
+func (o importOptions) validate() string {
+	switch {
+	case o.file == "":
+		return "--file is required"
+	case o.adopt && o.owner == "":
+		return "--adopt requires --owner"
+	case !o.adopt && o.owner != "":
+		return "--owner requires --adopt"
+	}
+	if _, _, _, ok := controller.ParseOwner(o.owner); o.adopt && !ok {
+		return fmt.Sprintf(`--owner must be "Kind namespace/name", not %q`, o.owner)
+	}
+	return ""
+}
+
+// importConflict is an imported record Pi-hole holds with another IP
+type importConflict struct {
+	pihole.DNSRecord
+	PiholeIP string
+}
+
+// importPlan sorts the imported records by what importing them does
+type importPlan struct {
+	Create    []pihole.DNSRecord
+	Present   []pihole.DNSRecord
+	Conflicts []importConflict
+
+	// overwrite replaces the conflicting records instead of skipping them
+	overwrite bool
+}
+
+// batch returns the Pi-hole changes of the plan
+func (p importPlan) batch() pihole.Batch {
+	batch := pihole.Batch{Creates: append([]pihole.DNSRecord(nil), p.Create...)}
+	if p.overwrite {
+		for _, c := range p.Conflicts {
+			batch.Deletes = append(batch.Deletes, c.Key())
+			batch.Creates = append(batch.Creates, c.DNSRecord)
+		}
+	}
+	return batch
+}
+
+// parseHosts reads hosts-file syntax: an IP followed by one or more names per line,
+// with "#" starting a comment. It returns the records in file order without repeats,
+// and a message for each invalid entry. Loopback and unspecified addresses, such as
+// the localhost lines of a system hosts file or 0.0.0.0 block entries, are skipped.
+func parseHosts(r io.Reader) ([]pihole.DNSRecord, []string, error) {
+	var records []pihole.DNSRecord
+	var invalid []string
+	seen := map[string]pihole.DNSRecord{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			invalid = append(invalid, fmt.Sprintf("line %d: %q is not an IP address", n, fields[0]))
+			continue
+		}
+		if len(fields) == 1 {
+			invalid = append(invalid, fmt.Sprintf("line %d: %s has no names", n, fields[0]))
+			continue
+		}
+		if ip.IsLoopback() || ip.IsUnspecified() {
+			continue
+		}
+		for _, name := range fields[1:] {
+			domain := strings.ToLower(strings.TrimSuffix(name, "."))
+			if errs := validation.IsDNS1123Subdomain(domain); len(errs) > 0 {
+				invalid = append(invalid, fmt.Sprintf("line %d: %q is not a valid domain", n, name))
+				continue
+			}
+			record := pihole.DNSRecord{Domain: domain, IP: ip.String()}
+			if earlier, ok := seen[record.Key()]; ok {
+				if earlier.IP != record.IP {
+					invalid = append(invalid, fmt.Sprintf("line %d: %s %s conflicts with %s earlier in the file",
+						n, domain, record.IP, earlier.IP))
+				}
+				continue
+			}
+			seen[record.Key()] = record
+			records = append(records, record)
+		}
+	}
+	return records, invalid, scanner.Err()
+}
+
+// planImport compares the imported records with those Pi-hole holds
+func planImport(records, existing []pihole.DNSRecord, overwrite bool) importPlan {
+	held := make(map[string]string, len(existing))
+	for _, r := range existing {
+		held[r.Key()] = r.IP
+	}
+	plan := importPlan{overwrite: overwrite}
+	for _, r := range records {
+		switch ip, ok := held[r.Key()]; {
+		case !ok:
+			plan.Create = append(plan.Create, r)
+		case ip == r.IP:
+			plan.Present = append(plan.Present, r)
+		default:
+			plan.Conflicts = append(plan.Conflicts, importConflict{DNSRecord: r, PiholeIP: ip})
+		}
+	}
+	return plan
+}
+
+// runImport loads the hosts file into Pi-hole and prints what it did, or with dryRun
+// what it would do
+func runImport(cfg *config.Config, restConfig *rest.Config, opts importOptions, stdin io.Reader,
+	stdout, stderr io.Writer) int {
+	if opts.adopt && cfg.AuditConfigMap == "" {
+		fmt.Fprintln(stderr, "--adopt records the owner in the audit ConfigMap, which requires AUDIT_CONFIGMAP")
+		return 2
+	}
+
+	in := stdin
+	if opts.file != "-" {
+		f, err := os.Open(opts.file)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return 1
+		}
+		defer func() { _ = f.Close() }()
+		in = f
+	}
+	records, invalid, err := parseHosts(in)
+	if err != nil {
+		fmt.Fprintf(stderr, "reading %s: %v\n", opts.file, err)
+		return 1
+	}
+	if len(invalid) > 0 {
+		for _, msg := range invalid {
+			fmt.Fprintln(stderr, msg)
+		}
+		fmt.Fprintf(stderr, "%d invalid entries, nothing imported\n", len(invalid))
+		return 1
+	}
+
+	reader, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(stderr, "creating client: %v\n", err)
+		return 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), recordsTimeout)
+	defer cancel()
+	piholeClient, err := reachablePihole(ctx, cfg, reader)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	existing, err := piholeClient.ListRecords(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "listing records: %v\n", err)
+		return 1
+	}
+	plan := planImport(records, existing, opts.overwrite)
+	if err := writeImportPlan(stdout, plan, opts.dryRun); err != nil {
+		fmt.Fprintf(stderr, "writing report: %v\n", err)
+		return 1
+	}
+	if opts.dryRun || plan.batch().Len() == 0 {
+		return 0
+	}
+
+	if opts.overwrite {
+		guard := &controller.DeletionGuard{MaxPerSync: cfg.MaxDeletionsPerSync}
+		if err := guard.Reserve(len(plan.Conflicts)); err != nil {
+			fmt.Fprintf(stderr, "%v; raise MAX_DELETIONS_PER_SYNC to replace them all\n", err)
+			return 1
+		}
+	}
+	if err := pihole.ApplyBatch(ctx, piholeClient, plan.batch()); err != nil {
+		fmt.Fprintf(stderr, "importing records: %v\n", err)
+		return 1
+	}
+	if opts.adopt {
+		sink := audit.NewConfigMapSink(reader, reader, cfg.RegistryNamespace, cfg.AuditConfigMap, cfg.AuditMaxEntries)
+		if err := adoptImported(ctx, sink, plan, opts.owner); err != nil {
+			fmt.Fprintf(stderr, "records imported, but recording their owner failed: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// adoptImported records owner as the owner of every imported record in the audit
+// trail, the ownership data records orphans reads, so they are cleaned up once the
+// owner is gone. Records Pi-hole already held with the same IP are adopted too.
+func adoptImported(ctx context.Context, sink audit.Sink, plan importPlan, owner string) error {
+	var entries []audit.Entry
+	for _, r := range append(plan.Create, plan.Present...) {
+		entries = append(entries, audit.Entry{Action: audit.ActionCreate, Domain: r.Domain, Type: r.Type(), NewIP: r.IP, Owner: owner})
+	}
+	if plan.overwrite {
+		for _, c := range plan.Conflicts {
+			entries = append(entries, audit.Entry{Action: audit.ActionUpdate, Domain: c.Domain, Type: c.Type(), OldIP: c.PiholeIP,
+				NewIP: c.IP, Owner: owner})
+		}
+	}
+	for _, e := range entries {
+		if err := sink.Record(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeImportPlan prints one line per imported record and a summary
+func writeImportPlan(w io.Writer, plan importPlan, dryRun bool) error {
+	created, replaced := "to create", "to replace"
+	if !dryRun {
+		created, replaced = "created", "replaced"
+	}
+	conflict := "conflict"
+	if plan.overwrite {
+		conflict = replaced
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tDOMAIN\tTYPE\tIP\tPIHOLE IP")
+	for _, r := range plan.Create {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t-\n", created, r.Domain, r.Type(), r.IP)
+	}
+	for _, r := range plan.Present {
+		fmt.Fprintf(tw, "present\t%s\t%s\t%s\t%s\n", r.Domain, r.Type(), r.IP, r.IP)
+	}
+	for _, c := range plan.Conflicts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", conflict, c.Domain, c.Type(), c.IP, c.PiholeIP)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	summary := fmt.Sprintf("%d %s, %d already present, %d conflicting", len(plan.Create), created,
+		len(plan.Present), len(plan.Conflicts))
+	switch {
+	case len(plan.Conflicts) > 0 && !plan.overwrite:
+		summary += " (kept; --overwrite replaces them)"
+	case len(plan.Conflicts) > 0:
+		summary += " (" + replaced + ")"
+	}
+	if dryRun {
+		summary = "dry run: " + summary
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
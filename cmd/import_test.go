@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestParseHosts(t *testing.T) {
+	hosts := `# exported from the old server
+127.0.0.1   localhost
+::1         localhost ip6-localhost
+0.0.0.0     ads.example.com
+
+192.168.1.10  nas.home  NAS.home.  # storage
+192.168.1.11  printer.home
+fd00::10      nas.home
+192.168.1.12  nas.home
+not-an-ip     broken.home
+192.168.1.13
+192.168.1.14  bad_name.home
+`
+	records, invalid, err := parseHosts(strings.NewReader(hosts))
+	if err != nil {
+		t.Fatalf("parseHosts() unexpected error: %v", err)
+	}
+	want := []pihole.DNSRecord{
+		{Domain: "nas.home", IP: "192.168.1.10"},
+		{Domain: "printer.home", IP: "192.168.1.11"},
+		{Domain: "nas.home", IP: "fd00::10"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %+v, want %+v", records, want)
+	}
+	wantInvalid := []string{
+		"line 9: nas.home 192.168.1.12 conflicts with 192.168.1.10 earlier in the file",
+		`line 10: "not-an-ip" is not an IP address`,
+		"line 11: 192.168.1.13 has no names",
+		`line 12: "bad_name.home" is not a valid domain`,
+	}
+	if !reflect.DeepEqual(invalid, wantInvalid) {
+		t.Errorf("invalid =\n%s\nwant\n%s", strings.Join(invalid, "\n"), strings.Join(wantInvalid, "\n"))
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	records := []pihole.DNSRecord{
+		{Domain: "new.home", IP: "192.168.1.10"},
+		{Domain: "same.home", IP: "192.168.1.11"},
+		{Domain: "moved.home", IP: "192.168.1.12"},
+	}
+	existing := []pihole.DNSRecord{
+		{Domain: "same.home", IP: "192.168.1.11"},
+		{Domain: "moved.home", IP: "192.168.1.99"},
+		{Domain: "other.home", IP: "192.168.1.50"},
+	}
+
+	plan := planImport(records, existing, false)
+	if len(plan.Create) != 1 || len(plan.Present) != 1 || len(plan.Conflicts) != 1 {
+		t.Fatalf("plan = %+v, want one record to create, one present and one conflict", plan)
+	}
+	if c := plan.Conflicts[0]; c.Domain != "moved.home" || c.PiholeIP != "192.168.1.99" {
+		t.Errorf("conflict = %+v, want moved.home held at 192.168.1.99", c)
+	}
+	if batch := plan.batch(); len(batch.Deletes) != 0 || len(batch.Creates) != 1 {
+		t.Errorf("batch() = %+v, want only new.home created", batch)
+	}
+
+	plan = planImport(records, existing, true)
+	batch := plan.batch()
+	if !reflect.DeepEqual(batch.Deletes, []string{pihole.RecordKey("moved.home", "A")}) {
+		t.Errorf("batch().Deletes = %v, want moved.home replaced", batch.Deletes)
+	}
+	if len(batch.Creates) != 2 || batch.Creates[1] != records[2] {
+		t.Errorf("batch().Creates = %+v, want new.home and moved.home", batch.Creates)
+	}
+}
+
+func TestWriteImportPlan(t *testing.T) {
+	plan := planImport(
+		[]pihole.DNSRecord{{Domain: "new.home", IP: "192.168.1.10"}, {Domain: "moved.home", IP: "192.168.1.12"}},
+		[]pihole.DNSRecord{{Domain: "moved.home", IP: "192.168.1.99"}},
+		false,
+	)
+
+	var out strings.Builder
+	if err := writeImportPlan(&out, plan, true); err != nil {
+		t.Fatalf("writeImportPlan() unexpected error: %v", err)
+	}
+	want := "ACTION     DOMAIN      TYPE  IP            PIHOLE IP\n" +
+		"to create  new.home    A     192.168.1.10  -\n" +
+		"conflict   moved.home  A     192.168.1.12  192.168.1.99\n" +
+		"dry run: 1 to create, 0 already present, 1 conflicting (kept; --overwrite replaces them)\n"
+	if out.String() != want {
+		t.Errorf("report =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	plan.overwrite = true
+	out.Reset()
+	if err := writeImportPlan(&out, plan, false); err != nil {
+		t.Fatalf("writeImportPlan() unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "replaced  moved.home") ||
+		!strings.HasSuffix(out.String(), "1 created, 0 already present, 1 conflicting (replaced)\n") {
+		t.Errorf("report =\n%s\nwant moved.home replaced", out.String())
+	}
+}
+
+func TestAdoptImported(t *testing.T) {
+	plan := planImport(
+		[]pihole.DNSRecord{
+			{Domain: "new.home", IP: "192.168.1.10"},
+			{Domain: "same.home", IP: "192.168.1.11"},
+			{Domain: "moved.home", IP: "192.168.1.12"},
+		},
+		[]pihole.DNSRecord{{Domain: "same.home", IP: "192.168.1.11"}, {Domain: "moved.home", IP: "192.168.1.99"}},
+		false,
+	)
+	owner := "Ingress apps/legacy"
+
+	sink := &entrySink{}
+	if err := adoptImported(context.Background(), sink, plan, owner); err != nil {
+		t.Fatalf("adoptImported() unexpected error: %v", err)
+	}
+	var adopted []string
+	for _, e := range sink.entries {
+		if e.Owner != owner {
+			t.Errorf("entry %+v, want owner %s", e, owner)
+		}
+		adopted = append(adopted, e.Domain)
+	}
+	// A conflict that was kept still belongs to whoever set it
+	if got := strings.Join(adopted, ","); got != "new.home,same.home" {
+		t.Errorf("adopted %s, want new.home,same.home", got)
+	}
+
+	plan.overwrite = true
+	sink = &entrySink{}
+	if err := adoptImported(context.Background(), sink, plan, owner); err != nil {
+		t.Fatalf("adoptImported() unexpected error: %v", err)
+	}
+	last := sink.entries[len(sink.entries)-1]
+	if last.Action != audit.ActionUpdate || last.Domain != "moved.home" || last.OldIP != "192.168.1.99" {
+		t.Errorf("last entry = %+v, want moved.home updated from 192.168.1.99", last)
+	}
+}
@@ -19,25 +19,44 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/api/v1alpha1"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/admin"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
 	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
 	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/notify"
 	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/version"
 )
 
 var (
@@ -46,111 +65,727 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 }
 
 func main() {
-	var metricsAddr string
-	var probeAddr string
-	var enableLeaderElection bool
-
-	flag.StringVar(&metricsAddr, "metrics-bind-address", "0",
-		"The address the metrics endpoint binds to. Use :8080 for HTTP, or 0 to disable.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election for controller manager.")
+	// Subcommands parse their own flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "records":
+			os.Exit(runRecordsCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "uninstall":
+			os.Exit(runUninstallCommand(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
+	var configFile string
+	var validate validateFlag
+	var showVersion bool
+	var once bool
+
+	// Every option has a flag named after its environment variable
+	flags := config.RegisterFlags(flag.CommandLine)
+	flag.StringVar(&configFile, "config", "",
+		"Path to a YAML config file, e.g. /etc/pihole-operator/config.yaml. Environment variables and flags override it.")
+	flag.Var(&validate, "validate-config",
+		"Check the configuration, print every setting with secrets redacted and exit. Use =connect to also sign in to Pi-hole.")
+	flag.BoolVar(&showVersion, "version", false, "Print the version and exit.")
+	flag.BoolVar(&once, "once", false,
+		"Reconcile every object once, print a summary and exit; the exit code is 1 when any object failed.")
 	flag.Parse()
 
+	if showVersion {
+		fmt.Println(version.String())
+		os.Exit(0)
+	}
+	if validate != validateOff {
+		if !runValidation(context.Background(), os.Stdout, configFile, flags, validate == validateConnect) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Load operator configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(configFile, flags)
 	if err != nil {
 		slog.Error("failed to load configuration", "error", err)
 		os.Exit(1)
 	}
-
-	// Set up structured logging
-	logLevel := slog.LevelInfo
-	switch cfg.LogLevel {
-	case "debug":
-		logLevel = slog.LevelDebug
-	case "warn":
-		logLevel = slog.LevelWarn
-	case "error":
-		logLevel = slog.LevelError
+	if once {
+		onceConfig(cfg)
 	}
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: logLevel,
-	}))
+	// Set up structured logging; the level follows configuration reloads
+	logLevel := &slog.LevelVar{}
+	logLevel.Set(parseLogLevel(cfg.LogLevel))
+
+	handlerOpts := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler
+	switch cfg.LogFormat {
+	case "text":
+		handler = slog.NewTextHandler(os.Stdout, handlerOpts)
+	case "console":
+		handler = newConsoleHandler(os.Stdout, handlerOpts)
+	default:
+		handler = slog.NewJSONHandler(os.Stdout, handlerOpts)
+	}
+	logger := slog.New(newDedupHandler(handler, cfg.LogDedupWindow))
 	slog.SetDefault(logger)
 
 	// Set up controller-runtime logger to use slog
-	ctrl.SetLogger(NewSlogLogr(logger))
+	ctrl.SetLogger(NewSlogLogr(logger, cfg.LogVerbosity))
 
-	// Create Pi-hole client
-	piholeClient := pihole.NewClient(cfg.PiholeURL, cfg.PiholePassword)
+	logger.Info("pihole-ingress-operator starting", "version", version.Version, "commit", version.Commit,
+		"date", version.Date)
 
-	// Check Pi-hole connectivity
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	if !piholeClient.Healthy(ctx) {
-		logger.Warn("pi-hole is not reachable at startup, will retry during reconciliation", "url", cfg.PiholeURL)
+	// Name and label the operator metrics before anything records them
+	if err := metrics.Configure(cfg.MetricsPrefix, cfg.MetricsConstLabels); err != nil {
+		logger.Error("failed to set up metrics", "error", err)
+		os.Exit(1)
+	}
+
+	// HTTP/2 stays disabled on every TLS endpoint unless asked for
+	tlsOpts := serverTLSOpts(cfg.EnableHTTP2)
+	if !cfg.EnableHTTP2 {
+		logger.Debug("disabling http/2 on the operator's TLS endpoints")
+	}
+
+	// Serve metrics over HTTPS to authorized ServiceAccounts unless METRICS_SECURE is off
+	metricsOpts := metricsserver.Options{
+		BindAddress:   cfg.MetricsBindAddress,
+		SecureServing: cfg.MetricsSecure,
+		CertDir:       cfg.MetricsCertPath,
+		CertName:      cfg.MetricsCertName,
+		KeyName:       cfg.MetricsCertKey,
+		TLSOpts:       tlsOpts,
+	}
+	if cfg.MetricsSecure {
+		metricsOpts.FilterProvider = metrics.WithAuthenticationAndAuthorization
 	}
-	cancel()
 
 	// Configure manager options
 	mgrOpts := ctrl.Options{
-		Scheme: scheme,
-		Metrics: metricsserver.Options{
-			BindAddress: metricsAddr,
-		},
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
+		Scheme:                 scheme,
+		Metrics:                metricsOpts,
+		HealthProbeBindAddress: cfg.HealthProbeBindAddress,
+		LeaderElection:         cfg.LeaderElect,
 		LeaderElectionID:       "d159a95c.pihole.io",
+
+		LeaderElectionNamespace:       cfg.LeaderElectionNamespace,
+		LeaderElectionResourceLock:    cfg.LeaderElectionResourceLock,
+		LeaderElectionReleaseOnCancel: cfg.LeaderElectionReleaseOnCancel,
+		LeaseDuration:                 &cfg.LeaderElectionLeaseDuration,
+		RenewDeadline:                 &cfg.LeaderElectionRenewDeadline,
+		RetryPeriod:                   &cfg.LeaderElectionRetryPeriod,
+
+		GracefulShutdownTimeout: &cfg.GracefulShutdownTimeout,
+	}
+	if cfg.LeaderElect {
+		logger.Info("leader election enabled", "namespace", cfg.LeaderElectionNamespace,
+			"lease_duration", cfg.LeaderElectionLeaseDuration.String(),
+			"renew_deadline", cfg.LeaderElectionRenewDeadline.String(),
+			"retry_period", cfg.LeaderElectionRetryPeriod.String(),
+			"resource_lock", cfg.LeaderElectionResourceLock,
+			"release_on_cancel", cfg.LeaderElectionReleaseOnCancel)
+	}
+
+	restConfig := ctrl.GetConfigOrDie()
+
+	// Optional sources are only watched when their CRD is installed; this is checked
+	// before the manager exists because its cache options may name them
+	domainMappings := false
+	if slices.Contains(cfg.Sources, config.SourceDomainMapping) {
+		if err := crdInstalled(restConfig, controller.DomainMappingGVK); err != nil {
+			logger.Info("source disabled, CRD not installed", "source", config.SourceDomainMapping, "error", err)
+		} else {
+			domainMappings = true
+		}
+	}
+
+	// Log the sources that are set up, leaving out one whose CRD is missing
+	var enabled []string
+	if slices.Contains(cfg.Sources, config.SourceIngress) {
+		enabled = append(enabled, config.SourceIngress)
+	}
+	if domainMappings {
+		enabled = append(enabled, config.SourceDomainMapping)
+	}
+	logger.Info("enabled sources", "sources", strings.Join(enabled, ","))
+
+	// Configure the cache; with REQUIRE_REGISTER_LABEL only labelled sources are held
+	var cached []client.Object
+	if slices.Contains(cfg.Sources, config.SourceIngress) {
+		cached = append(cached, &networkingv1.Ingress{})
+	}
+	if domainMappings {
+		dm := &unstructured.Unstructured{}
+		dm.SetGroupVersionKind(controller.DomainMappingGVK)
+		cached = append(cached, dm)
+	}
+	mgrOpts.Cache = controller.CacheOptions(cfg.RequireRegisterLabel, cfg.Selector(), cached...)
+	if cfg.RequireRegisterLabel {
+		logger.Info("caching only objects labelled for registration", "label", controller.LabelRegister+"=true")
+	}
+	if cfg.LabelSelector != "" {
+		logger.Info("considering only objects matching the label selector", "selector", cfg.LabelSelector)
+	}
+	if len(cfg.IngressClasses) > 0 {
+		logger.Info("considering only ingresses of the listed classes", "classes", strings.Join(cfg.IngressClasses, ","))
+	}
+	if len(cfg.ExcludeNamespaces) > 0 {
+		logger.Info("ignoring excluded namespaces", "namespaces", strings.Join(cfg.ExcludeNamespaces, ","))
 	}
 
 	// Configure namespace watching
 	if cfg.WatchNamespace != "" {
-		mgrOpts.Cache = cache.Options{
-			DefaultNamespaces: map[string]cache.Config{
-				cfg.WatchNamespace: {},
-			},
+		mgrOpts.Cache.DefaultNamespaces = map[string]cache.Config{
+			cfg.WatchNamespace: {},
 		}
 		logger.Info("watching namespace", "namespace", cfg.WatchNamespace)
 	} else {
 		logger.Info("watching all namespaces")
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOpts)
+	// Only the password Secrets are cached, since RBAC grants no other
+	instances := cfg.Instances()
+	var passwordSecrets []types.NamespacedName
+	for _, inst := range instances {
+		if secret, _ := inst.PasswordSecret(); secret.Name != "" {
+			passwordSecrets = append(passwordSecrets, secret)
+		}
+	}
+	if len(passwordSecrets) > 0 {
+		controller.SecretCacheOptions(&mgrOpts.Cache, passwordSecrets...)
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, mgrOpts)
 	if err != nil {
 		logger.Error("unable to start manager", "error", err)
 		os.Exit(1)
 	}
 
-	// Set up the Ingress controller
-	if err := (&controller.IngressReconciler{
-		Client:          mgr.GetClient(),
-		Scheme:          mgr.GetScheme(),
-		PiholeClient:    piholeClient,
-		DefaultTargetIP: cfg.DefaultTargetIP,
-		Logger:          logger,
-	}).SetupWithManager(mgr); err != nil {
-		logger.Error("unable to create controller", "controller", "Ingress", "error", err)
+	// Signals are handled from here on, so waiting for Pi-hole below can be interrupted
+	signalCtx := ctrl.SetupSignalHandler()
+
+	// Create a Pi-hole client for each instance, reading passwords from their Secrets
+	// before the cache has started
+	piholeInstances := make([]pihole.Instance, 0, len(instances))
+	piholeURLs := make([]string, 0, len(instances))
+	piholeSessions := make([]*pihole.HTTPClient, 0, len(instances))
+	for _, inst := range instances {
+		tlsConfig, err := inst.TLS.ClientConfig()
+		if err != nil {
+			logger.Error("invalid pihole tls settings", "instance", inst.Name, "error", err)
+			os.Exit(1)
+		}
+		password := inst.Password
+		secret, key := inst.PasswordSecret()
+		if secret.Name != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			password, err = controller.ReadPasswordSecret(ctx, mgr.GetAPIReader(), secret, key)
+			cancel()
+			if err != nil {
+				logger.Error("failed to read pihole password", "instance", inst.Name, "error", err)
+				os.Exit(1)
+			}
+		}
+		piholeHTTP := pihole.NewClient(inst.URL, password)
+		if tlsConfig != nil {
+			piholeHTTP.SetTLSConfig(tlsConfig)
+		}
+		piholeHTTP.SetShutdown(signalCtx, min(pihole.DefaultShutdownGrace, cfg.GracefulShutdownTimeout))
+		piholeHTTP.SetName(inst.Name)
+		piholeHTTP.SetClusterID(cfg.ClusterID)
+		piholeSessions = append(piholeSessions, piholeHTTP)
+
+		// Check Pi-hole connectivity as STARTUP_PIHOLE_CHECK asks
+		instLogger := logger.With("instance", inst.Name, "url", inst.URL)
+		if err := checkPihole(signalCtx, cfg.StartupPiholeCheck, cfg.StartupPiholeTimeout, piholeHTTP, instLogger); err != nil {
+			if signalCtx.Err() != nil {
+				instLogger.Info("stopped while waiting for pi-hole")
+				os.Exit(0)
+			}
+			instLogger.Error("pi-hole startup check failed", "mode", cfg.StartupPiholeCheck, "error", err)
+			os.Exit(1)
+		}
+
+		// Follow rotations of the password Secret
+		if secret.Name != "" {
+			if err := (&controller.PasswordSecretReconciler{
+				Client:   mgr.GetClient(),
+				Logger:   logger,
+				Secret:   secret,
+				Key:      key,
+				Instance: inst.Name,
+				Pihole:   piholeHTTP,
+			}).SetupWithManager(mgr); err != nil {
+				logger.Error("unable to create controller", "controller", "PasswordSecret", "error", err)
+				os.Exit(1)
+			}
+			logger.Info("reading pihole password from secret", "instance", inst.Name,
+				"secret", secret.String(), "key", key)
+		}
+
+		// Keep the reachability metrics current while no reconcile calls Pi-hole
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			piholeHTTP.ProbeIdle(ctx, pihole.DefaultIdleProbeInterval)
+			return nil
+		})); err != nil {
+			logger.Error("unable to set up pihole probe", "instance", inst.Name, "error", err)
+			os.Exit(1)
+		}
+		piholeInstances = append(piholeInstances, pihole.Instance{Name: inst.Name, Client: piholeHTTP})
+		piholeURLs = append(piholeURLs, inst.URL)
+	}
+
+	// A single instance is used directly and several are kept in step by a MultiClient.
+	// The listing cache lets the debug endpoint inspect records without calling Pi-hole,
+	// and lets reconciles share one listing for LISTING_MAX_AGE
+	var piholeBackend pihole.Client = piholeInstances[0].Client
+	if len(piholeInstances) > 1 {
+		piholeBackend = pihole.NewMultiClient(piholeInstances...)
+		logger.Info("writing records to every pi-hole instance", "instances", len(piholeInstances))
+	}
+	piholeClient := pihole.NewListingCache(piholeBackend)
+	piholeClient.MaxAge = cfg.ListingMaxAge
+	piholeClient.Cluster = cfg.ClusterID
+
+	// Set up the audit trail
+	var auditSinks audit.Multi
+	if cfg.AuditLogPath != "" {
+		auditSinks = append(auditSinks, audit.NewFileSink(cfg.AuditLogPath))
+		logger.Info("writing audit log", "path", cfg.AuditLogPath)
+	}
+	if cfg.AuditConfigMap != "" {
+		auditSinks = append(auditSinks, audit.NewConfigMapSink(mgr.GetClient(), mgr.GetAPIReader(),
+			cfg.RegistryNamespace, cfg.AuditConfigMap, cfg.AuditMaxEntries))
+		logger.Info("writing audit configmap", "namespace", cfg.RegistryNamespace, "name", cfg.AuditConfigMap)
+	}
+	var auditSink audit.Sink
+	if len(auditSinks) > 0 {
+		auditSink = auditSinks
+	}
+
+	// Set up change notifications
+	var notifier *notify.Dispatcher
+	if cfg.NotifyURL != "" {
+		var n notify.Notifier = notify.NewWebhookNotifier(cfg.NotifyURL)
+		if cfg.NotifyFormat == notify.FormatNtfy {
+			n = notify.NewNtfyNotifier(cfg.NotifyURL)
+		}
+		notifier = notify.NewDispatcher(n, cfg.NotifyEvents, notify.DefaultQueueSize, logger)
+		if err := mgr.Add(notifier); err != nil {
+			logger.Error("unable to set up notifier", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("sending change notifications", "format", cfg.NotifyFormat, "events", strings.Join(cfg.NotifyEvents, ","))
+	}
+
+	// Domain locks are shared by every writer so overlapping hosts are updated in turn
+	domainLocks := &controller.DomainLocks{}
+
+	// The desired-state index is shared so every source sees what the others want
+	desiredIndex := &controller.DesiredIndex{}
+
+	// Set up the state registry
+	var store *registry.Store
+	if cfg.RegistryNamespace != "" {
+		store = registry.NewStore(mgr.GetClient(), mgr.GetAPIReader(), cfg.RegistryNamespace, cfg.RegistryName)
+		if err := mgr.Add(&controller.DeferredDeleter{
+			PiholeClient: piholeClient,
+			Registry:     store,
+			Logger:       logger,
+			Audit:        auditSink,
+			Notifier:     notifier,
+			Locks:        domainLocks,
+		}); err != nil {
+			logger.Error("unable to set up deferred deleter", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("using state registry", "namespace", cfg.RegistryNamespace, "name", cfg.RegistryName)
+	} else {
+		logger.Warn("no registry namespace configured, features requiring persisted state are disabled")
+	}
+
+	// Options that may change on a configuration reload are shared by every reconciler
+	liveSettings := controller.NewLiveSettings(reconcilerSettings(cfg))
+
+	if !cfg.EnableFinalizers {
+		logger.Warn("finalizers disabled, DNS records may outlive Ingresses deleted while the operator is down")
+	}
+
+	// Record changes are optionally coalesced into batched writes
+	var batcher *controller.Batcher
+	if cfg.BatchInterval > 0 {
+		batcher = &controller.Batcher{
+			PiholeClient: piholeClient,
+			Logger:       logger,
+			Interval:     cfg.BatchInterval,
+			MaxSize:      cfg.BatchMaxSize,
+		}
+		if err := mgr.Add(batcher); err != nil {
+			logger.Error("unable to set up batcher", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("batching record changes", "interval", cfg.BatchInterval.String(), "max_size", cfg.BatchMaxSize)
+	}
+
+	// Pi-hole losing most managed records at once triggers a rate-limited restore
+	var wipeDetector *controller.WipeDetector
+	if cfg.WipeThreshold > 0 {
+		wipeDetector = &controller.WipeDetector{
+			PiholeClient: piholeClient,
+			Index:        desiredIndex,
+			Logger:       logger,
+			Threshold:    cfg.WipeThreshold,
+			MinRecords:   cfg.WipeMinRecords,
+			MaxPerMinute: cfg.RestoreMaxPerMinute,
+			Audit:        auditSink,
+			Locks:        domainLocks,
+		}
+		if err := mgr.Add(wipeDetector); err != nil {
+			logger.Error("unable to set up wipe detector", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Per-object sync state is optionally published in a PiholeSync status
+	var statusWriter *controller.StatusWriter
+	if cfg.StatusResource != "" {
+		if err := crdInstalled(restConfig, v1alpha1.GroupVersion.WithKind("PiholeSync")); err != nil {
+			logger.Warn("sync status disabled, CRD not installed", "name", cfg.StatusResource, "error", err)
+		} else {
+			statusWriter = &controller.StatusWriter{
+				Client:   mgr.GetClient(),
+				Reader:   mgr.GetAPIReader(),
+				Logger:   logger,
+				Name:     cfg.StatusResource,
+				Interval: cfg.StatusInterval,
+			}
+			if err := mgr.Add(statusWriter); err != nil {
+				logger.Error("unable to set up status writer", "error", err)
+				os.Exit(1)
+			}
+			logger.Info("publishing sync status", "name", cfg.StatusResource, "interval", cfg.StatusInterval.String())
+		}
+	}
+
+	// The deletion guard is shared so its budget covers every source
+	deletionGuard := &controller.DeletionGuard{
+		MaxPerSync:     cfg.MaxDeletionsPerSync,
+		MaxPerInterval: cfg.MaxDeletionsPerInterval,
+		Interval:       cfg.DeletionBudgetInterval,
+	}
+	// The record quota is shared too, so its limits cover every source
+	var quota *controller.RecordQuota
+	if cfg.MaxManagedRecords > 0 || cfg.RecordQuotaPerNamespace > 0 {
+		quota = &controller.RecordQuota{
+			MaxRecords:   cfg.MaxManagedRecords,
+			PerNamespace: cfg.RecordQuotaPerNamespace,
+		}
+		logger.Info("limiting managed records", "max_records", cfg.MaxManagedRecords,
+			"per_namespace", cfg.RecordQuotaPerNamespace)
+	}
+	rateLimit := controller.RateLimit{
+		BaseDelay: cfg.RateLimiterBaseDelay,
+		MaxDelay:  cfg.RateLimiterMaxDelay,
+		QPS:       cfg.RateLimiterQPS,
+		Burst:     cfg.RateLimiterBurst,
+	}
+	// Deleted objects waiting for DNS cleanup are tracked across sources
+	pendingDeletions := &controller.PendingDeletions{
+		Recorder:  mgr.GetEventRecorderFor("pihole-ingress-operator"),
+		WarnAfter: cfg.StuckDeletionWarning,
+	}
+
+	// The records ConfigMap mirrors every managed record for tools that can't call Pi-hole
+	var mirror *controller.RecordsMirror
+	if cfg.RecordsConfigMap != "" {
+		mirror = controller.NewRecordsMirror(mgr.GetClient(), mgr.GetAPIReader(),
+			cfg.RecordsConfigMapNamespace, cfg.RecordsConfigMap, cfg.RecordsConfigMapInterval, logger)
+		logger.Info("writing records configmap", "namespace", cfg.RecordsConfigMapNamespace, "name", cfg.RecordsConfigMap)
+	}
+
+	// Every finished reconcile is a heartbeat; liveness fails when a controller stops
+	heartbeats := &controller.Heartbeats{Staleness: cfg.HeartbeatStaleness}
+
+	var backoffs []*controller.Backoff
+	var reconcilers []*controller.IngressReconciler
+	newReconciler := func() *controller.IngressReconciler {
+		backoff := controller.NewBackoff(cfg.RequeueIntervalError, cfg.RetryMaxBackoff)
+		backoffs = append(backoffs, backoff)
+		r := &controller.IngressReconciler{
+			Client:                  mgr.GetClient(),
+			Scheme:                  mgr.GetScheme(),
+			PiholeClient:            piholeClient,
+			Logger:                  logger,
+			Recorder:                mgr.GetEventRecorderFor("pihole-ingress-operator"),
+			Audit:                   auditSink,
+			Notifier:                notifier,
+			Mirror:                  mirror,
+			Registry:                store,
+			Live:                    liveSettings,
+			Backoff:                 backoff,
+			DeletionGuard:           deletionGuard,
+			Quota:                   quota,
+			Locks:                   domainLocks,
+			Batcher:                 batcher,
+			Index:                   desiredIndex,
+			SyncStatus:              statusWriter,
+			Pending:                 pendingDeletions,
+			Heartbeats:              heartbeats,
+			Wipes:                   wipeDetector,
+			RateLimit:               rateLimit,
+			MaxConcurrentReconciles: cfg.MaxConcurrentReconciles,
+			RequireReady:            cfg.RequireIngressReady,
+			ReadyGracePeriod:        cfg.IngressReadyGracePeriod,
+			DisableFinalizers:       !cfg.EnableFinalizers,
+			StripFinalizers:         cfg.StripFinalizers,
+			FinalizerTimeout:        cfg.FinalizerTimeout,
+			FinalizerMaxAttempts:    cfg.FinalizerMaxAttempts,
+			RequireRegisterLabel:    cfg.RequireRegisterLabel,
+			LabelSelector:           cfg.Selector(),
+			ClusterID:               cfg.ClusterID,
+			APIReader:               mgr.GetAPIReader(),
+		}
+		reconcilers = append(reconcilers, r)
+		return r
+	}
+
+	// Resyncers and ownership sources are keyed by kind for the admin endpoint and signals
+	resyncers := map[string]admin.Resyncer{}
+	var owners []admin.OwnershipSource
+
+	// Set up the Ingress controller; a --once run calls the reconciler directly instead
+	var passes []reconcilePass
+	if slices.Contains(cfg.Sources, config.SourceIngress) {
+		ingressReconciler := newReconciler()
+		if once {
+			passes = append(passes, ingressReconciler.ReconcileAll)
+		} else if err := ingressReconciler.SetupWithManager(mgr); err != nil {
+			logger.Error("unable to create controller", "controller", "Ingress", "error", err)
+			os.Exit(1)
+		}
+		resyncers["Ingress"] = ingressReconciler
+		owners = append(owners, ingressReconciler)
+	}
+
+	// Set up the Knative DomainMapping controller when its CRD is installed
+	if domainMappings {
+		dmReconciler := controller.NewDomainMappingReconciler(newReconciler())
+		if once {
+			passes = append(passes, dmReconciler.ReconcileAll)
+		} else if err := dmReconciler.SetupWithManager(mgr); err != nil {
+			logger.Error("unable to create controller", "controller", "DomainMapping", "error", err)
+			os.Exit(1)
+		}
+		resyncers["DomainMapping"] = dmReconciler
+		owners = append(owners, dmReconciler)
+	}
+
+	// A disabled source has no controller to release the finalizers it added earlier
+	if !slices.Contains(cfg.Sources, config.SourceIngress) {
+		warnLingeringFinalizers(mgr.GetAPIReader(), config.SourceIngress, &networkingv1.IngressList{}, logger)
+	}
+	if !domainMappings && crdInstalled(restConfig, controller.DomainMappingGVK) == nil {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(controller.DomainMappingGVK.GroupVersion().WithKind(controller.DomainMappingGVK.Kind + "List"))
+		warnLingeringFinalizers(mgr.GetAPIReader(), config.SourceDomainMapping, list, logger)
+	}
+
+	// A --once run reconciles everything a single time and exits
+	if once {
+		logger.Info("reconciling every object once", "pihole_url", strings.Join(piholeURLs, ","))
+		code := runOnce(signalCtx, mgr, passes, notifier, os.Stdout, logger)
+		logoutPiholes(piholeSessions, logger)
+		os.Exit(code)
+	}
+
+	// SIGUSR1 resyncs everything, SIGUSR2 logs the ownership table
+	if err := mgr.Add(admin.NewSignalHandler(resyncers, owners, logger)); err != nil {
+		logger.Error("unable to set up signal handler", "error", err)
+		os.Exit(1)
+	}
+
+	// Disagreement between the desired records and the last listing is published as gauges
+	if err := mgr.Add(&controller.DriftMonitor{
+		Index:   desiredIndex,
+		Listing: piholeClient,
+		Kinds:   slices.Sorted(maps.Keys(resyncers)),
+		Mirror:  mirror,
+	}); err != nil {
+		logger.Error("unable to set up drift monitor", "error", err)
+		os.Exit(1)
+	}
+	if mirror != nil {
+		mirror.Sources = reconcilers
+		if err := mgr.Add(mirror); err != nil {
+			logger.Error("unable to set up records configmap", "error", err)
+			os.Exit(1)
+		}
+	}
+	pendingDeletions.Kinds = slices.Sorted(maps.Keys(resyncers))
+	if err := mgr.Add(pendingDeletions); err != nil {
+		logger.Error("unable to set up pending deletion tracking", "error", err)
+		os.Exit(1)
+	}
+
+	// Records past pihole.io/expire-after are removed even when nothing else changes
+	if store != nil {
+		if err := mgr.Add(&controller.ExpirySweeper{
+			Sources:  reconcilers,
+			Registry: store,
+			Logger:   logger,
+		}); err != nil {
+			logger.Error("unable to set up expiry sweeper", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// SIGHUP and edits of the config file apply the options that can change at runtime
+	reloader := newConfigReloader(configFile, flags, cfg, func(next *config.Config) {
+		logLevel.Set(parseLogLevel(next.LogLevel))
+		liveSettings.Store(reconcilerSettings(next))
+		for _, b := range backoffs {
+			b.SetLimits(next.RequeueIntervalError, next.RetryMaxBackoff)
+		}
+	}, resyncers, logger)
+	reloader.resyncLimit = rateLimit.NewLimiter()
+	if err := mgr.Add(reloader); err != nil {
+		logger.Error("unable to set up config reloader", "error", err)
 		os.Exit(1)
 	}
 
+	// Set up the admin endpoint
+	if cfg.AdminBindAddress != config.DisabledAddress {
+		if cfg.AdminToken == "" {
+			logger.Error("admin endpoint requires ADMIN_TOKEN")
+			os.Exit(1)
+		}
+		if err := mgr.Add(&admin.Server{
+			Addr:        cfg.AdminBindAddress,
+			Token:       cfg.AdminToken,
+			Logger:      logger,
+			Resyncers:   resyncers,
+			EnableDebug: cfg.EnableDebugEndpoints,
+			Owners:      owners,
+			Records:     piholeClient,
+			Desired:     desiredIndex,
+			Config:      reloader.Current,
+		}); err != nil {
+			logger.Error("unable to set up admin server", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Set up the profiling endpoint, on a listener of its own
+	if cfg.PprofBindAddress != config.DisabledAddress {
+		if err := mgr.Add(&admin.PprofServer{Addr: cfg.PprofBindAddress, Logger: logger}); err != nil {
+			logger.Error("unable to set up pprof server", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	// Set up health checks
-	// Both liveness and readiness use simple ping - the operator can function
-	// even if Pi-hole is temporarily unavailable (it will retry during reconciliation)
+	// Liveness never checks Pi-hole, so an outage does not restart the pod (it retries
+	// during reconciliation), but fails when a controller stops working through its
+	// queue; readiness fails once a Pi-hole outage has lasted a while
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		logger.Error("unable to set up health check", "error", err)
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	if err := mgr.AddHealthzCheck("controllers", heartbeats.Check); err != nil {
+		logger.Error("unable to set up health check", "error", err)
+		os.Exit(1)
+	}
+	if err := mgr.Add(heartbeats); err != nil {
+		logger.Error("unable to set up controller heartbeats", "error", err)
+		os.Exit(1)
+	}
+	readiness := newPiholeReadiness(piholeClient, cfg.ReadinessFailureThreshold, cfg.ReadinessUnavailableAfter)
+	if err := mgr.AddReadyzCheck("readyz", readiness.Check); err != nil {
 		logger.Error("unable to set up ready check", "error", err)
 		os.Exit(1)
 	}
 
-	logger.Info("starting manager", "pihole_url", cfg.PiholeURL, "default_target_ip", cfg.DefaultTargetIP)
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	logger.Info("starting manager", "pihole_url", strings.Join(piholeURLs, ","), "default_target_ip", cfg.DefaultTargetIP)
+	err = mgr.Start(signalCtx)
+	// The sessions are ended last, once the controllers no longer use them
+	logoutPiholes(piholeSessions, logger)
+	if err != nil {
 		logger.Error("problem running manager", "error", err)
 		os.Exit(1)
 	}
 }
+
+// warnLingeringFinalizers logs when objects of a disabled source still carry the cleanup
+// finalizer, since deleting them blocks until the source is enabled again
+func warnLingeringFinalizers(reader client.Reader, source string, list client.ObjectList, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	count, err := controller.LingeringFinalizers(ctx, reader, list)
+	if err != nil {
+		logger.Warn("could not check a disabled source for finalizers", "source", source, "error", err)
+		return
+	}
+	if count > 0 {
+		logger.Warn("objects of a disabled source still carry the cleanup finalizer; their deletion blocks until "+
+			"the source is enabled again or the finalizer is removed", "source", source, "objects", count,
+			"finalizer", controller.FinalizerName)
+	}
+}
+
+// parseLogLevel maps LOG_LEVEL to a slog level, defaulting to info
+func parseLogLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// reconcilerSettings returns the reconciler options taken from cfg that can change on
+// a configuration reload
+func reconcilerSettings(cfg *config.Config) controller.Settings {
+	var hostFilter *controller.HostFilter
+	if cfg.FilterInternalHosts {
+		hostFilter = &controller.HostFilter{InternalSuffixes: cfg.InternalHostSuffixes}
+	}
+	return controller.Settings{
+		DefaultTargetIP:               cfg.DefaultTargetIP,
+		TargetSource:                  controller.TargetSource(cfg.TargetSource),
+		DefaultTargetIPv6:             cfg.DefaultTargetIPv6,
+		DisableAAAA:                   !cfg.EnableAAAA,
+		DefaultDomainSuffix:           cfg.DefaultDomainSuffix,
+		SyncPolicy:                    controller.SyncPolicy(cfg.SyncPolicy),
+		ConflictPolicy:                controller.ConflictPolicy(cfg.ConflictPolicy),
+		HostsMode:                     controller.HostsMode(cfg.HostsMode),
+		DisableOverwrite:              !cfg.DefaultOverwrite,
+		AdoptExisting:                 cfg.AdoptExisting,
+		AllowCrossNamespaceDuplicates: cfg.AllowCrossNamespaceDuplicates,
+		HostFilter:                    hostFilter,
+		IngressClasses:                cfg.IngressClasses,
+		ExcludeNamespaces:             cfg.ExcludeNamespaces,
+		UpdateConflictRequeue:         cfg.RequeueIntervalConflict,
+		MinRequeueAfter:               cfg.MinRequeueAfter,
+	}
+}
+
+// crdInstalled reports an error unless the API server serves the given kind
+func crdInstalled(restConfig *rest.Config, gvk schema.GroupVersionKind) error {
+	httpClient, err := rest.HTTPClientFor(restConfig)
+	if err != nil {
+		return err
+	}
+	mapper, err := apiutil.NewDynamicRESTMapper(restConfig, httpClient)
+	if err != nil {
+		return err
+	}
+	_, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/notify"
+)
+
+// onceFlushTimeout bounds the delivery of the notifications left queued by a --once run
+var onceFlushTimeout = 30 * time.Second
+
+// reconcilePass reconciles every object of one kind once
+type reconcilePass func(ctx context.Context) (controller.PassResult, error)
+
+// onceConfig adapts cfg to a --once run, which is a single process serving no endpoints
+// and adds finalizers only when ONCE_FINALIZERS asks for them
+func onceConfig(cfg *config.Config) {
+	cfg.LeaderElect = false
+	cfg.MetricsBindAddress = config.DisabledAddress
+	cfg.HealthProbeBindAddress = config.DisabledAddress
+	cfg.EnableFinalizers = cfg.OnceFinalizers
+	if cfg.EnableFinalizers {
+		cfg.StripFinalizers = false
+	}
+}
+
+// runOnce starts the manager for its caches and background writers, runs every pass,
+// prints a summary to w and returns the exit code, 1 when any object failed
+func runOnce(ctx context.Context, mgr ctrl.Manager, passes []reconcilePass, notifier *notify.Dispatcher,
+	w io.Writer, logger *slog.Logger) int {
+	ctx, cancel := context.WithCancel(ctx)
+	stopped := make(chan error, 1)
+	go func() { stopped <- mgr.Start(ctx) }()
+
+	code := 0
+	if !mgr.GetCache().WaitForCacheSync(ctx) {
+		logger.Error("caches did not sync")
+		code = 1
+	} else if !runPasses(ctx, passes, w, logger) {
+		code = 1
+	}
+
+	cancel()
+	if err := <-stopped; err != nil {
+		logger.Error("problem running manager", "error", err)
+		code = 1
+	}
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), onceFlushTimeout)
+	defer cancelFlush()
+	notifier.Flush(flushCtx)
+	return code
+}
+
+// runPasses runs each pass, writes one line per kind and one per failed object to w,
+// and reports whether every object was reconciled or left pending
+func runPasses(ctx context.Context, passes []reconcilePass, w io.Writer, logger *slog.Logger) bool {
+	ok := true
+	for _, pass := range passes {
+		result, err := pass(ctx)
+		if err != nil {
+			logger.Error("reconcile pass failed", "kind", result.Kind, "error", err)
+			ok = false
+			continue
+		}
+		_, _ = fmt.Fprintln(w, result)
+
+		keys := make([]types.NamespacedName, 0, len(result.Failed))
+		for key := range result.Failed {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(a, b types.NamespacedName) int {
+			return strings.Compare(a.String(), b.String())
+		})
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(w, "  %s: %v\n", key, result.Failed[key])
+		}
+		if len(keys) > 0 {
+			ok = false
+		}
+	}
+	return ok
+}
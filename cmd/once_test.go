@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+)
+
+func TestRunPasses(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clean := func(context.Context) (controller.PassResult, error) {
+		return controller.PassResult{Kind: "DomainMapping", Reconciled: 1, Pending: 1}, nil
+	}
+	failing := func(context.Context) (controller.PassResult, error) {
+		return controller.PassResult{Kind: "Ingress", Reconciled: 2, Failed: map[types.NamespacedName]error{
+			{Namespace: "web", Name: "shop"}:    errors.New("pihole api error (status 500): down"),
+			{Namespace: "default", Name: "app"}: errors.New("pihole api error (status 500): down"),
+		}}, nil
+	}
+	unlisted := func(context.Context) (controller.PassResult, error) {
+		return controller.PassResult{Kind: "Ingress"}, errors.New("forbidden")
+	}
+
+	var out bytes.Buffer
+	if !runPasses(context.Background(), []reconcilePass{clean}, &out, logger) {
+		t.Error("runPasses() = false with nothing failed")
+	}
+	if want := "DomainMapping: 1 reconciled, 1 pending, 0 failed\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if runPasses(context.Background(), []reconcilePass{failing, clean}, &out, logger) {
+		t.Error("runPasses() = true with failed objects")
+	}
+	want := "Ingress: 2 reconciled, 0 pending, 2 failed\n" +
+		"  default/app: pihole api error (status 500): down\n" +
+		"  web/shop: pihole api error (status 500): down\n" +
+		"DomainMapping: 1 reconciled, 1 pending, 0 failed\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	if runPasses(context.Background(), []reconcilePass{unlisted}, io.Discard, logger) {
+		t.Error("runPasses() = true when a pass could not list its objects")
+	}
+}
+
+func TestOnceConfig(t *testing.T) {
+	cfg := &config.Config{LeaderElect: true, MetricsBindAddress: ":8443", HealthProbeBindAddress: ":8081",
+		EnableFinalizers: true}
+	onceConfig(cfg)
+	if cfg.LeaderElect || cfg.MetricsBindAddress != config.DisabledAddress ||
+		cfg.HealthProbeBindAddress != config.DisabledAddress || cfg.EnableFinalizers {
+		t.Errorf("onceConfig() = %+v, want no leader election, endpoints or finalizers", cfg)
+	}
+
+	cfg = &config.Config{OnceFinalizers: true, StripFinalizers: true}
+	onceConfig(cfg)
+	if !cfg.EnableFinalizers || cfg.StripFinalizers {
+		t.Errorf("EnableFinalizers, StripFinalizers = %v, %v, want true, false with ONCE_FINALIZERS",
+			cfg.EnableFinalizers, cfg.StripFinalizers)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// orphanOptions are the flags of the records orphans subcommand
+type orphanOptions struct {
+	unowned bool
+	delete  bool
+	yes     bool
+	output  string
+}
+
+// instanceRecords is one Pi-hole and the keys of the records it holds
+type instanceRecords struct {
+	name   string
+	client pihole.Client
+	keys   map[string]bool
+}
+
+// runOrphans prints the orphaned records and, with --delete, removes them once
+// confirmed. Confirmation is read from stdin and prompted on stderr, so the output
+// stays parseable.
+func runOrphans(cfg *config.Config, restConfig *rest.Config, opts orphanOptions, stdin io.Reader,
+	stdout, stderr io.Writer) int {
+	if cfg.AuditConfigMap == "" {
+		fmt.Fprintln(stderr, "AUDIT_CONFIGMAP is not set, so no record has a known owner; only --unowned records can be found")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordsTimeout)
+	reader, orphans, instances, err := scanOrphans(ctx, cfg, restConfig, opts.unowned)
+	cancel()
+	if err != nil {
+		fmt.Fprintf(stderr, "finding orphaned records: %v\n", err)
+		return 1
+	}
+	if err := writeOrphans(stdout, orphans, opts.output); err != nil {
+		fmt.Fprintf(stderr, "writing records: %v\n", err)
+		return 1
+	}
+	if !opts.delete || len(orphans) == 0 {
+		return 0
+	}
+
+	guard := &controller.DeletionGuard{MaxPerSync: cfg.MaxDeletionsPerSync}
+	if err := guard.Reserve(len(orphans)); err != nil {
+		fmt.Fprintf(stderr, "%v; raise MAX_DELETIONS_PER_SYNC to delete them all\n", err)
+		return 1
+	}
+	if !opts.yes && !confirm(stdin, stderr, fmt.Sprintf("Delete %d records?", len(orphans))) {
+		fmt.Fprintln(stderr, "nothing deleted")
+		return 1
+	}
+
+	var sink audit.Sink
+	if cfg.AuditConfigMap != "" {
+		sink = audit.NewConfigMapSink(reader, reader, cfg.RegistryNamespace, cfg.AuditConfigMap, cfg.AuditMaxEntries)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), recordsTimeout)
+	defer cancel()
+	if failed := deleteOrphans(ctx, orphans, instances, sink, stderr); failed > 0 {
+		fmt.Fprintf(stderr, "%d of %d records could not be deleted\n", failed, len(orphans))
+		return 1
+	}
+	fmt.Fprintf(stderr, "deleted %d records\n", len(orphans))
+	return 0
+}
+
+// scanOrphans reads the records of every Pi-hole and the ownership data, and diffs
+// them with the same OrphanScan the controller package provides for cleanup
+func scanOrphans(ctx context.Context, cfg *config.Config, restConfig *rest.Config, unowned bool) (
+	client.Client, []controller.Orphan, []instanceRecords, error) {
+	reader, sources, err := recordSources(cfg, restConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	scan := controller.OrphanScan{ClusterID: cfg.ClusterID}
+	kinds, live := map[string]bool{}, map[string]bool{}
+	for _, src := range sources {
+		owned, err := src.OwnedRecords(ctx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		scan.Owned = append(scan.Owned, owned...)
+		owners, err := src.ObjectOwners(ctx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, owner := range owners {
+			live[owner] = true
+		}
+		kinds[src.Kind()] = true
+	}
+	scan.OwnerExists = ownerExists(kinds, live, cfg.WatchNamespace)
+
+	if cfg.AuditConfigMap != "" {
+		entries, err := audit.ReadConfigMap(ctx, reader, cfg.RegistryNamespace, cfg.AuditConfigMap)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		scan.Claims = controller.ClaimsFromAudit(entries)
+	}
+	if cfg.RegistryNamespace != "" {
+		state, err := registry.NewStore(reader, reader, cfg.RegistryNamespace, cfg.RegistryName).Get(ctx)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("reading registry: %w", err)
+		}
+		scan.Pending = pendingKeys(state)
+	}
+	if unowned {
+		for _, ip := range []string{cfg.DefaultTargetIP, cfg.DefaultTargetIPv6} {
+			if ip != "" {
+				scan.TargetIPs = append(scan.TargetIPs, ip)
+			}
+		}
+	}
+
+	// A record held by several Pi-holes is reported once and deleted from each
+	var instances []instanceRecords
+	seen := map[string]bool{}
+	for _, inst := range cfg.Instances() {
+		piholeClient, err := instanceClient(ctx, inst, cfg.ClusterID, reader)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("pihole %s: %w", instanceName(inst), err)
+		}
+		records, err := piholeClient.ListRecords(ctx)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("pihole %s: %w", instanceName(inst), err)
+		}
+		held := instanceRecords{name: instanceName(inst), client: piholeClient, keys: map[string]bool{}}
+		for _, rec := range records {
+			held.keys[rec.Key()] = true
+			if !seen[rec.Key()] {
+				seen[rec.Key()] = true
+				scan.Records = append(scan.Records, rec)
+			}
+		}
+		instances = append(instances, held)
+	}
+	return reader, scan.Find(), instances, nil
+}
+
+// ownerExists reports whether an owner is one of the live objects. An owner whose kind
+// isn't listed, or outside the watched namespace, can't be checked and is assumed to
+// exist, so its records are left alone.
+func ownerExists(kinds, live map[string]bool, watchNamespace string) func(string) bool {
+	return func(owner string) bool {
+		kind, namespace, _, ok := controller.ParseOwner(owner)
+		if !ok || !kinds[kind] || (watchNamespace != "" && namespace != watchNamespace) {
+			return true
+		}
+		return live[owner]
+	}
+}
+
+// pendingKeys returns the record keys the registry has scheduled for deferred deletion
+func pendingKeys(state *registry.State) map[string]bool {
+	keys := make(map[string]bool, len(state.PendingDeletions))
+	for key := range state.PendingDeletions {
+		keys[key] = true
+	}
+	return keys
+}
+
+// deleteOrphans deletes each orphan from the Pi-holes holding it, recording the
+// deletions in sink when set, and returns how many failed
+func deleteOrphans(ctx context.Context, orphans []controller.Orphan, instances []instanceRecords, sink audit.Sink,
+	stderr io.Writer) int {
+	failed := 0
+	for _, o := range orphans {
+		var err error
+		for _, inst := range instances {
+			if !inst.keys[o.Key()] {
+				continue
+			}
+			if deleteErr := inst.client.DeleteRecord(ctx, o.Domain, o.Type); deleteErr != nil {
+				fmt.Fprintf(stderr, "deleting %s %s from pihole %s: %v\n", o.Domain, o.Type, inst.name, deleteErr)
+				err = deleteErr
+			}
+		}
+		if err != nil {
+			failed++
+			continue
+		}
+		if sink != nil {
+			entry := audit.Entry{Action: audit.ActionDelete, Domain: o.Domain, Type: o.Type, OldIP: o.IP, Owner: o.Owner}
+			if err := sink.Record(ctx, entry); err != nil {
+				fmt.Fprintf(stderr, "recording deletion of %s: %v\n", o.Domain, err)
+			}
+		}
+	}
+	return failed
+}
+
+// confirm asks question on w and reports whether the answer read from r is yes
+func confirm(r io.Reader, w io.Writer, question string) bool {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// writeOrphans prints the orphans as a table or a JSON array
+func writeOrphans(w io.Writer, orphans []controller.Orphan, format string) error {
+	if format == "json" {
+		if orphans == nil {
+			orphans = []controller.Orphan{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(orphans)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOMAIN\tTYPE\tIP\tOWNER\tREASON")
+	for _, o := range orphans {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", o.Domain, o.Type, o.IP, orDash(o.Owner), o.Reason)
+	}
+	return tw.Flush()
+}
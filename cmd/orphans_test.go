@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// deletingPihole records the deletions made through it, failing those of failDomain
+type deletingPihole struct {
+	pihole.Client
+	deleted    []string
+	failDomain string
+}
+
+func (p *deletingPihole) DeleteRecord(_ context.Context, domain, recordType string) error {
+	if domain == p.failDomain {
+		return errors.New("pihole unavailable")
+	}
+	p.deleted = append(p.deleted, pihole.RecordKey(domain, recordType))
+	return nil
+}
+
+// entrySink keeps the entries recorded through it
+type entrySink struct{ entries []audit.Entry }
+
+func (s *entrySink) Record(_ context.Context, entry audit.Entry) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func TestOwnerExists(t *testing.T) {
+	exists := ownerExists(map[string]bool{"Ingress": true}, map[string]bool{"Ingress apps/web": true}, "apps")
+	tests := []struct {
+		owner string
+		want  bool
+	}{
+		{owner: "Ingress apps/web", want: true},
+		{owner: "Ingress apps/gone", want: false},
+		// Neither can be checked, so both are kept
+		{owner: "Ingress other/gone", want: true},
+		{owner: "DomainMapping apps/gone", want: true},
+	}
+	for _, tt := range tests {
+		if got := exists(tt.owner); got != tt.want {
+			t.Errorf("ownerExists(%q) = %v, want %v", tt.owner, got, tt.want)
+		}
+	}
+}
+
+func TestPendingOrphans(t *testing.T) {
+	// An AAAA record scheduled for deletion is left to the operator, even with no A
+	// record beside it
+	state := &registry.State{}
+	state.AddPendingDeletion("app.local/AAAA", registry.PendingDeletion{Owner: "Ingress default/app", DeleteAfter: time.Now().Add(time.Hour)})
+	scan := controller.OrphanScan{
+		Records: []pihole.DNSRecord{{Domain: "app.local", IP: "fd00::1"}, {Domain: "gone.local", IP: "fd00::2"}},
+		Claims: controller.ClaimsFromAudit([]audit.Entry{
+			{Action: audit.ActionCreate, Domain: "app.local", Type: "AAAA", Owner: "Ingress default/app"},
+			{Action: audit.ActionCreate, Domain: "gone.local", Type: "AAAA", Owner: "Ingress default/gone"},
+		}),
+		Pending:     pendingKeys(state),
+		OwnerExists: func(string) bool { return false },
+	}
+
+	got := scan.Find()
+	if len(got) != 1 || got[0].Key() != "gone.local/AAAA" {
+		t.Errorf("Find() = %+v, want only gone.local/AAAA", got)
+	}
+}
+
+func TestDeleteOrphans(t *testing.T) {
+	primary := &deletingPihole{}
+	secondary := &deletingPihole{failDomain: "old.local"}
+	instances := []instanceRecords{
+		{name: "primary", client: primary, keys: map[string]bool{"gone.local": true, "old.local": true}},
+		{name: "secondary", client: secondary, keys: map[string]bool{"gone.local/AAAA": true, "old.local": true}},
+	}
+	orphans := []controller.Orphan{
+		{Domain: "gone.local", Type: "A", IP: "192.168.1.100", Owner: "Ingress default/gone"},
+		{Domain: "gone.local", Type: "AAAA", IP: "fd00::10", Owner: "Ingress default/gone"},
+		{Domain: "old.local", Type: "A", IP: "192.168.1.100"},
+	}
+	sink := &entrySink{}
+	var stderr strings.Builder
+
+	if failed := deleteOrphans(context.Background(), orphans, instances, sink, &stderr); failed != 1 {
+		t.Errorf("deleteOrphans() = %d failed, want 1", failed)
+	}
+	if got := strings.Join(primary.deleted, ","); got != "gone.local,old.local" {
+		t.Errorf("primary deleted %s, want gone.local,old.local", got)
+	}
+	if got := strings.Join(secondary.deleted, ","); got != "gone.local/AAAA" {
+		t.Errorf("secondary deleted %s, want gone.local/AAAA", got)
+	}
+	if !strings.Contains(stderr.String(), "deleting old.local A from pihole secondary") {
+		t.Errorf("stderr = %q, want the failed deletion", stderr.String())
+	}
+	// Only the records gone from every Pi-hole are audited
+	if len(sink.entries) != 2 || sink.entries[0].Owner != "Ingress default/gone" {
+		t.Errorf("audit entries = %+v, want the two gone.local deletions", sink.entries)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for answer, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		var prompt strings.Builder
+		if got := confirm(strings.NewReader(answer), &prompt, "Delete 2 records?"); got != want {
+			t.Errorf("confirm(%q) = %v, want %v", answer, got, want)
+		}
+		if prompt.String() != "Delete 2 records? [y/N] " {
+			t.Errorf("prompt = %q", prompt.String())
+		}
+	}
+}
+
+func TestWriteOrphans(t *testing.T) {
+	orphans := []controller.Orphan{
+		{Domain: "gone.local", Type: "A", IP: "192.168.1.100", Owner: "Ingress default/gone", Reason: controller.OrphanOwnerGone},
+		{Domain: "stray.local", Type: "A", IP: "192.168.1.100", Reason: controller.OrphanUnowned},
+	}
+
+	var table strings.Builder
+	if err := writeOrphans(&table, orphans, "table"); err != nil {
+		t.Fatalf("writeOrphans(table) unexpected error: %v", err)
+	}
+	want := "DOMAIN       TYPE  IP             OWNER                 REASON\n" +
+		"gone.local   A     192.168.1.100  Ingress default/gone  owner-gone\n" +
+		"stray.local  A     192.168.1.100  -                     unowned\n"
+	if table.String() != want {
+		t.Errorf("table =\n%s\nwant\n%s", table.String(), want)
+	}
+
+	var out strings.Builder
+	if err := writeOrphans(&out, nil, "json"); err != nil {
+		t.Fatalf("writeOrphans(json) unexpected error: %v", err)
+	}
+	var decoded []controller.Orphan
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil || decoded == nil {
+		t.Errorf("json output = %q, want an empty array", out.String())
+	}
+}
+
+func TestRunRecordsOrphansUsage(t *testing.T) {
+	var stderr strings.Builder
+	if code := runRecordsCommand([]string{"orphans", "--yes"}, strings.NewReader(""), io.Discard, &stderr); code != 2 {
+		t.Errorf("runRecordsCommand(orphans --yes) = %d, want 2", code)
+	}
+	if !strings.Contains(stderr.String(), "--yes requires --delete") {
+		t.Errorf("stderr = %q, want --yes requires --delete", stderr.String())
+	}
+}
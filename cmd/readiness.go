@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readinessProbeTimeout bounds the Pi-hole probe behind /readyz, so it answers within
+// the readiness probe's timeoutSeconds
+var readinessProbeTimeout = 4 * time.Second
+
+// piholeReadiness is a readiness check that fails once Pi-hole has been unreachable for
+// a while: after failureThreshold consecutive failed probes, or once unavailableAfter
+// (when not zero) has passed since the last success. A single successful probe makes it
+// pass again. Liveness does not use it, so a Pi-hole outage never restarts the pod.
+type piholeReadiness struct {
+	pihole           healthChecker
+	failureThreshold int
+	unavailableAfter time.Duration
+	now              func() time.Time
+
+	mu          sync.Mutex
+	failures    int
+	lastSuccess time.Time
+}
+
+// newPiholeReadiness returns a check that counts the operator's start as its last success
+func newPiholeReadiness(pihole healthChecker, failureThreshold int, unavailableAfter time.Duration) *piholeReadiness {
+	return &piholeReadiness{
+		pihole:           pihole,
+		failureThreshold: failureThreshold,
+		unavailableAfter: unavailableAfter,
+		now:              time.Now,
+		lastSuccess:      time.Now(),
+	}
+}
+
+// Check probes Pi-hole and returns an error when the operator should not be ready; it
+// is a healthz.Checker
+func (r *piholeReadiness) Check(req *http.Request) error {
+	ctx, cancel := context.WithTimeout(req.Context(), readinessProbeTimeout)
+	defer cancel()
+	healthy := r.pihole.Healthy(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if healthy {
+		r.failures = 0
+		r.lastSuccess = now
+		return nil
+	}
+	r.failures++
+	if r.failures >= r.failureThreshold {
+		return fmt.Errorf("pi-hole unreachable for %d consecutive probes", r.failures)
+	}
+	if down := now.Sub(r.lastSuccess); r.unavailableAfter > 0 && down >= r.unavailableAfter {
+		return fmt.Errorf("pi-hole unreachable for %s", down.Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// switchPihole is healthy while up is set
+type switchPihole struct {
+	up bool
+}
+
+func (p *switchPihole) Healthy(context.Context) bool {
+	return p.up
+}
+
+func TestPiholeReadinessHysteresis(t *testing.T) {
+	pihole := &switchPihole{up: true}
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	readiness := newPiholeReadiness(pihole, 3, 0)
+	readiness.now = func() time.Time { return clock }
+	req := httptest.NewRequest("GET", "/readyz", nil)
+
+	if err := readiness.Check(req); err != nil {
+		t.Fatalf("Check() with a healthy pi-hole = %v", err)
+	}
+
+	// A couple of failed probes are tolerated, the third is not
+	pihole.up = false
+	for i := 1; i <= 2; i++ {
+		if err := readiness.Check(req); err != nil {
+			t.Errorf("Check() after %d failed probes = %v, want ready", i, err)
+		}
+	}
+	if err := readiness.Check(req); err == nil {
+		t.Error("Check() after 3 failed probes = nil, want not ready")
+	}
+
+	// The first success recovers and resets the count
+	pihole.up = true
+	if err := readiness.Check(req); err != nil {
+		t.Errorf("Check() after recovering = %v, want ready", err)
+	}
+	pihole.up = false
+	if err := readiness.Check(req); err != nil {
+		t.Errorf("Check() after 1 failed probe = %v, want ready", err)
+	}
+}
+
+func TestPiholeReadinessUnavailableAfter(t *testing.T) {
+	pihole := &switchPihole{up: true}
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	readiness := newPiholeReadiness(pihole, 100, 5*time.Minute)
+	readiness.now = func() time.Time { return clock }
+	req := httptest.NewRequest("GET", "/readyz", nil)
+
+	if err := readiness.Check(req); err != nil {
+		t.Fatalf("Check() with a healthy pi-hole = %v", err)
+	}
+	pihole.up = false
+	clock = clock.Add(4 * time.Minute)
+	if err := readiness.Check(req); err != nil {
+		t.Errorf("Check() 4m after the last success = %v, want ready", err)
+	}
+	clock = clock.Add(time.Minute)
+	if err := readiness.Check(req); err == nil {
+		t.Error("Check() 5m after the last success = nil, want not ready")
+	}
+
+	pihole.up = true
+	if err := readiness.Check(req); err != nil {
+		t.Errorf("Check() after recovering = %v, want ready", err)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/admin"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// recordsTimeout bounds the cluster and Pi-hole reads of the records command
+var recordsTimeout = 30 * time.Second
+
+// recordsUsage is printed for a missing or unknown records subcommand
+const recordsUsage = `Usage: pihole-ingress-operator records list [-o table|json] [flags]
+       pihole-ingress-operator records orphans [--unowned] [--delete [--yes]] [-o table|json] [flags]
+       pihole-ingress-operator records export [--format json|hosts|csv] [--offline] [flags]
+       pihole-ingress-operator records import --file hosts.txt [--overwrite] [--adopt --owner "Kind ns/name"] [--dry-run] [flags]
+       pihole-ingress-operator records verify [--dns host[:port]] [--concurrency n] [-o table|json] [flags]
+
+list prints the records the operator manages, their owners and whether Pi-hole holds
+them.
+
+orphans prints the Pi-hole records the audit ConfigMap last recorded for an object that
+no longer exists and, with --unowned, those pointing at the default target IPs that
+nothing claims. With --delete it removes them once confirmed, refusing more than
+MAX_DELETIONS_PER_SYNC.
+
+export prints every managed record, sorted, for a backup or a diff: as JSON, as a hosts
+file Pi-hole can import, or as CSV. With --offline, Pi-hole is not contacted and the
+records come from the objects' tracking annotations alone.
+
+import creates the records of a hosts file ("-" reads stdin) in Pi-hole in one batch.
+Records Pi-hole holds with another IP are kept unless --overwrite is given. --adopt
+records --owner as their owner in the audit ConfigMap, so records orphans finds them
+once that object is gone.
+
+verify queries the Pi-hole resolver (--dns, by default the host of the Pi-hole URL on
+port 53) for every managed record and compares the answers with the targets. It exits 1
+when any record is missing or resolves elsewhere.
+
+All read the same environment variables and flags as the operator, and the cluster
+from the current kubeconfig context.`
+
+// runRecordsCommand runs "records <subcommand> args..." and returns the exit code: 0
+// on success, 1 when the records could not be listed or deleted and 2 for invalid usage
+func runRecordsCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || !slices.Contains([]string{"list", "orphans", "export", "import", "verify"}, args[0]) {
+		fmt.Fprintln(stderr, recordsUsage)
+		return 2
+	}
+	command := args[0]
+
+	fs := flag.NewFlagSet("records "+command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := config.RegisterFlags(fs)
+	configFile := fs.String("config", "", "Path to a YAML config file. Environment variables and flags override it.")
+	var output, format string
+	var offline bool
+	var opts orphanOptions
+	var imports importOptions
+	var dns string
+	var concurrency int
+	switch command {
+	case "list":
+		fs.StringVar(&output, "o", "table", "Output format: table or json.")
+	case "orphans":
+		fs.StringVar(&output, "o", "table", "Output format: table or json.")
+		fs.BoolVar(&opts.unowned, "unowned", false,
+			"Also report records pointing at the default target IPs that nothing claims.")
+		fs.BoolVar(&opts.delete, "delete", false, "Delete the orphaned records once confirmed.")
+		fs.BoolVar(&opts.yes, "yes", false, "Delete without asking for confirmation.")
+	case "export":
+		fs.StringVar(&format, "format", "json", "Output format: json, hosts or csv.")
+		fs.BoolVar(&offline, "offline", false, "Read the tracked records only, without contacting Pi-hole.")
+	case "import":
+		fs.StringVar(&imports.file, "file", "", "Hosts file to import; - reads stdin.")
+		fs.BoolVar(&imports.overwrite, "overwrite", false, "Replace records Pi-hole holds with another IP.")
+		fs.BoolVar(&imports.adopt, "adopt", false, "Record --owner as the owner of the imported records.")
+		fs.StringVar(&imports.owner, "owner", "", `Owner recorded by --adopt, as "Kind namespace/name".`)
+		fs.BoolVar(&imports.dryRun, "dry-run", false, "Print what would be imported without changing anything.")
+	case "verify":
+		fs.StringVar(&output, "o", "table", "Output format: table or json.")
+		fs.StringVar(&dns, "dns", "", "DNS server to query, as host or host:port; defaults to the Pi-hole host.")
+		fs.IntVar(&concurrency, "concurrency", DefaultVerifyConcurrency, "Number of DNS queries run at once.")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	switch command {
+	case "list", "orphans":
+		if output != "table" && output != "json" {
+			fmt.Fprintf(stderr, "-o must be table or json, not %q\n", output)
+			return 2
+		}
+		if opts.yes && !opts.delete {
+			fmt.Fprintln(stderr, "--yes requires --delete")
+			return 2
+		}
+	case "export":
+		if !slices.Contains(exportFormats, format) {
+			fmt.Fprintf(stderr, "--format must be one of %s, not %q\n", strings.Join(exportFormats, ", "), format)
+			return 2
+		}
+	case "import":
+		if msg := imports.validate(); msg != "" {
+			fmt.Fprintln(stderr, msg)
+			return 2
+		}
+	case "verify":
+		if output != "table" && output != "json" {
+			fmt.Fprintf(stderr, "-o must be table or json, not %q\n", output)
+			return 2
+		}
+		if concurrency < 1 {
+			fmt.Fprintf(stderr, "--concurrency must be at least 1, not %d\n", concurrency)
+			return 2
+		}
+	}
+
+	cfg, err := config.Load(*configFile, flags)
+	if err != nil {
+		fmt.Fprintf(stderr, "loading configuration: %v\n", err)
+		return 1
+	}
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "loading kubeconfig: %v\n", err)
+		return 1
+	}
+	switch command {
+	case "orphans":
+		opts.output = output
+		return runOrphans(cfg, restConfig, opts, stdin, stdout, stderr)
+	case "import":
+		return runImport(cfg, restConfig, imports, stdin, stdout, stderr)
+	case "verify":
+		return runVerify(cfg, restConfig, dns, concurrency, output, stdout, stderr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordsTimeout)
+	defer cancel()
+	if command == "export" {
+		records, err := exportRecords(ctx, cfg, restConfig, offline)
+		if err != nil {
+			fmt.Fprintf(stderr, "exporting records: %v\n", err)
+			return 1
+		}
+		if err := writeExport(stdout, records, format); err != nil {
+			fmt.Fprintf(stderr, "writing records: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	resp, err := listRecords(ctx, cfg, restConfig)
+	if err != nil {
+		fmt.Fprintf(stderr, "listing records: %v\n", err)
+		return 1
+	}
+	if err := writeRecords(stdout, resp, output); err != nil {
+		fmt.Fprintf(stderr, "writing records: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// listRecords reads the records tracked by the objects of every enabled source, and
+// those every Pi-hole holds, without writing to either
+func listRecords(ctx context.Context, cfg *config.Config, restConfig *rest.Config) (admin.DebugResponse, error) {
+	reader, sources, err := recordSources(cfg, restConfig)
+	if err != nil {
+		return admin.DebugResponse{}, err
+	}
+	var owned []controller.OwnedRecord
+	for _, src := range sources {
+		records, err := src.OwnedRecords(ctx)
+		if err != nil {
+			return admin.DebugResponse{}, err
+		}
+		owned = append(owned, records...)
+	}
+
+	snapshot := map[string]string{}
+	for _, inst := range cfg.Instances() {
+		records, err := listInstance(ctx, inst, reader)
+		if err != nil {
+			return admin.DebugResponse{}, fmt.Errorf("pihole %s: %w", instanceName(inst), err)
+		}
+		for _, r := range records {
+			snapshot[r.Key()] = r.IP
+		}
+	}
+	return admin.BuildDebugResponse(owned, snapshot, time.Now(), nil), nil
+}
+
+// recordSources returns a cluster client and a reconciler for each enabled source. The
+// reconcilers are only used to read ownership, so they have no Pi-hole client.
+func recordSources(cfg *config.Config, restConfig *rest.Config) (client.Client, []*controller.IngressReconciler, error) {
+	reader, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, nil, err
+	}
+	var objects client.Client = reader
+	if cfg.WatchNamespace != "" {
+		objects = client.NewNamespacedClient(reader, cfg.WatchNamespace)
+	}
+
+	var store *registry.Store
+	if cfg.RegistryNamespace != "" {
+		store = registry.NewStore(reader, reader, cfg.RegistryNamespace, cfg.RegistryName)
+	}
+	newReconciler := func() *controller.IngressReconciler {
+		return &controller.IngressReconciler{
+			Client:   objects,
+			Logger:   slog.New(slog.DiscardHandler),
+			Live:     controller.NewLiveSettings(reconcilerSettings(cfg)),
+			Registry: store,
+		}
+	}
+	var sources []*controller.IngressReconciler
+	if slices.Contains(cfg.Sources, config.SourceIngress) {
+		sources = append(sources, newReconciler())
+	}
+	if slices.Contains(cfg.Sources, config.SourceDomainMapping) && crdInstalled(restConfig, controller.DomainMappingGVK) == nil {
+		sources = append(sources, controller.NewDomainMappingReconciler(newReconciler()).IngressReconciler)
+	}
+	return reader, sources, nil
+}
+
+// listInstance lists the records of one Pi-hole, with the cluster marked on each,
+// reading its password Secret when it has one
+func listInstance(ctx context.Context, inst config.PiholeInstance, reader client.Reader) ([]pihole.DNSRecord, error) {
+	piholeClient, err := instanceClient(ctx, inst, "", reader)
+	if err != nil {
+		return nil, err
+	}
+	return piholeClient.ListRecords(ctx)
+}
+
+// instanceClient returns a client for one Pi-hole, reading its password Secret when it
+// has one. Its records are scoped to clusterID like the operator's.
+func instanceClient(ctx context.Context, inst config.PiholeInstance, clusterID string, reader client.Reader) (*pihole.HTTPClient, error) {
+	tlsConfig, err := inst.TLS.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	password := inst.Password
+	if secret, key := inst.PasswordSecret(); secret.Name != "" {
+		if password, err = controller.ReadPasswordSecret(ctx, reader, secret, key); err != nil {
+			return nil, err
+		}
+	}
+	piholeClient := pihole.NewClient(inst.URL, password)
+	if tlsConfig != nil {
+		piholeClient.SetTLSConfig(tlsConfig)
+	}
+	piholeClient.SetClusterID(clusterID)
+	return piholeClient, nil
+}
+
+// instanceName names an instance in messages by its name, or its URL when unnamed
+func instanceName(inst config.PiholeInstance) string {
+	if inst.Name != "" {
+		return inst.Name
+	}
+	return inst.URL
+}
+
+// writeRecords prints resp as a table or, with format json, as the JSON body of
+// GET /debug/records
+func writeRecords(w io.Writer, resp admin.DebugResponse, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resp)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOMAIN\tTYPE\tTARGET\tOWNER\tIN PIHOLE\tPIHOLE IP\tCREATED\tLAST SYNCED")
+	for _, rec := range resp.Records {
+		inPihole, piholeIP := "unknown", "-"
+		if rec.InPihole != nil {
+			inPihole = "no"
+			if *rec.InPihole {
+				inPihole, piholeIP = "yes", rec.PiholeIP
+			}
+		}
+		created, synced := "-", "-"
+		if rec.Ownership != nil {
+			created, synced = formatTime(rec.Ownership.CreatedAt), formatTime(rec.Ownership.LastSyncedAt)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", rec.Domain, rec.Type, orDash(rec.TargetIP), rec.Owner,
+			inPihole, piholeIP, created, synced)
+	}
+	return tw.Flush()
+}
+
+// formatTime returns t in RFC 3339 UTC, or "-" when it is zero
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
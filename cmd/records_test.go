@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/admin"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+func TestWriteRecords(t *testing.T) {
+	owned := []controller.OwnedRecord{
+		{Domain: "app.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/app",
+			Ownership: &registry.Ownership{Kind: "Ingress", Namespace: "default", Name: "app",
+				CreatedAt:    time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC),
+				LastSyncedAt: time.Date(2024, 3, 5, 18, 30, 0, 0, time.UTC)}},
+		{Domain: "gone.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/gone"},
+	}
+	resp := admin.BuildDebugResponse(owned, map[string]string{"app.local": "192.168.1.100"}, time.Time{}, nil)
+
+	var table strings.Builder
+	if err := writeRecords(&table, resp, "table"); err != nil {
+		t.Fatalf("writeRecords(table) unexpected error: %v", err)
+	}
+	want := "DOMAIN      TYPE  TARGET         OWNER                 IN PIHOLE  PIHOLE IP      CREATED               LAST SYNCED\n" +
+		"app.local   A     192.168.1.100  Ingress default/app   yes        192.168.1.100  2024-03-02T09:00:00Z  2024-03-05T18:30:00Z\n" +
+		"gone.local  A     192.168.1.100  Ingress default/gone  no         -              -                     -\n"
+	if table.String() != want {
+		t.Errorf("table =\n%s\nwant\n%s", table.String(), want)
+	}
+
+	var out strings.Builder
+	if err := writeRecords(&out, resp, "json"); err != nil {
+		t.Fatalf("writeRecords(json) unexpected error: %v", err)
+	}
+	var decoded admin.DebugResponse
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatalf("json output does not parse: %v\n%s", err, out.String())
+	}
+	if len(decoded.Records) != 2 || decoded.Records[1].InPihole == nil || *decoded.Records[1].InPihole {
+		t.Errorf("json records = %+v, want gone.local missing from pi-hole", decoded.Records)
+	}
+	if len(decoded.Records) > 0 && (decoded.Records[0].Ownership == nil ||
+		!decoded.Records[0].Ownership.CreatedAt.Equal(time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC))) {
+		t.Errorf("json ownership = %+v, want the creation of app.local", decoded.Records[0].Ownership)
+	}
+}
+
+func TestRunRecordsCommandUsage(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: nil, want: "Usage: pihole-ingress-operator records list"},
+		{args: []string{"delete"}, want: "Usage: pihole-ingress-operator records list"},
+		{args: []string{"list", "-o", "yaml"}, want: `-o must be table or json, not "yaml"`},
+		{args: []string{"list", "--no-such-flag"}, want: "flag provided but not defined"},
+		{args: []string{"export", "--format", "yaml"}, want: `--format must be one of json, hosts, csv, not "yaml"`},
+		{args: []string{"export", "-o", "json"}, want: "flag provided but not defined: -o"},
+		{args: []string{"import"}, want: "--file is required"},
+		{args: []string{"verify", "-o", "yaml"}, want: `-o must be table or json, not "yaml"`},
+		{args: []string{"verify", "--concurrency", "0"}, want: "--concurrency must be at least 1, not 0"},
+		{args: []string{"import", "--file", "hosts", "--adopt"}, want: "--adopt requires --owner"},
+		{args: []string{"import", "--file", "hosts", "--owner", "Ingress apps/web"}, want: "--owner requires --adopt"},
+		{args: []string{"import", "--file", "hosts", "--adopt", "--owner", "web"}, want: `--owner must be "Kind namespace/name"`},
+	}
+	for _, tt := range tests {
+		var stderr strings.Builder
+		if code := runRecordsCommand(tt.args, strings.NewReader(""), io.Discard, &stderr); code != 2 {
+			t.Errorf("runRecordsCommand(%v) = %d, want 2", tt.args, code)
+		}
+		if !strings.Contains(stderr.String(), tt.want) {
+			t.Errorf("runRecordsCommand(%v) printed %q, want %q", tt.args, stderr.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/time/rate"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/admin"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+)
+
+// reloadDebounce groups the burst of events from one edit of the config file, such as
+// the symlink swap of an updated ConfigMap volume
+const reloadDebounce = time.Second
+
+// configReloader reloads the configuration on SIGHUP and when the config file changes.
+// Keys that can change at runtime are handed to apply and, when they may change the
+// records of existing objects, every controller is resynced so the new values reach
+// them; other changes are logged as needing a restart. A configuration that fails to
+// load or validate is logged and ignored. The resync runs in the background, so a
+// paced resync of many objects doesn't hold up the next reload, which cancels it.
+type configReloader struct {
+	path      string
+	flags     *config.Flags
+	current   atomic.Pointer[config.Config]
+	apply     func(*config.Config)
+	resyncers map[string]admin.Resyncer
+	logger    *slog.Logger
+	signals   chan os.Signal
+
+	// resyncLimit, when set, paces the objects enqueued after a reload across all
+	// controllers that support it
+	resyncLimit *rate.Limiter
+
+	// stopResync cancels the running resync and waits for it to end; nil when none
+	// was started
+	stopResync func()
+}
+
+// pacedResyncer is a Resyncer that can spread its enqueues out with a limiter
+type pacedResyncer interface {
+	ResyncPaced(ctx context.Context, namespace string, limiter *rate.Limiter) (int, error)
+}
+
+// newConfigReloader subscribes to SIGHUP immediately, like the admin signal handler, so
+// a signal sent before the manager starts is not fatal. The flags given at startup keep
+// their precedence on every reload.
+func newConfigReloader(path string, flags *config.Flags, current *config.Config, apply func(*config.Config),
+	resyncers map[string]admin.Resyncer, logger *slog.Logger) *configReloader {
+	r := &configReloader{
+		path:      path,
+		flags:     flags,
+		apply:     apply,
+		resyncers: resyncers,
+		logger:    logger,
+		signals:   make(chan os.Signal, 1),
+	}
+	r.current.Store(current)
+	signal.Notify(r.signals, syscall.SIGHUP)
+	return r
+}
+
+// Current returns the configuration in effect, as last loaded
+func (r *configReloader) Current() *config.Config {
+	return r.current.Load()
+}
+
+// Start reloads on signals and file changes until ctx is cancelled; it implements
+// manager.Runnable. A replica that only now became leader first catches up with
+// changes made while it was waiting.
+func (r *configReloader) Start(ctx context.Context) error {
+	defer signal.Stop(r.signals)
+	defer r.cancelResync()
+	r.reload(ctx, "startup")
+
+	// The directory is watched rather than the file, which a ConfigMap volume
+	// replaces instead of writing to
+	var fileEvents <-chan fsnotify.Event
+	var fileErrors <-chan error
+	if r.path != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err == nil {
+			err = watcher.Add(filepath.Dir(r.path))
+		}
+		if err != nil {
+			r.logger.Warn("not watching the config file, reload with SIGHUP", "path", r.path, "error", err)
+		} else {
+			defer watcher.Close()
+			fileEvents, fileErrors = watcher.Events, watcher.Errors
+		}
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.signals:
+			r.reload(ctx, "SIGHUP")
+		case ev := <-fileEvents:
+			if r.affectsConfig(ev) {
+				debounce = time.After(reloadDebounce)
+			}
+		case err := <-fileErrors:
+			r.logger.Warn("config file watch error", "path", r.path, "error", err)
+		case <-debounce:
+			debounce = nil
+			r.reload(ctx, "file")
+		}
+	}
+}
+
+// NeedLeaderElection reloads only where the controllers run
+func (r *configReloader) NeedLeaderElection() bool {
+	return true
+}
+
+// affectsConfig reports whether a watch event may have changed the config file: a write
+// to it, or the ..data symlink a ConfigMap volume swaps on update
+func (r *configReloader) affectsConfig(ev fsnotify.Event) bool {
+	if ev.Op == fsnotify.Chmod {
+		return false
+	}
+	name := filepath.Base(ev.Name)
+	return name == filepath.Base(r.path) || name == "..data"
+}
+
+// reload loads the configuration again and applies what changed
+func (r *configReloader) reload(ctx context.Context, trigger string) {
+	next, err := config.Load(r.path, r.flags)
+	if err != nil {
+		r.logger.Error("configuration reload failed, keeping the current configuration",
+			"trigger", trigger, "error", err)
+		return
+	}
+
+	// Only the live keys are taken over; the others keep reporting a pending restart
+	current := r.Current()
+	live, restart := current.Changes(next)
+	if len(restart) > 0 {
+		r.logger.Warn("configuration changes require a restart to take effect",
+			"trigger", trigger, "keys", strings.Join(restart, ","))
+	}
+	if len(live) == 0 {
+		r.logger.Debug("configuration reloaded without live changes", "trigger", trigger)
+		return
+	}
+
+	r.current.Store(current.WithLive(next))
+	r.apply(next)
+	resync := config.ResyncKeys(live)
+	r.logger.Info("configuration reloaded", "trigger", trigger, "keys", strings.Join(live, ","),
+		"resync_keys", strings.Join(resync, ","))
+	if len(resync) > 0 {
+		r.startResync(ctx, trigger)
+	}
+}
+
+// startResync cancels any resync still running from an earlier reload and resyncs every
+// controller in the background, logging how many objects of each kind were enqueued
+// once it ends
+func (r *configReloader) startResync(ctx context.Context, trigger string) {
+	r.cancelResync()
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	r.stopResync = func() {
+		cancel()
+		<-done
+	}
+
+	go func() {
+		defer close(done)
+		enqueued := make([]string, 0, len(r.resyncers))
+		total := 0
+		for _, kind := range slices.Sorted(maps.Keys(r.resyncers)) {
+			n, err := r.resync(ctx, r.resyncers[kind])
+			enqueued = append(enqueued, fmt.Sprintf("%s=%d", kind, n))
+			total += n
+			if ctx.Err() != nil {
+				r.logger.Info("configuration resync cancelled", "trigger", trigger,
+					"enqueued", total, "enqueued_by_kind", strings.Join(enqueued, ","))
+				return
+			}
+			if err != nil {
+				r.logger.Error("resync failed", "kind", kind, "error", err)
+			}
+		}
+		r.logger.Info("configuration resync finished", "trigger", trigger,
+			"enqueued", total, "enqueued_by_kind", strings.Join(enqueued, ","))
+	}()
+}
+
+// cancelResync stops the resync of an earlier reload, if one is running
+func (r *configReloader) cancelResync() {
+	if r.stopResync != nil {
+		r.stopResync()
+		r.stopResync = nil
+	}
+}
+
+// resync enqueues every object managed by rs, paced by resyncLimit when rs supports it
+func (r *configReloader) resync(ctx context.Context, rs admin.Resyncer) (int, error) {
+	if paced, ok := rs.(pacedResyncer); ok && r.resyncLimit != nil {
+		return paced.ResyncPaced(ctx, "", r.resyncLimit)
+	}
+	return rs.Resync(ctx, "")
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/admin"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+)
+
+// lockedBuffer is a bytes.Buffer safe for the reloader goroutine and the test
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type countingResyncer struct{ calls chan struct{} }
+
+func (r countingResyncer) Resync(context.Context, string) (int, error) {
+	r.calls <- struct{}{}
+	return 1, nil
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+}
+
+func TestConfigReloader(t *testing.T) {
+	os.Clearenv()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	base := "piholeURL: http://192.168.1.2\npiholePassword: s3cret\n"
+	writeConfig(t, path, base+"defaultTargetIP: 192.168.1.100\n")
+	current, err := config.Load(path, nil)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	applied := make(chan *config.Config, 4)
+	resyncer := countingResyncer{calls: make(chan struct{}, 4)}
+	logs := &lockedBuffer{}
+	r := newConfigReloader(path, nil, current, func(next *config.Config) { applied <- next },
+		map[string]admin.Resyncer{"Ingress": resyncer}, slog.New(slog.NewTextHandler(logs, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = r.Start(ctx) }()
+
+	// An edit of the file is applied and resyncs the controllers
+	writeConfig(t, path, base+"defaultTargetIP: 192.168.1.101\n")
+	select {
+	case next := <-applied:
+		if next.DefaultTargetIP != "192.168.1.101" {
+			t.Errorf("applied DefaultTargetIP = %q, want 192.168.1.101", next.DefaultTargetIP)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config file change was not applied")
+	}
+	select {
+	case <-resyncer.calls:
+	case <-time.After(time.Second):
+		t.Error("controllers not resynced after the reload")
+	}
+	waitForLog(t, logs, `enqueued_by_kind="Ingress=1"`)
+
+	// A log level change is applied without a resync
+	writeConfig(t, path, base+"defaultTargetIP: 192.168.1.101\nlogLevel: debug\n")
+	select {
+	case next := <-applied:
+		if next.LogLevel != "debug" {
+			t.Errorf("applied LogLevel = %q, want debug", next.LogLevel)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("log level change was not applied")
+	}
+	waitForLog(t, logs, "keys=logLevel")
+	select {
+	case <-resyncer.calls:
+		t.Error("controllers resynced after a log level change")
+	default:
+	}
+
+	// An invalid file and a restart-only change are reported, not applied
+	writeConfig(t, path, base+"defaultTargetIP: not-an-ip\nlogLevel: debug\n")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("sending SIGHUP: %v", err)
+	}
+	waitForLog(t, logs, "configuration reload failed")
+	writeConfig(t, path, base+"defaultTargetIP: 192.168.1.101\nlogLevel: debug\nwatchNamespace: apps\n")
+	waitForLog(t, logs, "keys=watchNamespace")
+	select {
+	case next := <-applied:
+		t.Errorf("applied %+v without a live change", next)
+	default:
+	}
+}
+
+// blockingResyncer is a Resyncer whose resync lasts until it is cancelled, like a paced
+// resync of many objects
+type blockingResyncer struct{ started chan struct{} }
+
+func (r blockingResyncer) Resync(ctx context.Context, _ string) (int, error) {
+	r.started <- struct{}{}
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestConfigReloaderResyncInBackground(t *testing.T) {
+	os.Clearenv()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	base := "piholeURL: http://192.168.1.2\npiholePassword: s3cret\n"
+	writeConfig(t, path, base+"defaultTargetIP: 192.168.1.100\n")
+	current, err := config.Load(path, nil)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	resyncer := blockingResyncer{started: make(chan struct{}, 2)}
+	logs := &lockedBuffer{}
+	r := newConfigReloader(path, nil, current, func(*config.Config) {},
+		map[string]admin.Resyncer{"Ingress": resyncer}, slog.New(slog.NewTextHandler(logs, nil)))
+	defer signal.Stop(r.signals)
+	defer r.cancelResync()
+
+	// A reload returns while its resync is still running, and the next one cancels it
+	writeConfig(t, path, base+"defaultTargetIP: 192.168.1.101\n")
+	r.reload(t.Context(), "file")
+	<-resyncer.started
+	writeConfig(t, path, base+"defaultTargetIP: 192.168.1.100\n")
+	r.reload(t.Context(), "SIGHUP")
+	<-resyncer.started
+
+	if got := r.Current().DefaultTargetIP; got != "192.168.1.100" {
+		t.Errorf("DefaultTargetIP = %q, want the revert applied during the resync", got)
+	}
+	waitForLog(t, logs, `msg="configuration resync cancelled" trigger=file`)
+}
+
+func waitForLog(t *testing.T, logs *lockedBuffer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(logs.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("logs = %q, want %q", logs.String(), want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
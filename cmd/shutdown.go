@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// logoutTimeout bounds ending the Pi-hole sessions on shutdown, within the pod's
+// termination grace
+var logoutTimeout = time.Second
+
+// logoutPiholes ends the session of every Pi-hole client. Failures are only logged,
+// as the sessions expire on their own.
+func logoutPiholes(clients []*pihole.HTTPClient, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), logoutTimeout)
+	defer cancel()
+	for _, c := range clients {
+		if err := c.Logout(ctx); err != nil {
+			logger.Warn("failed to end pihole session", "error", err)
+		}
+	}
+}
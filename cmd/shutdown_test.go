@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// hungPihole accepts requests and never answers them until closed
+func hungPihole(t *testing.T) *httptest.Server {
+	t.Helper()
+	closed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-closed:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(closed)
+		server.Close()
+	})
+	return server
+}
+
+func TestShutdownWithHungPihole(t *testing.T) {
+	timeout := 300 * time.Millisecond
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "http://127.0.0.1:1"}, ctrl.Options{
+		Scheme:                  scheme,
+		Metrics:                 metricsserver.Options{BindAddress: "0"},
+		HealthProbeBindAddress:  "0",
+		GracefulShutdownTimeout: &timeout,
+	})
+	if err != nil {
+		t.Fatalf("NewManager() unexpected error: %v", err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	client := pihole.NewClient(hungPihole(t).URL, "password")
+	client.SetShutdown(ctx, pihole.DefaultShutdownGrace)
+	calling := make(chan struct{})
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		close(calling)
+		_, err := client.ListRecords(ctx)
+		return err
+	})); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- mgr.Start(ctx) }()
+	<-calling
+	start := time.Now()
+	stop()
+	select {
+	case <-done:
+		if elapsed := time.Since(start); elapsed > timeout+time.Second {
+			t.Errorf("manager stopped after %v, want within %v", elapsed, timeout)
+		}
+	case <-time.After(pihole.DefaultShutdownGrace):
+		t.Fatal("manager still running after the Pi-hole grace, ignoring the shutdown timeout")
+	}
+
+}
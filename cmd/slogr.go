@@ -1,28 +1,38 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 
 	"github.com/go-logr/logr"
 )
 
-// slogLogr adapts slog.Logger to logr.Logger interface
+// slogLogr adapts slog.Logger to logr.Logger interface. logr verbosity V(n) is logged at
+// slog level Info-n, so V(1) sits just below info and V(4) at debug, and nothing above
+// maxVerbosity is logged at all.
 type slogLogr struct {
-	logger *slog.Logger
-	name   string
+	logger       *slog.Logger
+	name         string
+	maxVerbosity int
 }
 
-// NewSlogLogr creates a new logr.Logger that uses slog
-func NewSlogLogr(logger *slog.Logger) logr.Logger {
-	return logr.New(&slogLogr{logger: logger})
+// NewSlogLogr creates a new logr.Logger that uses slog, dropping messages more verbose
+// than maxVerbosity
+func NewSlogLogr(logger *slog.Logger, maxVerbosity int) logr.Logger {
+	return logr.New(&slogLogr{logger: logger, maxVerbosity: maxVerbosity})
+}
+
+// slogLevel maps a logr verbosity to the slog level it is logged at
+func slogLevel(verbosity int) slog.Level {
+	return slog.LevelInfo - slog.Level(verbosity)
 }
 
 func (l *slogLogr) Init(info logr.RuntimeInfo) {}
 
+// Enabled reports whether a message at verbosity level would be written, so logr skips
+// formatting the ones that would not
 func (l *slogLogr) Enabled(level int) bool {
-	// controller-runtime uses V(0) for info, V(1) for debug
-	// Map to slog levels
-	return true
+	return level <= l.maxVerbosity && l.logger.Enabled(context.Background(), slogLevel(level))
 }
 
 func (l *slogLogr) Info(level int, msg string, keysAndValues ...any) {
@@ -30,11 +40,7 @@ func (l *slogLogr) Info(level int, msg string, keysAndValues ...any) {
 	if l.name != "" {
 		logger = logger.With("logger", l.name)
 	}
-	if level > 0 {
-		logger.Debug(msg, keysAndValues...)
-	} else {
-		logger.Info(msg, keysAndValues...)
-	}
+	logger.Log(context.Background(), slogLevel(level), msg, keysAndValues...)
 }
 
 func (l *slogLogr) Error(err error, msg string, keysAndValues ...any) {
@@ -48,8 +54,9 @@ func (l *slogLogr) Error(err error, msg string, keysAndValues ...any) {
 
 func (l *slogLogr) WithValues(keysAndValues ...any) logr.LogSink {
 	return &slogLogr{
-		logger: l.logger.With(keysAndValues...),
-		name:   l.name,
+		logger:       l.logger.With(keysAndValues...),
+		name:         l.name,
+		maxVerbosity: l.maxVerbosity,
 	}
 }
 
@@ -59,7 +66,8 @@ func (l *slogLogr) WithName(name string) logr.LogSink {
 		newName = l.name + "." + name
 	}
 	return &slogLogr{
-		logger: l.logger,
-		name:   newName,
+		logger:       l.logger,
+		name:         newName,
+		maxVerbosity: l.maxVerbosity,
 	}
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+// recordingHandler keeps the records at or above its level
+type recordingHandler struct {
+	level   slog.Level
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestSlogLogrVerbosity(t *testing.T) {
+	tests := []struct {
+		name         string
+		level        slog.Level
+		maxVerbosity int
+		want         []slog.Level
+	}{
+		{name: "info hides every V above 0", level: slog.LevelInfo, maxVerbosity: 10,
+			want: []slog.Level{slog.LevelInfo}},
+		{name: "debug shows V up to 4", level: slog.LevelDebug, maxVerbosity: 10,
+			want: []slog.Level{slog.LevelInfo, slog.LevelInfo - 1, slog.LevelInfo - 2, slog.LevelInfo - 3, slog.LevelDebug}},
+		{name: "verbosity gates below the handler level", level: slog.LevelDebug, maxVerbosity: 1,
+			want: []slog.Level{slog.LevelInfo, slog.LevelInfo - 1}},
+		{name: "a verbose handler shows V(5)", level: slog.LevelDebug - 1, maxVerbosity: 5,
+			want: []slog.Level{slog.LevelInfo, slog.LevelInfo - 1, slog.LevelInfo - 2, slog.LevelInfo - 3, slog.LevelDebug, slog.LevelDebug - 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &recordingHandler{level: tt.level}
+			log := NewSlogLogr(slog.New(handler), tt.maxVerbosity).WithName("controller")
+
+			for v := 0; v <= 5; v++ {
+				log.V(v).Info("message", "verbosity", v)
+				if enabled := log.V(v).Enabled(); enabled != (v < len(tt.want)) {
+					t.Errorf("V(%d).Enabled() = %v, want %v", v, enabled, v < len(tt.want))
+				}
+			}
+
+			var got []slog.Level
+			for _, r := range handler.records {
+				got = append(got, r.Level)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("logged levels = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("logged levels = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSlogLogrErrorIgnoresVerbosity(t *testing.T) {
+	handler := &recordingHandler{level: slog.LevelError}
+	NewSlogLogr(slog.New(handler), 0).V(3).Error(nil, "failed")
+	if len(handler.records) != 1 || handler.records[0].Level != slog.LevelError {
+		t.Errorf("records = %v, want one error", handler.records)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Probe timing for STARTUP_PIHOLE_CHECK=wait: the delay between probes starts at
+// startupProbeInitial and doubles up to startupProbeMax
+var (
+	startupProbeInitial = time.Second
+	startupProbeMax     = 30 * time.Second
+	startupProbeTimeout = 10 * time.Second
+)
+
+// healthChecker is the part of the Pi-hole client probed at startup
+type healthChecker interface {
+	Healthy(ctx context.Context) bool
+}
+
+// checkPihole probes Pi-hole once before the manager starts and handles an unreachable
+// server according to mode: warn logs and carries on, fail returns an error, and wait
+// keeps probing until Pi-hole answers, timeout elapses or ctx is cancelled
+func checkPihole(ctx context.Context, mode string, timeout time.Duration, pihole healthChecker, logger *slog.Logger) error {
+	if probe(ctx, pihole) {
+		return nil
+	}
+	switch mode {
+	case "fail":
+		return errors.New("pi-hole is not reachable")
+	case "wait":
+		return waitForPihole(ctx, timeout, pihole, logger)
+	}
+	logger.Warn("pi-hole is not reachable at startup, will retry during reconciliation")
+	return nil
+}
+
+// waitForPihole probes with backoff until Pi-hole is healthy. A zero timeout waits
+// until ctx is cancelled, e.g. by SIGTERM.
+func waitForPihole(ctx context.Context, timeout time.Duration, pihole healthChecker, logger *slog.Logger) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	logger.Info("waiting for pi-hole to become reachable", "timeout", timeout.String())
+
+	delay := startupProbeInitial
+	for attempt := 2; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("pi-hole not reachable within %s", timeout)
+			}
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if probe(ctx, pihole) {
+			logger.Info("pi-hole is reachable", "attempts", attempt)
+			return nil
+		}
+		logger.Debug("pi-hole not reachable yet", "attempt", attempt, "retry_in", delay.String())
+		delay = min(delay*2, startupProbeMax)
+	}
+}
+
+// probe runs one health check bounded by startupProbeTimeout
+func probe(ctx context.Context, pihole healthChecker) bool {
+	ctx, cancel := context.WithTimeout(ctx, startupProbeTimeout)
+	defer cancel()
+	return pihole.Healthy(ctx)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyPihole becomes healthy after failing a number of probes
+type flakyPihole struct {
+	failures int32
+	probes   atomic.Int32
+}
+
+func (p *flakyPihole) Healthy(context.Context) bool {
+	return p.probes.Add(1) > p.failures
+}
+
+func TestCheckPihole(t *testing.T) {
+	startupProbeInitial, startupProbeMax = time.Millisecond, 4*time.Millisecond
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	if err := checkPihole(ctx, "warn", 0, &flakyPihole{failures: 1}, logger); err != nil {
+		t.Errorf("checkPihole(warn) unexpected error: %v", err)
+	}
+	if err := checkPihole(ctx, "fail", 0, &flakyPihole{failures: 1}, logger); err == nil {
+		t.Error("checkPihole(fail) = nil with an unreachable pi-hole")
+	}
+
+	pihole := &flakyPihole{failures: 4}
+	if err := checkPihole(ctx, "wait", time.Minute, pihole, logger); err != nil {
+		t.Errorf("checkPihole(wait) unexpected error: %v", err)
+	}
+	if got := pihole.probes.Load(); got != 5 {
+		t.Errorf("checkPihole(wait) probed %d times, want until the fifth succeeded", got)
+	}
+
+	err := checkPihole(ctx, "wait", 20*time.Millisecond, &flakyPihole{failures: 1 << 20}, logger)
+	if err == nil || !strings.Contains(err.Error(), "not reachable within 20ms") {
+		t.Errorf("checkPihole(wait) error = %v, want the timeout", err)
+	}
+
+	// Cancelling, as SIGTERM does, stops a wait without a timeout
+	cancelled, cancel := context.WithCancel(ctx)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := checkPihole(cancelled, "wait", 0, &flakyPihole{failures: 1 << 20}, logger); err != context.Canceled {
+		t.Errorf("checkPihole(wait) error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"crypto/tls"
+)
+
+// disableHTTP2 limits a TLS server to HTTP/1.1, as a TLSOpts hook. HTTP/2 is off by
+// default on the operator's TLS endpoints because of the HTTP/2 Stream Cancellation
+// and Rapid Reset CVEs (GHSA-qppj-fm5r-hxr3, GHSA-4374-p667-p6c8).
+func disableHTTP2(c *tls.Config) {
+	c.NextProtos = []string{"http/1.1"}
+}
+
+// serverTLSOpts returns the TLS hooks applied to every TLS endpoint the operator serves
+func serverTLSOpts(enableHTTP2 bool) []func(*tls.Config) {
+	var opts []func(*tls.Config)
+	if !enableHTTP2 {
+		opts = append(opts, disableHTTP2)
+	}
+	return opts
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerTLSOptsNegotiatedProtocol(t *testing.T) {
+	tests := []struct {
+		enableHTTP2 bool
+		want        string
+	}{
+		{enableHTTP2: false, want: "HTTP/1.1"},
+		{enableHTTP2: true, want: "HTTP/2.0"},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		srv.EnableHTTP2 = true
+		srv.TLS = &tls.Config{}
+		for _, opt := range serverTLSOpts(tt.enableHTTP2) {
+			opt(srv.TLS)
+		}
+		srv.StartTLS()
+
+		resp, err := srv.Client().Get(srv.URL)
+		srv.Close()
+		if err != nil {
+			t.Fatalf("GET with enableHTTP2=%v: %v", tt.enableHTTP2, err)
+		}
+		_ = resp.Body.Close()
+		if resp.Proto != tt.want {
+			t.Errorf("enableHTTP2=%v negotiated %s, want %s", tt.enableHTTP2, resp.Proto, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// uninstallTimeout bounds a whole uninstall run, which may touch many objects
+var uninstallTimeout = 5 * time.Minute
+
+// uninstallUsage is printed when the uninstall command is given no action
+const uninstallUsage = `Usage: pihole-ingress-operator uninstall --remove-finalizers [--namespaces a,b] [--dry-run] [--cleanup-dns] [flags]
+
+Remove the pihole.io/dns-cleanup finalizer from every Ingress and DomainMapping, so
+they and their namespaces can still be deleted once the operator is gone. Objects
+already terminating are then deleted. One line is printed per object.
+
+With --cleanup-dns, the records each object tracks are first deleted from Pi-hole when
+it is reachable, as the operator would on deletion. This reads the same environment
+variables, flags and --config file as the operator. The cluster is read from the
+current kubeconfig context.`
+
+// runUninstallCommand runs "uninstall args..." and returns the exit code: 0 on
+// success, 1 when any finalizer could not be removed and 2 for invalid usage
+func runUninstallCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("uninstall", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	flags := config.RegisterFlags(fs)
+	configFile := fs.String("config", "", "Path to a YAML config file. Environment variables and flags override it.")
+	removeFinalizers := fs.Bool("remove-finalizers", false, "Remove the cleanup finalizer from every object.")
+	namespaces := fs.String("namespaces", "", "Comma-separated namespaces to limit the removal to; every namespace if empty.")
+	dryRun := fs.Bool("dry-run", false, "Print what would be done without changing anything.")
+	cleanupDNS := fs.Bool("cleanup-dns", false, "Delete the records of each object from Pi-hole first, if reachable.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !*removeFinalizers {
+		fmt.Fprintln(stderr, uninstallUsage)
+		return 2
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "loading kubeconfig: %v\n", err)
+		return 1
+	}
+	k8s, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(stderr, "creating client: %v\n", err)
+		return 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), uninstallTimeout)
+	defer cancel()
+
+	newReconciler := func() *controller.IngressReconciler {
+		return &controller.IngressReconciler{Client: k8s, Logger: slog.New(slog.DiscardHandler)}
+	}
+	if *cleanupDNS {
+		cfg, err := config.Load(*configFile, flags)
+		if err != nil {
+			fmt.Fprintf(stderr, "loading configuration: %v\n", err)
+			return 1
+		}
+		piholeClient, err := reachablePihole(ctx, cfg, k8s)
+		if err != nil {
+			fmt.Fprintf(stderr, "pi-hole unavailable, removing finalizers without DNS cleanup: %v\n", err)
+		}
+		live := controller.NewLiveSettings(reconcilerSettings(cfg))
+		newReconciler = func() *controller.IngressReconciler {
+			return &controller.IngressReconciler{
+				Client:       k8s,
+				Logger:       slog.New(slog.DiscardHandler),
+				PiholeClient: piholeClient,
+				Live:         live,
+			}
+		}
+	}
+
+	// Every source is checked, enabled or not, since a disabled one may still hold finalizers
+	sources := []*controller.IngressReconciler{newReconciler()}
+	if crdInstalled(restConfig, controller.DomainMappingGVK) == nil {
+		sources = append(sources, controller.NewDomainMappingReconciler(newReconciler()).IngressReconciler)
+	}
+
+	var scope []string
+	if *namespaces != "" {
+		for _, ns := range strings.Split(*namespaces, ",") {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				scope = append(scope, ns)
+			}
+		}
+	}
+	objects, failed := 0, 0
+	for _, src := range sources {
+		holders, err := src.FinalizerHolders(ctx, scope)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return 1
+		}
+		for _, obj := range holders {
+			objects++
+			release, err := src.ReleaseFinalizer(ctx, obj, *cleanupDNS, *dryRun)
+			if err != nil {
+				failed++
+			}
+			fmt.Fprintln(stdout, releaseReport(release, err, *dryRun))
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(stderr, "%d of %d finalizers could not be removed\n", failed, objects)
+		return 1
+	}
+	if objects == 0 {
+		fmt.Fprintf(stderr, "no object carries the %s finalizer\n", controller.FinalizerName)
+	}
+	return 0
+}
+
+// reachablePihole returns a client for the configured Pi-holes, or an error when any
+// of them can't be reached
+func reachablePihole(ctx context.Context, cfg *config.Config, reader client.Reader) (pihole.Client, error) {
+	var instances []pihole.Instance
+	for _, inst := range cfg.Instances() {
+		piholeClient, err := instanceClient(ctx, inst, cfg.ClusterID, reader)
+		if err != nil {
+			return nil, fmt.Errorf("pihole %s: %w", instanceName(inst), err)
+		}
+		if !piholeClient.Healthy(ctx) {
+			return nil, fmt.Errorf("pihole %s is not reachable", instanceName(inst))
+		}
+		instances = append(instances, pihole.Instance{Name: inst.Name, Client: piholeClient})
+	}
+	if len(instances) == 1 {
+		return instances[0].Client, nil
+	}
+	return pihole.NewMultiClient(instances...), nil
+}
+
+// releaseReport describes in one line what was done, or with dryRun would be done, to
+// an object
+func releaseReport(release controller.FinalizerRelease, err error, dryRun bool) string {
+	var b strings.Builder
+	b.WriteString(release.Owner)
+	if release.Terminating {
+		b.WriteString(" (terminating)")
+	}
+	b.WriteString(": ")
+	switch {
+	case err != nil:
+		fmt.Fprintf(&b, "failed: %v", err)
+	case release.Gone:
+		b.WriteString("already deleted")
+	case dryRun:
+		b.WriteString("would remove finalizer")
+	default:
+		b.WriteString("finalizer removed")
+	}
+	if len(release.Deleted) > 0 {
+		verb := "deleted"
+		if dryRun {
+			verb = "would delete"
+		}
+		fmt.Fprintf(&b, "; %s %s", verb, strings.Join(release.Deleted, ","))
+	}
+	if len(release.Kept) > 0 {
+		fmt.Fprintf(&b, "; kept %s in pi-hole", strings.Join(release.Kept, ","))
+	}
+	return b.String()
+}
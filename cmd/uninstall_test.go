@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+)
+
+func TestReleaseReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		release controller.FinalizerRelease
+		err     error
+		dryRun  bool
+		want    string
+	}{
+		{
+			name:    "removed",
+			release: controller.FinalizerRelease{Owner: "Ingress default/web", Deleted: []string{"web.local", "web.local/AAAA"}},
+			want:    "Ingress default/web: finalizer removed; deleted web.local,web.local/AAAA",
+		},
+		{
+			name:    "dry run",
+			release: controller.FinalizerRelease{Owner: "Ingress default/web", Deleted: []string{"web.local"}},
+			dryRun:  true,
+			want:    "Ingress default/web: would remove finalizer; would delete web.local",
+		},
+		{
+			name:    "terminating without cleanup",
+			release: controller.FinalizerRelease{Owner: "Ingress default/old", Terminating: true, Kept: []string{"old.local"}},
+			want:    "Ingress default/old (terminating): finalizer removed; kept old.local in pi-hole",
+		},
+		{
+			name:    "gone",
+			release: controller.FinalizerRelease{Owner: "DomainMapping apps/web.example.com", Gone: true},
+			want:    "DomainMapping apps/web.example.com: already deleted",
+		},
+		{
+			name:    "failed",
+			release: controller.FinalizerRelease{Owner: "Ingress default/web"},
+			err:     errors.New("removing finalizer: forbidden"),
+			want:    "Ingress default/web: failed: removing finalizer: forbidden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := releaseReport(tt.release, tt.err, tt.dryRun); got != tt.want {
+				t.Errorf("releaseReport() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUninstallCommandUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"--dry-run"}} {
+		var stderr strings.Builder
+		if code := runUninstallCommand(args, io.Discard, &stderr); code != 2 {
+			t.Errorf("runUninstallCommand(%v) = %d, want 2", args, code)
+		}
+		if !strings.Contains(stderr.String(), "Usage: pihole-ingress-operator uninstall --remove-finalizers") {
+			t.Errorf("runUninstallCommand(%v) printed %q, want the usage", args, stderr.String())
+		}
+	}
+}
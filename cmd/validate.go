@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// Modes of --validate-config
+const (
+	validateOff     = ""
+	validateConfig  = "true"
+	validateConnect = "connect"
+)
+
+// validateFlag is --validate-config: given alone it checks the configuration, and
+// --validate-config=connect also signs in to every Pi-hole
+type validateFlag string
+
+func (f *validateFlag) String() string { return string(*f) }
+
+func (f *validateFlag) Set(value string) error {
+	switch value {
+	case "true", "config":
+		*f = validateConfig
+	case "false":
+		*f = validateOff
+	case validateConnect:
+		*f = validateConnect
+	default:
+		return fmt.Errorf("must be true or connect")
+	}
+	return nil
+}
+
+// IsBoolFlag lets the flag be given without a value
+func (f *validateFlag) IsBoolFlag() bool { return true }
+
+// runValidation loads the configuration from path, the environment and flags, writes a
+// report of every setting with secrets redacted followed by the problems found, and
+// reports whether there were none. With connect, every Pi-hole is signed in to and listed;
+// passwords kept in Secrets cannot be read without the cluster and are skipped.
+func runValidation(ctx context.Context, w io.Writer, path string, flags *config.Flags, connect bool) bool {
+	var problems []string
+	cfg, err := config.Load(path, flags)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		fmt.Fprintln(w, "Settings:")
+		var report strings.Builder
+		enc := yaml.NewEncoder(&report)
+		enc.SetIndent(2)
+		if err := enc.Encode(cfg.Redacted()); err != nil {
+			problems = append(problems, fmt.Sprintf("printing settings: %v", err))
+		}
+		for _, line := range strings.Split(strings.TrimRight(report.String(), "\n"), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+		if connect {
+			problems = append(problems, checkConnections(ctx, w, cfg)...)
+		}
+	}
+
+	if len(problems) == 0 {
+		fmt.Fprintln(w, "Configuration is valid")
+		return true
+	}
+	fmt.Fprintln(w, "Problems:")
+	for _, p := range problems {
+		fmt.Fprintf(w, "  - %s\n", p)
+	}
+	return false
+}
+
+// checkConnections signs in to each Pi-hole and lists its records, returning a problem
+// per instance that fails
+func checkConnections(ctx context.Context, w io.Writer, cfg *config.Config) []string {
+	var problems []string
+	fmt.Fprintln(w, "Connections:")
+	for _, inst := range cfg.Instances() {
+		name := inst.Name
+		if name == "" {
+			name = inst.URL
+		}
+		if inst.SecretRef != "" {
+			fmt.Fprintf(w, "  %s: skipped, password is read from Secret %s\n", name, inst.SecretRef)
+			continue
+		}
+		tlsConfig, err := inst.TLS.ClientConfig()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("pihole %s: %v", name, err))
+			continue
+		}
+		client := pihole.NewClient(inst.URL, inst.Password)
+		if tlsConfig != nil {
+			client.SetTLSConfig(tlsConfig)
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		records, err := client.ListRecords(probeCtx)
+		cancel()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("pihole %s: %v", name, err))
+			fmt.Fprintf(w, "  %s: failed\n", name)
+			continue
+		}
+		fmt.Fprintf(w, "  %s: ok, %d records\n", name, len(records))
+	}
+	return problems
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRunValidation(t *testing.T) {
+	os.Clearenv()
+	t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+	t.Setenv("PIHOLE_PASSWORD", "s3cret")
+	t.Setenv("ADMIN_TOKEN", "t0ken")
+	t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+
+	var out strings.Builder
+	if !runValidation(context.Background(), &out, "", nil, false) {
+		t.Fatalf("runValidation() = false, report:\n%s", out.String())
+	}
+	report := out.String()
+	for _, want := range []string{"defaultTargetIP: 192.168.1.100", "piholePassword: <redacted>", "retryMaxBackoff: 10m0s", "Configuration is valid"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "s3cret") || strings.Contains(report, "t0ken") {
+		t.Errorf("report leaks a secret:\n%s", report)
+	}
+
+	t.Setenv("DEFAULT_TARGET_IP", "not-an-ip")
+	out.Reset()
+	if runValidation(context.Background(), &out, "", nil, false) {
+		t.Fatal("runValidation() = true for an invalid DEFAULT_TARGET_IP")
+	}
+	if !strings.Contains(out.String(), "Problems:\n  - DEFAULT_TARGET_IP is not a valid IPv4 address") {
+		t.Errorf("report = %q, want the validation error listed", out.String())
+	}
+}
+
+func TestRunValidationConnect(t *testing.T) {
+	pihole := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth":
+			_, _ = io.WriteString(w, `{"session":{"sid":"sid","csrf":"csrf","validity":300}}`)
+		case "/api/config/dns/hosts":
+			_, _ = io.WriteString(w, `{"config":{"dns":{"hosts":["192.168.1.100 app.local"]}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer pihole.Close()
+
+	os.Clearenv()
+	t.Setenv("PIHOLE_INSTANCES", `[{"name": "up", "url": "`+pihole.URL+`", "password": "s3cret"},
+		{"name": "down", "url": "http://127.0.0.1:1", "password": "s3cret"},
+		{"name": "secret", "url": "http://127.0.0.1:1", "secretRef": "pihole/secret#password"}]`)
+	t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+
+	var out strings.Builder
+	if runValidation(context.Background(), &out, "", nil, true) {
+		t.Fatalf("runValidation() = true with an unreachable instance, report:\n%s", out.String())
+	}
+	report := out.String()
+	for _, want := range []string{"up: ok, 1 records", "down: failed", "secret: skipped", "  - pihole down:"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestValidateFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want validateFlag
+	}{
+		{nil, validateOff},
+		{[]string{"--validate-config"}, validateConfig},
+		{[]string{"--validate-config=connect"}, validateConnect},
+	}
+	for _, tt := range tests {
+		var v validateFlag
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.Var(&v, "validate-config", "")
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatalf("Parse(%v) unexpected error: %v", tt.args, err)
+		}
+		if v != tt.want {
+			t.Errorf("Parse(%v) = %q, want %q", tt.args, v, tt.want)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var v validateFlag
+	fs.Var(&v, "validate-config", "")
+	if err := fs.Parse([]string{"--validate-config=ping"}); err == nil {
+		t.Error("Parse() accepted an unknown mode")
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+)
+
+// verifyQueryTimeout bounds each DNS query of records verify
+var verifyQueryTimeout = 5 * time.Second
+
+// DefaultVerifyConcurrency is how many DNS queries records verify runs at once
+const DefaultVerifyConcurrency = 16
+
+// The results of a verified record
+const (
+	verifyPass     = "pass"
+	verifyMismatch = "mismatch"
+	verifyNXDomain = "nxdomain"
+	verifyError    = "error"
+)
+
+// hostResolver looks up the addresses of a host; *net.Resolver implements it
+type hostResolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// verifyResult is the answer to the query for one managed record
+type verifyResult struct {
+	Domain   string   `json:"domain"`
+	Type     string   `json:"type"`
+	Expected string   `json:"expected"`
+	Answers  []string `json:"answers"`
+	Owner    string   `json:"owner"`
+	Result   string   `json:"result"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// verifyReport is the JSON output of records verify
+type verifyReport struct {
+	Server  string         `json:"server"`
+	Passed  int            `json:"passed"`
+	Failed  int            `json:"failed"`
+	Records []verifyResult `json:"records"`
+}
+
+// dnsServer returns the resolver records verify queries: server with port 53 added when
+// it has none, or without one the host of the first Pi-hole on port 53
+func dnsServer(server string, instances []config.PiholeInstance) (string, error) {
+	if server == "" {
+		if len(instances) == 0 {
+			return "", errors.New("no Pi-hole is configured; pass --dns")
+		}
+		u, err := url.Parse(instances[0].URL)
+		if err != nil || u.Hostname() == "" {
+			return "", fmt.Errorf("cannot take the DNS server from the Pi-hole URL %q; pass --dns", instances[0].URL)
+		}
+		server = u.Hostname()
+	}
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server, nil
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), "53"), nil
+}
+
+// newDNSResolver returns a resolver that sends every query to server
+func newDNSResolver(server string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// verifyRecords queries resolver for every record, at most concurrency at a time, and
+// returns the results in the order of records. A record passes when the answers include
+// its target; one without a target passes when the domain resolves at all. Records
+// sharing a domain and type are queried once.
+func verifyRecords(ctx context.Context, resolver hostResolver, records []controller.OwnedRecord, concurrency int) []verifyResult {
+	type answer struct {
+		addrs []netip.Addr
+		err   error
+	}
+	answers := map[string]*answer{}
+	for _, rec := range records {
+		answers[rec.Type+" "+rec.Domain] = &answer{}
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(concurrency, 1))
+	for key, a := range answers {
+		recordType, domain, _ := strings.Cut(key, " ")
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			queryCtx, cancel := context.WithTimeout(ctx, verifyQueryTimeout)
+			defer cancel()
+			a.addrs, a.err = resolver.LookupNetIP(queryCtx, network, domain)
+		}()
+	}
+	wg.Wait()
+
+	results := make([]verifyResult, 0, len(records))
+	for _, rec := range records {
+		a := answers[rec.Type+" "+rec.Domain]
+		result := verifyResult{Domain: rec.Domain, Type: rec.Type, Expected: rec.TargetIP, Owner: rec.Owner,
+			Answers: []string{}}
+		for _, addr := range a.addrs {
+			result.Answers = append(result.Answers, addr.Unmap().String())
+		}
+
+		var dnsErr *net.DNSError
+		switch {
+		case errors.As(a.err, &dnsErr) && dnsErr.IsNotFound:
+			result.Result = verifyNXDomain
+		case a.err != nil:
+			result.Result, result.Error = verifyError, a.err.Error()
+		case rec.TargetIP == "" && len(result.Answers) > 0:
+			result.Result = verifyPass
+		case slices.Contains(result.Answers, canonicalIP(rec.TargetIP)):
+			result.Result = verifyPass
+		default:
+			result.Result = verifyMismatch
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// canonicalIP returns ip in the form the resolver answers in, so that differently
+// written IPv6 targets compare equal
+func canonicalIP(ip string) string {
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		return addr.Unmap().String()
+	}
+	return ip
+}
+
+// runVerify resolves every managed record against the DNS server and prints the
+// results; it exits 1 when any record fails
+func runVerify(cfg *config.Config, restConfig *rest.Config, server string, concurrency int, output string,
+	stdout, stderr io.Writer) int {
+	server, err := dnsServer(server, cfg.Instances())
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordsTimeout)
+	defer cancel()
+	_, sources, err := recordSources(cfg, restConfig)
+	if err != nil {
+		fmt.Fprintf(stderr, "listing records: %v\n", err)
+		return 1
+	}
+	var owned []controller.OwnedRecord
+	for _, src := range sources {
+		records, err := src.OwnedRecords(ctx)
+		if err != nil {
+			fmt.Fprintf(stderr, "listing records: %v\n", err)
+			return 1
+		}
+		owned = append(owned, records...)
+	}
+
+	report := verifyReport{Server: server, Records: verifyRecords(ctx, newDNSResolver(server), owned, concurrency)}
+	for _, r := range report.Records {
+		if r.Result == verifyPass {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+	}
+	if err := writeVerify(stdout, report, output); err != nil {
+		fmt.Fprintf(stderr, "writing results: %v\n", err)
+		return 1
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// writeVerify prints report as a table with a summary line, or as JSON
+func writeVerify(w io.Writer, report verifyReport, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOMAIN\tTYPE\tEXPECTED\tANSWER\tOWNER\tRESULT")
+	for _, r := range report.Records {
+		answer := strings.Join(r.Answers, ",")
+		if r.Error != "" {
+			answer = r.Error
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Domain, r.Type, orDash(r.Expected), orDash(answer), r.Owner,
+			r.Result)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d passed, %d failed against %s\n", report.Passed, report.Failed, report.Server)
+	return err
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+)
+
+// fakeResolver answers from a map of "network host" to addresses, counting queries
+type fakeResolver struct {
+	mu      sync.Mutex
+	answers map[string][]string
+	errs    map[string]error
+	queries map[string]int
+}
+
+func (f *fakeResolver) LookupNetIP(_ context.Context, network, host string) ([]netip.Addr, error) {
+	key := network + " " + host
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.queries == nil {
+		f.queries = map[string]int{}
+	}
+	f.queries[key]++
+	if err := f.errs[key]; err != nil {
+		return nil, err
+	}
+	answers, ok := f.answers[key]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	var addrs []netip.Addr
+	for _, a := range answers {
+		addrs = append(addrs, netip.MustParseAddr(a))
+	}
+	return addrs, nil
+}
+
+func TestVerifyRecords(t *testing.T) {
+	resolver := &fakeResolver{
+		answers: map[string][]string{
+			"ip4 app.local":   {"192.168.1.100"},
+			"ip6 app.local":   {"fd00::1"},
+			"ip4 moved.local": {"192.168.1.99"},
+			"ip4 any.local":   {"192.168.1.7"},
+		},
+		errs: map[string]error{"ip4 down.local": errors.New("i/o timeout")},
+	}
+	records := []controller.OwnedRecord{
+		{Domain: "app.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/app"},
+		{Domain: "app.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/app-2"},
+		{Domain: "app.local", Type: "AAAA", TargetIP: "fd00:0::1", Owner: "Ingress default/app"},
+		{Domain: "moved.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/moved"},
+		{Domain: "gone.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/gone"},
+		{Domain: "down.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/down"},
+		{Domain: "any.local", Type: "A", Owner: "Ingress default/any"},
+	}
+
+	results := verifyRecords(context.Background(), resolver, records, 2)
+	want := []string{verifyPass, verifyPass, verifyPass, verifyMismatch, verifyNXDomain, verifyError, verifyPass}
+	if len(results) != len(want) {
+		t.Fatalf("verifyRecords() returned %d results, want %d", len(results), len(want))
+	}
+	for i, r := range results {
+		if r.Domain != records[i].Domain || r.Owner != records[i].Owner || r.Result != want[i] {
+			t.Errorf("result %d = %+v, want %s %s", i, r, records[i].Domain, want[i])
+		}
+	}
+	if got := results[3].Answers; len(got) != 1 || got[0] != "192.168.1.99" {
+		t.Errorf("moved.local answers = %v, want 192.168.1.99", got)
+	}
+	if results[5].Error != "i/o timeout" {
+		t.Errorf("down.local error = %q, want i/o timeout", results[5].Error)
+	}
+	if n := resolver.queries["ip4 app.local"]; n != 1 {
+		t.Errorf("app.local A queried %d times, want once for both owners", n)
+	}
+}
+
+func TestDNSServer(t *testing.T) {
+	instances := []config.PiholeInstance{{URL: "http://pihole.home:8080"}, {URL: "http://10.0.0.2"}}
+	tests := []struct {
+		server    string
+		instances []config.PiholeInstance
+		want      string
+		wantErr   bool
+	}{
+		{instances: instances, want: "pihole.home:53"},
+		{server: "192.168.1.2", instances: instances, want: "192.168.1.2:53"},
+		{server: "192.168.1.2:5353", want: "192.168.1.2:5353"},
+		{server: "fd00::2", want: "[fd00::2]:53"},
+		{server: "[fd00::2]", want: "[fd00::2]:53"},
+		{wantErr: true},
+		{instances: []config.PiholeInstance{{URL: "not a url"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := dnsServer(tt.server, tt.instances)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("dnsServer(%q) = %q, %v; want %q, error %v", tt.server, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWriteVerify(t *testing.T) {
+	report := verifyReport{
+		Server: "192.168.1.2:53",
+		Passed: 1,
+		Failed: 1,
+		Records: []verifyResult{
+			{Domain: "app.local", Type: "A", Expected: "192.168.1.100", Answers: []string{"192.168.1.100"},
+				Owner: "Ingress default/app", Result: verifyPass},
+			{Domain: "gone.local", Type: "A", Expected: "192.168.1.100", Answers: []string{},
+				Owner: "Ingress default/gone", Result: verifyNXDomain},
+		},
+	}
+
+	var table strings.Builder
+	if err := writeVerify(&table, report, "table"); err != nil {
+		t.Fatalf("writeVerify(table) unexpected error: %v", err)
+	}
+	want := "DOMAIN      TYPE  EXPECTED       ANSWER         OWNER                 RESULT\n" +
+		"app.local   A     192.168.1.100  192.168.1.100  Ingress default/app   pass\n" +
+		"gone.local  A     192.168.1.100  -              Ingress default/gone  nxdomain\n" +
+		"1 passed, 1 failed against 192.168.1.2:53\n"
+	if table.String() != want {
+		t.Errorf("table =\n%s\nwant\n%s", table.String(), want)
+	}
+
+	var out strings.Builder
+	if err := writeVerify(&out, report, "json"); err != nil {
+		t.Fatalf("writeVerify(json) unexpected error: %v", err)
+	}
+	var decoded verifyReport
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatalf("json output does not parse: %v\n%s", err, out.String())
+	}
+	if decoded.Failed != 1 || len(decoded.Records) != 2 || decoded.Records[1].Result != verifyNXDomain {
+		t.Errorf("json report = %+v, want gone.local failing", decoded)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command fakepihole serves the fake Pi-hole of internal/pihole/fakeserver, to run the
+// operator against locally:
+//
+//	go run ./hack/fakepihole --addr :8080 --password secret --hosts-file hosts.txt
+//	PIHOLE_URL=http://localhost:8080 PIHOLE_PASSWORD=secret make run
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole/fakeserver"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "The address to serve the fake Pi-hole API on.")
+	password := flag.String("password", "secret", "The password the fake Pi-hole accepts.")
+	validity := flag.Duration("validity", fakeserver.DefaultValidity, "How long a session lasts without use.")
+	hostsFile := flag.String("hosts-file", "", "Seed the local DNS records from a hosts file.")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	fake := fakeserver.New(*password)
+	fake.SetValidity(*validity)
+	fake.Logger = logger
+
+	if *hostsFile != "" {
+		hosts, err := readHosts(*hostsFile)
+		if err != nil {
+			logger.Error("reading hosts file", "error", err)
+			os.Exit(1)
+		}
+		fake.SetHosts(hosts...)
+	}
+
+	logger.Info("serving fake pi-hole", "addr", *addr, "records", len(fake.Hosts()))
+	srv := &http.Server{Addr: *addr, Handler: fake, ReadHeaderTimeout: 10 * time.Second}
+	if err := srv.ListenAndServe(); err != nil {
+		logger.Error("serving fake pi-hole", "error", err)
+		os.Exit(1)
+	}
+}
+
+// readHosts returns the "IP DOMAIN" entries of a hosts file, one per name, skipping
+// comments and blank lines
+func readHosts(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var hosts []string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected an address and a name", path, line)
+		}
+		for _, name := range fields[1:] {
+			hosts = append(hosts, fields[0]+" "+name)
+		}
+	}
+	return hosts, scanner.Err()
+}
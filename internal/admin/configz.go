@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+)
+
+// ConfigResponse is the JSON body returned by GET /configz
+type ConfigResponse struct {
+	// Config holds every setting keyed like the config file, secrets redacted
+	Config map[string]any `json:"config"`
+
+	Derived ConfigDerived `json:"derived"`
+}
+
+// ConfigDerived holds values worked out from the configuration
+type ConfigDerived struct {
+	// Controllers lists the kinds with a running controller
+	Controllers []string `json:"controllers"`
+
+	// WatchNamespaces is empty when every namespace is watched
+	WatchNamespaces   []string `json:"watchNamespaces"`
+	ExcludeNamespaces []string `json:"excludeNamespaces"`
+	AnnotationPrefix  string   `json:"annotationPrefix"`
+}
+
+// buildConfigResponse reports cfg with the controllers that run
+func buildConfigResponse(cfg *config.Config, controllers []string) (ConfigResponse, error) {
+	values, err := cfg.RedactedValues()
+	if err != nil {
+		return ConfigResponse{}, err
+	}
+	derived := ConfigDerived{
+		Controllers:       append([]string{}, controllers...),
+		WatchNamespaces:   []string{},
+		ExcludeNamespaces: append([]string{}, cfg.ExcludeNamespaces...),
+		AnnotationPrefix:  controller.AnnotationPrefix,
+	}
+	sort.Strings(derived.Controllers)
+	if cfg.WatchNamespace != "" {
+		derived.WatchNamespaces = append(derived.WatchNamespaces, cfg.WatchNamespace)
+	}
+	return ConfigResponse{Config: values, Derived: derived}, nil
+}
+
+// handleConfigz reports the configuration in effect, after defaults, the environment
+// and any reload
+func (s *Server) handleConfigz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	controllers := make([]string, 0, len(s.Resyncers))
+	for kind := range s.Resyncers {
+		controllers = append(controllers, kind)
+	}
+	resp, err := buildConfigResponse(s.Config(), controllers)
+	if err != nil {
+		s.Logger.Error("failed to report configuration", "error", err)
+		http.Error(w, "reporting configuration: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
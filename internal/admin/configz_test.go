@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+)
+
+func TestHandleConfigz(t *testing.T) {
+	cfg := &config.Config{
+		PiholeURL:         "http://192.168.1.2",
+		PiholePassword:    "s3cret",
+		AdminToken:        "t0ken",
+		DefaultTargetIP:   "192.168.1.100",
+		WatchNamespace:    "apps",
+		ExcludeNamespaces: []string{"kube-*"},
+	}
+	s := &Server{
+		Token:       "t0ken",
+		Logger:      slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		Resyncers:   map[string]Resyncer{"Ingress": &fakeResyncer{}, "DomainMapping": &fakeResyncer{}},
+		EnableDebug: true,
+		Config:      func() *config.Config { return cfg },
+	}
+	req := httptest.NewRequest(http.MethodGet, "/configz", nil)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "s3cret") || strings.Contains(body, "t0ken") {
+		t.Errorf("body leaks a secret: %s", body)
+	}
+	var resp ConfigResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if resp.Config["defaultTargetIP"] != "192.168.1.100" || resp.Config["piholePassword"] != config.Redacted {
+		t.Errorf("config = %v, want settings with the password redacted", resp.Config)
+	}
+	want := ConfigDerived{
+		Controllers:       []string{"DomainMapping", "Ingress"},
+		WatchNamespaces:   []string{"apps"},
+		ExcludeNamespaces: []string{"kube-*"},
+		AnnotationPrefix:  "pihole.io/",
+	}
+	if !slices.Equal(resp.Derived.Controllers, want.Controllers) || !slices.Equal(resp.Derived.WatchNamespaces, want.WatchNamespaces) ||
+		!slices.Equal(resp.Derived.ExcludeNamespaces, want.ExcludeNamespaces) || resp.Derived.AnnotationPrefix != want.AnnotationPrefix {
+		t.Errorf("derived = %+v, want %+v", resp.Derived, want)
+	}
+}
+
+func TestConfigzOptIn(t *testing.T) {
+	s := &Server{
+		Token:  "t0ken",
+		Logger: slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		Config: func() *config.Config { return &config.Config{} },
+	}
+	req := httptest.NewRequest(http.MethodGet, "/configz", nil)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status without EnableDebug = %d, want 404", rec.Code)
+	}
+
+	s.EnableDebug = true
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/configz", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status without a token = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// RecordSnapshot exposes the last known Pi-hole records, keyed by pihole.RecordKey,
+// without calling Pi-hole
+type RecordSnapshot interface {
+	Snapshot() (map[string]string, time.Time)
+}
+
+// DesiredState reports records that several objects want with different IPs
+type DesiredState interface {
+	Conflicts() []controller.DesiredRecord
+}
+
+// DebugRecord compares a managed record with what Pi-hole last reported
+type DebugRecord struct {
+	controller.OwnedRecord
+
+	// InPihole is nil when Pi-hole has not been listed yet
+	InPihole *bool  `json:"inPihole"`
+	PiholeIP string `json:"piholeIP,omitempty"`
+}
+
+// DebugResponse is the JSON body returned by GET /debug/records
+type DebugResponse struct {
+	Records        []DebugRecord `json:"records"`
+	PiholeListedAt time.Time     `json:"piholeListedAt,omitzero"`
+
+	// Conflicts lists records whose owners disagree on the IP
+	Conflicts []controller.DesiredRecord `json:"conflicts,omitempty"`
+}
+
+// BuildDebugResponse joins the owned records with the Pi-hole snapshot, which is
+// keyed by pihole.RecordKey; it is also what the records list command prints
+func BuildDebugResponse(owned []controller.OwnedRecord, snapshot map[string]string, listedAt time.Time, conflicts []controller.DesiredRecord) DebugResponse {
+	resp := DebugResponse{Records: make([]DebugRecord, 0, len(owned)), PiholeListedAt: listedAt, Conflicts: conflicts}
+	for _, rec := range owned {
+		entry := DebugRecord{OwnedRecord: rec}
+		if snapshot != nil {
+			ip, ok := snapshot[pihole.RecordKey(rec.Domain, rec.Type)]
+			entry.InPihole = &ok
+			entry.PiholeIP = ip
+		}
+		resp.Records = append(resp.Records, entry)
+	}
+	sort.SliceStable(resp.Records, func(i, j int) bool { return resp.Records[i].Domain < resp.Records[j].Domain })
+	return resp
+}
+
+// handleDebugRecords reports desired versus actual DNS state
+func (s *Server) handleDebugRecords(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var owned []controller.OwnedRecord
+	for _, src := range s.Owners {
+		records, err := src.OwnedRecords(r.Context())
+		if err != nil {
+			s.Logger.Error("failed to list owned records", "error", err)
+			http.Error(w, "listing owned records: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		owned = append(owned, records...)
+	}
+
+	var snapshot map[string]string
+	var listedAt time.Time
+	if s.Records != nil {
+		snapshot, listedAt = s.Records.Snapshot()
+	}
+
+	var conflicts []controller.DesiredRecord
+	if s.Desired != nil {
+		conflicts = s.Desired.Conflicts()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(BuildDebugResponse(owned, snapshot, listedAt, conflicts))
+}
@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+)
+
+func TestBuildDebugResponseJSON(t *testing.T) {
+	synced := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	listed := time.Date(2024, 1, 1, 12, 5, 0, 0, time.UTC)
+	owned := []controller.OwnedRecord{
+		{Domain: "web.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/web", LastSync: synced},
+		{Domain: "api.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/web"},
+		{Domain: "web.local", Type: "AAAA", TargetIP: "fd00::10", Owner: "Ingress default/web"},
+	}
+
+	conflict := controller.DesiredRecord{Domain: "shared.local", Type: "A", Targets: map[string]string{
+		"Ingress default/a": "192.168.1.100",
+		"Ingress default/b": "192.168.1.200",
+	}}
+
+	tests := []struct {
+		name      string
+		pihole    map[string]string
+		listed    time.Time
+		conflicts []controller.DesiredRecord
+		want      string
+	}{
+		{
+			name:   "with pihole listing",
+			pihole: map[string]string{"web.local": "192.168.1.100", "web.local/AAAA": "fd00::20"},
+			listed: listed,
+			want: `{"records":[` +
+				`{"domain":"api.local","type":"A","targetIP":"192.168.1.100","owner":"Ingress default/web","inPihole":false},` +
+				`{"domain":"web.local","type":"A","targetIP":"192.168.1.100","owner":"Ingress default/web","lastSync":"2024-01-01T12:00:00Z","inPihole":true,"piholeIP":"192.168.1.100"},` +
+				`{"domain":"web.local","type":"AAAA","targetIP":"fd00::10","owner":"Ingress default/web","inPihole":true,"piholeIP":"fd00::20"}` +
+				`],"piholeListedAt":"2024-01-01T12:05:00Z"}`,
+		},
+		{
+			name: "before first listing",
+			want: `{"records":[` +
+				`{"domain":"api.local","type":"A","targetIP":"192.168.1.100","owner":"Ingress default/web","inPihole":null},` +
+				`{"domain":"web.local","type":"A","targetIP":"192.168.1.100","owner":"Ingress default/web","lastSync":"2024-01-01T12:00:00Z","inPihole":null},` +
+				`{"domain":"web.local","type":"AAAA","targetIP":"fd00::10","owner":"Ingress default/web","inPihole":null}` +
+				`]}`,
+		},
+		{
+			name:      "with conflicts",
+			conflicts: []controller.DesiredRecord{conflict},
+			want: `{"records":[` +
+				`{"domain":"api.local","type":"A","targetIP":"192.168.1.100","owner":"Ingress default/web","inPihole":null},` +
+				`{"domain":"web.local","type":"A","targetIP":"192.168.1.100","owner":"Ingress default/web","lastSync":"2024-01-01T12:00:00Z","inPihole":null},` +
+				`{"domain":"web.local","type":"AAAA","targetIP":"fd00::10","owner":"Ingress default/web","inPihole":null}` +
+				`],"conflicts":[{"domain":"shared.local","type":"A","targets":{"Ingress default/a":"192.168.1.100","Ingress default/b":"192.168.1.200"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(BuildDebugResponse(owned, tt.pihole, tt.listed, tt.conflicts))
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("JSON =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugRecordsOptIn(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		s := &Server{
+			Token:       "secret",
+			Logger:      slog.New(slog.NewTextHandler(os.Stdout, nil)),
+			EnableDebug: enabled,
+		}
+		req := httptest.NewRequest(http.MethodGet, "/debug/records", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+
+		want := http.StatusNotFound
+		if enabled {
+			want = http.StatusOK
+		}
+		if rec.Code != want {
+			t.Errorf("EnableDebug=%v: status = %d, want %d", enabled, rec.Code, want)
+		}
+	}
+}
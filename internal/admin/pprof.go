@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+)
+
+// PprofServer serves the net/http/pprof profiles on a listener of its own. It has no
+// authentication, so its address should only be reachable through a port-forward.
+type PprofServer struct {
+	Addr   string
+	Logger *slog.Logger
+}
+
+// Handler returns the profiling handler, serving under /debug/pprof/
+func (s *PprofServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Start serves until ctx is cancelled; it implements manager.Runnable
+func (s *PprofServer) Start(ctx context.Context) error {
+	s.Logger.Warn("pprof endpoint is unauthenticated, do not expose it outside the pod", "address", s.Addr)
+	return serve(ctx, s.Addr, s.Handler(), s.Logger, "pprof server listening")
+}
+
+// NeedLeaderElection profiles every replica
+func (s *PprofServer) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,156 @@
+// Package admin serves operator maintenance endpoints
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/config"
+)
+
+// shutdownTimeout bounds how long in-flight requests may run after the manager stops
+const shutdownTimeout = 5 * time.Second
+
+// Resyncer re-enqueues the objects managed by one controller
+type Resyncer interface {
+	Resync(ctx context.Context, namespace string) (int, error)
+}
+
+// ResyncResponse is the JSON body returned by POST /resync
+type ResyncResponse struct {
+	Enqueued map[string]int `json:"enqueued"`
+	Total    int            `json:"total"`
+}
+
+// Server exposes the admin endpoints. Every request must carry the bearer token.
+type Server struct {
+	Addr   string
+	Token  string
+	Logger *slog.Logger
+
+	// Resyncers maps an object kind (e.g. "Ingress") to its controller
+	Resyncers map[string]Resyncer
+
+	// EnableDebug serves GET /debug/records from Owners, Records and Desired, and
+	// GET /configz from Config, which returns the configuration in effect
+	EnableDebug bool
+	Owners      []OwnershipSource
+	Records     RecordSnapshot
+	Desired     DesiredState
+	Config      func() *config.Config
+}
+
+// Handler returns the admin HTTP handler
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/resync", s.handleResync)
+	if s.EnableDebug {
+		mux.HandleFunc("/debug/records", s.handleDebugRecords)
+		if s.Config != nil {
+			mux.HandleFunc("/configz", s.handleConfigz)
+		}
+	}
+	return s.authenticate(mux)
+}
+
+// Start serves until ctx is cancelled; it implements manager.Runnable
+func (s *Server) Start(ctx context.Context) error {
+	return serve(ctx, s.Addr, s.Handler(), s.Logger, "admin server listening")
+}
+
+// serve runs an HTTP server on addr until ctx is cancelled, then shuts it down
+func serve(ctx context.Context, addr string, handler http.Handler, logger *slog.Logger, msg string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info(msg, "address", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// NeedLeaderElection keeps the endpoints available on every replica
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// authenticate rejects requests without the configured bearer token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleResync enqueues managed objects, optionally filtered by ?kind= and ?namespace=
+func (s *Server) handleResync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	kinds := make([]string, 0, len(s.Resyncers))
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		matched := ""
+		for k := range s.Resyncers {
+			if strings.EqualFold(k, kind) {
+				matched = k
+			}
+		}
+		if matched == "" {
+			http.Error(w, "unknown kind "+kind, http.StatusBadRequest)
+			return
+		}
+		kinds = append(kinds, matched)
+	} else {
+		for k := range s.Resyncers {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	resp := ResyncResponse{Enqueued: make(map[string]int, len(kinds))}
+	for _, kind := range kinds {
+		n, err := s.Resyncers[kind].Resync(r.Context(), namespace)
+		if err != nil {
+			s.Logger.Error("resync failed", "kind", kind, "namespace", namespace, "error", err)
+			http.Error(w, "resync failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Enqueued[kind] = n
+		resp.Total += n
+	}
+	s.Logger.Info("resync triggered", "namespace", namespace, "enqueued", resp.Total)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
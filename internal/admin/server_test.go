@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// fakeResyncer returns a fixed count and records the namespace it was asked for
+type fakeResyncer struct {
+	count     int
+	err       error
+	namespace string
+	calls     int
+}
+
+func (f *fakeResyncer) Resync(_ context.Context, namespace string) (int, error) {
+	f.calls++
+	f.namespace = namespace
+	return f.count, f.err
+}
+
+func TestHandleResync(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		token      string
+		err        error
+		wantStatus int
+		wantTotal  int
+		wantNS     string
+	}{
+		{name: "all kinds", method: http.MethodPost, target: "/resync", token: "secret", wantStatus: http.StatusOK, wantTotal: 3},
+		{name: "kind and namespace filter", method: http.MethodPost, target: "/resync?kind=ingress&namespace=apps", token: "secret", wantStatus: http.StatusOK, wantTotal: 3, wantNS: "apps"},
+		{name: "unknown kind", method: http.MethodPost, target: "/resync?kind=HTTPRoute", token: "secret", wantStatus: http.StatusBadRequest},
+		{name: "missing token", method: http.MethodPost, target: "/resync", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodPost, target: "/resync", token: "guess", wantStatus: http.StatusUnauthorized},
+		{name: "wrong method", method: http.MethodGet, target: "/resync", token: "secret", wantStatus: http.StatusMethodNotAllowed},
+		{name: "resync error", method: http.MethodPost, target: "/resync", token: "secret", err: errors.New("cache not synced"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resyncer := &fakeResyncer{count: 3, err: tt.err}
+			s := &Server{
+				Token:     "secret",
+				Logger:    slog.New(slog.NewTextHandler(os.Stdout, nil)),
+				Resyncers: map[string]Resyncer{"Ingress": resyncer},
+			}
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp ResyncResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.Total != tt.wantTotal || resp.Enqueued["Ingress"] != tt.wantTotal {
+				t.Errorf("response = %+v, want total %d", resp, tt.wantTotal)
+			}
+			if resyncer.namespace != tt.wantNS {
+				t.Errorf("namespace = %q, want %q", resyncer.namespace, tt.wantNS)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+)
+
+// OwnershipSource reports the records a controller manages
+type OwnershipSource interface {
+	OwnedRecords(ctx context.Context) ([]controller.OwnedRecord, error)
+}
+
+// SignalHandler resyncs every controller on SIGUSR1 and logs the ownership table
+// on SIGUSR2
+type SignalHandler struct {
+	resyncers map[string]Resyncer
+	owners    []OwnershipSource
+	logger    *slog.Logger
+	signals   chan os.Signal
+}
+
+// NewSignalHandler subscribes to SIGUSR1 and SIGUSR2 immediately, so signals sent
+// before the manager starts are queued instead of killing the process. Signals
+// arriving while one is pending are coalesced.
+func NewSignalHandler(resyncers map[string]Resyncer, owners []OwnershipSource, logger *slog.Logger) *SignalHandler {
+	h := &SignalHandler{
+		resyncers: resyncers,
+		owners:    owners,
+		logger:    logger,
+		signals:   make(chan os.Signal, 2),
+	}
+	signal.Notify(h.signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	return h
+}
+
+// Start handles signals until ctx is cancelled; it implements manager.Runnable
+func (h *SignalHandler) Start(ctx context.Context) error {
+	defer signal.Stop(h.signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig := <-h.signals:
+			switch sig {
+			case syscall.SIGUSR1:
+				h.resync(ctx)
+			case syscall.SIGUSR2:
+				h.dumpOwnership(ctx)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection runs the handler only where the controllers run
+func (h *SignalHandler) NeedLeaderElection() bool {
+	return true
+}
+
+// resync enqueues every managed object of every controller
+func (h *SignalHandler) resync(ctx context.Context) {
+	total := 0
+	for kind, r := range h.resyncers {
+		n, err := r.Resync(ctx, "")
+		if err != nil {
+			h.logger.Error("resync failed", "kind", kind, "error", err)
+			continue
+		}
+		total += n
+	}
+	h.logger.Info("resync triggered", "signal", "SIGUSR1", "enqueued", total)
+}
+
+// dumpOwnership logs one line per managed record
+func (h *SignalHandler) dumpOwnership(ctx context.Context) {
+	count := 0
+	for _, src := range h.owners {
+		records, err := src.OwnedRecords(ctx)
+		if err != nil {
+			h.logger.Error("failed to list owned records", "error", err)
+			continue
+		}
+		for _, rec := range records {
+			h.logger.Info("owned record", "host", rec.Domain, "type", rec.Type, "ip", rec.TargetIP, "owner", rec.Owner)
+		}
+		count += len(records)
+	}
+	h.logger.Info("ownership dump complete", "signal", "SIGUSR2", "records", count)
+}
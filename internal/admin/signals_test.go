@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/controller"
+)
+
+// notifyingResyncer reports each call on a channel
+type notifyingResyncer struct{ called chan struct{} }
+
+func (n *notifyingResyncer) Resync(context.Context, string) (int, error) {
+	n.called <- struct{}{}
+	return 1, nil
+}
+
+// notifyingOwners reports each call on a channel
+type notifyingOwners struct{ called chan struct{} }
+
+func (n *notifyingOwners) OwnedRecords(context.Context) ([]controller.OwnedRecord, error) {
+	n.called <- struct{}{}
+	return []controller.OwnedRecord{{Domain: "app.local", TargetIP: "192.168.1.100", Owner: "Ingress default/app"}}, nil
+}
+
+func TestSignalHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	resyncer := &notifyingResyncer{called: make(chan struct{}, 1)}
+	owners := &notifyingOwners{called: make(chan struct{}, 1)}
+
+	// Signals sent before Start are queued rather than terminating the process
+	h := NewSignalHandler(map[string]Resyncer{"Ingress": resyncer}, []OwnershipSource{owners}, logger)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("sending SIGUSR1: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- h.Start(ctx) }()
+
+	select {
+	case <-resyncer.called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGUSR1 did not trigger a resync")
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatalf("sending SIGUSR2: %v", err)
+	}
+	select {
+	case <-owners.called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGUSR2 did not dump ownership")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start() unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start() did not return after cancel")
+	}
+}
@@ -0,0 +1,46 @@
+// Package audit records every DNS mutation made by the operator
+package audit
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Actions recorded in the audit trail
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Entry is a single DNS mutation
+type Entry struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	Domain      string    `json:"domain"`
+	Type        string    `json:"type,omitempty"`
+	OldIP       string    `json:"oldIP,omitempty"`
+	NewIP       string    `json:"newIP,omitempty"`
+	Owner       string    `json:"owner,omitempty"`
+	ReconcileID string    `json:"reconcileID,omitempty"`
+}
+
+// Sink persists audit entries
+type Sink interface {
+	Record(ctx context.Context, entry Entry) error
+}
+
+// Multi fans entries out to several sinks, attempting all of them
+type Multi []Sink
+
+// Record writes entry to every sink and joins their errors
+func (m Multi) Record(ctx context.Context, entry Entry) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Record(ctx, entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
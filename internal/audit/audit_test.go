@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFileSinkAppends(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink := NewFileSink(path)
+
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: at, Action: ActionCreate, Domain: "nas.local", NewIP: "192.168.1.20", Owner: "Ingress default/nas"},
+		{Time: at, Action: ActionUpdate, Domain: "nas.local", OldIP: "192.168.1.20", NewIP: "192.168.1.21", ReconcileID: "abc"},
+	}
+	for _, e := range entries {
+		if err := sink.Record(ctx, e); err != nil {
+			t.Fatalf("Record() unexpected error: %v", err)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != len(entries) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(entries))
+	}
+	var got Entry
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatalf("decoding line: %v", err)
+	}
+	if got != entries[1] {
+		t.Errorf("entry = %+v, want %+v", got, entries[1])
+	}
+}
+
+func TestConfigMapSinkRingBuffer(t *testing.T) {
+	ctx := context.Background()
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	sink := NewConfigMapSink(k8s, k8s, "pihole-operator", "audit", 3)
+
+	for _, domain := range []string{"a.local", "b.local", "c.local", "d.local", "e.local"} {
+		if err := sink.Record(ctx, Entry{Action: ActionDelete, Domain: domain}); err != nil {
+			t.Fatalf("Record() unexpected error: %v", err)
+		}
+	}
+
+	var cm corev1.ConfigMap
+	if err := k8s.Get(ctx, types.NamespacedName{Namespace: "pihole-operator", Name: "audit"}, &cm); err != nil {
+		t.Fatalf("audit configmap not created: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(cm.Data[entriesKey]), "\n")
+	var domains []string
+	for _, line := range lines {
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("decoding line %q: %v", line, err)
+		}
+		domains = append(domains, e.Domain)
+	}
+	if strings.Join(domains, ",") != "c.local,d.local,e.local" {
+		t.Errorf("retained entries = %v, want the three most recent", domains)
+	}
+}
+
+func TestReadConfigMap(t *testing.T) {
+	ctx := context.Background()
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+	entries, err := ReadConfigMap(ctx, k8s, "pihole-operator", "audit")
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("ReadConfigMap() of a missing configmap = %v, %v, want no entries", entries, err)
+	}
+
+	sink := NewConfigMapSink(k8s, k8s, "pihole-operator", "audit", 0)
+	want := []Entry{
+		{Action: ActionCreate, Domain: "nas.local", NewIP: "192.168.1.20", Owner: "Ingress default/nas"},
+		{Action: ActionDelete, Domain: "nas.local", OldIP: "192.168.1.20", Owner: "Ingress default/nas"},
+	}
+	for _, e := range want {
+		if err := sink.Record(ctx, e); err != nil {
+			t.Fatalf("Record() unexpected error: %v", err)
+		}
+	}
+	entries, err = ReadConfigMap(ctx, k8s, "pihole-operator", "audit")
+	if err != nil {
+		t.Fatalf("ReadConfigMap() unexpected error: %v", err)
+	}
+	if len(entries) != len(want) || entries[0] != want[0] || entries[1] != want[1] {
+		t.Errorf("ReadConfigMap() = %+v, want %+v", entries, want)
+	}
+}
+
+type failingSink struct{ calls int }
+
+func (f *failingSink) Record(context.Context, Entry) error {
+	f.calls++
+	return errors.New("unavailable")
+}
+
+func TestMultiRecordsToAllSinks(t *testing.T) {
+	first, second := &failingSink{}, &failingSink{}
+	err := Multi{first, second}.Record(context.Background(), Entry{Action: ActionCreate, Domain: "a.local"})
+	if err == nil {
+		t.Fatal("Record() expected error, got nil")
+	}
+	if first.calls != 1 || second.calls != 1 {
+		t.Errorf("calls = %d/%d, want 1/1", first.calls, second.calls)
+	}
+}
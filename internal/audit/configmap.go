@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// entriesKey is the ConfigMap data key holding the JSON lines
+	entriesKey = "audit.jsonl"
+
+	// DefaultMaxEntries is the ring buffer size used when none is given
+	DefaultMaxEntries = 500
+
+	// maxConflictRetries bounds the read-modify-write retries on update conflicts
+	maxConflictRetries = 5
+)
+
+// ConfigMapSink keeps the most recent entries in a ConfigMap, dropping the oldest
+// once maxEntries is reached
+type ConfigMapSink struct {
+	client     client.Client
+	reader     client.Reader
+	key        types.NamespacedName
+	maxEntries int
+
+	mu sync.Mutex
+}
+
+// NewConfigMapSink creates a ConfigMapSink backed by the named ConfigMap. reader should
+// be an uncached reader so the operator doesn't need to watch ConfigMaps.
+func NewConfigMapSink(c client.Client, reader client.Reader, namespace, name string, maxEntries int) *ConfigMapSink {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &ConfigMapSink{
+		client:     c,
+		reader:     reader,
+		key:        types.NamespacedName{Namespace: namespace, Name: name},
+		maxEntries: maxEntries,
+	}
+}
+
+// Record appends entry to the ring buffer
+func (s *ConfigMapSink) Record(ctx context.Context, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding audit entry: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		err := s.append(ctx, string(line))
+		if err == nil {
+			return nil
+		}
+		if (!errors.IsConflict(err) && !errors.IsAlreadyExists(err)) || attempt >= maxConflictRetries {
+			return fmt.Errorf("writing audit configmap: %w", err)
+		}
+	}
+}
+
+// append performs one read-modify-write of the ConfigMap
+func (s *ConfigMapSink) append(ctx context.Context, line string) error {
+	cm := &corev1.ConfigMap{}
+	exists := true
+	if err := s.reader.Get(ctx, s.key, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		exists = false
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.key.Name,
+				Namespace: s.key.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/name":       "pihole-ingress-operator",
+					"app.kubernetes.io/managed-by": "pihole-ingress-operator",
+				},
+			},
+		}
+	}
+
+	var lines []string
+	if raw := strings.TrimSpace(cm.Data[entriesKey]); raw != "" {
+		lines = strings.Split(raw, "\n")
+	}
+	lines = append(lines, line)
+	if len(lines) > s.maxEntries {
+		lines = lines[len(lines)-s.maxEntries:]
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[entriesKey] = strings.Join(lines, "\n") + "\n"
+
+	if !exists {
+		return s.client.Create(ctx, cm)
+	}
+	return s.client.Update(ctx, cm)
+}
+
+// ReadConfigMap returns the entries kept in the named ConfigMap, oldest first. A
+// missing ConfigMap holds no entries.
+func ReadConfigMap(ctx context.Context, reader client.Reader, namespace, name string) ([]Entry, error) {
+	cm := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cm); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading audit configmap: %w", err)
+	}
+
+	var entries []Entry
+	for _, line := range strings.Split(strings.TrimSpace(cm.Data[entriesKey]), "\n") {
+		if line == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("decoding audit entry %q: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
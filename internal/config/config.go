@@ -1,35 +1,490 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/url"
 	"os"
+	"path"
+	"regexp"
 	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
-// Config holds operator configuration
+// Config holds operator configuration; the yaml tags name the keys of the config file
 type Config struct {
-	PiholeURL       string
-	PiholePassword  string
-	DefaultTargetIP string
-	LogLevel        string
-	WatchNamespace  string
+	PiholeURL       string        `yaml:"piholeURL"`
+	PiholePassword  string        `yaml:"piholePassword"`
+	DefaultTargetIP string        `yaml:"defaultTargetIP"`
+	LogLevel        string        `yaml:"logLevel"`
+	LogFormat       string        `yaml:"logFormat"`
+	WatchNamespace  string        `yaml:"watchNamespace"`
+	RetryMaxBackoff time.Duration `yaml:"retryMaxBackoff"`
+
+	// LogVerbosity is the most verbose controller-runtime logr level, V(n), that is
+	// logged; V(n) is logged at slog level info-n, so LogLevel filters it too
+	LogVerbosity int `yaml:"logVerbosity"`
+
+	// LogDedupWindow logs an error repeating within it once, with the number of repeats
+	// when it closes; 0 logs every error
+	LogDedupWindow time.Duration `yaml:"logDedupWindow"`
+
+	// RequeueIntervalError is the first retry delay after a Pi-hole API error, doubling
+	// up to RetryMaxBackoff; RequeueIntervalConflict retries an object whose managed-hosts
+	// annotation could not be written
+	RequeueIntervalError    time.Duration `yaml:"requeueIntervalError"`
+	RequeueIntervalConflict time.Duration `yaml:"requeueIntervalConflict"`
+
+	// MinRequeueAfter is the shortest resync interval an object may ask for with the
+	// pihole.io/requeue-after annotation
+	MinRequeueAfter time.Duration `yaml:"minRequeueAfter"`
+
+	// PiholePasswordSecret names a Secret key holding the password as namespace/name#key,
+	// instead of PiholePassword; the password follows the Secret when it is rotated
+	PiholePasswordSecret string `yaml:"piholePasswordSecret"`
+
+	// PiholeInstances lists the Pi-hole servers kept in step, instead of PiholeURL and
+	// its password; see Instances
+	PiholeInstances []PiholeInstance `yaml:"piholeInstances"`
+
+	// StartupPiholeCheck decides what happens when Pi-hole is unreachable at startup: warn
+	// carries on, fail exits, and wait probes with backoff for up to StartupPiholeTimeout
+	// (zero waits indefinitely) before exiting
+	StartupPiholeCheck   string        `yaml:"startupPiholeCheck"`
+	StartupPiholeTimeout time.Duration `yaml:"startupPiholeTimeout"`
+
+	// ReadinessFailureThreshold is how many consecutive failed Pi-hole probes make the
+	// operator unready, and ReadinessUnavailableAfter how long since the last success
+	// does (zero disables it); the first success makes it ready again
+	ReadinessFailureThreshold int           `yaml:"readinessFailureThreshold"`
+	ReadinessUnavailableAfter time.Duration `yaml:"readinessUnavailableAfter"`
+
+	// HeartbeatStaleness is how long a controller with work queued may go without
+	// finishing a reconcile before liveness fails; zero disables the check
+	HeartbeatStaleness time.Duration `yaml:"heartbeatStaleness"`
+
+	// TargetSource selects where target IPs come from: static uses DefaultTargetIP, status
+	// the object's load-balancer address, with DefaultTargetIP as an optional fallback
+	TargetSource string `yaml:"targetSource"`
+
+	// DefaultTargetIPv6 adds an AAAA record for every host; empty disables it. Set alone,
+	// without DefaultTargetIP, only AAAA records are managed by default.
+	DefaultTargetIPv6 string `yaml:"defaultTargetIPv6"`
+
+	// EnableAAAA allows AAAA records, by default or through the target-ipv6 annotation;
+	// turn it off for a Pi-hole that should only hold A records
+	EnableAAAA bool `yaml:"enableAAAA"`
+
+	// DefaultDomainSuffix is appended to hosts without a dot; empty disables it
+	DefaultDomainSuffix string `yaml:"defaultDomainSuffix"`
+
+	// SyncPolicy limits the changes made to Pi-hole: sync, upsert-only or create-only
+	SyncPolicy string `yaml:"syncPolicy"`
+
+	// ConflictPolicy settles objects wanting different IPs for one record: strict or oldest-wins
+	ConflictPolicy string `yaml:"conflictPolicy"`
+
+	// HostsMode decides whether the pihole.io/hosts annotation replaces the hosts of an
+	// object's spec or is merged with them: replace or merge
+	HostsMode string `yaml:"hostsMode"`
+
+	// ClusterID marks the records this operator creates, so operators of several
+	// clusters sharing a Pi-hole leave each other's records alone
+	ClusterID string `yaml:"clusterID"`
+
+	// Sources lists the kinds registered in Pi-hole; optional sources whose CRD
+	// is not installed are skipped at startup
+	Sources []string `yaml:"sources"`
+
+	// RequireRegisterLabel only caches and registers objects labelled pihole.io/register=true
+	RequireRegisterLabel bool `yaml:"requireRegisterLabel"`
+
+	// IngressClasses limits Ingresses to these classes; empty considers all of them
+	IngressClasses []string `yaml:"ingressClasses"`
+
+	// ExcludeNamespaces lists namespaces, by exact name or glob, that are never managed
+	ExcludeNamespaces []string `yaml:"excludeNamespaces"`
+
+	// LabelSelector limits every source to objects matching this label selector;
+	// empty considers all objects
+	LabelSelector string `yaml:"labelSelector"`
+
+	// MaxConcurrentReconciles is the number of objects of each kind reconciled in parallel
+	MaxConcurrentReconciles int `yaml:"maxConcurrentReconciles"`
+
+	// Workqueue rate limiting per controller: failed objects back off exponentially from
+	// RateLimiterBaseDelay to RateLimiterMaxDelay, and all objects share a token bucket
+	RateLimiterBaseDelay time.Duration `yaml:"rateLimiterBaseDelay"`
+	RateLimiterMaxDelay  time.Duration `yaml:"rateLimiterMaxDelay"`
+	RateLimiterQPS       float64       `yaml:"rateLimiterQPS"`
+	RateLimiterBurst     int           `yaml:"rateLimiterBurst"`
+
+	// BatchInterval coalesces record changes into batched writes flushed at this interval,
+	// or once BatchMaxSize changes are waiting; zero writes each change directly
+	BatchInterval time.Duration `yaml:"batchInterval"`
+	BatchMaxSize  int           `yaml:"batchMaxSize"`
+
+	// ListingMaxAge is how long one Pi-hole listing is shared by the reconciles that
+	// follow it, kept current with their writes; zero lists Pi-hole on every reconcile
+	ListingMaxAge time.Duration `yaml:"listingMaxAge"`
+
+	// WipeThreshold is the fraction of managed records that must vanish from Pi-hole at
+	// once, with at least WipeMinRecords present before, to trigger a restore limited
+	// to RestoreMaxPerMinute records a minute; zero disables detection
+	WipeThreshold       float64 `yaml:"wipeThreshold"`
+	WipeMinRecords      int     `yaml:"wipeMinRecords"`
+	RestoreMaxPerMinute int     `yaml:"restoreMaxPerMinute"`
+
+	// StatusResource names the cluster-scoped PiholeSync that reports per-object sync
+	// state, written at most every StatusInterval; empty disables it
+	StatusResource string        `yaml:"statusResource"`
+	StatusInterval time.Duration `yaml:"statusInterval"`
+
+	// DefaultOverwrite allows Ingresses to replace records that already exist in Pi-hole
+	DefaultOverwrite bool `yaml:"defaultOverwrite"`
+
+	// AdoptExisting lets Ingresses adopt records that already exist in Pi-hole with the
+	// desired IP even when they may not overwrite them
+	AdoptExisting bool `yaml:"adoptExisting"`
+
+	// AllowCrossNamespaceDuplicates lets objects in different namespaces share a host
+	// when they want the same IP
+	AllowCrossNamespaceDuplicates bool `yaml:"allowCrossNamespaceDuplicates"`
+
+	// Deletion safety thresholds; zero disables the corresponding check
+	MaxDeletionsPerSync     int           `yaml:"maxDeletionsPerSync"`
+	MaxDeletionsPerInterval int           `yaml:"maxDeletionsPerInterval"`
+	DeletionBudgetInterval  time.Duration `yaml:"deletionBudgetInterval"`
+
+	// MaxManagedRecords caps the records managed overall and RecordQuotaPerNamespace
+	// those of each namespace; zero is unlimited
+	MaxManagedRecords       int `yaml:"maxManagedRecords"`
+	RecordQuotaPerNamespace int `yaml:"recordQuotaPerNamespace"`
+
+	// RequireIngressReady defers registration until an Ingress has a load-balancer status
+	RequireIngressReady     bool          `yaml:"requireIngressReady"`
+	IngressReadyGracePeriod time.Duration `yaml:"ingressReadyGracePeriod"`
+
+	// FilterInternalHosts skips IP literals and hosts under InternalHostSuffixes
+	FilterInternalHosts  bool     `yaml:"filterInternalHosts"`
+	InternalHostSuffixes []string `yaml:"internalHostSuffixes"`
+
+	// EnableFinalizers controls whether pihole.io/dns-cleanup is added to Ingresses;
+	// StripFinalizers removes previously added finalizers when they are disabled
+	EnableFinalizers bool `yaml:"enableFinalizers"`
+	StripFinalizers  bool `yaml:"stripFinalizers"`
+
+	// OnceFinalizers replaces EnableFinalizers for a single pass run with --once. Off by
+	// default, since an object deleted between runs would wait for the next one; with it
+	// on, that wait is what lets the next run remove the object's records.
+	OnceFinalizers bool `yaml:"onceFinalizers"`
+
+	// FinalizerTimeout and FinalizerMaxAttempts bound how long deletion waits for DNS cleanup
+	FinalizerTimeout     time.Duration `yaml:"finalizerTimeout"`
+	FinalizerMaxAttempts int           `yaml:"finalizerMaxAttempts"`
+
+	// StuckDeletionWarning is how long a deleted object may wait for DNS cleanup before
+	// a Warning event is emitted on it; 0 disables the event
+	StuckDeletionWarning time.Duration `yaml:"stuckDeletionWarning"`
+
+	// Audit trail destinations; empty values disable the corresponding sink
+	AuditLogPath    string `yaml:"auditLogPath"`
+	AuditConfigMap  string `yaml:"auditConfigMap"`
+	AuditMaxEntries int    `yaml:"auditMaxEntries"`
+
+	// RecordsConfigMap mirrors every managed record, as a hosts file and as JSON, into
+	// a ConfigMap of this name in RecordsConfigMapNamespace, the registry namespace when
+	// empty; an empty name disables it. Writes are at least RecordsConfigMapInterval apart.
+	RecordsConfigMap          string        `yaml:"recordsConfigMap"`
+	RecordsConfigMapNamespace string        `yaml:"recordsConfigMapNamespace"`
+	RecordsConfigMapInterval  time.Duration `yaml:"recordsConfigMapInterval"`
+
+	// Change notifications; an empty NotifyURL disables them
+	NotifyURL    string   `yaml:"notifyURL"`
+	NotifyFormat string   `yaml:"notifyFormat"`
+	NotifyEvents []string `yaml:"notifyEvents"`
+
+	// AdminToken is the bearer token required by the admin endpoint
+	AdminToken string `yaml:"adminToken"`
+
+	// MetricsPrefix is prepended to every operator metric name, and MetricsConstLabels
+	// are added to every series
+	MetricsPrefix      string            `yaml:"metricsPrefix"`
+	MetricsConstLabels map[string]string `yaml:"metricsConstLabels"`
+
+	// Endpoints served by the manager; an address of 0 disables the endpoint
+	MetricsBindAddress     string `yaml:"metricsBindAddress"`
+	HealthProbeBindAddress string `yaml:"healthProbeBindAddress"`
+	AdminBindAddress       string `yaml:"adminBindAddress"`
+
+	// MetricsSecure serves metrics over HTTPS to requests whose ServiceAccount token may
+	// get /metrics; the certificate is read from MetricsCertPath when set, otherwise a
+	// self-signed one is generated
+	MetricsSecure   bool   `yaml:"metricsSecure"`
+	MetricsCertPath string `yaml:"metricsCertPath"`
+	MetricsCertName string `yaml:"metricsCertName"`
+	MetricsCertKey  string `yaml:"metricsCertKey"`
+
+	// EnableHTTP2 allows HTTP/2 on the TLS endpoints, such as metrics, where it is off
+	// by default because of the HTTP/2 Stream Cancellation and Rapid Reset CVEs
+	EnableHTTP2 bool `yaml:"enableHTTP2"`
+
+	// PprofBindAddress serves the unauthenticated pprof profiles on a listener of their own
+	PprofBindAddress string `yaml:"pprofBindAddress"`
+
+	// EnableDebugEndpoints serves the debug handlers on the admin endpoint
+	EnableDebugEndpoints bool `yaml:"enableDebugEndpoints"`
+
+	// LeaderElect runs the controllers in one replica at a time
+	LeaderElect bool `yaml:"leaderElect"`
+
+	// Leader election tuning: a leader holds the lease for LeaderElectionLeaseDuration,
+	// gives it up when it cannot renew within LeaderElectionRenewDeadline, and candidates
+	// retry every LeaderElectionRetryPeriod. The lease lives in LeaderElectionNamespace,
+	// the operator's own namespace when empty. LeaderElectionReleaseOnCancel hands the
+	// lease over on a graceful shutdown instead of letting it expire.
+	LeaderElectionNamespace       string        `yaml:"leaderElectionNamespace"`
+	LeaderElectionLeaseDuration   time.Duration `yaml:"leaderElectionLeaseDuration"`
+	LeaderElectionRenewDeadline   time.Duration `yaml:"leaderElectionRenewDeadline"`
+	LeaderElectionRetryPeriod     time.Duration `yaml:"leaderElectionRetryPeriod"`
+	LeaderElectionResourceLock    string        `yaml:"leaderElectionResourceLock"`
+	LeaderElectionReleaseOnCancel bool          `yaml:"leaderElectionReleaseOnCancel"`
+
+	// GracefulShutdownTimeout bounds how long the operator waits for its controllers and
+	// in-flight Pi-hole calls on shutdown; 0 stops at once. Keep it below the pod's
+	// terminationGracePeriodSeconds so the shutdown finishes before a SIGKILL.
+	GracefulShutdownTimeout time.Duration `yaml:"gracefulShutdownTimeout"`
+
+	// Registry ConfigMap holding persisted operator state; an empty namespace disables it
+	RegistryNamespace string `yaml:"registryNamespace"`
+	RegistryName      string `yaml:"registryName"`
 }
 
-// Load reads configuration from environment variables and validates it
-func Load() (*Config, error) {
+const (
+	// DefaultLogFormat writes JSON lines, suited to log aggregation
+	DefaultLogFormat = "json"
+
+	// DefaultLogVerbosity logs controller-runtime's V(1) messages at debug level and
+	// drops its more verbose ones
+	DefaultLogVerbosity = 1
+
+	// DefaultStartupPiholeCheck logs an unreachable Pi-hole at startup and carries on
+	DefaultStartupPiholeCheck = "warn"
+
+	// DefaultStartupPiholeTimeout is how long STARTUP_PIHOLE_CHECK=wait waits for Pi-hole
+	DefaultStartupPiholeTimeout = 5 * time.Minute
+
+	// DefaultReadinessFailureThreshold and DefaultReadinessUnavailableAfter make the
+	// operator unready after three failed Pi-hole probes or ten minutes without a success
+	DefaultReadinessFailureThreshold = 3
+	DefaultReadinessUnavailableAfter = 10 * time.Minute
+
+	// DefaultHeartbeatStaleness fails liveness once a controller with work queued has
+	// not finished a reconcile in five minutes
+	DefaultHeartbeatStaleness = 5 * time.Minute
+
+	// DefaultTargetSource points every record at DEFAULT_TARGET_IP
+	DefaultTargetSource = "static"
+
+	// DefaultSyncPolicy creates, updates and deletes records
+	DefaultSyncPolicy = "sync"
+
+	// DefaultConflictPolicy reports conflicting records without settling them
+	DefaultConflictPolicy = "strict"
+
+	// DefaultHostsMode lets the pihole.io/hosts annotation replace the spec hosts
+	DefaultHostsMode = "replace"
+
+	// DefaultRetryMaxBackoff caps the per-Ingress retry delay after Pi-hole API errors
+	DefaultRetryMaxBackoff = 10 * time.Minute
+
+	// DefaultRequeueIntervalError and DefaultRequeueIntervalConflict are the retry delays
+	// after a Pi-hole API error and a failed annotation update
+	DefaultRequeueIntervalError    = 30 * time.Second
+	DefaultRequeueIntervalConflict = 10 * time.Second
+
+	// DefaultMinRequeueAfter is the shortest interval pihole.io/requeue-after may request
+	DefaultMinRequeueAfter = 10 * time.Second
+
+	// DefaultDeletionBudgetInterval is the rolling window for MAX_DELETIONS_PER_INTERVAL
+	DefaultDeletionBudgetInterval = time.Hour
+
+	// DefaultIngressReadyGracePeriod is how long a load-balancer status may stay empty
+	// before previously published records are withdrawn
+	DefaultIngressReadyGracePeriod = 5 * time.Minute
+
+	// DefaultFinalizerTimeout is how long a deleted Ingress waits for DNS cleanup
+	DefaultFinalizerTimeout = time.Hour
+
+	// DefaultStuckDeletionWarning is how long a deleted object waits for DNS cleanup
+	// before it is reported as stuck
+	DefaultStuckDeletionWarning = 10 * time.Minute
+
+	// Rate limiter defaults, matching controller-runtime
+	DefaultRateLimiterBaseDelay = 5 * time.Millisecond
+	DefaultRateLimiterMaxDelay  = 1000 * time.Second
+	DefaultRateLimiterQPS       = 10
+	DefaultRateLimiterBurst     = 100
+
+	// DefaultBatchMaxSize is how many waiting changes trigger an early batch flush
+	DefaultBatchMaxSize = 100
+
+	// DefaultListingMaxAge is how long reconciles share one Pi-hole listing
+	DefaultListingMaxAge = 5 * time.Second
+
+	// DefaultWipeThreshold, DefaultWipeMinRecords and DefaultRestoreMaxPerMinute
+	// tune wipe detection
+	DefaultWipeThreshold       = 0.5
+	DefaultWipeMinRecords      = 5
+	DefaultRestoreMaxPerMinute = 30
+
+	// DefaultStatusInterval is the shortest time between writes of the PiholeSync status
+	DefaultStatusInterval = 10 * time.Second
+
+	// DefaultAuditMaxEntries is how many entries the audit ConfigMap retains
+	DefaultAuditMaxEntries = 500
+
+	// DefaultRecordsConfigMapInterval is the least time between two writes of the
+	// records ConfigMap
+	DefaultRecordsConfigMapInterval = 10 * time.Second
+
+	// DefaultNotifyFormat posts change summaries as JSON
+	DefaultNotifyFormat = "json"
+
+	// DefaultMetricsPrefix names metrics pihole_<name>
+	DefaultMetricsPrefix = "pihole"
+
+	// DefaultMetricsCertName and DefaultMetricsCertKey are the file names of the metrics
+	// certificate and key in METRICS_CERT_PATH
+	DefaultMetricsCertName = "tls.crt"
+	DefaultMetricsCertKey  = "tls.key"
+
+	// DefaultHealthProbeBindAddress is where the health probes are served
+	DefaultHealthProbeBindAddress = ":8081"
+
+	// Leader election defaults, those of controller-runtime
+	DefaultLeaderElectionLeaseDuration = 15 * time.Second
+	DefaultLeaderElectionRenewDeadline = 10 * time.Second
+	DefaultLeaderElectionRetryPeriod   = 2 * time.Second
+	DefaultLeaderElectionResourceLock  = "leases"
+
+	// DefaultGracefulShutdownTimeout leaves room within the manifest's 10s termination grace
+	DefaultGracefulShutdownTimeout = 8 * time.Second
+
+	// DisabledAddress is the bind address that disables an endpoint
+	DisabledAddress = "0"
+
+	// DefaultRegistryName is the default name of the registry ConfigMap
+	DefaultRegistryName = "pihole-operator-registry"
+
+	// Record sources accepted in SOURCES
+	SourceIngress       = "ingress"
+	SourceDomainMapping = "domainmapping"
+)
+
+// metricNamePattern matches a Prometheus metric name prefix or label name
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// clusterIDPattern matches a cluster ID, which becomes a DNS label of the record markers
+var clusterIDPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// leaderElectionJitter is client-go's leaderelection.JitterFactor, by which the renew
+// deadline must exceed the retry period
+const leaderElectionJitter = 1.2
+
+// DefaultInternalHostSuffixes are the cluster-internal DNS suffixes skipped by default
+var DefaultInternalHostSuffixes = []string{".svc", ".cluster.local", ".svc.cluster.local"}
+
+// DefaultNotifyEvents are the change types notified by default
+var DefaultNotifyEvents = []string{"create", "update", "delete"}
+
+// DefaultSources are the record sources enabled by default
+var DefaultSources = []string{SourceIngress, SourceDomainMapping}
+
+// Load reads configuration from the YAML file at path, when path is not empty, applies
+// the environment variables that are set over it and then the flags given, when flags
+// is not nil, and validates the result
+func Load(path string, flags *Flags) (*Config, error) {
 	cfg := &Config{
-		PiholeURL:       os.Getenv("PIHOLE_URL"),
-		PiholePassword:  os.Getenv("PIHOLE_PASSWORD"),
-		DefaultTargetIP: os.Getenv("DEFAULT_TARGET_IP"),
-		LogLevel:        os.Getenv("LOG_LEVEL"),
-		WatchNamespace:  os.Getenv("WATCH_NAMESPACE"),
+		LogLevel:                  "info",
+		LogFormat:                 DefaultLogFormat,
+		LogVerbosity:              DefaultLogVerbosity,
+		TargetSource:              DefaultTargetSource,
+		StartupPiholeCheck:        DefaultStartupPiholeCheck,
+		StartupPiholeTimeout:      DefaultStartupPiholeTimeout,
+		ReadinessFailureThreshold: DefaultReadinessFailureThreshold,
+		ReadinessUnavailableAfter: DefaultReadinessUnavailableAfter,
+		HeartbeatStaleness:        DefaultHeartbeatStaleness,
+		RetryMaxBackoff:           DefaultRetryMaxBackoff,
+		RequeueIntervalError:      DefaultRequeueIntervalError,
+		RequeueIntervalConflict:   DefaultRequeueIntervalConflict,
+		MinRequeueAfter:           DefaultMinRequeueAfter,
+		Sources:                   DefaultSources,
+		MaxConcurrentReconciles:   1,
+		RateLimiterBaseDelay:      DefaultRateLimiterBaseDelay,
+		RateLimiterMaxDelay:       DefaultRateLimiterMaxDelay,
+		RateLimiterQPS:            DefaultRateLimiterQPS,
+		RateLimiterBurst:          DefaultRateLimiterBurst,
+		BatchMaxSize:              DefaultBatchMaxSize,
+		ListingMaxAge:             DefaultListingMaxAge,
+		WipeThreshold:             DefaultWipeThreshold,
+		WipeMinRecords:            DefaultWipeMinRecords,
+		RestoreMaxPerMinute:       DefaultRestoreMaxPerMinute,
+		StatusInterval:            DefaultStatusInterval,
+		DefaultOverwrite:          true,
+		DeletionBudgetInterval:    DefaultDeletionBudgetInterval,
+		IngressReadyGracePeriod:   DefaultIngressReadyGracePeriod,
+		FilterInternalHosts:       true,
+		EnableAAAA:                true,
+		InternalHostSuffixes:      DefaultInternalHostSuffixes,
+		EnableFinalizers:          true,
+		FinalizerTimeout:          DefaultFinalizerTimeout,
+		StuckDeletionWarning:      DefaultStuckDeletionWarning,
+		AuditMaxEntries:           DefaultAuditMaxEntries,
+		RecordsConfigMapInterval:  DefaultRecordsConfigMapInterval,
+		NotifyEvents:              DefaultNotifyEvents,
+		RegistryName:              DefaultRegistryName,
+		MetricsPrefix:             DefaultMetricsPrefix,
+		MetricsBindAddress:        DisabledAddress,
+		HealthProbeBindAddress:    DefaultHealthProbeBindAddress,
+		AdminBindAddress:          DisabledAddress,
+		PprofBindAddress:          DisabledAddress,
+		MetricsSecure:             true,
+		MetricsCertName:           DefaultMetricsCertName,
+		MetricsCertKey:            DefaultMetricsCertKey,
+
+		LeaderElectionLeaseDuration:   DefaultLeaderElectionLeaseDuration,
+		LeaderElectionRenewDeadline:   DefaultLeaderElectionRenewDeadline,
+		LeaderElectionRetryPeriod:     DefaultLeaderElectionRetryPeriod,
+		LeaderElectionResourceLock:    DefaultLeaderElectionResourceLock,
+		LeaderElectionReleaseOnCancel: true,
+		GracefulShutdownTimeout:       DefaultGracefulShutdownTimeout,
 	}
 
-	// Set defaults
-	if cfg.LogLevel == "" {
-		cfg.LogLevel = "info"
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	if err := flags.apply(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.readInstancePasswords(); err != nil {
+		return nil, err
+	}
+	if cfg.RegistryNamespace == "" {
+		cfg.RegistryNamespace = os.Getenv("POD_NAMESPACE")
+	}
+	if cfg.RecordsConfigMapNamespace == "" {
+		cfg.RecordsConfigMapNamespace = cfg.RegistryNamespace
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -39,33 +494,103 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// loadFile overlays the settings in a YAML config file. Unknown keys are rejected so
+// that typos fail loudly instead of leaving the default in place.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Selector returns LABEL_SELECTOR parsed, or nil when it is empty
+func (c *Config) Selector() labels.Selector {
+	if c.LabelSelector == "" {
+		return nil
+	}
+	selector, err := labels.Parse(c.LabelSelector)
+	if err != nil {
+		return nil // rejected by Validate
+	}
+	return selector
+}
+
+// parseSecretKeyRef splits a namespace/name#key reference
+func parseSecretKeyRef(ref string) (namespace, name, key string, ok bool) {
+	secret, key, found := strings.Cut(ref, "#")
+	namespace, name, slash := strings.Cut(secret, "/")
+	if !found || !slash || namespace == "" || name == "" || key == "" {
+		return "", "", "", false
+	}
+	return namespace, name, key, true
+}
+
 // Validate checks that all required configuration is present and valid
 func (c *Config) Validate() error {
-	// Validate PIHOLE_URL
-	if c.PiholeURL == "" {
-		return fmt.Errorf("PIHOLE_URL is required")
+	// Validate PIHOLE_INSTANCES, or PIHOLE_URL and its password
+	if len(c.PiholeInstances) > 0 {
+		if err := c.validateInstances(); err != nil {
+			return err
+		}
+	} else if err := c.validatePihole(); err != nil {
+		return err
 	}
-	parsedURL, err := url.Parse(c.PiholeURL)
-	if err != nil {
-		return fmt.Errorf("PIHOLE_URL is not a valid URL: %w", err)
+
+	// Validate STARTUP_PIHOLE_CHECK
+	switch c.StartupPiholeCheck = strings.ToLower(c.StartupPiholeCheck); c.StartupPiholeCheck {
+	case "":
+		c.StartupPiholeCheck = DefaultStartupPiholeCheck
+	case "warn", "wait", "fail":
+	default:
+		return fmt.Errorf("STARTUP_PIHOLE_CHECK must be one of: warn, wait, fail")
 	}
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return fmt.Errorf("PIHOLE_URL must be an HTTP or HTTPS URL")
+	if c.StartupPiholeTimeout < 0 {
+		return fmt.Errorf("STARTUP_PIHOLE_TIMEOUT must not be negative")
+	}
+	if c.ReadinessFailureThreshold < 1 {
+		return fmt.Errorf("READINESS_FAILURE_THRESHOLD must be at least 1")
+	}
+	if c.ReadinessUnavailableAfter < 0 {
+		return fmt.Errorf("READINESS_UNAVAILABLE_AFTER must not be negative")
+	}
+	if c.HeartbeatStaleness < 0 {
+		return fmt.Errorf("HEARTBEAT_STALENESS must not be negative")
 	}
 
-	// Validate PIHOLE_PASSWORD
-	if c.PiholePassword == "" {
-		return fmt.Errorf("PIHOLE_PASSWORD is required")
+	// Validate TARGET_SOURCE
+	switch c.TargetSource = strings.ToLower(c.TargetSource); c.TargetSource {
+	case "":
+		c.TargetSource = DefaultTargetSource
+	case "static", "status":
+	default:
+		return fmt.Errorf("TARGET_SOURCE must be one of: static, status")
 	}
 
-	// Validate DEFAULT_TARGET_IP
-	if c.DefaultTargetIP == "" {
-		return fmt.Errorf("DEFAULT_TARGET_IP is required")
+	// Validate DEFAULT_TARGET_IP, only optional when targets come from object status or
+	// every record is IPv6
+	if c.DefaultTargetIP == "" && c.DefaultTargetIPv6 == "" && c.TargetSource == "static" {
+		return fmt.Errorf("DEFAULT_TARGET_IP is required unless DEFAULT_TARGET_IPV6 is set")
 	}
-	if !isValidIPv4(c.DefaultTargetIP) {
+	if c.DefaultTargetIP != "" && !isValidIPv4(c.DefaultTargetIP) {
 		return fmt.Errorf("DEFAULT_TARGET_IP is not a valid IPv4 address: %s", c.DefaultTargetIP)
 	}
 
+	// Validate DEFAULT_TARGET_IPV6
+	if c.DefaultTargetIPv6 != "" && !isValidIPv6(c.DefaultTargetIPv6) {
+		return fmt.Errorf("DEFAULT_TARGET_IPV6 is not a valid IPv6 address: %s", c.DefaultTargetIPv6)
+	}
+	if c.DefaultTargetIPv6 != "" && !c.EnableAAAA {
+		return fmt.Errorf("DEFAULT_TARGET_IPV6 requires ENABLE_AAAA")
+	}
+
 	// Validate LOG_LEVEL
 	validLogLevels := map[string]bool{
 		"debug": true,
@@ -78,9 +603,332 @@ func (c *Config) Validate() error {
 	}
 	c.LogLevel = strings.ToLower(c.LogLevel)
 
+	// Validate LOG_FORMAT
+	switch c.LogFormat = strings.ToLower(c.LogFormat); c.LogFormat {
+	case "":
+		c.LogFormat = DefaultLogFormat
+	case "json", "text", "console":
+	default:
+		return fmt.Errorf("LOG_FORMAT must be one of: json, text, console")
+	}
+	if c.LogVerbosity < 0 {
+		return fmt.Errorf("LOG_VERBOSITY must not be negative")
+	}
+	if c.LogDedupWindow < 0 {
+		return fmt.Errorf("LOG_DEDUP_WINDOW must not be negative")
+	}
+
+	// Validate METRICS_PREFIX and METRICS_CONST_LABELS; a trailing underscore is dropped
+	// since one joins the prefix to each name
+	c.MetricsPrefix = strings.TrimSuffix(c.MetricsPrefix, "_")
+	if !metricNamePattern.MatchString(c.MetricsPrefix) {
+		return fmt.Errorf("METRICS_PREFIX must be letters, digits and underscores, not starting with a digit: %q", c.MetricsPrefix)
+	}
+	for name := range c.MetricsConstLabels {
+		if !metricNamePattern.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("METRICS_CONST_LABELS has an invalid label name: %q", name)
+		}
+	}
+
+	// Validate PPROF_BIND_ADDRESS, which must not expose the profiles on another endpoint
+	if c.PprofBindAddress != DisabledAddress {
+		for name, addr := range map[string]string{
+			"METRICS_BIND_ADDRESS":      c.MetricsBindAddress,
+			"HEALTH_PROBE_BIND_ADDRESS": c.HealthProbeBindAddress,
+			"ADMIN_BIND_ADDRESS":        c.AdminBindAddress,
+		} {
+			if addr == c.PprofBindAddress {
+				return fmt.Errorf("PPROF_BIND_ADDRESS must differ from %s", name)
+			}
+		}
+	}
+
+	if err := c.validateLeaderElection(); err != nil {
+		return err
+	}
+	if c.GracefulShutdownTimeout < 0 {
+		return fmt.Errorf("GRACEFUL_SHUTDOWN_TIMEOUT must not be negative")
+	}
+
+	// Validate SYNC_POLICY
+	switch c.SyncPolicy = strings.ToLower(c.SyncPolicy); c.SyncPolicy {
+	case "":
+		c.SyncPolicy = DefaultSyncPolicy
+	case "sync", "upsert-only", "create-only":
+	default:
+		return fmt.Errorf("SYNC_POLICY must be one of: sync, upsert-only, create-only")
+	}
+
+	// Validate CONFLICT_POLICY
+	switch c.ConflictPolicy = strings.ToLower(c.ConflictPolicy); c.ConflictPolicy {
+	case "":
+		c.ConflictPolicy = DefaultConflictPolicy
+	case "strict", "oldest-wins":
+	default:
+		return fmt.Errorf("CONFLICT_POLICY must be one of: strict, oldest-wins")
+	}
+
+	// Validate HOSTS_MODE
+	switch c.HostsMode = strings.ToLower(c.HostsMode); c.HostsMode {
+	case "":
+		c.HostsMode = DefaultHostsMode
+	case "replace", "merge":
+	default:
+		return fmt.Errorf("HOSTS_MODE must be one of: replace, merge")
+	}
+
+	// Validate CLUSTER_ID
+	if c.ClusterID != "" && !clusterIDPattern.MatchString(c.ClusterID) {
+		return fmt.Errorf("CLUSTER_ID must be a DNS label of lowercase letters, digits and hyphens: %q", c.ClusterID)
+	}
+
+	// Validate RETRY_MAX_BACKOFF
+	if c.RetryMaxBackoff <= 0 {
+		return fmt.Errorf("RETRY_MAX_BACKOFF must be a positive duration")
+	}
+
+	// Validate requeue intervals
+	if c.RequeueIntervalError <= 0 {
+		return fmt.Errorf("REQUEUE_INTERVAL_ERROR must be a positive duration")
+	}
+	if c.RequeueIntervalError > c.RetryMaxBackoff {
+		return fmt.Errorf("REQUEUE_INTERVAL_ERROR must not exceed RETRY_MAX_BACKOFF")
+	}
+	if c.RequeueIntervalConflict <= 0 {
+		return fmt.Errorf("REQUEUE_INTERVAL_CONFLICT must be a positive duration")
+	}
+	if c.MinRequeueAfter <= 0 {
+		return fmt.Errorf("MIN_REQUEUE_AFTER must be a positive duration")
+	}
+
+	if c.MaxConcurrentReconciles < 1 {
+		return fmt.Errorf("MAX_CONCURRENT_RECONCILES must be at least 1")
+	}
+
+	// Validate rate limiter settings
+	if c.RateLimiterBaseDelay <= 0 {
+		return fmt.Errorf("RATE_LIMITER_BASE_DELAY must be a positive duration")
+	}
+	if c.RateLimiterMaxDelay < c.RateLimiterBaseDelay {
+		return fmt.Errorf("RATE_LIMITER_MAX_DELAY must not be less than RATE_LIMITER_BASE_DELAY")
+	}
+	if c.RateLimiterQPS <= 0 {
+		return fmt.Errorf("RATE_LIMITER_QPS must be positive")
+	}
+	if c.RateLimiterBurst < 1 {
+		return fmt.Errorf("RATE_LIMITER_BURST must be at least 1")
+	}
+
+	if c.BatchInterval < 0 {
+		return fmt.Errorf("BATCH_INTERVAL must not be negative")
+	}
+	if c.BatchMaxSize < 1 {
+		return fmt.Errorf("BATCH_MAX_SIZE must be at least 1")
+	}
+	if c.ListingMaxAge < 0 {
+		return fmt.Errorf("LISTING_MAX_AGE must not be negative")
+	}
+
+	if c.WipeThreshold < 0 || c.WipeThreshold > 1 {
+		return fmt.Errorf("WIPE_THRESHOLD must be between 0 and 1")
+	}
+	if c.WipeMinRecords < 1 {
+		return fmt.Errorf("WIPE_MIN_RECORDS must be at least 1")
+	}
+	if c.RestoreMaxPerMinute < 1 {
+		return fmt.Errorf("RESTORE_MAX_PER_MINUTE must be at least 1")
+	}
+
+	if c.StatusInterval <= 0 {
+		return fmt.Errorf("STATUS_UPDATE_INTERVAL must be a positive duration")
+	}
+
+	// Validate deletion thresholds
+	if c.MaxDeletionsPerSync < 0 {
+		return fmt.Errorf("MAX_DELETIONS_PER_SYNC must not be negative")
+	}
+	if c.MaxDeletionsPerInterval < 0 {
+		return fmt.Errorf("MAX_DELETIONS_PER_INTERVAL must not be negative")
+	}
+	if c.DeletionBudgetInterval <= 0 {
+		return fmt.Errorf("DELETION_BUDGET_INTERVAL must be a positive duration")
+	}
+
+	// Validate record quotas
+	if c.MaxManagedRecords < 0 {
+		return fmt.Errorf("MAX_MANAGED_RECORDS must not be negative")
+	}
+	if c.RecordQuotaPerNamespace < 0 {
+		return fmt.Errorf("RECORD_QUOTA_PER_NAMESPACE must not be negative")
+	}
+	if c.MaxManagedRecords > 0 && c.RecordQuotaPerNamespace > c.MaxManagedRecords {
+		return fmt.Errorf("RECORD_QUOTA_PER_NAMESPACE must not exceed MAX_MANAGED_RECORDS")
+	}
+
+	// Validate finalizer settings
+	if c.StripFinalizers && c.EnableFinalizers {
+		return fmt.Errorf("STRIP_FINALIZERS requires ENABLE_FINALIZERS=false")
+	}
+
+	if c.FinalizerTimeout < 0 {
+		return fmt.Errorf("FINALIZER_TIMEOUT must not be negative")
+	}
+	if c.FinalizerMaxAttempts < 0 {
+		return fmt.Errorf("FINALIZER_MAX_ATTEMPTS must not be negative")
+	}
+	if c.StuckDeletionWarning < 0 {
+		return fmt.Errorf("STUCK_DELETION_WARNING must not be negative")
+	}
+
+	// Validate audit settings
+	if c.AuditConfigMap != "" && c.RegistryNamespace == "" {
+		return fmt.Errorf("AUDIT_CONFIGMAP requires REGISTRY_NAMESPACE or POD_NAMESPACE")
+	}
+	if c.AuditMaxEntries <= 0 {
+		return fmt.Errorf("AUDIT_MAX_ENTRIES must be positive")
+	}
+
+	// Validate records ConfigMap settings
+	if c.RecordsConfigMap != "" && c.RecordsConfigMapNamespace == "" {
+		return fmt.Errorf("RECORDS_CONFIGMAP requires RECORDS_CONFIGMAP_NAMESPACE, REGISTRY_NAMESPACE or POD_NAMESPACE")
+	}
+	if c.RecordsConfigMapInterval <= 0 {
+		return fmt.Errorf("RECORDS_CONFIGMAP_INTERVAL must be positive")
+	}
+
+	if _, err := labels.Parse(c.LabelSelector); err != nil {
+		return fmt.Errorf("LABEL_SELECTOR is not a valid label selector: %w", err)
+	}
+
+	// Validate EXCLUDE_NAMESPACES
+	for _, pattern := range c.ExcludeNamespaces {
+		matched, err := path.Match(pattern, c.WatchNamespace)
+		if err != nil {
+			return fmt.Errorf("EXCLUDE_NAMESPACES has an invalid pattern %q: %w", pattern, err)
+		}
+		if matched && c.WatchNamespace != "" {
+			return fmt.Errorf("EXCLUDE_NAMESPACES pattern %q excludes WATCH_NAMESPACE %s", pattern, c.WatchNamespace)
+		}
+	}
+
+	// Validate SOURCES
+	if len(c.Sources) == 0 {
+		return fmt.Errorf("SOURCES must list at least one source")
+	}
+	sources := make([]string, 0, len(c.Sources))
+	for _, src := range c.Sources {
+		src = strings.ToLower(src)
+		if src != SourceIngress && src != SourceDomainMapping {
+			return fmt.Errorf("SOURCES may only contain: ingress, domainmapping")
+		}
+		sources = append(sources, src)
+	}
+	c.Sources = sources
+
+	// Validate notification settings
+	if c.NotifyURL != "" {
+		if _, err := url.ParseRequestURI(c.NotifyURL); err != nil {
+			return fmt.Errorf("NOTIFY_URL is not a valid URL: %w", err)
+		}
+	}
+	switch c.NotifyFormat = strings.ToLower(c.NotifyFormat); c.NotifyFormat {
+	case "":
+		c.NotifyFormat = DefaultNotifyFormat
+	case "json", "ntfy":
+	default:
+		return fmt.Errorf("NOTIFY_FORMAT must be one of: json, ntfy")
+	}
+	for _, e := range c.NotifyEvents {
+		if e != "create" && e != "update" && e != "delete" {
+			return fmt.Errorf("NOTIFY_EVENTS may only contain: create, update, delete")
+		}
+	}
+
+	// Validate INGRESS_READY_GRACE_PERIOD
+	if c.IngressReadyGracePeriod < 0 {
+		return fmt.Errorf("INGRESS_READY_GRACE_PERIOD must not be negative")
+	}
+
+	return nil
+}
+
+// validatePihole checks PIHOLE_URL and its password, the single-instance shorthand
+func (c *Config) validatePihole() error {
+	if c.PiholeURL == "" {
+		return fmt.Errorf("PIHOLE_URL is required")
+	}
+	parsedURL, err := url.Parse(c.PiholeURL)
+	if err != nil {
+		return fmt.Errorf("PIHOLE_URL is not a valid URL: %w", err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return fmt.Errorf("PIHOLE_URL must be an HTTP or HTTPS URL")
+	}
+
+	// Validate PIHOLE_PASSWORD and PIHOLE_PASSWORD_SECRET
+	if c.PiholePasswordSecret != "" {
+		if c.PiholePassword != "" {
+			return fmt.Errorf("PIHOLE_PASSWORD_SECRET cannot be combined with PIHOLE_PASSWORD or PIHOLE_PASSWORD_FILE")
+		}
+		if _, _, _, ok := parseSecretKeyRef(c.PiholePasswordSecret); !ok {
+			return fmt.Errorf("PIHOLE_PASSWORD_SECRET must be namespace/name#key")
+		}
+	} else if c.PiholePassword == "" {
+		return fmt.Errorf("PIHOLE_PASSWORD is required")
+	}
 	return nil
 }
 
+// secretEnv reads a secret from the named environment variable or from the file named
+// by name_FILE, the usual way of mounting a Secret without exposing it in the pod spec,
+// returning "" when neither is set. The file's contents are trimmed of surrounding
+// whitespace.
+func secretEnv(name string) (string, error) {
+	value, path := os.Getenv(name), os.Getenv(name+"_FILE")
+	if path == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("%s and %s_FILE are mutually exclusive", name, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s_FILE: %w", name, err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%s_FILE %s is empty", name, path)
+	}
+	return secret, nil
+}
+
+// parseList splits a comma-separated list, dropping empty items
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseMap reads comma-separated key=value pairs; name is where value came from, for errors
+func parseMap(name, value string) (map[string]string, error) {
+	items := map[string]string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("%s must be a list of key=value pairs: %q", name, item)
+		}
+		items[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return items, nil
+}
+
 // isValidIPv4 checks if the given string is a valid IPv4 address
 func isValidIPv4(ip string) bool {
 	parsed := net.ParseIP(ip)
@@ -90,3 +938,31 @@ func isValidIPv4(ip string) bool {
 	// Check it's IPv4 (not IPv6)
 	return parsed.To4() != nil
 }
+
+// isValidIPv6 checks if the given string is a valid IPv6 address
+func isValidIPv6(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() == nil
+}
+
+// validateLeaderElection checks the leader election timings in the order client-go
+// requires: the lease outlasts the renew deadline, which outlasts a retry with jitter
+func (c *Config) validateLeaderElection() error {
+	if c.LeaderElectionRetryPeriod <= 0 {
+		return fmt.Errorf("LEADER_ELECTION_RETRY_PERIOD must be a positive duration")
+	}
+	if float64(c.LeaderElectionRenewDeadline) <= leaderElectionJitter*float64(c.LeaderElectionRetryPeriod) {
+		return fmt.Errorf("LEADER_ELECTION_RENEW_DEADLINE must be more than %v times LEADER_ELECTION_RETRY_PERIOD",
+			leaderElectionJitter)
+	}
+	if c.LeaderElectionLeaseDuration <= c.LeaderElectionRenewDeadline {
+		return fmt.Errorf("LEADER_ELECTION_LEASE_DURATION must be longer than LEADER_ELECTION_RENEW_DEADLINE")
+	}
+	if c.LeaderElectionResourceLock = strings.ToLower(c.LeaderElectionResourceLock); c.LeaderElectionResourceLock == "" {
+		c.LeaderElectionResourceLock = DefaultLeaderElectionResourceLock
+	}
+	if c.LeaderElectionResourceLock != "leases" {
+		return fmt.Errorf("LEADER_ELECTION_RESOURCE_LOCK must be leases, the only lock client-go still supports")
+	}
+	return nil
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -80,26 +84,719 @@ func TestLoad(t *testing.T) {
 			wantErr: true,
 			errMsg:  "DEFAULT_TARGET_IP is not a valid IPv4 address:",
 		},
+		{
+			name: "valid DEFAULT_TARGET_IPV6",
+			envVars: map[string]string{
+				"PIHOLE_URL":          "http://192.168.1.2",
+				"PIHOLE_PASSWORD":     "test-password",
+				"DEFAULT_TARGET_IP":   "192.168.1.100",
+				"DEFAULT_TARGET_IPV6": "fd00::10",
+			},
+			wantErr: false,
+		},
+		{
+			name: "IPv4 DEFAULT_TARGET_IPV6 not allowed",
+			envVars: map[string]string{
+				"PIHOLE_URL":          "http://192.168.1.2",
+				"PIHOLE_PASSWORD":     "test-password",
+				"DEFAULT_TARGET_IP":   "192.168.1.100",
+				"DEFAULT_TARGET_IPV6": "192.168.1.100",
+			},
+			wantErr: true,
+			errMsg:  "DEFAULT_TARGET_IPV6 is not a valid IPv6 address:",
+		},
 		{
 			name: "IPv6 DEFAULT_TARGET_IP not allowed",
 			envVars: map[string]string{
-				"PIHOLE_URL":        "http://192.168.1.2",
-				"PIHOLE_PASSWORD":   "test-password",
-				"DEFAULT_TARGET_IP": "::1",
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "::1",
+			},
+			wantErr: true,
+			errMsg:  "DEFAULT_TARGET_IP is not a valid IPv4 address:",
+		},
+		{
+			name: "invalid LOG_LEVEL",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"LOG_LEVEL":         "invalid",
+			},
+			wantErr: true,
+			errMsg:  "LOG_LEVEL must be one of: debug, info, warn, error",
+		},
+		{
+			name: "invalid RETRY_MAX_BACKOFF",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"RETRY_MAX_BACKOFF": "ten minutes",
+			},
+			wantErr: true,
+			errMsg:  "RETRY_MAX_BACKOFF is not a valid duration",
+		},
+		{
+			name: "non-positive RETRY_MAX_BACKOFF",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"RETRY_MAX_BACKOFF": "0s",
+			},
+			wantErr: true,
+			errMsg:  "RETRY_MAX_BACKOFF must be a positive duration",
+		},
+		{
+			name: "non-positive REQUEUE_INTERVAL_ERROR",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+				"REQUEUE_INTERVAL_ERROR": "0s",
+			},
+			wantErr: true,
+			errMsg:  "REQUEUE_INTERVAL_ERROR must be a positive duration",
+		},
+		{
+			name: "REQUEUE_INTERVAL_ERROR above RETRY_MAX_BACKOFF",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+				"REQUEUE_INTERVAL_ERROR": "5m",
+				"RETRY_MAX_BACKOFF":      "1m",
+			},
+			wantErr: true,
+			errMsg:  "REQUEUE_INTERVAL_ERROR must not exceed RETRY_MAX_BACKOFF",
+		},
+		{
+			name: "invalid REQUEUE_INTERVAL_CONFLICT",
+			envVars: map[string]string{
+				"PIHOLE_URL":                "http://192.168.1.2",
+				"PIHOLE_PASSWORD":           "test-password",
+				"DEFAULT_TARGET_IP":         "192.168.1.100",
+				"REQUEUE_INTERVAL_CONFLICT": "-1s",
+			},
+			wantErr: true,
+			errMsg:  "REQUEUE_INTERVAL_CONFLICT must be a positive duration",
+		},
+		{
+			name: "non-positive MIN_REQUEUE_AFTER",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"MIN_REQUEUE_AFTER": "0s",
+			},
+			wantErr: true,
+			errMsg:  "MIN_REQUEUE_AFTER must be a positive duration",
+		},
+		{
+			name: "custom requeue intervals",
+			envVars: map[string]string{
+				"PIHOLE_URL":                "http://192.168.1.2",
+				"PIHOLE_PASSWORD":           "test-password",
+				"DEFAULT_TARGET_IP":         "192.168.1.100",
+				"REQUEUE_INTERVAL_ERROR":    "1m",
+				"REQUEUE_INTERVAL_CONFLICT": "2s",
+				"MIN_REQUEUE_AFTER":         "30s",
+			},
+			wantErr: false,
+		},
+		{
+			name: "EXCLUDE_NAMESPACES with globs",
+			envVars: map[string]string{
+				"PIHOLE_URL":         "http://192.168.1.2",
+				"PIHOLE_PASSWORD":    "test-password",
+				"DEFAULT_TARGET_IP":  "192.168.1.100",
+				"WATCH_NAMESPACE":    "apps",
+				"EXCLUDE_NAMESPACES": "kube-system, flux-system, tenant-*",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid EXCLUDE_NAMESPACES pattern",
+			envVars: map[string]string{
+				"PIHOLE_URL":         "http://192.168.1.2",
+				"PIHOLE_PASSWORD":    "test-password",
+				"DEFAULT_TARGET_IP":  "192.168.1.100",
+				"EXCLUDE_NAMESPACES": "tenant-[",
+			},
+			wantErr: true,
+			errMsg:  "EXCLUDE_NAMESPACES has an invalid pattern",
+		},
+		{
+			name: "EXCLUDE_NAMESPACES overlapping WATCH_NAMESPACE",
+			envVars: map[string]string{
+				"PIHOLE_URL":         "http://192.168.1.2",
+				"PIHOLE_PASSWORD":    "test-password",
+				"DEFAULT_TARGET_IP":  "192.168.1.100",
+				"WATCH_NAMESPACE":    "tenant-a",
+				"EXCLUDE_NAMESPACES": "kube-system,tenant-*",
+			},
+			wantErr: true,
+			errMsg:  "excludes WATCH_NAMESPACE tenant-a",
+		},
+		{
+			name: "console LOG_FORMAT",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"LOG_FORMAT":        "Console",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid LOG_FORMAT",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"LOG_FORMAT":        "logfmt",
+			},
+			wantErr: true,
+			errMsg:  "LOG_FORMAT must be one of: json, text, console",
+		},
+		{
+			name: "wait STARTUP_PIHOLE_CHECK",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+				"STARTUP_PIHOLE_CHECK":   "Wait",
+				"STARTUP_PIHOLE_TIMEOUT": "0",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid STARTUP_PIHOLE_CHECK",
+			envVars: map[string]string{
+				"PIHOLE_URL":           "http://192.168.1.2",
+				"PIHOLE_PASSWORD":      "test-password",
+				"DEFAULT_TARGET_IP":    "192.168.1.100",
+				"STARTUP_PIHOLE_CHECK": "block",
+			},
+			wantErr: true,
+			errMsg:  "STARTUP_PIHOLE_CHECK must be one of: warn, wait, fail",
+		},
+		{
+			name: "negative STARTUP_PIHOLE_TIMEOUT",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+				"STARTUP_PIHOLE_TIMEOUT": "-1s",
+			},
+			wantErr: true,
+			errMsg:  "STARTUP_PIHOLE_TIMEOUT must not be negative",
+		},
+		{
+			name: "zero READINESS_FAILURE_THRESHOLD",
+			envVars: map[string]string{
+				"PIHOLE_URL":                  "http://192.168.1.2",
+				"PIHOLE_PASSWORD":             "test-password",
+				"DEFAULT_TARGET_IP":           "192.168.1.100",
+				"READINESS_FAILURE_THRESHOLD": "0",
+			},
+			wantErr: true,
+			errMsg:  "READINESS_FAILURE_THRESHOLD must be at least 1",
+		},
+		{
+			name: "zero MAX_CONCURRENT_RECONCILES",
+			envVars: map[string]string{
+				"PIHOLE_URL":                "http://192.168.1.2",
+				"PIHOLE_PASSWORD":           "test-password",
+				"DEFAULT_TARGET_IP":         "192.168.1.100",
+				"MAX_CONCURRENT_RECONCILES": "0",
+			},
+			wantErr: true,
+			errMsg:  "MAX_CONCURRENT_RECONCILES must be at least 1",
+		},
+		{
+			name: "negative GRACEFUL_SHUTDOWN_TIMEOUT",
+			envVars: map[string]string{
+				"PIHOLE_URL":                "http://192.168.1.2",
+				"PIHOLE_PASSWORD":           "test-password",
+				"DEFAULT_TARGET_IP":         "192.168.1.100",
+				"GRACEFUL_SHUTDOWN_TIMEOUT": "-1s",
+			},
+			wantErr: true,
+			errMsg:  "GRACEFUL_SHUTDOWN_TIMEOUT must not be negative",
+		},
+		{
+			name: "negative BATCH_INTERVAL",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"BATCH_INTERVAL":    "-1s",
+			},
+			wantErr: true,
+			errMsg:  "BATCH_INTERVAL must not be negative",
+		},
+		{
+			name: "negative LISTING_MAX_AGE",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"LISTING_MAX_AGE":   "-1s",
+			},
+			wantErr: true,
+			errMsg:  "LISTING_MAX_AGE must not be negative",
+		},
+		{
+			name: "invalid LABEL_SELECTOR",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"LABEL_SELECTOR":    "team in platform",
+			},
+			wantErr: true,
+			errMsg:  "LABEL_SELECTOR is not a valid label selector",
+		},
+		{
+			name: "WIPE_THRESHOLD above 1",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"WIPE_THRESHOLD":    "1.5",
+			},
+			wantErr: true,
+			errMsg:  "WIPE_THRESHOLD must be between 0 and 1",
+		},
+		{
+			name: "zero RESTORE_MAX_PER_MINUTE",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+				"RESTORE_MAX_PER_MINUTE": "0",
+			},
+			wantErr: true,
+			errMsg:  "RESTORE_MAX_PER_MINUTE must be at least 1",
+		},
+		{
+			name: "zero STATUS_UPDATE_INTERVAL",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+				"STATUS_UPDATE_INTERVAL": "0s",
+			},
+			wantErr: true,
+			errMsg:  "STATUS_UPDATE_INTERVAL must be a positive duration",
+		},
+		{
+			name: "invalid RATE_LIMITER_QPS",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"RATE_LIMITER_QPS":  "fast",
+			},
+			wantErr: true,
+			errMsg:  "RATE_LIMITER_QPS is not a valid number",
+		},
+		{
+			name: "zero RATE_LIMITER_QPS",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"RATE_LIMITER_QPS":  "0",
+			},
+			wantErr: true,
+			errMsg:  "RATE_LIMITER_QPS must be positive",
+		},
+		{
+			name: "RATE_LIMITER_MAX_DELAY below base delay",
+			envVars: map[string]string{
+				"PIHOLE_URL":              "http://192.168.1.2",
+				"PIHOLE_PASSWORD":         "test-password",
+				"DEFAULT_TARGET_IP":       "192.168.1.100",
+				"RATE_LIMITER_BASE_DELAY": "1s",
+				"RATE_LIMITER_MAX_DELAY":  "500ms",
+			},
+			wantErr: true,
+			errMsg:  "RATE_LIMITER_MAX_DELAY must not be less than RATE_LIMITER_BASE_DELAY",
+		},
+		{
+			name: "invalid MAX_DELETIONS_PER_SYNC",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+				"MAX_DELETIONS_PER_SYNC": "lots",
+			},
+			wantErr: true,
+			errMsg:  "MAX_DELETIONS_PER_SYNC is not a valid integer",
+		},
+		{
+			name: "record quotas",
+			envVars: map[string]string{
+				"PIHOLE_URL":                 "http://192.168.1.2",
+				"PIHOLE_PASSWORD":            "test-password",
+				"DEFAULT_TARGET_IP":          "192.168.1.100",
+				"MAX_MANAGED_RECORDS":        "500",
+				"RECORD_QUOTA_PER_NAMESPACE": "50",
+				"MAX_DELETIONS_PER_SYNC":     "10",
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative MAX_MANAGED_RECORDS",
+			envVars: map[string]string{
+				"PIHOLE_URL":          "http://192.168.1.2",
+				"PIHOLE_PASSWORD":     "test-password",
+				"DEFAULT_TARGET_IP":   "192.168.1.100",
+				"MAX_MANAGED_RECORDS": "-1",
+			},
+			wantErr: true,
+			errMsg:  "MAX_MANAGED_RECORDS must not be negative",
+		},
+		{
+			name: "invalid RECORD_QUOTA_PER_NAMESPACE",
+			envVars: map[string]string{
+				"PIHOLE_URL":                 "http://192.168.1.2",
+				"PIHOLE_PASSWORD":            "test-password",
+				"DEFAULT_TARGET_IP":          "192.168.1.100",
+				"RECORD_QUOTA_PER_NAMESPACE": "ten",
+			},
+			wantErr: true,
+			errMsg:  "RECORD_QUOTA_PER_NAMESPACE is not a valid integer",
+		},
+		{
+			name: "negative RECORD_QUOTA_PER_NAMESPACE",
+			envVars: map[string]string{
+				"PIHOLE_URL":                 "http://192.168.1.2",
+				"PIHOLE_PASSWORD":            "test-password",
+				"DEFAULT_TARGET_IP":          "192.168.1.100",
+				"RECORD_QUOTA_PER_NAMESPACE": "-5",
+			},
+			wantErr: true,
+			errMsg:  "RECORD_QUOTA_PER_NAMESPACE must not be negative",
+		},
+		{
+			name: "RECORD_QUOTA_PER_NAMESPACE above MAX_MANAGED_RECORDS",
+			envVars: map[string]string{
+				"PIHOLE_URL":                 "http://192.168.1.2",
+				"PIHOLE_PASSWORD":            "test-password",
+				"DEFAULT_TARGET_IP":          "192.168.1.100",
+				"MAX_MANAGED_RECORDS":        "10",
+				"RECORD_QUOTA_PER_NAMESPACE": "20",
+			},
+			wantErr: true,
+			errMsg:  "RECORD_QUOTA_PER_NAMESPACE must not exceed MAX_MANAGED_RECORDS",
+		},
+		{
+			name: "negative MAX_DELETIONS_PER_INTERVAL",
+			envVars: map[string]string{
+				"PIHOLE_URL":                 "http://192.168.1.2",
+				"PIHOLE_PASSWORD":            "test-password",
+				"DEFAULT_TARGET_IP":          "192.168.1.100",
+				"MAX_DELETIONS_PER_INTERVAL": "-1",
+			},
+			wantErr: true,
+			errMsg:  "MAX_DELETIONS_PER_INTERVAL must not be negative",
+		},
+		{
+			name: "invalid REQUIRE_INGRESS_READY",
+			envVars: map[string]string{
+				"PIHOLE_URL":            "http://192.168.1.2",
+				"PIHOLE_PASSWORD":       "test-password",
+				"DEFAULT_TARGET_IP":     "192.168.1.100",
+				"REQUIRE_INGRESS_READY": "maybe",
+			},
+			wantErr: true,
+			errMsg:  "REQUIRE_INGRESS_READY is not a valid boolean",
+		},
+		{
+			name: "invalid REQUIRE_REGISTER_LABEL",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+				"REQUIRE_REGISTER_LABEL": "maybe",
+			},
+			wantErr: true,
+			errMsg:  "REQUIRE_REGISTER_LABEL is not a valid boolean",
+		},
+		{
+			name: "STRIP_FINALIZERS with finalizers enabled",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"STRIP_FINALIZERS":  "true",
+			},
+			wantErr: true,
+			errMsg:  "STRIP_FINALIZERS requires ENABLE_FINALIZERS=false",
+		},
+		{
+			name: "STRIP_FINALIZERS with finalizers disabled",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"ENABLE_FINALIZERS": "false",
+				"STRIP_FINALIZERS":  "true",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid SYNC_POLICY",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"SYNC_POLICY":       "delete-only",
+			},
+			wantErr: true,
+			errMsg:  "SYNC_POLICY must be one of: sync, upsert-only, create-only",
+		},
+		{
+			name: "password from a Secret",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD_SECRET": "pihole-operator/pihole-operator-secret#PIHOLE_PASSWORD",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+			},
+			wantErr: false,
+		},
+		{
+			name: "PIHOLE_PASSWORD_SECRET with PIHOLE_PASSWORD",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"PIHOLE_PASSWORD_SECRET": "pihole-operator/pihole-operator-secret#PIHOLE_PASSWORD",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+			},
+			wantErr: true,
+			errMsg:  "PIHOLE_PASSWORD_SECRET cannot be combined with PIHOLE_PASSWORD or PIHOLE_PASSWORD_FILE",
+		},
+		{
+			name: "PIHOLE_PASSWORD_SECRET without key",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD_SECRET": "pihole-operator/pihole-operator-secret",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+			},
+			wantErr: true,
+			errMsg:  "PIHOLE_PASSWORD_SECRET must be namespace/name#key",
+		},
+		{
+			name: "PIHOLE_PASSWORD_SECRET without namespace",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD_SECRET": "pihole-operator-secret#PIHOLE_PASSWORD",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+			},
+			wantErr: true,
+			errMsg:  "PIHOLE_PASSWORD_SECRET must be namespace/name#key",
+		},
+		{
+			name: "invalid CONFLICT_POLICY",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"CONFLICT_POLICY":   "newest-wins",
+			},
+			wantErr: true,
+			errMsg:  "CONFLICT_POLICY must be one of: strict, oldest-wins",
+		},
+		{
+			name: "invalid HOSTS_MODE",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"HOSTS_MODE":        "append",
+			},
+			wantErr: true,
+			errMsg:  "HOSTS_MODE must be one of: replace, merge",
+		},
+		{
+			name: "invalid CLUSTER_ID",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"CLUSTER_ID":        "Prod.Cluster",
+			},
+			wantErr: true,
+			errMsg:  `CLUSTER_ID must be a DNS label of lowercase letters, digits and hyphens: "Prod.Cluster"`,
+		},
+		{
+			name: "AUDIT_CONFIGMAP without namespace",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"AUDIT_CONFIGMAP":   "pihole-operator-audit",
+			},
+			wantErr: true,
+			errMsg:  "AUDIT_CONFIGMAP requires REGISTRY_NAMESPACE or POD_NAMESPACE",
+		},
+		{
+			name: "RECORDS_CONFIGMAP without namespace",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"RECORDS_CONFIGMAP": "pihole-operator-records",
+			},
+			wantErr: true,
+			errMsg:  "RECORDS_CONFIGMAP requires RECORDS_CONFIGMAP_NAMESPACE, REGISTRY_NAMESPACE or POD_NAMESPACE",
+		},
+		{
+			name: "invalid NOTIFY_EVENTS",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"NOTIFY_URL":        "https://ntfy.sh/homelab",
+				"NOTIFY_EVENTS":     "create,rename",
+			},
+			wantErr: true,
+			errMsg:  "NOTIFY_EVENTS may only contain: create, update, delete",
+		},
+		{
+			name: "invalid SOURCES",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"SOURCES":           "ingress,httproute",
+			},
+			wantErr: true,
+			errMsg:  "SOURCES may only contain: ingress, domainmapping",
+		},
+		{
+			name: "SOURCES is case-insensitive",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"SOURCES":           "DomainMapping",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid NOTIFY_FORMAT",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"NOTIFY_FORMAT":     "slack",
+			},
+			wantErr: true,
+			errMsg:  "NOTIFY_FORMAT must be one of: json, ntfy",
+		},
+		{
+			name: "negative FINALIZER_MAX_ATTEMPTS",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+				"FINALIZER_MAX_ATTEMPTS": "-1",
+			},
+			wantErr: true,
+			errMsg:  "FINALIZER_MAX_ATTEMPTS must not be negative",
+		},
+		{
+			name: "invalid FINALIZER_TIMEOUT",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"FINALIZER_TIMEOUT": "soon",
+			},
+			wantErr: true,
+			errMsg:  "FINALIZER_TIMEOUT is not a valid duration",
+		},
+		{
+			name: "negative LOG_DEDUP_WINDOW",
+			envVars: map[string]string{
+				"PIHOLE_URL":        "http://192.168.1.2",
+				"PIHOLE_PASSWORD":   "test-password",
+				"DEFAULT_TARGET_IP": "192.168.1.100",
+				"LOG_DEDUP_WINDOW":  "-1s",
+			},
+			wantErr: true,
+			errMsg:  "LOG_DEDUP_WINDOW must not be negative",
+		},
+		{
+			name: "negative HEARTBEAT_STALENESS",
+			envVars: map[string]string{
+				"PIHOLE_URL":          "http://192.168.1.2",
+				"PIHOLE_PASSWORD":     "test-password",
+				"DEFAULT_TARGET_IP":   "192.168.1.100",
+				"HEARTBEAT_STALENESS": "-1m",
+			},
+			wantErr: true,
+			errMsg:  "HEARTBEAT_STALENESS must not be negative",
+		},
+		{
+			name: "negative STUCK_DELETION_WARNING",
+			envVars: map[string]string{
+				"PIHOLE_URL":             "http://192.168.1.2",
+				"PIHOLE_PASSWORD":        "test-password",
+				"DEFAULT_TARGET_IP":      "192.168.1.100",
+				"STUCK_DELETION_WARNING": "-1m",
 			},
 			wantErr: true,
-			errMsg:  "DEFAULT_TARGET_IP is not a valid IPv4 address:",
+			errMsg:  "STUCK_DELETION_WARNING must not be negative",
 		},
 		{
-			name: "invalid LOG_LEVEL",
+			name: "invalid metrics prefix",
 			envVars: map[string]string{
 				"PIHOLE_URL":        "http://192.168.1.2",
 				"PIHOLE_PASSWORD":   "test-password",
 				"DEFAULT_TARGET_IP": "192.168.1.100",
-				"LOG_LEVEL":         "invalid",
+				"METRICS_PREFIX":    "homelab-dns",
 			},
 			wantErr: true,
-			errMsg:  "LOG_LEVEL must be one of: debug, info, warn, error",
+			errMsg:  "METRICS_PREFIX must be letters, digits and underscores",
+		},
+		{
+			name: "malformed metrics const labels",
+			envVars: map[string]string{
+				"PIHOLE_URL":           "http://192.168.1.2",
+				"PIHOLE_PASSWORD":      "test-password",
+				"DEFAULT_TARGET_IP":    "192.168.1.100",
+				"METRICS_CONST_LABELS": "cluster",
+			},
+			wantErr: true,
+			errMsg:  "METRICS_CONST_LABELS must be a list of key=value pairs",
+		},
+		{
+			name: "invalid metrics const label name",
+			envVars: map[string]string{
+				"PIHOLE_URL":           "http://192.168.1.2",
+				"PIHOLE_PASSWORD":      "test-password",
+				"DEFAULT_TARGET_IP":    "192.168.1.100",
+				"METRICS_CONST_LABELS": "k8s-cluster=home",
+			},
+			wantErr: true,
+			errMsg:  "METRICS_CONST_LABELS has an invalid label name",
+		},
+		{
+			name: "pprof sharing the probe listener",
+			envVars: map[string]string{
+				"PIHOLE_URL":         "http://192.168.1.2",
+				"PIHOLE_PASSWORD":    "test-password",
+				"DEFAULT_TARGET_IP":  "192.168.1.100",
+				"PPROF_BIND_ADDRESS": ":8081",
+			},
+			wantErr: true,
+			errMsg:  "PPROF_BIND_ADDRESS must differ from HEALTH_PROBE_BIND_ADDRESS",
 		},
 	}
 
@@ -113,7 +810,7 @@ func TestLoad(t *testing.T) {
 				t.Setenv(k, v)
 			}
 
-			cfg, err := Load()
+			cfg, err := Load("", nil)
 
 			if tt.wantErr {
 				if err == nil {
@@ -156,7 +853,7 @@ func TestLoadDefaults(t *testing.T) {
 	t.Setenv("PIHOLE_PASSWORD", "test-password")
 	t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
 
-	cfg, err := Load()
+	cfg, err := Load("", nil)
 	if err != nil {
 		t.Fatalf("Load() unexpected error: %v", err)
 	}
@@ -165,9 +862,542 @@ func TestLoadDefaults(t *testing.T) {
 		t.Errorf("LogLevel default = %q, want %q", cfg.LogLevel, "info")
 	}
 
+	if cfg.LogFormat != DefaultLogFormat {
+		t.Errorf("LogFormat default = %q, want %q", cfg.LogFormat, DefaultLogFormat)
+	}
+
+	if cfg.StartupPiholeCheck != DefaultStartupPiholeCheck || cfg.StartupPiholeTimeout != DefaultStartupPiholeTimeout {
+		t.Errorf("startup check defaults = %q, %v, want %q, %v", cfg.StartupPiholeCheck, cfg.StartupPiholeTimeout,
+			DefaultStartupPiholeCheck, DefaultStartupPiholeTimeout)
+	}
+
 	if cfg.WatchNamespace != "" {
 		t.Errorf("WatchNamespace default = %q, want empty", cfg.WatchNamespace)
 	}
+
+	if cfg.MaxDeletionsPerSync != 0 || cfg.MaxDeletionsPerInterval != 0 {
+		t.Errorf("deletion limits default = %d/%d, want unlimited", cfg.MaxDeletionsPerSync, cfg.MaxDeletionsPerInterval)
+	}
+	if cfg.MaxManagedRecords != 0 || cfg.RecordQuotaPerNamespace != 0 {
+		t.Errorf("record quotas default = %d/%d, want unlimited", cfg.MaxManagedRecords, cfg.RecordQuotaPerNamespace)
+	}
+
+	if cfg.RequireIngressReady {
+		t.Error("RequireIngressReady default = true, want false")
+	}
+
+	if cfg.IngressReadyGracePeriod != DefaultIngressReadyGracePeriod {
+		t.Errorf("IngressReadyGracePeriod default = %v, want %v", cfg.IngressReadyGracePeriod, DefaultIngressReadyGracePeriod)
+	}
+
+	if cfg.RequeueIntervalError != DefaultRequeueIntervalError || cfg.RequeueIntervalConflict != DefaultRequeueIntervalConflict {
+		t.Errorf("requeue intervals default = %v/%v, want %v/%v", cfg.RequeueIntervalError, cfg.RequeueIntervalConflict,
+			DefaultRequeueIntervalError, DefaultRequeueIntervalConflict)
+	}
+	if cfg.MinRequeueAfter != DefaultMinRequeueAfter {
+		t.Errorf("MinRequeueAfter default = %v, want %v", cfg.MinRequeueAfter, DefaultMinRequeueAfter)
+	}
+
+	if !cfg.EnableFinalizers || cfg.StripFinalizers {
+		t.Errorf("finalizer defaults = enable %v strip %v, want true/false", cfg.EnableFinalizers, cfg.StripFinalizers)
+	}
+
+	if cfg.FinalizerTimeout != DefaultFinalizerTimeout || cfg.FinalizerMaxAttempts != 0 {
+		t.Errorf("finalizer limits = %v/%d, want %v/0", cfg.FinalizerTimeout, cfg.FinalizerMaxAttempts, DefaultFinalizerTimeout)
+	}
+
+	if cfg.SyncPolicy != DefaultSyncPolicy {
+		t.Errorf("SyncPolicy default = %q, want %q", cfg.SyncPolicy, DefaultSyncPolicy)
+	}
+
+	if cfg.ConflictPolicy != DefaultConflictPolicy {
+		t.Errorf("ConflictPolicy default = %q, want %q", cfg.ConflictPolicy, DefaultConflictPolicy)
+	}
+
+	if cfg.HostsMode != DefaultHostsMode {
+		t.Errorf("HostsMode default = %q, want %q", cfg.HostsMode, DefaultHostsMode)
+	}
+
+	if len(cfg.Sources) != len(DefaultSources) {
+		t.Errorf("Sources default = %v, want %v", cfg.Sources, DefaultSources)
+	}
+
+	if !cfg.DefaultOverwrite {
+		t.Error("DefaultOverwrite default = false, want true")
+	}
+	if cfg.AdoptExisting {
+		t.Error("AdoptExisting default = true, want false")
+	}
+	if cfg.AllowCrossNamespaceDuplicates {
+		t.Error("AllowCrossNamespaceDuplicates default = true, want false")
+	}
+
+	if !cfg.FilterInternalHosts {
+		t.Error("FilterInternalHosts default = false, want true")
+	}
+
+	if len(cfg.InternalHostSuffixes) != len(DefaultInternalHostSuffixes) {
+		t.Errorf("InternalHostSuffixes default = %v, want %v", cfg.InternalHostSuffixes, DefaultInternalHostSuffixes)
+	}
+
+	if cfg.RetryMaxBackoff != DefaultRetryMaxBackoff {
+		t.Errorf("RetryMaxBackoff default = %v, want %v", cfg.RetryMaxBackoff, DefaultRetryMaxBackoff)
+	}
+
+	if cfg.RateLimiterBaseDelay != DefaultRateLimiterBaseDelay || cfg.RateLimiterMaxDelay != DefaultRateLimiterMaxDelay ||
+		cfg.RateLimiterQPS != DefaultRateLimiterQPS || cfg.RateLimiterBurst != DefaultRateLimiterBurst {
+		t.Errorf("rate limiter defaults = %v/%v/%v/%d, want %v/%v/%v/%d",
+			cfg.RateLimiterBaseDelay, cfg.RateLimiterMaxDelay, cfg.RateLimiterQPS, cfg.RateLimiterBurst,
+			DefaultRateLimiterBaseDelay, DefaultRateLimiterMaxDelay, DefaultRateLimiterQPS, DefaultRateLimiterBurst)
+	}
+
+	if cfg.ListingMaxAge != DefaultListingMaxAge {
+		t.Errorf("ListingMaxAge default = %v, want %v", cfg.ListingMaxAge, DefaultListingMaxAge)
+	}
+
+	if cfg.WipeThreshold != DefaultWipeThreshold || cfg.WipeMinRecords != DefaultWipeMinRecords ||
+		cfg.RestoreMaxPerMinute != DefaultRestoreMaxPerMinute {
+		t.Errorf("wipe defaults = %v/%d/%d, want %v/%d/%d", cfg.WipeThreshold, cfg.WipeMinRecords, cfg.RestoreMaxPerMinute,
+			DefaultWipeThreshold, DefaultWipeMinRecords, DefaultRestoreMaxPerMinute)
+	}
+
+	if cfg.Selector() != nil {
+		t.Errorf("Selector() default = %v, want nil", cfg.Selector())
+	}
+
+	if cfg.RecordsConfigMap != "" || cfg.RecordsConfigMapInterval != DefaultRecordsConfigMapInterval {
+		t.Errorf("records configmap defaults = %q/%v, want disabled/%v", cfg.RecordsConfigMap, cfg.RecordsConfigMapInterval,
+			DefaultRecordsConfigMapInterval)
+	}
+
+	if cfg.StatusResource != "" || cfg.StatusInterval != DefaultStatusInterval {
+		t.Errorf("status defaults = %q/%v, want disabled/%v", cfg.StatusResource, cfg.StatusInterval, DefaultStatusInterval)
+	}
+
+	if cfg.MetricsPrefix != DefaultMetricsPrefix || cfg.MetricsConstLabels != nil {
+		t.Errorf("metrics defaults = %q/%v, want %q/none", cfg.MetricsPrefix, cfg.MetricsConstLabels, DefaultMetricsPrefix)
+	}
+}
+
+func TestLoadInternalHostSuffixes(t *testing.T) {
+	os.Clearenv()
+	t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+	t.Setenv("PIHOLE_PASSWORD", "test-password")
+	t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+	t.Setenv("INTERNAL_HOST_SUFFIXES", " .internal , .corp,, ")
+	t.Setenv("FILTER_INTERNAL_HOSTS", "false")
+
+	cfg, err := Load("", nil)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	if cfg.FilterInternalHosts {
+		t.Error("FilterInternalHosts = true, want false")
+	}
+	if len(cfg.InternalHostSuffixes) != 2 || cfg.InternalHostSuffixes[0] != ".internal" || cfg.InternalHostSuffixes[1] != ".corp" {
+		t.Errorf("InternalHostSuffixes = %v, want [.internal .corp]", cfg.InternalHostSuffixes)
+	}
+}
+
+func TestLoadMetricsSettings(t *testing.T) {
+	os.Clearenv()
+	t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+	t.Setenv("PIHOLE_PASSWORD", "test-password")
+	t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+	t.Setenv("METRICS_PREFIX", "homelab_dns_")
+	t.Setenv("METRICS_CONST_LABELS", " cluster = home ,, env=lab")
+
+	cfg, err := Load("", nil)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	if cfg.MetricsPrefix != "homelab_dns" {
+		t.Errorf("MetricsPrefix = %q, want %q", cfg.MetricsPrefix, "homelab_dns")
+	}
+	want := map[string]string{"cluster": "home", "env": "lab"}
+	if !maps.Equal(cfg.MetricsConstLabels, want) {
+		t.Errorf("MetricsConstLabels = %v, want %v", cfg.MetricsConstLabels, want)
+	}
+}
+
+func TestLoadPasswordFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() unexpected error: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		password string
+		want     string
+		errMsg   string
+	}{
+		{name: "trailing newline", file: write("newline", "s3cret\n"), want: "s3cret"},
+		{name: "surrounding whitespace", file: write("spaces", "  s3cret \r\n\n"), want: "s3cret"},
+		{name: "inner whitespace kept", file: write("inner", "correct horse\n"), want: "correct horse"},
+		{name: "empty file", file: write("empty", " \n"), errMsg: "is empty"},
+		{name: "missing file", file: filepath.Join(dir, "missing"), errMsg: "reading PIHOLE_PASSWORD_FILE"},
+		{name: "both set", file: write("both", "s3cret"), password: "other", errMsg: "mutually exclusive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+			t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+			t.Setenv("PIHOLE_PASSWORD_FILE", tt.file)
+			if tt.password != "" {
+				t.Setenv("PIHOLE_PASSWORD", tt.password)
+			}
+
+			cfg, err := Load("", nil)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Load() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if cfg.PiholePassword != tt.want {
+				t.Errorf("PiholePassword = %q, want %q", cfg.PiholePassword, tt.want)
+			}
+		})
+	}
+}
+
+// TestTargetSourceValidation covers which combinations of TARGET_SOURCE and
+// DEFAULT_TARGET_IP are accepted
+func TestTargetSourceValidation(t *testing.T) {
+	tests := []struct {
+		source string
+		ip     string
+		want   string
+		errMsg string
+	}{
+		{source: "", ip: "192.168.1.100", want: "static"},
+		{source: "", ip: "", errMsg: "DEFAULT_TARGET_IP is required"},
+		{source: "static", ip: "192.168.1.100", want: "static"},
+		{source: "static", ip: "", errMsg: "DEFAULT_TARGET_IP is required"},
+		{source: "status", ip: "", want: "status"},
+		{source: "Status", ip: "192.168.1.100", want: "status"},
+		{source: "status", ip: "not-an-ip", errMsg: "DEFAULT_TARGET_IP is not a valid IPv4 address"},
+		{source: "service", ip: "192.168.1.100", errMsg: "TARGET_SOURCE must be one of: static, status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source+"/"+tt.ip, func(t *testing.T) {
+			os.Clearenv()
+			t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+			t.Setenv("PIHOLE_PASSWORD", "test-password")
+			t.Setenv("TARGET_SOURCE", tt.source)
+			t.Setenv("DEFAULT_TARGET_IP", tt.ip)
+
+			cfg, err := Load("", nil)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Load() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if cfg.TargetSource != tt.want {
+				t.Errorf("TargetSource = %q, want %q", cfg.TargetSource, tt.want)
+			}
+		})
+	}
+}
+
+// TestTargetFamilyValidation covers the combinations of DEFAULT_TARGET_IP,
+// DEFAULT_TARGET_IPV6 and ENABLE_AAAA
+func TestTargetFamilyValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		ip         string
+		ipv6       string
+		enableAAAA string
+		errMsg     string
+	}{
+		{name: "v4 only", ip: "192.168.1.100"},
+		{name: "v6 only", ipv6: "fd00::10"},
+		{name: "dual stack", ip: "192.168.1.100", ipv6: "fd00::10"},
+		{name: "neither", errMsg: "DEFAULT_TARGET_IP is required unless DEFAULT_TARGET_IPV6 is set"},
+		{name: "v4 only with AAAA disabled", ip: "192.168.1.100", enableAAAA: "false"},
+		{name: "v6 default with AAAA disabled", ip: "192.168.1.100", ipv6: "fd00::10", enableAAAA: "false",
+			errMsg: "DEFAULT_TARGET_IPV6 requires ENABLE_AAAA"},
+		{name: "v6 only with AAAA disabled", ipv6: "fd00::10", enableAAAA: "false",
+			errMsg: "DEFAULT_TARGET_IPV6 requires ENABLE_AAAA"},
+		{name: "v4 address as v6 default", ip: "192.168.1.100", ipv6: "192.168.1.101",
+			errMsg: "DEFAULT_TARGET_IPV6 is not a valid IPv6 address"},
+		{name: "invalid v6 default", ipv6: "not-an-ip", errMsg: "DEFAULT_TARGET_IPV6 is not a valid IPv6 address"},
+		{name: "v6 address as v4 default", ip: "fd00::10", errMsg: "DEFAULT_TARGET_IP is not a valid IPv4 address"},
+		{name: "invalid ENABLE_AAAA", ip: "192.168.1.100", enableAAAA: "maybe", errMsg: "ENABLE_AAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+			t.Setenv("PIHOLE_PASSWORD", "test-password")
+			t.Setenv("DEFAULT_TARGET_IP", tt.ip)
+			t.Setenv("DEFAULT_TARGET_IPV6", tt.ipv6)
+			if tt.enableAAAA != "" {
+				t.Setenv("ENABLE_AAAA", tt.enableAAAA)
+			}
+
+			cfg, err := Load("", nil)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Load() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if want := tt.enableAAAA != "false"; cfg.EnableAAAA != want {
+				t.Errorf("EnableAAAA = %v, want %v", cfg.EnableAAAA, want)
+			}
+		})
+	}
+}
+
+func TestLeaderElectionValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		errMsg string
+	}{
+		{name: "defaults"},
+		{name: "slower failover", env: map[string]string{
+			"LEADER_ELECTION_LEASE_DURATION": "60s",
+			"LEADER_ELECTION_RENEW_DEADLINE": "40s",
+			"LEADER_ELECTION_RETRY_PERIOD":   "5s",
+		}},
+		{name: "lease not longer than renew deadline", env: map[string]string{"LEADER_ELECTION_LEASE_DURATION": "10s"},
+			errMsg: "LEADER_ELECTION_LEASE_DURATION must be longer than LEADER_ELECTION_RENEW_DEADLINE"},
+		{name: "renew deadline within a jittered retry", env: map[string]string{"LEADER_ELECTION_RETRY_PERIOD": "9s"},
+			errMsg: "LEADER_ELECTION_RENEW_DEADLINE must be more than 1.2 times LEADER_ELECTION_RETRY_PERIOD"},
+		{name: "zero retry period", env: map[string]string{"LEADER_ELECTION_RETRY_PERIOD": "0s"},
+			errMsg: "LEADER_ELECTION_RETRY_PERIOD must be a positive duration"},
+		{name: "removed lock type", env: map[string]string{"LEADER_ELECTION_RESOURCE_LOCK": "configmapsleases"},
+			errMsg: "LEADER_ELECTION_RESOURCE_LOCK must be leases"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+			t.Setenv("PIHOLE_PASSWORD", "test-password")
+			t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load("", nil)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Load() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if cfg.LeaderElectionResourceLock != "leases" || !cfg.LeaderElectionReleaseOnCancel {
+				t.Errorf("LeaderElectionResourceLock, LeaderElectionReleaseOnCancel = %q, %v, want leases, true",
+					cfg.LeaderElectionResourceLock, cfg.LeaderElectionReleaseOnCancel)
+			}
+		})
+	}
+}
+
+func TestLoadAdminTokenFile(t *testing.T) {
+	os.Clearenv()
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("t0ken\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+	t.Setenv("PIHOLE_PASSWORD", "test-password")
+	t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+	t.Setenv("ADMIN_TOKEN_FILE", path)
+
+	cfg, err := Load("", nil)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if cfg.AdminToken != "t0ken" {
+		t.Errorf("AdminToken = %q, want the file's t0ken", cfg.AdminToken)
+	}
+
+	t.Setenv("ADMIN_TOKEN", "other")
+	if _, err := Load("", nil); err == nil || !strings.Contains(err.Error(), "ADMIN_TOKEN and ADMIN_TOKEN_FILE are mutually exclusive") {
+		t.Errorf("Load() error = %v, want the variables mutually exclusive", err)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() unexpected error: %v", err)
+		}
+		return path
+	}
+	base := "piholeURL: http://192.168.1.2\npiholePassword: s3cret\ndefaultTargetIP: 192.168.1.100\n"
+
+	tests := []struct {
+		name    string
+		file    string
+		envVars map[string]string
+		check   func(t *testing.T, cfg *Config)
+		errMsg  string
+	}{
+		{
+			name: "typed values",
+			file: write("typed", base+`retryMaxBackoff: 90s
+maxConcurrentReconciles: 4
+rateLimiterQPS: 2.5
+defaultOverwrite: false
+ingressClasses: [nginx, traefik]
+internalHostSuffixes:
+  - .internal
+`),
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.RetryMaxBackoff != 90*time.Second {
+					t.Errorf("RetryMaxBackoff = %v, want 90s", cfg.RetryMaxBackoff)
+				}
+				if cfg.MaxConcurrentReconciles != 4 || cfg.RateLimiterQPS != 2.5 || cfg.DefaultOverwrite {
+					t.Errorf("MaxConcurrentReconciles, RateLimiterQPS, DefaultOverwrite = %d, %v, %v, want 4, 2.5, false",
+						cfg.MaxConcurrentReconciles, cfg.RateLimiterQPS, cfg.DefaultOverwrite)
+				}
+				if !slices.Equal(cfg.IngressClasses, []string{"nginx", "traefik"}) {
+					t.Errorf("IngressClasses = %v, want [nginx traefik]", cfg.IngressClasses)
+				}
+				if !slices.Equal(cfg.InternalHostSuffixes, []string{".internal"}) {
+					t.Errorf("InternalHostSuffixes = %v, want [.internal]", cfg.InternalHostSuffixes)
+				}
+			},
+		},
+		{
+			name: "defaults for unset keys",
+			file: write("defaults", base),
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.RetryMaxBackoff != DefaultRetryMaxBackoff || !cfg.DefaultOverwrite || cfg.LogLevel != "info" {
+					t.Errorf("RetryMaxBackoff, DefaultOverwrite, LogLevel = %v, %v, %q, want defaults",
+						cfg.RetryMaxBackoff, cfg.DefaultOverwrite, cfg.LogLevel)
+				}
+				if !slices.Equal(cfg.Sources, DefaultSources) {
+					t.Errorf("Sources = %v, want %v", cfg.Sources, DefaultSources)
+				}
+			},
+		},
+		{
+			name:    "env overrides file",
+			file:    write("override", base+"logLevel: debug\nretryMaxBackoff: 90s\ningressClasses: [nginx]\ndefaultOverwrite: false\n"),
+			envVars: map[string]string{"LOG_LEVEL": "warn", "RETRY_MAX_BACKOFF": "2m", "INGRESS_CLASSES": "traefik", "DEFAULT_OVERWRITE": "true"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.LogLevel != "warn" || cfg.RetryMaxBackoff != 2*time.Minute || !cfg.DefaultOverwrite {
+					t.Errorf("LogLevel, RetryMaxBackoff, DefaultOverwrite = %q, %v, %v, want warn, 2m, true",
+						cfg.LogLevel, cfg.RetryMaxBackoff, cfg.DefaultOverwrite)
+				}
+				if !slices.Equal(cfg.IngressClasses, []string{"traefik"}) {
+					t.Errorf("IngressClasses = %v, want [traefik]", cfg.IngressClasses)
+				}
+			},
+		},
+		{
+			name:    "empty env keeps file",
+			file:    write("empty-env", base+"logLevel: debug\n"),
+			envVars: map[string]string{"LOG_LEVEL": ""},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.LogLevel != "debug" {
+					t.Errorf("LogLevel = %q, want debug", cfg.LogLevel)
+				}
+			},
+		},
+		{
+			name:    "env completes file",
+			file:    write("partial", "piholeURL: http://192.168.1.2\ndefaultTargetIP: 192.168.1.100\n"),
+			envVars: map[string]string{"PIHOLE_PASSWORD": "from-env"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.PiholePassword != "from-env" {
+					t.Errorf("PiholePassword = %q, want from-env", cfg.PiholePassword)
+				}
+			},
+		},
+		{
+			name:   "file values are validated",
+			file:   write("invalid", base+"syncPolicy: delete-all\n"),
+			errMsg: "SYNC_POLICY must be one of",
+		},
+		{
+			name:   "unknown key",
+			file:   write("unknown", base+"retryMaxBackof: 90s\n"),
+			errMsg: "field retryMaxBackof not found",
+		},
+		{
+			name:   "invalid duration",
+			file:   write("duration", base+"retryMaxBackoff: soon\n"),
+			errMsg: "parsing config file",
+		},
+		{
+			name:   "missing file",
+			file:   filepath.Join(dir, "missing"),
+			errMsg: "reading config file",
+		},
+		{
+			name:    "empty file",
+			file:    write("empty", ""),
+			envVars: map[string]string{"PIHOLE_URL": "http://192.168.1.2", "PIHOLE_PASSWORD": "s3cret", "DEFAULT_TARGET_IP": "192.168.1.100"},
+			check:   func(t *testing.T, cfg *Config) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load(tt.file, nil)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Load() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestPasswordSecret(t *testing.T) {
+	cfg := &Config{PiholePasswordSecret: "pihole-operator/pihole-operator-secret#PIHOLE_PASSWORD"}
+	secret, key := cfg.Instances()[0].PasswordSecret()
+	if secret.Namespace != "pihole-operator" || secret.Name != "pihole-operator-secret" || key != "PIHOLE_PASSWORD" {
+		t.Errorf("PasswordSecret() = %v#%s, want pihole-operator/pihole-operator-secret#PIHOLE_PASSWORD", secret, key)
+	}
+
+	if secret, _ := (&Config{}).Instances()[0].PasswordSecret(); secret.Name != "" {
+		t.Errorf("PasswordSecret() unset = %v, want empty", secret)
+	}
 }
 
 func TestIsValidIPv4(t *testing.T) {
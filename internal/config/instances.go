@@ -0,0 +1,156 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PiholeInstance is one Pi-hole server the records are written to. Exactly one of
+// Password, PasswordFile and SecretRef supplies its password.
+type PiholeInstance struct {
+	Name     string `yaml:"name"`
+	URL      string `yaml:"url"`
+	Password string `yaml:"password"`
+
+	// PasswordFile is read into Password by Load, trimmed of surrounding whitespace
+	PasswordFile string `yaml:"passwordFile"`
+
+	// SecretRef names a Secret key holding the password as namespace/name#key; the
+	// password follows the Secret when it is rotated
+	SecretRef string `yaml:"secretRef"`
+
+	TLS PiholeTLS `yaml:"tls"`
+}
+
+// PiholeTLS tunes how the Pi-hole server's certificate is verified
+type PiholeTLS struct {
+	// CAFile is a PEM bundle trusted instead of the system roots
+	CAFile string `yaml:"caFile"`
+
+	// ServerName overrides the name the certificate is checked against
+	ServerName string `yaml:"serverName"`
+
+	// InsecureSkipVerify accepts any certificate, e.g. Pi-hole's self-signed default
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify"`
+}
+
+// Instances returns the configured Pi-hole servers: PIHOLE_INSTANCES, or one unnamed
+// instance built from PIHOLE_URL and its password
+func (c *Config) Instances() []PiholeInstance {
+	if len(c.PiholeInstances) > 0 {
+		return c.PiholeInstances
+	}
+	return []PiholeInstance{{
+		URL:       c.PiholeURL,
+		Password:  c.PiholePassword,
+		SecretRef: c.PiholePasswordSecret,
+	}}
+}
+
+// PasswordSecret returns SecretRef split into the Secret and its key; the name is
+// empty when the instance has no SecretRef
+func (i PiholeInstance) PasswordSecret() (types.NamespacedName, string) {
+	namespace, name, key, _ := parseSecretKeyRef(i.SecretRef)
+	return types.NamespacedName{Namespace: namespace, Name: name}, key
+}
+
+// ClientConfig returns the TLS configuration for the instance, or nil to use the
+// defaults
+func (t PiholeTLS) ClientConfig() (*tls.Config, error) {
+	if t == (PiholeTLS{}) {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s holds no PEM certificates", t.CAFile)
+		}
+	}
+	return cfg, nil
+}
+
+// parseInstances reads a JSON or YAML list of instances, as given in PIHOLE_INSTANCES;
+// name is where value came from, for errors
+func parseInstances(name, value string) ([]PiholeInstance, error) {
+	dec := yaml.NewDecoder(strings.NewReader(value))
+	dec.KnownFields(true)
+	var instances []PiholeInstance
+	if err := dec.Decode(&instances); err != nil {
+		return nil, fmt.Errorf("%s is not a valid list of instances: %w", name, err)
+	}
+	return instances, nil
+}
+
+// readInstancePasswords replaces the PasswordFile of each instance with its contents
+func (c *Config) readInstancePasswords() error {
+	for i := range c.PiholeInstances {
+		inst := &c.PiholeInstances[i]
+		if inst.PasswordFile == "" {
+			continue
+		}
+		if inst.Password != "" {
+			return fmt.Errorf("PIHOLE_INSTANCES %s: password and passwordFile are mutually exclusive", inst.Name)
+		}
+		data, err := os.ReadFile(inst.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("PIHOLE_INSTANCES %s: reading passwordFile: %w", inst.Name, err)
+		}
+		if inst.Password = strings.TrimSpace(string(data)); inst.Password == "" {
+			return fmt.Errorf("PIHOLE_INSTANCES %s: passwordFile %s is empty", inst.Name, inst.PasswordFile)
+		}
+	}
+	return nil
+}
+
+// validateInstances checks PIHOLE_INSTANCES: every instance needs a unique name once
+// there is more than one, an HTTP or HTTPS URL and exactly one password source
+func (c *Config) validateInstances() error {
+	if c.PiholeURL != "" || c.PiholePassword != "" || c.PiholePasswordSecret != "" {
+		return fmt.Errorf("PIHOLE_INSTANCES cannot be combined with PIHOLE_URL, PIHOLE_PASSWORD or PIHOLE_PASSWORD_SECRET")
+	}
+
+	names := make(map[string]bool, len(c.PiholeInstances))
+	for _, inst := range c.PiholeInstances {
+		if inst.Name == "" && len(c.PiholeInstances) > 1 {
+			return fmt.Errorf("PIHOLE_INSTANCES entries must each have a name")
+		}
+		if names[inst.Name] {
+			return fmt.Errorf("PIHOLE_INSTANCES name %s is used more than once", inst.Name)
+		}
+		names[inst.Name] = true
+
+		parsedURL, err := url.Parse(inst.URL)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			return fmt.Errorf("PIHOLE_INSTANCES %s: url must be an HTTP or HTTPS URL", inst.Name)
+		}
+
+		hasPassword := inst.Password != "" || inst.PasswordFile != ""
+		if hasPassword == (inst.SecretRef != "") {
+			return fmt.Errorf("PIHOLE_INSTANCES %s: set one of password, passwordFile or secretRef", inst.Name)
+		}
+		if inst.SecretRef != "" {
+			if _, _, _, ok := parseSecretKeyRef(inst.SecretRef); !ok {
+				return fmt.Errorf("PIHOLE_INSTANCES %s: secretRef must be namespace/name#key", inst.Name)
+			}
+		}
+		if inst.TLS.CAFile != "" && inst.TLS.InsecureSkipVerify {
+			return fmt.Errorf("PIHOLE_INSTANCES %s: tls caFile and insecureSkipVerify are mutually exclusive", inst.Name)
+		}
+	}
+	return nil
+}
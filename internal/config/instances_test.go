@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadInstances(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(passwordFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    []PiholeInstance
+		errMsg  string
+	}{
+		{
+			name:    "shorthand",
+			envVars: map[string]string{"PIHOLE_URL": "http://192.168.1.2", "PIHOLE_PASSWORD": "s3cret"},
+			want:    []PiholeInstance{{URL: "http://192.168.1.2", Password: "s3cret"}},
+		},
+		{
+			name: "json",
+			envVars: map[string]string{"PIHOLE_INSTANCES": `[
+				{"name": "primary", "url": "http://192.168.1.2", "password": "one"},
+				{"name": "secondary", "url": "https://192.168.1.3", "secretRef": "pihole/secondary#password",
+				 "tls": {"insecureSkipVerify": true}}]`},
+			want: []PiholeInstance{
+				{Name: "primary", URL: "http://192.168.1.2", Password: "one"},
+				{Name: "secondary", URL: "https://192.168.1.3", SecretRef: "pihole/secondary#password",
+					TLS: PiholeTLS{InsecureSkipVerify: true}},
+			},
+		},
+		{
+			name: "yaml with password file",
+			envVars: map[string]string{"PIHOLE_INSTANCES": `
+- name: primary
+  url: http://192.168.1.2
+  passwordFile: ` + passwordFile},
+			want: []PiholeInstance{{Name: "primary", URL: "http://192.168.1.2", Password: "from-file", PasswordFile: passwordFile}},
+		},
+		{
+			name:    "single unnamed instance",
+			envVars: map[string]string{"PIHOLE_INSTANCES": `[{"url": "http://192.168.1.2", "password": "one"}]`},
+			want:    []PiholeInstance{{URL: "http://192.168.1.2", Password: "one"}},
+		},
+		{
+			name:    "neither",
+			envVars: map[string]string{},
+			errMsg:  "PIHOLE_URL is required",
+		},
+		{
+			name: "combined with shorthand",
+			envVars: map[string]string{"PIHOLE_URL": "http://192.168.1.2",
+				"PIHOLE_INSTANCES": `[{"url": "http://192.168.1.3", "password": "one"}]`},
+			errMsg: "cannot be combined",
+		},
+		{
+			name: "duplicate names",
+			envVars: map[string]string{"PIHOLE_INSTANCES": `[{"name": "a", "url": "http://192.168.1.2", "password": "one"},
+				{"name": "a", "url": "http://192.168.1.3", "password": "two"}]`},
+			errMsg: "name a is used more than once",
+		},
+		{
+			name: "unnamed among several",
+			envVars: map[string]string{"PIHOLE_INSTANCES": `[{"name": "a", "url": "http://192.168.1.2", "password": "one"},
+				{"url": "http://192.168.1.3", "password": "two"}]`},
+			errMsg: "must each have a name",
+		},
+		{
+			name:    "no password",
+			envVars: map[string]string{"PIHOLE_INSTANCES": `[{"name": "a", "url": "http://192.168.1.2"}]`},
+			errMsg:  "set one of password, passwordFile or secretRef",
+		},
+		{
+			name: "two passwords",
+			envVars: map[string]string{"PIHOLE_INSTANCES": `[{"name": "a", "url": "http://192.168.1.2", "password": "one",
+				"secretRef": "pihole/a#password"}]`},
+			errMsg: "set one of password, passwordFile or secretRef",
+		},
+		{
+			name:    "invalid url",
+			envVars: map[string]string{"PIHOLE_INSTANCES": `[{"name": "a", "url": "192.168.1.2", "password": "one"}]`},
+			errMsg:  "a: url must be an HTTP or HTTPS URL",
+		},
+		{
+			name:    "unknown key",
+			envVars: map[string]string{"PIHOLE_INSTANCES": `[{"name": "a", "url": "http://192.168.1.2", "pasword": "one"}]`},
+			errMsg:  "field pasword not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load("", nil)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Load() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			got := cfg.Instances()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Instances() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Instances()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadInstancesFromFile(t *testing.T) {
+	os.Clearenv()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `defaultTargetIP: 192.168.1.100
+piholeInstances:
+  - name: primary
+    url: http://192.168.1.2
+    password: one
+  - name: secondary
+    url: https://pihole2.lan
+    password: two
+    tls:
+      serverName: pihole2
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+
+	cfg, err := Load(path, nil)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	got := cfg.Instances()
+	if len(got) != 2 || got[1].Name != "secondary" || got[1].TLS.ServerName != "pihole2" {
+		t.Errorf("Instances() = %+v, want primary and secondary with its server name", got)
+	}
+}
+
+func TestPiholeTLSClientConfig(t *testing.T) {
+	if cfg, err := (PiholeTLS{}).ClientConfig(); cfg != nil || err != nil {
+		t.Errorf("ClientConfig() unset = %v, %v, want nil", cfg, err)
+	}
+
+	cfg, err := PiholeTLS{ServerName: "pihole.lan", InsecureSkipVerify: true}.ClientConfig()
+	if err != nil || cfg.ServerName != "pihole.lan" || !cfg.InsecureSkipVerify {
+		t.Errorf("ClientConfig() = %+v, %v, want pihole.lan skipping verification", cfg, err)
+	}
+
+	bad := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(bad, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if _, err := (PiholeTLS{CAFile: bad}).ClientConfig(); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Errorf("ClientConfig() with a bad CA file error = %v, want no PEM certificates", err)
+	}
+}
@@ -0,0 +1,374 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// option is a setting given as an environment variable or as the flag named after it in
+// lower case with dashes, e.g. PIHOLE_URL and --pihole-url. A flag takes precedence over
+// the variable, which takes precedence over the config file.
+type option struct {
+	env   string
+	usage string
+
+	// set parses value into c; name is the variable or flag it came from, for errors
+	set func(c *Config, name, value string) error
+
+	// boolean options are given as a flag without a value
+	boolean bool
+
+	// secret options may also be read from the file named by the variable with _FILE
+	secret bool
+}
+
+// flagName returns the name of the option's flag
+func (o option) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(o.env), "_", "-")
+}
+
+// options lists every setting that can be given on the command line or in the environment
+var options = []option{
+	stringOption("PIHOLE_URL", "Base URL of the Pi-hole instance, e.g. http://192.168.1.2",
+		func(c *Config) *string { return &c.PiholeURL }),
+	secretOption("PIHOLE_PASSWORD", "Pi-hole web interface password",
+		func(c *Config) *string { return &c.PiholePassword }),
+	stringOption("PIHOLE_PASSWORD_SECRET", "Secret key holding the Pi-hole password, as namespace/name#key",
+		func(c *Config) *string { return &c.PiholePasswordSecret }),
+	{env: "PIHOLE_INSTANCES", usage: "JSON or YAML list of Pi-hole servers kept in step, instead of the URL and password",
+		set: func(c *Config, name, value string) (err error) {
+			c.PiholeInstances, err = parseInstances(name, value)
+			return err
+		}},
+	stringOption("STARTUP_PIHOLE_CHECK", "What to do when Pi-hole is unreachable at startup: warn, wait or fail",
+		func(c *Config) *string { return &c.StartupPiholeCheck }),
+	durationOption("STARTUP_PIHOLE_TIMEOUT", "How long a wait startup check waits for Pi-hole; 0 waits indefinitely",
+		func(c *Config) *time.Duration { return &c.StartupPiholeTimeout }),
+	intOption("READINESS_FAILURE_THRESHOLD", "Consecutive failed Pi-hole probes that make the operator unready",
+		func(c *Config) *int { return &c.ReadinessFailureThreshold }),
+	durationOption("READINESS_UNAVAILABLE_AFTER", "Time since the last successful Pi-hole probe that makes the operator unready; 0 disables it",
+		func(c *Config) *time.Duration { return &c.ReadinessUnavailableAfter }),
+	durationOption("HEARTBEAT_STALENESS", "Fail liveness when a controller with work queued has not finished a reconcile for this long; 0 disables it",
+		func(c *Config) *time.Duration { return &c.HeartbeatStaleness }),
+	stringOption("DEFAULT_TARGET_IP", "Default IP for DNS A records",
+		func(c *Config) *string { return &c.DefaultTargetIP }),
+	stringOption("TARGET_SOURCE", "Where target IPs come from: static or status",
+		func(c *Config) *string { return &c.TargetSource }),
+	stringOption("DEFAULT_TARGET_IPV6", "Default IP for DNS AAAA records",
+		func(c *Config) *string { return &c.DefaultTargetIPv6 }),
+	boolOption("ENABLE_AAAA", "Allow AAAA records",
+		func(c *Config) *bool { return &c.EnableAAAA }),
+	stringOption("DEFAULT_DOMAIN_SUFFIX", "Zone appended to hosts without a dot",
+		func(c *Config) *string { return &c.DefaultDomainSuffix }),
+	stringOption("LOG_LEVEL", "Log level: debug, info, warn or error",
+		func(c *Config) *string { return &c.LogLevel }),
+	stringOption("LOG_FORMAT", "Log format: json, text or console",
+		func(c *Config) *string { return &c.LogFormat }),
+	intOption("LOG_VERBOSITY", "Most verbose controller-runtime log level, V(n), that is logged",
+		func(c *Config) *int { return &c.LogVerbosity }),
+	durationOption("LOG_DEDUP_WINDOW", "Log an error repeating within this window once, with a count of the repeats; 0 logs every error",
+		func(c *Config) *time.Duration { return &c.LogDedupWindow }),
+	stringOption("METRICS_PREFIX", "Prefix of the operator metric names",
+		func(c *Config) *string { return &c.MetricsPrefix }),
+	mapOption("METRICS_CONST_LABELS", "Labels added to every operator metric, as comma-separated key=value pairs",
+		func(c *Config) *map[string]string { return &c.MetricsConstLabels }),
+	stringOption("METRICS_BIND_ADDRESS", "The address the metrics endpoint binds to. Use :8080 for HTTP, or 0 to disable.",
+		func(c *Config) *string { return &c.MetricsBindAddress }),
+	boolOption("METRICS_SECURE", "Serve metrics over HTTPS with authentication and authorization; false serves plain HTTP.",
+		func(c *Config) *bool { return &c.MetricsSecure }),
+	stringOption("METRICS_CERT_PATH", "Directory holding the metrics server certificate; empty generates a self-signed one.",
+		func(c *Config) *string { return &c.MetricsCertPath }),
+	stringOption("METRICS_CERT_NAME", "File name of the metrics server certificate.",
+		func(c *Config) *string { return &c.MetricsCertName }),
+	stringOption("METRICS_CERT_KEY", "File name of the metrics server key.",
+		func(c *Config) *string { return &c.MetricsCertKey }),
+	boolOption("ENABLE_HTTP2", "Enable HTTP/2 on the operator's TLS endpoints, such as the metrics server.",
+		func(c *Config) *bool { return &c.EnableHTTP2 }),
+	stringOption("HEALTH_PROBE_BIND_ADDRESS", "The address the probe endpoint binds to.",
+		func(c *Config) *string { return &c.HealthProbeBindAddress }),
+	stringOption("ADMIN_BIND_ADDRESS", "The address the admin endpoint binds to (requires ADMIN_TOKEN). Use 0 to disable.",
+		func(c *Config) *string { return &c.AdminBindAddress }),
+	stringOption("PPROF_BIND_ADDRESS", "The address the unauthenticated pprof endpoint binds to. Use 0 to disable.",
+		func(c *Config) *string { return &c.PprofBindAddress }),
+	boolOption("ENABLE_DEBUG_ENDPOINTS", "Serve GET /debug/records and GET /configz on the admin endpoint.",
+		func(c *Config) *bool { return &c.EnableDebugEndpoints }),
+	boolOption("LEADER_ELECT", "Enable leader election for controller manager.",
+		func(c *Config) *bool { return &c.LeaderElect }),
+	stringOption("LEADER_ELECTION_NAMESPACE", "Namespace of the leader election lease; empty uses the operator's namespace",
+		func(c *Config) *string { return &c.LeaderElectionNamespace }),
+	durationOption("LEADER_ELECTION_LEASE_DURATION", "How long a leader holds the lease without renewing it",
+		func(c *Config) *time.Duration { return &c.LeaderElectionLeaseDuration }),
+	durationOption("LEADER_ELECTION_RENEW_DEADLINE", "How long the leader keeps trying to renew before giving up leadership",
+		func(c *Config) *time.Duration { return &c.LeaderElectionRenewDeadline }),
+	durationOption("LEADER_ELECTION_RETRY_PERIOD", "How often candidates try to acquire or renew the lease",
+		func(c *Config) *time.Duration { return &c.LeaderElectionRetryPeriod }),
+	stringOption("LEADER_ELECTION_RESOURCE_LOCK", "Resource used as the leader election lock: leases",
+		func(c *Config) *string { return &c.LeaderElectionResourceLock }),
+	boolOption("LEADER_ELECTION_RELEASE_ON_CANCEL", "Release the lease on a graceful shutdown so another replica takes over at once",
+		func(c *Config) *bool { return &c.LeaderElectionReleaseOnCancel }),
+	durationOption("GRACEFUL_SHUTDOWN_TIMEOUT", "How long shutdown waits for controllers and in-flight Pi-hole calls; 0 stops at once",
+		func(c *Config) *time.Duration { return &c.GracefulShutdownTimeout }),
+	stringOption("WATCH_NAMESPACE", "Namespace to watch; empty watches all namespaces",
+		func(c *Config) *string { return &c.WatchNamespace }),
+	stringOption("SYNC_POLICY", "Changes made to Pi-hole: sync, upsert-only or create-only",
+		func(c *Config) *string { return &c.SyncPolicy }),
+	stringOption("CONFLICT_POLICY", "How objects wanting different IPs for one host are settled: strict or oldest-wins",
+		func(c *Config) *string { return &c.ConflictPolicy }),
+	stringOption("HOSTS_MODE", "Whether the pihole.io/hosts annotation replaces the spec hosts or is merged with them: replace or merge",
+		func(c *Config) *string { return &c.HostsMode }),
+	stringOption("CLUSTER_ID", "Cluster ID marked on the records created, so clusters sharing a Pi-hole leave each other's records alone",
+		func(c *Config) *string { return &c.ClusterID }),
+	listOption("SOURCES", "Comma-separated kinds to register: ingress, domainmapping",
+		func(c *Config) *[]string { return &c.Sources }),
+	listOption("INGRESS_CLASSES", "Comma-separated IngressClasses to consider; empty considers all",
+		func(c *Config) *[]string { return &c.IngressClasses }),
+	listOption("EXCLUDE_NAMESPACES", "Comma-separated namespaces never managed, by exact name or glob",
+		func(c *Config) *[]string { return &c.ExcludeNamespaces }),
+	stringOption("LABEL_SELECTOR", "Only consider objects matching this label selector",
+		func(c *Config) *string { return &c.LabelSelector }),
+	boolOption("REQUIRE_REGISTER_LABEL", "Only cache and register objects labelled pihole.io/register=true",
+		func(c *Config) *bool { return &c.RequireRegisterLabel }),
+	boolOption("DEFAULT_OVERWRITE", "Replace records that already exist in Pi-hole with a different IP",
+		func(c *Config) *bool { return &c.DefaultOverwrite }),
+	boolOption("ADOPT_EXISTING", "Adopt records that already exist in Pi-hole with the desired IP, even without overwrite",
+		func(c *Config) *bool { return &c.AdoptExisting }),
+	boolOption("ALLOW_CROSS_NAMESPACE_DUPLICATES", "Let objects in different namespaces share a host when they want the same IP",
+		func(c *Config) *bool { return &c.AllowCrossNamespaceDuplicates }),
+	intOption("MAX_CONCURRENT_RECONCILES", "Objects of each kind reconciled in parallel",
+		func(c *Config) *int { return &c.MaxConcurrentReconciles }),
+	durationOption("RATE_LIMITER_BASE_DELAY", "First workqueue retry delay after a failed reconcile",
+		func(c *Config) *time.Duration { return &c.RateLimiterBaseDelay }),
+	durationOption("RATE_LIMITER_MAX_DELAY", "Upper bound for the workqueue retry delay",
+		func(c *Config) *time.Duration { return &c.RateLimiterMaxDelay }),
+	floatOption("RATE_LIMITER_QPS", "Objects per second each controller may reconcile overall",
+		func(c *Config) *float64 { return &c.RateLimiterQPS }),
+	intOption("RATE_LIMITER_BURST", "Burst allowed above the rate limiter QPS",
+		func(c *Config) *int { return &c.RateLimiterBurst }),
+	durationOption("BATCH_INTERVAL", "Coalesce record changes into bulk writes at most this often; 0 writes each change directly",
+		func(c *Config) *time.Duration { return &c.BatchInterval }),
+	intOption("BATCH_MAX_SIZE", "Flush a batch early once this many changes are waiting",
+		func(c *Config) *int { return &c.BatchMaxSize }),
+	durationOption("LISTING_MAX_AGE", "How long reconciles share one Pi-hole listing; 0 lists Pi-hole on every reconcile",
+		func(c *Config) *time.Duration { return &c.ListingMaxAge }),
+	floatOption("WIPE_THRESHOLD", "Fraction of managed records that must vanish to trigger a restore; 0 disables detection",
+		func(c *Config) *float64 { return &c.WipeThreshold }),
+	intOption("WIPE_MIN_RECORDS", "Only check for a wipe once at least this many managed records were present",
+		func(c *Config) *int { return &c.WipeMinRecords }),
+	intOption("RESTORE_MAX_PER_MINUTE", "Records recreated per minute during a restore",
+		func(c *Config) *int { return &c.RestoreMaxPerMinute }),
+	stringOption("STATUS_RESOURCE", "Name of the PiholeSync reporting the sync state of every managed object",
+		func(c *Config) *string { return &c.StatusResource }),
+	durationOption("STATUS_UPDATE_INTERVAL", "Shortest time between writes of the PiholeSync status",
+		func(c *Config) *time.Duration { return &c.StatusInterval }),
+	durationOption("RETRY_MAX_BACKOFF", "Upper bound for the retry delay after Pi-hole API errors",
+		func(c *Config) *time.Duration { return &c.RetryMaxBackoff }),
+	durationOption("REQUEUE_INTERVAL_ERROR", "First retry delay after a Pi-hole API error",
+		func(c *Config) *time.Duration { return &c.RequeueIntervalError }),
+	durationOption("REQUEUE_INTERVAL_CONFLICT", "Retry delay after the managed-hosts annotation could not be written",
+		func(c *Config) *time.Duration { return &c.RequeueIntervalConflict }),
+	durationOption("MIN_REQUEUE_AFTER", "Shortest resync interval the pihole.io/requeue-after annotation may request",
+		func(c *Config) *time.Duration { return &c.MinRequeueAfter }),
+	intOption("MAX_DELETIONS_PER_SYNC", "Refuse to delete more records than this in one reconcile; 0 is unlimited",
+		func(c *Config) *int { return &c.MaxDeletionsPerSync }),
+	intOption("MAX_DELETIONS_PER_INTERVAL", "Refuse to delete more records than this per deletion budget interval; 0 is unlimited",
+		func(c *Config) *int { return &c.MaxDeletionsPerInterval }),
+	durationOption("DELETION_BUDGET_INTERVAL", "Rolling window for the deletion budget",
+		func(c *Config) *time.Duration { return &c.DeletionBudgetInterval }),
+	intOption("MAX_MANAGED_RECORDS", "Refuse to register more records than this overall; 0 is unlimited",
+		func(c *Config) *int { return &c.MaxManagedRecords }),
+	intOption("RECORD_QUOTA_PER_NAMESPACE", "Refuse to register more records than this per namespace; 0 is unlimited",
+		func(c *Config) *int { return &c.RecordQuotaPerNamespace }),
+	boolOption("REQUIRE_INGRESS_READY", "Only register hosts once the object has a load-balancer status",
+		func(c *Config) *bool { return &c.RequireIngressReady }),
+	durationOption("INGRESS_READY_GRACE_PERIOD", "How long readiness may be lost before published records are removed",
+		func(c *Config) *time.Duration { return &c.IngressReadyGracePeriod }),
+	boolOption("FILTER_INTERNAL_HOSTS", "Skip hosts that are IP literals or end in an internal suffix",
+		func(c *Config) *bool { return &c.FilterInternalHosts }),
+	listOption("INTERNAL_HOST_SUFFIXES", "Comma-separated suffixes treated as cluster-internal",
+		func(c *Config) *[]string { return &c.InternalHostSuffixes }),
+	boolOption("ENABLE_FINALIZERS", "Add the pihole.io/dns-cleanup finalizer",
+		func(c *Config) *bool { return &c.EnableFinalizers }),
+	boolOption("STRIP_FINALIZERS", "With finalizers disabled, remove finalizers added by earlier runs",
+		func(c *Config) *bool { return &c.StripFinalizers }),
+	boolOption("ONCE_FINALIZERS", "Add the pihole.io/dns-cleanup finalizer in a --once run",
+		func(c *Config) *bool { return &c.OnceFinalizers }),
+	durationOption("FINALIZER_TIMEOUT", "How long a deleted object waits for DNS cleanup; 0 waits forever",
+		func(c *Config) *time.Duration { return &c.FinalizerTimeout }),
+	intOption("FINALIZER_MAX_ATTEMPTS", "Release the finalizer after this many failed cleanup attempts; 0 is unlimited",
+		func(c *Config) *int { return &c.FinalizerMaxAttempts }),
+	durationOption("STUCK_DELETION_WARNING", "Warn on a deleted object still waiting for DNS cleanup after this long; 0 disables it",
+		func(c *Config) *time.Duration { return &c.StuckDeletionWarning }),
+	stringOption("AUDIT_LOG_PATH", "Append a JSON line for every record change to this file",
+		func(c *Config) *string { return &c.AuditLogPath }),
+	stringOption("AUDIT_CONFIGMAP", "Keep recent audit entries in this ConfigMap",
+		func(c *Config) *string { return &c.AuditConfigMap }),
+	intOption("AUDIT_MAX_ENTRIES", "Number of entries retained in the audit ConfigMap",
+		func(c *Config) *int { return &c.AuditMaxEntries }),
+	stringOption("RECORDS_CONFIGMAP", "Mirror every managed record, as a hosts file and as JSON, into this ConfigMap",
+		func(c *Config) *string { return &c.RecordsConfigMap }),
+	stringOption("RECORDS_CONFIGMAP_NAMESPACE", "Namespace of the records ConfigMap; defaults to the registry namespace",
+		func(c *Config) *string { return &c.RecordsConfigMapNamespace }),
+	durationOption("RECORDS_CONFIGMAP_INTERVAL", "Least time between two writes of the records ConfigMap",
+		func(c *Config) *time.Duration { return &c.RecordsConfigMapInterval }),
+	stringOption("NOTIFY_URL", "Endpoint notified after each reconcile that changed records",
+		func(c *Config) *string { return &c.NotifyURL }),
+	stringOption("NOTIFY_FORMAT", "Notification format: json or ntfy",
+		func(c *Config) *string { return &c.NotifyFormat }),
+	listOption("NOTIFY_EVENTS", "Comma-separated change types included in notifications",
+		func(c *Config) *[]string { return &c.NotifyEvents }),
+	secretOption("ADMIN_TOKEN", "Bearer token required by the admin endpoint",
+		func(c *Config) *string { return &c.AdminToken }),
+	stringOption("REGISTRY_NAMESPACE", "Namespace of the ConfigMap persisting operator state; defaults to POD_NAMESPACE",
+		func(c *Config) *string { return &c.RegistryNamespace }),
+	stringOption("REGISTRY_CONFIGMAP", "Name of the ConfigMap persisting operator state",
+		func(c *Config) *string { return &c.RegistryName }),
+}
+
+// loadEnv overrides the current settings with the environment variables that are set
+func (c *Config) loadEnv() error {
+	for _, o := range options {
+		value := os.Getenv(o.env)
+		if o.secret {
+			var err error
+			if value, err = secretEnv(o.env); err != nil {
+				return err
+			}
+		}
+		if value == "" {
+			continue
+		}
+		if err := o.set(c, o.env, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flags holds the options given on the command line; see RegisterFlags
+type Flags struct {
+	values map[string]string
+}
+
+// RegisterFlags defines a flag on fs for every option. The values given are applied
+// by Load, over the environment.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{values: map[string]string{}}
+	for _, o := range options {
+		fs.Var(&flagValue{flags: f, env: o.env, boolean: o.boolean}, o.flagName(), o.usage)
+	}
+	return f
+}
+
+// apply overrides the settings of c with the flags given
+func (f *Flags) apply(c *Config) error {
+	if f == nil {
+		return nil
+	}
+	for _, o := range options {
+		value, ok := f.values[o.env]
+		if !ok {
+			continue
+		}
+		if err := o.set(c, "--"+o.flagName(), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagValue records the value of an option's flag
+type flagValue struct {
+	flags   *Flags
+	env     string
+	boolean bool
+}
+
+func (v *flagValue) String() string {
+	if v.flags == nil {
+		return ""
+	}
+	return v.flags.values[v.env]
+}
+
+func (v *flagValue) Set(value string) error {
+	v.flags.values[v.env] = value
+	return nil
+}
+
+// IsBoolFlag lets boolean options be given without a value
+func (v *flagValue) IsBoolFlag() bool { return v.boolean }
+
+func stringOption(env, usage string, field func(*Config) *string) option {
+	return option{env: env, usage: usage, set: func(c *Config, _, value string) error {
+		*field(c) = value
+		return nil
+	}}
+}
+
+func secretOption(env, usage string, field func(*Config) *string) option {
+	o := stringOption(env, usage, field)
+	o.secret = true
+	return o
+}
+
+func listOption(env, usage string, field func(*Config) *[]string) option {
+	return option{env: env, usage: usage, set: func(c *Config, _, value string) error {
+		*field(c) = parseList(value)
+		return nil
+	}}
+}
+
+func mapOption(env, usage string, field func(*Config) *map[string]string) option {
+	return option{env: env, usage: usage, set: func(c *Config, name, value string) (err error) {
+		*field(c), err = parseMap(name, value)
+		return err
+	}}
+}
+
+func boolOption(env, usage string, field func(*Config) *bool) option {
+	return option{env: env, usage: usage, boolean: true, set: func(c *Config, name, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid boolean: %w", name, err)
+		}
+		*field(c) = b
+		return nil
+	}}
+}
+
+func intOption(env, usage string, field func(*Config) *int) option {
+	return option{env: env, usage: usage, set: func(c *Config, name, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid integer: %w", name, err)
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+func floatOption(env, usage string, field func(*Config) *float64) option {
+	return option{env: env, usage: usage, set: func(c *Config, name, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid number: %w", name, err)
+		}
+		*field(c) = f
+		return nil
+	}}
+}
+
+func durationOption(env, usage string, field func(*Config) *time.Duration) option {
+	return option{env: env, usage: usage, set: func(c *Config, name, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid duration: %w", name, err)
+		}
+		*field(c) = d
+		return nil
+	}}
+}
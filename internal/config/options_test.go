@@ -0,0 +1,143 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newFlags registers the options on a fresh flag set and parses args
+func newFlags(t *testing.T, args ...string) *Flags {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse(%v) unexpected error: %v", args, err)
+	}
+	return flags
+}
+
+func TestFlagPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("logLevel: warn\nsyncPolicy: create-only\nbatchMaxSize: 10\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Clearenv()
+	t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+	t.Setenv("PIHOLE_PASSWORD", "test-password")
+	t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("SYNC_POLICY", "upsert-only")
+
+	cfg, err := Load(path, newFlags(t, "--log-level=debug", "--leader-elect", "--metrics-bind-address", ":8443"))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	// flag over environment over file over default
+	if cfg.LogLevel != "debug" {
+		t.Errorf("LogLevel = %q, want the flag's debug", cfg.LogLevel)
+	}
+	if cfg.SyncPolicy != "upsert-only" {
+		t.Errorf("SyncPolicy = %q, want the environment's upsert-only", cfg.SyncPolicy)
+	}
+	if cfg.BatchMaxSize != 10 {
+		t.Errorf("BatchMaxSize = %d, want the file's 10", cfg.BatchMaxSize)
+	}
+	if cfg.HealthProbeBindAddress != DefaultHealthProbeBindAddress {
+		t.Errorf("HealthProbeBindAddress = %q, want the default %q", cfg.HealthProbeBindAddress, DefaultHealthProbeBindAddress)
+	}
+	if !cfg.LeaderElect || cfg.MetricsBindAddress != ":8443" {
+		t.Errorf("LeaderElect, MetricsBindAddress = %v, %q, want true, :8443", cfg.LeaderElect, cfg.MetricsBindAddress)
+	}
+
+	// The endpoint settings that used to be flags only can now come from the environment
+	t.Setenv("LEADER_ELECT", "true")
+	t.Setenv("ADMIN_BIND_ADDRESS", ":8082")
+	cfg, err = Load(path, newFlags(t))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if !cfg.LeaderElect || cfg.AdminBindAddress != ":8082" {
+		t.Errorf("LeaderElect, AdminBindAddress = %v, %q, want true, :8082", cfg.LeaderElect, cfg.AdminBindAddress)
+	}
+
+	// A flag can turn off what the environment turned on
+	cfg, err = Load(path, newFlags(t, "--leader-elect=false"))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if cfg.LeaderElect {
+		t.Error("LeaderElect = true, want the flag's false")
+	}
+}
+
+func TestFlagErrors(t *testing.T) {
+	os.Clearenv()
+	t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+	t.Setenv("PIHOLE_PASSWORD", "test-password")
+	t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+
+	tests := []struct {
+		arg    string
+		errMsg string
+	}{
+		{arg: "--max-concurrent-reconciles=many", errMsg: "--max-concurrent-reconciles is not a valid integer"},
+		{arg: "--finalizer-timeout=soon", errMsg: "--finalizer-timeout is not a valid duration"},
+		{arg: "--metrics-const-labels=cluster", errMsg: "--metrics-const-labels must be a list of key=value pairs"},
+		{arg: "--sync-policy=never", errMsg: "SYNC_POLICY must be one of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			_, err := Load("", newFlags(t, tt.arg))
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// TestEveryFieldHasOption sets each option on an empty Config and checks that every
+// field can be given as a flag and environment variable
+func TestEveryFieldHasOption(t *testing.T) {
+	samples := []string{"true", "1", "1s", "a=b", "[{url: http://pihole}]"}
+	covered := map[string]bool{}
+	names := map[string]bool{}
+	for _, o := range options {
+		if names[o.flagName()] {
+			t.Errorf("option %s is defined twice", o.env)
+		}
+		names[o.flagName()] = true
+
+		set := false
+		for _, value := range samples {
+			var c Config
+			if o.set(&c, o.env, value) != nil {
+				continue
+			}
+			v := reflect.ValueOf(c)
+			for i := 0; i < v.NumField(); i++ {
+				if !v.Field(i).IsZero() {
+					covered[v.Type().Field(i).Name] = true
+					set = true
+				}
+			}
+			break
+		}
+		if !set {
+			t.Errorf("option %s sets no field", o.env)
+		}
+	}
+
+	fields := reflect.TypeOf(Config{})
+	for i := 0; i < fields.NumField(); i++ {
+		if name := fields.Field(i).Name; !covered[name] {
+			t.Errorf("Config.%s has no flag or environment variable", name)
+		}
+	}
+}
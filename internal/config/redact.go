@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Redacted replaces secrets in reports of the configuration
+const Redacted = "<redacted>"
+
+// Redacted returns a copy of c with passwords, the admin token and the user info of the
+// notification URL replaced by Redacted. References to Secrets and files are kept.
+func (c *Config) Redacted() *Config {
+	out := *c
+	if out.PiholePassword != "" {
+		out.PiholePassword = Redacted
+	}
+	if out.AdminToken != "" {
+		out.AdminToken = Redacted
+	}
+	out.PiholeInstances = slices.Clone(c.PiholeInstances)
+	for i := range out.PiholeInstances {
+		if out.PiholeInstances[i].Password != "" {
+			out.PiholeInstances[i].Password = Redacted
+		}
+	}
+	if u, err := url.Parse(c.NotifyURL); err == nil && u.User != nil {
+		u.User = nil
+		scheme, rest, _ := strings.Cut(u.String(), "://")
+		out.NotifyURL = scheme + "://" + Redacted + "@" + rest
+	}
+	return &out
+}
+
+// RedactedValues returns the settings of c with secrets redacted, keyed like the config
+// file, with durations written the way they are configured
+func (c *Config) RedactedValues() (map[string]any, error) {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	values := make(map[string]any)
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("decoding config: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fillStrings sets every string field of the struct v points to, recursively, to value
+func fillStrings(v reflect.Value, value string) {
+	for i := 0; i < v.NumField(); i++ {
+		switch f := v.Field(i); f.Kind() {
+		case reflect.String:
+			f.SetString(value)
+		case reflect.Struct:
+			fillStrings(f, value)
+		}
+	}
+}
+
+// TestRedactedValuesLeakNoSecrets fills every string setting with a marker and checks
+// which keys still show it. A new setting fails this test until it is either redacted
+// or added to the list of values that are safe to show.
+func TestRedactedValuesLeakNoSecrets(t *testing.T) {
+	const marker = "s3cret"
+	cfg := &Config{PiholeInstances: make([]PiholeInstance, 1)}
+	fillStrings(reflect.ValueOf(cfg).Elem(), marker)
+	fillStrings(reflect.ValueOf(&cfg.PiholeInstances[0]).Elem(), marker)
+	cfg.NotifyURL = "https://user:" + marker + "@hooks.example.com/" + marker + "-topic"
+
+	values, err := cfg.RedactedValues()
+	if err != nil {
+		t.Fatalf("RedactedValues() unexpected error: %v", err)
+	}
+	var shown []string
+	for key, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("json.Marshal(%s) unexpected error: %v", key, err)
+		}
+		if key == "piholeInstances" {
+			var instances []map[string]any
+			if err := json.Unmarshal(data, &instances); err != nil || len(instances) != 1 {
+				t.Fatalf("piholeInstances = %s, want one instance", data)
+			}
+			for instKey, instValue := range instances[0] {
+				if instData, _ := json.Marshal(instValue); strings.Contains(string(instData), marker) {
+					shown = append(shown, "piholeInstances."+instKey)
+				}
+			}
+			continue
+		}
+		if strings.Contains(string(data), marker) {
+			shown = append(shown, key)
+		}
+	}
+	slices.Sort(shown)
+
+	safe := []string{
+		"adminBindAddress", "auditConfigMap", "auditLogPath", "clusterID", "conflictPolicy", "defaultDomainSuffix",
+		"defaultTargetIP", "defaultTargetIPv6", "healthProbeBindAddress", "hostsMode", "labelSelector",
+		"leaderElectionNamespace", "leaderElectionResourceLock", "logFormat", "logLevel", "metricsBindAddress", "metricsCertKey", "metricsCertName",
+		"metricsCertPath", "metricsPrefix", "notifyFormat", "notifyURL", "piholeInstances.name",
+		"piholeInstances.passwordFile", "piholeInstances.secretRef", "piholeInstances.tls", "piholeInstances.url",
+		"piholePasswordSecret", "piholeURL", "pprofBindAddress", "recordsConfigMap", "recordsConfigMapNamespace",
+		"registryName", "registryNamespace", "startupPiholeCheck", "statusResource", "syncPolicy", "targetSource",
+		"watchNamespace",
+	}
+	if !slices.Equal(shown, safe) {
+		t.Errorf("keys showing their value = %v\nwant %v", shown, safe)
+	}
+
+	if got := values["notifyURL"]; got != "https://"+Redacted+"@hooks.example.com/"+marker+"-topic" {
+		t.Errorf("notifyURL = %v, want the user info redacted", got)
+	}
+	for _, key := range []string{"piholePassword", "adminToken"} {
+		if values[key] != Redacted {
+			t.Errorf("%s = %v, want %s", key, values[key], Redacted)
+		}
+	}
+}
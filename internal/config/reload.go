@@ -0,0 +1,85 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// liveKeys are the config keys applied to a running operator when the configuration is
+// reloaded; every other key only takes effect after a restart
+var liveKeys = map[string]bool{
+	"logLevel":                      true,
+	"defaultTargetIP":               true,
+	"targetSource":                  true,
+	"defaultTargetIPv6":             true,
+	"enableAAAA":                    true,
+	"defaultDomainSuffix":           true,
+	"syncPolicy":                    true,
+	"conflictPolicy":                true,
+	"hostsMode":                     true,
+	"defaultOverwrite":              true,
+	"adoptExisting":                 true,
+	"allowCrossNamespaceDuplicates": true,
+	"filterInternalHosts":           true,
+	"internalHostSuffixes":          true,
+	"ingressClasses":                true,
+	"excludeNamespaces":             true,
+	"retryMaxBackoff":               true,
+	"requeueIntervalError":          true,
+	"requeueIntervalConflict":       true,
+	"minRequeueAfter":               true,
+}
+
+// scheduleKeys are the live keys that only change logging or when objects are retried,
+// not the records a reconcile produces
+var scheduleKeys = map[string]bool{
+	"logLevel":                true,
+	"retryMaxBackoff":         true,
+	"requeueIntervalError":    true,
+	"requeueIntervalConflict": true,
+	"minRequeueAfter":         true,
+}
+
+// ResyncKeys returns the keys among live whose new value may change the records of
+// existing objects, so every managed object has to be reconciled again to apply it
+func ResyncKeys(live []string) []string {
+	var keys []string
+	for _, key := range live {
+		if !scheduleKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Changes compares c with a reloaded configuration and returns the keys that differ,
+// split into those applied live and those requiring a restart
+func (c *Config) Changes(next *Config) (live, restart []string) {
+	before, after := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < before.NumField(); i++ {
+		if reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			continue
+		}
+		key, _, _ := strings.Cut(before.Type().Field(i).Tag.Get("yaml"), ",")
+		if liveKeys[key] {
+			live = append(live, key)
+		} else {
+			restart = append(restart, key)
+		}
+	}
+	return live, restart
+}
+
+// WithLive returns a copy of c taking the keys applied live from next, which is what
+// a running operator uses after reloading next
+func (c *Config) WithLive(next *Config) *Config {
+	out := *c
+	v, from := reflect.ValueOf(&out).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		if liveKeys[key] {
+			v.Field(i).Set(from.Field(i))
+		}
+	}
+	return &out
+}
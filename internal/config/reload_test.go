@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestConfigChanges(t *testing.T) {
+	os.Clearenv()
+	t.Setenv("PIHOLE_URL", "http://192.168.1.2")
+	t.Setenv("PIHOLE_PASSWORD", "s3cret")
+	t.Setenv("DEFAULT_TARGET_IP", "192.168.1.100")
+	current, err := Load("", nil)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	same := *current
+	if live, restart := current.Changes(&same); live != nil || restart != nil {
+		t.Errorf("Changes() of an identical config = %v, %v, want none", live, restart)
+	}
+
+	next := *current
+	next.DefaultTargetIP = "192.168.1.101"
+	next.IngressClasses = []string{"nginx"}
+	next.RequeueIntervalError = time.Minute
+	next.WatchNamespace = "apps"
+	next.PiholeURL = "http://192.168.1.3"
+	live, restart := current.Changes(&next)
+	if want := []string{"defaultTargetIP", "requeueIntervalError", "ingressClasses"}; !slices.Equal(live, want) {
+		t.Errorf("Changes() live = %v, want %v", live, want)
+	}
+	if want := []string{"piholeURL", "watchNamespace"}; !slices.Equal(restart, want) {
+		t.Errorf("Changes() restart = %v, want %v", restart, want)
+	}
+	if want := []string{"defaultTargetIP", "ingressClasses"}; !slices.Equal(ResyncKeys(live), want) {
+		t.Errorf("ResyncKeys() = %v, want %v", ResyncKeys(live), want)
+	}
+	if keys := ResyncKeys([]string{"logLevel", "minRequeueAfter"}); keys != nil {
+		t.Errorf("ResyncKeys() of timing keys = %v, want none", keys)
+	}
+
+	applied := current.WithLive(&next)
+	if applied.DefaultTargetIP != "192.168.1.101" || applied.WatchNamespace != "" || applied.PiholeURL != current.PiholeURL {
+		t.Errorf("WithLive() = %+v, want only the live keys taken over", applied)
+	}
+	if live, restart := applied.Changes(&next); live != nil || len(restart) != 2 {
+		t.Errorf("Changes() after WithLive() = %v, %v, want only the restart keys left", live, restart)
+	}
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// AnnotationAdopt controls whether records that already exist in Pi-hole with the
+// desired IP are adopted when the object may not overwrite them
+const AnnotationAdopt = "pihole.io/adopt"
+
+// adoptAllowed reports whether records that already exist in Pi-hole with the desired
+// IP may be adopted without overwrite
+func (r *IngressReconciler) adoptAllowed(obj client.Object, logger *slog.Logger) bool {
+	def := r.settings().AdoptExisting
+	value, ok := obj.GetAnnotations()[AnnotationAdopt]
+	if !ok || value == "" {
+		return def
+	}
+	allowed, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Warn("invalid annotation", "annotation", AnnotationAdopt, "value", value, "error", err)
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+			"%s=%q is not a valid boolean; using default %t", AnnotationAdopt, value, def)
+		return def
+	}
+	return allowed
+}
+
+// adoptable returns the unchanged records of plan outside tracked: those that already
+// exist in Pi-hole with the desired IP and would be taken over without a write
+func adoptable(plan Plan, tracked []string) []string {
+	return withoutHosts(plan.Unchanged, tracked)
+}
+
+// reportAdoptions logs and counts the records obj adopted, and records them in the
+// registry
+func (r *IngressReconciler) reportAdoptions(ctx context.Context, obj client.Object, adopted []string, ips map[string]string, logger *slog.Logger) {
+	if len(adopted) == 0 {
+		return
+	}
+	for _, key := range adopted {
+		logger.Info("dns record adopted", "host", key, "ip", ips[key])
+	}
+	metrics.RecordsAdopted.WithLabelValues(r.src().kind()).Add(float64(len(adopted)))
+
+	if r.Registry == nil {
+		return
+	}
+	owner := r.ownership(obj)
+	now := time.Now().UTC()
+	if err := r.Registry.Update(ctx, func(st *registry.State) bool {
+		for _, key := range adopted {
+			owner.TargetIP = ips[key]
+			st.RecordAdopted(key, owner, now)
+		}
+		return true
+	}); err != nil {
+		logger.Error("failed to record ownership", "error", err)
+	}
+}
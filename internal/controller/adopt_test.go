@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// adoptedCount returns how many records Ingresses adopted
+func adoptedCount(t *testing.T) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.RecordsAdopted.WithLabelValues("Ingress").Write(&m); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestReconcileAdoptExisting(t *testing.T) {
+	tests := []struct {
+		name        string
+		adopt       bool
+		annotations map[string]string
+		wantAdopted bool
+	}{
+		{name: "overwrite disabled leaves existing records foreign"},
+		{name: "adopt existing", adopt: true, wantAdopted: true},
+		{name: "annotation enables adoption", annotations: map[string]string{AnnotationAdopt: "true"}, wantAdopted: true},
+		{name: "annotation disables adoption", adopt: true, annotations: map[string]string{AnnotationAdopt: "false"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ph := newFakePiholeClient(
+				pihole.DNSRecord{Domain: "same.home.lan", IP: "192.168.1.100"},
+				pihole.DNSRecord{Domain: "other.home.lan", IP: "192.168.1.50"},
+			)
+			annotations := map[string]string{AnnotationRegister: "true"}
+			for k, v := range tt.annotations {
+				annotations[k] = v
+			}
+			ingress := testIngress("app", annotations, "same.home.lan", "other.home.lan", "new.home.lan")
+			r := newTestReconciler(ph, ingress)
+			r.DisableOverwrite = true
+			r.AdoptExisting = tt.adopt
+			r.Registry = registry.NewStore(r.Client, r.Client, "pihole-operator", "registry")
+
+			before := adoptedCount(t)
+			reconcileIngress(t, r, "default", "app")
+
+			// Only the missing record is written; the one with another IP is kept either way
+			for _, call := range ph.calls {
+				if call != "list" && call != "create new.home.lan" {
+					t.Errorf("unexpected pi-hole call %q", call)
+				}
+			}
+			if ph.ip("other.home.lan") != "192.168.1.50" {
+				t.Errorf("record with another IP overwritten: %v", ph.records)
+			}
+
+			managed := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]
+			if got := strings.Contains(managed, "same.home.lan"); got != tt.wantAdopted {
+				t.Errorf("managed hosts = %q, adopted same.home.lan = %v, want %v", managed, got, tt.wantAdopted)
+			}
+			if strings.Contains(managed, "other.home.lan") {
+				t.Errorf("managed hosts = %q, want the conflicting record left foreign", managed)
+			}
+
+			wantCount := 0.0
+			if tt.wantAdopted {
+				wantCount = 1
+			}
+			if got := adoptedCount(t) - before; got != wantCount {
+				t.Errorf("adopted records counted = %v, want %v", got, wantCount)
+			}
+
+			state, err := r.Registry.Get(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			owner, ok := state.Records["same.home.lan"]
+			if ok != tt.wantAdopted {
+				t.Fatalf("registry entry for same.home.lan = %v, want %v", ok, tt.wantAdopted)
+			}
+			if ok && (owner.AdoptedAt.IsZero() || !owner.CreatedAt.IsZero() || owner.TargetIP != "192.168.1.100") {
+				t.Errorf("adopted ownership = %+v", owner)
+			}
+		})
+	}
+}
+
+func TestReconcileAdoptCountsOnce(t *testing.T) {
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "same.home.lan", IP: "192.168.1.100"})
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "same.home.lan")
+	r := newTestReconciler(ph, ingress)
+
+	// With overwrite allowed, existing records are adopted too
+	before := adoptedCount(t)
+	reconcileIngress(t, r, "default", "app")
+	reconcileIngress(t, r, "default", "app")
+	if got := adoptedCount(t) - before; got != 1 {
+		t.Errorf("adopted records counted = %v, want 1 across resyncs", got)
+	}
+}
+
+func TestAdoptAllowedInvalidAnnotation(t *testing.T) {
+	r := newTestReconciler(newFakePiholeClient())
+	r.AdoptExisting = true
+	ingress := testIngress("app", map[string]string{AnnotationAdopt: "maybe"})
+
+	if !r.adoptAllowed(ingress, r.Logger) {
+		t.Error("adoptAllowed() = false, want the default")
+	}
+	select {
+	case event := <-r.Recorder.(*record.FakeRecorder).Events:
+		if !strings.Contains(event, "InvalidAnnotation") || !strings.Contains(event, AnnotationAdopt) {
+			t.Errorf("event = %q, want an InvalidAnnotation warning", event)
+		}
+	default:
+		t.Error("no event for the invalid annotation")
+	}
+}
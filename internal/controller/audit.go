@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+)
+
+// ownerOf identifies an object in audit entries and registry records
+func (r *IngressReconciler) ownerOf(obj client.Object) string {
+	return r.src().kind() + " " + client.ObjectKeyFromObject(obj).String()
+}
+
+// recordAudit writes a DNS mutation to sink, stamping the time and reconcile ID.
+// Failures are logged but never block the change itself.
+func recordAudit(ctx context.Context, sink audit.Sink, entry audit.Entry, logger *slog.Logger) {
+	if sink == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	if id := ctrlcontroller.ReconcileIDFromContext(ctx); id != "" {
+		entry.ReconcileID = string(id)
+	}
+	if err := sink.Record(ctx, entry); err != nil {
+		logger.Error("failed to write audit entry", "action", entry.Action, "host", entry.Domain, "error", err)
+	}
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// DefaultBackoffBase is the first retry delay after a failed reconcile
+	DefaultBackoffBase = 30 * time.Second
+
+	// DefaultBackoffMax caps the retry delay for persistently failing objects
+	DefaultBackoffMax = 10 * time.Minute
+
+	// DefaultUpdateConflictRequeue is the retry delay after a failed annotation update
+	DefaultUpdateConflictRequeue = 10 * time.Second
+)
+
+// Backoff tracks consecutive failures per object and computes exponential retry delays.
+// Entries are removed on success or when the object goes away, so the map only ever
+// holds objects that are currently failing.
+type Backoff struct {
+	base time.Duration
+	max  time.Duration
+
+	mu       sync.Mutex
+	failures map[types.NamespacedName]int
+}
+
+// NewBackoff creates a Backoff starting at base and doubling up to maxDelay
+func NewBackoff(base, maxDelay time.Duration) *Backoff {
+	b := &Backoff{failures: make(map[types.NamespacedName]int)}
+	b.SetLimits(base, maxDelay)
+	return b
+}
+
+// SetLimits changes the first delay and the cap; failures already counted keep
+// doubling from the new base
+func (b *Backoff) SetLimits(base, maxDelay time.Duration) {
+	if base <= 0 {
+		base = DefaultBackoffBase
+	}
+	if maxDelay < base {
+		maxDelay = base
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.base = base
+	b.max = maxDelay
+}
+
+// Next records a failure for key and returns how long to wait before retrying
+func (b *Backoff) Next(key types.NamespacedName) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	attempt := b.failures[key]
+	b.failures[key] = attempt + 1
+
+	delay := b.base
+	for i := 0; i < attempt; i++ {
+		delay *= 2
+		if delay >= b.max {
+			return b.max
+		}
+	}
+	return delay
+}
+
+// Reset forgets the failure history for key
+func (b *Backoff) Reset(key types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, key)
+}
+
+// Failures returns the number of consecutive failures recorded for key
+func (b *Backoff) Failures(key types.NamespacedName) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures[key]
+}
+
+// Len returns the number of objects currently being backed off
+func (b *Backoff) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.failures)
+}
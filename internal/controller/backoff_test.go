@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestBackoffNext(t *testing.T) {
+	b := NewBackoff(30*time.Second, 10*time.Minute)
+	key := types.NamespacedName{Namespace: "default", Name: "app"}
+
+	want := []time.Duration{
+		30 * time.Second,
+		1 * time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		8 * time.Minute,
+		10 * time.Minute,
+		10 * time.Minute,
+	}
+	for i, w := range want {
+		if got := b.Next(key); got != w {
+			t.Errorf("Next() attempt %d = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestBackoffPerObject(t *testing.T) {
+	b := NewBackoff(time.Second, time.Minute)
+	a := types.NamespacedName{Namespace: "default", Name: "a"}
+	other := types.NamespacedName{Namespace: "default", Name: "b"}
+
+	b.Next(a)
+	b.Next(a)
+	if got := b.Next(other); got != time.Second {
+		t.Errorf("Next() for unrelated object = %v, want %v", got, time.Second)
+	}
+	if got := b.Next(a); got != 4*time.Second {
+		t.Errorf("Next() third attempt = %v, want %v", got, 4*time.Second)
+	}
+}
+
+func TestBackoffReset(t *testing.T) {
+	b := NewBackoff(time.Second, time.Minute)
+	key := types.NamespacedName{Namespace: "default", Name: "app"}
+
+	b.Next(key)
+	b.Next(key)
+	b.Reset(key)
+
+	if b.Len() != 0 {
+		t.Errorf("Len() after reset = %d, want 0", b.Len())
+	}
+	if got := b.Next(key); got != time.Second {
+		t.Errorf("Next() after reset = %v, want %v", got, time.Second)
+	}
+}
+
+func TestNewBackoffBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		maxDelay time.Duration
+		want     time.Duration
+	}{
+		{"zero base uses default", 0, time.Hour, DefaultBackoffBase},
+		{"max below base is raised", time.Minute, time.Second, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBackoff(tt.base, tt.maxDelay)
+			if got := b.Next(types.NamespacedName{Name: "x"}); got != tt.want {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffSetLimits(t *testing.T) {
+	b := NewBackoff(time.Second, time.Minute)
+	key := types.NamespacedName{Namespace: "default", Name: "app"}
+	b.Next(key)
+
+	b.SetLimits(10*time.Second, 15*time.Second)
+	if got := b.Next(key); got != 15*time.Second {
+		t.Errorf("Next() after SetLimits = %v, want the new cap 15s", got)
+	}
+	b.SetLimits(0, 0)
+	if got := b.Next(types.NamespacedName{Namespace: "default", Name: "other"}); got != DefaultBackoffBase {
+		t.Errorf("Next() with unset limits = %v, want %v", got, DefaultBackoffBase)
+	}
+}
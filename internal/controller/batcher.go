@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+const (
+	// DefaultBatchInterval is how long changes wait to be coalesced
+	DefaultBatchInterval = 500 * time.Millisecond
+
+	// DefaultBatchMaxSize flushes early once this many changes are waiting
+	DefaultBatchMaxSize = 100
+)
+
+// Batcher coalesces the record changes of concurrent reconciles into batched Pi-hole
+// writes. Submit blocks until the batch holding the changes is flushed and returns its
+// outcome. A merged batch is all or nothing, so when one fails each submission is
+// replayed alone and gets the error of its own changes: a host Pi-hole rejects only
+// fails the object that asked for it.
+// The reconciler holds the domain locks of a submission until it completes, so the
+// changes of different submitters in one batch never touch the same domain.
+type Batcher struct {
+	PiholeClient pihole.Client
+	Logger       *slog.Logger
+
+	// Interval is the longest a change waits for a flush; MaxSize flushes earlier
+	// once that many changes are waiting. Zero values use the defaults.
+	Interval time.Duration
+	MaxSize  int
+
+	mu      sync.Mutex
+	pending []*batchRequest
+	size    int
+	full    chan struct{}
+}
+
+// batchRequest is one submission waiting for its flush
+type batchRequest struct {
+	batch pihole.Batch
+	done  chan error
+}
+
+// Submit queues batch for the next flush and waits for its outcome. If ctx ends
+// first the changes may still be applied by the flush.
+func (b *Batcher) Submit(ctx context.Context, batch pihole.Batch) error {
+	if batch.Len() == 0 {
+		return nil
+	}
+	req := &batchRequest{batch: batch, done: make(chan error, 1)}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, req)
+	b.size += batch.Len()
+	full := b.size >= b.maxSize()
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.fullCh() <- struct{}{}:
+		default:
+		}
+	}
+
+	select {
+	case err := <-req.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Start flushes pending changes every Interval, or sooner when MaxSize is reached,
+// until ctx is cancelled; it implements manager.Runnable
+func (b *Batcher) Start(ctx context.Context) error {
+	interval := b.Interval
+	if interval <= 0 {
+		interval = DefaultBatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.fail(ctx.Err())
+			return nil
+		case <-ticker.C:
+		case <-b.fullCh():
+		}
+		b.flush(ctx)
+	}
+}
+
+// flush applies every pending submission in one batch and completes them with the outcome
+func (b *Batcher) flush(ctx context.Context) {
+	reqs := b.take()
+	if len(reqs) == 0 {
+		return
+	}
+
+	var merged pihole.Batch
+	for _, req := range reqs {
+		merged.Merge(req.batch)
+	}
+
+	err := pihole.ApplyBatch(ctx, b.PiholeClient, merged)
+	if err == nil {
+		b.Logger.Debug("batch flushed", "changes", merged.Len(), "submissions", len(reqs))
+		for _, req := range reqs {
+			req.done <- nil
+		}
+		return
+	}
+	if len(reqs) == 1 {
+		b.Logger.Error("pihole api error", "operation", "batch", "changes", merged.Len(), "submissions", 1, "error", err)
+		reqs[0].done <- err
+		return
+	}
+
+	b.Logger.Warn("batch failed; replaying submissions separately", "changes", merged.Len(), "submissions", len(reqs), "error", err)
+	for _, req := range reqs {
+		err := pihole.ApplyBatch(ctx, b.PiholeClient, req.batch)
+		if err != nil {
+			b.Logger.Error("pihole api error", "operation", "batch", "changes", req.batch.Len(), "submissions", 1, "error", err)
+		}
+		req.done <- err
+	}
+}
+
+// fail completes every pending submission with err
+func (b *Batcher) fail(err error) {
+	for _, req := range b.take() {
+		req.done <- err
+	}
+}
+
+// take removes and returns the pending submissions
+func (b *Batcher) take() []*batchRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	reqs := b.pending
+	b.pending = nil
+	b.size = 0
+	return reqs
+}
+
+func (b *Batcher) maxSize() int {
+	if b.MaxSize <= 0 {
+		return DefaultBatchMaxSize
+	}
+	return b.MaxSize
+}
+
+// fullCh returns the channel signalling that MaxSize was reached
+func (b *Batcher) fullCh() chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.full == nil {
+		b.full = make(chan struct{}, 1)
+	}
+	return b.full
+}
+
+// NeedLeaderElection ensures only the leader writes to Pi-hole
+func (b *Batcher) NeedLeaderElection() bool {
+	return true
+}
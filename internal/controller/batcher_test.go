@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// startBatcher runs a batcher that only flushes once maxSize changes are waiting
+func startBatcher(t *testing.T, ph *fakePiholeClient, maxSize int) *Batcher {
+	t.Helper()
+	b := &Batcher{
+		PiholeClient: ph,
+		Logger:       slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		Interval:     time.Hour,
+		MaxSize:      maxSize,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = b.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return b
+}
+
+func TestBatcherCoalesces(t *testing.T) {
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "moved.local", IP: "10.0.0.1"})
+	b := startBatcher(t, ph, 4)
+
+	batches := []pihole.Batch{
+		{Creates: []pihole.DNSRecord{{Domain: "a.local", IP: "10.0.0.1"}}},
+		{Creates: []pihole.DNSRecord{{Domain: "b.local", IP: "10.0.0.1"}}},
+		{Deletes: []string{"moved.local"}, Creates: []pihole.DNSRecord{{Domain: "moved.local", IP: "10.0.0.2"}}},
+	}
+	var wg sync.WaitGroup
+	for _, batch := range batches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.Submit(context.Background(), batch); err != nil {
+				t.Errorf("Submit() unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(ph.batches) != 1 || ph.batches[0].Len() != 4 {
+		t.Fatalf("batches = %+v, want one batch of 4 changes", ph.batches)
+	}
+	// The delete of an IP change must not remove the record created in its place
+	if got := ph.ip("moved.local"); got != "10.0.0.2" {
+		t.Errorf("moved.local ip = %q, want 10.0.0.2", got)
+	}
+	if ph.ip("a.local") == "" || ph.ip("b.local") == "" {
+		t.Error("created records missing")
+	}
+}
+
+func TestBatcherFlushFailure(t *testing.T) {
+	ph := newFakePiholeClient()
+	ph.err = errors.New("pihole unreachable")
+	b := startBatcher(t, ph, 2)
+
+	errs := make(chan error, 2)
+	for _, domain := range []string{"a.local", "b.local"} {
+		go func() {
+			errs <- b.Submit(context.Background(), pihole.Batch{Creates: []pihole.DNSRecord{{Domain: domain, IP: "10.0.0.1"}}})
+		}()
+	}
+	for range 2 {
+		if err := <-errs; !errors.Is(err, ph.err) {
+			t.Errorf("Submit() error = %v, want the flush error", err)
+		}
+	}
+}
+
+func TestBatcherFlushReplaysSubmissions(t *testing.T) {
+	ph := newFakePiholeClient()
+	ph.reject = "bad host.local"
+	b := startBatcher(t, ph, 2)
+
+	errs := make(map[string]chan error)
+	for _, domain := range []string{"good.local", "bad host.local"} {
+		done := make(chan error, 1)
+		errs[domain] = done
+		go func() {
+			done <- b.Submit(context.Background(), pihole.Batch{Creates: []pihole.DNSRecord{{Domain: domain, IP: "10.0.0.1"}}})
+		}()
+	}
+	if err := <-errs["good.local"]; err != nil {
+		t.Errorf("Submit(good.local) error = %v, want the unrelated submission applied", err)
+	}
+	var apiErr *pihole.APIError
+	if err := <-errs["bad host.local"]; !errors.As(err, &apiErr) || apiErr.StatusCode != 400 {
+		t.Errorf("Submit(bad host.local) error = %v, want the 400 of its own replay", err)
+	}
+	if ph.ip("good.local") != "10.0.0.1" {
+		t.Errorf("good.local not created: %v", ph.records)
+	}
+	if len(ph.batches) != 3 {
+		t.Errorf("%d batches, want the merged one and a replay of each submission", len(ph.batches))
+	}
+}
+
+func TestBatcherSubmitCancelled(t *testing.T) {
+	b := &Batcher{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := b.Submit(ctx, pihole.Batch{Creates: []pihole.DNSRecord{{Domain: "a.local", IP: "10.0.0.1"}}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Submit() error = %v, want context.Canceled", err)
+	}
+}
+
+// TestReconcileBatched reconciles two Ingresses in parallel through one batch and
+// checks a failed flush, replayed per Ingress, requeues both
+func TestReconcileBatched(t *testing.T) {
+	for _, fail := range []bool{false, true} {
+		ph := newFakePiholeClient()
+		if fail {
+			ph.err = errors.New("pihole unreachable")
+		}
+		r := newTestReconciler(ph,
+			testIngress("a", map[string]string{AnnotationRegister: "true"}, "a.local"),
+			testIngress("b", map[string]string{AnnotationRegister: "true"}, "b.local"),
+		)
+		r.DisableFinalizers = true
+		r.Locks = &DomainLocks{}
+		r.Batcher = startBatcher(t, ph, 2)
+
+		results := make(chan ctrl.Result, 2)
+		for _, name := range []string{"a", "b"} {
+			go func() {
+				res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}})
+				if err != nil {
+					t.Errorf("Reconcile(%s) unexpected error: %v", name, err)
+				}
+				results <- res
+			}()
+		}
+		for range 2 {
+			res := <-results
+			if fail && res.RequeueAfter == 0 {
+				t.Error("failed flush did not requeue a contributing Ingress")
+			}
+			if !fail && res.RequeueAfter != 0 {
+				t.Errorf("successful flush requeued after %s", res.RequeueAfter)
+			}
+		}
+
+		// A failed flush replays each submission alone
+		wantBatches := 1
+		if fail {
+			wantBatches = 3
+		}
+		if len(ph.batches) != wantBatches {
+			t.Errorf("fail=%v: %d batches, want %d", fail, len(ph.batches), wantBatches)
+		}
+		wantManaged := "a.local"
+		if fail {
+			wantManaged = ""
+		}
+		if got := getIngress(t, r, "default", "a").Annotations[AnnotationManagedHosts]; got != wantManaged {
+			t.Errorf("fail=%v: managed hosts = %q, want %q", fail, got, wantManaged)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LabelRegister opts an object in like the pihole.io/register annotation. Annotations
+// cannot be selected on, so the label is what lets the cache skip everything else.
+const LabelRegister = "pihole.io/register"
+
+// RegisteredSelector matches objects labelled pihole.io/register=true
+func RegisteredSelector() labels.Selector {
+	return labels.SelectorFromSet(labels.Set{LabelRegister: "true"})
+}
+
+// CacheOptions returns manager cache options for the given source objects. Managed
+// fields are stripped from everything cached; the rest of the object is kept since
+// reconciles write cached objects back with Update, which leaves managed fields alone
+// when they are omitted but would erase any other field dropped here. Sources are
+// cached only if they match selector, when not nil, and with registeredOnly only if
+// they also carry the register label.
+func CacheOptions(registeredOnly bool, selector labels.Selector, sources ...client.Object) cache.Options {
+	opts := cache.Options{DefaultTransform: cache.TransformStripManagedFields()}
+	if selector == nil {
+		selector = labels.Everything()
+	}
+	if registeredOnly {
+		registered, _ := RegisteredSelector().Requirements()
+		selector = selector.Add(registered...)
+	}
+	if selector.Empty() {
+		return opts
+	}
+
+	opts.ByObject = make(map[client.Object]cache.ByObject, len(sources))
+	for _, obj := range sources {
+		opts.ByObject[obj] = cache.ByObject{Label: selector}
+	}
+	return opts
+}
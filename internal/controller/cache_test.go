@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// managedIngress builds an Ingress with the managed fields typical of one edited by
+// kubectl, an ingress controller and this operator
+func managedIngress() *networkingv1.Ingress {
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local", "api.local")
+	for i, manager := range []string{"kubectl-client-side-apply", "ingress-nginx", "pihole-ingress-operator"} {
+		fields := fmt.Sprintf(`{"f:metadata":{"f:annotations":{".":{},"f:pihole.io/register":{},"f:pihole.io/managed-hosts":{}},`+
+			`"f:finalizers":{".":{},"v:\"pihole.io/dns-cleanup\"":{}}},"f:spec":{"f:ingressClassName":{},"f:rules":{}},`+
+			`"f:status":{"f:loadBalancer":{"f:ingress":{}}},"f:entry":{"f:n":%d}}`, i)
+		ingress.ManagedFields = append(ingress.ManagedFields, metav1.ManagedFieldsEntry{
+			Manager:    manager,
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			APIVersion: "networking.k8s.io/v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(fields)},
+		})
+	}
+	return ingress
+}
+
+func objectSize(t testing.TB, obj any) int {
+	t.Helper()
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	return len(data)
+}
+
+func TestCacheOptionsTransform(t *testing.T) {
+	opts := CacheOptions(false, nil)
+	ingress := managedIngress()
+	before := objectSize(t, ingress)
+
+	out, err := opts.DefaultTransform(ingress)
+	if err != nil {
+		t.Fatalf("transform unexpected error: %v", err)
+	}
+	stripped := out.(*networkingv1.Ingress)
+	after := objectSize(t, stripped)
+	t.Logf("cached ingress: %d bytes before, %d bytes after", before, after)
+
+	if len(stripped.ManagedFields) != 0 {
+		t.Errorf("managed fields not stripped: %d entries", len(stripped.ManagedFields))
+	}
+	if after*2 > before {
+		t.Errorf("cached size %d bytes, want less than half of %d", after, before)
+	}
+	if got := (ingressSource{}).hosts(stripped); !slicesEqual(got, []string{"app.local", "api.local"}) {
+		t.Errorf("hosts() = %v, want the hosts to survive the transform", got)
+	}
+	if opts.ByObject != nil {
+		t.Errorf("ByObject = %v, want nil without registeredOnly", opts.ByObject)
+	}
+}
+
+func TestCacheOptionsRegisteredOnly(t *testing.T) {
+	ingress := &networkingv1.Ingress{}
+	opts := CacheOptions(true, nil, ingress)
+
+	sel := opts.ByObject[ingress].Label
+	if sel == nil {
+		t.Fatal("no label selector for the Ingress source")
+	}
+	if !sel.Matches(labels.Set{LabelRegister: "true"}) {
+		t.Error("selector does not match labelled objects")
+	}
+	if sel.Matches(labels.Set{}) || sel.Matches(labels.Set{LabelRegister: "false"}) {
+		t.Error("selector matches objects without the register label")
+	}
+}
+
+// cacheMiss is a client whose reads never find anything, like a label-filtered cache
+// after the object's label was removed
+type cacheMiss struct {
+	client.Client
+}
+
+func (c cacheMiss) Get(_ context.Context, key client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+	return errors.NewNotFound(networkingv1.Resource("ingresses"), key.Name)
+}
+
+func TestReconcileRegisterLabelRemoved(t *testing.T) {
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.100"})
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true", AnnotationManagedHosts: "app.local"}, "app.local")
+	controllerutil.AddFinalizer(ingress, FinalizerName)
+	r := newTestReconciler(ph, ingress)
+	r.RequireRegisterLabel = true
+	r.APIReader = r.Client
+	r.Client = cacheMiss{r.Client}
+
+	reconcileIngress(t, r, "default", "app")
+
+	if ph.ip("app.local") != "" {
+		t.Error("record not cleaned up after the object left the cache")
+	}
+	current := &networkingv1.Ingress{}
+	if err := r.APIReader.Get(context.Background(), client.ObjectKeyFromObject(ingress), current); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if controllerutil.ContainsFinalizer(current, FinalizerName) {
+		t.Error("finalizer not removed")
+	}
+	if _, ok := current.Annotations[AnnotationManagedHosts]; ok {
+		t.Error("managed hosts annotation not removed")
+	}
+}
+
+func TestCacheOptionsSelector(t *testing.T) {
+	ingress := &networkingv1.Ingress{}
+	selector, err := labels.Parse("team=platform,dns!=external")
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	sel := CacheOptions(true, selector, ingress).ByObject[ingress].Label
+	if sel == nil {
+		t.Fatal("no label selector for the Ingress source")
+	}
+	if !sel.Matches(labels.Set{"team": "platform", LabelRegister: "true"}) {
+		t.Error("selector does not match labelled objects of the team")
+	}
+	if sel.Matches(labels.Set{"team": "platform"}) || sel.Matches(labels.Set{"team": "platform", LabelRegister: "true", "dns": "external"}) {
+		t.Error("selector ignores part of the requirements")
+	}
+	if got := CacheOptions(false, labels.Everything(), ingress).ByObject; got != nil {
+		t.Errorf("ByObject = %v, want nil for an empty selector", got)
+	}
+}
+
+func TestReconcileLabelSelector(t *testing.T) {
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "old.local", IP: "192.168.1.100"})
+	other := testIngress("other", map[string]string{AnnotationRegister: "true", AnnotationManagedHosts: "old.local"}, "old.local")
+	other.Labels = map[string]string{"team": "web"}
+	controllerutil.AddFinalizer(other, FinalizerName)
+	platform := testIngress("platform", map[string]string{AnnotationRegister: "true"}, "app.local")
+	platform.Labels = map[string]string{"team": "platform"}
+	r := newTestReconciler(ph, other, platform)
+	selector, err := labels.Parse("team=platform")
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	r.LabelSelector = selector
+
+	reconcileIngress(t, r, "default", "other")
+	reconcileIngress(t, r, "default", "platform")
+
+	if ph.ip("old.local") != "" {
+		t.Error("records of an object that stopped matching were not cleaned up")
+	}
+	if controllerutil.ContainsFinalizer(getIngress(t, r, "default", "other"), FinalizerName) {
+		t.Error("finalizer not removed from an object that stopped matching")
+	}
+	if ph.ip("app.local") == "" {
+		t.Error("matching object not registered")
+	}
+}
+
+func BenchmarkCacheTransform(b *testing.B) {
+	transform := CacheOptions(false, nil).DefaultTransform
+	before := objectSize(b, managedIngress())
+	var after int
+	for b.Loop() {
+		out, err := transform(managedIngress())
+		if err != nil {
+			b.Fatal(err)
+		}
+		after = objectSize(b, out)
+	}
+	b.ReportMetric(float64(before), "bytes-before/op")
+	b.ReportMetric(float64(after), "bytes-after/op")
+}
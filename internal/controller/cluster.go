@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// skipOtherClusters drops from plan every change and adoption of a record that another
+// cluster sharing the Pi-hole has marked, whatever the overwrite setting, and reports
+// the desired ones. It returns the record keys dropped, which obj must stop tracking.
+func (r *IngressReconciler) skipOtherClusters(obj client.Object, plan *Plan, current []pihole.DNSRecord, logger *slog.Logger) []string {
+	clusters := make(map[string]string)
+	marked := make(map[string]bool)
+	for _, rec := range current {
+		if rec.OtherCluster(r.ClusterID) {
+			clusters[rec.Key()] = rec.Cluster
+			marked[rec.Key()] = true
+		}
+	}
+	if len(marked) == 0 {
+		return nil
+	}
+
+	var released []string
+	for _, d := range plan.Deletes {
+		if marked[d.Key()] {
+			released = append(released, d.Key())
+		}
+	}
+	if len(released) > 0 {
+		logger.Info("dns record taken over by another cluster, no longer tracked", "hosts", strings.Join(released, ","))
+	}
+	skipped := plan.dropRecords(marked)
+	for _, key := range skipped {
+		logger.Warn("dns record owned by another cluster", "host", key, "cluster", clusters[key])
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "RecordOwnedByOtherCluster",
+			"%s is managed by cluster %s; leaving it alone", key, clusters[key])
+	}
+	return append(skipped, released...)
+}
+
+// markCluster rewrites the unchanged records of plan that carry no cluster marker, so
+// records created before CLUSTER_ID was set are marked as this cluster's. Pi-hole has
+// no in-place update, so each becomes an update to the same IP.
+func (r *IngressReconciler) markCluster(plan *Plan, current []pihole.DNSRecord, logger *slog.Logger) {
+	if r.ClusterID == "" {
+		return
+	}
+	unmarked := make(map[string]pihole.DNSRecord)
+	for _, rec := range current {
+		if rec.Cluster == "" {
+			unmarked[rec.Key()] = rec
+		}
+	}
+	var marked []string
+	unchanged := plan.Unchanged[:0]
+	for _, key := range plan.Unchanged {
+		rec, ok := unmarked[key]
+		if !ok {
+			unchanged = append(unchanged, key)
+			continue
+		}
+		plan.Updates = append(plan.Updates, RecordUpdate{Domain: rec.Domain, OldIP: rec.IP, NewIP: rec.IP})
+		marked = append(marked, key)
+	}
+	if len(marked) == 0 {
+		return
+	}
+	plan.Unchanged = unchanged
+	sort.Slice(plan.Updates, func(i, j int) bool { return plan.Updates[i].Key() < plan.Updates[j].Key() })
+	logger.Info("marking dns records with the cluster id", "hosts", strings.Join(marked, ","), "cluster", r.ClusterID)
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// clusterReconciler returns a reconciler for cluster "lab" whose fake Pi-hole client
+// marks records the same way
+func clusterReconciler(ph *fakePiholeClient, ingress *networkingv1.Ingress) *IngressReconciler {
+	ph.cluster = "lab"
+	r := newTestReconciler(ph, ingress)
+	r.ClusterID = "lab"
+	return r
+}
+
+func TestReconcileOtherClusterRecords(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+	}{
+		{name: "another ip", ip: "10.0.0.1"},
+		{name: "same ip is not adopted", ip: "192.168.1.100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ph := newFakePiholeClient(pihole.DNSRecord{Domain: "shared.home.lan", IP: tt.ip, Cluster: "prod"})
+			ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "shared.home.lan", "app.home.lan")
+			r := clusterReconciler(ph, ingress)
+			r.AdoptExisting = true
+
+			reconcileIngress(t, r, "default", "app")
+			for _, call := range ph.calls {
+				if strings.Contains(call, "shared.home.lan") {
+					t.Errorf("unexpected pi-hole call %q for another cluster's record", call)
+				}
+			}
+			if ph.ip("shared.home.lan") != tt.ip || ph.clusters["shared.home.lan"] != "prod" {
+				t.Errorf("record of cluster prod changed: %v %v", ph.records, ph.clusters)
+			}
+			if ph.ip("app.home.lan") == "" || ph.clusters["app.home.lan"] != "lab" {
+				t.Errorf("app.home.lan not created for cluster lab: %v %v", ph.records, ph.clusters)
+			}
+			if managed := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; managed != "app.home.lan" {
+				t.Errorf("managed hosts = %q, want only app.home.lan", managed)
+			}
+			if want := "shared.home.lan is managed by cluster prod; leaving it alone"; !hasEvent(r, want) {
+				t.Errorf("no RecordOwnedByOtherCluster event %q", want)
+			}
+		})
+	}
+}
+
+func TestReconcileRecordTakenOverByOtherCluster(t *testing.T) {
+	// A record tracked here was since marked by cluster prod; removing the host must not
+	// delete it
+	ph := newFakePiholeClient(
+		pihole.DNSRecord{Domain: "app.home.lan", IP: "192.168.1.100", Cluster: "lab"},
+		pihole.DNSRecord{Domain: "old.home.lan", IP: "192.168.1.100", Cluster: "prod"},
+	)
+	ingress := testIngress("app", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationManagedHosts: "app.home.lan,old.home.lan",
+	}, "app.home.lan")
+	r := clusterReconciler(ph, ingress)
+
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("old.home.lan") == "" {
+		t.Errorf("record of cluster prod deleted: %v", ph.records)
+	}
+	for _, call := range ph.calls {
+		if call != "list" {
+			t.Errorf("unexpected pi-hole call %q", call)
+		}
+	}
+	if managed := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; managed != "app.home.lan" {
+		t.Errorf("managed hosts = %q, want old.home.lan no longer tracked", managed)
+	}
+}
+
+func TestReconcileMarksLegacyRecords(t *testing.T) {
+	// Records written before CLUSTER_ID was set carry no marker
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "app.home.lan", IP: "192.168.1.100"})
+	ingress := testIngress("app", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationManagedHosts: "app.home.lan",
+	}, "app.home.lan")
+	r := clusterReconciler(ph, ingress)
+	r.Registry = registry.NewStore(r.Client, r.Client, "pihole-operator", "registry")
+
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("app.home.lan") != "192.168.1.100" || ph.clusters["app.home.lan"] != "lab" {
+		t.Errorf("legacy record not marked for cluster lab: %v %v", ph.records, ph.clusters)
+	}
+	state, err := r.Registry.Get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.Records["app.home.lan"].ClusterID; got != "lab" {
+		t.Errorf("registry cluster id = %q, want lab", got)
+	}
+
+	// Once marked, resyncs leave the record alone
+	ph.calls = nil
+	reconcileIngress(t, r, "default", "app")
+	for _, call := range ph.calls {
+		if call != "list" {
+			t.Errorf("unexpected pi-hole call %q after marking", call)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// ConflictPolicy decides what happens when objects want different IPs for one record
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyStrict reports the conflict and leaves it to the objects to agree
+	ConflictPolicyStrict ConflictPolicy = "strict"
+
+	// ConflictPolicyOldestWins gives the record to the object created first, breaking
+	// ties by UID; the others leave it alone until the winner lets go of it
+	ConflictPolicyOldestWins ConflictPolicy = "oldest-wins"
+)
+
+// conflictRequeue is how often an object that lost a record checks whether it was handed over
+const conflictRequeue = 30 * time.Second
+
+// ParseConflictPolicy validates a conflict policy name
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case ConflictPolicyStrict, ConflictPolicyOldestWins:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %q", s)
+}
+
+// resolveConflicts applies oldest-wins to the desired records of obj, which must
+// already be in the index. It returns the records obj keeps, the keys it lost to an
+// older object and the keys it won from a newer one.
+func (r *IngressReconciler) resolveConflicts(obj client.Object, desired []pihole.DNSRecord, logger *slog.Logger) (kept []pihole.DNSRecord, lost, won []string) {
+	self := r.ownerOf(obj)
+	kept = desired[:0:0]
+	for _, rec := range desired {
+		targets := r.Index.Targets(rec.Key())
+		if !(DesiredRecord{Targets: targets}).Conflicting() {
+			kept = append(kept, rec)
+			continue
+		}
+		oldest := r.Index.Oldest(rec.Key())
+		if oldest == self {
+			kept = append(kept, rec)
+			won = append(won, rec.Key())
+			continue
+		}
+		lost = append(lost, rec.Key())
+		logger.Warn("dns record owned by an older object", "host", rec.Key(), "ip", rec.IP,
+			"owner", oldest, "owner_ip", targets[oldest])
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "RecordConflictLost",
+			"%s is owned by the older %s, which wants %s; not registering it", rec.Key(), oldest, targets[oldest])
+	}
+	if len(won) > 0 {
+		logger.Info("dns record kept from newer objects", "hosts", strings.Join(won, ","))
+	}
+	return kept, lost, won
+}
+
+// resolveNamespaceConflicts applies the cross-namespace duplicate policy to the desired
+// records of obj, which must already be in the index. Records also wanted from another
+// namespace go to the oldest object, as under oldest-wins; see
+// DesiredIndex.NamespaceConflict. It returns the records obj keeps, the keys it lost
+// and the keys it won.
+func (r *IngressReconciler) resolveNamespaceConflicts(obj client.Object, desired []pihole.DNSRecord, logger *slog.Logger) (kept []pihole.DNSRecord, lost, won []string) {
+	self := r.ownerOf(obj)
+	allow := r.settings().AllowCrossNamespaceDuplicates
+	kept = desired[:0:0]
+	for _, rec := range desired {
+		keeper := r.Index.NamespaceConflict(self, rec, allow)
+		switch keeper {
+		case "":
+			kept = append(kept, rec)
+			continue
+		case self:
+			kept = append(kept, rec)
+			won = append(won, rec.Key())
+			continue
+		}
+		lost = append(lost, rec.Key())
+		logger.Warn("dns record owned by an object in another namespace", "host", rec.Key(), "ip", rec.IP,
+			"owner", keeper, "owner_ip", r.Index.Targets(rec.Key())[keeper])
+		if allow {
+			r.Recorder.Eventf(obj, corev1.EventTypeWarning, "CrossNamespaceConflict",
+				"%s is owned by %s in another namespace, which wants %s; not registering it",
+				rec.Key(), keeper, r.Index.Targets(rec.Key())[keeper])
+		} else {
+			r.Recorder.Eventf(obj, corev1.EventTypeWarning, "CrossNamespaceConflict",
+				"%s is owned by %s in another namespace; not registering it", rec.Key(), keeper)
+		}
+	}
+	if len(won) > 0 {
+		logger.Info("dns record kept from objects in other namespaces", "hosts", strings.Join(won, ","))
+	}
+	return kept, lost, won
+}
+
+// handedOver returns the keys, among those of ips which maps record keys to the IP
+// Pi-hole holds, that another object still wants at a different IP. Under oldest-wins
+// they are deleted rather than kept for it, so the next owner creates its own record
+// instead of finding a foreign one in its way.
+func (r *IngressReconciler) handedOver(ips map[string]string) []string {
+	if r.settings().ConflictPolicy != ConflictPolicyOldestWins {
+		return nil
+	}
+	var keys []string
+	for key, ip := range ips {
+		next := r.Index.Oldest(key)
+		if next != "" && r.Index.Targets(key)[next] != ip {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// targetIPs maps managed-hosts keys to the IPs obj resolves for them
+func (r *IngressReconciler) targetIPs(obj client.Object, keys []string) map[string]string {
+	ips := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if _, recordType := pihole.ParseRecordKey(key); recordType == pihole.TypeAAAA {
+			ips[key], _ = r.resolveTargetIPv6(obj)
+		} else {
+			ips[key] = r.resolveTargetIP(obj)
+		}
+	}
+	return ips
+}
@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestParseConflictPolicy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ConflictPolicy
+		wantErr bool
+	}{
+		{input: "strict", want: ConflictPolicyStrict},
+		{input: " Oldest-Wins ", want: ConflictPolicyOldestWins},
+		{input: "newest-wins", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseConflictPolicy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConflictPolicy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseConflictPolicy(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDesiredIndexOldest(t *testing.T) {
+	x := &DesiredIndex{}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, owner := range []string{"Ingress default/a", "Ingress default/b", "Ingress default/c", "Ingress default/d"} {
+		x.Set(owner, []pihole.DNSRecord{{Domain: "shared.local", IP: "10.0.0.1"}})
+	}
+	x.SetAge("Ingress default/b", created.Add(time.Hour), "2")
+	x.SetAge("Ingress default/c", created, "3")
+	x.SetAge("Ingress default/d", created, "1")
+
+	// Same timestamp: the lower UID wins; a has no known age and comes last
+	if got := x.Oldest("shared.local"); got != "Ingress default/d" {
+		t.Errorf("Oldest() = %q, want Ingress default/d", got)
+	}
+	x.Remove("Ingress default/d")
+	if got := x.Oldest("shared.local"); got != "Ingress default/c" {
+		t.Errorf("Oldest() after removal = %q, want Ingress default/c", got)
+	}
+	if got := x.Oldest("other.local"); got != "" {
+		t.Errorf("Oldest() of an unwanted key = %q, want none", got)
+	}
+}
+
+// agedIngress registers hosts at ip for an Ingress created the given hours after a fixed time
+func agedIngress(name, ip string, hours int, annotations map[string]string, hosts ...string) *networkingv1.Ingress {
+	ingress := testIngress(name, annotations, hosts...)
+	ingress.Annotations[AnnotationRegister] = "true"
+	ingress.Annotations[AnnotationTargetIP] = ip
+	ingress.CreationTimestamp = metav1.NewTime(time.Date(2024, 1, 1, hours, 0, 0, 0, time.UTC))
+	ingress.UID = types.UID(name)
+	return ingress
+}
+
+func TestReconcileOldestWinsHandover(t *testing.T) {
+	ctx := context.Background()
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		agedIngress("old", "10.0.0.1", 0, map[string]string{}, "shared.local"),
+		agedIngress("new", "10.0.0.2", 1, map[string]string{}, "shared.local", "new.local"),
+	)
+	r.Index = &DesiredIndex{}
+	r.ConflictPolicy = ConflictPolicyOldestWins
+	events := r.Recorder.(*record.FakeRecorder).Events
+
+	reconcileIngress(t, r, "default", "old")
+	res := reconcileIngress(t, r, "default", "new")
+
+	if got := ph.ip("shared.local"); got != "10.0.0.1" {
+		t.Errorf("shared.local = %q, want the older object's 10.0.0.1", got)
+	}
+	if got := getIngress(t, r, "default", "new").Annotations[AnnotationManagedHosts]; got != "new.local" {
+		t.Errorf("newer managed hosts = %q, want new.local only", got)
+	}
+	if res.RequeueAfter != conflictRequeue {
+		t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, conflictRequeue)
+	}
+	select {
+	case event := <-events:
+		if !strings.Contains(event, "RecordConflictLost") || !strings.Contains(event, "Ingress default/old") {
+			t.Errorf("event = %q, want RecordConflictLost naming the older object", event)
+		}
+	default:
+		t.Error("no event for the lost record")
+	}
+
+	// The owner reconciling again does not take the record back and forth
+	calls := len(ph.calls)
+	reconcileIngress(t, r, "default", "old")
+	reconcileIngress(t, r, "default", "new")
+	if got := ph.calls[calls:]; !slicesEqual(got, []string{"list", "list"}) {
+		t.Errorf("pihole calls in steady state = %v, want listings only", got)
+	}
+
+	// Deleting the owner hands the record over
+	if err := r.Delete(ctx, getIngress(t, r, "default", "old")); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "old")
+	if got := ph.ip("shared.local"); got != "" {
+		t.Errorf("shared.local = %q after the owner was deleted, want it released", got)
+	}
+	reconcileIngress(t, r, "default", "new")
+
+	if got := ph.ip("shared.local"); got != "10.0.0.2" {
+		t.Errorf("shared.local = %q, want the new owner's 10.0.0.2", got)
+	}
+	if got := getIngress(t, r, "default", "new").Annotations[AnnotationManagedHosts]; got != "shared.local,new.local" {
+		t.Errorf("new owner managed hosts = %q, want shared.local,new.local", got)
+	}
+}
+
+// TestReconcileOldestWinsTakesOver lets an older object registering late replace the
+// newer one's record even though it may not overwrite records it did not create
+func TestReconcileOldestWinsTakesOver(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		agedIngress("old", "10.0.0.1", 0, map[string]string{AnnotationOverwrite: "false"}, "shared.local"),
+		agedIngress("new", "10.0.0.2", 1, map[string]string{}, "shared.local"),
+	)
+	r.Index = &DesiredIndex{}
+	r.ConflictPolicy = ConflictPolicyOldestWins
+
+	reconcileIngress(t, r, "default", "new")
+	reconcileIngress(t, r, "default", "old")
+	reconcileIngress(t, r, "default", "new")
+
+	if got := ph.ip("shared.local"); got != "10.0.0.1" {
+		t.Errorf("shared.local = %q, want the older object's 10.0.0.1", got)
+	}
+	if got := getIngress(t, r, "default", "old").Annotations[AnnotationManagedHosts]; got != "shared.local" {
+		t.Errorf("older managed hosts = %q, want shared.local", got)
+	}
+	if got := getIngress(t, r, "default", "new").Annotations[AnnotationManagedHosts]; got != "" {
+		t.Errorf("newer managed hosts = %q, want none", got)
+	}
+}
+
+func TestDesiredIndexNamespaceConflict(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		other string
+		ip    string
+		allow bool
+		// older ages the other owner before the one asking
+		older bool
+		want  string
+	}{
+		{name: "same namespace same ip", other: "Ingress tenant-a/other", ip: "10.0.0.1"},
+		{name: "same namespace different ip", other: "Ingress tenant-a/other", ip: "10.0.0.2", older: true},
+		{name: "same namespace allowed", other: "Ingress tenant-a/other", ip: "10.0.0.2", allow: true, older: true},
+		{name: "other namespace same ip", other: "Ingress tenant-b/other", ip: "10.0.0.1", older: true,
+			want: "Ingress tenant-b/other"},
+		{name: "other namespace same ip newer", other: "Ingress tenant-b/other", ip: "10.0.0.1",
+			want: "Ingress tenant-a/portal"},
+		{name: "other namespace different ip", other: "Ingress tenant-b/other", ip: "10.0.0.2", older: true,
+			want: "Ingress tenant-b/other"},
+		{name: "other namespace same ip allowed", other: "Ingress tenant-b/other", ip: "10.0.0.1", allow: true, older: true},
+		{name: "other namespace different ip allowed", other: "Ingress tenant-b/other", ip: "10.0.0.2", allow: true,
+			older: true, want: "Ingress tenant-b/other"},
+		{name: "other namespace different ip allowed newer", other: "Ingress tenant-b/other", ip: "10.0.0.2",
+			allow: true, want: "Ingress tenant-a/portal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const self = "Ingress tenant-a/portal"
+			rec := pihole.DNSRecord{Domain: "portal.home.lan", IP: "10.0.0.1"}
+			x := &DesiredIndex{}
+			x.Set(self, []pihole.DNSRecord{rec})
+			x.Set(tt.other, []pihole.DNSRecord{{Domain: "portal.home.lan", IP: tt.ip}})
+			x.SetAge(self, created.Add(time.Hour), "1")
+			if tt.older {
+				x.SetAge(tt.other, created, "2")
+			} else {
+				x.SetAge(tt.other, created.Add(2*time.Hour), "2")
+			}
+
+			if got := x.NamespaceConflict(self, rec, tt.allow); got != tt.want {
+				t.Errorf("NamespaceConflict() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	var nilIndex *DesiredIndex
+	if got := nilIndex.NamespaceConflict("Ingress tenant-a/portal", pihole.DNSRecord{Domain: "portal.home.lan"}, false); got != "" {
+		t.Errorf("nil NamespaceConflict() = %q, want none", got)
+	}
+}
+
+func TestReconcileCrossNamespaceDuplicates(t *testing.T) {
+	tests := []struct {
+		name     string
+		allow    bool
+		newerIP  string
+		wantKept bool
+	}{
+		{name: "duplicates refused", newerIP: "10.0.0.1"},
+		{name: "duplicates allowed with the same ip", allow: true, newerIP: "10.0.0.1", wantKept: true},
+		{name: "duplicates allowed with a different ip", allow: true, newerIP: "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			older := agedIngress("portal", "10.0.0.1", 0, map[string]string{}, "portal.home.lan")
+			older.Namespace = "tenant-a"
+			newer := agedIngress("portal", tt.newerIP, 1, map[string]string{}, "portal.home.lan")
+			newer.Namespace = "tenant-b"
+			ph := newFakePiholeClient()
+			r := newTestReconciler(ph, older, newer)
+			r.Index = &DesiredIndex{}
+			r.AllowCrossNamespaceDuplicates = tt.allow
+
+			// The newer object registering first still gives the record up
+			reconcileIngress(t, r, "tenant-b", "portal")
+			reconcileIngress(t, r, "tenant-a", "portal")
+			res := reconcileIngress(t, r, "tenant-b", "portal")
+
+			if got := ph.ip("portal.home.lan"); got != "10.0.0.1" {
+				t.Errorf("portal.home.lan = %q, want the older object's 10.0.0.1", got)
+			}
+			if got := getIngress(t, r, "tenant-a", "portal").Annotations[AnnotationManagedHosts]; got != "portal.home.lan" {
+				t.Errorf("older managed hosts = %q, want portal.home.lan", got)
+			}
+			got := getIngress(t, r, "tenant-b", "portal").Annotations[AnnotationManagedHosts]
+			if kept := got == "portal.home.lan"; kept != tt.wantKept {
+				t.Errorf("newer managed hosts = %q, shared = %v, want %v", got, kept, tt.wantKept)
+			}
+			if tt.wantKept {
+				return
+			}
+			if res.RequeueAfter != conflictRequeue {
+				t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, conflictRequeue)
+			}
+			if !hasEvent(r, "portal.home.lan is owned by Ingress tenant-a/portal in another namespace") {
+				t.Error("no CrossNamespaceConflict event naming the owner")
+			}
+		})
+	}
+}
+
+func TestReconcileSameNamespaceDuplicatesShared(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		agedIngress("a", "10.0.0.1", 0, map[string]string{}, "portal.home.lan"),
+		agedIngress("b", "10.0.0.1", 1, map[string]string{}, "portal.home.lan"),
+	)
+	r.Index = &DesiredIndex{}
+
+	reconcileIngress(t, r, "default", "a")
+	reconcileIngress(t, r, "default", "b")
+	for _, name := range []string{"a", "b"} {
+		if got := getIngress(t, r, "default", name).Annotations[AnnotationManagedHosts]; got != "portal.home.lan" {
+			t.Errorf("%s managed hosts = %q, want the shared portal.home.lan", name, got)
+		}
+	}
+	if hasEvent(r, "CrossNamespaceConflict") {
+		t.Error("CrossNamespaceConflict for objects in one namespace")
+	}
+}
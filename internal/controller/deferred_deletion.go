@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/notify"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// DefaultDeferredDeletionInterval is how often scheduled deletions are checked
+const DefaultDeferredDeletionInterval = 30 * time.Second
+
+// DeferredDeleter removes DNS records whose deletion was postponed, either by the
+// pihole.io/deletion-grace-period annotation or because finalizer cleanup gave up
+// while Pi-hole was unreachable. Scheduled deletions live in the registry so they
+// survive operator restarts; a reconcile that claims the same host cancels its
+// pending deletion.
+type DeferredDeleter struct {
+	PiholeClient pihole.Client
+	Registry     *registry.Store
+	Logger       *slog.Logger
+	Interval     time.Duration
+
+	// Audit receives every deletion; nil disables auditing
+	Audit audit.Sink
+
+	// Notifier receives a summary of each pass's deletions; nil disables it
+	Notifier *notify.Dispatcher
+
+	// Locks serializes deletions with reconciles writing the same domain; nil disables it
+	Locks *DomainLocks
+}
+
+// Start runs the deletion loop until ctx is cancelled; it implements manager.Runnable
+func (d *DeferredDeleter) Start(ctx context.Context) error {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = DefaultDeferredDeletionInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		d.runOnce(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection ensures only the leader deletes records
+func (d *DeferredDeleter) NeedLeaderElection() bool {
+	return true
+}
+
+// runOnce deletes every record whose grace period has elapsed
+func (d *DeferredDeleter) runOnce(ctx context.Context, now time.Time) {
+	state, err := d.Registry.Get(ctx)
+	if err != nil {
+		d.Logger.Error("failed to read registry", "error", err)
+		return
+	}
+
+	due := state.DuePendingDeletions(now)
+	sort.Strings(due)
+	var deleted []string
+	for _, host := range due {
+		pending := state.PendingDeletions[host]
+
+		// Claim the entry before deleting; a reconcile may have cancelled or
+		// rescheduled it since the state was read
+		claimed := false
+		if err := d.Registry.Update(ctx, func(st *registry.State) bool {
+			current, ok := st.PendingDeletions[host]
+			if !ok || !current.DeleteAfter.Equal(pending.DeleteAfter) {
+				return false
+			}
+			delete(st.PendingDeletions, host)
+			claimed = true
+			return true
+		}); err != nil {
+			d.Logger.Error("failed to update registry", "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		domain, recordType := pihole.ParseRecordKey(host)
+		unlock := d.Locks.Lock(domain)
+		err := d.PiholeClient.DeleteRecord(ctx, domain, recordType)
+		unlock()
+		if err != nil {
+			d.Logger.Error("pihole api error", "operation", "delete", "host", host, "error", err)
+			// Put the entry back so the next pass retries it
+			if err := d.Registry.Update(ctx, func(st *registry.State) bool {
+				if _, ok := st.PendingDeletions[host]; ok {
+					return false
+				}
+				st.AddPendingDeletion(host, pending)
+				return true
+			}); err != nil {
+				d.Logger.Error("failed to update registry", "error", err)
+			}
+			continue
+		}
+		attrs := []any{"host", host, "owner", pending.Owner, "reason", pending.Reason}
+		if owner, ok := state.Records[host]; ok {
+			attrs = append(attrs, "ownership", owner.String())
+		}
+		d.Logger.Info("dns record deleted", attrs...)
+		forgetRecords(ctx, d.Registry, []string{host}, d.Logger)
+		deleted = append(deleted, host)
+		recordAudit(ctx, d.Audit, audit.Entry{Action: audit.ActionDelete, Domain: domain, Type: recordType, Owner: pending.Owner}, d.Logger)
+	}
+	d.Notifier.Enqueue(deletionSummary("deferred deletion", deleted))
+}
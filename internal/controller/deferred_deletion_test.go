@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+func TestDeferredDeleterRunOnce(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	store := registry.NewStore(k8s, k8s, "pihole-operator", "registry")
+	if err := store.Update(ctx, func(st *registry.State) bool {
+		st.SchedulePendingDeletion("due.local", "Ingress default/old", now.Add(-time.Second))
+		st.SchedulePendingDeletion("later.local", "Ingress default/old", now.Add(time.Hour))
+		return true
+	}); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+
+	ph := newFakePiholeClient(
+		pihole.DNSRecord{Domain: "due.local", IP: "192.168.1.100"},
+		pihole.DNSRecord{Domain: "later.local", IP: "192.168.1.100"},
+	)
+	d := &DeferredDeleter{PiholeClient: ph, Registry: store, Logger: logger}
+
+	d.runOnce(ctx, now)
+
+	if ph.ip("due.local") != "" {
+		t.Error("due.local should have been deleted")
+	}
+	if ph.ip("later.local") == "" {
+		t.Error("later.local should not be deleted before its grace period")
+	}
+
+	state, err := store.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if _, ok := state.PendingDeletions["due.local"]; ok {
+		t.Error("due.local should no longer be pending")
+	}
+	if _, ok := state.PendingDeletions["later.local"]; !ok {
+		t.Error("later.local should still be pending")
+	}
+}
+
+func TestDeferredDeleterRetriesOnFailure(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	now := time.Now()
+
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	store := registry.NewStore(k8s, k8s, "pihole-operator", "registry")
+	if err := store.Update(ctx, func(st *registry.State) bool {
+		st.SchedulePendingDeletion("due.local", "Ingress default/old", now.Add(-time.Second))
+		return true
+	}); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "due.local", IP: "192.168.1.100"})
+	ph.err = errors.New("pihole unreachable")
+	d := &DeferredDeleter{PiholeClient: ph, Registry: store, Logger: logger}
+
+	d.runOnce(ctx, now)
+
+	state, err := store.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if _, ok := state.PendingDeletions["due.local"]; !ok {
+		t.Error("failed deletion should remain pending for retry")
+	}
+}
+
+func TestHandleDeletionWithGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name        string
+		grace       string
+		wantDeleted bool
+		wantPending bool
+	}{
+		{"no grace period", "", true, false},
+		{"valid grace period", "10m", false, true},
+		{"invalid grace period", "soon", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "app",
+					Namespace:  "default",
+					Finalizers: []string{FinalizerName},
+					Annotations: map[string]string{
+						AnnotationManagedHosts:        "app.local",
+						AnnotationDeletionGracePeriod: tt.grace,
+					},
+				},
+			}
+			k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ingress).Build()
+			store := registry.NewStore(k8s, k8s, "pihole-operator", "registry")
+			ph := newFakePiholeClient(pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.100"})
+			r := &IngressReconciler{
+				Client:       k8s,
+				PiholeClient: ph,
+				Logger:       logger,
+				Recorder:     record.NewFakeRecorder(10),
+				Registry:     store,
+				Backoff:      NewBackoff(DefaultBackoffBase, DefaultBackoffMax),
+			}
+
+			if _, err := r.handleDeletion(ctx, ingress, logger); err != nil {
+				t.Fatalf("handleDeletion() unexpected error: %v", err)
+			}
+			if controllerutil.ContainsFinalizer(ingress, FinalizerName) {
+				t.Error("finalizer should be removed promptly")
+			}
+			if deleted := ph.ip("app.local") == ""; deleted != tt.wantDeleted {
+				t.Errorf("record deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			state, err := store.Get(ctx)
+			if err != nil {
+				t.Fatalf("Get() unexpected error: %v", err)
+			}
+			if _, pending := state.PendingDeletions["app.local"]; pending != tt.wantPending {
+				t.Errorf("pending deletion = %v, want %v", pending, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestHandleDeletionAbandonsCleanup(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		maxAttempts  int
+		deletedAgo   time.Duration
+		wantReleased bool
+	}{
+		{name: "within timeout", timeout: time.Hour, deletedAgo: time.Minute, wantReleased: false},
+		{name: "timeout exceeded", timeout: time.Hour, deletedAgo: 2 * time.Hour, wantReleased: true},
+		{name: "max attempts reached", maxAttempts: 1, deletedAgo: time.Minute, wantReleased: true},
+		{name: "no limits", deletedAgo: 24 * time.Hour, wantReleased: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "app",
+					Namespace:         "default",
+					Annotations:       map[string]string{AnnotationManagedHosts: "app.local"},
+					Finalizers:        []string{FinalizerName},
+					DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-tt.deletedAgo)},
+				},
+			}
+			k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ingress).Build()
+			store := registry.NewStore(k8s, k8s, "pihole-operator", "registry")
+			ph := newFakePiholeClient(pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.100"})
+			ph.err = errors.New("connection refused")
+			recorder := record.NewFakeRecorder(10)
+			r := &IngressReconciler{
+				Client:               k8s,
+				PiholeClient:         ph,
+				Logger:               logger,
+				Recorder:             recorder,
+				Registry:             store,
+				Backoff:              NewBackoff(DefaultBackoffBase, DefaultBackoffMax),
+				FinalizerTimeout:     tt.timeout,
+				FinalizerMaxAttempts: tt.maxAttempts,
+			}
+
+			if _, err := r.handleDeletion(ctx, ingress, logger); err != nil {
+				t.Fatalf("handleDeletion() unexpected error: %v", err)
+			}
+			if released := !controllerutil.ContainsFinalizer(ingress, FinalizerName); released != tt.wantReleased {
+				t.Errorf("finalizer released = %v, want %v", released, tt.wantReleased)
+			}
+			state, err := store.Get(ctx)
+			if err != nil {
+				t.Fatalf("Get() unexpected error: %v", err)
+			}
+			pending, ok := state.PendingDeletions["app.local"]
+			if ok != tt.wantReleased {
+				t.Fatalf("pending cleanup = %v, want %v", ok, tt.wantReleased)
+			}
+			if ok && pending.Reason != registry.ReasonCleanupTimeout {
+				t.Errorf("pending reason = %q, want %q", pending.Reason, registry.ReasonCleanupTimeout)
+			}
+			if tt.wantReleased && len(recorder.Events) == 0 {
+				t.Error("expected CleanupAbandoned event")
+			}
+		})
+	}
+}
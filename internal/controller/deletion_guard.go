@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DeletionScopeSync identifies the per-reconcile deletion limit
+	DeletionScopeSync = "sync"
+
+	// DeletionScopeInterval identifies the rolling-window deletion budget
+	DeletionScopeInterval = "interval"
+)
+
+// DeletionLimitError is returned when a set of deletions would exceed a safety threshold
+type DeletionLimitError struct {
+	Scope      string
+	Requested  int
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e *DeletionLimitError) Error() string {
+	return fmt.Sprintf("refusing to delete %d records: exceeds %s limit of %d", e.Requested, e.Scope, e.Limit)
+}
+
+// DeletionGuard is a circuit breaker limiting how many DNS records may be deleted,
+// both within a single reconcile and across all reconciles in a rolling window.
+// A zero limit disables the corresponding check; a nil guard allows everything.
+type DeletionGuard struct {
+	MaxPerSync     int
+	MaxPerInterval int
+	Interval       time.Duration
+
+	mu      sync.Mutex
+	history []time.Time
+	now     func() time.Time
+}
+
+// Reserve checks whether n deletions may proceed and, if so, charges them to the budget
+func (g *DeletionGuard) Reserve(n int) error {
+	if g == nil || n == 0 {
+		return nil
+	}
+
+	if g.MaxPerSync > 0 && n > g.MaxPerSync {
+		return &DeletionLimitError{Scope: DeletionScopeSync, Requested: n, Limit: g.MaxPerSync}
+	}
+
+	if g.MaxPerInterval <= 0 || g.Interval <= 0 {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock()
+	cutoff := now.Add(-g.Interval)
+	kept := g.history[:0]
+	for _, t := range g.history {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	g.history = kept
+
+	if len(g.history)+n > g.MaxPerInterval {
+		retryAfter := g.Interval
+		if len(g.history) > 0 {
+			retryAfter = g.history[0].Add(g.Interval).Sub(now)
+		}
+		return &DeletionLimitError{
+			Scope:      DeletionScopeInterval,
+			Requested:  n,
+			Limit:      g.MaxPerInterval,
+			RetryAfter: retryAfter,
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		g.history = append(g.history, now)
+	}
+	return nil
+}
+
+// Refund returns n reserved deletions that did not happen, such as those left when a
+// Pi-hole call failed, so retries of a failing delete do not use up the budget
+func (g *DeletionGuard) Refund(n int) {
+	if g == nil || n <= 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.history = g.history[:max(len(g.history)-n, 0)]
+}
+
+func (g *DeletionGuard) clock() time.Time {
+	if g.now != nil {
+		return g.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestDeletionGuardReserve(t *testing.T) {
+	tests := []struct {
+		name      string
+		guard     *DeletionGuard
+		requests  []int
+		wantScope string // scope of the error for the last request, empty for success
+	}{
+		{
+			name:     "nil guard allows everything",
+			guard:    nil,
+			requests: []int{1000},
+		},
+		{
+			name:     "zero limits allow everything",
+			guard:    &DeletionGuard{},
+			requests: []int{1000},
+		},
+		{
+			name:     "within per-sync limit",
+			guard:    &DeletionGuard{MaxPerSync: 5},
+			requests: []int{5},
+		},
+		{
+			name:      "exceeds per-sync limit",
+			guard:     &DeletionGuard{MaxPerSync: 5},
+			requests:  []int{6},
+			wantScope: DeletionScopeSync,
+		},
+		{
+			name:     "within interval budget",
+			guard:    &DeletionGuard{MaxPerInterval: 10, Interval: time.Hour},
+			requests: []int{4, 6},
+		},
+		{
+			name:      "exceeds interval budget across reconciles",
+			guard:     &DeletionGuard{MaxPerInterval: 10, Interval: time.Hour},
+			requests:  []int{4, 4, 4},
+			wantScope: DeletionScopeInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			for _, n := range tt.requests {
+				err = tt.guard.Reserve(n)
+			}
+
+			if tt.wantScope == "" {
+				if err != nil {
+					t.Errorf("Reserve() unexpected error: %v", err)
+				}
+				return
+			}
+
+			var limitErr *DeletionLimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("Reserve() error = %v, want DeletionLimitError", err)
+			}
+			if limitErr.Scope != tt.wantScope {
+				t.Errorf("Reserve() scope = %q, want %q", limitErr.Scope, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestDeletionGuardIntervalExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := &DeletionGuard{
+		MaxPerInterval: 3,
+		Interval:       time.Hour,
+		now:            func() time.Time { return now },
+	}
+
+	if err := g.Reserve(3); err != nil {
+		t.Fatalf("Reserve() unexpected error: %v", err)
+	}
+
+	now = now.Add(20 * time.Minute)
+	err := g.Reserve(1)
+	var limitErr *DeletionLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Reserve() error = %v, want DeletionLimitError", err)
+	}
+	if limitErr.RetryAfter != 40*time.Minute {
+		t.Errorf("RetryAfter = %v, want %v", limitErr.RetryAfter, 40*time.Minute)
+	}
+
+	now = now.Add(41 * time.Minute)
+	if err := g.Reserve(3); err != nil {
+		t.Errorf("Reserve() after window expired unexpected error: %v", err)
+	}
+}
+
+func TestCheckDeletionGuard(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		hosts       []string
+		wantBlocked bool
+	}{
+		{
+			name:        "below threshold",
+			hosts:       []string{"a.local"},
+			wantBlocked: false,
+		},
+		{
+			name:        "above threshold",
+			hosts:       []string{"a.local", "b.local", "c.local"},
+			wantBlocked: true,
+		},
+		{
+			name: "above threshold with confirmation",
+			annotations: map[string]string{
+				AnnotationConfirmDeletions: "true",
+			},
+			hosts:       []string{"a.local", "b.local", "c.local"},
+			wantBlocked: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &IngressReconciler{
+				Logger:        logger,
+				Recorder:      recorder,
+				DeletionGuard: &DeletionGuard{MaxPerSync: 2},
+			}
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+			}
+
+			blocked, _ := r.checkDeletionGuard(ingress, tt.hosts, logger)
+			if blocked != tt.wantBlocked {
+				t.Errorf("checkDeletionGuard() blocked = %v, want %v", blocked, tt.wantBlocked)
+			}
+			if tt.wantBlocked && len(recorder.Events) != 1 {
+				t.Errorf("expected one Warning event, got %d", len(recorder.Events))
+			}
+		})
+	}
+}
+
+func TestReconcileDeletionGuardRefundsFailedDeletes(t *testing.T) {
+	// A Pi-hole failing every delete must not use up the budget, or the guard trips
+	// before anything was deleted
+	ph := newFakePiholeClient(
+		pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.100"},
+		pihole.DNSRecord{Domain: "old.local", IP: "192.168.1.100"},
+	)
+	ingress := testIngress("app", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationManagedHosts: "app.local,old.local",
+	}, "app.local")
+	r := newTestReconciler(ph, ingress)
+	r.DeletionGuard = &DeletionGuard{MaxPerInterval: 2, Interval: time.Hour}
+
+	ph.err = errors.New("pihole unreachable")
+	for range 3 {
+		reconcileIngress(t, r, "default", "app")
+	}
+	ph.err = nil
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("old.local") != "" {
+		t.Errorf("old.local not deleted once Pi-hole recovered: %v", ph.records)
+	}
+	if hasEvent(r, "DeletionsBlocked") {
+		t.Error("deletions blocked although every earlier delete failed")
+	}
+}
+
+func TestDeletionGuardRefund(t *testing.T) {
+	g := &DeletionGuard{MaxPerInterval: 3, Interval: time.Hour}
+	if err := g.Reserve(3); err != nil {
+		t.Fatalf("Reserve() unexpected error: %v", err)
+	}
+	g.Refund(2)
+	if err := g.Reserve(2); err != nil {
+		t.Errorf("Reserve() after refund unexpected error: %v", err)
+	}
+	g.Refund(10)
+	if len(g.history) != 0 {
+		t.Errorf("history = %v, want empty after refunding more than reserved", g.history)
+	}
+
+	var unset *DeletionGuard
+	unset.Refund(1)
+}
@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// DesiredRecord is one record key and the IP each owner wants for it
+type DesiredRecord struct {
+	Domain string `json:"domain"`
+	Type   string `json:"type"`
+
+	// Targets maps each owner to the IP it wants
+	Targets map[string]string `json:"targets"`
+}
+
+// Conflicting reports whether the owners disagree on the IP
+func (d DesiredRecord) Conflicting() bool {
+	var first string
+	for _, ip := range d.Targets {
+		if first == "" {
+			first = ip
+		} else if ip != first {
+			return true
+		}
+	}
+	return false
+}
+
+// DesiredIndex holds the desired records of every watched object across all sources.
+// Reconciles update an object's entry whenever they compute what it wants and drop it
+// once it stops registering, so questions spanning objects — does anyone else still
+// want this record, do owners disagree on its IP — are answered without listing
+// objects. Entries fill in as objects are reconciled, which the initial informer sync
+// does for all of them. A nil *DesiredIndex is empty.
+type DesiredIndex struct {
+	mu      sync.RWMutex
+	byOwner map[string][]pihole.DNSRecord
+	byKey   map[string]map[string]string
+	ages    map[string]ownerAge
+}
+
+// ownerAge orders owners for the oldest-wins conflict policy
+type ownerAge struct {
+	created time.Time
+	uid     string
+}
+
+// before reports whether a is older than b, breaking ties by UID
+func (a ownerAge) before(b ownerAge) bool {
+	if !a.created.Equal(b.created) {
+		return a.created.Before(b.created)
+	}
+	return a.uid < b.uid
+}
+
+// Set replaces the desired records of owner
+func (x *DesiredIndex) Set(owner string, records []pihole.DNSRecord) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.removeLocked(owner)
+	if len(records) == 0 {
+		return
+	}
+	if x.byOwner == nil {
+		x.byOwner = make(map[string][]pihole.DNSRecord)
+		x.byKey = make(map[string]map[string]string)
+	}
+	x.byOwner[owner] = append([]pihole.DNSRecord(nil), records...)
+	for _, rec := range records {
+		targets := x.byKey[rec.Key()]
+		if targets == nil {
+			targets = make(map[string]string)
+			x.byKey[rec.Key()] = targets
+		}
+		targets[owner] = rec.IP
+	}
+}
+
+// Remove forgets owner
+func (x *DesiredIndex) Remove(owner string) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(owner)
+	delete(x.ages, owner)
+}
+
+// SetAge records when owner was created, with its UID to break ties
+func (x *DesiredIndex) SetAge(owner string, created time.Time, uid string) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.ages == nil {
+		x.ages = make(map[string]ownerAge)
+	}
+	x.ages[owner] = ownerAge{created: created, uid: uid}
+}
+
+func (x *DesiredIndex) removeLocked(owner string) {
+	for _, rec := range x.byOwner[owner] {
+		targets := x.byKey[rec.Key()]
+		delete(targets, owner)
+		if len(targets) == 0 {
+			delete(x.byKey, rec.Key())
+		}
+	}
+	delete(x.byOwner, owner)
+}
+
+// Get returns the desired records of owner
+func (x *DesiredIndex) Get(owner string) []pihole.DNSRecord {
+	if x == nil {
+		return nil
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return append([]pihole.DNSRecord(nil), x.byOwner[owner]...)
+}
+
+// Targets returns the IP each owner wants for the record key
+func (x *DesiredIndex) Targets(key string) map[string]string {
+	if x == nil {
+		return nil
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return maps.Clone(x.byKey[key])
+}
+
+// Oldest returns the oldest owner wanting the record key, or "" when nobody does.
+// Owners of unknown age come after the rest, in name order.
+func (x *DesiredIndex) Oldest(key string) string {
+	if x == nil {
+		return ""
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var oldest string
+	for owner := range x.byKey[key] {
+		if oldest == "" || x.olderLocked(owner, oldest) {
+			oldest = owner
+		}
+	}
+	return oldest
+}
+
+func (x *DesiredIndex) olderLocked(a, b string) bool {
+	ageA, knownA := x.ages[a]
+	ageB, knownB := x.ages[b]
+	switch {
+	case knownA && knownB:
+		return ageA.before(ageB)
+	case knownA != knownB:
+		return knownA
+	}
+	return a < b
+}
+
+// NamespaceConflict applies the cross-namespace duplicate policy to rec wanted by
+// owner. Without allowDuplicates, any owner in another namespace wanting the record
+// key conflicts with owner; with it, only one wanting a different IP does. Owners in
+// the same namespace never conflict here. It returns "" when nothing conflicts, and
+// otherwise the oldest of owner and the conflicting owners, who keeps the record.
+func (x *DesiredIndex) NamespaceConflict(owner string, rec pihole.DNSRecord, allowDuplicates bool) string {
+	if x == nil {
+		return ""
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	namespace := ownerNamespace(owner)
+	keeper := ""
+	for other, ip := range x.byKey[rec.Key()] {
+		if other == owner || ownerNamespace(other) == namespace || (allowDuplicates && ip == rec.IP) {
+			continue
+		}
+		if keeper == "" || x.olderLocked(other, keeper) {
+			keeper = other
+		}
+	}
+	if keeper != "" && x.olderLocked(owner, keeper) {
+		keeper = owner
+	}
+	return keeper
+}
+
+// ClaimedByOthers returns the keys that some owner other than owner still wants
+func (x *DesiredIndex) ClaimedByOthers(owner string, keys []string) []string {
+	if x == nil {
+		return nil
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var claimed []string
+	for _, key := range keys {
+		targets := x.byKey[key]
+		if _, self := targets[owner]; len(targets) > 1 || (len(targets) == 1 && !self) {
+			claimed = append(claimed, key)
+		}
+	}
+	return claimed
+}
+
+// Records returns every desired record, sorted by domain and type
+func (x *DesiredIndex) Records() []DesiredRecord {
+	if x == nil {
+		return nil
+	}
+	x.mu.RLock()
+	records := make([]DesiredRecord, 0, len(x.byKey))
+	for key, targets := range x.byKey {
+		domain, recordType := pihole.ParseRecordKey(key)
+		records = append(records, DesiredRecord{Domain: domain, Type: recordType, Targets: maps.Clone(targets)})
+	}
+	x.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Domain != records[j].Domain {
+			return records[i].Domain < records[j].Domain
+		}
+		return records[i].Type < records[j].Type
+	})
+	return records
+}
+
+// Conflicts returns the records whose owners disagree on the IP
+func (x *DesiredIndex) Conflicts() []DesiredRecord {
+	var conflicts []DesiredRecord
+	for _, rec := range x.Records() {
+		if rec.Conflicting() {
+			conflicts = append(conflicts, rec)
+		}
+	}
+	return conflicts
+}
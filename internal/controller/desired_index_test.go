@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestDesiredIndex(t *testing.T) {
+	x := &DesiredIndex{}
+	x.Set("Ingress default/a", []pihole.DNSRecord{
+		{Domain: "shared.local", IP: "10.0.0.1"},
+		{Domain: "a.local", IP: "10.0.0.1"},
+	})
+	x.Set("Ingress default/b", []pihole.DNSRecord{
+		{Domain: "shared.local", IP: "10.0.0.2"},
+		{Domain: "shared.local", IP: "fd00::2"},
+	})
+
+	got := x.ClaimedByOthers("Ingress default/a", []string{"shared.local", "a.local", "shared.local/AAAA", "gone.local"})
+	if want := []string{"shared.local", "shared.local/AAAA"}; !slicesEqual(got, want) {
+		t.Errorf("ClaimedByOthers() = %v, want %v", got, want)
+	}
+
+	conflicts := x.Conflicts()
+	want := []DesiredRecord{{Domain: "shared.local", Type: pihole.TypeA, Targets: map[string]string{
+		"Ingress default/a": "10.0.0.1",
+		"Ingress default/b": "10.0.0.2",
+	}}}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("Conflicts() = %+v, want %+v", conflicts, want)
+	}
+
+	// Replacing an owner's records drops the ones it no longer wants
+	x.Set("Ingress default/b", []pihole.DNSRecord{{Domain: "b.local", IP: "10.0.0.2"}})
+	if got := x.ClaimedByOthers("Ingress default/a", []string{"shared.local"}); len(got) != 0 {
+		t.Errorf("ClaimedByOthers() after replace = %v, want none", got)
+	}
+
+	x.Remove("Ingress default/a")
+	x.Remove("Ingress default/b")
+	if records := x.Records(); len(records) != 0 {
+		t.Errorf("Records() after removing every owner = %+v, want none", records)
+	}
+}
+
+func TestDesiredIndexNil(t *testing.T) {
+	var x *DesiredIndex
+	x.Set("Ingress default/a", []pihole.DNSRecord{{Domain: "a.local", IP: "10.0.0.1"}})
+	x.Remove("Ingress default/a")
+	if got := x.ClaimedByOthers("Ingress default/b", []string{"a.local"}); got != nil {
+		t.Errorf("ClaimedByOthers() = %v, want nil", got)
+	}
+	if got := x.Conflicts(); got != nil {
+		t.Errorf("Conflicts() = %v, want nil", got)
+	}
+}
+
+func TestReconcileSharedHostKeptOnDelete(t *testing.T) {
+	ctx := context.Background()
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		testIngress("a", map[string]string{AnnotationRegister: "true"}, "shared.local", "a.local"),
+		testIngress("b", map[string]string{AnnotationRegister: "true"}, "shared.local"),
+	)
+	r.DisableFinalizers = true
+	r.Index = &DesiredIndex{}
+
+	reconcileIngress(t, r, "default", "a")
+	reconcileIngress(t, r, "default", "b")
+
+	// a unregisters; b still wants shared.local
+	a := getIngress(t, r, "default", "a")
+	delete(a.Annotations, AnnotationRegister)
+	if err := r.Update(ctx, a); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "a")
+
+	if ph.ip("a.local") != "" {
+		t.Error("a.local not cleaned up")
+	}
+	if ph.ip("shared.local") == "" {
+		t.Error("shared.local deleted while b still wants it")
+	}
+	if got := getIngress(t, r, "default", "a").Annotations[AnnotationManagedHosts]; got != "" {
+		t.Errorf("a managed hosts = %q, want none", got)
+	}
+}
+
+func TestReconcileTargetConflict(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		testIngress("a", map[string]string{AnnotationRegister: "true", AnnotationTargetIP: "10.0.0.1"}, "shared.local"),
+		testIngress("b", map[string]string{AnnotationRegister: "true", AnnotationTargetIP: "10.0.0.2"}, "shared.local"),
+	)
+	r.DisableFinalizers = true
+	r.Index = &DesiredIndex{}
+
+	reconcileIngress(t, r, "default", "a")
+	reconcileIngress(t, r, "default", "b")
+
+	events := r.Recorder.(*record.FakeRecorder).Events
+	select {
+	case event := <-events:
+		if !strings.Contains(event, "TargetConflict") || !strings.Contains(event, "Ingress default/a=10.0.0.1") {
+			t.Errorf("event = %q, want a TargetConflict naming the other owner", event)
+		}
+	default:
+		t.Error("no event for conflicting targets")
+	}
+	if n := len(r.Index.Conflicts()); n != 1 {
+		t.Errorf("Conflicts() has %d records, want 1", n)
+	}
+}
@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// LingeringFinalizers counts the objects in list that still carry the pihole.io/dns-cleanup
+// finalizer, for a source that is disabled. Nothing releases the finalizer while the
+// source is off, so deleting such an object blocks until the source is enabled again.
+// The reader should be uncached, as disabled sources have no informer.
+func LingeringFinalizers(ctx context.Context, reader client.Reader, list client.ObjectList) (int, error) {
+	if err := reader.List(ctx, list); err != nil {
+		return 0, fmt.Errorf("listing objects: %w", err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, item := range items {
+		if obj, ok := item.(client.Object); ok && controllerutil.ContainsFinalizer(obj, FinalizerName) {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLingeringFinalizers(t *testing.T) {
+	finalized := testIngress("finalized", map[string]string{AnnotationRegister: "true"}, "app.local")
+	finalized.Finalizers = []string{FinalizerName}
+	foreign := testIngress("foreign", nil, "other.local")
+	foreign.Finalizers = []string{"example.com/other"}
+	reader := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithObjects(finalized, foreign, testIngress("plain", nil, "plain.local")).Build()
+
+	count, err := LingeringFinalizers(context.Background(), reader, &networkingv1.IngressList{})
+	if err != nil {
+		t.Fatalf("LingeringFinalizers() unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("LingeringFinalizers() = %d, want 1", count)
+	}
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"strings"
+	"sync"
+)
+
+// DomainLocks serializes Pi-hole writes per domain. Reconciles touching the same host
+// take turns, while those with disjoint hosts proceed in parallel. The A and AAAA
+// records of a domain share one lock. A nil *DomainLocks does no locking.
+type DomainLocks struct {
+	mu    sync.Mutex
+	locks map[string]*domainLock
+}
+
+// domainLock is reference counted so entries are dropped once nobody holds or awaits them
+type domainLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Lock blocks until domain is free and returns the function releasing it
+func (l *DomainLocks) Lock(domain string) (unlock func()) {
+	if l == nil {
+		return func() {}
+	}
+	key := normalizeDomain(domain)
+
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*domainLock)
+	}
+	dl := l.locks[key]
+	if dl == nil {
+		dl = &domainLock{}
+		l.locks[key] = dl
+	}
+	dl.refs++
+	l.mu.Unlock()
+
+	dl.mu.Lock()
+	return func() {
+		dl.mu.Unlock()
+		l.mu.Lock()
+		dl.refs--
+		if dl.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// size returns the number of domains currently locked or awaited
+func (l *DomainLocks) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
+
+// normalizeDomain folds the spellings of a domain that Pi-hole treats as one
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestDomainLocks(t *testing.T) {
+	locks := &DomainLocks{}
+
+	unlock := locks.Lock("App.Local.")
+	acquired := make(chan struct{})
+	go func() {
+		defer locks.Lock("app.local")()
+		close(acquired)
+	}()
+
+	// A different domain is not held up
+	locks.Lock("other.local")()
+
+	select {
+	case <-acquired:
+		t.Fatal("second lock on the same domain acquired while the first is held")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second lock not acquired after release")
+	}
+
+	if n := locks.size(); n != 0 {
+		t.Errorf("size() = %d after all locks were released, want 0", n)
+	}
+}
+
+func TestDomainLocksNil(t *testing.T) {
+	var locks *DomainLocks
+	locks.Lock("app.local")()
+	locks.Lock("app.local")()
+}
+
+// TestReconcileConcurrentSharedHost reconciles two Ingresses that fight over a host in
+// parallel, as with MaxConcurrentReconciles above one; run it with -race
+func TestReconcileConcurrentSharedHost(t *testing.T) {
+	ph := newFakePiholeClient()
+	ph.delay = time.Millisecond
+	r := newTestReconciler(ph,
+		testIngress("a", map[string]string{AnnotationRegister: "true", AnnotationTargetIP: "10.0.0.1"}, "shared.local", "a.local"),
+		testIngress("b", map[string]string{AnnotationRegister: "true", AnnotationTargetIP: "10.0.0.2"}, "shared.local", "b.local"),
+	)
+	r.DisableFinalizers = true
+	r.Locks = &DomainLocks{}
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}}
+			for range 20 {
+				if _, err := r.Reconcile(context.Background(), req); err != nil {
+					t.Errorf("Reconcile(%s) unexpected error: %v", name, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	if ph.overlaps != 0 {
+		t.Errorf("%d overlapping writes to the same domain, want none", ph.overlaps)
+	}
+	if ip := ph.records["shared.local"]; ip != "10.0.0.1" && ip != "10.0.0.2" {
+		t.Errorf("shared.local ip = %q, want one of the owners' targets", ip)
+	}
+	if ph.records["a.local"] != "10.0.0.1" || ph.records["b.local"] != "10.0.0.2" {
+		t.Errorf("records = %v, want each Ingress's own host registered", ph.records)
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DomainMappingGVK identifies Knative DomainMappings. They are handled as unstructured
+// objects so the operator does not depend on the Knative API module.
+var DomainMappingGVK = schema.GroupVersionKind{Group: "serving.knative.dev", Version: "v1beta1", Kind: "DomainMapping"}
+
+// DomainMappingReconciler registers the names of Knative DomainMappings. It shares the
+// Ingress reconcile logic, annotations and options; a DomainMapping asks for exactly one
+// host, its metadata.name.
+type DomainMappingReconciler struct {
+	*IngressReconciler
+}
+
+// NewDomainMappingReconciler returns a DomainMappingReconciler using r's options and
+// clients, ready to use without SetupWithManager
+func NewDomainMappingReconciler(r *IngressReconciler) *DomainMappingReconciler {
+	r.source = domainMappingSource{}
+	return &DomainMappingReconciler{IngressReconciler: r}
+}
+
+// +kubebuilder:rbac:groups=serving.knative.dev,resources=domainmappings,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=serving.knative.dev,resources=domainmappings/finalizers,verbs=update
+
+// SetupWithManager sets up the controller with the Manager
+func (r *DomainMappingReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.source = domainMappingSource{}
+	return r.IngressReconciler.SetupWithManager(mgr)
+}
+
+// domainMappingSource reads hosts and readiness from unstructured DomainMappings
+type domainMappingSource struct{}
+
+func (domainMappingSource) kind() string { return DomainMappingGVK.Kind }
+
+func (domainMappingSource) newObject() client.Object {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(DomainMappingGVK)
+	return u
+}
+
+func (domainMappingSource) newList() client.ObjectList {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(DomainMappingGVK.GroupVersion().WithKind(DomainMappingGVK.Kind + "List"))
+	return list
+}
+
+func (domainMappingSource) items(list client.ObjectList) []client.Object {
+	mappings := list.(*unstructured.UnstructuredList).Items
+	objs := make([]client.Object, 0, len(mappings))
+	for i := range mappings {
+		objs = append(objs, &mappings[i])
+	}
+	return objs
+}
+
+// hosts returns the mapping's name, which Knative requires to be the FQDN it serves
+func (domainMappingSource) hosts(obj client.Object) []string {
+	return []string{obj.GetName()}
+}
+
+// ready reports whether Knative has set the Ready condition to True
+func (domainMappingSource) ready(obj client.Object) bool {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return false
+	}
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if cond["type"] == "Ready" {
+			return cond["status"] == "True"
+		}
+	}
+	return false
+}
+
+// backends returns nothing: a DomainMapping points at a Knative Service, which is
+// served through Knative's own ingress, and its Ready condition already covers it
+func (domainMappingSource) backends(client.Object) []string {
+	return nil
+}
+
+// statusIP returns nothing: a DomainMapping's status holds a URL, not an address
+func (domainMappingSource) statusIP(client.Object) string {
+	return ""
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func testDomainMapping(name string, annotations map[string]string, ready string) *unstructured.Unstructured {
+	u := domainMappingSource{}.newObject().(*unstructured.Unstructured)
+	u.SetName(name)
+	u.SetNamespace("default")
+	u.SetAnnotations(annotations)
+	if ready != "" {
+		_ = unstructured.SetNestedSlice(u.Object, []interface{}{
+			map[string]interface{}{"type": "Ready", "status": ready},
+		}, "status", "conditions")
+	}
+	return u
+}
+
+// newTestDomainMappingReconciler builds a DomainMappingReconciler whose fake client
+// knows the DomainMapping kind
+func newTestDomainMappingReconciler(ph *fakePiholeClient, objs ...client.Object) *DomainMappingReconciler {
+	s := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(s)
+	s.AddKnownTypeWithName(DomainMappingGVK, &unstructured.Unstructured{})
+	s.AddKnownTypeWithName(DomainMappingGVK.GroupVersion().WithKind(DomainMappingGVK.Kind+"List"), &unstructured.UnstructuredList{})
+
+	r := &DomainMappingReconciler{IngressReconciler: newTestReconciler(ph)}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+	r.Scheme = s
+	r.source = domainMappingSource{}
+	return r
+}
+
+func getDomainMapping(t *testing.T, r *DomainMappingReconciler, name string) *unstructured.Unstructured {
+	t.Helper()
+	u := domainMappingSource{}.newObject().(*unstructured.Unstructured)
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, u); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	return u
+}
+
+func TestDomainMappingReady(t *testing.T) {
+	tests := []struct {
+		name  string
+		ready string
+		want  bool
+	}{
+		{"no conditions", "", false},
+		{"ready", "True", true},
+		{"not ready", "False", false},
+		{"unknown", "Unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (domainMappingSource{}).ready(testDomainMapping("app.example.com", nil, tt.ready)); got != tt.want {
+				t.Errorf("ready() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileDomainMapping(t *testing.T) {
+	ctx := context.Background()
+	ph := newFakePiholeClient()
+	dm := testDomainMapping("app.example.com", map[string]string{
+		AnnotationRegister: "true",
+		AnnotationTargetIP: "10.0.0.5",
+	}, "True")
+	r := newTestDomainMappingReconciler(ph, dm)
+
+	reconcileIngress(t, r.IngressReconciler, "default", "app.example.com")
+
+	if got := ph.ip("app.example.com"); got != "10.0.0.5" {
+		t.Fatalf("app.example.com ip = %q, want 10.0.0.5", got)
+	}
+	current := getDomainMapping(t, r, "app.example.com")
+	if !controllerutil.ContainsFinalizer(current, FinalizerName) {
+		t.Error("finalizer not added")
+	}
+	if got := current.GetAnnotations()[AnnotationManagedHosts]; got != "app.example.com" {
+		t.Errorf("managed hosts = %q, want app.example.com", got)
+	}
+	records, err := r.OwnedRecords(ctx)
+	if err != nil {
+		t.Fatalf("OwnedRecords() unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Owner != "DomainMapping default/app.example.com" {
+		t.Errorf("OwnedRecords() = %+v, want one record owned by the DomainMapping", records)
+	}
+
+	if err := r.Delete(ctx, current); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r.IngressReconciler, "default", "app.example.com")
+
+	if ph.ip("app.example.com") != "" {
+		t.Error("record not cleaned up after delete")
+	}
+}
+
+func TestReconcileDomainMappingRequireReady(t *testing.T) {
+	ph := newFakePiholeClient()
+	dm := testDomainMapping("app.example.com", map[string]string{AnnotationRegister: "true"}, "False")
+	r := newTestDomainMappingReconciler(ph, dm)
+	r.RequireReady = true
+
+	reconcileIngress(t, r.IngressReconciler, "default", "app.example.com")
+	if ph.ip("app.example.com") != "" {
+		t.Fatal("record created before the DomainMapping is ready")
+	}
+
+	current := getDomainMapping(t, r, "app.example.com")
+	_ = unstructured.SetNestedSlice(current.Object, []interface{}{
+		map[string]interface{}{"type": "Ready", "status": "True"},
+	}, "status", "conditions")
+	if err := r.Update(context.Background(), current); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r.IngressReconciler, "default", "app.example.com")
+
+	if got := ph.ip("app.example.com"); got != "192.168.1.100" {
+		t.Errorf("app.example.com ip = %q, want default target", got)
+	}
+}
+
+func TestDomainMappingExtractHosts(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		mode        HostsMode
+		want        []string
+	}{
+		{name: "name only", want: []string{"app.example.com"}},
+		{name: "extra hosts added to the name", annotations: map[string]string{AnnotationExtraHosts: "alias.example.com"}, want: []string{"app.example.com", "alias.example.com"}},
+		{name: "hosts override replaces the name", annotations: map[string]string{AnnotationHosts: "api.example.com", AnnotationExtraHosts: "alias.example.com"}, want: []string{"api.example.com", "alias.example.com"}},
+		{name: "hosts merged with the name", annotations: map[string]string{AnnotationHosts: "api.example.com"}, mode: HostsModeMerge, want: []string{"app.example.com", "api.example.com"}},
+		{name: "exclusion wins over extra hosts", annotations: map[string]string{AnnotationExtraHosts: "alias.example.com", AnnotationExcludeHosts: "alias.example.com"}, want: []string{"app.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestDomainMappingReconciler(newFakePiholeClient())
+			dm := testDomainMapping("app.example.com", tt.annotations, "True")
+			if got := r.extractHosts(dm, nil, tt.mode); !slicesEqual(got, tt.want) {
+				t.Errorf("extractHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// DefaultDriftInterval is how often desired records are compared with Pi-hole
+const DefaultDriftInterval = 30 * time.Second
+
+// DriftMonitor compares the desired index with the last Pi-hole listing every Interval
+// and publishes how far they disagree in the record drift and foreign record gauges.
+// It never calls Pi-hole: the listing cache is refreshed by reconciles and kept up to
+// date with every write, so a repair shows up as soon as it is made.
+type DriftMonitor struct {
+	Index   *DesiredIndex
+	Listing *pihole.ListingCache
+
+	// Kinds are reported on every pass, so their drift reads 0 rather than vanishing
+	// when nothing of that kind drifts
+	Kinds []string
+
+	// Mirror, when set, is refreshed on every pass, so the records ConfigMap catches up
+	// with changes no reconcile reported
+	Mirror *RecordsMirror
+
+	Interval time.Duration
+}
+
+// Drift is the outcome of comparing desired records with Pi-hole
+type Drift struct {
+	// ByKind counts, per owner kind, the desired records Pi-hole lacks or holds with an
+	// IP no owner wants
+	ByKind map[string]int
+
+	// Foreign counts the records Pi-hole holds for a desired domain and type with an IP
+	// no owner wants, such as ones created by hand or by another tool
+	Foreign int
+}
+
+// Start publishes the drift every Interval until ctx is cancelled; it implements
+// manager.Runnable
+func (m *DriftMonitor) Start(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = DefaultDriftInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		m.publish()
+		m.Mirror.Changed()
+	}
+}
+
+// NeedLeaderElection reports drift from the leader only, whose listing is kept current
+// by its reconciles
+func (m *DriftMonitor) NeedLeaderElection() bool {
+	return true
+}
+
+// publish sets the gauges from the current drift; it leaves them alone until Pi-hole
+// has been listed
+func (m *DriftMonitor) publish() {
+	snapshot, _ := m.Listing.Snapshot()
+	if snapshot == nil {
+		return
+	}
+	drift := ComputeDrift(m.Index.Records(), snapshot)
+	for _, kind := range m.Kinds {
+		metrics.RecordDrift.WithLabelValues(kind).Set(float64(drift.ByKind[kind]))
+	}
+	for kind, n := range drift.ByKind {
+		metrics.RecordDrift.WithLabelValues(kind).Set(float64(n))
+	}
+	metrics.ForeignRecords.Set(float64(drift.Foreign))
+}
+
+// ComputeDrift compares desired records with a listing keyed by pihole.RecordKey. A
+// record whose owners disagree is in sync while Pi-hole holds any of their IPs.
+func ComputeDrift(desired []DesiredRecord, listing map[string]string) Drift {
+	drift := Drift{ByKind: map[string]int{}}
+	for _, rec := range desired {
+		ip, listed := listing[pihole.RecordKey(rec.Domain, rec.Type)]
+		wanted := false
+		for _, target := range rec.Targets {
+			wanted = wanted || target == ip
+		}
+		if listed && wanted {
+			continue
+		}
+		if listed {
+			drift.Foreign++
+		}
+
+		kinds := map[string]bool{}
+		for owner := range rec.Targets {
+			if kind, _, _, ok := ParseOwner(owner); ok {
+				kinds[kind] = true
+			}
+		}
+		for kind := range kinds {
+			drift.ByKind[kind]++
+		}
+	}
+	return drift
+}
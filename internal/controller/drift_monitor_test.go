@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestComputeDrift(t *testing.T) {
+	desired := []DesiredRecord{
+		{Domain: "ok.local", Type: "A", Targets: map[string]string{"Ingress default/ok": "192.168.1.100"}},
+		{Domain: "missing.local", Type: "A", Targets: map[string]string{"Ingress default/missing": "192.168.1.100"}},
+		{Domain: "moved.local", Type: "A", Targets: map[string]string{"DomainMapping default/moved": "192.168.1.100"}},
+		{Domain: "shared.local", Type: "A", Targets: map[string]string{
+			"Ingress default/a":       "192.168.1.100",
+			"DomainMapping default/b": "192.168.1.200",
+		}},
+		{Domain: "ok.local", Type: "AAAA", Targets: map[string]string{"Ingress default/ok": "fd00::1"}},
+	}
+	listing := map[string]string{
+		"ok.local":       "192.168.1.100",
+		"moved.local":    "192.168.1.50",
+		"shared.local":   "192.168.1.200",
+		"ok.local/AAAA":  "fd00::2",
+		"unrelated.home": "192.168.1.9",
+	}
+
+	drift := ComputeDrift(desired, listing)
+	if drift.ByKind["Ingress"] != 2 || drift.ByKind["DomainMapping"] != 1 {
+		t.Errorf("ByKind = %v, want Ingress 2 (missing, ok AAAA) and DomainMapping 1 (moved)", drift.ByKind)
+	}
+	if drift.Foreign != 2 {
+		t.Errorf("Foreign = %d, want 2 (moved, ok AAAA)", drift.Foreign)
+	}
+}
+
+func TestDriftMonitorPublish(t *testing.T) {
+	gauge := func(kind string) float64 {
+		t.Helper()
+		var m dto.Metric
+		if err := metrics.RecordDrift.WithLabelValues(kind).Write(&m); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+		return m.GetGauge().GetValue()
+	}
+	foreign := func() float64 {
+		t.Helper()
+		var m dto.Metric
+		if err := metrics.ForeignRecords.Write(&m); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+		return m.GetGauge().GetValue()
+	}
+
+	ctx := context.Background()
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.50"})
+	listing := pihole.NewListingCache(ph)
+	index := &DesiredIndex{}
+	index.Set("Ingress default/app", []pihole.DNSRecord{{Domain: "app.local", IP: "192.168.1.100"}})
+	index.Set("Ingress default/web", []pihole.DNSRecord{{Domain: "web.local", IP: "192.168.1.100"}})
+	m := &DriftMonitor{Index: index, Listing: listing, Kinds: []string{"DomainMapping", "Ingress"}}
+
+	// Nothing is published before Pi-hole has been listed
+	metrics.RecordDrift.WithLabelValues("Ingress").Set(-1)
+	m.publish()
+	if got := gauge("Ingress"); got != -1 {
+		t.Errorf("drift before the first listing = %v, want it left alone", got)
+	}
+
+	if _, err := listing.ListRecords(ctx); err != nil {
+		t.Fatalf("ListRecords() unexpected error: %v", err)
+	}
+	m.publish()
+	if gauge("Ingress") != 2 || gauge("DomainMapping") != 0 || foreign() != 1 {
+		t.Errorf("drift = Ingress %v, DomainMapping %v, foreign %v; want 2, 0, 1",
+			gauge("Ingress"), gauge("DomainMapping"), foreign())
+	}
+
+	// Writes through the listing cache repair the drift without another listing
+	if err := listing.ApplyBatch(ctx, pihole.Batch{
+		Deletes: []string{"app.local"},
+		Creates: []pihole.DNSRecord{{Domain: "app.local", IP: "192.168.1.100"}, {Domain: "web.local", IP: "192.168.1.100"}},
+	}); err != nil {
+		t.Fatalf("ApplyBatch() unexpected error: %v", err)
+	}
+	m.publish()
+	if gauge("Ingress") != 0 || foreign() != 0 {
+		t.Errorf("drift after the repair = Ingress %v, foreign %v; want 0", gauge("Ingress"), foreign())
+	}
+}
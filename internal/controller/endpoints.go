@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// AnnotationRequireReadyEndpoints defers registration until a backend Service of the
+// object has a ready endpoint
+const AnnotationRequireReadyEndpoints = "pihole.io/require-ready-endpoints"
+
+// backendServiceIndex indexes objects requiring ready endpoints by their backend Services,
+// so an EndpointSlice change finds the objects waiting for it without listing them all
+const backendServiceIndex = "pihole.io/backend-service"
+
+// requiresReadyEndpoints reports whether the object opted into the ready-endpoints check
+func requiresReadyEndpoints(obj client.Object) bool {
+	return obj.GetAnnotations()[AnnotationRequireReadyEndpoints] == "true"
+}
+
+// ingressBackends returns the Services an Ingress routes to, sorted and deduplicated
+func ingressBackends(ing *networkingv1.Ingress) []string {
+	var services []string
+	if b := ing.Spec.DefaultBackend; b != nil && b.Service != nil {
+		services = append(services, b.Service.Name)
+	}
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service != nil {
+				services = append(services, path.Backend.Service.Name)
+			}
+		}
+	}
+	services = uniqueHosts(services)
+	sort.Strings(services)
+	return services
+}
+
+// backendServices is the backendServiceIndex function; only objects requiring ready
+// endpoints are indexed
+func (r *IngressReconciler) backendServices(obj client.Object) []string {
+	if !requiresReadyEndpoints(obj) {
+		return nil
+	}
+	return r.src().backends(obj)
+}
+
+// endpointsReady reports whether any backend Service of the object has a ready
+// endpoint. Objects without Service backends have nothing to wait for.
+func (r *IngressReconciler) endpointsReady(ctx context.Context, obj client.Object) (bool, error) {
+	services := r.src().backends(obj)
+	for _, service := range services {
+		var list discoveryv1.EndpointSliceList
+		if err := r.List(ctx, &list, client.InNamespace(obj.GetNamespace()),
+			client.MatchingLabels{discoveryv1.LabelServiceName: service}); err != nil {
+			return false, fmt.Errorf("listing endpoint slices of service %s: %w", service, err)
+		}
+		for i := range list.Items {
+			if sliceReady(&list.Items[i]) {
+				return true, nil
+			}
+		}
+	}
+	return len(services) == 0, nil
+}
+
+// sliceReady reports whether an EndpointSlice has a ready endpoint; an unset ready
+// condition means ready
+func sliceReady(slice *discoveryv1.EndpointSlice) bool {
+	for _, ep := range slice.Endpoints {
+		if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+			return true
+		}
+	}
+	return false
+}
+
+// objectsForEndpointSlice maps an EndpointSlice to the objects waiting for its Service
+func (r *IngressReconciler) objectsForEndpointSlice(ctx context.Context, slice client.Object) []reconcile.Request {
+	service := slice.GetLabels()[discoveryv1.LabelServiceName]
+	if service == "" {
+		return nil
+	}
+	list := r.src().newList()
+	if err := r.List(ctx, list, client.InNamespace(slice.GetNamespace()),
+		client.MatchingFields{backendServiceIndex: service}); err != nil {
+		r.Logger.Error("failed to list objects for endpoint slice", "endpointslice",
+			client.ObjectKeyFromObject(slice).String(), "error", err)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, obj := range r.src().items(list) {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+	}
+	return requests
+}
+
+// endpointReadinessChanged passes EndpointSlice updates only when the slice gains its
+// first ready endpoint or loses its last, so rollouts churning endpoints do not
+// reconcile the objects behind them on every change
+func endpointReadinessChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldSlice, okOld := e.ObjectOld.(*discoveryv1.EndpointSlice)
+			newSlice, okNew := e.ObjectNew.(*discoveryv1.EndpointSlice)
+			return !okOld || !okNew || sliceReady(oldSlice) != sliceReady(newSlice)
+		},
+	}
+}
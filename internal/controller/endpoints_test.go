@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// backedIngress returns a registered Ingress requiring ready endpoints of the given Services
+func backedIngress(name string, services ...string) *networkingv1.Ingress {
+	ingress := testIngress(name, map[string]string{
+		AnnotationRegister:              "true",
+		AnnotationRequireReadyEndpoints: "true",
+	}, name+".local")
+	var paths []networkingv1.HTTPIngressPath
+	for _, service := range services {
+		paths = append(paths, networkingv1.HTTPIngressPath{Backend: networkingv1.IngressBackend{
+			Service: &networkingv1.IngressServiceBackend{Name: service},
+		}})
+	}
+	ingress.Spec.Rules[0].HTTP = &networkingv1.HTTPIngressRuleValue{Paths: paths}
+	return ingress
+}
+
+// endpointSlice returns a slice of service with one endpoint in the given ready state
+func endpointSlice(service string, ready bool) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      service + "-abc12",
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{{
+			Addresses:  []string{"10.244.0.10"},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+		}},
+	}
+}
+
+func TestIngressBackends(t *testing.T) {
+	ingress := backedIngress("app", "web", "api", "web")
+	ingress.Spec.DefaultBackend = &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "fallback"}}
+	ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{Host: "bare.local"})
+
+	want := []string{"api", "fallback", "web"}
+	if got := ingressBackends(ingress); !slicesEqual(got, want) {
+		t.Errorf("ingressBackends() = %v, want %v", got, want)
+	}
+}
+
+func TestEndpointReadinessChanged(t *testing.T) {
+	p := endpointReadinessChanged()
+	ready, notReady := endpointSlice("web", true), endpointSlice("web", false)
+	scaled := endpointSlice("web", true)
+	scaled.Endpoints = append(scaled.Endpoints, scaled.Endpoints[0])
+
+	if !p.Update(event.UpdateEvent{ObjectOld: notReady, ObjectNew: ready}) {
+		t.Error("first ready endpoint filtered out")
+	}
+	if !p.Update(event.UpdateEvent{ObjectOld: ready, ObjectNew: notReady}) {
+		t.Error("loss of the last ready endpoint filtered out")
+	}
+	if p.Update(event.UpdateEvent{ObjectOld: ready, ObjectNew: scaled}) {
+		t.Error("endpoint churn without a readiness change passed")
+	}
+}
+
+func TestReconcileRequireReadyEndpoints(t *testing.T) {
+	ctx := context.Background()
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, backedIngress("app", "web"))
+	r.DisableFinalizers = true
+	r.ReadyGracePeriod = DefaultReadyGracePeriod
+
+	// No endpoints yet: registration waits
+	res := reconcileIngress(t, r, "default", "app")
+	if ph.ip("app.local") != "" {
+		t.Fatal("record created before the backend had a ready endpoint")
+	}
+	if res.RequeueAfter != notReadyRequeue {
+		t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, notReadyRequeue)
+	}
+
+	slice := endpointSlice("web", true)
+	if err := r.Create(ctx, slice); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("app.local") == "" {
+		t.Fatal("record not created once an endpoint was ready")
+	}
+
+	// Readiness dropping to zero within the grace period keeps the record
+	*slice.Endpoints[0].Conditions.Ready = false
+	if err := r.Update(ctx, slice); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("app.local") == "" {
+		t.Fatal("record withdrawn within the grace period")
+	}
+
+	r.ReadyGracePeriod = 0
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("app.local") != "" {
+		t.Error("record kept after the grace period")
+	}
+}
+
+func TestObjectsForEndpointSlice(t *testing.T) {
+	r := newTestReconciler(newFakePiholeClient())
+	r.Client = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithObjects(
+			backedIngress("app", "web"),
+			backedIngress("other", "api"),
+			testIngress("plain", map[string]string{AnnotationRegister: "true"}, "plain.local"),
+		).
+		WithIndex(&networkingv1.Ingress{}, backendServiceIndex, r.backendServices).
+		Build()
+
+	reqs := r.objectsForEndpointSlice(context.Background(), endpointSlice("web", true))
+	if len(reqs) != 1 || reqs[0].NamespacedName != (client.ObjectKey{Namespace: "default", Name: "app"}) {
+		t.Errorf("objectsForEndpointSlice() = %v, want default/app", reqs)
+	}
+}
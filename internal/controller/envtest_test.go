@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// envtestConfig reaches the API server TestMain starts when KUBEBUILDER_ASSETS points
+// at the envtest binaries, as make test does; it is nil otherwise and the envtest
+// tests are skipped
+var envtestConfig *rest.Config
+
+func TestMain(m *testing.M) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		os.Exit(m.Run())
+	}
+	env := &envtest.Environment{}
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "starting envtest: %v\n", err)
+		os.Exit(1)
+	}
+	envtestConfig = cfg
+	code := m.Run()
+	if err := env.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "stopping envtest: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+// envtestNamespace creates a namespace of its own for the test, as envtest cannot
+// delete namespaces between tests
+func envtestNamespace(t *testing.T, c client.Client) string {
+	t.Helper()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "pihole-"}}
+	if err := c.Create(context.Background(), ns); err != nil {
+		t.Fatalf("creating namespace: %v", err)
+	}
+	return ns.Name
+}
+
+// newEnvtestReconciler returns a reconciler working against the envtest API server,
+// through funcs when given, and a namespace for the test's objects
+func newEnvtestReconciler(t *testing.T, ph *fakePiholeClient, funcs *interceptor.Funcs) (*IngressReconciler, string) {
+	t.Helper()
+	if envtestConfig == nil {
+		t.Skip("KUBEBUILDER_ASSETS is not set; run make test to start the envtest API server")
+	}
+	c, err := client.New(envtestConfig, client.Options{Scheme: scheme.Scheme})
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	ns := envtestNamespace(t, c)
+	if funcs != nil {
+		c = interceptor.NewClient(client.WithWatch(c.(client.WithWatch)), *funcs)
+	}
+	return &IngressReconciler{
+		Client:          c,
+		Scheme:          scheme.Scheme,
+		PiholeClient:    ph,
+		DefaultTargetIP: "192.168.1.100",
+		Logger:          slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		Recorder:        record.NewFakeRecorder(100),
+		Backoff:         NewBackoff(DefaultBackoffBase, DefaultBackoffMax),
+	}, ns
+}
+
+// envtestIngress creates an Ingress with the register annotation and one rule per host
+func envtestIngress(t *testing.T, r *IngressReconciler, namespace, name string, hosts ...string) {
+	t.Helper()
+	ingress := testIngress(name, map[string]string{AnnotationRegister: "true"}, hosts...)
+	ingress.Namespace = namespace
+	if err := r.Create(context.Background(), ingress); err != nil {
+		t.Fatalf("creating ingress: %v", err)
+	}
+}
+
+// mutateIngress applies mutate to the current Ingress, retrying on conflicts
+func mutateIngress(t *testing.T, r *IngressReconciler, namespace, name string, mutate func(*networkingv1.Ingress)) {
+	t.Helper()
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ingress := getIngress(t, r, namespace, name)
+		mutate(ingress)
+		return r.Update(context.Background(), ingress)
+	}); err != nil {
+		t.Fatalf("updating ingress: %v", err)
+	}
+}
+
+func setRules(ingress *networkingv1.Ingress, hosts ...string) {
+	ingress.Spec.Rules = nil
+	for _, h := range hosts {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{Host: h})
+	}
+}
+
+func TestEnvtestIngressLifecycle(t *testing.T) {
+	ph := newFakePiholeClient()
+	r, ns := newEnvtestReconciler(t, ph, nil)
+	envtestIngress(t, r, ns, "app", "app.example.com", "old.example.com")
+
+	// Creating registers the hosts, adds the finalizer and tracks the hosts
+	reconcileIngress(t, r, ns, "app")
+	current := getIngress(t, r, ns, "app")
+	if !controllerutil.ContainsFinalizer(current, FinalizerName) {
+		t.Error("finalizer not added")
+	}
+	if got := current.Annotations[AnnotationManagedHosts]; got != "app.example.com,old.example.com" {
+		t.Errorf("managed hosts = %q", got)
+	}
+	if ph.ip("app.example.com") != "192.168.1.100" || ph.ip("old.example.com") != "192.168.1.100" {
+		t.Fatalf("records not created: %v", ph.records)
+	}
+
+	// Replacing a host prunes the old record
+	mutateIngress(t, r, ns, "app", func(ing *networkingv1.Ingress) {
+		setRules(ing, "app.example.com", "new.example.com")
+	})
+	reconcileIngress(t, r, ns, "app")
+	if ph.ip("old.example.com") != "" || ph.ip("new.example.com") != "192.168.1.100" {
+		t.Errorf("hosts change not applied: %v", ph.records)
+	}
+	if got := getIngress(t, r, ns, "app").Annotations[AnnotationManagedHosts]; got != "app.example.com,new.example.com" {
+		t.Errorf("managed hosts after the hosts change = %q", got)
+	}
+
+	// Changing the target moves every record
+	mutateIngress(t, r, ns, "app", func(ing *networkingv1.Ingress) {
+		ing.Annotations[AnnotationTargetIP] = "10.0.0.5"
+	})
+	reconcileIngress(t, r, ns, "app")
+	if ph.ip("app.example.com") != "10.0.0.5" || ph.ip("new.example.com") != "10.0.0.5" {
+		t.Errorf("target change not applied: %v", ph.records)
+	}
+
+	// Deleting removes the records, then the finalizer lets the Ingress go
+	if err := r.Delete(context.Background(), getIngress(t, r, ns, "app")); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, ns, "app")
+	if len(ph.records) != 0 {
+		t.Errorf("records left after deletion: %v", ph.records)
+	}
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: ns, Name: "app"}, &networkingv1.Ingress{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Ingress still present after its finalizer was removed: %v", err)
+	}
+}
+
+func TestEnvtestRegisterAnnotationRemoved(t *testing.T) {
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "other.example.com", IP: "10.0.0.9"})
+	r, ns := newEnvtestReconciler(t, ph, nil)
+	envtestIngress(t, r, ns, "app", "app.example.com")
+	reconcileIngress(t, r, ns, "app")
+
+	mutateIngress(t, r, ns, "app", func(ing *networkingv1.Ingress) {
+		delete(ing.Annotations, AnnotationRegister)
+	})
+	reconcileIngress(t, r, ns, "app")
+
+	if ph.ip("app.example.com") != "" {
+		t.Error("record kept after the register annotation was removed")
+	}
+	if ph.ip("other.example.com") != "10.0.0.9" {
+		t.Error("unmanaged record removed")
+	}
+	current := getIngress(t, r, ns, "app")
+	if controllerutil.ContainsFinalizer(current, FinalizerName) {
+		t.Error("finalizer kept after the register annotation was removed")
+	}
+	if _, ok := current.Annotations[AnnotationManagedHosts]; ok {
+		t.Error("managed hosts annotation kept after the register annotation was removed")
+	}
+}
+
+func TestEnvtestDeletionWhilePiholeUnreachable(t *testing.T) {
+	ph := newFakePiholeClient()
+	r, ns := newEnvtestReconciler(t, ph, nil)
+	envtestIngress(t, r, ns, "app", "app.example.com")
+	reconcileIngress(t, r, ns, "app")
+
+	ph.err = errors.New("pihole unreachable")
+	if err := r.Delete(context.Background(), getIngress(t, r, ns, "app")); err != nil {
+		t.Fatal(err)
+	}
+	res := reconcileIngress(t, r, ns, "app")
+	if res.RequeueAfter == 0 {
+		t.Error("a failed cleanup should be retried")
+	}
+	current := getIngress(t, r, ns, "app")
+	if current.DeletionTimestamp.IsZero() || !controllerutil.ContainsFinalizer(current, FinalizerName) {
+		t.Fatal("the finalizer should hold the Ingress until its records are removed")
+	}
+
+	// Once Pi-hole is back the retry removes the record and releases the Ingress
+	ph.err = nil
+	reconcileIngress(t, r, ns, "app")
+	if ph.ip("app.example.com") != "" {
+		t.Error("record kept after Pi-hole came back")
+	}
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: ns, Name: "app"}, &networkingv1.Ingress{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Ingress still present after cleanup: %v", err)
+	}
+}
+
+func TestEnvtestManagedHostsConflict(t *testing.T) {
+	ph := newFakePiholeClient()
+	var conflicted atomic.Bool
+	// The first write of the managed hosts races another writer, so the API server
+	// refuses it with a conflict
+	funcs := &interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if _, ok := obj.GetAnnotations()[AnnotationManagedHosts]; ok && conflicted.CompareAndSwap(false, true) {
+				other := &networkingv1.Ingress{}
+				if err := c.Get(ctx, client.ObjectKeyFromObject(obj), other); err != nil {
+					return err
+				}
+				other.Labels = map[string]string{"touched": "true"}
+				if err := c.Update(ctx, other); err != nil {
+					return err
+				}
+			}
+			return c.Update(ctx, obj, opts...)
+		},
+	}
+	r, ns := newEnvtestReconciler(t, ph, funcs)
+	envtestIngress(t, r, ns, "app", "app.example.com")
+
+	res := reconcileIngress(t, r, ns, "app")
+	if !conflicted.Load() {
+		t.Fatal("the managed hosts were never written")
+	}
+	if res.RequeueAfter != DefaultUpdateConflictRequeue {
+		t.Errorf("RequeueAfter = %v, want %v after a conflict", res.RequeueAfter, DefaultUpdateConflictRequeue)
+	}
+	if ph.ip("app.example.com") != "192.168.1.100" {
+		t.Error("record should be created before the annotation is written")
+	}
+	if got := getIngress(t, r, ns, "app").Annotations[AnnotationManagedHosts]; got != "" {
+		t.Errorf("managed hosts = %q written despite the conflict", got)
+	}
+
+	// The retry writes the annotation
+	reconcileIngress(t, r, ns, "app")
+	current := getIngress(t, r, ns, "app")
+	if got := current.Annotations[AnnotationManagedHosts]; got != "app.example.com" {
+		t.Errorf("managed hosts after the retry = %q", got)
+	}
+	if current.Labels["touched"] != "true" {
+		t.Error("the other writer's change was lost")
+	}
+}
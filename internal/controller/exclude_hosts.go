@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnnotationExcludeHosts lists hosts that are never registered, even though the spec
+// or pihole.io/hosts names them, e.g. a public name served by external DNS. An entry
+// starting with "*." or "." excludes every name under that domain instead.
+const AnnotationExcludeHosts = "pihole.io/exclude-hosts"
+
+// excludeHosts returns hosts without those matching an entry of AnnotationExcludeHosts
+func excludeHosts(obj client.Object, hosts []string) []string {
+	patterns := parseCommaSeparated(obj.GetAnnotations()[AnnotationExcludeHosts])
+	if len(patterns) == 0 {
+		return hosts
+	}
+
+	kept := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if !hostExcluded(host, patterns) {
+			kept = append(kept, host)
+		}
+	}
+	return kept
+}
+
+// hostExcluded reports whether host equals one of patterns, or lies under a "*." or
+// "." pattern; names compare case-insensitively and without a trailing dot
+func hostExcluded(host string, patterns []string) bool {
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+		suffix := strings.TrimPrefix(pattern, "*")
+		if strings.HasPrefix(suffix, ".") {
+			if strings.HasSuffix(name, suffix) {
+				return true
+			}
+			continue
+		}
+		if name == pattern {
+			return true
+		}
+	}
+	return false
+}
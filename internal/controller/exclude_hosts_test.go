@@ -0,0 +1,69 @@
+package controller
+
+import "testing"
+
+func TestExcludeHosts(t *testing.T) {
+	hosts := []string{"app.home.lan", "public.example.com", "www.example.com", "example.com", "API.Example.Com."}
+	tests := []struct {
+		name    string
+		exclude string
+		want    []string
+	}{
+		{name: "no annotation", want: hosts},
+		{name: "exact name", exclude: "public.example.com", want: []string{"app.home.lan", "www.example.com", "example.com", "API.Example.Com."}},
+		{name: "case and trailing dot ignored", exclude: "api.example.com., PUBLIC.example.com", want: []string{"app.home.lan", "www.example.com", "example.com"}},
+		{name: "wildcard suffix keeps the apex", exclude: "*.example.com", want: []string{"app.home.lan", "example.com"}},
+		{name: "dot suffix", exclude: ".example.com", want: []string{"app.home.lan", "example.com"}},
+		{name: "suffix matches whole labels only", exclude: "*.ample.com", want: hosts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := testIngress("app", map[string]string{AnnotationExcludeHosts: tt.exclude})
+			if got := excludeHosts(obj, hosts); !slicesEqual(got, tt.want) {
+				t.Errorf("excludeHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileExcludeHosts(t *testing.T) {
+	ph := newFakePiholeClient()
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.home.lan", "public.example.com")
+	r := newTestReconciler(ph, ingress)
+
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("public.example.com") == "" {
+		t.Fatalf("public.example.com not registered before the exclusion: %v", ph.records)
+	}
+
+	// Excluding a managed host prunes its record
+	current := getIngress(t, r, "default", "app")
+	current.Annotations[AnnotationExcludeHosts] = "public.example.com"
+	if err := r.Update(t.Context(), current); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("public.example.com") != "" {
+		t.Errorf("excluded host kept: %v", ph.records)
+	}
+	if ph.ip("app.home.lan") == "" {
+		t.Errorf("remaining host pruned: %v", ph.records)
+	}
+	if managed := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; managed != "app.home.lan" {
+		t.Errorf("managed hosts = %q, want app.home.lan", managed)
+	}
+}
+
+func TestReconcileDomainMappingExcludeHosts(t *testing.T) {
+	ph := newFakePiholeClient()
+	dm := testDomainMapping("app.example.com", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationExcludeHosts: "*.example.com",
+	}, "True")
+	r := newTestDomainMappingReconciler(ph, dm)
+
+	reconcileIngress(t, r.IngressReconciler, "default", "app.example.com")
+	if len(ph.records) != 0 {
+		t.Errorf("excluded DomainMapping host registered: %v", ph.records)
+	}
+}
@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// AnnotationExpireAfter removes each record of the object once it has existed this
+// long, e.g. "72h" for a preview environment. Expired records are not created again
+// until the annotation changes or is removed.
+const AnnotationExpireAfter = "pihole.io/expire-after"
+
+// DefaultExpirySweepInterval is how often the expiry sweeper looks for records due
+const DefaultExpirySweepInterval = time.Minute
+
+// expireAfter returns the record lifetime requested via annotation, or zero when there
+// is none. Invalid values, and expiry without the registry, are reported and ignored.
+func (r *IngressReconciler) expireAfter(obj client.Object, logger *slog.Logger) time.Duration {
+	value := obj.GetAnnotations()[AnnotationExpireAfter]
+	if value == "" {
+		return 0
+	}
+
+	lifetime, err := time.ParseDuration(value)
+	if err != nil || lifetime <= 0 {
+		logger.Warn("invalid annotation", "annotation", AnnotationExpireAfter,
+			"value", value, "error", "not a positive duration")
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+			"%s=%q is not a positive duration; records will not expire", AnnotationExpireAfter, value)
+		return 0
+	}
+	if r.Registry == nil {
+		logger.Warn("record expiry requires the registry; records will not expire")
+		return 0
+	}
+	return lifetime
+}
+
+// applyExpiry drops the desired records of obj that have outlived AnnotationExpireAfter,
+// so the plan removes them, and records their expiry in the registry. Expiries recorded
+// under another value of the annotation, or before it was removed, are lifted. It
+// returns the records to keep, the keys of the expired ones and how long until the
+// next record expires, zero when none will.
+func (r *IngressReconciler) applyExpiry(ctx context.Context, obj client.Object, desired []pihole.DNSRecord, logger *slog.Logger) ([]pihole.DNSRecord, []string, time.Duration, error) {
+	lifetime := r.expireAfter(obj, logger)
+	if r.Registry == nil {
+		return desired, nil, 0, nil
+	}
+	state, err := r.Registry.Get(ctx)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	value := obj.GetAnnotations()[AnnotationExpireAfter]
+	owner, self := r.ownerOf(obj), r.ownership(obj)
+	var lifted []string
+	for key, e := range state.Expired {
+		if e.Owner == owner && (e.UID != self.UID || lifetime == 0 || e.After != value) {
+			lifted = append(lifted, key)
+		}
+	}
+
+	now := time.Now().UTC()
+	kept := make([]pihole.DNSRecord, 0, len(desired))
+	var expired, due []string
+	var next time.Duration
+	for _, rec := range desired {
+		key := rec.Key()
+		if lifetime == 0 {
+			kept = append(kept, rec)
+			continue
+		}
+		if e, ok := state.Expired[key]; ok && e.Owner == owner && e.UID == self.UID && e.After == value {
+			expired = append(expired, key)
+			continue
+		}
+		// A record not tracked yet is created now
+		remaining := lifetime
+		if o, ok := state.Records[key]; ok && o.SameObject(self) && !o.TrackedSince.IsZero() {
+			remaining = o.TrackedSince.Add(lifetime).Sub(now)
+		}
+		if remaining <= 0 {
+			expired = append(expired, key)
+			due = append(due, key)
+			continue
+		}
+		if next == 0 || remaining < next {
+			next = remaining
+		}
+		kept = append(kept, rec)
+	}
+
+	if len(due) == 0 && len(lifted) == 0 {
+		return kept, expired, next, nil
+	}
+	if err := r.Registry.Update(ctx, func(st *registry.State) bool {
+		st.ForgetExpired(lifted)
+		for _, key := range due {
+			st.Expire(key, registry.Expiry{Owner: owner, UID: self.UID, After: value, ExpiredAt: now})
+		}
+		return true
+	}); err != nil {
+		return nil, nil, 0, err
+	}
+	if len(lifted) > 0 {
+		logger.Info("record expiry lifted", "hosts", strings.Join(lifted, ","))
+	}
+	if len(due) > 0 {
+		logger.Info("dns records expired", "hosts", strings.Join(due, ","), "expire_after", value)
+		r.Recorder.Eventf(obj, corev1.EventTypeNormal, "RecordsExpired",
+			"%s=%s elapsed; removing %s until the annotation changes", AnnotationExpireAfter, value, strings.Join(due, ","))
+	}
+	return kept, expired, next, nil
+}
+
+// forgetExpiries lifts the expiries of an object that is going away
+func (r *IngressReconciler) forgetExpiries(ctx context.Context, obj client.Object, logger *slog.Logger) {
+	if r.Registry == nil {
+		return
+	}
+	owner := r.ownerOf(obj)
+	if err := r.Registry.Update(ctx, func(st *registry.State) bool {
+		var keys []string
+		for key, e := range st.Expired {
+			if e.Owner == owner {
+				keys = append(keys, key)
+			}
+		}
+		return st.ForgetExpired(keys)
+	}); err != nil {
+		logger.Error("failed to update registry", "error", err)
+	}
+}
+
+// dueForExpiry reports whether a record of obj has outlived AnnotationExpireAfter but
+// is not recorded as expired yet
+func (r *IngressReconciler) dueForExpiry(obj client.Object, state *registry.State, now time.Time) bool {
+	value := obj.GetAnnotations()[AnnotationExpireAfter]
+	lifetime, err := time.ParseDuration(value)
+	if value == "" || err != nil || lifetime <= 0 {
+		return false
+	}
+	self := r.ownership(obj)
+	for _, key := range r.getManagedHosts(obj) {
+		if _, ok := state.Expired[key]; ok {
+			continue
+		}
+		o, ok := state.Records[key]
+		if ok && o.SameObject(self) && !o.TrackedSince.IsZero() && !now.Before(o.TrackedSince.Add(lifetime)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpirySweeper enqueues the objects whose records have outlived
+// pihole.io/expire-after, so they expire on time even when nothing else reconciles
+// them. The creation times live in the registry, so expiry survives restarts.
+type ExpirySweeper struct {
+	Sources  []*IngressReconciler
+	Registry *registry.Store
+	Logger   *slog.Logger
+	Interval time.Duration
+}
+
+// Start runs the sweep loop until ctx is cancelled; it implements manager.Runnable
+func (s *ExpirySweeper) Start(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultExpirySweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection ensures only the leader, whose controllers run, sweeps
+func (s *ExpirySweeper) NeedLeaderElection() bool {
+	return true
+}
+
+// runOnce enqueues every object with a record due to expire
+func (s *ExpirySweeper) runOnce(ctx context.Context, now time.Time) {
+	state, err := s.Registry.Get(ctx)
+	if err != nil {
+		s.Logger.Error("failed to read registry", "error", err)
+		return
+	}
+	for _, r := range s.Sources {
+		if r.resync == nil {
+			continue
+		}
+		list := r.src().newList()
+		if err := r.List(ctx, list); err != nil {
+			s.Logger.Error("failed to list objects", "kind", r.src().kind(), "error", err)
+			continue
+		}
+		for _, obj := range r.src().items(list) {
+			if !r.dueForExpiry(obj, state, now) {
+				continue
+			}
+			select {
+			case r.resync <- event.GenericEvent{Object: obj}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
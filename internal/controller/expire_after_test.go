@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// backdate moves the time the record key was taken on back by d
+func backdate(t *testing.T, r *IngressReconciler, key string, d time.Duration) {
+	t.Helper()
+	if err := r.Registry.Update(t.Context(), func(st *registry.State) bool {
+		o := st.Records[key]
+		o.TrackedSince = o.TrackedSince.Add(-d)
+		st.Records[key] = o
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcileExpireAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		// lift edits the Ingress once its record has expired
+		lift func(annotations map[string]string)
+	}{
+		{name: "changing the annotation", lift: func(a map[string]string) { a[AnnotationExpireAfter] = "96h" }},
+		{name: "removing the annotation", lift: func(a map[string]string) { delete(a, AnnotationExpireAfter) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ph := newFakePiholeClient()
+			ingress := testIngress("preview", map[string]string{
+				AnnotationRegister:    "true",
+				AnnotationExpireAfter: "72h",
+			}, "preview.home.lan")
+			r := newTestReconciler(ph, ingress)
+			r.Registry = registry.NewStore(r.Client, r.Client, "pihole-operator", "registry")
+			r.SyncStatus = &StatusWriter{}
+			key := statusKey("Ingress", types.NamespacedName{Namespace: "default", Name: "preview"})
+
+			res := reconcileIngress(t, r, "default", "preview")
+			if ph.ip("preview.home.lan") == "" {
+				t.Fatalf("record not created: %v", ph.records)
+			}
+			if res.RequeueAfter <= 71*time.Hour || res.RequeueAfter > 72*time.Hour {
+				t.Errorf("RequeueAfter = %v, want the time until expiry", res.RequeueAfter)
+			}
+
+			backdate(t, r, "preview.home.lan", 73*time.Hour)
+			reconcileIngress(t, r, "default", "preview")
+			if ph.ip("preview.home.lan") != "" {
+				t.Errorf("expired record kept: %v", ph.records)
+			}
+			if want := "pihole.io/expire-after=72h elapsed; removing preview.home.lan"; !hasEvent(r, want) {
+				t.Errorf("no RecordsExpired event %q", want)
+			}
+			if got := r.SyncStatus.objects[key].Expired; !slicesEqual(got, []string{"preview.home.lan"}) {
+				t.Errorf("status expired = %v, want preview.home.lan", got)
+			}
+
+			// The expiry is persisted, so a restarted operator does not create the record again
+			r.Registry = registry.NewStore(r.Client, r.Client, "pihole-operator", "registry")
+			ph.calls = nil
+			reconcileIngress(t, r, "default", "preview")
+			for _, call := range ph.calls {
+				if call != "list" {
+					t.Errorf("unexpected pi-hole call %q after expiry", call)
+				}
+			}
+
+			current := getIngress(t, r, "default", "preview")
+			tt.lift(current.Annotations)
+			if err := r.Update(t.Context(), current); err != nil {
+				t.Fatal(err)
+			}
+			reconcileIngress(t, r, "default", "preview")
+			if ph.ip("preview.home.lan") == "" {
+				t.Errorf("record not created again once the expiry was lifted: %v", ph.records)
+			}
+			state, err := r.Registry.Get(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(state.Expired) != 0 {
+				t.Errorf("expired = %v, want the expiry lifted", state.Expired)
+			}
+			if got := r.SyncStatus.objects[key].Expired; len(got) != 0 {
+				t.Errorf("status expired = %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestReconcileExpireAfterDeletionLiftsExpiry(t *testing.T) {
+	ph := newFakePiholeClient()
+	ingress := testIngress("preview", map[string]string{
+		AnnotationRegister:    "true",
+		AnnotationExpireAfter: "1h",
+	}, "preview.home.lan")
+	r := newTestReconciler(ph, ingress)
+	r.Registry = registry.NewStore(r.Client, r.Client, "pihole-operator", "registry")
+
+	reconcileIngress(t, r, "default", "preview")
+	backdate(t, r, "preview.home.lan", 2*time.Hour)
+	reconcileIngress(t, r, "default", "preview")
+
+	if err := r.Delete(t.Context(), getIngress(t, r, "default", "preview")); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "preview")
+	state, err := r.Registry.Get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Expired) != 0 {
+		t.Errorf("expired = %v, want the expiries of the deleted Ingress lifted", state.Expired)
+	}
+}
+
+func TestExpirySweeperEnqueuesDueObjects(t *testing.T) {
+	ph := newFakePiholeClient()
+	due := testIngress("due", map[string]string{AnnotationRegister: "true", AnnotationExpireAfter: "1h"}, "due.home.lan")
+	fresh := testIngress("fresh", map[string]string{AnnotationRegister: "true", AnnotationExpireAfter: "1h"}, "fresh.home.lan")
+	plain := testIngress("plain", map[string]string{AnnotationRegister: "true"}, "plain.home.lan")
+	r := newTestReconciler(ph, due, fresh, plain)
+	r.Registry = registry.NewStore(r.Client, r.Client, "pihole-operator", "registry")
+	for _, name := range []string{"due", "fresh", "plain"} {
+		reconcileIngress(t, r, "default", name)
+	}
+	backdate(t, r, "due.home.lan", 2*time.Hour)
+	backdate(t, r, "plain.home.lan", 2*time.Hour)
+
+	r.resync = make(chan event.GenericEvent, 10)
+	s := &ExpirySweeper{Sources: []*IngressReconciler{r}, Registry: r.Registry, Logger: r.Logger}
+	s.runOnce(t.Context(), time.Now())
+	close(r.resync)
+
+	var enqueued []string
+	for e := range r.resync {
+		enqueued = append(enqueued, e.Object.GetName())
+	}
+	if strings.Join(enqueued, ",") != "due" {
+		t.Errorf("enqueued = %v, want only the object with a record due", enqueued)
+	}
+}
+
+func TestExpireAfterInvalidAnnotation(t *testing.T) {
+	r := newTestReconciler(newFakePiholeClient())
+	r.Registry = registry.NewStore(r.Client, r.Client, "pihole-operator", "registry")
+	ingress := testIngress("app", map[string]string{AnnotationExpireAfter: "-1h"})
+
+	if got := r.expireAfter(ingress, r.Logger); got != 0 {
+		t.Errorf("expireAfter() = %v, want 0", got)
+	}
+	select {
+	case event := <-r.Recorder.(*record.FakeRecorder).Events:
+		if !strings.Contains(event, "InvalidAnnotation") || !strings.Contains(event, AnnotationExpireAfter) {
+			t.Errorf("event = %q, want an InvalidAnnotation warning", event)
+		}
+	default:
+		t.Error("no event for the invalid annotation")
+	}
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// fakePiholeClient is an in-memory pihole.Client for controller tests.
+// Records are keyed by pihole.RecordKey.
+type fakePiholeClient struct {
+	mu      sync.Mutex
+	records map[string]string
+	calls   []string
+	batches []pihole.Batch
+
+	// clusters holds the cluster marker of each record; cluster, like
+	// pihole.HTTPClient.SetClusterID, marks created records and scopes deletes
+	clusters map[string]string
+	cluster  string
+
+	// err, when set, is returned by every mutating call
+	err error
+
+	// reject, when set, makes any batch touching that domain fail with a 400, the way
+	// Pi-hole refuses an invalid host
+	reject string
+
+	// delay, when set, stretches every mutating call so concurrent writes to the same
+	// domain overlap; overlaps counts how often that happened
+	delay    time.Duration
+	inflight map[string]int
+	overlaps int
+}
+
+func newFakePiholeClient(records ...pihole.DNSRecord) *fakePiholeClient {
+	f := &fakePiholeClient{records: make(map[string]string), clusters: make(map[string]string)}
+	for _, r := range records {
+		f.records[r.Key()] = r.IP
+		f.clusters[r.Key()] = r.Cluster
+	}
+	return f
+}
+
+func (f *fakePiholeClient) ListRecords(_ context.Context) ([]pihole.DNSRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "list")
+
+	records := make([]pihole.DNSRecord, 0, len(f.records))
+	for key, ip := range f.records {
+		domain, _ := pihole.ParseRecordKey(key)
+		records = append(records, pihole.DNSRecord{Domain: domain, IP: ip, Cluster: f.clusters[key]})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key() < records[j].Key() })
+	return records, nil
+}
+
+func (f *fakePiholeClient) CreateRecord(_ context.Context, record pihole.DNSRecord) error {
+	defer f.track(record.Domain)()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "create "+record.Key())
+	if f.err != nil {
+		return f.err
+	}
+	f.set(record)
+	return nil
+}
+
+func (f *fakePiholeClient) DeleteRecord(_ context.Context, domain, recordType string) error {
+	key := pihole.RecordKey(domain, recordType)
+	defer f.track(domain)()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "delete "+key)
+	if f.err != nil {
+		return f.err
+	}
+	f.remove(key)
+	return nil
+}
+
+func (f *fakePiholeClient) ApplyBatch(_ context.Context, batch pihole.Batch) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "batch")
+	f.batches = append(f.batches, batch)
+	if f.err != nil {
+		return f.err
+	}
+	for _, record := range batch.Creates {
+		if f.reject != "" && record.Domain == f.reject {
+			return &pihole.APIError{StatusCode: http.StatusBadRequest, Message: "invalid host " + record.Domain}
+		}
+	}
+	for _, key := range batch.Deletes {
+		f.remove(key)
+	}
+	for _, record := range batch.Creates {
+		f.set(record)
+	}
+	return nil
+}
+
+// set stores record marked with the client's cluster; callers hold mu
+func (f *fakePiholeClient) set(record pihole.DNSRecord) {
+	f.records[record.Key()] = record.IP
+	f.clusters[record.Key()] = f.cluster
+}
+
+// remove deletes the record key unless another cluster marked it; callers hold mu
+func (f *fakePiholeClient) remove(key string) {
+	if (pihole.DNSRecord{Cluster: f.clusters[key]}).OtherCluster(f.cluster) {
+		return
+	}
+	delete(f.records, key)
+	delete(f.clusters, key)
+}
+
+// track marks a write to domain as in flight for the configured delay and returns the
+// function ending it
+func (f *fakePiholeClient) track(domain string) func() {
+	f.mu.Lock()
+	if f.inflight == nil {
+		f.inflight = make(map[string]int)
+	}
+	f.inflight[domain]++
+	if f.inflight[domain] > 1 {
+		f.overlaps++
+	}
+	delay := f.delay
+	f.mu.Unlock()
+
+	time.Sleep(delay)
+	return func() {
+		f.mu.Lock()
+		f.inflight[domain]--
+		f.mu.Unlock()
+	}
+}
+
+func (f *fakePiholeClient) Healthy(_ context.Context) bool {
+	return true
+}
+
+// ip returns the current IP for a record key, or empty if absent; for A records
+// the key is the bare domain
+func (f *fakePiholeClient) ip(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.records[key]
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole/fakeserver"
+)
+
+// newFaultyReconciler returns a reconciler talking to a fake Pi-hole over HTTP, so
+// faults injected into the server reach it the way a misbehaving Pi-hole would
+func newFaultyReconciler(t *testing.T, objs ...client.Object) (*IngressReconciler, *fakeserver.Server) {
+	t.Helper()
+	fake := fakeserver.New("secret")
+	srv := fake.Start()
+	t.Cleanup(srv.Close)
+	r := newTestReconciler(nil, objs...)
+	r.PiholeClient = pihole.NewClient(srv.URL, "secret")
+	return r, fake
+}
+
+// reconcileUntilSynced reconciles the named Ingress until it reports no error and no
+// requeue, giving up after attempts
+func reconcileUntilSynced(t *testing.T, r *IngressReconciler, namespace, name string, attempts int) {
+	t.Helper()
+	for range attempts {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Namespace: namespace, Name: name}})
+		cancel()
+		if err == nil && res.IsZero() {
+			return
+		}
+	}
+	t.Fatalf("%s/%s not synced after %d reconciles", namespace, name, attempts)
+}
+
+func TestReconcileConvergesDespiteFaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault fakeserver.Fault
+	}{
+		{
+			name:  "consecutive server errors",
+			fault: fakeserver.Fault{Path: "/api/config", Status: http.StatusInternalServerError, Times: 3},
+		},
+		{
+			name:  "dropped writes",
+			fault: fakeserver.Fault{Method: http.MethodPut, Path: "/api/config", Times: 2},
+		},
+		{
+			name:  "latency beyond the deadline",
+			fault: fakeserver.Fault{Mode: fakeserver.FaultSlow, Path: "/api/config", Delay: 300 * time.Millisecond, Times: 1},
+		},
+		{
+			name:  "random latency",
+			fault: fakeserver.Fault{Mode: fakeserver.FaultSlow, Path: "/api/", Jitter: 10 * time.Millisecond},
+		},
+		{
+			name:  "session invalidated in flight",
+			fault: fakeserver.Fault{Mode: fakeserver.FaultExpireSession, Path: "/api/config", Times: 3},
+		},
+		{
+			name:  "truncated listing",
+			fault: fakeserver.Fault{Mode: fakeserver.FaultTruncate, Method: http.MethodGet, Path: "/api/config", Times: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.example.com")
+			r, fake := newFaultyReconciler(t, ingress)
+			fake.Inject(tt.fault)
+
+			reconcileUntilSynced(t, r, "default", "app", 5)
+			if got := fake.Hosts(); !slices.Equal(got, []string{"192.168.1.100 app.example.com"}) {
+				t.Errorf("hosts = %v, want the Ingress's record once", got)
+			}
+			if got := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; got != "app.example.com" {
+				t.Errorf("managed hosts = %q", got)
+			}
+		})
+	}
+}
+
+func TestReconcileRepairsWritesPiholeDropped(t *testing.T) {
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.example.com")
+	r, fake := newFaultyReconciler(t, ingress)
+
+	// Pi-hole accepts the write but loses it, which only the next listing shows
+	fake.SetReadOnly(true)
+	reconcileIngress(t, r, "default", "app")
+	if got := fake.Hosts(); len(got) != 0 {
+		t.Fatalf("hosts = %v, want the write lost", got)
+	}
+
+	fake.SetReadOnly(false)
+	reconcileIngress(t, r, "default", "app")
+	if got := fake.Hosts(); !slices.Equal(got, []string{"192.168.1.100 app.example.com"}) {
+		t.Errorf("hosts = %v, want the record written again", got)
+	}
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// DefaultHeartbeatInterval is how often Heartbeats refreshes the controllers with
+// nothing queued
+const DefaultHeartbeatInterval = 30 * time.Second
+
+// Heartbeats records when each controller last finished a reconcile without error, so
+// liveness can tell a deadlocked or failing controller from an idle one. A controller is
+// stale when it has items queued but has not succeeded at a reconcile for Staleness; Start refreshes those
+// with an empty queue every Interval, so work arriving after a quiet spell does not
+// count as stale. A nil *Heartbeats records nothing.
+type Heartbeats struct {
+	// Staleness is how long a controller with work queued may go without a heartbeat;
+	// zero disables the check
+	Staleness time.Duration
+
+	Interval time.Duration
+
+	// Depth returns the number of items waiting in a controller's workqueue; nil reads
+	// the workqueue_depth metric of controller-runtime
+	Depth func(controller string) int
+
+	mu   sync.Mutex
+	last map[string]time.Time
+	now  func() time.Time
+}
+
+// Register adds a controller, counting as a heartbeat
+func (h *Heartbeats) Register(controller string) {
+	h.Beat(controller)
+}
+
+// Beat records that controller finished a reconcile without error
+func (h *Heartbeats) Beat(controller string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.last == nil {
+		h.last = map[string]time.Time{}
+	}
+	h.last[controller] = h.clock()
+}
+
+// Start refreshes the idle controllers every Interval until ctx is cancelled; it
+// implements manager.Runnable
+func (h *Heartbeats) Start(ctx context.Context) error {
+	interval := h.Interval
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		h.beatIdle()
+	}
+}
+
+// NeedLeaderElection is false: a replica that is not leading has no controllers running,
+// so they all count as idle
+func (h *Heartbeats) NeedLeaderElection() bool {
+	return false
+}
+
+// beatIdle refreshes the controllers whose queue is empty
+func (h *Heartbeats) beatIdle() {
+	for _, controller := range h.controllers() {
+		if h.depth(controller) == 0 {
+			h.Beat(controller)
+		}
+	}
+}
+
+// Check fails when any controller is stale; it is a healthz.Checker
+func (h *Heartbeats) Check(_ *http.Request) error {
+	if h == nil || h.Staleness <= 0 {
+		return nil
+	}
+	var stale []string
+	for _, controller := range h.controllers() {
+		h.mu.Lock()
+		since := h.clock().Sub(h.last[controller])
+		h.mu.Unlock()
+		if since < h.Staleness {
+			continue
+		}
+		if queued := h.depth(controller); queued > 0 {
+			stale = append(stale, fmt.Sprintf("%s (%d queued, last reconcile %s ago)", controller, queued,
+				since.Round(time.Second)))
+		}
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("controllers not making progress: %s", strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+// controllers returns the registered controllers in order
+func (h *Heartbeats) controllers() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	names := make([]string, 0, len(h.last))
+	for name := range h.last {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (h *Heartbeats) depth(controller string) int {
+	if h.Depth != nil {
+		return h.Depth(controller)
+	}
+	return workqueueDepth(controller)
+}
+
+func (h *Heartbeats) clock() time.Time {
+	if h.now != nil {
+		return h.now()
+	}
+	return time.Now()
+}
+
+// workqueueDepth reads the depth of a controller's workqueue from the metrics
+// controller-runtime keeps for it, summed over priorities
+func workqueueDepth(controller string) int {
+	// Gather returns what it could collect even when some collector fails
+	families, _ := ctrlmetrics.Registry.Gather()
+	depth := 0.0
+	for _, family := range families {
+		if family.GetName() != "workqueue_depth" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "controller" && label.GetValue() == controller {
+					depth += m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return int(depth)
+}
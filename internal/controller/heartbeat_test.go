@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestHeartbeatsCheck(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	queued := map[string]int{}
+	h := &Heartbeats{
+		Staleness: 5 * time.Minute,
+		Depth:     func(controller string) int { return queued[controller] },
+		now:       func() time.Time { return now },
+	}
+	h.Register("ingress")
+	h.Register("domainmapping")
+
+	if err := h.Check(nil); err != nil {
+		t.Fatalf("fresh controllers: %v", err)
+	}
+
+	// An idle controller is never stale, however long since its last reconcile
+	now = now.Add(time.Hour)
+	if err := h.Check(nil); err != nil {
+		t.Errorf("idle controllers: %v", err)
+	}
+
+	// Work queued behind an old heartbeat is stale
+	queued["ingress"] = 3
+	err := h.Check(nil)
+	if err == nil {
+		t.Fatal("expected a controller with work queued and an old heartbeat to fail the check")
+	}
+	if !strings.Contains(err.Error(), "ingress (3 queued") || strings.Contains(err.Error(), "domainmapping") {
+		t.Errorf("error = %q, want only ingress reported", err)
+	}
+
+	// A reconcile finishing clears it, and it stays healthy within the window
+	h.Beat("ingress")
+	now = now.Add(4 * time.Minute)
+	if err := h.Check(nil); err != nil {
+		t.Errorf("after a heartbeat: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err := h.Check(nil); err == nil {
+		t.Error("expected the check to fail once the window passed without a heartbeat")
+	}
+}
+
+func TestHeartbeatsBeatIdle(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	queued := map[string]int{"domainmapping": 1}
+	h := &Heartbeats{
+		Staleness: 5 * time.Minute,
+		Depth:     func(controller string) int { return queued[controller] },
+		now:       func() time.Time { return now },
+	}
+	h.Register("ingress")
+	h.Register("domainmapping")
+
+	// The idle tick refreshes only the controller with nothing queued, so work reaching
+	// it later is not mistaken for a hang
+	now = now.Add(10 * time.Minute)
+	h.beatIdle()
+	queued["ingress"] = 1
+	err := h.Check(nil)
+	if err == nil || strings.Contains(err.Error(), "ingress") || !strings.Contains(err.Error(), "domainmapping") {
+		t.Errorf("Check() = %v, want only domainmapping stale", err)
+	}
+}
+
+func TestHeartbeatsDisabled(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := &Heartbeats{
+		Depth: func(string) int { return 1 },
+		now:   func() time.Time { return now },
+	}
+	h.Register("ingress")
+	now = now.Add(time.Hour)
+	if err := h.Check(nil); err != nil {
+		t.Errorf("zero staleness should disable the check: %v", err)
+	}
+
+	var unset *Heartbeats
+	unset.Beat("ingress")
+	if err := unset.Check(nil); err != nil {
+		t.Errorf("nil heartbeats: %v", err)
+	}
+}
+
+func TestReconcileBeats(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"))
+	r.Heartbeats = &Heartbeats{
+		Staleness: time.Minute,
+		Depth:     func(string) int { return 1 },
+		now:       func() time.Time { return now },
+	}
+	r.Heartbeats.Register("ingress")
+
+	now = now.Add(time.Hour)
+	if err := r.Heartbeats.Check(nil); err == nil {
+		t.Fatal("expected a stale heartbeat before reconciling")
+	}
+	reconcileIngress(t, r, "default", "app")
+	if err := r.Heartbeats.Check(nil); err != nil {
+		t.Errorf("after a reconcile: %v", err)
+	}
+}
+
+func TestReconcileErrorDoesNotBeat(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := newTestReconciler(newFakePiholeClient())
+	r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+		Get: func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+			return errors.New("apiserver unavailable")
+		},
+	})
+	r.Heartbeats = &Heartbeats{
+		Staleness: time.Minute,
+		Depth:     func(string) int { return 1 },
+		now:       func() time.Time { return now },
+	}
+	r.Heartbeats.Register("ingress")
+
+	now = now.Add(time.Hour)
+	_, err := r.Reconcile(t.Context(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "app"}})
+	if err == nil {
+		t.Fatal("Reconcile() error = nil, want the Get error")
+	}
+	if err := r.Heartbeats.Check(nil); err == nil {
+		t.Error("a failed reconcile refreshed the heartbeat")
+	}
+}
+
+func TestWorkqueueDepthUnknownController(t *testing.T) {
+	if got := workqueueDepth("no-such-controller"); got != 0 {
+		t.Errorf("workqueueDepth() = %d, want 0", got)
+	}
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+)
+
+const (
+	// skipReasonIPLiteral marks hosts that are IP addresses rather than names
+	skipReasonIPLiteral = "ip-literal"
+
+	// skipReasonInternal marks hosts under a cluster-internal suffix
+	skipReasonInternal = "internal-suffix"
+)
+
+// HostFilter drops hosts that make no sense as Pi-hole records: IP literals and
+// names that only resolve inside the cluster. A nil filter keeps every host.
+type HostFilter struct {
+	InternalSuffixes []string
+}
+
+// Filter returns the hosts that should be registered, logging each skipped host at debug
+func (f *HostFilter) Filter(hosts []string, logger *slog.Logger) []string {
+	if f == nil {
+		return hosts
+	}
+
+	kept := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if reason := f.skipReason(host); reason != "" {
+			logger.Debug("host skipped", "host", host, "reason", reason)
+			metrics.HostsSkipped.WithLabelValues(reason).Inc()
+			continue
+		}
+		kept = append(kept, host)
+	}
+	return kept
+}
+
+// skipReason returns why host should be skipped, or empty to keep it
+func (f *HostFilter) skipReason(host string) string {
+	if net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return skipReasonIPLiteral
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, suffix := range f.InternalSuffixes {
+		suffix = strings.ToLower(suffix)
+		if !strings.HasPrefix(suffix, ".") {
+			suffix = "." + suffix
+		}
+		if strings.HasSuffix(name, suffix) {
+			return skipReasonInternal
+		}
+	}
+	return ""
+}
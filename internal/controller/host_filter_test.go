@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestHostFilter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	suffixes := []string{".svc", ".cluster.local", "svc.cluster.local"}
+
+	tests := []struct {
+		name   string
+		filter *HostFilter
+		hosts  []string
+		want   []string
+	}{
+		{
+			name:   "nil filter keeps everything",
+			filter: nil,
+			hosts:  []string{"192.168.1.5", "app.default.svc"},
+			want:   []string{"192.168.1.5", "app.default.svc"},
+		},
+		{
+			name:   "regular hosts kept",
+			filter: &HostFilter{InternalSuffixes: suffixes},
+			hosts:  []string{"app.home.lan", "svc.home.lan"},
+			want:   []string{"app.home.lan", "svc.home.lan"},
+		},
+		{
+			name:   "ip literals skipped",
+			filter: &HostFilter{InternalSuffixes: suffixes},
+			hosts:  []string{"192.168.1.5", "app.home.lan", "fd00::1", "[fd00::2]"},
+			want:   []string{"app.home.lan"},
+		},
+		{
+			name:   "internal suffixes skipped",
+			filter: &HostFilter{InternalSuffixes: suffixes},
+			hosts:  []string{"myapp.default.svc.cluster.local", "myapp.default.svc", "MyApp.Cluster.Local.", "app.home.lan"},
+			want:   []string{"app.home.lan"},
+		},
+		{
+			name:   "suffix without leading dot matches whole labels only",
+			filter: &HostFilter{InternalSuffixes: []string{"svc"}},
+			hosts:  []string{"foo.svc", "foosvc"},
+			want:   []string{"foosvc"},
+		},
+		{
+			name:   "empty suffix list still skips ip literals",
+			filter: &HostFilter{},
+			hosts:  []string{"10.0.0.1", "app.default.svc"},
+			want:   []string{"app.default.svc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.filter.Filter(tt.hosts, logger)
+			if !slicesEqual(got, tt.want) {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// AnnotationHostsFromConfigMap names a ConfigMap key, as "namespace/name#key", holding
+// more hosts to register, separated by newlines or commas. The namespace defaults to
+// the object's.
+const AnnotationHostsFromConfigMap = "pihole.io/hosts-from-configmap"
+
+// hostsConfigMapIndex indexes objects by the ConfigMap their hosts come from, as
+// "namespace/name", so a ConfigMap change finds the objects reading it
+const hostsConfigMapIndex = "pihole.io/hosts-configmap"
+
+// errHostsUnavailable marks a hosts ConfigMap or key that does not exist
+var errHostsUnavailable = errors.New("hosts unavailable")
+
+// hostsConfigMapRef parses AnnotationHostsFromConfigMap; set is false when it is unset
+func hostsConfigMapRef(obj client.Object) (cm types.NamespacedName, key string, set bool, err error) {
+	value := obj.GetAnnotations()[AnnotationHostsFromConfigMap]
+	if value == "" {
+		return types.NamespacedName{}, "", false, nil
+	}
+	ref, key, found := strings.Cut(value, "#")
+	if !found || key == "" {
+		return types.NamespacedName{}, "", true, fmt.Errorf("%q has no #key", value)
+	}
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found {
+		namespace, name = obj.GetNamespace(), ref
+	}
+	if namespace == "" || name == "" {
+		return types.NamespacedName{}, "", true, fmt.Errorf("%q is not namespace/name#key", value)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, key, true, nil
+}
+
+// hostsConfigMaps is the hostsConfigMapIndex function
+func hostsConfigMaps(obj client.Object) []string {
+	cm, _, ok, err := hostsConfigMapRef(obj)
+	if !ok || err != nil {
+		return nil
+	}
+	return []string{cm.String()}
+}
+
+// hostsFromConfigMap returns the hosts of the ConfigMap key named by
+// AnnotationHostsFromConfigMap. ok is false when the annotation is invalid or the
+// ConfigMap or key does not exist, which is reported; the object's records are then
+// left as they are until it does.
+func (r *IngressReconciler) hostsFromConfigMap(ctx context.Context, obj client.Object, logger *slog.Logger) ([]string, bool, error) {
+	ref, key, set, err := hostsConfigMapRef(obj)
+	if !set {
+		return nil, true, nil
+	}
+	value := obj.GetAnnotations()[AnnotationHostsFromConfigMap]
+	if err != nil {
+		logger.Warn("invalid annotation", "annotation", AnnotationHostsFromConfigMap, "value", value, "error", err)
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+			"%s: %v; leaving DNS records unchanged", AnnotationHostsFromConfigMap, err)
+		return nil, false, nil
+	}
+
+	hosts, err := r.configMapHosts(ctx, ref, key)
+	if errors.Is(err, errHostsUnavailable) {
+		logger.Warn("hosts configmap unavailable, leaving records unchanged", "annotation", AnnotationHostsFromConfigMap,
+			"value", value, "error", err)
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "HostsUnavailable",
+			"%s: %v; leaving DNS records unchanged", AnnotationHostsFromConfigMap, err)
+		return nil, false, nil
+	}
+	return hosts, err == nil, err
+}
+
+// configMapHosts reads the hosts of key in the ConfigMap ref. A ConfigMap or key that
+// does not exist yields errHostsUnavailable.
+func (r *IngressReconciler) configMapHosts(ctx context.Context, ref types.NamespacedName, key string) ([]string, error) {
+	// ConfigMaps are only watched for their metadata, so their data is read directly
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+	var cm corev1.ConfigMap
+	if err := reader.Get(ctx, ref, &cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: configmap %s not found", errHostsUnavailable, ref)
+		}
+		return nil, fmt.Errorf("reading configmap %s: %w", ref, err)
+	}
+	data, found := cm.Data[key]
+	if !found {
+		return nil, fmt.Errorf("%w: configmap %s has no key %s", errHostsUnavailable, ref, key)
+	}
+	return parseCommaSeparated(strings.ReplaceAll(data, "\n", ",")), nil
+}
+
+// objectsForConfigMap maps a ConfigMap to the objects sourcing hosts from it
+func (r *IngressReconciler) objectsForConfigMap(ctx context.Context, cm client.Object) []reconcile.Request {
+	list := r.src().newList()
+	if err := r.List(ctx, list, client.MatchingFields{hostsConfigMapIndex: client.ObjectKeyFromObject(cm).String()}); err != nil {
+		r.Logger.Error("failed to list objects for configmap", "configmap",
+			client.ObjectKeyFromObject(cm).String(), "error", err)
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, obj := range r.src().items(list) {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+	}
+	return requests
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func hostsConfigMap(namespace, name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}, Data: data}
+}
+
+func TestHostsConfigMapRef(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    types.NamespacedName
+		key     string
+		wantErr bool
+	}{
+		{value: "media/aliases#hosts", want: types.NamespacedName{Namespace: "media", Name: "aliases"}, key: "hosts"},
+		{value: "aliases#hosts", want: types.NamespacedName{Namespace: "default", Name: "aliases"}, key: "hosts"},
+		{value: "media/aliases", wantErr: true},
+		{value: "media/aliases#", wantErr: true},
+		{value: "/aliases#hosts", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			obj := testIngress("app", map[string]string{AnnotationHostsFromConfigMap: tt.value})
+			ref, key, set, err := hostsConfigMapRef(obj)
+			if !set || (err != nil) != tt.wantErr {
+				t.Fatalf("hostsConfigMapRef() set = %v, error = %v, wantErr %v", set, err, tt.wantErr)
+			}
+			if !tt.wantErr && (ref != tt.want || key != tt.key) {
+				t.Errorf("hostsConfigMapRef() = %v#%s, want %v#%s", ref, key, tt.want, tt.key)
+			}
+		})
+	}
+}
+
+func TestReconcileHostsFromConfigMap(t *testing.T) {
+	ph := newFakePiholeClient()
+	ingress := testIngress("app", map[string]string{
+		AnnotationRegister:           "true",
+		AnnotationHostsFromConfigMap: "media/aliases#hosts",
+	}, "app.home.lan")
+	cm := hostsConfigMap("media", "aliases", map[string]string{"hosts": "a.home.lan\nb.home.lan, c.home.lan\n\n"})
+	r := newTestReconciler(ph, ingress, cm)
+
+	reconcileIngress(t, r, "default", "app")
+	for _, host := range []string{"app.home.lan", "a.home.lan", "b.home.lan", "c.home.lan"} {
+		if ph.ip(host) == "" {
+			t.Errorf("%s not registered: %v", host, ph.records)
+		}
+	}
+
+	// Editing the ConfigMap prunes the hosts it no longer lists
+	cm.Data["hosts"] = "a.home.lan"
+	if err := r.Update(t.Context(), cm); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("b.home.lan") != "" || ph.ip("c.home.lan") != "" {
+		t.Errorf("hosts removed from the configmap kept: %v", ph.records)
+	}
+	if ph.ip("a.home.lan") == "" || ph.ip("app.home.lan") == "" {
+		t.Errorf("listed hosts pruned: %v", ph.records)
+	}
+}
+
+func TestReconcileHostsConfigMapUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		objs []client.Object
+		want string
+	}{
+		{name: "missing configmap", want: "configmap media/aliases not found"},
+		{
+			name: "missing key",
+			objs: []client.Object{hostsConfigMap("media", "aliases", map[string]string{"other": "x.home.lan"})},
+			want: "configmap media/aliases has no key hosts",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Records from an earlier sync stay while the hosts cannot be read
+			ph := newFakePiholeClient(pihole.DNSRecord{Domain: "a.home.lan", IP: "192.168.1.100"})
+			ingress := testIngress("app", map[string]string{
+				AnnotationRegister:           "true",
+				AnnotationHostsFromConfigMap: "media/aliases#hosts",
+				AnnotationManagedHosts:       "a.home.lan",
+			}, "app.home.lan")
+			r := newTestReconciler(ph, append(tt.objs, ingress)...)
+
+			reconcileIngress(t, r, "default", "app")
+			if ph.ip("a.home.lan") == "" {
+				t.Error("previously managed host removed")
+			}
+			if got := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; got != "a.home.lan" {
+				t.Errorf("managed hosts = %q, want them untouched", got)
+			}
+			select {
+			case event := <-r.Recorder.(*record.FakeRecorder).Events:
+				if !strings.Contains(event, "Warning HostsUnavailable") || !strings.Contains(event, tt.want) {
+					t.Errorf("event = %q, want a HostsUnavailable warning with %q", event, tt.want)
+				}
+			default:
+				t.Error("no event for the unavailable hosts")
+			}
+		})
+	}
+}
+
+func TestObjectsForConfigMap(t *testing.T) {
+	r := newTestReconciler(newFakePiholeClient())
+	r.Client = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithObjects(
+			testIngress("app", map[string]string{AnnotationHostsFromConfigMap: "media/aliases#hosts"}),
+			testIngress("local", map[string]string{AnnotationHostsFromConfigMap: "aliases#hosts"}),
+			testIngress("plain", map[string]string{AnnotationRegister: "true"}, "plain.local"),
+		).
+		WithIndex(&networkingv1.Ingress{}, hostsConfigMapIndex, hostsConfigMaps).
+		Build()
+
+	reqs := r.objectsForConfigMap(context.Background(), hostsConfigMap("media", "aliases", nil))
+	if len(reqs) != 1 || reqs[0].NamespacedName != (client.ObjectKey{Namespace: "default", Name: "app"}) {
+		t.Errorf("objectsForConfigMap(media/aliases) = %v, want default/app", reqs)
+	}
+	reqs = r.objectsForConfigMap(context.Background(), hostsConfigMap("default", "aliases", nil))
+	if len(reqs) != 1 || reqs[0].NamespacedName != (client.ObjectKey{Namespace: "default", Name: "local"}) {
+		t.Errorf("objectsForConfigMap(default/aliases) = %v, want default/local", reqs)
+	}
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnnotationHostsMode overrides the operator-wide hosts mode for one object
+const AnnotationHostsMode = "pihole.io/hosts-mode"
+
+// HostsMode decides how the pihole.io/hosts annotation combines with the hosts of the
+// source, e.g. spec.rules of an Ingress
+type HostsMode string
+
+const (
+	// HostsModeReplace registers the annotation hosts instead of the source hosts
+	HostsModeReplace HostsMode = "replace"
+
+	// HostsModeMerge registers the annotation hosts as well as the source hosts
+	HostsModeMerge HostsMode = "merge"
+)
+
+// ParseHostsMode validates a hosts mode name
+func ParseHostsMode(s string) (HostsMode, error) {
+	switch m := HostsMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case HostsModeReplace, HostsModeMerge:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown hosts mode %q", s)
+}
+
+// resolveHostsMode returns the hosts mode of obj, or the default with an error when
+// the annotation holds an invalid value
+func (r *IngressReconciler) resolveHostsMode(obj client.Object) (HostsMode, error) {
+	def := r.settings().HostsMode
+	if def == "" {
+		def = HostsModeReplace
+	}
+	value := obj.GetAnnotations()[AnnotationHostsMode]
+	if value == "" {
+		return def, nil
+	}
+	mode, err := ParseHostsMode(value)
+	if err != nil {
+		return def, err
+	}
+	return mode, nil
+}
+
+// hostsMode resolves the hosts mode of obj, reporting an invalid annotation
+func (r *IngressReconciler) hostsMode(obj client.Object, logger *slog.Logger) HostsMode {
+	mode, err := r.resolveHostsMode(obj)
+	if err != nil {
+		value := obj.GetAnnotations()[AnnotationHostsMode]
+		logger.Warn("invalid annotation", "annotation", AnnotationHostsMode, "value", value, "error", err)
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+			"%s=%q is not a valid hosts mode; using %s", AnnotationHostsMode, value, mode)
+	}
+	return mode
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+)
+
+func TestHostsMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		def       HostsMode
+		value     string
+		want      HostsMode
+		wantEvent bool
+	}{
+		{name: "zero default replaces", want: HostsModeReplace},
+		{name: "configured default", def: HostsModeMerge, want: HostsModeMerge},
+		{name: "annotation overrides default", def: HostsModeMerge, value: "replace", want: HostsModeReplace},
+		{name: "annotation is case insensitive", value: "Merge", want: HostsModeMerge},
+		{name: "invalid annotation falls back to default", def: HostsModeMerge, value: "append", want: HostsModeMerge, wantEvent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestReconciler(newFakePiholeClient())
+			r.HostsMode = tt.def
+			ingress := testIngress("app", map[string]string{AnnotationHostsMode: tt.value})
+
+			if got := r.hostsMode(ingress, r.Logger); got != tt.want {
+				t.Errorf("hostsMode() = %q, want %q", got, tt.want)
+			}
+			select {
+			case event := <-r.Recorder.(*record.FakeRecorder).Events:
+				if !tt.wantEvent {
+					t.Errorf("unexpected event %q", event)
+				} else if !strings.Contains(event, "InvalidAnnotation") || !strings.Contains(event, AnnotationHostsMode) {
+					t.Errorf("event = %q, want an InvalidAnnotation warning", event)
+				}
+			default:
+				if tt.wantEvent {
+					t.Error("no event for the invalid annotation")
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileHostsMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		def         HostsMode
+		annotations map[string]string
+		want        []string
+	}{
+		{name: "replace by default", want: []string{"alias.home.lan"}},
+		{name: "merge by default", def: HostsModeMerge, want: []string{"app.home.lan", "alias.home.lan"}},
+		{name: "merge by annotation", annotations: map[string]string{AnnotationHostsMode: "merge"}, want: []string{"app.home.lan", "alias.home.lan"}},
+		{name: "replace by annotation", def: HostsModeMerge, annotations: map[string]string{AnnotationHostsMode: "replace"}, want: []string{"alias.home.lan"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ph := newFakePiholeClient()
+			annotations := map[string]string{AnnotationRegister: "true", AnnotationHosts: "alias.home.lan"}
+			for k, v := range tt.annotations {
+				annotations[k] = v
+			}
+			r := newTestReconciler(ph, testIngress("app", annotations, "app.home.lan"))
+			r.HostsMode = tt.def
+
+			reconcileIngress(t, r, "default", "app")
+			managed := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]
+			if managed != strings.Join(tt.want, ",") {
+				t.Errorf("managed hosts = %q, want %v", managed, tt.want)
+			}
+			if len(ph.records) != len(tt.want) {
+				t.Errorf("records = %v, want %v", ph.records, tt.want)
+			}
+		})
+	}
+}
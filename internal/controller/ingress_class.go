@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"slices"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnnotationIngressClass is the deprecated predecessor of spec.ingressClassName, still
+// set by older charts
+const AnnotationIngressClass = "kubernetes.io/ingress.class"
+
+// ingressClass returns the class of an Ingress the way ingress-nginx resolves it:
+// spec.ingressClassName when set, even if the legacy annotation disagrees, otherwise
+// the annotation. Empty means the Ingress names no class.
+func ingressClass(ing *networkingv1.Ingress) string {
+	if ing.Spec.IngressClassName != nil && *ing.Spec.IngressClassName != "" {
+		return *ing.Spec.IngressClassName
+	}
+	return ing.Annotations[AnnotationIngressClass]
+}
+
+// classAllowed reports whether an object passes the IngressClasses filter. Only
+// Ingresses have a class; other sources always pass.
+func (r *IngressReconciler) classAllowed(obj client.Object) bool {
+	ing, ok := obj.(*networkingv1.Ingress)
+	classes := r.settings().IngressClasses
+	if !ok || len(classes) == 0 {
+		return true
+	}
+	return slices.Contains(classes, ingressClass(ing))
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestIngressClass(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       *string
+		annotation string
+		want       string
+	}{
+		{name: "spec field", spec: stringPtr("nginx"), want: "nginx"},
+		{name: "legacy annotation", annotation: "nginx", want: "nginx"},
+		{name: "spec wins over a conflicting annotation", spec: stringPtr("traefik"), annotation: "nginx", want: "traefik"},
+		{name: "empty spec falls back to the annotation", spec: stringPtr(""), annotation: "nginx", want: "nginx"},
+		{name: "no class", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing := testIngress("app", map[string]string{}, "app.local")
+			ing.Spec.IngressClassName = tt.spec
+			if tt.annotation != "" {
+				ing.Annotations[AnnotationIngressClass] = tt.annotation
+			}
+			if got := ingressClass(ing); got != tt.want {
+				t.Errorf("ingressClass() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileIngressClasses(t *testing.T) {
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "legacy.local", IP: "192.168.1.100"})
+	spec := testIngress("spec", map[string]string{AnnotationRegister: "true"}, "spec.local")
+	spec.Spec.IngressClassName = stringPtr("internal")
+	annotated := testIngress("annotated", map[string]string{AnnotationRegister: "true", AnnotationIngressClass: "internal"}, "annotated.local")
+	conflicting := testIngress("conflicting", map[string]string{AnnotationRegister: "true", AnnotationIngressClass: "internal"}, "conflicting.local")
+	conflicting.Spec.IngressClassName = stringPtr("public")
+	// Registered earlier, then moved to a class outside the filter
+	legacy := testIngress("legacy", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationIngressClass: "public",
+		AnnotationManagedHosts: "legacy.local",
+	}, "legacy.local")
+	controllerutil.AddFinalizer(legacy, FinalizerName)
+	r := newTestReconciler(ph, spec, annotated, conflicting, legacy,
+		testIngress("classless", map[string]string{AnnotationRegister: "true"}, "classless.local"))
+	r.IngressClasses = []string{"internal"}
+
+	for _, name := range []string{"spec", "annotated", "conflicting", "legacy", "classless"} {
+		reconcileIngress(t, r, "default", name)
+	}
+
+	if ph.ip("spec.local") == "" || ph.ip("annotated.local") == "" {
+		t.Error("Ingress of an allowed class not registered")
+	}
+	if ph.ip("conflicting.local") != "" {
+		t.Error("legacy annotation overrode spec.ingressClassName")
+	}
+	if ph.ip("classless.local") != "" {
+		t.Error("Ingress without a class registered despite the filter")
+	}
+	if ph.ip("legacy.local") != "" {
+		t.Error("records of an Ingress outside the filter not cleaned up")
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -2,28 +2,67 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
-	networkingv1 "k8s.io/api/networking/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/notify"
 	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
 )
 
 const (
+	// AnnotationPrefix starts every annotation and label the operator reads or writes
+	AnnotationPrefix = "pihole.io/"
+
 	// Annotation keys
-	AnnotationRegister     = "pihole.io/register"
-	AnnotationTargetIP     = "pihole.io/target-ip"
-	AnnotationHosts        = "pihole.io/hosts"
+	AnnotationRegister   = "pihole.io/register"
+	AnnotationTargetIP   = "pihole.io/target-ip"
+	AnnotationTargetIPv6 = "pihole.io/target-ipv6"
+	AnnotationHosts      = "pihole.io/hosts"
+
+	// AnnotationExtraHosts adds hosts to those from the spec or AnnotationHosts,
+	// e.g. vanity aliases, rather than replacing them
+	AnnotationExtraHosts = "pihole.io/extra-hosts"
+
+	// AnnotationDomainSuffix completes hosts without a dot into FQDNs
+	AnnotationDomainSuffix = "pihole.io/domain-suffix"
+
+	// AnnotationManagedHosts tracks the records owned by an object as pihole.RecordKey
+	// values: the bare host for its A record and host/AAAA for its AAAA record
 	AnnotationManagedHosts = "pihole.io/managed-hosts"
 
+	// AnnotationConfirmDeletions bypasses the deletion guard for this Ingress
+	AnnotationConfirmDeletions = "pihole.io/confirm-deletions"
+
+	// AnnotationOverwrite controls whether pre-existing Pi-hole records may be replaced
+	AnnotationOverwrite = "pihole.io/overwrite"
+
+	// AnnotationDeletionGracePeriod postpones record removal after the Ingress is deleted
+	AnnotationDeletionGracePeriod = "pihole.io/deletion-grace-period"
+
 	// Finalizer name
 	FinalizerName = "pihole.io/dns-cleanup"
 )
@@ -35,257 +74,1272 @@ type IngressReconciler struct {
 	PiholeClient    pihole.Client
 	DefaultTargetIP string
 	Logger          *slog.Logger
+	Recorder        record.EventRecorder
+
+	// TargetSource selects where target IPs come from; the zero value behaves as static.
+	// DefaultTargetIP may be empty with TargetSourceStatus, leaving objects without an
+	// address waiting for one.
+	TargetSource TargetSource
+
+	// DefaultTargetIPv6 adds an AAAA record for every host; empty means A records only
+	// unless the pihole.io/target-ipv6 annotation asks for one. Without any IPv4 target,
+	// only AAAA records are managed.
+	DefaultTargetIPv6 string
+
+	// DisableAAAA manages A records only, ignoring DefaultTargetIPv6 and the
+	// pihole.io/target-ipv6 annotation; AAAA records tracked earlier are removed
+	DisableAAAA bool
+
+	// DefaultDomainSuffix is appended to hosts without a dot; the pihole.io/domain-suffix
+	// annotation overrides it per object
+	DefaultDomainSuffix string
+
+	// SyncPolicy restricts which changes are made to Pi-hole; the pihole.io/sync-policy
+	// annotation overrides it per Ingress. The zero value behaves as SyncPolicySync.
+	SyncPolicy SyncPolicy
+
+	// DisableOverwrite stops Ingresses from replacing or adopting records that already
+	// existed in Pi-hole; the pihole.io/overwrite annotation overrides it per Ingress
+	DisableOverwrite bool
+
+	// AllowCrossNamespaceDuplicates lets objects in different namespaces share a record
+	// when they want the same IP; otherwise a record wanted from another namespace goes
+	// to the oldest object alone
+	AllowCrossNamespaceDuplicates bool
+
+	// HostsMode decides whether the pihole.io/hosts annotation replaces the hosts of
+	// the source or is merged with them; the pihole.io/hosts-mode annotation overrides
+	// it per object. The zero value behaves as replace.
+	HostsMode HostsMode
+
+	// AdoptExisting lets Ingresses adopt records that already exist in Pi-hole with the
+	// desired IP even when overwrite is disabled; the pihole.io/adopt annotation
+	// overrides it per Ingress
+	AdoptExisting bool
+
+	// Audit receives every DNS mutation; nil disables auditing
+	Audit audit.Sink
+
+	// Notifier receives a summary of the changes made by each reconcile; nil disables it
+	Notifier *notify.Dispatcher
+
+	// Mirror is told after each reconcile so it can refresh the records ConfigMap; nil
+	// disables it
+	Mirror *RecordsMirror
+
+	// Registry persists operator state across restarts; nil disables features that need it
+	Registry *registry.Store
+
+	// HostFilter drops IP literals and cluster-internal hosts; nil disables filtering
+	HostFilter *HostFilter
+
+	// DeletionGuard limits how many records may be deleted at once; nil disables it
+	DeletionGuard *DeletionGuard
+
+	// Quota limits how many records are managed overall and per namespace, shared by
+	// every source; nil disables it
+	Quota *RecordQuota
+
+	// Locks serializes writes to a domain across concurrent reconciles and sources;
+	// nil disables locking, which is only safe with MaxConcurrentReconciles of one
+	Locks *DomainLocks
+
+	// Index is the desired state of all objects, shared across sources. Records another
+	// object still wants are not deleted, and owners disagreeing on an IP are reported;
+	// nil disables both.
+	Index *DesiredIndex
+
+	// Wipes checks each listing for a Pi-hole that lost most managed records and
+	// restores them; nil disables the check
+	Wipes *WipeDetector
+
+	// SyncStatus publishes each object's sync state in a PiholeSync; nil disables it
+	SyncStatus *StatusWriter
+
+	// Pending tracks deleted objects waiting for DNS cleanup, shared across sources;
+	// nil disables it
+	Pending *PendingDeletions
+
+	// Heartbeats records every finished reconcile for the liveness check; nil disables it
+	Heartbeats *Heartbeats
+
+	// Batcher coalesces the changes of concurrent reconciles into batched writes;
+	// nil applies each change with its own call
+	Batcher *Batcher
+
+	// MaxConcurrentReconciles is the number of objects reconciled in parallel; zero means one
+	MaxConcurrentReconciles int
+
+	// RateLimit tunes the controller's workqueue; the zero value matches controller-runtime
+	RateLimit RateLimit
+
+	// Backoff computes per-Ingress retry delays for Pi-hole API failures
+	Backoff *Backoff
+
+	// UpdateConflictRequeue is how soon an object is retried after its managed-hosts
+	// annotation could not be written, usually an update conflict; zero means
+	// DefaultUpdateConflictRequeue
+	UpdateConflictRequeue time.Duration
+
+	// MinRequeueAfter is the shortest resync interval the pihole.io/requeue-after
+	// annotation may request; zero means DefaultMinRequeueAfter
+	MinRequeueAfter time.Duration
+
+	// RequireReady defers registration until the Ingress has a load-balancer status,
+	// and withdraws records once the status has been empty for ReadyGracePeriod
+	RequireReady     bool
+	ReadyGracePeriod time.Duration
+
+	// DisableFinalizers stops the operator from adding pihole.io/dns-cleanup. Cleanup then
+	// relies on delete events, so records may outlive an Ingress deleted while the operator
+	// is down. StripFinalizers additionally removes finalizers added by earlier runs.
+	DisableFinalizers bool
+	StripFinalizers   bool
+
+	// FinalizerTimeout and FinalizerMaxAttempts bound how long a deleted Ingress waits for
+	// DNS cleanup; afterwards the finalizer is released and leftover records are handed to
+	// the registry for later removal. Zero values never give up.
+	FinalizerTimeout     time.Duration
+	FinalizerMaxAttempts int
+
+	// RequireRegisterLabel only registers objects labelled pihole.io/register=true, for use
+	// with a cache restricted to them (see CacheOptions). An object whose label is removed
+	// drops out of that cache, so APIReader is consulted before a cache miss is treated as
+	// a deletion; this keeps its records and finalizer from being left behind.
+	RequireRegisterLabel bool
+	APIReader            client.Reader
+
+	// LabelSelector restricts the operator to matching objects, whatever their
+	// annotations; objects that stop matching have their records cleaned up. With a
+	// cache filtered by the same selector, APIReader is consulted as above. nil
+	// matches everything.
+	LabelSelector labels.Selector
+
+	// ClusterID is the cluster the Pi-hole client marks its records with. Records marked
+	// by another cluster sharing the Pi-hole are foreign whatever the overwrite
+	// setting: never updated, adopted, recovered or deleted.
+	ClusterID string
+
+	// IngressClasses limits Ingresses to these classes, taken from spec.ingressClassName
+	// or else the legacy kubernetes.io/ingress.class annotation; Ingresses leaving the
+	// filter have their records cleaned up. Empty allows every class, and classless
+	// Ingresses only pass then.
+	IngressClasses []string
+
+	// ExcludeNamespaces lists namespaces, by exact name or glob, whose objects are never
+	// registered whatever their annotations; records of objects already registered
+	// there are cleaned up
+	ExcludeNamespaces []string
+
+	// ConflictPolicy settles objects wanting different IPs for one record; the zero
+	// value behaves as strict
+	ConflictPolicy ConflictPolicy
+
+	// Live, when set, supplies the options of Settings in place of the fields above
+	// and is read on every use, so a configuration reload takes effect without a restart
+	Live *LiveSettings
+
+	resync     chan event.GenericEvent
+	passErrors map[types.NamespacedName]error
+	lastSync   syncTracker
+	notReady   notReadyTracker
+	tombstones tombstones
+
+	// source adapts the watched kind; nil means Ingresses
+	source objectSource
 }
 
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
 
 // Reconcile handles Ingress create/update/delete events
 func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	logger := r.Logger.With("ingress", req.String())
+	var skipped string
+	result, err := r.reconcile(ctx, req, &skipped)
+	metrics.ReconcileOutcomes.WithLabelValues(r.src().kind(), reconcileOutcome(skipped, result, err)).Inc()
+	// Only a successful reconcile proves progress; a controller failing every
+	// reconcile must not look healthy
+	if err == nil {
+		r.Heartbeats.Beat(r.controllerName())
+	}
+	r.Mirror.Changed()
+	return result, err
+}
+
+// reconcile does the work of Reconcile, setting skipped to the outcome when the object
+// is skipped for a reason its owner has to fix
+func (r *IngressReconciler) reconcile(ctx context.Context, req ctrl.Request, skipped *string) (ctrl.Result, error) {
+	began := time.Now()
+	logger := r.Logger.With(strings.ToLower(r.src().kind()), req.String())
 	logger.Debug("reconcile started")
 
-	var ingress networkingv1.Ingress
-	if err := r.Get(ctx, req.NamespacedName, &ingress); err != nil {
+	obj := r.src().newObject()
+	err := r.Get(ctx, req.NamespacedName, obj)
+	if errors.IsNotFound(err) && (r.RequireRegisterLabel || r.LabelSelector != nil) && r.APIReader != nil {
+		// The object may only have left the label-filtered cache
+		if err = r.APIReader.Get(ctx, req.NamespacedName, obj); err == nil {
+			r.tombstones.forget(req.NamespacedName)
+		}
+	}
+	if err != nil {
 		if errors.IsNotFound(err) {
+			r.Index.Remove(r.src().kind() + " " + req.String())
+			r.Pending.Forget(r.src().kind() + " " + req.String())
+			r.SyncStatus.Forget(r.src().kind(), req.NamespacedName)
+			if tomb := r.tombstones.get(req.NamespacedName); tomb != nil {
+				return r.handleTombstone(ctx, tomb, objectLogger(logger, tomb))
+			}
 			logger.Debug("ingress not found, likely deleted")
+			r.Backoff.Reset(req.NamespacedName)
+			r.notReady.clear(req.NamespacedName)
+			r.lastSync.clear(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		logger.Error("failed to get ingress", "error", err)
 		return ctrl.Result{}, err
 	}
+	logger = objectLogger(logger, obj)
 
 	// Check if the ingress is being deleted
-	if !ingress.DeletionTimestamp.IsZero() {
-		return r.handleDeletion(ctx, &ingress, logger)
+	if !obj.GetDeletionTimestamp().IsZero() {
+		return r.handleDeletion(ctx, obj, logger)
+	}
+
+	if r.DisableFinalizers && r.StripFinalizers && controllerutil.ContainsFinalizer(obj, FinalizerName) {
+		logger.Info("removing finalizer", "reason", "finalizers disabled")
+		controllerutil.RemoveFinalizer(obj, FinalizerName)
+		if err := r.Update(ctx, obj); err != nil {
+			logger.Error("failed to remove finalizer", "error", err)
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Event(obj, corev1.EventTypeNormal, "FinalizerRemoved",
+			"Finalizers are disabled; DNS records may outlive this Ingress if it is deleted while the operator is down")
+	}
+
+	if err := r.repairManagedHosts(ctx, obj, logger); err != nil {
+		logger.Error("failed to update managed hosts annotation", "error", err)
+		return ctrl.Result{}, err
 	}
 
 	// Check if registration is enabled
-	if !r.hasRegistrationAnnotation(&ingress) {
-		// Annotation not present or removed - clean up if we have a finalizer
-		if controllerutil.ContainsFinalizer(&ingress, FinalizerName) {
-			return r.handleDeletion(ctx, &ingress, logger)
+	if !r.hasRegistrationAnnotation(obj) {
+		// Annotation not present or removed - clean up if we have a finalizer or tracked records
+		if controllerutil.ContainsFinalizer(obj, FinalizerName) || len(r.getManagedHosts(obj)) > 0 {
+			return r.handleDeletion(ctx, obj, logger)
 		}
+		r.SyncStatus.Forget(r.src().kind(), req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
 
-	// Add finalizer if not present
-	if !controllerutil.ContainsFinalizer(&ingress, FinalizerName) {
+	policy := r.syncPolicy(obj, logger)
+
+	// Add finalizer if not present; it has nothing to do when deletions are not allowed
+	addedFinalizer := false
+	if !r.DisableFinalizers && policy.AllowsDelete() && !controllerutil.ContainsFinalizer(obj, FinalizerName) {
 		logger.Debug("adding finalizer")
-		controllerutil.AddFinalizer(&ingress, FinalizerName)
-		if err := r.Update(ctx, &ingress); err != nil {
+		addedFinalizer = true
+		controllerutil.AddFinalizer(obj, FinalizerName)
+		if err := r.Update(ctx, obj); err != nil {
 			logger.Error("failed to add finalizer", "error", err)
 			return ctrl.Result{}, err
 		}
 		// Re-fetch after update to get the latest version
-		if err := r.Get(ctx, req.NamespacedName, &ingress); err != nil {
+		if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
+	reason, err := r.notReadyReason(ctx, obj)
+	if err != nil {
+		logger.Error("failed to check readiness", "error", err)
+		return ctrl.Result{}, err
+	}
+	if reason != "" {
+		return r.handleNotReady(ctx, obj, reason, logger)
+	}
+	r.notReady.clear(req.NamespacedName)
+
 	// Get desired state
-	desiredHosts := r.extractHosts(&ingress)
+	r.warnInvalidPairPrefixes(obj, logger)
+	listedHosts, ok, err := r.hostsFromConfigMap(ctx, obj, logger)
+	if err != nil {
+		logger.Error("failed to read hosts configmap", "error", err)
+		return ctrl.Result{}, err
+	}
+	if !ok {
+		// The ConfigMap watch reconciles again once it can be read
+		return ctrl.Result{}, nil
+	}
+	mode := r.hostsMode(obj, logger)
+	desiredHosts := r.settings().HostFilter.Filter(r.extractHosts(obj, listedHosts, mode), logger)
 	if len(desiredHosts) == 0 {
+		r.Index.Remove(r.ownerOf(obj))
+		r.SyncStatus.Forget(r.src().kind(), req.NamespacedName)
 		logger.Warn("ingress skipped (no hosts)")
+		*skipped = outcomeSkippedNoHosts
 		return ctrl.Result{}, nil
 	}
 
-	targetIP := r.resolveTargetIP(&ingress)
-	if targetIP == "" {
+	targetIP := r.resolveTargetIP(obj)
+	if targetIP == "" && obj.GetAnnotations()[AnnotationTargetIP] != "" {
 		logger.Warn("invalid annotation", "annotation", AnnotationTargetIP,
-			"value", ingress.Annotations[AnnotationTargetIP], "error", "not a valid IPv4 address")
+			"value", obj.GetAnnotations()[AnnotationTargetIP], "error", "not a valid IPv4 address")
+		*skipped = outcomeSkippedInvalidIP
 		return ctrl.Result{}, nil // Don't requeue - user needs to fix annotation
 	}
+	if targetIPv6, _ := r.resolveTargetIPv6(obj); targetIP == "" && targetIPv6 == "" {
+		// No default and no address in the status yet; status updates reconcile again
+		logger.Warn("no target ip, waiting for a load-balancer address")
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "NoTargetIP",
+			"No target IP: set %s or wait for a load-balancer address", AnnotationTargetIP)
+		return ctrl.Result{RequeueAfter: notReadyRequeue}, nil
+	}
 
 	// Get current Pi-hole records
+	start := time.Now()
 	currentRecords, err := r.PiholeClient.ListRecords(ctx)
+	r.observePhase(phaseList, start)
 	if err != nil {
 		logger.Error("pihole api error", "operation", "list", "error", err)
-		return ctrl.Result{RequeueAfter: 30 * time.Second}, err
+		return r.handleAPIError(err, req.NamespacedName, logger)
+	}
+	if r.Wipes.Observe(currentRecords) {
+		r.Recorder.Event(obj, corev1.EventTypeWarning, "PiholeWipeDetected",
+			"Pi-hole lost most of the DNS records managed by the operator; restoring them")
 	}
 
-	// Build a map of current records for quick lookup
-	currentRecordMap := make(map[string]string)
-	for _, record := range currentRecords {
-		currentRecordMap[record.Domain] = record.IP
+	// Get previously managed records. A finalizer added by an earlier reconcile means
+	// records were registered, so a missing annotation was removed by someone else.
+	// Adopting existing records is overwriting them, so recovery needs overwrite.
+	managedHosts := r.getManagedHosts(obj)
+	overwrite := r.overwriteAllowed(obj, logger) && policy.AllowsUpdate()
+	if len(managedHosts) == 0 && overwrite && !addedFinalizer && controllerutil.ContainsFinalizer(obj, FinalizerName) {
+		if managedHosts, err = r.recoverManagedHosts(ctx, obj, currentRecords, logger); err != nil {
+			logger.Error("failed to recover managed hosts", "error", err)
+			return ctrl.Result{}, err
+		}
 	}
 
-	// Get previously managed hosts
-	managedHosts := r.getManagedHosts(&ingress)
+	// AAAA records are managed independently; an invalid IPv6 target leaves the
+	// existing ones alone instead of blocking the A records
+	targetIPv6, ok := r.resolveTargetIPv6(obj)
+	var keptHosts []string
+	if !ok {
+		value := obj.GetAnnotations()[AnnotationTargetIPv6]
+		logger.Warn("invalid annotation", "annotation", AnnotationTargetIPv6, "value", value, "error", "not a valid IPv6 address")
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+			"%s=%q is not a valid IPv6 address; AAAA records left unchanged", AnnotationTargetIPv6, value)
+		keptHosts = keysOfType(managedHosts, pihole.TypeAAAA)
+		managedHosts = withoutHosts(managedHosts, keptHosts)
+	}
 
-	// Sync desired records
+	desired := make([]pihole.DNSRecord, 0, 2*len(desiredHosts))
 	for _, host := range desiredHosts {
-		currentIP, exists := currentRecordMap[host]
-		if !exists || currentIP != targetIP {
-			// Need to create or update
-			if exists && currentIP != targetIP {
-				// Delete old record first (Pi-hole doesn't support update)
-				if err := r.PiholeClient.DeleteRecord(ctx, host); err != nil {
-					logger.Error("pihole api error", "operation", "delete", "error", err)
-					return r.handleAPIError(err, logger)
-				}
-				logger.Info("dns record updated", "host", host, "old_ip", currentIP, "new_ip", targetIP)
-			}
+		if targetIP != "" {
+			desired = append(desired, pihole.DNSRecord{Domain: host, IP: targetIP})
+		}
+		if targetIPv6 != "" {
+			desired = append(desired, pihole.DNSRecord{Domain: host, IP: targetIPv6})
+		}
+	}
+	// Records past their expiry are dropped, so the plan removes them
+	desired, expired, expiresIn, err := r.applyExpiry(ctx, obj, desired, logger)
+	if err != nil {
+		logger.Error("failed to update registry", "error", err)
+		return ctrl.Result{}, err
+	}
+	// The index keeps every record the object wants, so records it loses under
+	// oldest-wins are handed over once the older object lets go of them. Records
+	// beyond the record quota are left out of both.
+	desired, overQuota := r.admitRecords(obj, desired, managedHosts, logger)
+	// Records wanted from other namespaces go to the oldest object first; the conflict
+	// policy then settles what is left between objects that may share records
+	r.Index.SetAge(r.ownerOf(obj), obj.GetCreationTimestamp().Time, string(obj.GetUID()))
+	desired, lost, won := r.resolveNamespaceConflicts(obj, desired, logger)
+	managedHosts = withoutHosts(managedHosts, lost)
+	if r.settings().ConflictPolicy == ConflictPolicyOldestWins {
+		var lostOldest, wonOldest []string
+		desired, lostOldest, wonOldest = r.resolveConflicts(obj, desired, logger)
+		managedHosts = withoutHosts(managedHosts, lostOldest)
+		lost, won = append(lost, lostOldest...), append(won, wonOldest...)
+	} else {
+		r.reportTargetConflicts(obj, desired, logger)
+	}
+	desiredKeys := recordKeys(desired)
 
-			record := pihole.DNSRecord{Domain: host, IP: targetIP}
-			if err := r.PiholeClient.CreateRecord(ctx, record); err != nil {
-				logger.Error("pihole api error", "operation", "create", "error", err)
-				return r.handleAPIError(err, logger)
-			}
+	// Claiming a record cancels any deferred deletion left behind by a previous owner
+	if r.Registry != nil {
+		if err := r.Registry.Update(ctx, func(st *registry.State) bool {
+			return st.CancelPendingDeletions(desiredKeys)
+		}); err != nil {
+			logger.Error("failed to update registry", "error", err)
+			return ctrl.Result{}, err
+		}
+	}
 
-			if !exists {
-				logger.Info("dns record created", "host", host, "ip", targetIP)
-			}
+	plan := computePlan(currentRecords, desired, managedHosts)
+	plan.addPartialDeletes(pihole.PartialRecords(r.PiholeClient), managedHosts)
+	// Stale records another object still wants are handed over instead of deleted
+	shared := r.Index.ClaimedByOthers(r.ownerOf(obj), recordKeys(plan.Deletes))
+	if shared = withoutHosts(shared, r.handedOver(recordIPs(plan.Deletes))); len(shared) > 0 {
+		logger.Info("dns record kept, still wanted by another object", "hosts", strings.Join(shared, ","))
+		plan.dropDeletes(shared)
+	}
+	// Records another cluster has marked are left to it, and no longer tracked
+	released := r.skipOtherClusters(obj, &plan, currentRecords, logger)
+	managedHosts = withoutHosts(managedHosts, released)
+	claimedHosts := withoutHosts(desiredKeys, released)
+	// Records that already exist with the desired IP are adopted without a write
+	owned := append(append([]string{}, managedHosts...), won...)
+	adopted := adoptable(plan, owned)
+	// create-only never touches existing records, so it implies no overwrite. Records
+	// won from newer objects are theirs, not foreign. Adoption writes nothing, so
+	// it is allowed on its own.
+	if !overwrite {
+		if r.adoptAllowed(obj, logger) {
+			owned = append(owned, adopted...)
+		} else {
+			adopted = nil
+		}
+		conflicts, foreign := plan.dropForeign(owned)
+		for _, c := range conflicts {
+			logger.Warn("existing record not overwritten", "host", c.Domain, "existing_ip", c.OldIP)
+			r.Recorder.Eventf(obj, corev1.EventTypeWarning, "RecordConflict",
+				"Pi-hole already resolves %s to %s; not overwriting", c.Domain, c.OldIP)
 		}
+		claimedHosts = withoutHosts(claimedHosts, append(updateKeys(conflicts), foreign...))
 	}
+	r.markCluster(&plan, currentRecords, logger)
 
-	// Delete records for hosts no longer desired
-	desiredHostSet := make(map[string]bool)
-	for _, h := range desiredHosts {
-		desiredHostSet[h] = true
+	// Changes withheld by the sync policy stay tracked so a later switch to sync applies them
+	trackedHosts := append(append([]string{}, claimedHosts...), keptHosts...)
+	if !policy.AllowsUpdate() && len(plan.Updates) > 0 {
+		logger.Info("dns record update skipped", "hosts", strings.Join(updateKeys(plan.Updates), ","),
+			"sync_policy", string(policy))
+		plan.Updates = nil
+	}
+	if !policy.AllowsDelete() && len(plan.Deletes) > 0 {
+		skipDeletions(policy, recordKeys(plan.Deletes), logger)
+		trackedHosts = append(trackedHosts, recordKeys(plan.Deletes)...)
+		plan.Deletes = nil
+	}
+
+	if plan.IsEmpty() {
+		logger.Debug("change plan computed", "plan", plan)
+	} else {
+		logger.Info("change plan computed", "plan", plan)
+	}
+
+	// Stale hosts stay tracked when the deletion guard refuses to prune them,
+	// so they are removed once the threshold is raised or confirmed
+	result := ctrl.Result{}
+	if blocked, res := r.checkDeletionGuard(obj, recordKeys(plan.Deletes), logger); blocked {
+		trackedHosts = append(trackedHosts, recordKeys(plan.Deletes)...)
+		plan.Deletes = nil
+		result = res
+	}
+	if len(lost) > 0 && (result.RequeueAfter == 0 || result.RequeueAfter > conflictRequeue) {
+		result.RequeueAfter = conflictRequeue
+	}
+	if overQuota && (result.RequeueAfter == 0 || result.RequeueAfter > quotaRequeue) {
+		result.RequeueAfter = quotaRequeue
+	}
+	if every := r.requeueAfter(obj, logger); every > 0 && (result.RequeueAfter == 0 || result.RequeueAfter > every) {
+		result.RequeueAfter = every
+	}
+	if expiresIn > 0 && (result.RequeueAfter == 0 || result.RequeueAfter > expiresIn) {
+		result.RequeueAfter = expiresIn
 	}
 
-	for _, host := range managedHosts {
-		if !desiredHostSet[host] {
-			if err := r.PiholeClient.DeleteRecord(ctx, host); err != nil {
+	r.announceRemovals(ctx, obj, recordKeys(plan.Deletes), logger)
+	start = time.Now()
+	applied, err := r.applyPlan(ctx, r.ownerOf(obj), plan, logger)
+	r.observePhase(phaseSync, start)
+	if err != nil {
+		r.refundDeletions(obj, len(withoutHosts(recordKeys(plan.Deletes), recordKeys(applied.Deletes))))
+		r.recordOwnership(ctx, obj, applied, nil, currentRecords, logger)
+	} else {
+		r.recordOwnership(ctx, obj, applied, trackedHosts, currentRecords, logger)
+	}
+	r.Notifier.Enqueue(planSummary(r.ownerOf(obj), applied))
+	r.logSyncSummary(logger, req.String(), applied, len(plan.Unchanged), targetIP, targetIPv6, time.Since(began), err)
+	if err != nil {
+		return r.handleAPIError(err, req.NamespacedName, logger)
+	}
+
+	// Update managed hosts annotation
+	start = time.Now()
+	err = r.updateManagedHosts(ctx, obj, trackedHosts)
+	r.observePhase(phaseAnnotate, start)
+	if err != nil {
+		logger.Error("failed to update managed hosts annotation", "error", err)
+		r.SyncStatus.Failed(r.src().kind(), req.NamespacedName, err)
+		return ctrl.Result{RequeueAfter: r.updateConflictRequeue()}, nil
+	}
+
+	r.reportAdoptions(ctx, obj, adopted, recordIPs(currentRecords), logger)
+
+	r.Backoff.Reset(req.NamespacedName)
+	r.lastSync.mark(req.NamespacedName, time.Now())
+	r.SyncStatus.Synced(r.src().kind(), req.NamespacedName, trackedHosts)
+	r.SyncStatus.Expired(r.src().kind(), req.NamespacedName, expired)
+	return result, nil
+}
+
+// applyPlan executes a change plan against Pi-hole. Updates delete the old record
+// before creating the new one since Pi-hole has no in-place update. It returns the
+// part of the plan that was applied, which is partial when an error stops it.
+func (r *IngressReconciler) applyPlan(ctx context.Context, owner string, plan Plan, logger *slog.Logger) (Plan, error) {
+	if r.Batcher != nil {
+		return r.applyPlanBatched(ctx, owner, plan, logger)
+	}
+
+	var applied Plan
+	for _, u := range plan.Updates {
+		deleted, err := r.updateRecord(ctx, u)
+		if err != nil {
+			if deleted {
+				logger.Error("pihole api error", "operation", "create", "error", err)
+				// The old record is already gone
+				applied.Deletes = append(applied.Deletes, pihole.DNSRecord{Domain: u.Domain, IP: u.OldIP})
+				recordAudit(ctx, r.Audit, audit.Entry{Action: audit.ActionDelete, Domain: u.Domain, Type: u.Type(), OldIP: u.OldIP, Owner: owner}, logger)
+			} else {
 				logger.Error("pihole api error", "operation", "delete", "error", err)
-				return r.handleAPIError(err, logger)
 			}
-			logger.Info("dns record deleted", "host", host)
+			return applied, err
 		}
+		logger.Debug("dns record updated", "host", u.Domain, "old_ip", u.OldIP, "new_ip", u.NewIP)
+		applied.Updates = append(applied.Updates, u)
+		recordAudit(ctx, r.Audit, audit.Entry{Action: audit.ActionUpdate, Domain: u.Domain, Type: u.Type(), OldIP: u.OldIP, NewIP: u.NewIP, Owner: owner}, logger)
 	}
 
-	// Update managed hosts annotation
-	if err := r.updateManagedHosts(ctx, &ingress, desiredHosts); err != nil {
+	for _, record := range plan.Creates {
+		unlock := r.Locks.Lock(record.Domain)
+		err := r.PiholeClient.CreateRecord(ctx, record)
+		unlock()
+		if err != nil {
+			logger.Error("pihole api error", "operation", "create", "error", err)
+			return applied, err
+		}
+		logger.Debug("dns record created", "host", record.Domain, "ip", record.IP)
+		applied.Creates = append(applied.Creates, record)
+		recordAudit(ctx, r.Audit, audit.Entry{Action: audit.ActionCreate, Domain: record.Domain, Type: record.Type(), NewIP: record.IP, Owner: owner}, logger)
+	}
+
+	for _, record := range plan.Deletes {
+		if err := r.deleteRecordKey(ctx, record.Key()); err != nil {
+			logger.Error("pihole api error", "operation", "delete", "error", err)
+			return applied, err
+		}
+		logger.Debug("dns record deleted", "host", record.Key())
+		applied.Deletes = append(applied.Deletes, record)
+		recordAudit(ctx, r.Audit, audit.Entry{Action: audit.ActionDelete, Domain: record.Domain, Type: record.Type(), OldIP: record.IP, Owner: owner}, logger)
+	}
+
+	return applied, nil
+}
+
+// applyPlanBatched submits the whole plan to the batcher, which applies it all or
+// nothing. The plan's domains stay locked until the batch is flushed.
+func (r *IngressReconciler) applyPlanBatched(ctx context.Context, owner string, plan Plan, logger *slog.Logger) (Plan, error) {
+	var batch pihole.Batch
+	var domains []string
+	for _, u := range plan.Updates {
+		batch.Deletes = append(batch.Deletes, u.Key())
+		batch.Creates = append(batch.Creates, pihole.DNSRecord{Domain: u.Domain, IP: u.NewIP})
+		domains = append(domains, u.Domain)
+	}
+	for _, record := range plan.Creates {
+		batch.Creates = append(batch.Creates, record)
+		domains = append(domains, record.Domain)
+	}
+	for _, record := range plan.Deletes {
+		batch.Deletes = append(batch.Deletes, record.Key())
+		domains = append(domains, record.Domain)
+	}
+	if batch.Len() == 0 {
+		return Plan{}, nil
+	}
+
+	// Locks are taken in sorted order so overlapping plans cannot deadlock
+	domains = uniqueHosts(domains)
+	sort.Strings(domains)
+	for _, domain := range domains {
+		defer r.Locks.Lock(domain)()
+	}
+
+	if err := r.Batcher.Submit(ctx, batch); err != nil {
+		logger.Error("pihole api error", "operation", "batch", "error", err)
+		return Plan{}, err
+	}
+
+	for _, u := range plan.Updates {
+		logger.Debug("dns record updated", "host", u.Domain, "old_ip", u.OldIP, "new_ip", u.NewIP)
+		recordAudit(ctx, r.Audit, audit.Entry{Action: audit.ActionUpdate, Domain: u.Domain, Type: u.Type(), OldIP: u.OldIP, NewIP: u.NewIP, Owner: owner}, logger)
+	}
+	for _, record := range plan.Creates {
+		logger.Debug("dns record created", "host", record.Domain, "ip", record.IP)
+		recordAudit(ctx, r.Audit, audit.Entry{Action: audit.ActionCreate, Domain: record.Domain, Type: record.Type(), NewIP: record.IP, Owner: owner}, logger)
+	}
+	for _, record := range plan.Deletes {
+		logger.Debug("dns record deleted", "host", record.Key())
+		recordAudit(ctx, r.Audit, audit.Entry{Action: audit.ActionDelete, Domain: record.Domain, Type: record.Type(), OldIP: record.IP, Owner: owner}, logger)
+	}
+	return plan, nil
+}
+
+// updateRecord replaces a record under its domain lock, so no other reconcile sees the
+// gap between the delete and the create. deleted reports whether the old record is gone.
+func (r *IngressReconciler) updateRecord(ctx context.Context, u RecordUpdate) (deleted bool, err error) {
+	defer r.Locks.Lock(u.Domain)()
+	if err := r.PiholeClient.DeleteRecord(ctx, u.Domain, u.Type()); err != nil {
+		return false, err
+	}
+	return true, r.PiholeClient.CreateRecord(ctx, pihole.DNSRecord{Domain: u.Domain, IP: u.NewIP})
+}
+
+// handleNotReady holds back registration for an object that is not ready, e.g. an
+// Ingress without a load-balancer status. Records that were already published are
+// withdrawn once the grace period expires.
+func (r *IngressReconciler) handleNotReady(ctx context.Context, obj client.Object, reason string, logger *slog.Logger) (ctrl.Result, error) {
+	key := client.ObjectKeyFromObject(obj)
+	managedHosts := r.getManagedHosts(obj)
+	if len(managedHosts) == 0 {
+		logger.Debug("waiting for readiness before registering", "reason", reason)
+		return ctrl.Result{RequeueAfter: notReadyRequeue}, nil
+	}
+
+	now := time.Now()
+	since := r.notReady.mark(key, now)
+	if remaining := since.Add(r.ReadyGracePeriod).Sub(now); remaining > 0 {
+		logger.Debug("readiness lost, waiting for grace period", "reason", reason, "remaining", remaining.String())
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	// The object no longer wants its records; those another object wants are left alone
+	r.Index.Remove(r.ownerOf(obj))
+	managedHosts = withoutHosts(managedHosts, r.Index.ClaimedByOthers(r.ownerOf(obj), managedHosts))
+
+	if policy := r.syncPolicy(obj, logger); !policy.AllowsDelete() {
+		// Records stay tracked; the next status change triggers another reconcile
+		skipDeletions(policy, managedHosts, logger)
+		return ctrl.Result{}, nil
+	}
+	if blocked, res := r.checkDeletionGuard(obj, managedHosts, logger); blocked {
+		return res, nil
+	}
+	r.announceRemovals(ctx, obj, managedHosts, logger)
+	var deleted []string
+	defer func() {
+		forgetRecords(ctx, r.Registry, deleted, logger)
+		r.Notifier.Enqueue(deletionSummary(r.ownerOf(obj), deleted))
+	}()
+	for i, host := range managedHosts {
+		if err := r.deleteRecordKey(ctx, host); err != nil {
+			logger.Error("pihole api error", "operation", "delete", "error", err)
+			r.refundDeletions(obj, len(managedHosts)-i)
+			return r.handleAPIError(err, key, logger)
+		}
+		logger.Info("dns record deleted", "host", host, "reason", "not ready")
+		deleted = append(deleted, host)
+		domain, recordType := pihole.ParseRecordKey(host)
+		recordAudit(ctx, r.Audit, audit.Entry{Action: audit.ActionDelete, Domain: domain, Type: recordType, Owner: r.ownerOf(obj)}, logger)
+	}
+	r.Recorder.Eventf(obj, corev1.EventTypeNormal, "RecordsWithdrawn",
+		"%s for %s; removed %d DNS records", reason, r.ReadyGracePeriod, len(managedHosts))
+
+	if err := r.updateManagedHosts(ctx, obj, nil); err != nil {
 		logger.Error("failed to update managed hosts annotation", "error", err)
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, err
+		return ctrl.Result{RequeueAfter: r.updateConflictRequeue()}, nil
 	}
 
-	return ctrl.Result{}, nil
+	r.Backoff.Reset(key)
+	r.SyncStatus.Synced(r.src().kind(), key, nil)
+	return ctrl.Result{RequeueAfter: notReadyRequeue}, nil
+}
+
+// reportTargetConflicts warns when other objects want a different IP for one of the
+// desired records; whichever reconciles last wins until the objects agree
+func (r *IngressReconciler) reportTargetConflicts(obj client.Object, desired []pihole.DNSRecord, logger *slog.Logger) {
+	self := r.ownerOf(obj)
+	for _, rec := range desired {
+		targets := r.Index.Targets(rec.Key())
+		owners := make([]string, 0, len(targets))
+		for owner, ip := range targets {
+			if owner != self && ip != rec.IP {
+				owners = append(owners, owner+"="+ip)
+			}
+		}
+		if len(owners) == 0 {
+			continue
+		}
+		sort.Strings(owners)
+		logger.Warn("record wanted with a different ip by another object", "host", rec.Key(), "ip", rec.IP,
+			"other_owners", strings.Join(owners, ","))
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "TargetConflict",
+			"%s is also wanted by %s", rec.Key(), strings.Join(owners, ", "))
+	}
 }
 
 // handleDeletion cleans up DNS records and removes finalizer
-func (r *IngressReconciler) handleDeletion(ctx context.Context, ingress *networkingv1.Ingress, logger *slog.Logger) (ctrl.Result, error) {
-	// Check if we have our finalizer
-	if !controllerutil.ContainsFinalizer(ingress, FinalizerName) {
+func (r *IngressReconciler) handleDeletion(ctx context.Context, obj client.Object, logger *slog.Logger) (ctrl.Result, error) {
+	r.Index.Remove(r.ownerOf(obj))
+	r.Pending.Track(r.ownerOf(obj), obj)
+	hasFinalizer := controllerutil.ContainsFinalizer(obj, FinalizerName)
+	managedHosts := r.getManagedHosts(obj)
+	if !hasFinalizer && len(managedHosts) == 0 {
 		return ctrl.Result{}, nil
 	}
 
-	// Clean up DNS records
-	managedHosts := r.getManagedHosts(ingress)
-	for _, host := range managedHosts {
-		if err := r.PiholeClient.DeleteRecord(ctx, host); err != nil {
-			logger.Error("pihole api error", "operation", "delete", "error", err)
-			return r.handleAPIError(err, logger)
-		}
-		logger.Info("dns record deleted", "host", host)
+	if done, res, err := r.cleanupRecords(ctx, obj, managedHosts, logger); !done {
+		return res, err
 	}
+	r.forgetExpiries(ctx, obj, logger)
 
-	// Remove finalizer
-	logger.Debug("removing finalizer")
-	controllerutil.RemoveFinalizer(ingress, FinalizerName)
-	if err := r.Update(ctx, ingress); err != nil {
+	// Drop the finalizer and, if the Ingress lives on, the now-stale tracking annotation
+	if hasFinalizer {
+		logger.Debug("removing finalizer")
+		controllerutil.RemoveFinalizer(obj, FinalizerName)
+	}
+	if obj.GetDeletionTimestamp().IsZero() {
+		annotations := obj.GetAnnotations()
+		delete(annotations, AnnotationManagedHosts)
+		obj.SetAnnotations(annotations)
+	}
+	if err := r.Update(ctx, obj); err != nil {
 		logger.Error("failed to remove finalizer", "error", err)
 		return ctrl.Result{}, err
 	}
+	r.Pending.Forget(r.ownerOf(obj))
+
+	r.Backoff.Reset(client.ObjectKeyFromObject(obj))
+	r.SyncStatus.Forget(r.src().kind(), client.ObjectKeyFromObject(obj))
+	return ctrl.Result{}, nil
+}
+
+// objectLogger adds the identity of obj to logger, telling apart the lines of an object
+// deleted and recreated under the same name
+func objectLogger(logger *slog.Logger, obj client.Object) *slog.Logger {
+	return logger.With("uid", string(obj.GetUID()), "generation", obj.GetGeneration(),
+		"resource_version", obj.GetResourceVersion())
+}
+
+// handleTombstone cleans up records of an Ingress deleted while finalizers are disabled
+func (r *IngressReconciler) handleTombstone(ctx context.Context, tomb client.Object, logger *slog.Logger) (ctrl.Result, error) {
+	if done, res, err := r.cleanupRecords(ctx, tomb, r.getManagedHosts(tomb), logger); !done {
+		return res, err
+	}
 
+	key := client.ObjectKeyFromObject(tomb)
+	r.tombstones.forget(key)
+	r.Backoff.Reset(key)
+	r.notReady.clear(key)
+	r.SyncStatus.Forget(r.src().kind(), key)
 	return ctrl.Result{}, nil
 }
 
-// handleAPIError determines the requeue behavior based on the error type
-func (r *IngressReconciler) handleAPIError(err error, logger *slog.Logger) (ctrl.Result, error) {
-	if apiErr, ok := err.(*pihole.APIError); ok {
+// cleanupRecords removes (or schedules removal of) the given hosts for an Ingress that
+// is going away. It reports done=false with the result to return when cleanup must wait.
+func (r *IngressReconciler) cleanupRecords(ctx context.Context, obj client.Object, hosts []string, logger *slog.Logger) (bool, ctrl.Result, error) {
+	shared := r.Index.ClaimedByOthers(r.ownerOf(obj), hosts)
+	if shared = withoutHosts(shared, r.handedOver(r.targetIPs(obj, shared))); len(shared) > 0 {
+		logger.Info("dns record kept, still wanted by another object", "hosts", strings.Join(shared, ","))
+		hosts = withoutHosts(hosts, shared)
+	}
+
+	if policy := r.syncPolicy(obj, logger); !policy.AllowsDelete() {
+		skipDeletions(policy, hosts, logger)
+		return true, ctrl.Result{}, nil
+	}
+
+	if blocked, res := r.checkDeletionGuard(obj, hosts, logger); blocked {
+		return false, res, nil
+	}
+
+	if grace, ok := r.deletionGracePeriod(obj, logger); ok && len(hosts) > 0 {
+		if err := r.scheduleDeletion(ctx, obj, hosts, grace); err != nil {
+			logger.Error("failed to schedule deferred deletion", "error", err)
+			return false, ctrl.Result{}, err
+		}
+		logger.Info("dns record deletion deferred", "hosts", strings.Join(hosts, ","), "grace_period", grace.String())
+		r.Recorder.Eventf(obj, corev1.EventTypeNormal, "DeletionDeferred",
+			"DNS records for %s will be removed in %s unless reclaimed", strings.Join(hosts, ","), grace)
+		return true, ctrl.Result{}, nil
+	}
+
+	r.announceRemovals(ctx, obj, hosts, logger)
+	var deleted []string
+	defer func() {
+		forgetRecords(ctx, r.Registry, deleted, logger)
+		r.Notifier.Enqueue(deletionSummary(r.ownerOf(obj), deleted))
+	}()
+	defer r.observePhase(phasePrune, time.Now())
+	for i, host := range hosts {
+		if err := r.deleteRecordKey(ctx, host); err != nil {
+			logger.Error("pihole api error", "operation", "delete", "error", err)
+			r.refundDeletions(obj, len(hosts)-i)
+			if r.cleanupExpired(obj) {
+				return r.abandonCleanup(ctx, obj, hosts[i:], logger)
+			}
+			res, err := r.handleAPIError(err, client.ObjectKeyFromObject(obj), logger)
+			return false, res, err
+		}
+		logger.Info("dns record deleted", "host", host)
+		deleted = append(deleted, host)
+		domain, recordType := pihole.ParseRecordKey(host)
+		recordAudit(ctx, r.Audit, audit.Entry{Action: audit.ActionDelete, Domain: domain, Type: recordType, Owner: r.ownerOf(obj)}, logger)
+	}
+	return true, ctrl.Result{}, nil
+}
+
+// cleanupExpired reports whether a deleted Ingress has waited too long for DNS cleanup
+func (r *IngressReconciler) cleanupExpired(obj client.Object) bool {
+	if obj.GetDeletionTimestamp().IsZero() {
+		return false
+	}
+	if r.FinalizerTimeout > 0 && time.Since(obj.GetDeletionTimestamp().Time) >= r.FinalizerTimeout {
+		return true
+	}
+	// The current failure is not yet counted by the backoff
+	return r.FinalizerMaxAttempts > 0 && r.Backoff.Failures(client.ObjectKeyFromObject(obj))+1 >= r.FinalizerMaxAttempts
+}
+
+// abandonCleanup releases a deleted Ingress whose DNS cleanup keeps failing. Remaining
+// hosts are recorded as pending deletions so the deferred deleter retries them.
+func (r *IngressReconciler) abandonCleanup(ctx context.Context, obj client.Object, hosts []string, logger *slog.Logger) (bool, ctrl.Result, error) {
+	metrics.FinalizerTimeouts.Inc()
+
+	if r.Registry == nil {
+		logger.Warn("dns cleanup abandoned, records left in pi-hole", "hosts", strings.Join(hosts, ","))
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "CleanupAbandoned",
+			"Pi-hole unreachable; releasing finalizer and leaving DNS records %s in place", strings.Join(hosts, ","))
+		return true, ctrl.Result{}, nil
+	}
+
+	owner := r.ownerOf(obj)
+	now := time.Now()
+	if err := r.Registry.Update(ctx, func(st *registry.State) bool {
+		for _, host := range hosts {
+			st.AddPendingDeletion(host, registry.PendingDeletion{
+				Owner:       owner,
+				DeleteAfter: now,
+				Reason:      registry.ReasonCleanupTimeout,
+			})
+		}
+		return true
+	}); err != nil {
+		logger.Error("failed to record pending cleanup", "error", err)
+		return false, ctrl.Result{}, err
+	}
+
+	logger.Warn("dns cleanup abandoned, records queued for later removal", "hosts", strings.Join(hosts, ","))
+	r.Recorder.Eventf(obj, corev1.EventTypeWarning, "CleanupAbandoned",
+		"Pi-hole unreachable; releasing finalizer and queueing DNS records %s for removal once it returns", strings.Join(hosts, ","))
+	return true, ctrl.Result{}, nil
+}
+
+// deletionGracePeriod returns the grace period requested via annotation, if any.
+// Invalid values are reported and ignored so cleanup proceeds immediately.
+func (r *IngressReconciler) deletionGracePeriod(obj client.Object, logger *slog.Logger) (time.Duration, bool) {
+	value := obj.GetAnnotations()[AnnotationDeletionGracePeriod]
+	if value == "" {
+		return 0, false
+	}
+
+	grace, err := time.ParseDuration(value)
+	if err != nil || grace <= 0 {
+		logger.Warn("invalid annotation", "annotation", AnnotationDeletionGracePeriod,
+			"value", value, "error", "not a positive duration")
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+			"%s=%q is not a positive duration; deleting records immediately", AnnotationDeletionGracePeriod, value)
+		return 0, false
+	}
+	if r.Registry == nil {
+		logger.Warn("deletion grace period requires the registry; deleting records immediately")
+		return 0, false
+	}
+	return grace, true
+}
+
+// scheduleDeletion hands hosts to the deferred deleter via the registry
+func (r *IngressReconciler) scheduleDeletion(ctx context.Context, obj client.Object, hosts []string, grace time.Duration) error {
+	owner := r.ownerOf(obj)
+	deleteAfter := time.Now().Add(grace)
+	return r.Registry.Update(ctx, func(st *registry.State) bool {
+		for _, host := range hosts {
+			st.SchedulePendingDeletion(host, owner, deleteAfter)
+		}
+		return true
+	})
+}
+
+// updateConflictRequeue returns the delay before retrying a failed annotation update.
+// The error is logged rather than returned, since controller-runtime would otherwise
+// ignore the delay.
+func (r *IngressReconciler) updateConflictRequeue() time.Duration {
+	if delay := r.settings().UpdateConflictRequeue; delay > 0 {
+		return delay
+	}
+	return DefaultUpdateConflictRequeue
+}
+
+// handleAPIError determines the requeue behavior based on the error type.
+// Retryable errors are requeued with a per-object exponential backoff; the error
+// itself is not returned because controller-runtime ignores RequeueAfter when it is.
+// The failure is recorded in the sync status.
+func (r *IngressReconciler) handleAPIError(err error, key types.NamespacedName, logger *slog.Logger) (ctrl.Result, error) {
+	r.SyncStatus.Failed(r.src().kind(), key, err)
+	if r.passErrors != nil {
+		r.passErrors[key] = err
+	}
+	r.SyncStatus.PiholeError(err)
+	if apiErr, ok := pihole.AsAPIError(err); ok {
 		if !apiErr.IsRetryable() {
 			logger.Warn("non-retryable api error", "error", err)
+			r.Backoff.Reset(key)
 			return ctrl.Result{}, nil // Don't requeue
 		}
 	}
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, err
+	delay := r.Backoff.Next(key)
+	logger.Debug("requeueing after api error", "retry_in", delay.String())
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// checkDeletionGuard reports whether the deletion guard refuses to delete hosts for this Ingress.
+// When blocked, a Warning Event is emitted and the returned result carries the requeue to use.
+func (r *IngressReconciler) checkDeletionGuard(obj client.Object, hosts []string, logger *slog.Logger) (bool, ctrl.Result) {
+	if len(hosts) == 0 || obj.GetAnnotations()[AnnotationConfirmDeletions] == "true" {
+		return false, ctrl.Result{}
+	}
+
+	err := r.DeletionGuard.Reserve(len(hosts))
+	if err == nil {
+		return false, ctrl.Result{}
+	}
+
+	limitErr, ok := err.(*DeletionLimitError)
+	if !ok {
+		return false, ctrl.Result{}
+	}
+
+	metrics.DeletionsBlocked.WithLabelValues(limitErr.Scope).Inc()
+	logger.Warn("dns record deletions blocked", "scope", limitErr.Scope, "requested", limitErr.Requested,
+		"limit", limitErr.Limit, "hosts", strings.Join(hosts, ","))
+	r.Recorder.Eventf(obj, corev1.EventTypeWarning, "DeletionsBlocked",
+		"%s; raise the limit or set %s=\"true\" to proceed", limitErr.Error(), AnnotationConfirmDeletions)
+
+	// The per-sync limit only changes when the user acts, which triggers a new reconcile;
+	// the rolling budget frees up on its own
+	return true, ctrl.Result{RequeueAfter: limitErr.RetryAfter}
+}
+
+// refundDeletions returns n deletions charged by checkDeletionGuard that failed
+func (r *IngressReconciler) refundDeletions(obj client.Object, n int) {
+	if obj.GetAnnotations()[AnnotationConfirmDeletions] == "true" {
+		return
+	}
+	r.DeletionGuard.Refund(n)
 }
 
-// hasRegistrationAnnotation checks if the Ingress has the registration annotation set to "true"
-func (r *IngressReconciler) hasRegistrationAnnotation(ingress *networkingv1.Ingress) bool {
-	if ingress.Annotations == nil {
+// hasRegistrationAnnotation checks if the object has the registration annotation or
+// label set to "true"; only the label counts with RequireRegisterLabel. Objects outside
+// LabelSelector or IngressClasses, or in ExcludeNamespaces, are never registered.
+func (r *IngressReconciler) hasRegistrationAnnotation(obj client.Object) bool {
+	if r.namespaceExcluded(obj.GetNamespace()) {
+		return false
+	}
+	if r.LabelSelector != nil && !r.LabelSelector.Matches(labels.Set(obj.GetLabels())) {
 		return false
 	}
-	return ingress.Annotations[AnnotationRegister] == "true"
+	if !r.classAllowed(obj) {
+		return false
+	}
+	if obj.GetLabels()[LabelRegister] == "true" {
+		return true
+	}
+	return !r.RequireRegisterLabel && obj.GetAnnotations()[AnnotationRegister] == "true"
 }
 
-// extractHosts gets the list of hostnames from the Ingress
-func (r *IngressReconciler) extractHosts(ingress *networkingv1.Ingress) []string {
-	// Check for override annotation
-	if ingress.Annotations != nil {
-		if hostsAnnotation, ok := ingress.Annotations[AnnotationHosts]; ok && hostsAnnotation != "" {
-			return parseCommaSeparated(hostsAnnotation)
+// extractHosts gets the list of hostnames from the object. In order of precedence:
+//   - AnnotationHosts, when set, replaces the hosts of the source, e.g. spec.rules, or
+//     with HostsModeMerge is added to them
+//   - AnnotationExtraHosts and listed, the hosts read from AnnotationHostsFromConfigMap,
+//     are added to those
+//   - AnnotationExcludeHosts removes hosts from the result whichever of these named them
+//
+// Short names are completed and AnnotationRegisterPair variants added before the
+// exclusion, so it sees the names that would be registered.
+func (r *IngressReconciler) extractHosts(obj client.Object, listed []string, mode HostsMode) []string {
+	// Check for override annotation, otherwise extract from the source, e.g. spec.rules
+	// of an Ingress, where a host commonly repeats across rules for different paths
+	hosts := r.src().hosts(obj)
+	if hostsAnnotation := obj.GetAnnotations()[AnnotationHosts]; hostsAnnotation != "" {
+		if mode == HostsModeMerge {
+			hosts = append(hosts, parseCommaSeparated(hostsAnnotation)...)
+		} else {
+			hosts = parseCommaSeparated(hostsAnnotation)
 		}
 	}
+	hosts = append(hosts, parseCommaSeparated(obj.GetAnnotations()[AnnotationExtraHosts])...)
+	hosts = append(hosts, listed...)
 
-	// Extract from spec.rules
-	var hosts []string
-	for _, rule := range ingress.Spec.Rules {
-		if rule.Host != "" {
-			hosts = append(hosts, rule.Host)
+	// Complete short names before deduplicating, so "grafana" and its FQDN collapse
+	if suffix := r.domainSuffix(obj); suffix != "" {
+		completed := make([]string, 0, len(hosts))
+		for _, h := range hosts {
+			if !strings.Contains(h, ".") {
+				h += "." + suffix
+			}
+			completed = append(completed, h)
 		}
+		hosts = completed
+	}
+	if prefixes, _ := pairPrefixes(obj); len(prefixes) > 0 {
+		hosts = withPairs(hosts, prefixes)
+	}
+	return uniqueHosts(excludeHosts(obj, hosts))
+}
+
+// domainSuffix returns the zone appended to short hostnames, without surrounding dots
+func (r *IngressReconciler) domainSuffix(obj client.Object) string {
+	suffix := r.settings().DefaultDomainSuffix
+	if value, ok := obj.GetAnnotations()[AnnotationDomainSuffix]; ok {
+		suffix = value
+	}
+	return strings.Trim(strings.TrimSpace(suffix), ".")
+}
+
+// overwriteAllowed reports whether records that already exist in Pi-hole may be replaced
+func (r *IngressReconciler) overwriteAllowed(obj client.Object, logger *slog.Logger) bool {
+	def := !r.settings().DisableOverwrite
+	value, ok := obj.GetAnnotations()[AnnotationOverwrite]
+	if !ok || value == "" {
+		return def
 	}
-	return hosts
+	allowed, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Warn("invalid annotation", "annotation", AnnotationOverwrite, "value", value, "error", err)
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+			"%s=%q is not a valid boolean; using default %t", AnnotationOverwrite, value, def)
+		return def
+	}
+	return allowed
 }
 
 // resolveTargetIP determines the target IP for DNS records
-func (r *IngressReconciler) resolveTargetIP(ingress *networkingv1.Ingress) string {
-	// Check for per-Ingress override
-	if ingress.Annotations != nil {
-		if ip, ok := ingress.Annotations[AnnotationTargetIP]; ok && ip != "" {
-			if isValidIPv4(ip) {
-				return ip
-			}
-			return "" // Invalid IP - return empty to signal error
+func (r *IngressReconciler) resolveTargetIP(obj client.Object) string {
+	// Check for per-object override
+	if ip := obj.GetAnnotations()[AnnotationTargetIP]; ip != "" {
+		if isValidIPv4(ip) {
+			return ip
 		}
+		return "" // Invalid IP - return empty to signal error
 	}
-	return r.DefaultTargetIP
+	if ip := r.statusTargetIP(obj); ip != "" {
+		return ip
+	}
+	return r.settings().DefaultTargetIP
 }
 
-// getManagedHosts returns the list of hosts currently managed for this Ingress
-func (r *IngressReconciler) getManagedHosts(ingress *networkingv1.Ingress) []string {
-	if ingress.Annotations == nil {
+// resolveTargetIPv6 determines the target for AAAA records; empty means none.
+// It reports false when the annotation holds an invalid address.
+func (r *IngressReconciler) resolveTargetIPv6(obj client.Object) (string, bool) {
+	if r.settings().DisableAAAA {
+		return "", true
+	}
+	if ip := obj.GetAnnotations()[AnnotationTargetIPv6]; ip != "" {
+		if isValidIPv6(ip) {
+			return ip, true
+		}
+		return "", false
+	}
+	return r.settings().DefaultTargetIPv6, true
+}
+
+// getManagedHosts returns the record keys currently managed for this object
+func (r *IngressReconciler) getManagedHosts(obj client.Object) []string {
+	managed := obj.GetAnnotations()[AnnotationManagedHosts]
+	if managed == "" {
 		return nil
 	}
-	managed, ok := ingress.Annotations[AnnotationManagedHosts]
-	if !ok || managed == "" {
+	return uniqueHosts(parseCommaSeparated(managed))
+}
+
+// repairManagedHosts rewrites a managed-hosts annotation holding duplicates, as written
+// by earlier versions, in its canonical form. obj is updated in place.
+func (r *IngressReconciler) repairManagedHosts(ctx context.Context, obj client.Object, logger *slog.Logger) error {
+	annotations := obj.GetAnnotations()
+	raw := annotations[AnnotationManagedHosts]
+	canonical := strings.Join(r.getManagedHosts(obj), ",")
+	if raw == canonical {
 		return nil
 	}
-	return parseCommaSeparated(managed)
+
+	logger.Info("repairing managed hosts annotation", "old", raw, "new", canonical)
+	if canonical == "" {
+		delete(annotations, AnnotationManagedHosts)
+	} else {
+		annotations[AnnotationManagedHosts] = canonical
+	}
+	obj.SetAnnotations(annotations)
+	return r.Update(ctx, obj)
 }
 
-// updateManagedHosts updates the managed-hosts annotation on the Ingress
-func (r *IngressReconciler) updateManagedHosts(ctx context.Context, ingress *networkingv1.Ingress, hosts []string) error {
+// updateManagedHosts updates the managed-hosts annotation on the object. Nothing is
+// written when the annotation already holds the value, so a steady-state reconcile
+// does not bump the resourceVersion and trigger watches, including its own.
+func (r *IngressReconciler) updateManagedHosts(ctx context.Context, obj client.Object, hosts []string) error {
 	// Get fresh copy to avoid conflicts
-	var fresh networkingv1.Ingress
-	if err := r.Get(ctx, client.ObjectKeyFromObject(ingress), &fresh); err != nil {
+	fresh := r.src().newObject()
+	if err := r.Get(ctx, client.ObjectKeyFromObject(obj), fresh); err != nil {
 		return err
 	}
 
-	if fresh.Annotations == nil {
-		fresh.Annotations = make(map[string]string)
+	value := strings.Join(uniqueHosts(hosts), ",")
+	annotations := fresh.GetAnnotations()
+	if current, ok := annotations[AnnotationManagedHosts]; current == value && ok == (value != "") {
+		return nil
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
 	}
 
-	if len(hosts) == 0 {
-		delete(fresh.Annotations, AnnotationManagedHosts)
+	if value == "" {
+		delete(annotations, AnnotationManagedHosts)
 	} else {
-		fresh.Annotations[AnnotationManagedHosts] = strings.Join(hosts, ",")
+		annotations[AnnotationManagedHosts] = value
 	}
+	fresh.SetAnnotations(annotations)
 
-	return r.Update(ctx, &fresh)
+	return r.Update(ctx, fresh)
+}
+
+// controllerName names the controller of the reconciler's source
+func (r *IngressReconciler) controllerName() string {
+	return strings.ToLower(r.src().kind())
 }
 
 // SetupWithManager sets up the controller with the Manager
 func (r *IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&networkingv1.Ingress{}).
-		Named("ingress").
+	if r.Backoff == nil {
+		r.Backoff = NewBackoff(DefaultBackoffBase, DefaultBackoffMax)
+	}
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("pihole-ingress-operator")
+	}
+	r.resync = make(chan event.GenericEvent)
+
+	r.Heartbeats.Register(r.controllerName())
+
+	b := ctrl.NewControllerManagedBy(mgr).Named(r.controllerName()).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter:             r.RateLimit.newRateLimiter(),
+		})
+	if r.DisableFinalizers {
+		// Without finalizers the delete event carries the last record of the managed hosts
+		b = b.Watches(r.src().newObject(), &tombstoneHandler{tombstones: &r.tombstones})
+	} else {
+		b = b.For(r.src().newObject())
+	}
+	// Objects requiring ready endpoints are found through their backend Services
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), r.src().newObject(), backendServiceIndex,
+		r.backendServices); err != nil {
+		return fmt.Errorf("indexing backend services: %w", err)
+	}
+	// Objects sourcing hosts from a ConfigMap are found through it
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), r.src().newObject(), hostsConfigMapIndex,
+		hostsConfigMaps); err != nil {
+		return fmt.Errorf("indexing hosts configmaps: %w", err)
+	}
+	return b.
+		Watches(&discoveryv1.EndpointSlice{}, handler.EnqueueRequestsFromMapFunc(r.objectsForEndpointSlice),
+			builder.WithPredicates(endpointReadinessChanged())).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.objectsForConfigMap),
+			builder.OnlyMetadata).
+		WatchesRawSource(source.Channel(r.resync, &handler.EnqueueRequestForObject{})).
 		Complete(r)
 }
 
+// recordKeys returns the keys of the given records
+func recordKeys(records []pihole.DNSRecord) []string {
+	keys := make([]string, 0, len(records))
+	for _, r := range records {
+		keys = append(keys, r.Key())
+	}
+	return keys
+}
+
+// recordIPs maps the keys of the given records to their IPs
+func recordIPs(records []pihole.DNSRecord) map[string]string {
+	ips := make(map[string]string, len(records))
+	for _, r := range records {
+		ips[r.Key()] = r.IP
+	}
+	return ips
+}
+
+// updateKeys returns the keys of the given updates
+func updateKeys(updates []RecordUpdate) []string {
+	keys := make([]string, 0, len(updates))
+	for _, u := range updates {
+		keys = append(keys, u.Key())
+	}
+	return keys
+}
+
+// keysOfType returns the record keys of the given type
+func keysOfType(keys []string, recordType string) []string {
+	var matching []string
+	for _, key := range keys {
+		if _, t := pihole.ParseRecordKey(key); t == recordType {
+			matching = append(matching, key)
+		}
+	}
+	return matching
+}
+
+// deleteRecordKey deletes the record identified by a managed-hosts entry under its domain lock
+func (r *IngressReconciler) deleteRecordKey(ctx context.Context, key string) error {
+	domain, recordType := pihole.ParseRecordKey(key)
+	defer r.Locks.Lock(domain)()
+	return r.PiholeClient.DeleteRecord(ctx, domain, recordType)
+}
+
+// withoutHosts returns hosts with every entry in exclude removed
+func withoutHosts(hosts, exclude []string) []string {
+	if len(exclude) == 0 {
+		return hosts
+	}
+	skip := make(map[string]bool, len(exclude))
+	for _, h := range exclude {
+		skip[h] = true
+	}
+	kept := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		if !skip[h] {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
+
+// uniqueHosts returns hosts without repeats, keeping the first occurrence of each
+func uniqueHosts(hosts []string) []string {
+	seen := make(map[string]bool, len(hosts))
+	var unique []string
+	for _, h := range hosts {
+		if !seen[h] {
+			seen[h] = true
+			unique = append(unique, h)
+		}
+	}
+	return unique
+}
+
 // parseCommaSeparated parses a comma-separated string into a slice of trimmed strings
 func parseCommaSeparated(s string) []string {
 	parts := strings.Split(s, ",")
@@ -307,3 +1361,9 @@ func isValidIPv4(ip string) bool {
 	}
 	return parsed.To4() != nil
 }
+
+// isValidIPv6 checks if the given string is a valid IPv6 address
+func isValidIPv6(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() == nil
+}
@@ -1,12 +1,23 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
 )
 
 func TestHasRegistrationAnnotation(t *testing.T) {
@@ -14,9 +25,11 @@ func TestHasRegistrationAnnotation(t *testing.T) {
 	r := &IngressReconciler{Logger: logger}
 
 	tests := []struct {
-		name        string
-		annotations map[string]string
-		want        bool
+		name         string
+		annotations  map[string]string
+		labels       map[string]string
+		requireLabel bool
+		want         bool
 	}{
 		{
 			name:        "no annotations",
@@ -49,13 +62,32 @@ func TestHasRegistrationAnnotation(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name:   "label set to true",
+			labels: map[string]string{LabelRegister: "true"},
+			want:   true,
+		},
+		{
+			name:         "label required",
+			labels:       map[string]string{LabelRegister: "true"},
+			requireLabel: true,
+			want:         true,
+		},
+		{
+			name:         "annotation without required label",
+			annotations:  map[string]string{AnnotationRegister: "true"},
+			requireLabel: true,
+			want:         false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			r.RequireRegisterLabel = tt.requireLabel
 			ingress := &networkingv1.Ingress{
 				ObjectMeta: metav1.ObjectMeta{
 					Annotations: tt.annotations,
+					Labels:      tt.labels,
 				},
 			}
 			got := r.hasRegistrationAnnotation(ingress)
@@ -74,6 +106,7 @@ func TestExtractHosts(t *testing.T) {
 		name        string
 		annotations map[string]string
 		rules       []networkingv1.IngressRule
+		mode        HostsMode
 		want        []string
 	}{
 		{
@@ -115,6 +148,24 @@ func TestExtractHosts(t *testing.T) {
 			},
 			want: []string{"host1.local", "host2.local"},
 		},
+		{
+			name: "host repeated across rules",
+			rules: []networkingv1.IngressRule{
+				{Host: "app.local"},
+				{Host: "api.local"},
+				{Host: "app.local"},
+				{Host: "app.local"},
+				{Host: "api.local"},
+			},
+			want: []string{"app.local", "api.local"},
+		},
+		{
+			name: "annotation with duplicates",
+			annotations: map[string]string{
+				AnnotationHosts: "b.local,a.local,b.local, a.local ,b.local",
+			},
+			want: []string{"b.local", "a.local"},
+		},
 		{
 			name: "empty annotation falls back to rules",
 			annotations: map[string]string{
@@ -125,6 +176,48 @@ func TestExtractHosts(t *testing.T) {
 			},
 			want: []string{"app.local"},
 		},
+		{
+			name: "replace mode overrides rules",
+			annotations: map[string]string{
+				AnnotationHosts: "custom.local",
+			},
+			rules: []networkingv1.IngressRule{
+				{Host: "app.local"},
+			},
+			mode: HostsModeReplace,
+			want: []string{"custom.local"},
+		},
+		{
+			name: "merge mode adds annotation to rules",
+			annotations: map[string]string{
+				AnnotationHosts: "custom.local,override.local",
+			},
+			rules: []networkingv1.IngressRule{
+				{Host: "app.local"},
+			},
+			mode: HostsModeMerge,
+			want: []string{"app.local", "custom.local", "override.local"},
+		},
+		{
+			name: "merge mode deduplicates",
+			annotations: map[string]string{
+				AnnotationHosts: "api.local, app.local",
+			},
+			rules: []networkingv1.IngressRule{
+				{Host: "app.local"},
+				{Host: "api.local"},
+			},
+			mode: HostsModeMerge,
+			want: []string{"app.local", "api.local"},
+		},
+		{
+			name: "merge mode without annotation",
+			rules: []networkingv1.IngressRule{
+				{Host: "app.local"},
+			},
+			mode: HostsModeMerge,
+			want: []string{"app.local"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -137,7 +230,7 @@ func TestExtractHosts(t *testing.T) {
 					Rules: tt.rules,
 				},
 			}
-			got := r.extractHosts(ingress)
+			got := r.extractHosts(ingress, nil, tt.mode)
 			if !slicesEqual(got, tt.want) {
 				t.Errorf("extractHosts() = %v, want %v", got, tt.want)
 			}
@@ -145,6 +238,93 @@ func TestExtractHosts(t *testing.T) {
 	}
 }
 
+func TestExtractHostsDomainSuffix(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultSuffix string
+		annotations   map[string]string
+		rules         []string
+		want          []string
+	}{
+		{name: "no suffix", rules: []string{"grafana"}, want: []string{"grafana"}},
+		{name: "default suffix", defaultSuffix: "home.lan", rules: []string{"grafana", "app.local"}, want: []string{"grafana.home.lan", "app.local"}},
+		{name: "annotation overrides default", defaultSuffix: "home.lan", annotations: map[string]string{AnnotationDomainSuffix: "lab.lan"}, rules: []string{"grafana"}, want: []string{"grafana.lab.lan"}},
+		{name: "empty annotation disables default", defaultSuffix: "home.lan", annotations: map[string]string{AnnotationDomainSuffix: ""}, rules: []string{"grafana"}, want: []string{"grafana"}},
+		{name: "surrounding dots trimmed", annotations: map[string]string{AnnotationDomainSuffix: ".home.lan."}, rules: []string{"grafana"}, want: []string{"grafana.home.lan"}},
+		{name: "applies to hosts annotation", annotations: map[string]string{AnnotationDomainSuffix: "home.lan", AnnotationHosts: "grafana,loki"}, want: []string{"grafana.home.lan", "loki.home.lan"}},
+		{name: "short and full name collapse", defaultSuffix: "home.lan", rules: []string{"grafana", "grafana.home.lan"}, want: []string{"grafana.home.lan"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &IngressReconciler{DefaultDomainSuffix: tt.defaultSuffix}
+			ingress := testIngress("app", tt.annotations, tt.rules...)
+			if got := r.extractHosts(ingress, nil, HostsModeReplace); !slicesEqual(got, tt.want) {
+				t.Errorf("extractHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractHostsExtraHosts(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		rules       []string
+		mode        HostsMode
+		want        []string
+	}{
+		{name: "added to spec hosts", annotations: map[string]string{AnnotationExtraHosts: "alias.home.lan"}, rules: []string{"app.home.lan"}, want: []string{"app.home.lan", "alias.home.lan"}},
+		{name: "added to hosts override", annotations: map[string]string{AnnotationHosts: "api.home.lan", AnnotationExtraHosts: "alias.home.lan"}, rules: []string{"app.home.lan"}, want: []string{"api.home.lan", "alias.home.lan"}},
+		{name: "duplicates of spec hosts collapse", annotations: map[string]string{AnnotationExtraHosts: "app.home.lan, alias.home.lan,alias.home.lan"}, rules: []string{"app.home.lan"}, want: []string{"app.home.lan", "alias.home.lan"}},
+		{name: "short names completed", annotations: map[string]string{AnnotationExtraHosts: "alias", AnnotationDomainSuffix: "home.lan"}, rules: []string{"app.home.lan"}, want: []string{"app.home.lan", "alias.home.lan"}},
+		{name: "exclusion wins over extra hosts", annotations: map[string]string{AnnotationExtraHosts: "alias.home.lan,www.example.com", AnnotationExcludeHosts: "alias.home.lan,*.example.com"}, rules: []string{"app.home.lan"}, want: []string{"app.home.lan"}},
+		{name: "added to merged hosts", annotations: map[string]string{AnnotationHosts: "api.home.lan", AnnotationExtraHosts: "alias.home.lan"}, rules: []string{"app.home.lan"}, mode: HostsModeMerge, want: []string{"app.home.lan", "api.home.lan", "alias.home.lan"}},
+		{name: "extra hosts alone", annotations: map[string]string{AnnotationExtraHosts: "alias.home.lan"}, want: []string{"alias.home.lan"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &IngressReconciler{}
+			ingress := testIngress("app", tt.annotations, tt.rules...)
+			if got := r.extractHosts(ingress, nil, tt.mode); !slicesEqual(got, tt.want) {
+				t.Errorf("extractHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileExtraHosts(t *testing.T) {
+	ph := newFakePiholeClient()
+	ingress := testIngress("app", map[string]string{
+		AnnotationRegister:   "true",
+		AnnotationExtraHosts: "alias.home.lan",
+	}, "app.home.lan")
+	r := newTestReconciler(ph, ingress)
+
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("app.home.lan") == "" || ph.ip("alias.home.lan") == "" {
+		t.Fatalf("spec host and alias not both registered: %v", ph.records)
+	}
+	if managed := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; managed != "app.home.lan,alias.home.lan" {
+		t.Errorf("managed hosts = %q, want the alias tracked", managed)
+	}
+
+	// Removing the alias prunes only its record
+	current := getIngress(t, r, "default", "app")
+	delete(current.Annotations, AnnotationExtraHosts)
+	if err := r.Update(t.Context(), current); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("alias.home.lan") != "" {
+		t.Errorf("removed alias kept: %v", ph.records)
+	}
+	if ph.ip("app.home.lan") == "" {
+		t.Errorf("spec host pruned: %v", ph.records)
+	}
+}
+
 func TestResolveTargetIP(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	r := &IngressReconciler{
@@ -207,6 +387,33 @@ func TestResolveTargetIP(t *testing.T) {
 	}
 }
 
+func TestResolveTargetIPv6(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultIP   string
+		annotations map[string]string
+		want        string
+		wantOK      bool
+	}{
+		{name: "no default", want: "", wantOK: true},
+		{name: "use default", defaultIP: "fd00::1", want: "fd00::1", wantOK: true},
+		{name: "override with valid IP", defaultIP: "fd00::1", annotations: map[string]string{AnnotationTargetIPv6: "fd00::2"}, want: "fd00::2", wantOK: true},
+		{name: "IPv4 is invalid", annotations: map[string]string{AnnotationTargetIPv6: "10.0.0.1"}, want: "", wantOK: false},
+		{name: "garbage is invalid", defaultIP: "fd00::1", annotations: map[string]string{AnnotationTargetIPv6: "bogus"}, want: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &IngressReconciler{DefaultTargetIPv6: tt.defaultIP}
+			ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			got, ok := r.resolveTargetIPv6(ingress)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("resolveTargetIPv6() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
 func TestGetManagedHosts(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	r := &IngressReconciler{Logger: logger}
@@ -242,6 +449,13 @@ func TestGetManagedHosts(t *testing.T) {
 			},
 			want: nil,
 		},
+		{
+			name: "duplicated hosts",
+			annotations: map[string]string{
+				AnnotationManagedHosts: "app.local,app.local,api.local,app.local,api.local",
+			},
+			want: []string{"app.local", "api.local"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -319,3 +533,394 @@ func slicesEqual(a, b []string) bool {
 	}
 	return true
 }
+
+// newTestReconciler builds an IngressReconciler backed by a fake Kubernetes client
+// and an in-memory Pi-hole
+func newTestReconciler(ph *fakePiholeClient, objs ...client.Object) *IngressReconciler {
+	return &IngressReconciler{
+		Client:          fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objs...).Build(),
+		Scheme:          scheme.Scheme,
+		PiholeClient:    ph,
+		DefaultTargetIP: "192.168.1.100",
+		Logger:          slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		Recorder:        record.NewFakeRecorder(100),
+		Backoff:         NewBackoff(DefaultBackoffBase, DefaultBackoffMax),
+	}
+}
+
+// reconcileIngress runs one reconcile for the named Ingress
+func reconcileIngress(t *testing.T, r *IngressReconciler, namespace, name string) ctrl.Result {
+	t.Helper()
+	res, err := r.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: namespace, Name: name},
+	})
+	if err != nil {
+		t.Fatalf("Reconcile() unexpected error: %v", err)
+	}
+	return res
+}
+
+// getIngress fetches the current state of the named Ingress
+func getIngress(t *testing.T, r *IngressReconciler, namespace, name string) *networkingv1.Ingress {
+	t.Helper()
+	var ingress networkingv1.Ingress
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, &ingress); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	return &ingress
+}
+
+func TestReconcileOverwrite(t *testing.T) {
+	tests := []struct {
+		name             string
+		annotation       string
+		disableOverwrite bool
+		wantIP           string
+		wantManaged      string
+	}{
+		{name: "default overwrites and adopts", wantIP: "192.168.1.100", wantManaged: "app.local,nas.local"},
+		{name: "annotation refuses", annotation: "false", wantIP: "192.168.1.20", wantManaged: "app.local"},
+		{name: "global default refuses", disableOverwrite: true, wantIP: "192.168.1.20", wantManaged: "app.local"},
+		{name: "annotation overrides global default", annotation: "true", disableOverwrite: true, wantIP: "192.168.1.100", wantManaged: "app.local,nas.local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{AnnotationRegister: "true"}
+			if tt.annotation != "" {
+				annotations[AnnotationOverwrite] = tt.annotation
+			}
+			ph := newFakePiholeClient(pihole.DNSRecord{Domain: "nas.local", IP: "192.168.1.20"})
+			r := newTestReconciler(ph, testIngress("app", annotations, "app.local", "nas.local"))
+			r.DisableOverwrite = tt.disableOverwrite
+
+			reconcileIngress(t, r, "default", "app")
+
+			if got := ph.ip("nas.local"); got != tt.wantIP {
+				t.Errorf("nas.local ip = %q, want %q", got, tt.wantIP)
+			}
+			if ph.ip("app.local") != "192.168.1.100" {
+				t.Error("app.local should be created regardless of overwrite")
+			}
+			current := getIngress(t, r, "default", "app")
+			if got := current.Annotations[AnnotationManagedHosts]; got != tt.wantManaged {
+				t.Errorf("managed hosts = %q, want %q", got, tt.wantManaged)
+			}
+		})
+	}
+}
+
+func TestReconcileDuplicateHosts(t *testing.T) {
+	ph := newFakePiholeClient()
+	ingress := testIngress("app", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationManagedHosts: "old.local,app.local,old.local,app.local,old.local",
+	}, "app.local", "app.local", "api.local", "app.local", "api.local")
+	ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{"app.local", "api.local"}}}
+	ph.records["old.local"] = "192.168.1.100"
+	r := newTestReconciler(ph, ingress)
+
+	reconcileIngress(t, r, "default", "app")
+
+	want := []string{"list", "create api.local", "create app.local", "delete old.local"}
+	if !slicesEqual(ph.calls, want) {
+		t.Errorf("pihole calls = %v, want %v", ph.calls, want)
+	}
+	current := getIngress(t, r, "default", "app")
+	if got := current.Annotations[AnnotationManagedHosts]; got != "app.local,api.local" {
+		t.Errorf("managed hosts = %q, want %q", got, "app.local,api.local")
+	}
+}
+
+func TestReconcileRepairsManagedHosts(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+	}{
+		{
+			name:        "registered without hosts",
+			annotations: map[string]string{AnnotationRegister: "true", AnnotationManagedHosts: "a.local,a.local,b.local,a.local"},
+			want:        "a.local,b.local",
+		},
+		{
+			name:        "invalid target ip",
+			annotations: map[string]string{AnnotationRegister: "true", AnnotationTargetIP: "bad", AnnotationManagedHosts: "a.local, a.local ,,a.local"},
+			want:        "a.local",
+		},
+		{
+			name:        "already canonical",
+			annotations: map[string]string{AnnotationRegister: "true", AnnotationTargetIP: "bad", AnnotationManagedHosts: "a.local,b.local"},
+			want:        "a.local,b.local",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ph := newFakePiholeClient()
+			r := newTestReconciler(ph, testIngress("app", tt.annotations))
+			r.DisableFinalizers = true
+
+			reconcileIngress(t, r, "default", "app")
+
+			current := getIngress(t, r, "default", "app")
+			if got := current.Annotations[AnnotationManagedHosts]; got != tt.want {
+				t.Errorf("managed hosts = %q, want %q", got, tt.want)
+			}
+			if len(ph.calls) != 0 {
+				t.Errorf("pihole calls = %v, want none", ph.calls)
+			}
+		})
+	}
+}
+
+// countingClient counts the Update calls reaching the API server
+type countingClient struct {
+	client.Client
+	updates int
+}
+
+func (c *countingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.updates++
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// conflictingClient fails every Update as if the object had changed underneath
+type conflictingClient struct {
+	client.Client
+}
+
+func (c *conflictingClient) Update(context.Context, client.Object, ...client.UpdateOption) error {
+	return fmt.Errorf("the object has been modified; please apply your changes to the latest version")
+}
+
+func TestReconcileConfiguredRequeueIntervals(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"))
+	r.DisableFinalizers = true
+	r.Backoff = NewBackoff(45*time.Second, time.Hour)
+	r.UpdateConflictRequeue = 3 * time.Second
+
+	ph.err = fmt.Errorf("connection refused")
+	if res := reconcileIngress(t, r, "default", "app"); res.RequeueAfter != 45*time.Second {
+		t.Errorf("RequeueAfter after an API error = %v, want the configured 45s", res.RequeueAfter)
+	}
+
+	ph.err = nil
+	r.Client = &conflictingClient{Client: r.Client}
+	if res := reconcileIngress(t, r, "default", "app"); res.RequeueAfter != 3*time.Second {
+		t.Errorf("RequeueAfter after a failed annotation update = %v, want the configured 3s", res.RequeueAfter)
+	}
+}
+
+func TestReconcileSteadyStateSkipsUpdate(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local", "api.local"))
+	counting := &countingClient{Client: r.Client}
+	r.Client = counting
+
+	reconcileIngress(t, r, "default", "app")
+	if counting.updates == 0 {
+		t.Fatal("first reconcile wrote nothing")
+	}
+
+	counting.updates = 0
+	reconcileIngress(t, r, "default", "app")
+	if counting.updates != 0 {
+		t.Errorf("steady-state reconcile made %d updates, want 0", counting.updates)
+	}
+}
+
+func TestReconcileDualStack(t *testing.T) {
+	ctx := context.Background()
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{
+		AnnotationRegister:   "true",
+		AnnotationTargetIPv6: "fd00::10",
+	}, "app.local"))
+	sink := &memoryAuditSink{}
+	r.Audit = sink
+
+	setIPv6 := func(value string) {
+		t.Helper()
+		current := getIngress(t, r, "default", "app")
+		if value == "" {
+			delete(current.Annotations, AnnotationTargetIPv6)
+		} else {
+			current.Annotations[AnnotationTargetIPv6] = value
+		}
+		if err := r.Update(ctx, current); err != nil {
+			t.Fatalf("Update() unexpected error: %v", err)
+		}
+	}
+	check := func(step, wantA, wantAAAA, wantManaged string) {
+		t.Helper()
+		if got := ph.ip("app.local"); got != wantA {
+			t.Errorf("%s: A = %q, want %q", step, got, wantA)
+		}
+		if got := ph.ip("app.local/AAAA"); got != wantAAAA {
+			t.Errorf("%s: AAAA = %q, want %q", step, got, wantAAAA)
+		}
+		if got := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; got != wantManaged {
+			t.Errorf("%s: managed hosts = %q, want %q", step, got, wantManaged)
+		}
+	}
+
+	reconcileIngress(t, r, "default", "app")
+	check("created", "192.168.1.100", "fd00::10", "app.local,app.local/AAAA")
+
+	setIPv6("bogus")
+	reconcileIngress(t, r, "default", "app")
+	check("invalid ipv6", "192.168.1.100", "fd00::10", "app.local,app.local/AAAA")
+
+	setIPv6("fd00::20")
+	reconcileIngress(t, r, "default", "app")
+	check("updated ipv6", "192.168.1.100", "fd00::20", "app.local,app.local/AAAA")
+
+	setIPv6("")
+	reconcileIngress(t, r, "default", "app")
+	check("removed ipv6", "192.168.1.100", "", "app.local")
+
+	setIPv6("fd00::10")
+	reconcileIngress(t, r, "default", "app")
+	if err := r.Delete(ctx, getIngress(t, r, "default", "app")); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if len(ph.records) != 0 {
+		t.Errorf("records left after delete: %v", ph.records)
+	}
+
+	// The cleanup audits the record type apart from the domain, as creates do
+	var deletes []string
+	for _, e := range sink.entries[len(sink.entries)-2:] {
+		deletes = append(deletes, e.Action+" "+e.Domain+" "+e.Type)
+	}
+	if want := []string{"delete app.local A", "delete app.local AAAA"}; !slicesEqual(deletes, want) {
+		t.Errorf("cleanup audit = %v, want %v", deletes, want)
+	}
+}
+
+func TestReconcileIPv6LeavesForeignRecords(t *testing.T) {
+	// Someone else's AAAA record must not be touched when only A is managed
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "app.local", IP: "fd00::99"})
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"))
+
+	reconcileIngress(t, r, "default", "app")
+	if err := r.Delete(context.Background(), getIngress(t, r, "default", "app")); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "app")
+
+	if ph.ip("app.local") != "" {
+		t.Error("A record not cleaned up")
+	}
+	if got := ph.ip("app.local/AAAA"); got != "fd00::99" {
+		t.Errorf("foreign AAAA = %q, want it untouched", got)
+	}
+}
+
+func TestReconcileIPv6OnlyDefault(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"))
+	r.DefaultTargetIP = ""
+	r.DefaultTargetIPv6 = "fd00::10"
+
+	reconcileIngress(t, r, "default", "app")
+	if got := ph.ip("app.local"); got != "" {
+		t.Errorf("A = %q, want none", got)
+	}
+	if got := ph.ip("app.local/AAAA"); got != "fd00::10" {
+		t.Errorf("AAAA = %q, want %q", got, "fd00::10")
+	}
+	if got := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; got != "app.local/AAAA" {
+		t.Errorf("managed hosts = %q, want %q", got, "app.local/AAAA")
+	}
+}
+
+func TestReconcileDisableAAAA(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{
+		AnnotationRegister:   "true",
+		AnnotationTargetIPv6: "fd00::20",
+	}, "app.local"))
+	r.DefaultTargetIPv6 = "fd00::10"
+
+	reconcileIngress(t, r, "default", "app")
+	if got := ph.ip("app.local/AAAA"); got != "fd00::20" {
+		t.Fatalf("AAAA = %q, want %q", got, "fd00::20")
+	}
+
+	// Turning AAAA off removes the records created before and ignores both targets
+	r.DisableAAAA = true
+	reconcileIngress(t, r, "default", "app")
+	if got := ph.ip("app.local"); got != "192.168.1.100" {
+		t.Errorf("A = %q, want %q", got, "192.168.1.100")
+	}
+	if got := ph.ip("app.local/AAAA"); got != "" {
+		t.Errorf("AAAA = %q, want none", got)
+	}
+	if got := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; got != "app.local" {
+		t.Errorf("managed hosts = %q, want %q", got, "app.local")
+	}
+}
+
+func TestReconcileDomainSuffixChange(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("grafana", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationDomainSuffix: "home.lan",
+	}, "grafana"))
+
+	reconcileIngress(t, r, "default", "grafana")
+	if ph.ip("grafana.home.lan") == "" {
+		t.Fatalf("grafana.home.lan not created: %v", ph.records)
+	}
+
+	current := getIngress(t, r, "default", "grafana")
+	current.Annotations[AnnotationDomainSuffix] = "lab.lan"
+	if err := r.Update(context.Background(), current); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "grafana")
+
+	if ph.ip("grafana.home.lan") != "" {
+		t.Error("old FQDN not removed after suffix change")
+	}
+	if ph.ip("grafana.lab.lan") == "" {
+		t.Error("new FQDN not created after suffix change")
+	}
+	if got := getIngress(t, r, "default", "grafana").Annotations[AnnotationManagedHosts]; got != "grafana.lab.lan" {
+		t.Errorf("managed hosts = %q, want grafana.lab.lan", got)
+	}
+}
+
+func TestReconcileSharedListing(t *testing.T) {
+	// A burst of reconciles shares one Pi-hole listing, kept current with their writes
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		testIngress("a", map[string]string{AnnotationRegister: "true"}, "a.local"),
+		testIngress("b", map[string]string{AnnotationRegister: "true"}, "b.local"),
+		testIngress("c", map[string]string{AnnotationRegister: "true"}, "a.local"),
+	)
+	listing := pihole.NewListingCache(ph)
+	listing.MaxAge = time.Minute
+	r.PiholeClient = listing
+
+	for _, name := range []string{"a", "b", "c"} {
+		reconcileIngress(t, r, "default", name)
+	}
+	lists := 0
+	for _, call := range ph.calls {
+		if call == "list" {
+			lists++
+		}
+	}
+	if lists != 1 {
+		t.Errorf("%d listings for 3 reconciles, want 1", lists)
+	}
+	// c saw the record a created, so it was not written twice
+	if want := []string{"list", "create a.local", "create b.local"}; !slicesEqual(ph.calls, want) {
+		t.Errorf("calls = %v, want %v", ph.calls, want)
+	}
+}
@@ -0,0 +1,14 @@
+package controller
+
+import "path"
+
+// namespaceExcluded reports whether a namespace matches ExcludeNamespaces, by exact name
+// or by a glob such as tenant-*
+func (r *IngressReconciler) namespaceExcluded(namespace string) bool {
+	for _, pattern := range r.settings().ExcludeNamespaces {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestNamespaceExcluded(t *testing.T) {
+	r := &IngressReconciler{ExcludeNamespaces: []string{"kube-system", "tenant-*"}}
+	tests := map[string]bool{
+		"kube-system":   true,
+		"tenant-a":      true,
+		"tenant-":       true,
+		"kube-public":   false,
+		"tenants":       false,
+		"default":       false,
+		"my-tenant-abc": false,
+	}
+	for namespace, want := range tests {
+		if got := r.namespaceExcluded(namespace); got != want {
+			t.Errorf("namespaceExcluded(%q) = %v, want %v", namespace, got, want)
+		}
+	}
+}
+
+func TestReconcileExcludedNamespace(t *testing.T) {
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "old.local", IP: "192.168.1.100"})
+	// Registered before its namespace was excluded
+	old := testIngress("old", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationManagedHosts: "old.local",
+	}, "old.local")
+	controllerutil.AddFinalizer(old, FinalizerName)
+	r := newTestReconciler(ph, old, testIngress("new", map[string]string{AnnotationRegister: "true"}, "new.local"))
+	r.ExcludeNamespaces = []string{"def*"}
+
+	reconcileIngress(t, r, "default", "old")
+	reconcileIngress(t, r, "default", "new")
+
+	if ph.ip("new.local") != "" {
+		t.Error("annotated Ingress in an excluded namespace registered")
+	}
+	if ph.ip("old.local") != "" {
+		t.Error("records of an Ingress in a newly excluded namespace not cleaned up")
+	}
+	if ingress := getIngress(t, r, "default", "new"); len(ingress.Finalizers) != 0 {
+		t.Errorf("finalizers = %v on an Ingress in an excluded namespace, want none", ingress.Finalizers)
+	}
+}
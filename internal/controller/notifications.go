@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/notify"
+)
+
+// planSummary converts the applied part of a plan into a notification summary
+func planSummary(owner string, plan Plan) notify.Summary {
+	summary := notify.Summary{Owner: owner}
+	for _, u := range plan.Updates {
+		summary.Changes = append(summary.Changes, notify.Change{Action: audit.ActionUpdate, Domain: u.Domain, OldIP: u.OldIP, NewIP: u.NewIP})
+	}
+	for _, c := range plan.Creates {
+		summary.Changes = append(summary.Changes, notify.Change{Action: audit.ActionCreate, Domain: c.Domain, NewIP: c.IP})
+	}
+	for _, d := range plan.Deletes {
+		summary.Changes = append(summary.Changes, notify.Change{Action: audit.ActionDelete, Domain: d.Domain, OldIP: d.IP})
+	}
+	return summary
+}
+
+// deletionSummary builds a notification summary for deleted hosts
+func deletionSummary(owner string, hosts []string) notify.Summary {
+	summary := notify.Summary{Owner: owner}
+	for _, h := range hosts {
+		summary.Changes = append(summary.Changes, notify.Change{Action: audit.ActionDelete, Domain: h})
+	}
+	return summary
+}
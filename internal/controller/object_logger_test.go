@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// logLines parses the JSON lines written to buf by message, keeping the last of each
+func logLines(t *testing.T, buf *bytes.Buffer) map[string]map[string]any {
+	t.Helper()
+	lines := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		lines[entry["msg"].(string)] = entry
+	}
+	buf.Reset()
+	return lines
+}
+
+func TestReconcileLogsObjectIdentity(t *testing.T) {
+	ctx := context.Background()
+	ph := newFakePiholeClient()
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local")
+	ingress.UID = types.UID("first-uid")
+	ingress.Generation = 3
+	r := newTestReconciler(ph, ingress)
+	var buf bytes.Buffer
+	r.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	assertIdentity := func(lines map[string]map[string]any, msg, uid string) {
+		t.Helper()
+		entry, ok := lines[msg]
+		if !ok {
+			t.Fatalf("no %q line logged", msg)
+		}
+		if entry["uid"] != uid {
+			t.Errorf("%q uid = %v, want %s", msg, entry["uid"], uid)
+		}
+		if entry["generation"] == nil || entry["resource_version"] == nil || entry["resource_version"] == "" {
+			t.Errorf("%q lacks generation or resource_version: %v", msg, entry)
+		}
+	}
+
+	reconcileIngress(t, r, "default", "app")
+	lines := logLines(t, &buf)
+	for _, msg := range []string{"change plan computed", "dns record created", "sync complete"} {
+		assertIdentity(lines, msg, "first-uid")
+	}
+	if got := lines["sync complete"]["generation"]; got != 3.0 {
+		t.Errorf("generation = %v, want 3", got)
+	}
+	if _, ok := lines["reconcile started"]["uid"]; ok {
+		t.Error("the identity is only known once the object has been read")
+	}
+
+	// The deletion path carries the identity of the deleted object
+	if err := r.Delete(ctx, getIngress(t, r, "default", "app")); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	assertIdentity(logLines(t, &buf), "dns record deleted", "first-uid")
+
+	// An object recreated under the same name is told apart by its UID
+	recreated := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local")
+	recreated.UID = types.UID("second-uid")
+	if err := r.Create(ctx, recreated); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	assertIdentity(logLines(t, &buf), "sync complete", "second-uid")
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PassResult summarizes a single reconciliation pass over every object of a kind
+type PassResult struct {
+	Kind string
+
+	// Reconciled counts the objects whose records were brought in line, and Pending
+	// those left for a later pass, e.g. because they are not ready yet
+	Reconciled int
+	Pending    int
+
+	// Failed maps the objects that could not be reconciled to their error
+	Failed map[types.NamespacedName]error
+}
+
+// String describes the result on one line, e.g. "Ingress: 3 reconciled, 1 pending, 0 failed"
+func (p PassResult) String() string {
+	return fmt.Sprintf("%s: %d reconciled, %d pending, %d failed", p.Kind, p.Reconciled, p.Pending, len(p.Failed))
+}
+
+// ReconcileAll reconciles every object the operator manages once, calling Reconcile
+// directly rather than through the controller's watches, and reports the outcome. A
+// Pi-hole error counts as a failure even though Reconcile only schedules a retry for
+// it. It returns an error only when the objects cannot be listed, and must not run
+// alongside the controller.
+func (r *IngressReconciler) ReconcileAll(ctx context.Context) (PassResult, error) {
+	if r.Backoff == nil {
+		r.Backoff = NewBackoff(DefaultBackoffBase, DefaultBackoffMax)
+	}
+	result := PassResult{Kind: r.src().kind(), Failed: map[types.NamespacedName]error{}}
+	r.passErrors = result.Failed
+	defer func() { r.passErrors = nil }()
+
+	list := r.src().newList()
+	if err := r.List(ctx, list); err != nil {
+		return result, fmt.Errorf("listing %s objects: %w", strings.ToLower(result.Kind), err)
+	}
+	for _, obj := range r.src().items(list) {
+		if !r.isManaged(obj) {
+			continue
+		}
+		key := client.ObjectKeyFromObject(obj)
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		switch {
+		case err != nil:
+			result.Failed[key] = err
+		case result.Failed[key] != nil:
+		case !res.IsZero():
+			result.Pending++
+		default:
+			result.Reconciled++
+		}
+	}
+	return result, nil
+}
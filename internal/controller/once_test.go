@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestReconcileAll(t *testing.T) {
+	ctx := context.Background()
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		testIngress("a", map[string]string{AnnotationRegister: "true"}, "a.local"),
+		testIngress("b", map[string]string{AnnotationRegister: "true"}, "b.local"),
+		testIngress("unrelated", nil, "unrelated.local"),
+	)
+
+	result, err := r.ReconcileAll(ctx)
+	if err != nil {
+		t.Fatalf("ReconcileAll() unexpected error: %v", err)
+	}
+	if result.Reconciled != 2 || result.Pending != 0 || len(result.Failed) != 0 {
+		t.Errorf("ReconcileAll() = %s, want 2 reconciled", result)
+	}
+	if len(ph.records) != 2 {
+		t.Errorf("records = %v, want a.local and b.local", ph.records)
+	}
+
+	// A Pi-hole error is a failure, though Reconcile only schedules a retry for it
+	if err := r.Create(ctx, testIngress("c", map[string]string{AnnotationRegister: "true"}, "c.local")); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	ph.err = &pihole.APIError{StatusCode: 500, Message: "down"}
+	result, err = r.ReconcileAll(ctx)
+	if err != nil {
+		t.Fatalf("ReconcileAll() unexpected error: %v", err)
+	}
+	if result.Reconciled != 2 || len(result.Failed) != 1 || result.Failed[types.NamespacedName{Namespace: "default", Name: "c"}] == nil {
+		t.Errorf("ReconcileAll() = %s, failed %v, want default/c to fail", result, result.Failed)
+	}
+	if want := "Ingress: 2 reconciled, 0 pending, 1 failed"; result.String() != want {
+		t.Errorf("String() = %q, want %q", result.String(), want)
+	}
+	if r.passErrors != nil {
+		t.Error("passErrors still set after the pass")
+	}
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// Orphan reasons
+const (
+	// OrphanOwnerGone is a record the ownership data claims for an object that no
+	// longer exists
+	OrphanOwnerGone = "owner-gone"
+
+	// OrphanUnowned is a record pointing at a target IP that nothing claims
+	OrphanUnowned = "unowned"
+)
+
+// Orphan is a Pi-hole record no watched object tracks
+type Orphan struct {
+	Domain string `json:"domain"`
+	Type   string `json:"type"`
+	IP     string `json:"ip"`
+	// Owner is the object the ownership data last recorded for the domain; empty for
+	// an unowned record
+	Owner  string `json:"owner,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Key returns the record key of the orphan
+func (o Orphan) Key() string {
+	return pihole.RecordKey(o.Domain, o.Type)
+}
+
+// OrphanScan holds what is known about the records and their owners. Find diffs it
+// into the records that may be cleaned up.
+type OrphanScan struct {
+	// Records are the records Pi-hole holds
+	Records []pihole.DNSRecord
+
+	// Owned are the records tracked by the managed-hosts annotation of live objects;
+	// they are never orphans
+	Owned []OwnedRecord
+
+	// Claims maps a record key to the object the ownership data last recorded
+	// creating or updating it, see ClaimsFromAudit
+	Claims map[string]string
+
+	// Pending are the record keys already scheduled for deferred deletion, which the
+	// deferred deleter removes in its own time
+	Pending map[string]bool
+
+	// OwnerExists reports whether the object named by an owner string still exists
+	OwnerExists func(owner string) bool
+
+	// ClusterID is the cluster of the operator; records marked by another cluster
+	// sharing the Pi-hole are never orphans
+	ClusterID string
+
+	// TargetIPs, when set, also reports records pointing at one of them that are
+	// neither tracked nor claimed
+	TargetIPs []string
+}
+
+// Find returns the orphaned records sorted by domain and type
+func (s OrphanScan) Find() []Orphan {
+	owned := make(map[string]bool, len(s.Owned))
+	for _, rec := range s.Owned {
+		owned[pihole.RecordKey(rec.Domain, rec.Type)] = true
+	}
+
+	var orphans []Orphan
+	for _, rec := range s.Records {
+		if owned[rec.Key()] || s.Pending[rec.Key()] || rec.OtherCluster(s.ClusterID) {
+			continue
+		}
+		orphan := Orphan{Domain: rec.Domain, Type: rec.Type(), IP: rec.IP}
+		if owner, ok := s.Claims[rec.Key()]; ok {
+			if s.OwnerExists != nil && s.OwnerExists(owner) {
+				continue
+			}
+			orphan.Owner, orphan.Reason = owner, OrphanOwnerGone
+		} else if slices.Contains(s.TargetIPs, rec.IP) {
+			orphan.Reason = OrphanUnowned
+		} else {
+			continue
+		}
+		orphans = append(orphans, orphan)
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Domain != orphans[j].Domain {
+			return orphans[i].Domain < orphans[j].Domain
+		}
+		return orphans[i].Type < orphans[j].Type
+	})
+	return orphans
+}
+
+// ClaimsFromAudit returns the owner of each record key according to an audit trail
+// in chronological order: the owner of its last create or update, unless a later
+// delete removed it
+func ClaimsFromAudit(entries []audit.Entry) map[string]string {
+	claims := make(map[string]string)
+	for _, e := range entries {
+		key := pihole.RecordKey(e.Domain, e.Type)
+		switch e.Action {
+		case audit.ActionCreate, audit.ActionUpdate:
+			if e.Owner != "" {
+				claims[key] = e.Owner
+			}
+		case audit.ActionDelete:
+			delete(claims, key)
+		}
+	}
+	return claims
+}
+
+// ObjectOwners returns the owner string of every watched object, as used in audit
+// entries and OwnedRecord, whether or not it tracks records
+func (r *IngressReconciler) ObjectOwners(ctx context.Context) ([]string, error) {
+	list := r.src().newList()
+	if err := r.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("listing %s objects: %w", r.src().kind(), err)
+	}
+	var owners []string
+	for _, obj := range r.src().items(list) {
+		owners = append(owners, r.ownerOf(obj))
+	}
+	return owners, nil
+}
+
+// Kind returns the Kubernetes kind the reconciler watches, the first word of its
+// owner strings
+func (r *IngressReconciler) Kind() string {
+	return r.src().kind()
+}
+
+// ParseOwner splits an owner string of the form "Kind namespace/name"
+func ParseOwner(owner string) (kind, namespace, name string, ok bool) {
+	kind, key, ok := strings.Cut(owner, " ")
+	if !ok {
+		return "", "", "", false
+	}
+	namespace, name, ok = strings.Cut(key, "/")
+	return kind, namespace, name, ok
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestOrphanScanFind(t *testing.T) {
+	live := map[string]bool{"Ingress default/web": true}
+	scan := OrphanScan{
+		Records: []pihole.DNSRecord{
+			{Domain: "web.local", IP: "192.168.1.100"},
+			{Domain: "moved.local", IP: "192.168.1.100"},
+			{Domain: "gone.local", IP: "192.168.1.100"},
+			{Domain: "gone.local", IP: "fd00::10"},
+			{Domain: "pending.local", IP: "192.168.1.100"},
+			{Domain: "stray.local", IP: "192.168.1.100"},
+			{Domain: "router.local", IP: "192.168.1.1"},
+			{Domain: "prod.local", IP: "192.168.1.100", Cluster: "prod"},
+		},
+		Owned: []OwnedRecord{{Domain: "web.local", Type: "A", Owner: "Ingress default/web"}},
+		Claims: map[string]string{
+			"moved.local":     "Ingress default/web",
+			"gone.local":      "Ingress default/old",
+			"gone.local/AAAA": "Ingress default/old",
+			"pending.local":   "Ingress default/old",
+			"prod.local":      "Ingress default/old",
+		},
+		Pending:     map[string]bool{"pending.local": true},
+		OwnerExists: func(owner string) bool { return live[owner] },
+		ClusterID:   "lab",
+	}
+
+	// Records marked by another cluster are never orphans
+	want := []Orphan{
+		{Domain: "gone.local", Type: "A", IP: "192.168.1.100", Owner: "Ingress default/old", Reason: OrphanOwnerGone},
+		{Domain: "gone.local", Type: "AAAA", IP: "fd00::10", Owner: "Ingress default/old", Reason: OrphanOwnerGone},
+	}
+	if got := scan.Find(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() = %+v, want %+v", got, want)
+	}
+
+	// With the target IPs, records pointing at them that nothing claims are reported too
+	scan.TargetIPs = []string{"192.168.1.100"}
+	want = append(want, Orphan{Domain: "stray.local", Type: "A", IP: "192.168.1.100", Reason: OrphanUnowned})
+	if got := scan.Find(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() with target IPs = %+v, want %+v", got, want)
+	}
+}
+
+func TestClaimsFromAudit(t *testing.T) {
+	got := ClaimsFromAudit([]audit.Entry{
+		{Action: audit.ActionCreate, Domain: "a.local", Owner: "Ingress default/a"},
+		{Action: audit.ActionCreate, Domain: "b.local", Owner: "Ingress default/b"},
+		{Action: audit.ActionUpdate, Domain: "a.local", Owner: "Ingress default/a2"},
+		{Action: audit.ActionDelete, Domain: "b.local", Owner: "Ingress default/b"},
+		{Action: audit.ActionCreate, Domain: "c.local"},
+		// Deleting the AAAA record leaves the claim on the A record
+		{Action: audit.ActionCreate, Domain: "d.local", Type: "A", Owner: "Ingress default/d"},
+		{Action: audit.ActionCreate, Domain: "d.local", Type: "AAAA", Owner: "Ingress default/d"},
+		{Action: audit.ActionDelete, Domain: "d.local", Type: "AAAA", Owner: "Ingress default/d"},
+	})
+	want := map[string]string{"a.local": "Ingress default/a2", "d.local": "Ingress default/d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ClaimsFromAudit() = %v, want %v", got, want)
+	}
+}
+
+func TestObjectOwners(t *testing.T) {
+	r := newTestReconciler(newFakePiholeClient(),
+		testIngress("web", map[string]string{AnnotationManagedHosts: "web.local"}, "web.local"),
+		testIngress("plain", nil, "plain.local"),
+	)
+	got, err := r.ObjectOwners(context.Background())
+	if err != nil {
+		t.Fatalf("ObjectOwners() unexpected error: %v", err)
+	}
+	want := []string{"Ingress default/plain", "Ingress default/web"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ObjectOwners() = %v, want %v", got, want)
+	}
+}
+
+func TestParseOwner(t *testing.T) {
+	kind, namespace, name, ok := ParseOwner("DomainMapping apps/web.example.com")
+	if !ok || kind != "DomainMapping" || namespace != "apps" || name != "web.example.com" {
+		t.Errorf("ParseOwner() = %q, %q, %q, %v", kind, namespace, name, ok)
+	}
+	if _, _, _, ok := ParseOwner("Ingress"); ok {
+		t.Error("ParseOwner(\"Ingress\") ok = true, want false")
+	}
+}
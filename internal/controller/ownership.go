@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// OwnedRecord is a DNS record the operator manages on behalf of an object
+type OwnedRecord struct {
+	Domain   string `json:"domain"`
+	Type     string `json:"type"`
+	TargetIP string `json:"targetIP"`
+	Owner    string `json:"owner"`
+
+	// LastSync is when the owner last reconciled successfully; zero if not since startup
+	LastSync time.Time `json:"lastSync,omitzero"`
+
+	// Ownership is what the registry recorded about the record; nil without a registry
+	// or before the owner first synced it
+	Ownership *registry.Ownership `json:"ownership,omitempty"`
+}
+
+// OwnedRecords lists the records tracked in the managed-hosts annotation of every
+// watched object, sorted by domain and type
+func (r *IngressReconciler) OwnedRecords(ctx context.Context) ([]OwnedRecord, error) {
+	list := r.src().newList()
+	if err := r.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("listing %s objects: %w", r.src().kind(), err)
+	}
+
+	state := &registry.State{}
+	if r.Registry != nil {
+		var err error
+		if state, err = r.Registry.Get(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var records []OwnedRecord
+	for _, obj := range r.src().items(list) {
+		targetIP := r.resolveTargetIP(obj)
+		targetIPv6, _ := r.resolveTargetIPv6(obj)
+		lastSync := r.lastSync.get(client.ObjectKeyFromObject(obj))
+		for _, key := range r.getManagedHosts(obj) {
+			domain, recordType := pihole.ParseRecordKey(key)
+			record := OwnedRecord{Domain: domain, Type: recordType, TargetIP: targetIP, Owner: r.ownerOf(obj), LastSync: lastSync}
+			if recordType == pihole.TypeAAAA {
+				record.TargetIP = targetIPv6
+			}
+			if owner, ok := state.Records[key]; ok {
+				record.Ownership = &owner
+			}
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Domain != records[j].Domain {
+			return records[i].Domain < records[j].Domain
+		}
+		if records[i].Type != records[j].Type {
+			return records[i].Type < records[j].Type
+		}
+		return records[i].Owner < records[j].Owner
+	})
+	return records, nil
+}
+
+// recoverManagedHosts rebuilds the managed hosts of an object whose annotation was
+// removed, e.g. pruned by GitOps tooling. The records the object wanted before, from
+// the desired index, are used when known. After a restart the index has nothing yet,
+// so Pi-hole is scanned for records pointing at the object's targets that no other
+// object wants or tracks. Only records still in Pi-hole are returned; the tracking is
+// rewritten once the reconcile completes.
+func (r *IngressReconciler) recoverManagedHosts(ctx context.Context, obj client.Object, current []pihole.DNSRecord, logger *slog.Logger) ([]string, error) {
+	currentIPs := make(map[string]string, len(current))
+	for _, rec := range current {
+		if !rec.OtherCluster(r.ClusterID) {
+			currentIPs[rec.Key()] = rec.IP
+		}
+	}
+
+	owner := r.ownerOf(obj)
+	from := "desired state"
+	var recovered []string
+	if previous := r.Index.Get(owner); len(previous) > 0 {
+		for _, rec := range previous {
+			if currentIPs[rec.Key()] == rec.IP {
+				recovered = append(recovered, rec.Key())
+			}
+		}
+	} else {
+		from = "Pi-hole records"
+		others, err := r.hostsOfOthers(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		targets := map[string]string{pihole.TypeA: r.resolveTargetIP(obj)}
+		if ipv6, ok := r.resolveTargetIPv6(obj); ok && ipv6 != "" {
+			targets[pihole.TypeAAAA] = ipv6
+		}
+		for _, rec := range current {
+			if rec.IP == targets[rec.Type()] && !others[rec.Key()] && !rec.OtherCluster(r.ClusterID) {
+				recovered = append(recovered, rec.Key())
+			}
+		}
+		recovered = withoutHosts(recovered, r.Index.ClaimedByOthers(owner, recovered))
+	}
+	if len(recovered) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(recovered)
+	logger.Warn("managed hosts annotation missing, recovered tracking", "from", from, "hosts", strings.Join(recovered, ","))
+	r.Recorder.Eventf(obj, corev1.EventTypeWarning, "ManagedHostsRecovered",
+		"%s was missing; recovered %s from %s", AnnotationManagedHosts, strings.Join(recovered, ","), from)
+	return recovered, nil
+}
+
+// hostsOfOthers returns the record keys every other object of the source wants or tracks
+func (r *IngressReconciler) hostsOfOthers(ctx context.Context, obj client.Object) (map[string]bool, error) {
+	list := r.src().newList()
+	if err := r.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("listing %s objects: %w", r.src().kind(), err)
+	}
+
+	keys := make(map[string]bool)
+	self := client.ObjectKeyFromObject(obj)
+	for _, other := range r.src().items(list) {
+		if client.ObjectKeyFromObject(other) == self {
+			continue
+		}
+		for _, key := range r.getManagedHosts(other) {
+			keys[key] = true
+		}
+		mode, _ := r.resolveHostsMode(other)
+		for _, host := range r.extractHosts(other, nil, mode) {
+			keys[host] = true
+			keys[pihole.RecordKey(host, pihole.TypeAAAA)] = true
+		}
+	}
+	return keys, nil
+}
+
+// syncTracker remembers when each object last reconciled successfully
+type syncTracker struct {
+	mu sync.Mutex
+	at map[types.NamespacedName]time.Time
+}
+
+// mark records a successful reconcile of key at now
+func (s *syncTracker) mark(key types.NamespacedName, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.at == nil {
+		s.at = make(map[types.NamespacedName]time.Time)
+	}
+	s.at[key] = now
+}
+
+// get returns the last successful reconcile of key, or the zero time
+func (s *syncTracker) get(key types.NamespacedName) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.at[key]
+}
+
+// clear forgets key
+func (s *syncTracker) clear(key types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.at, key)
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestOwnedRecords(t *testing.T) {
+	r := newTestReconciler(newFakePiholeClient(),
+		testIngress("web", map[string]string{
+			AnnotationRegister:     "true",
+			AnnotationTargetIPv6:   "fd00::10",
+			AnnotationManagedHosts: "web.local/AAAA,web.local,api.local",
+		}, "web.local", "api.local"),
+		testIngress("nas", map[string]string{
+			AnnotationRegister:     "true",
+			AnnotationTargetIP:     "192.168.1.20",
+			AnnotationManagedHosts: "nas.local",
+		}, "nas.local"),
+		testIngress("pending", map[string]string{AnnotationRegister: "true"}, "pending.local"),
+	)
+
+	got, err := r.OwnedRecords(context.Background())
+	if err != nil {
+		t.Fatalf("OwnedRecords() unexpected error: %v", err)
+	}
+	want := []OwnedRecord{
+		{Domain: "api.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/web"},
+		{Domain: "nas.local", Type: "A", TargetIP: "192.168.1.20", Owner: "Ingress default/nas"},
+		{Domain: "web.local", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/web"},
+		{Domain: "web.local", Type: "AAAA", TargetIP: "fd00::10", Owner: "Ingress default/web"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("OwnedRecords() = %+v, want %+v", got, want)
+	}
+}
+
+// stripManagedHosts removes the managed-hosts annotation and shrinks the Ingress to its
+// first host, as a GitOps sync pruning the annotation alongside a spec change would
+func stripManagedHosts(t *testing.T, r *IngressReconciler, name string) {
+	t.Helper()
+	ingress := getIngress(t, r, "default", name)
+	delete(ingress.Annotations, AnnotationManagedHosts)
+	ingress.Spec.Rules = ingress.Spec.Rules[:1]
+	if err := r.Update(context.Background(), ingress); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+}
+
+func TestReconcileRecoversManagedHosts(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local", "old.local"))
+	r.Index = &DesiredIndex{}
+
+	reconcileIngress(t, r, "default", "app")
+	stripManagedHosts(t, r, "app")
+	reconcileIngress(t, r, "default", "app")
+
+	if ph.ip("old.local") != "" {
+		t.Error("host removed from the spec not cleaned up after the annotation was lost")
+	}
+	if got := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; got != "app.local" {
+		t.Errorf("managed hosts = %q, want app.local", got)
+	}
+	select {
+	case event := <-r.Recorder.(*record.FakeRecorder).Events:
+		if !strings.Contains(event, "ManagedHostsRecovered") || !strings.Contains(event, "desired state") {
+			t.Errorf("event = %q, want ManagedHostsRecovered from the desired state", event)
+		}
+	default:
+		t.Error("no event for the recovery")
+	}
+}
+
+// TestReconcileRecoversManagedHostsAfterRestart recovers from Pi-hole alone, claiming
+// only records at the object's target that no other object wants
+func TestReconcileRecoversManagedHostsAfterRestart(t *testing.T) {
+	ph := newFakePiholeClient(
+		pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.100"},
+		pihole.DNSRecord{Domain: "old.local", IP: "192.168.1.100"},
+		pihole.DNSRecord{Domain: "other.local", IP: "192.168.1.100"},
+		pihole.DNSRecord{Domain: "manual.local", IP: "192.168.1.50"},
+	)
+	app := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local")
+	controllerutil.AddFinalizer(app, FinalizerName)
+	r := newTestReconciler(ph, app,
+		testIngress("other", map[string]string{AnnotationRegister: "true"}, "other.local"))
+	r.Index = &DesiredIndex{}
+
+	reconcileIngress(t, r, "default", "app")
+
+	if ph.ip("old.local") != "" {
+		t.Error("orphaned record at the object's target not cleaned up")
+	}
+	if ph.ip("other.local") == "" || ph.ip("manual.local") == "" {
+		t.Error("records of another object or at another IP were claimed")
+	}
+}
+
+func TestReconcileNewObjectRecoversNothing(t *testing.T) {
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "manual.local", IP: "192.168.1.100"})
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"))
+
+	reconcileIngress(t, r, "default", "app")
+
+	if ph.ip("manual.local") == "" {
+		t.Error("a new object claimed a record it never created")
+	}
+}
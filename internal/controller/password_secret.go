@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// PasswordSetter is a Pi-hole client whose password can change at runtime.
+// SetPassword reports whether the password changed.
+type PasswordSetter interface {
+	SetPassword(password string) bool
+}
+
+// PasswordSecretReconciler keeps the Pi-hole password in step with a Secret, so
+// rotating the Secret takes effect without restarting the operator. It runs on every
+// replica, since standbys hold a Pi-hole client too. A Secret that goes missing or
+// loses its key leaves the current password in place.
+//
+// The Secret is granted by name in config/rbac/password_secret_role.yaml rather than
+// with a marker, which could only grant every Secret.
+type PasswordSecretReconciler struct {
+	client.Client
+	Logger *slog.Logger
+
+	// Secret and Key locate the password
+	Secret types.NamespacedName
+	Key    string
+
+	// Instance names the Pi-hole instance the password is for; empty for a single one
+	Instance string
+	Pihole   PasswordSetter
+}
+
+// ReadPasswordSecret returns the password stored under key in the Secret, trimmed of
+// surrounding whitespace
+func ReadPasswordSecret(ctx context.Context, reader client.Reader, secret types.NamespacedName, key string) (string, error) {
+	var s corev1.Secret
+	if err := reader.Get(ctx, secret, &s); err != nil {
+		return "", fmt.Errorf("reading password secret %s: %w", secret, err)
+	}
+	data, ok := s.Data[key]
+	if !ok {
+		return "", fmt.Errorf("password secret %s has no key %q", secret, key)
+	}
+	password := strings.TrimSpace(string(data))
+	if password == "" {
+		return "", fmt.Errorf("password secret %s key %q is empty", secret, key)
+	}
+	return password, nil
+}
+
+// Reconcile applies the Secret's current password
+func (r *PasswordSecretReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	password, err := ReadPasswordSecret(ctx, r.Client, r.Secret, r.Key)
+	if err != nil {
+		// Nothing to retry until the Secret changes again
+		r.Logger.Error("keeping the current pihole password", "instance", r.Instance, "error", err)
+		return ctrl.Result{}, nil
+	}
+	if r.Pihole.SetPassword(password) {
+		r.Logger.Info("pihole password rotated, session invalidated", "instance", r.Instance, "secret", r.Secret.String())
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *PasswordSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	needLeaderElection := false
+	name := "password-secret"
+	if r.Instance != "" {
+		name += "-" + r.Instance
+	}
+	return ctrl.NewControllerManagedBy(mgr).Named(name).
+		For(&corev1.Secret{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return client.ObjectKeyFromObject(obj) == r.Secret
+		}))).
+		WithOptions(controller.Options{NeedLeaderElection: &needLeaderElection}).
+		Complete(r)
+}
+
+// SecretCacheOptions limits the cached Secrets to the named ones, the only Secrets the
+// operator reads and may read. A namespace holding more than one of them is cached
+// whole, since a field selector can only match a single name.
+func SecretCacheOptions(opts *cache.Options, secrets ...types.NamespacedName) {
+	if opts.ByObject == nil {
+		opts.ByObject = make(map[client.Object]cache.ByObject)
+	}
+	names := make(map[string][]string)
+	for _, secret := range secrets {
+		if !slices.Contains(names[secret.Namespace], secret.Name) {
+			names[secret.Namespace] = append(names[secret.Namespace], secret.Name)
+		}
+	}
+	namespaces := make(map[string]cache.Config, len(names))
+	for namespace, inNamespace := range names {
+		var config cache.Config
+		if len(inNamespace) == 1 {
+			config.FieldSelector = fields.OneTermEqualSelector("metadata.name", inNamespace[0])
+		}
+		namespaces[namespace] = config
+	}
+	opts.ByObject[&corev1.Secret{}] = cache.ByObject{Namespaces: namespaces}
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testPasswordSecret = types.NamespacedName{Namespace: "pihole-operator", Name: "pihole-operator-secret"}
+
+func passwordSecret(data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testPasswordSecret.Namespace, Name: testPasswordSecret.Name},
+		Data:       map[string][]byte{},
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+// fakePasswordSetter records the passwords set on it
+type fakePasswordSetter struct {
+	password string
+	changes  int
+}
+
+func (f *fakePasswordSetter) SetPassword(password string) bool {
+	if password == f.password {
+		return false
+	}
+	f.password = password
+	f.changes++
+	return true
+}
+
+func TestReadPasswordSecret(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+		want   string
+		errMsg string
+	}{
+		{name: "trimmed", secret: passwordSecret(map[string]string{"password": "s3cret\n"}), want: "s3cret"},
+		{name: "missing secret", errMsg: "reading password secret"},
+		{name: "missing key", secret: passwordSecret(map[string]string{"other": "s3cret"}), errMsg: `no key "password"`},
+		{name: "empty value", secret: passwordSecret(map[string]string{"password": " \n"}), errMsg: "is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+			if tt.secret != nil {
+				b = b.WithObjects(tt.secret)
+			}
+
+			got, err := ReadPasswordSecret(context.Background(), b.Build(), testPasswordSecret, "password")
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("ReadPasswordSecret() error = %v, want to contain %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadPasswordSecret() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadPasswordSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordSecretRotation(t *testing.T) {
+	ctx := context.Background()
+	secret := passwordSecret(map[string]string{"password": "first"})
+	pihole := &fakePasswordSetter{password: "first"}
+	r := &PasswordSecretReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build(),
+		Logger: slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		Secret: testPasswordSecret,
+		Key:    "password",
+		Pihole: pihole,
+	}
+	reconcile := func() {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: testPasswordSecret}); err != nil {
+			t.Fatalf("Reconcile() unexpected error: %v", err)
+		}
+	}
+
+	// The startup reconcile finds the password already in use
+	reconcile()
+	if pihole.changes != 0 {
+		t.Errorf("password set %d times without a rotation", pihole.changes)
+	}
+
+	secret.Data["password"] = []byte("second")
+	if err := r.Update(ctx, secret); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	reconcile()
+	if pihole.password != "second" || pihole.changes != 1 {
+		t.Errorf("password = %q after %d changes, want second after 1", pihole.password, pihole.changes)
+	}
+
+	// Losing the key keeps the current password
+	delete(secret.Data, "password")
+	if err := r.Update(ctx, secret); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	reconcile()
+	if pihole.password != "second" {
+		t.Errorf("password = %q after the key was removed, want second kept", pihole.password)
+	}
+}
+
+func TestSecretCacheOptions(t *testing.T) {
+	var opts cache.Options
+	SecretCacheOptions(&opts,
+		testPasswordSecret,
+		types.NamespacedName{Namespace: "pihole", Name: "primary"},
+		types.NamespacedName{Namespace: "pihole", Name: "secondary"},
+	)
+
+	var byObject cache.ByObject
+	for _, b := range opts.ByObject {
+		byObject = b
+	}
+	if len(byObject.Namespaces) != 2 {
+		t.Fatalf("cached namespaces = %v, want 2", byObject.Namespaces)
+	}
+	if sel := byObject.Namespaces[testPasswordSecret.Namespace].FieldSelector; sel == nil ||
+		sel.String() != "metadata.name="+testPasswordSecret.Name {
+		t.Errorf("field selector = %v, want the one secret's name", sel)
+	}
+	if sel := byObject.Namespaces["pihole"].FieldSelector; sel != nil {
+		t.Errorf("field selector = %v, want none for a namespace with two secrets", sel)
+	}
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+)
+
+// DefaultPendingDeletionInterval is how often the pending deletion gauges are refreshed
+const DefaultPendingDeletionInterval = 30 * time.Second
+
+// PendingDeletions tracks deleted objects still held by the finalizer while their DNS
+// cleanup is retried, shared across sources. Reconciles add an object when they fail to
+// clean it up and drop it once it is released; Start refreshes the gauges so the age
+// of the oldest keeps growing between reconciles, and warns on each object pending
+// longer than WarnAfter. A nil *PendingDeletions tracks nothing.
+type PendingDeletions struct {
+	Recorder record.EventRecorder
+
+	// WarnAfter is how long an object may be pending before a Warning event explains
+	// the hang; zero disables the event
+	WarnAfter time.Duration
+
+	// Kinds are reported on every refresh, so their count reads 0 rather than vanishing
+	Kinds []string
+
+	Interval time.Duration
+
+	mu      sync.Mutex
+	objects map[string]*pendingDeletion
+}
+
+// pendingDeletion is one deleted object waiting for its DNS cleanup
+type pendingDeletion struct {
+	kind   string
+	obj    client.Object
+	warned bool
+}
+
+// Track records obj as pending when it is being deleted and still carries the
+// finalizer, and forgets it otherwise
+func (p *PendingDeletions) Track(owner string, obj client.Object) {
+	if p == nil {
+		return
+	}
+	if obj.GetDeletionTimestamp().IsZero() || !controllerutil.ContainsFinalizer(obj, FinalizerName) {
+		p.Forget(owner)
+		return
+	}
+	kind, _, _, _ := ParseOwner(owner)
+	p.mu.Lock()
+	if p.objects == nil {
+		p.objects = map[string]*pendingDeletion{}
+	}
+	pending, ok := p.objects[owner]
+	if !ok {
+		pending = &pendingDeletion{kind: kind}
+		p.objects[owner] = pending
+	}
+	pending.obj = obj.DeepCopyObject().(client.Object)
+	p.mu.Unlock()
+	p.refresh(time.Now())
+}
+
+// Forget drops an object that was released or is gone
+func (p *PendingDeletions) Forget(owner string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	_, ok := p.objects[owner]
+	delete(p.objects, owner)
+	p.mu.Unlock()
+	if ok {
+		p.refresh(time.Now())
+	}
+}
+
+// Start refreshes the gauges every Interval until ctx is cancelled; it implements
+// manager.Runnable
+func (p *PendingDeletions) Start(ctx context.Context) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultPendingDeletionInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.refresh(time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection reports from the leader only, whose reconciles track the objects
+func (p *PendingDeletions) NeedLeaderElection() bool {
+	return true
+}
+
+// refresh sets the gauges as of now and warns on objects newly pending too long
+func (p *PendingDeletions) refresh(now time.Time) {
+	counts := map[string]int{}
+	for _, kind := range p.Kinds {
+		counts[kind] = 0
+	}
+	var oldest time.Duration
+	var stuck []client.Object
+
+	p.mu.Lock()
+	for _, pending := range p.objects {
+		counts[pending.kind]++
+		age := now.Sub(pending.obj.GetDeletionTimestamp().Time)
+		oldest = max(oldest, age)
+		if p.WarnAfter > 0 && age >= p.WarnAfter && !pending.warned {
+			pending.warned = true
+			stuck = append(stuck, pending.obj)
+		}
+	}
+	p.mu.Unlock()
+
+	for kind, n := range counts {
+		metrics.PendingDeletions.WithLabelValues(kind).Set(float64(n))
+	}
+	metrics.OldestPendingDeletion.Set(oldest.Seconds())
+
+	sort.Slice(stuck, func(i, j int) bool {
+		return client.ObjectKeyFromObject(stuck[i]).String() < client.ObjectKeyFromObject(stuck[j]).String()
+	})
+	for _, obj := range stuck {
+		if p.Recorder != nil {
+			p.Recorder.Eventf(obj, corev1.EventTypeWarning, "DeletionStuck",
+				"Deleted %s ago, but the %s finalizer is still waiting for DNS cleanup to succeed; check the operator logs",
+				now.Sub(obj.GetDeletionTimestamp().Time).Round(time.Second), FinalizerName)
+		}
+	}
+}
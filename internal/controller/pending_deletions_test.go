@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+)
+
+// pendingGauges returns the pending Ingress deletions and the age of the oldest
+func pendingGauges(t *testing.T) (float64, float64) {
+	t.Helper()
+	var count, oldest dto.Metric
+	if err := metrics.PendingDeletions.WithLabelValues("Ingress").Write(&count); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	if err := metrics.OldestPendingDeletion.Write(&oldest); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	return count.GetGauge().GetValue(), oldest.GetGauge().GetValue()
+}
+
+func TestPendingDeletionsRefresh(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	p := &PendingDeletions{Recorder: recorder, WarnAfter: 10 * time.Minute, Kinds: []string{"Ingress"}}
+	deleted := time.Now().Add(-5 * time.Minute)
+	ingress := testIngress("app", nil, "app.local")
+	ingress.Finalizers = []string{FinalizerName}
+	ingress.DeletionTimestamp = &metav1.Time{Time: deleted}
+
+	p.Track("Ingress default/app", ingress)
+	if count, oldest := pendingGauges(t); count != 1 || oldest < 300 {
+		t.Errorf("gauges = %v pending, oldest %vs; want 1, at least 300s", count, oldest)
+	}
+
+	// The age keeps growing without reconciles, and crossing WarnAfter warns once
+	p.refresh(deleted.Add(11 * time.Minute))
+	p.refresh(deleted.Add(12 * time.Minute))
+	if _, oldest := pendingGauges(t); oldest != 720 {
+		t.Errorf("oldest = %vs, want 720s", oldest)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("%d events, want one DeletionStuck warning", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "Warning DeletionStuck Deleted 11m0s ago") {
+		t.Errorf("event = %q, want a DeletionStuck warning", event)
+	}
+
+	// An object no longer holding the finalizer is not pending
+	ingress.Finalizers = nil
+	p.Track("Ingress default/app", ingress)
+	if count, oldest := pendingGauges(t); count != 0 || oldest != 0 {
+		t.Errorf("gauges = %v pending, oldest %vs; want 0", count, oldest)
+	}
+}
+
+func TestReconcileTracksPendingDeletions(t *testing.T) {
+	ctx := context.Background()
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"))
+	r.Pending = &PendingDeletions{Kinds: []string{"Ingress"}}
+	reconcileIngress(t, r, "default", "app")
+
+	if err := r.Delete(ctx, getIngress(t, r, "default", "app")); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	ph.err = errors.New("pihole unavailable")
+	reconcileIngress(t, r, "default", "app")
+	if count, _ := pendingGauges(t); count != 1 {
+		t.Errorf("pending after a failed cleanup = %v, want 1", count)
+	}
+
+	ph.err = nil
+	reconcileIngress(t, r, "default", "app")
+	if count, _ := pendingGauges(t); count != 0 {
+		t.Errorf("pending after the cleanup = %v, want 0", count)
+	}
+}
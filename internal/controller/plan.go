@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"log/slog"
+	"sort"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// RecordUpdate describes a record whose target IP changes within one address family
+type RecordUpdate struct {
+	Domain string
+	OldIP  string
+	NewIP  string
+}
+
+// Type returns the record type of the update
+func (u RecordUpdate) Type() string {
+	return pihole.DNSRecord{Domain: u.Domain, IP: u.NewIP}.Type()
+}
+
+// Key identifies the updated record; see pihole.RecordKey
+func (u RecordUpdate) Key() string {
+	return pihole.RecordKey(u.Domain, u.Type())
+}
+
+// Plan describes the DNS changes needed to bring Pi-hole in line with the desired records.
+// All slices are sorted by record key so plans are deterministic; Unchanged holds keys.
+type Plan struct {
+	Creates   []pihole.DNSRecord
+	Updates   []RecordUpdate
+	Deletes   []pihole.DNSRecord
+	Unchanged []string
+}
+
+// IsEmpty reports whether the plan requires any Pi-hole writes
+func (p Plan) IsEmpty() bool {
+	return len(p.Creates) == 0 && len(p.Updates) == 0 && len(p.Deletes) == 0
+}
+
+// LogValue renders the plan as a structured log group
+func (p Plan) LogValue() slog.Value {
+	creates := make([]string, 0, len(p.Creates))
+	for _, r := range p.Creates {
+		creates = append(creates, r.Domain+"="+r.IP)
+	}
+	updates := make([]string, 0, len(p.Updates))
+	for _, u := range p.Updates {
+		updates = append(updates, u.Domain+"="+u.OldIP+"->"+u.NewIP)
+	}
+	deletes := make([]string, 0, len(p.Deletes))
+	for _, r := range p.Deletes {
+		deletes = append(deletes, r.Key())
+	}
+	return slog.GroupValue(
+		slog.Any("create", creates),
+		slog.Any("update", updates),
+		slog.Any("delete", deletes),
+		slog.Int("unchanged", len(p.Unchanged)),
+	)
+}
+
+// computePlan diffs the current Pi-hole records against the desired records.
+// Records are matched by key, so A and AAAA records of a domain are independent.
+// Only keys in managed are eligible for deletion, so records created outside
+// the operator are never removed. Managed records already absent from Pi-hole
+// need no deletion.
+func computePlan(current, desired []pihole.DNSRecord, managed []string) Plan {
+	currentIPs := make(map[string]string, len(current))
+	for _, r := range current {
+		currentIPs[r.Key()] = r.IP
+	}
+
+	var plan Plan
+	desiredSet := make(map[string]bool, len(desired))
+	for _, want := range desired {
+		key := want.Key()
+		if desiredSet[key] {
+			continue
+		}
+		desiredSet[key] = true
+
+		ip, exists := currentIPs[key]
+		switch {
+		case !exists:
+			plan.Creates = append(plan.Creates, want)
+		case ip != want.IP:
+			plan.Updates = append(plan.Updates, RecordUpdate{Domain: want.Domain, OldIP: ip, NewIP: want.IP})
+		default:
+			plan.Unchanged = append(plan.Unchanged, key)
+		}
+	}
+
+	deleted := make(map[string]bool)
+	for _, key := range managed {
+		if desiredSet[key] || deleted[key] {
+			continue
+		}
+		if ip, exists := currentIPs[key]; exists {
+			deleted[key] = true
+			domain, _ := pihole.ParseRecordKey(key)
+			plan.Deletes = append(plan.Deletes, pihole.DNSRecord{Domain: domain, IP: ip})
+		}
+	}
+
+	sort.Slice(plan.Creates, func(i, j int) bool { return plan.Creates[i].Key() < plan.Creates[j].Key() })
+	sort.Slice(plan.Updates, func(i, j int) bool { return plan.Updates[i].Key() < plan.Updates[j].Key() })
+	sort.Slice(plan.Deletes, func(i, j int) bool { return plan.Deletes[i].Key() < plan.Deletes[j].Key() })
+	sort.Strings(plan.Unchanged)
+
+	return plan
+}
+
+// addPartialDeletes plans the deletion of managed records the listing left out because
+// only some Pi-hole servers still hold them, such as after a delete failed on one.
+// Desired keys among them are already planned as creates.
+func (p *Plan) addPartialDeletes(partial []pihole.DNSRecord, managed []string) {
+	if len(partial) == 0 {
+		return
+	}
+	skip := make(map[string]bool, len(p.Creates)+len(p.Deletes))
+	for _, r := range p.Creates {
+		skip[r.Key()] = true
+	}
+	for _, r := range p.Deletes {
+		skip[r.Key()] = true
+	}
+	held := make(map[string]pihole.DNSRecord, len(partial))
+	for _, r := range partial {
+		held[r.Key()] = r
+	}
+	for _, key := range managed {
+		if r, ok := held[key]; ok && !skip[key] {
+			skip[key] = true
+			p.Deletes = append(p.Deletes, r)
+		}
+	}
+	sort.Slice(p.Deletes, func(i, j int) bool { return p.Deletes[i].Key() < p.Deletes[j].Key() })
+}
+
+// dropDeletes removes the deletions of the given record keys
+func (p *Plan) dropDeletes(keys []string) {
+	drop := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		drop[key] = true
+	}
+	deletes := p.Deletes[:0]
+	for _, d := range p.Deletes {
+		if !drop[d.Key()] {
+			deletes = append(deletes, d)
+		}
+	}
+	p.Deletes = deletes
+}
+
+// dropRecords removes every change and unchanged entry for the given record keys. It
+// returns the desired keys dropped, those of updates and unchanged entries.
+func (p *Plan) dropRecords(keys map[string]bool) []string {
+	var dropped []string
+	updates := p.Updates[:0]
+	for _, u := range p.Updates {
+		if keys[u.Key()] {
+			dropped = append(dropped, u.Key())
+		} else {
+			updates = append(updates, u)
+		}
+	}
+	p.Updates = updates
+
+	unchanged := p.Unchanged[:0]
+	for _, key := range p.Unchanged {
+		if keys[key] {
+			dropped = append(dropped, key)
+		} else {
+			unchanged = append(unchanged, key)
+		}
+	}
+	p.Unchanged = unchanged
+
+	deletes := p.Deletes[:0]
+	for _, d := range p.Deletes {
+		if !keys[d.Key()] {
+			deletes = append(deletes, d)
+		}
+	}
+	p.Deletes = deletes
+	sort.Strings(dropped)
+	return dropped
+}
+
+// dropForeign removes updates and unchanged entries for records outside managed, so
+// records that already existed in Pi-hole are neither overwritten nor adopted. It
+// returns the skipped updates and the skipped unchanged keys.
+func (p *Plan) dropForeign(managed []string) ([]RecordUpdate, []string) {
+	owned := make(map[string]bool, len(managed))
+	for _, key := range managed {
+		owned[key] = true
+	}
+
+	var conflicts []RecordUpdate
+	updates := p.Updates[:0]
+	for _, u := range p.Updates {
+		if owned[u.Key()] {
+			updates = append(updates, u)
+		} else {
+			conflicts = append(conflicts, u)
+		}
+	}
+	p.Updates = updates
+
+	var foreign []string
+	unchanged := p.Unchanged[:0]
+	for _, key := range p.Unchanged {
+		if owned[key] {
+			unchanged = append(unchanged, key)
+		} else {
+			foreign = append(foreign, key)
+		}
+	}
+	p.Unchanged = unchanged
+
+	return conflicts, foreign
+}
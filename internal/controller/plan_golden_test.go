@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+// planFixture describes one plan case in testdata/plans
+type planFixture struct {
+	// Current holds Pi-hole's records as "IP DOMAIN"
+	Current []string `yaml:"current"`
+	// Hosts are the object's hosts before filtering
+	Hosts      []string `yaml:"hosts"`
+	TargetIP   string   `yaml:"targetIP"`
+	TargetIPv6 string   `yaml:"targetIPv6"`
+	// Managed holds the record keys of the managed-hosts annotation
+	Managed []string `yaml:"managed"`
+	// Overwrite defaults to true; false drops the changes to foreign records
+	Overwrite        *bool    `yaml:"overwrite"`
+	InternalSuffixes []string `yaml:"internalSuffixes"`
+}
+
+// renderPlan plans fixture the way a reconcile does, logging to handler what the
+// reconcile logs along the way: the skipped hosts, the conflicts and the plan
+func renderPlan(fixture planFixture, handler slog.Handler) {
+	logger := slog.New(handler)
+
+	filter := &HostFilter{InternalSuffixes: fixture.InternalSuffixes}
+	hosts := filter.Filter(fixture.Hosts, logger)
+
+	var current []pihole.DNSRecord
+	for _, entry := range fixture.Current {
+		fields := strings.Fields(entry)
+		current = append(current, pihole.DNSRecord{IP: fields[0], Domain: fields[1]})
+	}
+	var desired []pihole.DNSRecord
+	for _, host := range hosts {
+		if fixture.TargetIP != "" {
+			desired = append(desired, pihole.DNSRecord{Domain: host, IP: fixture.TargetIP})
+		}
+		if fixture.TargetIPv6 != "" {
+			desired = append(desired, pihole.DNSRecord{Domain: host, IP: fixture.TargetIPv6})
+		}
+	}
+
+	plan := computePlan(current, desired, fixture.Managed)
+	if fixture.Overwrite != nil && !*fixture.Overwrite {
+		conflicts, _ := plan.dropForeign(fixture.Managed)
+		for _, c := range conflicts {
+			logger.Warn("existing record not overwritten", "host", c.Domain, "existing_ip", c.OldIP)
+		}
+	}
+	if plan.IsEmpty() {
+		logger.Debug("change plan computed", "plan", plan)
+	} else {
+		logger.Info("change plan computed", "plan", plan)
+	}
+}
+
+// TestPlanGolden renders every fixture in testdata/plans as the JSON and text log
+// lines a reconcile writes and compares them with the committed golden files. Run
+// go test -run TestPlanGolden -update to rewrite them after a deliberate change.
+func TestPlanGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "plans", "*.yaml"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("no plan fixtures: %v", err)
+	}
+
+	// Drop the time so the output is stable
+	opts := &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+
+	for _, path := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(path), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fixture planFixture
+			if err := yaml.Unmarshal(data, &fixture); err != nil {
+				t.Fatalf("parsing %s: %v", path, err)
+			}
+
+			var structured, summary bytes.Buffer
+			renderPlan(fixture, slog.NewJSONHandler(&structured, opts))
+			renderPlan(fixture, slog.NewTextHandler(&summary, opts))
+
+			base := strings.TrimSuffix(path, ".yaml")
+			compareGolden(t, base+".json", structured.Bytes())
+			compareGolden(t, base+".txt", summary.Bytes())
+		})
+	}
+}
+
+// compareGolden fails the test when got differs from the golden file at path, or
+// rewrites the file under -update
+func compareGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs; run with -update if the change is deliberate\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,303 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestComputePlan(t *testing.T) {
+	const target = "192.168.1.100"
+
+	tests := []struct {
+		name    string
+		current []pihole.DNSRecord
+		desired []string
+		managed []string
+		want    Plan
+	}{
+		{
+			name:    "empty everything",
+			current: nil,
+			desired: nil,
+			managed: nil,
+			want:    Plan{},
+		},
+		{
+			name:    "create missing records",
+			current: nil,
+			desired: []string{"b.local", "a.local"},
+			want: Plan{
+				Creates: []pihole.DNSRecord{
+					{Domain: "a.local", IP: target},
+					{Domain: "b.local", IP: target},
+				},
+			},
+		},
+		{
+			name:    "unchanged records",
+			current: []pihole.DNSRecord{{Domain: "a.local", IP: target}},
+			desired: []string{"a.local"},
+			managed: []string{"a.local"},
+			want:    Plan{Unchanged: []string{"a.local"}},
+		},
+		{
+			name:    "update record with different ip",
+			current: []pihole.DNSRecord{{Domain: "a.local", IP: "10.0.0.1"}},
+			desired: []string{"a.local"},
+			managed: []string{"a.local"},
+			want: Plan{
+				Updates: []RecordUpdate{{Domain: "a.local", OldIP: "10.0.0.1", NewIP: target}},
+			},
+		},
+		{
+			name:    "update unmanaged record claimed by ingress",
+			current: []pihole.DNSRecord{{Domain: "nas.local", IP: "192.168.1.20"}},
+			desired: []string{"nas.local"},
+			want: Plan{
+				Updates: []RecordUpdate{{Domain: "nas.local", OldIP: "192.168.1.20", NewIP: target}},
+			},
+		},
+		{
+			name: "delete managed records no longer desired",
+			current: []pihole.DNSRecord{
+				{Domain: "a.local", IP: target},
+				{Domain: "old.local", IP: target},
+			},
+			desired: []string{"a.local"},
+			managed: []string{"a.local", "old.local"},
+			want: Plan{
+				Deletes:   []pihole.DNSRecord{{Domain: "old.local", IP: target}},
+				Unchanged: []string{"a.local"},
+			},
+		},
+		{
+			name:    "managed record already gone needs no delete",
+			current: nil,
+			desired: nil,
+			managed: []string{"gone.local"},
+			want:    Plan{},
+		},
+		{
+			name:    "unmanaged records are never deleted",
+			current: []pihole.DNSRecord{{Domain: "manual.local", IP: "10.0.0.9"}},
+			desired: []string{"a.local"},
+			managed: nil,
+			want: Plan{
+				Creates: []pihole.DNSRecord{{Domain: "a.local", IP: target}},
+			},
+		},
+		{
+			name:    "duplicate desired and managed entries",
+			current: []pihole.DNSRecord{{Domain: "old.local", IP: target}},
+			desired: []string{"a.local", "a.local"},
+			managed: []string{"old.local", "old.local"},
+			want: Plan{
+				Creates: []pihole.DNSRecord{{Domain: "a.local", IP: target}},
+				Deletes: []pihole.DNSRecord{{Domain: "old.local", IP: target}},
+			},
+		},
+		{
+			name: "mixed plan",
+			current: []pihole.DNSRecord{
+				{Domain: "keep.local", IP: target},
+				{Domain: "move.local", IP: "10.0.0.1"},
+				{Domain: "drop.local", IP: target},
+			},
+			desired: []string{"new.local", "move.local", "keep.local"},
+			managed: []string{"keep.local", "move.local", "drop.local"},
+			want: Plan{
+				Creates:   []pihole.DNSRecord{{Domain: "new.local", IP: target}},
+				Updates:   []RecordUpdate{{Domain: "move.local", OldIP: "10.0.0.1", NewIP: target}},
+				Deletes:   []pihole.DNSRecord{{Domain: "drop.local", IP: target}},
+				Unchanged: []string{"keep.local"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired := make([]pihole.DNSRecord, 0, len(tt.desired))
+			for _, h := range tt.desired {
+				desired = append(desired, pihole.DNSRecord{Domain: h, IP: target})
+			}
+
+			got := computePlan(tt.current, desired, tt.managed)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("computePlan() = %+v, want %+v", got, tt.want)
+			}
+			if got.IsEmpty() != tt.want.IsEmpty() {
+				t.Errorf("IsEmpty() = %v, want %v", got.IsEmpty(), tt.want.IsEmpty())
+			}
+		})
+	}
+}
+
+func TestApplyPlan(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	fake := newFakePiholeClient(
+		pihole.DNSRecord{Domain: "move.local", IP: "10.0.0.1"},
+		pihole.DNSRecord{Domain: "drop.local", IP: "10.0.0.2"},
+	)
+	sink := &memoryAuditSink{}
+	r := &IngressReconciler{Logger: logger, PiholeClient: fake, Audit: sink}
+
+	plan := Plan{
+		Creates: []pihole.DNSRecord{{Domain: "new.local", IP: "10.0.0.3"}},
+		Updates: []RecordUpdate{{Domain: "move.local", OldIP: "10.0.0.1", NewIP: "10.0.0.3"}},
+		Deletes: []pihole.DNSRecord{{Domain: "drop.local", IP: "10.0.0.2"}},
+	}
+	applied, err := r.applyPlan(context.Background(), "Ingress default/app", plan, logger)
+	if err != nil {
+		t.Fatalf("applyPlan() unexpected error: %v", err)
+	}
+
+	wantCalls := []string{"delete move.local", "create move.local", "create new.local", "delete drop.local"}
+	if !reflect.DeepEqual(fake.calls, wantCalls) {
+		t.Errorf("calls = %v, want %v", fake.calls, wantCalls)
+	}
+	if fake.ip("move.local") != "10.0.0.3" || fake.ip("new.local") != "10.0.0.3" || fake.ip("drop.local") != "" {
+		t.Errorf("unexpected final records: %v", fake.records)
+	}
+
+	if !reflect.DeepEqual(applied, plan) {
+		t.Errorf("applied = %+v, want %+v", applied, plan)
+	}
+
+	wantAudit := []string{"update move.local", "create new.local", "delete drop.local"}
+	var gotAudit []string
+	for _, e := range sink.entries {
+		gotAudit = append(gotAudit, e.Action+" "+e.Domain)
+		if e.Owner != "Ingress default/app" || e.Time.IsZero() {
+			t.Errorf("audit entry missing owner or time: %+v", e)
+		}
+	}
+	if !reflect.DeepEqual(gotAudit, wantAudit) {
+		t.Errorf("audit = %v, want %v", gotAudit, wantAudit)
+	}
+}
+
+// memoryAuditSink collects audit entries in memory
+type memoryAuditSink struct {
+	entries []audit.Entry
+}
+
+func (m *memoryAuditSink) Record(_ context.Context, entry audit.Entry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func TestComputePlanDualStack(t *testing.T) {
+	current := []pihole.DNSRecord{
+		{Domain: "a.local", IP: "192.168.1.100"},
+		{Domain: "a.local", IP: "fd00::1"},
+		{Domain: "b.local", IP: "192.168.1.100"},
+		{Domain: "b.local", IP: "fd00::1"},
+	}
+	desired := []pihole.DNSRecord{
+		{Domain: "a.local", IP: "192.168.1.100"},
+		{Domain: "a.local", IP: "fd00::2"},
+		{Domain: "b.local", IP: "192.168.1.100"},
+		{Domain: "c.local", IP: "fd00::2"},
+	}
+	managed := []string{"a.local", "a.local/AAAA", "b.local", "b.local/AAAA"}
+
+	got := computePlan(current, desired, managed)
+	want := Plan{
+		Creates:   []pihole.DNSRecord{{Domain: "c.local", IP: "fd00::2"}},
+		Updates:   []RecordUpdate{{Domain: "a.local", OldIP: "fd00::1", NewIP: "fd00::2"}},
+		Deletes:   []pihole.DNSRecord{{Domain: "b.local", IP: "fd00::1"}},
+		Unchanged: []string{"a.local", "b.local"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("computePlan() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPlanDropForeign(t *testing.T) {
+	plan := Plan{
+		Creates: []pihole.DNSRecord{{Domain: "new.local", IP: "10.0.0.3"}},
+		Updates: []RecordUpdate{
+			{Domain: "mine.local", OldIP: "10.0.0.1", NewIP: "10.0.0.3"},
+			{Domain: "nas.local", OldIP: "192.168.1.20", NewIP: "10.0.0.3"},
+		},
+		Unchanged: []string{"kept.local", "manual.local"},
+	}
+
+	conflicts, foreign := plan.dropForeign([]string{"mine.local", "kept.local"})
+
+	wantConflicts := []RecordUpdate{{Domain: "nas.local", OldIP: "192.168.1.20", NewIP: "10.0.0.3"}}
+	if !reflect.DeepEqual(conflicts, wantConflicts) {
+		t.Errorf("conflicts = %v, want %v", conflicts, wantConflicts)
+	}
+	if !slicesEqual(foreign, []string{"manual.local"}) {
+		t.Errorf("foreign = %v, want [manual.local]", foreign)
+	}
+	wantUpdates := []RecordUpdate{{Domain: "mine.local", OldIP: "10.0.0.1", NewIP: "10.0.0.3"}}
+	if !reflect.DeepEqual(plan.Updates, wantUpdates) {
+		t.Errorf("Updates = %v, want %v", plan.Updates, wantUpdates)
+	}
+	if !slicesEqual(plan.Unchanged, []string{"kept.local"}) {
+		t.Errorf("Unchanged = %v, want [kept.local]", plan.Unchanged)
+	}
+	if len(plan.Creates) != 1 {
+		t.Errorf("Creates = %v, want untouched", plan.Creates)
+	}
+}
+
+func TestReconcilePartialDeleteFailure(t *testing.T) {
+	// old.local is deleted from the primary but the secondary refuses; the next sync must
+	// still delete it there although the instances no longer agree on it
+	records := []pihole.DNSRecord{{Domain: "app.local", IP: "192.168.1.100"}, {Domain: "old.local", IP: "192.168.1.100"}}
+	primary, secondary := newFakePiholeClient(records...), newFakePiholeClient(records...)
+	ingress := testIngress("app", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationManagedHosts: "app.local,old.local",
+	}, "app.local")
+	r := newTestReconciler(primary, ingress)
+	r.PiholeClient = pihole.NewListingCache(pihole.NewMultiClient(
+		pihole.Instance{Name: "primary", Client: primary},
+		pihole.Instance{Name: "secondary", Client: secondary},
+	))
+
+	secondary.err = errors.New("pihole unreachable")
+	if res := reconcileIngress(t, r, "default", "app"); res.RequeueAfter == 0 {
+		t.Error("failed delete did not requeue")
+	}
+	if primary.ip("old.local") != "" || secondary.ip("old.local") == "" {
+		t.Fatalf("want old.local deleted from the primary only: %v %v", primary.records, secondary.records)
+	}
+
+	secondary.err = nil
+	reconcileIngress(t, r, "default", "app")
+	if secondary.ip("old.local") != "" {
+		t.Errorf("old.local left on the secondary: %v", secondary.records)
+	}
+	if managed := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; managed != "app.local" {
+		t.Errorf("managed hosts = %q, want app.local", managed)
+	}
+}
+
+func TestPlanAddPartialDeletes(t *testing.T) {
+	plan := Plan{
+		Creates: []pihole.DNSRecord{{Domain: "wanted.local", IP: "10.0.0.1"}},
+		Deletes: []pihole.DNSRecord{{Domain: "stale.local", IP: "10.0.0.1"}},
+	}
+	partial := []pihole.DNSRecord{
+		{Domain: "wanted.local", IP: "10.0.0.1"},
+		{Domain: "half.local", IP: "10.0.0.1"},
+		{Domain: "manual.local", IP: "10.0.0.1"},
+	}
+
+	plan.addPartialDeletes(partial, []string{"wanted.local", "stale.local", "half.local"})
+	want := []pihole.DNSRecord{{Domain: "half.local", IP: "10.0.0.1"}, {Domain: "stale.local", IP: "10.0.0.1"}}
+	if !reflect.DeepEqual(plan.Deletes, want) {
+		t.Errorf("Deletes = %v, want %v", plan.Deletes, want)
+	}
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Defaults matching the controller-runtime workqueue rate limiter
+const (
+	DefaultRateLimiterBaseDelay = 5 * time.Millisecond
+	DefaultRateLimiterMaxDelay  = 1000 * time.Second
+	DefaultRateLimiterQPS       = 10
+	DefaultRateLimiterBurst     = 100
+)
+
+// RateLimit tunes how fast a controller's workqueue hands out objects. Failed
+// reconciles of one object back off exponentially from BaseDelay to MaxDelay, and all
+// objects together are limited to QPS with bursts of Burst. Zero fields use the defaults.
+type RateLimit struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	QPS       float64
+	Burst     int
+}
+
+// newRateLimiter builds the workqueue rate limiter described by l
+func (l RateLimit) newRateLimiter() workqueue.TypedRateLimiter[reconcile.Request] {
+	base, maxDelay := l.BaseDelay, l.MaxDelay
+	if base <= 0 {
+		base = DefaultRateLimiterBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRateLimiterMaxDelay
+	}
+
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](base, maxDelay),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: l.NewLimiter()},
+	)
+}
+
+// NewLimiter returns a token bucket allowing QPS with bursts of Burst, for pacing
+// objects enqueued outside the workqueue rate limiter such as a resync
+func (l RateLimit) NewLimiter() *rate.Limiter {
+	qps, burst := l.QPS, l.Burst
+	if qps <= 0 {
+		qps = DefaultRateLimiterQPS
+	}
+	if burst <= 0 {
+		burst = DefaultRateLimiterBurst
+	}
+	return rate.NewLimiter(rate.Limit(qps), burst)
+}
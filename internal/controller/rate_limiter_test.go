@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRateLimitNewRateLimiter(t *testing.T) {
+	req := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}}
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		limiter := RateLimit{}.newRateLimiter()
+		if got := limiter.When(req("a")); got != DefaultRateLimiterBaseDelay {
+			t.Errorf("first delay = %v, want %v", got, DefaultRateLimiterBaseDelay)
+		}
+	})
+
+	t.Run("exponential backoff capped", func(t *testing.T) {
+		limiter := RateLimit{BaseDelay: time.Second, MaxDelay: 3 * time.Second}.newRateLimiter()
+		var got []time.Duration
+		for range 4 {
+			got = append(got, limiter.When(req("a")))
+		}
+		want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("delays = %v, want %v", got, want)
+			}
+		}
+
+		limiter.Forget(req("a"))
+		if d := limiter.When(req("a")); d != time.Second {
+			t.Errorf("delay after Forget = %v, want %v", d, time.Second)
+		}
+	})
+
+	t.Run("overall qps", func(t *testing.T) {
+		limiter := RateLimit{QPS: 1, Burst: 1}.newRateLimiter()
+		limiter.When(req("a"))
+		// A different object still waits for the shared bucket to refill
+		if d := limiter.When(req("b")); d < 500*time.Millisecond {
+			t.Errorf("delay for second object = %v, want about a second", d)
+		}
+	})
+}
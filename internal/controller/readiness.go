@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultReadyGracePeriod is how long a published Ingress may lose its
+	// load-balancer status before its records are withdrawn
+	DefaultReadyGracePeriod = 5 * time.Minute
+
+	// notReadyRequeue is the fallback poll interval while waiting for an Ingress
+	// to be admitted; status updates also trigger a reconcile directly
+	notReadyRequeue = 30 * time.Second
+)
+
+// ingressReady reports whether the ingress controller has admitted the Ingress
+func ingressReady(ingress *networkingv1.Ingress) bool {
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if lb.IP != "" || lb.Hostname != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// notReadyReason explains why the object's records must not be published yet, or
+// returns "" when they may be
+func (r *IngressReconciler) notReadyReason(ctx context.Context, obj client.Object) (string, error) {
+	if r.RequireReady && !r.src().ready(obj) {
+		return "Load balancer status empty", nil
+	}
+	if requiresReadyEndpoints(obj) {
+		ready, err := r.endpointsReady(ctx, obj)
+		if err != nil {
+			return "", err
+		}
+		if !ready {
+			return "No ready endpoints", nil
+		}
+	}
+	return "", nil
+}
+
+// notReadyTracker remembers when each Ingress was first seen without a
+// load-balancer status, so the grace period survives repeated reconciles
+type notReadyTracker struct {
+	mu    sync.Mutex
+	since map[types.NamespacedName]time.Time
+}
+
+// mark records now as the start of the not-ready period unless one is already running,
+// and returns the start of the current period
+func (t *notReadyTracker) mark(key types.NamespacedName, now time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.since == nil {
+		t.since = make(map[types.NamespacedName]time.Time)
+	}
+	if start, ok := t.since[key]; ok {
+		return start
+	}
+	t.since[key] = now
+	return now
+}
+
+// clear ends the not-ready period for key
+func (t *notReadyTracker) clear(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.since, key)
+}
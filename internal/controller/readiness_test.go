@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestIngressReady(t *testing.T) {
+	tests := []struct {
+		name string
+		lb   []networkingv1.IngressLoadBalancerIngress
+		want bool
+	}{
+		{"no status", nil, false},
+		{"empty entry", []networkingv1.IngressLoadBalancerIngress{{}}, false},
+		{"ip", []networkingv1.IngressLoadBalancerIngress{{IP: "192.168.1.100"}}, true},
+		{"hostname", []networkingv1.IngressLoadBalancerIngress{{Hostname: "lb.local"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := &networkingv1.Ingress{
+				Status: networkingv1.IngressStatus{
+					LoadBalancer: networkingv1.IngressLoadBalancerStatus{Ingress: tt.lb},
+				},
+			}
+			if got := ingressReady(ingress); got != tt.want {
+				t.Errorf("ingressReady() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotReadyTracker(t *testing.T) {
+	var tracker notReadyTracker
+	key := types.NamespacedName{Namespace: "default", Name: "app"}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if got := tracker.mark(key, start); !got.Equal(start) {
+		t.Errorf("mark() = %v, want %v", got, start)
+	}
+	if got := tracker.mark(key, start.Add(time.Minute)); !got.Equal(start) {
+		t.Errorf("mark() second call = %v, want original start %v", got, start)
+	}
+
+	tracker.clear(key)
+	later := start.Add(time.Hour)
+	if got := tracker.mark(key, later); !got.Equal(later) {
+		t.Errorf("mark() after clear = %v, want %v", got, later)
+	}
+}
+
+func TestHandleNotReady(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name        string
+		managed     string
+		grace       time.Duration
+		wantRecord  bool
+		wantManaged string
+	}{
+		{
+			name:        "nothing published yet",
+			managed:     "",
+			grace:       0,
+			wantRecord:  true,
+			wantManaged: "",
+		},
+		{
+			name:        "within grace period",
+			managed:     "app.local",
+			grace:       time.Hour,
+			wantRecord:  true,
+			wantManaged: "app.local",
+		},
+		{
+			name:        "grace period expired",
+			managed:     "app.local",
+			grace:       0,
+			wantRecord:  false,
+			wantManaged: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "app",
+					Namespace: "default",
+					Annotations: map[string]string{
+						AnnotationRegister: "true",
+					},
+				},
+			}
+			if tt.managed != "" {
+				ingress.Annotations[AnnotationManagedHosts] = tt.managed
+			}
+
+			k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(ingress).Build()
+			ph := newFakePiholeClient(pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.100"})
+			r := &IngressReconciler{
+				Client:           k8s,
+				PiholeClient:     ph,
+				Logger:           logger,
+				Recorder:         record.NewFakeRecorder(10),
+				Backoff:          NewBackoff(DefaultBackoffBase, DefaultBackoffMax),
+				RequireReady:     true,
+				ReadyGracePeriod: tt.grace,
+			}
+
+			res, err := r.handleNotReady(context.Background(), ingress, "Load balancer status empty", logger)
+			if err != nil {
+				t.Fatalf("handleNotReady() unexpected error: %v", err)
+			}
+			if res.RequeueAfter <= 0 {
+				t.Errorf("handleNotReady() RequeueAfter = %v, want > 0", res.RequeueAfter)
+			}
+			if got := ph.ip("app.local") != ""; got != tt.wantRecord {
+				t.Errorf("record present = %v, want %v", got, tt.wantRecord)
+			}
+
+			var fresh networkingv1.Ingress
+			if err := k8s.Get(context.Background(), client.ObjectKeyFromObject(ingress), &fresh); err != nil {
+				t.Fatalf("Get() unexpected error: %v", err)
+			}
+			if got := fresh.Annotations[AnnotationManagedHosts]; got != tt.wantManaged {
+				t.Errorf("managed-hosts = %q, want %q", got, tt.wantManaged)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+)
+
+// The phases of a reconcile timed by the reconcile duration histogram
+const (
+	// phaseList lists the records Pi-hole holds
+	phaseList = "list"
+
+	// phaseSync applies the change plan to Pi-hole
+	phaseSync = "sync"
+
+	// phasePrune deletes the records of an object going away
+	phasePrune = "prune"
+
+	// phaseAnnotate writes the managed-hosts annotation
+	phaseAnnotate = "annotate"
+)
+
+// The outcomes counted by the reconcile outcome counter
+const (
+	outcomeSuccess          = "success"
+	outcomeRequeue          = "requeue"
+	outcomeError            = "error"
+	outcomeSkippedNoHosts   = "skipped-no-hosts"
+	outcomeSkippedInvalidIP = "skipped-invalid-ip"
+)
+
+// observePhase records the time since start as the duration of a reconcile phase
+func (r *IngressReconciler) observePhase(phase string, start time.Time) {
+	metrics.ReconcileDuration.WithLabelValues(r.src().kind(), phase).Observe(time.Since(start).Seconds())
+}
+
+// reconcileOutcome classifies a finished reconcile; skipped is the reason it did
+// nothing, if any
+func reconcileOutcome(skipped string, result ctrl.Result, err error) string {
+	switch {
+	case err != nil:
+		return outcomeError
+	case skipped != "":
+		return skipped
+	case result.RequeueAfter > 0:
+		return outcomeRequeue
+	}
+	return outcomeSuccess
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+)
+
+// phaseCount returns how many durations were observed for an Ingress reconcile phase
+func phaseCount(t *testing.T, phase string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.ReconcileDuration.WithLabelValues("Ingress", phase).(prometheus.Histogram).Write(&m); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+// outcomeCount returns how many Ingress reconciles ended with outcome
+func outcomeCount(t *testing.T, outcome string) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.ReconcileOutcomes.WithLabelValues("Ingress", outcome).Write(&m); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestReconcilePhaseDurations(t *testing.T) {
+	before := map[string]uint64{}
+	for _, phase := range []string{phaseList, phaseSync, phasePrune, phaseAnnotate} {
+		before[phase] = phaseCount(t, phase)
+	}
+	observed := func(phase string) uint64 { return phaseCount(t, phase) - before[phase] }
+
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"))
+	reconcileIngress(t, r, "default", "app")
+	for _, phase := range []string{phaseList, phaseSync, phaseAnnotate} {
+		if n := observed(phase); n != 1 {
+			t.Errorf("%s observed %d times after a sync, want 1", phase, n)
+		}
+	}
+	if n := observed(phasePrune); n != 0 {
+		t.Errorf("prune observed %d times after a sync, want 0", n)
+	}
+
+	if err := r.Delete(context.Background(), getIngress(t, r, "default", "app")); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if n := observed(phasePrune); n != 1 {
+		t.Errorf("prune observed %d times after a deletion, want 1", n)
+	}
+	if len(ph.records) != 0 {
+		t.Errorf("records left after delete: %v", ph.records)
+	}
+}
+
+func TestReconcileOutcomes(t *testing.T) {
+	outcomes := []string{outcomeSuccess, outcomeRequeue, outcomeSkippedNoHosts, outcomeSkippedInvalidIP}
+	before := map[string]float64{}
+	for _, outcome := range outcomes {
+		before[outcome] = outcomeCount(t, outcome)
+	}
+
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"),
+		testIngress("empty", map[string]string{AnnotationRegister: "true"}),
+		testIngress("bad-ip", map[string]string{AnnotationRegister: "true", AnnotationTargetIP: "not-an-ip"}, "bad.local"),
+		testIngress("failing", map[string]string{AnnotationRegister: "true"}, "failing.local"),
+	)
+	for _, name := range []string{"app", "empty", "bad-ip"} {
+		reconcileIngress(t, r, "default", name)
+	}
+	ph.err = errors.New("pihole unavailable")
+	reconcileIngress(t, r, "default", "failing")
+
+	for _, outcome := range outcomes {
+		if n := outcomeCount(t, outcome) - before[outcome]; n != 1 {
+			t.Errorf("%s counted %v times, want 1", outcome, n)
+		}
+	}
+}
+
+func TestReconcileOutcome(t *testing.T) {
+	tests := []struct {
+		name    string
+		skipped string
+		result  ctrl.Result
+		err     error
+		want    string
+	}{
+		{name: "done", want: outcomeSuccess},
+		{name: "requeued", result: ctrl.Result{RequeueAfter: time.Minute}, want: outcomeRequeue},
+		{name: "failed", err: errors.New("boom"), want: outcomeError},
+		{name: "skipped", skipped: outcomeSkippedNoHosts, want: outcomeSkippedNoHosts},
+		{name: "error wins", skipped: outcomeSkippedNoHosts, err: errors.New("boom"), want: outcomeError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reconcileOutcome(tt.skipped, tt.result, tt.err); got != tt.want {
+				t.Errorf("reconcileOutcome() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
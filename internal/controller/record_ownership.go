@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+// lastSyncedResolution bounds how often a record's LastSyncedAt is written to the
+// registry, so a resync of a large cluster doesn't rewrite it for every object
+const lastSyncedResolution = 10 * time.Minute
+
+// ownership identifies obj as the owner of a record in the registry
+func (r *IngressReconciler) ownership(obj client.Object) registry.Ownership {
+	return registry.Ownership{
+		Kind:      r.src().kind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		UID:       string(obj.GetUID()),
+		ClusterID: r.ClusterID,
+	}
+}
+
+// recordOwnership updates the registry after a sync of obj: the records the applied
+// plan created are recorded as new, every tracked record as synced and the deleted
+// ones are forgotten. Failures are logged but never fail the sync.
+func (r *IngressReconciler) recordOwnership(ctx context.Context, obj client.Object, applied Plan, tracked []string, current []pihole.DNSRecord, logger *slog.Logger) {
+	if r.Registry == nil {
+		return
+	}
+	ips := make(map[string]string, len(current))
+	for _, rec := range current {
+		ips[rec.Key()] = rec.IP
+	}
+
+	owner := r.ownership(obj)
+	now := time.Now().UTC()
+	if err := r.Registry.Update(ctx, func(st *registry.State) bool {
+		changed := st.ForgetRecords(recordKeys(applied.Deletes))
+		created := make(map[string]bool, len(applied.Creates)+len(applied.Updates))
+		for _, rec := range applied.Creates {
+			owner.TargetIP = rec.IP
+			st.RecordCreated(rec.Key(), owner, now)
+			created[rec.Key()] = true
+		}
+		// Pi-hole has no in-place update, so an updated record is a new one; one
+		// rewritten only to mark its cluster is not
+		for _, u := range applied.Updates {
+			if u.OldIP == u.NewIP {
+				continue
+			}
+			owner.TargetIP = u.NewIP
+			st.RecordCreated(u.Key(), owner, now)
+			created[u.Key()] = true
+		}
+		owner.TargetIP = ""
+		for _, key := range tracked {
+			if !created[key] && st.RecordSynced(key, owner, ips[key], now, lastSyncedResolution) {
+				changed = true
+			}
+		}
+		return changed || len(created) > 0
+	}); err != nil {
+		logger.Error("failed to record ownership", "error", err)
+	}
+}
+
+// announceRemovals emits an Event for every record of obj about to be removed, naming
+// when and by whom it was created
+func (r *IngressReconciler) announceRemovals(ctx context.Context, obj client.Object, keys []string, logger *slog.Logger) {
+	if r.Registry == nil || len(keys) == 0 {
+		return
+	}
+	state, err := r.Registry.Get(ctx)
+	if err != nil {
+		logger.Error("failed to read registry", "error", err)
+		return
+	}
+	for _, key := range keys {
+		owner, ok := state.Records[key]
+		if !ok {
+			owner = r.ownership(obj)
+		}
+		r.Recorder.Eventf(obj, corev1.EventTypeNormal, "RecordRemoved", "removing record %s %s", key, owner)
+	}
+}
+
+// forgetRecords drops the ownership of deleted records from the registry
+func forgetRecords(ctx context.Context, store *registry.Store, keys []string, logger *slog.Logger) {
+	if store == nil || len(keys) == 0 {
+		return
+	}
+	if err := store.Update(ctx, func(st *registry.State) bool {
+		return st.ForgetRecords(keys)
+	}); err != nil {
+		logger.Error("failed to record ownership", "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/registry"
+)
+
+func TestReconcileRecordsOwnership(t *testing.T) {
+	ph := newFakePiholeClient()
+	ingress := testIngress("jellyfin", map[string]string{AnnotationRegister: "true"}, "jellyfin.home.lan")
+	ingress.UID = "uid-1"
+	r := newTestReconciler(ph, ingress)
+	r.Registry = registry.NewStore(r.Client, r.Client, "pihole-operator", "registry")
+
+	before := time.Now().UTC()
+	reconcileIngress(t, r, "default", "jellyfin")
+	state, err := r.Registry.Get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := state.Records["jellyfin.home.lan"]
+	if !ok {
+		t.Fatalf("ownership not recorded: %+v", state)
+	}
+	if got.Kind != "Ingress" || got.Namespace != "default" || got.Name != "jellyfin" || got.UID != "uid-1" ||
+		got.TargetIP != "192.168.1.100" || got.CreatedAt.Before(before) || !got.LastSyncedAt.Equal(got.CreatedAt) {
+		t.Errorf("ownership = %+v", got)
+	}
+
+	records, err := r.OwnedRecords(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Ownership == nil || !records[0].Ownership.CreatedAt.Equal(got.CreatedAt) {
+		t.Errorf("owned records = %+v, want the recorded ownership", records)
+	}
+
+	// Removing the host removes the record with an Event naming its creation
+	current := getIngress(t, r, "default", "jellyfin")
+	current.Spec.Rules[0].Host = "media.home.lan"
+	if err := r.Update(t.Context(), current); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "jellyfin")
+	want := "removing record jellyfin.home.lan created " + got.CreatedAt.Format(time.DateOnly) +
+		" by ingress default/jellyfin"
+	if !hasEvent(r, want) {
+		t.Errorf("no RecordRemoved event %q", want)
+	}
+	state, err = r.Registry.Get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state.Records["jellyfin.home.lan"]; ok {
+		t.Error("ownership of the removed record kept")
+	}
+	if _, ok := state.Records["media.home.lan"]; !ok {
+		t.Error("ownership of the new record not recorded")
+	}
+}
+
+func TestReconcileUpgradesUntrackedOwnership(t *testing.T) {
+	// Tracked by a version that recorded no ownership
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "app.home.lan", IP: "192.168.1.100"})
+	ingress := testIngress("app", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationManagedHosts: "app.home.lan",
+	}, "app.home.lan")
+	r := newTestReconciler(ph, ingress)
+	r.Registry = registry.NewStore(r.Client, r.Client, "pihole-operator", "registry")
+
+	reconcileIngress(t, r, "default", "app")
+	state, err := r.Registry.Get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := state.Records["app.home.lan"]
+	if !ok {
+		t.Fatalf("ownership not upgraded: %+v", state)
+	}
+	if !got.CreatedAt.IsZero() || got.LastSyncedAt.IsZero() || got.TargetIP != "192.168.1.100" || got.Name != "app" {
+		t.Errorf("upgraded ownership = %+v, want no creation time", got)
+	}
+
+	// Deleting the Ingress names its owner without a date
+	current := getIngress(t, r, "default", "app")
+	if err := r.Delete(t.Context(), current); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if want := "removing record app.home.lan created by ingress default/app"; !hasEvent(r, want) {
+		t.Errorf("no RecordRemoved event %q", want)
+	}
+}
+
+// hasEvent drains the recorded events, reporting whether one contains message
+func hasEvent(r *IngressReconciler, message string) bool {
+	found := false
+	for {
+		select {
+		case event := <-r.Recorder.(*record.FakeRecorder).Events:
+			if strings.Contains(event, message) {
+				found = true
+			}
+		default:
+			return found
+		}
+	}
+}
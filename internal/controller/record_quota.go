@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// quotaRequeue is how soon an object with refused records tries again, in case
+// other objects have freed quota in the meantime
+const quotaRequeue = time.Minute
+
+// RecordQuota caps the records the operator manages: MaxRecords across every object
+// and PerNamespace for the objects of each namespace, counting a record wanted by
+// several objects once. Usage is taken from the desired index shared by every source,
+// which Admit updates under the quota's lock so concurrent reconciles cannot overshoot.
+// Records an object already holds, those in the index or in its managed-hosts
+// annotation, stay admitted when a limit is lowered, including after a restart has
+// emptied the index; only new records are refused. Zero limits are unlimited, and a nil quota admits everything.
+type RecordQuota struct {
+	MaxRecords   int
+	PerNamespace int
+
+	mu sync.Mutex
+}
+
+// Admit stores in index the records of owner that fit the quota and returns them,
+// along with those refused. managed holds the record keys owner already manages in
+// Pi-hole, which count as held like those in the index.
+func (q *RecordQuota) Admit(index *DesiredIndex, owner string, records []pihole.DNSRecord, managed []string) (admitted, refused []pihole.DNSRecord) {
+	if q == nil || (q.MaxRecords <= 0 && q.PerNamespace <= 0) {
+		index.Set(owner, records)
+		return records, nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	all, inNamespace := index.usage(owner)
+	held := make(map[string]bool)
+	for _, rec := range index.Get(owner) {
+		held[rec.Key()] = true
+	}
+	for _, key := range managed {
+		held[key] = true
+	}
+
+	// Records already held are charged first, so they are never displaced by new ones
+	fits := make(map[string]bool, len(records))
+	charge := func(key string) {
+		fits[key] = true
+		all[key] = true
+		inNamespace[key] = true
+	}
+	for _, rec := range records {
+		if held[rec.Key()] {
+			charge(rec.Key())
+		}
+	}
+	for _, rec := range records {
+		key := rec.Key()
+		if fits[key] {
+			continue
+		}
+		if (q.MaxRecords > 0 && !all[key] && len(all) >= q.MaxRecords) ||
+			(q.PerNamespace > 0 && !inNamespace[key] && len(inNamespace) >= q.PerNamespace) {
+			continue
+		}
+		charge(key)
+	}
+
+	for _, rec := range records {
+		if fits[rec.Key()] {
+			admitted = append(admitted, rec)
+		} else {
+			refused = append(refused, rec)
+		}
+	}
+	index.Set(owner, admitted)
+	return admitted, refused
+}
+
+// usage returns the record keys wanted by owners other than owner, overall and by
+// those in owner's namespace
+func (x *DesiredIndex) usage(owner string) (all, inNamespace map[string]bool) {
+	all, inNamespace = make(map[string]bool), make(map[string]bool)
+	if x == nil {
+		return all, inNamespace
+	}
+	namespace := ownerNamespace(owner)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	for key, targets := range x.byKey {
+		for other := range targets {
+			if other == owner {
+				continue
+			}
+			all[key] = true
+			if ownerNamespace(other) == namespace {
+				inNamespace[key] = true
+			}
+		}
+	}
+	return all, inNamespace
+}
+
+// ownerNamespace returns the namespace of an owner as formatted by ownerOf, "Kind namespace/name"
+func ownerNamespace(owner string) string {
+	_, key, _ := strings.Cut(owner, " ")
+	namespace, _, _ := strings.Cut(key, "/")
+	return namespace
+}
+
+// admitRecords applies the record quota to the records an object wants and stores
+// the admitted ones in the index. Refused records are reported and left out, so they
+// are neither created nor tracked; the returned flag asks for a later retry. Records
+// in managed are already held and always admitted.
+func (r *IngressReconciler) admitRecords(obj client.Object, desired []pihole.DNSRecord, managed []string, logger *slog.Logger) ([]pihole.DNSRecord, bool) {
+	admitted, refused := r.Quota.Admit(r.Index, r.ownerOf(obj), desired, managed)
+	if len(refused) == 0 {
+		return admitted, false
+	}
+	hosts := recordKeys(refused)
+	logger.Warn("dns records refused, record quota exceeded", "hosts", strings.Join(hosts, ","),
+		"max_records", r.Quota.MaxRecords, "per_namespace", r.Quota.PerNamespace)
+	r.Recorder.Eventf(obj, corev1.EventTypeWarning, "RecordQuotaExceeded",
+		"Not registering %s: the record quota is exhausted", strings.Join(hosts, ", "))
+	return admitted, true
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestRecordQuotaAdmit(t *testing.T) {
+	index := &DesiredIndex{}
+	q := &RecordQuota{MaxRecords: 4, PerNamespace: 2}
+	rec := func(domain string) pihole.DNSRecord { return pihole.DNSRecord{Domain: domain, IP: "10.0.0.1"} }
+
+	admitted, refused := q.Admit(index, "Ingress a/one", []pihole.DNSRecord{rec("a1.local"), rec("a2.local"), rec("a3.local")}, nil)
+	if len(admitted) != 2 || len(refused) != 1 || refused[0].Domain != "a3.local" {
+		t.Fatalf("Admit() = %v, %v, want two admitted and a3.local refused by the namespace quota", admitted, refused)
+	}
+
+	// A record another object in the namespace already wants costs nothing
+	admitted, _ = q.Admit(index, "Ingress a/two", []pihole.DNSRecord{rec("a1.local")}, nil)
+	if len(admitted) != 1 {
+		t.Errorf("Admit() shared record = %v, want it admitted", admitted)
+	}
+
+	admitted, refused = q.Admit(index, "Ingress b/one", []pihole.DNSRecord{rec("b1.local"), rec("b2.local"), rec("b3.local")}, nil)
+	if len(admitted) != 2 || len(refused) != 1 {
+		t.Fatalf("Admit() = %v, %v, want two admitted within the namespace quota", admitted, refused)
+	}
+	if _, refused = q.Admit(index, "Ingress c/one", []pihole.DNSRecord{rec("c1.local")}, nil); len(refused) != 1 {
+		t.Errorf("Admit() refused %v, want c1.local refused by the global quota", refused)
+	}
+
+	// Held records stay admitted after the limits are lowered, ahead of new ones
+	q.MaxRecords, q.PerNamespace = 1, 1
+	admitted, refused = q.Admit(index, "Ingress b/one", []pihole.DNSRecord{rec("b0.local"), rec("b1.local"), rec("b2.local")}, nil)
+	if len(admitted) != 2 || len(refused) != 1 || refused[0].Domain != "b0.local" {
+		t.Errorf("Admit() = %v, %v, want b1 and b2 kept and b0 refused", admitted, refused)
+	}
+
+	var unlimited *RecordQuota
+	if admitted, refused := unlimited.Admit(index, "Ingress d/one", []pihole.DNSRecord{rec("d1.local")}, nil); len(admitted) != 1 || refused != nil {
+		t.Errorf("nil quota Admit() = %v, %v, want everything admitted", admitted, refused)
+	}
+	if len(index.Get("Ingress d/one")) != 1 {
+		t.Error("nil quota did not store the records in the index")
+	}
+}
+
+func TestReconcileRecordQuota(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		testIngress("first", map[string]string{AnnotationRegister: "true"}, "one.local", "two.local"),
+		testIngress("second", map[string]string{AnnotationRegister: "true"}, "three.local"),
+	)
+	r.Index = &DesiredIndex{}
+	r.Quota = &RecordQuota{PerNamespace: 2}
+
+	reconcileIngress(t, r, "default", "first")
+	res := reconcileIngress(t, r, "default", "second")
+	if ph.ip("one.local") == "" || ph.ip("two.local") == "" {
+		t.Error("records within the quota not created")
+	}
+	if ph.ip("three.local") != "" {
+		t.Error("record beyond the quota created")
+	}
+	if res.RequeueAfter != quotaRequeue {
+		t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, quotaRequeue)
+	}
+	select {
+	case event := <-r.Recorder.(*record.FakeRecorder).Events:
+		if !strings.Contains(event, "RecordQuotaExceeded") {
+			t.Errorf("event = %q, want RecordQuotaExceeded", event)
+		}
+	default:
+		t.Error("no event for the refused record")
+	}
+
+	// Deleting the first Ingress frees the quota for the second
+	if err := r.Delete(t.Context(), getIngress(t, r, "default", "first")); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "first")
+	reconcileIngress(t, r, "default", "second")
+	if ph.ip("three.local") == "" {
+		t.Error("record not created once quota was freed")
+	}
+}
+
+func TestReconcileRecordQuotaAfterRestart(t *testing.T) {
+	// A restarted operator starts with an empty index; the records an Ingress already
+	// manages must survive a limit lowered below them
+	ph := newFakePiholeClient(
+		pihole.DNSRecord{Domain: "one.local", IP: "192.168.1.100"},
+		pihole.DNSRecord{Domain: "two.local", IP: "192.168.1.100"},
+	)
+	r := newTestReconciler(ph, testIngress("first", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationManagedHosts: "one.local,two.local",
+	}, "one.local", "two.local", "three.local"))
+	r.Index = &DesiredIndex{}
+	r.Quota = &RecordQuota{PerNamespace: 1}
+
+	reconcileIngress(t, r, "default", "first")
+	if ph.ip("one.local") == "" || ph.ip("two.local") == "" {
+		t.Errorf("held records deleted after a restart: %v", ph.records)
+	}
+	if ph.ip("three.local") != "" {
+		t.Error("new record beyond the quota created")
+	}
+	if managed := getIngress(t, r, "default", "first").Annotations[AnnotationManagedHosts]; managed != "one.local,two.local" {
+		t.Errorf("managed hosts = %q, want the held records kept", managed)
+	}
+}
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// AnnotationRecordsHash holds a hash of the records ConfigMap data, so watchers can
+	// tell whether the records changed without reading them
+	AnnotationRecordsHash = "pihole.io/records-hash"
+
+	// RecordsHostsKey and RecordsJSONKey are the records ConfigMap data keys holding
+	// the records as a hosts file and as JSON
+	RecordsHostsKey = "hosts"
+	RecordsJSONKey  = "records.json"
+
+	// DefaultRecordsMirrorInterval is the least time between two records ConfigMap writes
+	DefaultRecordsMirrorInterval = 10 * time.Second
+
+	// recordsMirrorDebounce gathers the changes of a burst of reconciles into one write
+	recordsMirrorDebounce = time.Second
+)
+
+// MirroredRecord is one record in the JSON of the records ConfigMap
+type MirroredRecord struct {
+	Domain string   `json:"domain"`
+	Type   string   `json:"type"`
+	IP     string   `json:"ip"`
+	Owners []string `json:"owners"`
+}
+
+// RecordsMirror keeps a ConfigMap listing every managed record, for tools that should
+// not talk to the Pi-hole API. Reconciles and the drift monitor report changes; writes
+// follow a short debounce, are at least Interval apart and are skipped when the
+// records are unchanged. A failed write is retried after Interval.
+type RecordsMirror struct {
+	client   client.Client
+	reader   client.Reader
+	key      types.NamespacedName
+	interval time.Duration
+	logger   *slog.Logger
+	changed  chan struct{}
+
+	// Sources are the reconcilers whose managed records are mirrored
+	Sources []*IngressReconciler
+}
+
+// NewRecordsMirror creates a RecordsMirror writing the named ConfigMap. reader should
+// be an uncached reader so the operator doesn't need to watch ConfigMaps.
+func NewRecordsMirror(c client.Client, reader client.Reader, namespace, name string, interval time.Duration, logger *slog.Logger) *RecordsMirror {
+	if interval <= 0 {
+		interval = DefaultRecordsMirrorInterval
+	}
+	return &RecordsMirror{
+		client:   c,
+		reader:   reader,
+		key:      types.NamespacedName{Namespace: namespace, Name: name},
+		interval: interval,
+		logger:   logger,
+		changed:  make(chan struct{}, 1),
+	}
+}
+
+// Changed schedules a write of the ConfigMap. It never blocks and is a no-op on a nil
+// RecordsMirror.
+func (m *RecordsMirror) Changed() {
+	if m == nil {
+		return
+	}
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Start writes the ConfigMap after each reported change until ctx is cancelled; it
+// implements manager.Runnable
+func (m *RecordsMirror) Start(ctx context.Context) error {
+	m.Changed()
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-m.changed:
+		}
+
+		wait := recordsMirrorDebounce
+		if next := time.Until(last.Add(m.interval)); next > wait {
+			wait = next
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		// Changes reported while waiting are part of this write
+		select {
+		case <-m.changed:
+		default:
+		}
+
+		last = time.Now()
+		if err := m.publish(ctx); err != nil {
+			// Retried after the interval, so a conflict or an apiserver hiccup does not
+			// leave the ConfigMap stale until the records change again
+			m.logger.Error("failed to write records configmap", "namespace", m.key.Namespace, "name", m.key.Name, "error", err)
+			m.Changed()
+		}
+	}
+}
+
+// NeedLeaderElection ensures only the leader, whose controllers run, writes the ConfigMap
+func (m *RecordsMirror) NeedLeaderElection() bool {
+	return true
+}
+
+// publish writes the current records to the ConfigMap unless its hash shows them
+// unchanged
+func (m *RecordsMirror) publish(ctx context.Context) error {
+	var owned []OwnedRecord
+	for _, r := range m.Sources {
+		records, err := r.OwnedRecords(ctx)
+		if err != nil {
+			return err
+		}
+		owned = append(owned, records...)
+	}
+	data, err := mirrorData(owned)
+	if err != nil {
+		return err
+	}
+	hash := mirrorHash(data)
+
+	cm := &corev1.ConfigMap{}
+	exists := true
+	if err := m.reader.Get(ctx, m.key, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		exists = false
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      m.key.Name,
+				Namespace: m.key.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/name":       "pihole-ingress-operator",
+					"app.kubernetes.io/managed-by": "pihole-ingress-operator",
+				},
+			},
+		}
+	}
+	if exists && cm.Annotations[AnnotationRecordsHash] == hash {
+		return nil
+	}
+
+	if cm.Annotations == nil {
+		cm.Annotations = make(map[string]string)
+	}
+	cm.Annotations[AnnotationRecordsHash] = hash
+	cm.Data = data
+	if !exists {
+		err = m.client.Create(ctx, cm)
+	} else {
+		err = m.client.Update(ctx, cm)
+	}
+	if err != nil {
+		return err
+	}
+	m.logger.Debug("records configmap written", "namespace", m.key.Namespace, "name", m.key.Name, "hash", hash)
+	return nil
+}
+
+// mirrorData renders owned as the records ConfigMap data: a hosts file of "IP domain"
+// lines and a JSON list with the owners of each record. Records are sorted by domain
+// and type; those without a known IP are left out.
+func mirrorData(owned []OwnedRecord) (map[string]string, error) {
+	var records []MirroredRecord
+	byKey := make(map[string]int)
+	for _, rec := range owned {
+		if rec.TargetIP == "" {
+			continue
+		}
+		key := rec.Domain + " " + rec.Type + " " + rec.TargetIP
+		i, ok := byKey[key]
+		if !ok {
+			i = len(records)
+			byKey[key] = i
+			records = append(records, MirroredRecord{Domain: rec.Domain, Type: rec.Type, IP: rec.TargetIP})
+		}
+		if !slices.Contains(records[i].Owners, rec.Owner) {
+			records[i].Owners = append(records[i].Owners, rec.Owner)
+		}
+	}
+	slices.SortFunc(records, func(a, b MirroredRecord) int {
+		if c := strings.Compare(a.Domain, b.Domain); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Type, b.Type); c != 0 {
+			return c
+		}
+		return strings.Compare(a.IP, b.IP)
+	})
+
+	var hosts strings.Builder
+	for i := range records {
+		slices.Sort(records[i].Owners)
+		fmt.Fprintf(&hosts, "%s %s\n", records[i].IP, records[i].Domain)
+	}
+	if records == nil {
+		records = []MirroredRecord{}
+	}
+	encoded, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding records: %w", err)
+	}
+	return map[string]string{RecordsHostsKey: hosts.String(), RecordsJSONKey: string(encoded) + "\n"}, nil
+}
+
+// mirrorHash returns the hex SHA-256 of the records ConfigMap data
+func mirrorHash(data map[string]string) string {
+	h := sha256.New()
+	for _, key := range []string{RecordsHostsKey, RecordsJSONKey} {
+		h.Write([]byte(key + "\x00" + data[key] + "\x00"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,221 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestMirrorData(t *testing.T) {
+	data, err := mirrorData([]OwnedRecord{
+		{Domain: "b.home.lan", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/b"},
+		{Domain: "a.home.lan", Type: "AAAA", TargetIP: "fd00::10", Owner: "Ingress default/a"},
+		{Domain: "a.home.lan", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress media/a"},
+		{Domain: "a.home.lan", Type: "A", TargetIP: "192.168.1.100", Owner: "Ingress default/a"},
+		{Domain: "waiting.home.lan", Type: "A", Owner: "Ingress default/waiting"},
+	})
+	if err != nil {
+		t.Fatalf("mirrorData() unexpected error: %v", err)
+	}
+
+	wantHosts := "192.168.1.100 a.home.lan\nfd00::10 a.home.lan\n192.168.1.100 b.home.lan\n"
+	if data[RecordsHostsKey] != wantHosts {
+		t.Errorf("hosts = %q, want %q", data[RecordsHostsKey], wantHosts)
+	}
+	wantJSON := `[
+  {
+    "domain": "a.home.lan",
+    "type": "A",
+    "ip": "192.168.1.100",
+    "owners": [
+      "Ingress default/a",
+      "Ingress media/a"
+    ]
+  },
+  {
+    "domain": "a.home.lan",
+    "type": "AAAA",
+    "ip": "fd00::10",
+    "owners": [
+      "Ingress default/a"
+    ]
+  },
+  {
+    "domain": "b.home.lan",
+    "type": "A",
+    "ip": "192.168.1.100",
+    "owners": [
+      "Ingress default/b"
+    ]
+  }
+]
+`
+	if data[RecordsJSONKey] != wantJSON {
+		t.Errorf("json = %s, want %s", data[RecordsJSONKey], wantJSON)
+	}
+
+	empty, err := mirrorData(nil)
+	if err != nil || empty[RecordsHostsKey] != "" || empty[RecordsJSONKey] != "[]\n" {
+		t.Errorf("mirrorData(nil) = %q, %v, want an empty list", empty, err)
+	}
+}
+
+// getRecordsConfigMap fetches the records ConfigMap written by a test mirror
+func getRecordsConfigMap(t *testing.T, r *IngressReconciler) *corev1.ConfigMap {
+	t.Helper()
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(t.Context(), types.NamespacedName{Namespace: "pihole-operator", Name: "records"}, cm); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	return cm
+}
+
+func TestRecordsMirrorPublish(t *testing.T) {
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.home.lan")
+	r := newTestReconciler(newFakePiholeClient(), ingress)
+	reconcileIngress(t, r, "default", "app")
+
+	m := NewRecordsMirror(r.Client, r.Client, "pihole-operator", "records", 0, r.Logger)
+	m.Sources = []*IngressReconciler{r}
+	if err := m.publish(t.Context()); err != nil {
+		t.Fatalf("publish() unexpected error: %v", err)
+	}
+	cm := getRecordsConfigMap(t, r)
+	if cm.Data[RecordsHostsKey] != "192.168.1.100 app.home.lan\n" {
+		t.Errorf("hosts = %q", cm.Data[RecordsHostsKey])
+	}
+	hash := cm.Annotations[AnnotationRecordsHash]
+	if hash != mirrorHash(cm.Data) {
+		t.Errorf("hash annotation = %q, want the hash of the data", hash)
+	}
+
+	// Unchanged records are not written again
+	if err := m.publish(t.Context()); err != nil {
+		t.Fatalf("publish() unexpected error: %v", err)
+	}
+	if got := getRecordsConfigMap(t, r); got.ResourceVersion != cm.ResourceVersion {
+		t.Errorf("resourceVersion = %s, want %s with nothing changed", got.ResourceVersion, cm.ResourceVersion)
+	}
+
+	current := getIngress(t, r, "default", "app")
+	current.Annotations[AnnotationExtraHosts] = "alias.home.lan"
+	if err := r.Update(t.Context(), current); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if err := m.publish(t.Context()); err != nil {
+		t.Fatalf("publish() unexpected error: %v", err)
+	}
+	cm = getRecordsConfigMap(t, r)
+	if cm.Data[RecordsHostsKey] != "192.168.1.100 alias.home.lan\n192.168.1.100 app.home.lan\n" {
+		t.Errorf("hosts = %q, want the alias added", cm.Data[RecordsHostsKey])
+	}
+	if cm.Annotations[AnnotationRecordsHash] == hash {
+		t.Error("hash annotation unchanged after the records changed")
+	}
+}
+
+func TestRecordsMirrorRateLimited(t *testing.T) {
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.home.lan")
+	r := newTestReconciler(newFakePiholeClient(), ingress)
+	reconcileIngress(t, r, "default", "app")
+
+	var writes atomic.Int32
+	c := interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			writes.Add(1)
+			return c.Create(ctx, obj, opts...)
+		},
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			writes.Add(1)
+			return c.Update(ctx, obj, opts...)
+		},
+	})
+	m := NewRecordsMirror(c, c, "pihole-operator", "records", time.Hour, r.Logger)
+	m.Sources = []*IngressReconciler{r}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = m.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// A burst of changes is written once
+	for range 5 {
+		m.Changed()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for writes.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if writes.Load() != 1 {
+		t.Fatalf("writes = %d, want 1 after the debounce", writes.Load())
+	}
+
+	// Within the interval, further changes wait
+	current := getIngress(t, r, "default", "app")
+	current.Annotations[AnnotationExtraHosts] = "alias.home.lan"
+	if err := r.Update(t.Context(), current); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	m.Changed()
+	time.Sleep(recordsMirrorDebounce + 200*time.Millisecond)
+	if writes.Load() != 1 {
+		t.Errorf("writes = %d, want 1 within the interval", writes.Load())
+	}
+}
+
+func TestRecordsMirrorRetriesFailedWrite(t *testing.T) {
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.home.lan")
+	r := newTestReconciler(newFakePiholeClient(), ingress)
+	reconcileIngress(t, r, "default", "app")
+
+	var attempts atomic.Int32
+	c := interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if attempts.Add(1) == 1 {
+				return errors.New("apiserver unavailable")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	})
+	m := NewRecordsMirror(c, c, "pihole-operator", "records", 100*time.Millisecond, r.Logger)
+	m.Sources = []*IngressReconciler{r}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = m.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Nothing reports a change after the failure; the mirror retries on its own
+	key := types.NamespacedName{Namespace: "pihole-operator", Name: "records"}
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Get(t.Context(), key, &corev1.ConfigMap{}) != nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if attempts.Load() != 2 {
+		t.Fatalf("%d write attempts, want the failed write retried once", attempts.Load())
+	}
+	if cm := getRecordsConfigMap(t, r); cm.Data[RecordsHostsKey] != "192.168.1.100 app.home.lan\n" {
+		t.Errorf("hosts = %q after the retry", cm.Data[RecordsHostsKey])
+	}
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"log/slog"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnnotationRegisterPair lists prefixes, such as "www", whose variant of every host
+// is registered with it: www.foo.home.lan for foo.home.lan, and foo.home.lan for a
+// host that already is www.foo.home.lan
+const AnnotationRegisterPair = "pihole.io/register-pair"
+
+// pairPrefixes returns the prefixes of AnnotationRegisterPair that are DNS labels and
+// those that are not
+func pairPrefixes(obj client.Object) (valid, invalid []string) {
+	for _, prefix := range parseCommaSeparated(obj.GetAnnotations()[AnnotationRegisterPair]) {
+		prefix = strings.ToLower(strings.Trim(prefix, "."))
+		if len(validation.IsDNS1123Label(prefix)) > 0 {
+			invalid = append(invalid, prefix)
+			continue
+		}
+		valid = append(valid, prefix)
+	}
+	return valid, invalid
+}
+
+// withPairs returns hosts followed by their variant for each prefix: the prefixed
+// name, or the apex for a host that carries the prefix. Wildcards get no variant,
+// and neither does a host whose apex would be a single label.
+func withPairs(hosts, prefixes []string) []string {
+	paired := append([]string{}, hosts...)
+	for _, host := range hosts {
+		if strings.HasPrefix(host, "*") {
+			continue
+		}
+		for _, prefix := range prefixes {
+			apex, prefixed := strings.CutPrefix(strings.ToLower(host), prefix+".")
+			switch {
+			case !prefixed:
+				paired = append(paired, prefix+"."+host)
+			case strings.Contains(apex, "."):
+				paired = append(paired, host[len(prefix)+1:])
+			}
+		}
+	}
+	return paired
+}
+
+// warnInvalidPairPrefixes reports the prefixes of AnnotationRegisterPair that are
+// ignored because they are not DNS labels
+func (r *IngressReconciler) warnInvalidPairPrefixes(obj client.Object, logger *slog.Logger) {
+	_, invalid := pairPrefixes(obj)
+	if len(invalid) == 0 {
+		return
+	}
+	value := obj.GetAnnotations()[AnnotationRegisterPair]
+	logger.Warn("invalid annotation", "annotation", AnnotationRegisterPair, "value", value,
+		"error", "not a DNS label", "prefixes", strings.Join(invalid, ","))
+	r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+		"%s: %s is not a DNS label; ignoring it", AnnotationRegisterPair, strings.Join(invalid, ","))
+}
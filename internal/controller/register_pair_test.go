@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+)
+
+func TestWithPairs(t *testing.T) {
+	tests := []struct {
+		name     string
+		hosts    []string
+		prefixes []string
+		want     []string
+	}{
+		{
+			name:     "apex gains the prefixed name",
+			hosts:    []string{"foo.home.lan"},
+			prefixes: []string{"www"},
+			want:     []string{"foo.home.lan", "www.foo.home.lan"},
+		},
+		{
+			name:     "prefixed host gains its apex",
+			hosts:    []string{"www.foo.home.lan"},
+			prefixes: []string{"www"},
+			want:     []string{"www.foo.home.lan", "foo.home.lan"},
+		},
+		{
+			name:     "several prefixes",
+			hosts:    []string{"foo.home.lan"},
+			prefixes: []string{"www", "m"},
+			want:     []string{"foo.home.lan", "www.foo.home.lan", "m.foo.home.lan"},
+		},
+		{
+			name:     "apex of a single label is not paired",
+			hosts:    []string{"www.lan"},
+			prefixes: []string{"www"},
+			want:     []string{"www.lan"},
+		},
+		{
+			name:     "wildcards are not paired",
+			hosts:    []string{"*.home.lan"},
+			prefixes: []string{"www"},
+			want:     []string{"*.home.lan"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withPairs(tt.hosts, tt.prefixes); !slices.Equal(got, tt.want) {
+				t.Errorf("withPairs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileRegisterPair(t *testing.T) {
+	ph := newFakePiholeClient()
+	ingress := testIngress("app", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationRegisterPair: "www",
+	}, "foo.home.lan", "www.bar.home.lan")
+	r := newTestReconciler(ph, ingress)
+
+	reconcileIngress(t, r, "default", "app")
+	for _, host := range []string{"foo.home.lan", "www.foo.home.lan", "www.bar.home.lan", "bar.home.lan"} {
+		if ph.ip(host) != "192.168.1.100" {
+			t.Errorf("%s not registered: %v", host, ph.records)
+		}
+	}
+	managed := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]
+	if !strings.Contains(managed, "www.foo.home.lan") || !strings.Contains(managed, "bar.home.lan") {
+		t.Errorf("managed hosts = %q, want the generated variants tracked", managed)
+	}
+
+	// Dropping the annotation prunes only the generated variants
+	current := getIngress(t, r, "default", "app")
+	delete(current.Annotations, AnnotationRegisterPair)
+	if err := r.Update(t.Context(), current); err != nil {
+		t.Fatal(err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("www.foo.home.lan") != "" || ph.ip("bar.home.lan") != "" {
+		t.Errorf("generated variants kept: %v", ph.records)
+	}
+	if ph.ip("foo.home.lan") == "" || ph.ip("www.bar.home.lan") == "" {
+		t.Errorf("spec hosts pruned: %v", ph.records)
+	}
+}
+
+func TestReconcileRegisterPairInvalidPrefix(t *testing.T) {
+	ph := newFakePiholeClient()
+	ingress := testIngress("app", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationRegisterPair: "www,not_a_label",
+	}, "foo.home.lan")
+	r := newTestReconciler(ph, ingress)
+
+	reconcileIngress(t, r, "default", "app")
+	if ph.ip("www.foo.home.lan") == "" {
+		t.Error("valid prefix not applied")
+	}
+	if len(ph.records) != 2 {
+		t.Errorf("records = %v, want the host and its www variant", ph.records)
+	}
+	select {
+	case event := <-r.Recorder.(*record.FakeRecorder).Events:
+		if !strings.Contains(event, "InvalidAnnotation") || !strings.Contains(event, "not_a_label") {
+			t.Errorf("event = %q, want an InvalidAnnotation warning naming the prefix", event)
+		}
+	default:
+		t.Error("no event for the invalid prefix")
+	}
+}
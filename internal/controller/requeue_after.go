@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnnotationRequeueAfter asks for an object to be resynced this long after each
+// successful reconcile, such as one whose target changes often
+const AnnotationRequeueAfter = "pihole.io/requeue-after"
+
+// DefaultMinRequeueAfter is the shortest interval AnnotationRequeueAfter may request, so
+// that no object can make the operator hammer the Pi-hole API
+const DefaultMinRequeueAfter = 10 * time.Second
+
+// requeueAfter returns the resync interval requested via annotation, raised to the
+// minimum, or zero when there is none. Invalid values are reported and ignored.
+func (r *IngressReconciler) requeueAfter(obj client.Object, logger *slog.Logger) time.Duration {
+	value := obj.GetAnnotations()[AnnotationRequeueAfter]
+	if value == "" {
+		return 0
+	}
+
+	every, err := time.ParseDuration(value)
+	if err != nil || every <= 0 {
+		logger.Warn("invalid annotation", "annotation", AnnotationRequeueAfter,
+			"value", value, "error", "not a positive duration")
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+			"%s=%q is not a positive duration; ignoring it", AnnotationRequeueAfter, value)
+		return 0
+	}
+	minimum := r.settings().MinRequeueAfter
+	if minimum <= 0 {
+		minimum = DefaultMinRequeueAfter
+	}
+	if every < minimum {
+		logger.Warn("invalid annotation", "annotation", AnnotationRequeueAfter,
+			"value", value, "error", "below the minimum", "minimum", minimum)
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+			"%s=%q is below the minimum of %s; using %s", AnnotationRequeueAfter, value, minimum, minimum)
+		return minimum
+	}
+	return every
+}
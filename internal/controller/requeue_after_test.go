@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReconcileRequeueAfter(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		minimum   time.Duration
+		want      time.Duration
+		wantEvent string
+	}{
+		{name: "absent", want: 0},
+		{name: "valid", value: "60s", want: time.Minute},
+		{name: "below the default minimum", value: "1ms", want: DefaultMinRequeueAfter, wantEvent: "below the minimum"},
+		{name: "below a configured minimum", value: "20s", minimum: 30 * time.Second, want: 30 * time.Second,
+			wantEvent: "below the minimum"},
+		{name: "invalid", value: "often", want: 0, wantEvent: "not a positive duration"},
+		{name: "negative", value: "-1m", want: 0, wantEvent: "not a positive duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{AnnotationRegister: "true"}
+			if tt.value != "" {
+				annotations[AnnotationRequeueAfter] = tt.value
+			}
+			ph := newFakePiholeClient()
+			r := newTestReconciler(ph, testIngress("roaming", annotations, "laptop.local"))
+			r.MinRequeueAfter = tt.minimum
+			events := r.Recorder.(*record.FakeRecorder).Events
+
+			res := reconcileIngress(t, r, "default", "roaming")
+
+			if ph.ip("laptop.local") != "192.168.1.100" {
+				t.Error("record should be created regardless of the annotation")
+			}
+			if res.RequeueAfter != tt.want {
+				t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, tt.want)
+			}
+			select {
+			case event := <-events:
+				if tt.wantEvent == "" || !strings.Contains(event, tt.wantEvent) {
+					t.Errorf("unexpected event %q", event)
+				}
+			default:
+				if tt.wantEvent != "" {
+					t.Errorf("expected a Warning event mentioning %q", tt.wantEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileRequeueAfterKeepsShorterRequeue(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationRequeueAfter: "1h",
+	}, "one.local", "two.local"))
+	r.Index = &DesiredIndex{}
+	r.Quota = &RecordQuota{PerNamespace: 1}
+
+	if res := reconcileIngress(t, r, "default", "app"); res.RequeueAfter != quotaRequeue {
+		t.Errorf("RequeueAfter = %v, want the quota's %v", res.RequeueAfter, quotaRequeue)
+	}
+}
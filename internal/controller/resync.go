@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// Resync enqueues every object the operator manages, optionally limited to one
+// namespace, and returns how many were enqueued
+func (r *IngressReconciler) Resync(ctx context.Context, namespace string) (int, error) {
+	return r.ResyncPaced(ctx, namespace, nil)
+}
+
+// ResyncPaced is Resync taking a token from limiter before each enqueue, so resyncing
+// everything at once doesn't send a burst of reconciles to Pi-hole. Events enqueued
+// this way bypass the workqueue rate limiter. A nil limiter enqueues at once.
+func (r *IngressReconciler) ResyncPaced(ctx context.Context, namespace string, limiter *rate.Limiter) (int, error) {
+	if r.resync == nil {
+		return 0, fmt.Errorf("%s controller is not set up", strings.ToLower(r.src().kind()))
+	}
+
+	list := r.src().newList()
+	var opts []client.ListOption
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := r.List(ctx, list, opts...); err != nil {
+		return 0, fmt.Errorf("listing %s objects: %w", r.src().kind(), err)
+	}
+
+	enqueued := 0
+	for _, obj := range r.src().items(list) {
+		if !r.isManaged(obj) {
+			continue
+		}
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return enqueued, err
+			}
+		}
+		select {
+		case r.resync <- event.GenericEvent{Object: obj}:
+			enqueued++
+		case <-ctx.Done():
+			return enqueued, ctx.Err()
+		}
+	}
+	return enqueued, nil
+}
+
+// isManaged reports whether the operator registers, or still tracks records for, an object
+func (r *IngressReconciler) isManaged(obj client.Object) bool {
+	return r.hasRegistrationAnnotation(obj) ||
+		len(r.getManagedHosts(obj)) > 0 ||
+		controllerutil.ContainsFinalizer(obj, FinalizerName)
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestResync(t *testing.T) {
+	objs := []struct {
+		name        string
+		annotations map[string]string
+	}{
+		{name: "registered", annotations: map[string]string{AnnotationRegister: "true"}},
+		{name: "unregistered-but-tracked", annotations: map[string]string{AnnotationManagedHosts: "old.local"}},
+		{name: "unrelated", annotations: nil},
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		want      int
+	}{
+		{name: "all namespaces", want: 2},
+		{name: "matching namespace", namespace: "default", want: 2},
+		{name: "other namespace", namespace: "apps", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestReconciler(newFakePiholeClient())
+			for _, o := range objs {
+				if err := r.Create(context.Background(), testIngress(o.name, o.annotations, o.name+".local")); err != nil {
+					t.Fatalf("Create() unexpected error: %v", err)
+				}
+			}
+			r.resync = make(chan event.GenericEvent, len(objs))
+
+			got, err := r.Resync(context.Background(), tt.namespace)
+			if err != nil {
+				t.Fatalf("Resync() unexpected error: %v", err)
+			}
+			if got != tt.want || len(r.resync) != tt.want {
+				t.Errorf("Resync() = %d (queued %d), want %d", got, len(r.resync), tt.want)
+			}
+		})
+	}
+}
+
+func TestResyncBeforeSetup(t *testing.T) {
+	r := newTestReconciler(newFakePiholeClient())
+	if _, err := r.Resync(context.Background(), ""); err == nil {
+		t.Error("Resync() expected error before SetupWithManager, got nil")
+	}
+}
+
+func TestResyncPaced(t *testing.T) {
+	r := newTestReconciler(newFakePiholeClient())
+	for _, name := range []string{"a", "b", "c"} {
+		if err := r.Create(context.Background(), testIngress(name, map[string]string{AnnotationRegister: "true"}, name+".local")); err != nil {
+			t.Fatalf("Create() unexpected error: %v", err)
+		}
+	}
+	r.resync = make(chan event.GenericEvent, 3)
+
+	// One object goes at once, the others wait for the limiter
+	start := time.Now()
+	got, err := r.ResyncPaced(context.Background(), "", rate.NewLimiter(rate.Every(50*time.Millisecond), 1))
+	if err != nil {
+		t.Fatalf("ResyncPaced() unexpected error: %v", err)
+	}
+	if got != 3 {
+		t.Errorf("ResyncPaced() = %d, want 3", got)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("ResyncPaced() took %v, want the enqueues spread out", elapsed)
+	}
+
+	// A cancelled resync stops waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.ResyncPaced(ctx, "", rate.NewLimiter(rate.Every(time.Hour), 1)); err == nil {
+		t.Error("ResyncPaced() expected error once cancelled, got nil")
+	}
+}
@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole/fakeserver"
+)
+
+// apiCalls counts the requests a fake Pi-hole answered, by what they did
+type apiCalls struct {
+	logins, lists, creates, deletes, batches int
+}
+
+func countCalls(requests []fakeserver.Request) apiCalls {
+	var c apiCalls
+	for _, req := range requests {
+		switch {
+		case req.Path == "/api/auth":
+			c.logins++
+		case req.Method == http.MethodGet:
+			c.lists++
+		case req.Method == http.MethodPut:
+			c.creates++
+		case req.Method == http.MethodDelete:
+			c.deletes++
+		case req.Method == http.MethodPatch:
+			c.batches++
+		}
+	}
+	return c
+}
+
+func (c apiCalls) writes() int {
+	return c.creates + c.deletes + c.batches
+}
+
+func (c apiCalls) total() int {
+	return c.logins + c.lists + c.writes()
+}
+
+// report adds the calls of iterations runs to the benchmark's results
+func (c apiCalls) report(b *testing.B, iterations int) {
+	n := float64(iterations)
+	b.ReportMetric(float64(c.total())/n, "api-calls/op")
+	b.ReportMetric(float64(c.lists)/n, "lists/op")
+	b.ReportMetric(float64(c.writes())/n, "writes/op")
+}
+
+// scaleIngresses returns n registered Ingresses with hostsEach hosts apiece
+func scaleIngresses(n, hostsEach int) []*networkingv1.Ingress {
+	ingresses := make([]*networkingv1.Ingress, 0, n)
+	for i := range n {
+		hosts := make([]string, hostsEach)
+		for j := range hosts {
+			hosts[j] = fmt.Sprintf("h%d.app%d.example.com", j, i)
+		}
+		ingresses = append(ingresses,
+			testIngress(fmt.Sprintf("app%d", i), map[string]string{AnnotationRegister: "true"}, hosts...))
+	}
+	return ingresses
+}
+
+// newScaleReconciler returns a quiet reconciler for ingresses talking over HTTP to a
+// fake Pi-hole through the listing cache, as in the operator
+func newScaleReconciler(tb testing.TB, ingresses []*networkingv1.Ingress) (*IngressReconciler, *fakeserver.Server) {
+	tb.Helper()
+	fake := fakeserver.New("secret")
+	srv := fake.Start()
+	tb.Cleanup(srv.Close)
+	objs := make([]client.Object, len(ingresses))
+	for i, ingress := range ingresses {
+		objs[i] = ingress
+	}
+	r := newTestReconciler(nil, objs...)
+	r.PiholeClient = pihole.NewListingCache(pihole.NewClient(srv.URL, "secret"))
+	r.Logger = slog.New(slog.DiscardHandler)
+	r.Recorder = &record.FakeRecorder{}
+	return r, fake
+}
+
+// syncAll reconciles every Ingress once, as a resync with one worker does
+func syncAll(tb testing.TB, r *IngressReconciler, ingresses []*networkingv1.Ingress) {
+	tb.Helper()
+	for _, ingress := range ingresses {
+		res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ingress)})
+		if err != nil || !res.IsZero() {
+			tb.Fatalf("reconciling %s: %v, %+v", ingress.Name, err, res)
+		}
+	}
+}
+
+// deleteAll deletes every Ingress and reconciles the deletions
+func deleteAll(tb testing.TB, r *IngressReconciler, ingresses []*networkingv1.Ingress) {
+	tb.Helper()
+	ctx := context.Background()
+	for _, ingress := range ingresses {
+		current := &networkingv1.Ingress{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(ingress), current); err != nil {
+			tb.Fatal(err)
+		}
+		if err := r.Delete(ctx, current); err != nil {
+			tb.Fatal(err)
+		}
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ingress)})
+		if err != nil || !res.IsZero() {
+			tb.Fatalf("reconciling the deletion of %s: %v, %+v", ingress.Name, err, res)
+		}
+	}
+}
+
+// TestScaleAPICalls pins the Pi-hole calls of a sync, a resync and a teardown, so a
+// change that adds calls per object or per record shows up here before it shows up
+// on a large cluster. Update the counts deliberately when the call pattern improves.
+func TestScaleAPICalls(t *testing.T) {
+	const objects, hostsEach = 20, 3
+	const records = objects * hostsEach
+	ingresses := scaleIngresses(objects, hostsEach)
+	r, fake := newScaleReconciler(t, ingresses)
+
+	phase := func(name string, run func(testing.TB, *IngressReconciler, []*networkingv1.Ingress)) apiCalls {
+		t.Helper()
+		before := len(fake.Requests())
+		run(t, r, ingresses)
+		calls := countCalls(fake.Requests()[before:])
+		t.Logf("%s: %+v", name, calls)
+		return calls
+	}
+
+	sync := phase("sync", syncAll)
+	if sync.creates != records || sync.deletes != 0 {
+		t.Errorf("sync wrote %d creates and %d deletes, want %d creates", sync.creates, sync.deletes, records)
+	}
+	if sync.lists > objects {
+		t.Errorf("sync listed %d times, want at most once per object (%d)", sync.lists, objects)
+	}
+	if sync.logins != 1 {
+		t.Errorf("sync logged in %d times, want once", sync.logins)
+	}
+
+	resync := phase("resync", syncAll)
+	if resync.writes() != 0 {
+		t.Errorf("resync of synced objects wrote %d times", resync.writes())
+	}
+	if resync.lists > objects {
+		t.Errorf("resync listed %d times, want at most once per object (%d)", resync.lists, objects)
+	}
+
+	teardown := phase("teardown", deleteAll)
+	if teardown.deletes != records {
+		t.Errorf("teardown deleted %d records, want %d", teardown.deletes, records)
+	}
+	// The HTTP client lists to find the entry of each record it deletes; more than
+	// that is a regression
+	if teardown.lists > records {
+		t.Errorf("teardown listed %d times, want at most once per record (%d)", teardown.lists, records)
+	}
+	if hosts := fake.Hosts(); len(hosts) != 0 {
+		t.Errorf("records left after the teardown: %v", hosts)
+	}
+}
+
+var scaleSizes = []struct{ objects, hostsEach int }{
+	{objects: 50, hostsEach: 4},
+	{objects: 500, hostsEach: 4},
+}
+
+// BenchmarkFullSync times registering every host of a fresh cluster
+func BenchmarkFullSync(b *testing.B) {
+	for _, size := range scaleSizes {
+		b.Run(fmt.Sprintf("objects=%d/hosts=%d", size.objects, size.objects*size.hostsEach), func(b *testing.B) {
+			b.ReportAllocs()
+			var requests []fakeserver.Request
+			iterations := 0
+			for b.Loop() {
+				b.StopTimer()
+				ingresses := scaleIngresses(size.objects, size.hostsEach)
+				r, fake := newScaleReconciler(b, ingresses)
+				b.StartTimer()
+
+				syncAll(b, r, ingresses)
+
+				b.StopTimer()
+				requests = append(requests, fake.Requests()...)
+				iterations++
+				b.StartTimer()
+			}
+			countCalls(requests).report(b, iterations)
+		})
+	}
+}
+
+// BenchmarkResync times a resync of a cluster whose records are all in place
+func BenchmarkResync(b *testing.B) {
+	for _, size := range scaleSizes {
+		b.Run(fmt.Sprintf("objects=%d/hosts=%d", size.objects, size.objects*size.hostsEach), func(b *testing.B) {
+			ingresses := scaleIngresses(size.objects, size.hostsEach)
+			r, fake := newScaleReconciler(b, ingresses)
+			syncAll(b, r, ingresses)
+			before := len(fake.Requests())
+
+			b.ReportAllocs()
+			iterations := 0
+			for b.Loop() {
+				syncAll(b, r, ingresses)
+				iterations++
+			}
+			countCalls(fake.Requests()[before:]).report(b, iterations)
+		})
+	}
+}
+
+// BenchmarkTeardown times deleting every object of a synced cluster
+func BenchmarkTeardown(b *testing.B) {
+	for _, size := range scaleSizes {
+		b.Run(fmt.Sprintf("objects=%d/hosts=%d", size.objects, size.objects*size.hostsEach), func(b *testing.B) {
+			b.ReportAllocs()
+			var requests []fakeserver.Request
+			iterations := 0
+			for b.Loop() {
+				b.StopTimer()
+				ingresses := scaleIngresses(size.objects, size.hostsEach)
+				r, fake := newScaleReconciler(b, ingresses)
+				syncAll(b, r, ingresses)
+				before := len(fake.Requests())
+				b.StartTimer()
+
+				deleteAll(b, r, ingresses)
+
+				b.StopTimer()
+				requests = append(requests, fake.Requests()[before:]...)
+				iterations++
+				b.StartTimer()
+			}
+			countCalls(requests).report(b, iterations)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Settings are the reconciler options that may change while the operator runs, when
+// the configuration is reloaded. Each field matches the IngressReconciler field of the
+// same name.
+type Settings struct {
+	DefaultTargetIP               string
+	TargetSource                  TargetSource
+	DefaultTargetIPv6             string
+	DisableAAAA                   bool
+	DefaultDomainSuffix           string
+	SyncPolicy                    SyncPolicy
+	ConflictPolicy                ConflictPolicy
+	HostsMode                     HostsMode
+	DisableOverwrite              bool
+	AdoptExisting                 bool
+	AllowCrossNamespaceDuplicates bool
+	HostFilter                    *HostFilter
+	IngressClasses                []string
+	ExcludeNamespaces             []string
+	UpdateConflictRequeue         time.Duration
+	MinRequeueAfter               time.Duration
+}
+
+// LiveSettings holds the current Settings shared by every reconciler. Store replaces
+// them as a whole, so a reader never sees half of a reload.
+type LiveSettings struct {
+	current atomic.Pointer[Settings]
+}
+
+// NewLiveSettings returns a holder starting with s
+func NewLiveSettings(s Settings) *LiveSettings {
+	l := &LiveSettings{}
+	l.Store(s)
+	return l
+}
+
+// Load returns the current settings
+func (l *LiveSettings) Load() Settings {
+	if s := l.current.Load(); s != nil {
+		return *s
+	}
+	return Settings{}
+}
+
+// Store replaces the current settings
+func (l *LiveSettings) Store(s Settings) {
+	l.current.Store(&s)
+}
+
+// settings returns the options in effect: those of Live when set, else the
+// reconciler's own fields
+func (r *IngressReconciler) settings() Settings {
+	if r.Live != nil {
+		return r.Live.Load()
+	}
+	return Settings{
+		DefaultTargetIP:               r.DefaultTargetIP,
+		TargetSource:                  r.TargetSource,
+		DefaultTargetIPv6:             r.DefaultTargetIPv6,
+		DisableAAAA:                   r.DisableAAAA,
+		DefaultDomainSuffix:           r.DefaultDomainSuffix,
+		SyncPolicy:                    r.SyncPolicy,
+		ConflictPolicy:                r.ConflictPolicy,
+		HostsMode:                     r.HostsMode,
+		DisableOverwrite:              r.DisableOverwrite,
+		AdoptExisting:                 r.AdoptExisting,
+		AllowCrossNamespaceDuplicates: r.AllowCrossNamespaceDuplicates,
+		HostFilter:                    r.HostFilter,
+		IngressClasses:                r.IngressClasses,
+		ExcludeNamespaces:             r.ExcludeNamespaces,
+		UpdateConflictRequeue:         r.UpdateConflictRequeue,
+		MinRequeueAfter:               r.MinRequeueAfter,
+	}
+}
@@ -0,0 +1,26 @@
+package controller
+
+import "testing"
+
+func TestReconcileLiveSettings(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"))
+	r.Live = NewLiveSettings(Settings{DefaultTargetIP: "10.0.0.1"})
+
+	reconcileIngress(t, r, "default", "app")
+	if got := ph.ip("app.local"); got != "10.0.0.1" {
+		t.Fatalf("app.local = %q, want the live 10.0.0.1 over the reconciler field", got)
+	}
+
+	r.Live.Store(Settings{DefaultTargetIP: "10.0.0.2"})
+	reconcileIngress(t, r, "default", "app")
+	if got := ph.ip("app.local"); got != "10.0.0.2" {
+		t.Errorf("app.local = %q after the settings changed, want 10.0.0.2", got)
+	}
+
+	r.Live.Store(Settings{DefaultTargetIP: "10.0.0.2", ExcludeNamespaces: []string{"default"}})
+	reconcileIngress(t, r, "default", "app")
+	if got := ph.ip("app.local"); got != "" {
+		t.Errorf("app.local = %q after its namespace was excluded, want it removed", got)
+	}
+}
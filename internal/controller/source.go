@@ -0,0 +1,80 @@
+package controller
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// objectSource adapts a Kubernetes kind to the reconciler. Annotations, finalizers
+// and cleanup are handled generically; a source only knows where hosts come from
+// and when the object counts as ready.
+type objectSource interface {
+	// kind is the Kubernetes kind, used in logs, owners and error messages
+	kind() string
+
+	newObject() client.Object
+	newList() client.ObjectList
+	items(list client.ObjectList) []client.Object
+
+	// hosts returns the hostnames the object asks for, before any annotation override
+	hosts(obj client.Object) []string
+
+	// ready reports whether the object has been admitted, for RequireReady
+	ready(obj client.Object) bool
+
+	// backends returns the Services in the object's namespace that serve it, for
+	// the ready-endpoints check
+	backends(obj client.Object) []string
+
+	// statusIP returns the IPv4 address the object is served on according to its
+	// status, for TargetSourceStatus; "" when it reports none
+	statusIP(obj client.Object) string
+}
+
+// ingressSource is the default source for networking.k8s.io/v1 Ingresses
+type ingressSource struct{}
+
+func (ingressSource) kind() string { return "Ingress" }
+
+func (ingressSource) newObject() client.Object { return &networkingv1.Ingress{} }
+
+func (ingressSource) newList() client.ObjectList { return &networkingv1.IngressList{} }
+
+func (ingressSource) items(list client.ObjectList) []client.Object {
+	ingresses := list.(*networkingv1.IngressList).Items
+	objs := make([]client.Object, 0, len(ingresses))
+	for i := range ingresses {
+		objs = append(objs, &ingresses[i])
+	}
+	return objs
+}
+
+func (ingressSource) hosts(obj client.Object) []string {
+	var hosts []string
+	for _, rule := range obj.(*networkingv1.Ingress).Spec.Rules {
+		if rule.Host != "" {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	return hosts
+}
+
+func (ingressSource) ready(obj client.Object) bool {
+	return ingressReady(obj.(*networkingv1.Ingress))
+}
+
+func (ingressSource) backends(obj client.Object) []string {
+	return ingressBackends(obj.(*networkingv1.Ingress))
+}
+
+func (ingressSource) statusIP(obj client.Object) string {
+	return loadBalancerIPv4(obj.(*networkingv1.Ingress))
+}
+
+// src returns the reconciler's object source, defaulting to Ingresses
+func (r *IngressReconciler) src() objectSource {
+	if r.source == nil {
+		return ingressSource{}
+	}
+	return r.source
+}
@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/api/v1alpha1"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// DefaultStatusInterval is how often pending sync state is written to the PiholeSync
+const DefaultStatusInterval = 10 * time.Second
+
+// StatusWriter collects the sync state reported by the reconcilers of every source and
+// publishes it in the status of one cluster-scoped PiholeSync. Reports only update
+// memory; Start writes the object at most once per Interval and only when something
+// changed, so a burst of reconciles costs a single API write. A nil *StatusWriter
+// discards reports.
+type StatusWriter struct {
+	Client client.Client
+	Logger *slog.Logger
+
+	// Reader fetches the PiholeSync without a cache, so no informer is started for it
+	Reader client.Reader
+
+	// Name of the PiholeSync, created if missing
+	Name string
+
+	// Interval is the shortest time between writes; zero uses DefaultStatusInterval
+	Interval time.Duration
+
+	mu        sync.Mutex
+	objects   map[string]*v1alpha1.ObjectSyncStatus
+	reachable *metav1.Condition
+	dirty     bool
+}
+
+// +kubebuilder:rbac:groups=pihole.io,resources=piholesyncs,verbs=get;create
+// +kubebuilder:rbac:groups=pihole.io,resources=piholesyncs/status,verbs=get;update;patch
+
+// Synced records a successful sync of an object that now owns the given record keys.
+// Reaching this point required a Pi-hole listing, so Pi-hole is also marked reachable.
+func (w *StatusWriter) Synced(kind string, key types.NamespacedName, domains []string) {
+	if w == nil {
+		return
+	}
+	domains = uniqueHosts(domains)
+	sort.Strings(domains)
+	now := metav1.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := w.entryLocked(kind, key)
+	status.Domains = domains
+	status.LastSyncTime = &now
+	status.LastError = ""
+	w.setReachableLocked(nil)
+	w.dirty = true
+}
+
+// Failed records a failed sync of an object. Domains and the last sync time are kept,
+// since the records of the last successful sync are still in place.
+func (w *StatusWriter) Failed(kind string, key types.NamespacedName, err error) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.entryLocked(kind, key).LastError = err.Error()
+	w.dirty = true
+}
+
+// Expired records the record keys of an object that have expired and are kept out of
+// Pi-hole
+func (w *StatusWriter) Expired(kind string, key types.NamespacedName, domains []string) {
+	if w == nil {
+		return
+	}
+	domains = uniqueHosts(domains)
+	sort.Strings(domains)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := w.entryLocked(kind, key)
+	if slices.Equal(status.Expired, domains) {
+		return
+	}
+	status.Expired = domains
+	w.dirty = true
+}
+
+// PiholeError records the outcome of a failed Pi-hole API call. Only errors without an
+// HTTP response, or with a server error, mean Pi-hole is unreachable.
+func (w *StatusWriter) PiholeError(err error) {
+	if w == nil {
+		return
+	}
+	if apiErr, ok := pihole.AsAPIError(err); ok && apiErr.StatusCode < 500 {
+		err = nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.setReachableLocked(err)
+}
+
+// Forget drops an object the operator no longer manages
+func (w *StatusWriter) Forget(kind string, key types.NamespacedName) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.objects[statusKey(kind, key)]; ok {
+		delete(w.objects, statusKey(kind, key))
+		w.dirty = true
+	}
+}
+
+func (w *StatusWriter) entryLocked(kind string, key types.NamespacedName) *v1alpha1.ObjectSyncStatus {
+	if w.objects == nil {
+		w.objects = make(map[string]*v1alpha1.ObjectSyncStatus)
+	}
+	status := w.objects[statusKey(kind, key)]
+	if status == nil {
+		status = &v1alpha1.ObjectSyncStatus{Kind: kind, Namespace: key.Namespace, Name: key.Name}
+		w.objects[statusKey(kind, key)] = status
+	}
+	return status
+}
+
+// setReachableLocked updates the PiholeReachable condition; nil means reachable
+func (w *StatusWriter) setReachableLocked(err error) {
+	cond := metav1.Condition{
+		Type:   v1alpha1.ConditionPiholeReachable,
+		Status: metav1.ConditionTrue,
+		Reason: "Reachable",
+	}
+	if err != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "Unreachable"
+		cond.Message = err.Error()
+	}
+	if w.reachable == nil || w.reachable.Status != cond.Status || w.reachable.Message != cond.Message {
+		w.reachable = &cond
+		w.dirty = true
+	}
+}
+
+// Start writes pending sync state every Interval until ctx is cancelled; it
+// implements manager.Runnable
+func (w *StatusWriter) Start(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultStatusInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := w.flush(ctx); err != nil {
+			w.Logger.Error("failed to write sync status", "name", w.Name, "error", err)
+		}
+	}
+}
+
+// flush writes the current sync state if it changed since the last write. A failed
+// write leaves the state pending for the next attempt.
+func (w *StatusWriter) flush(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.dirty {
+		w.mu.Unlock()
+		return nil
+	}
+	w.dirty = false
+	objects, reachable := w.snapshotLocked()
+	w.mu.Unlock()
+
+	if err := w.write(ctx, objects, reachable); err != nil {
+		w.mu.Lock()
+		w.dirty = true
+		w.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// snapshotLocked copies the tracked objects, sorted by kind, namespace and name
+func (w *StatusWriter) snapshotLocked() ([]v1alpha1.ObjectSyncStatus, *metav1.Condition) {
+	objects := make([]v1alpha1.ObjectSyncStatus, 0, len(w.objects))
+	for _, status := range w.objects {
+		objects = append(objects, *status.DeepCopy())
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		a, b := objects[i], objects[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	var reachable *metav1.Condition
+	if w.reachable != nil {
+		c := *w.reachable
+		reachable = &c
+	}
+	return objects, reachable
+}
+
+func (w *StatusWriter) write(ctx context.Context, objects []v1alpha1.ObjectSyncStatus, reachable *metav1.Condition) error {
+	obj := &v1alpha1.PiholeSync{}
+	err := w.Reader.Get(ctx, client.ObjectKey{Name: w.Name}, obj)
+	if errors.IsNotFound(err) {
+		obj = &v1alpha1.PiholeSync{ObjectMeta: metav1.ObjectMeta{Name: w.Name}}
+		err = w.Client.Create(ctx, obj)
+	}
+	if err != nil {
+		return fmt.Errorf("getting PiholeSync %s: %w", w.Name, err)
+	}
+
+	obj.Status.Objects = objects
+	meta.SetStatusCondition(&obj.Status.Conditions, allSyncedCondition(objects, obj.Generation))
+	if reachable == nil {
+		reachable = &metav1.Condition{
+			Type:    v1alpha1.ConditionPiholeReachable,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "NotChecked",
+			Message: "No Pi-hole call has completed yet",
+		}
+	}
+	reachable.ObservedGeneration = obj.Generation
+	meta.SetStatusCondition(&obj.Status.Conditions, *reachable)
+
+	if err := w.Client.Status().Update(ctx, obj); err != nil {
+		return fmt.Errorf("updating PiholeSync %s status: %w", w.Name, err)
+	}
+	return nil
+}
+
+// allSyncedCondition summarizes whether every object's last sync succeeded
+func allSyncedCondition(objects []v1alpha1.ObjectSyncStatus, generation int64) metav1.Condition {
+	failing := 0
+	for _, status := range objects {
+		if status.LastError != "" {
+			failing++
+		}
+	}
+	cond := metav1.Condition{
+		Type:               v1alpha1.ConditionAllSynced,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Synced",
+		Message:            fmt.Sprintf("%d objects synced", len(objects)),
+		ObservedGeneration: generation,
+	}
+	if failing > 0 {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "SyncFailed"
+		cond.Message = fmt.Sprintf("%d of %d objects failed their last sync", failing, len(objects))
+	}
+	return cond
+}
+
+// NeedLeaderElection ensures only the leader, whose reconcilers report, writes the status
+func (w *StatusWriter) NeedLeaderElection() bool {
+	return true
+}
+
+// statusKey identifies an object across sources
+func statusKey(kind string, key types.NamespacedName) string {
+	return kind + " " + key.String()
+}
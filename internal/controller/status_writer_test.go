@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/api/v1alpha1"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func newTestStatusWriter(t *testing.T) *StatusWriter {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatalf("AddToScheme() unexpected error: %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&v1alpha1.PiholeSync{}).Build()
+	return &StatusWriter{
+		Client: c,
+		Reader: c,
+		Logger: slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		Name:   "pihole",
+	}
+}
+
+// flushStatus writes pending state and returns the resulting PiholeSync
+func flushStatus(t *testing.T, w *StatusWriter) *v1alpha1.PiholeSync {
+	t.Helper()
+	if err := w.flush(context.Background()); err != nil {
+		t.Fatalf("flush() unexpected error: %v", err)
+	}
+	var obj v1alpha1.PiholeSync
+	if err := w.Reader.Get(context.Background(), client.ObjectKey{Name: w.Name}, &obj); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	return &obj
+}
+
+func TestStatusWriter(t *testing.T) {
+	w := newTestStatusWriter(t)
+	a := types.NamespacedName{Namespace: "default", Name: "a"}
+	b := types.NamespacedName{Namespace: "default", Name: "b"}
+
+	w.Synced("Ingress", b, []string{"b.local", "b.local/AAAA", "b.local"})
+	w.Synced("Ingress", a, []string{"a.local"})
+	unreachable := errors.New("connection refused")
+	w.Failed("Ingress", b, unreachable)
+	w.PiholeError(unreachable)
+
+	obj := flushStatus(t, w)
+	objects := obj.Status.Objects
+	if len(objects) != 2 || objects[0].Name != "a" || objects[1].Name != "b" {
+		t.Fatalf("objects = %+v, want a and b in order", objects)
+	}
+	if got := objects[1].Domains; !slicesEqual(got, []string{"b.local", "b.local/AAAA"}) {
+		t.Errorf("b domains = %v, want the domains of its last sync", got)
+	}
+	if objects[1].LastError != unreachable.Error() || objects[1].LastSyncTime == nil {
+		t.Errorf("b = %+v, want the error alongside its last sync time", objects[1])
+	}
+	if !meta.IsStatusConditionFalse(obj.Status.Conditions, v1alpha1.ConditionAllSynced) {
+		t.Errorf("AllSynced not False with a failing object: %+v", obj.Status.Conditions)
+	}
+	if !meta.IsStatusConditionFalse(obj.Status.Conditions, v1alpha1.ConditionPiholeReachable) {
+		t.Errorf("PiholeReachable not False after a connection error: %+v", obj.Status.Conditions)
+	}
+
+	// Nothing changed, so nothing is written
+	version := obj.ResourceVersion
+	if obj := flushStatus(t, w); obj.ResourceVersion != version {
+		t.Errorf("unchanged status written again: resource version %s, want %s", obj.ResourceVersion, version)
+	}
+
+	w.Synced("Ingress", b, []string{"b.local"})
+	w.Forget("Ingress", a)
+	obj = flushStatus(t, w)
+	if len(obj.Status.Objects) != 1 || obj.Status.Objects[0].LastError != "" {
+		t.Errorf("objects = %+v, want only b without an error", obj.Status.Objects)
+	}
+	if !meta.IsStatusConditionTrue(obj.Status.Conditions, v1alpha1.ConditionAllSynced) ||
+		!meta.IsStatusConditionTrue(obj.Status.Conditions, v1alpha1.ConditionPiholeReachable) {
+		t.Errorf("conditions = %+v, want AllSynced and PiholeReachable True", obj.Status.Conditions)
+	}
+}
+
+func TestStatusWriterPiholeError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want metav1.ConditionStatus
+	}{
+		{&pihole.APIError{StatusCode: 400, Message: "bad request"}, metav1.ConditionTrue},
+		{&pihole.APIError{StatusCode: 503, Message: "unavailable"}, metav1.ConditionFalse},
+		{errors.New("dial tcp: i/o timeout"), metav1.ConditionFalse},
+	}
+	for _, tt := range tests {
+		w := &StatusWriter{}
+		w.PiholeError(tt.err)
+		if w.reachable.Status != tt.want {
+			t.Errorf("PiholeError(%v) reachable = %s, want %s", tt.err, w.reachable.Status, tt.want)
+		}
+	}
+}
+
+func TestStatusWriterNil(t *testing.T) {
+	var w *StatusWriter
+	key := types.NamespacedName{Namespace: "default", Name: "a"}
+	w.Synced("Ingress", key, []string{"a.local"})
+	w.Failed("Ingress", key, errors.New("boom"))
+	w.Expired("Ingress", key, []string{"a.local"})
+	w.PiholeError(errors.New("boom"))
+	w.Forget("Ingress", key)
+}
+
+func TestReconcileReportsSyncStatus(t *testing.T) {
+	ph := newFakePiholeClient()
+	ph.err = errors.New("pihole unreachable")
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"))
+	r.DisableFinalizers = true
+	r.SyncStatus = &StatusWriter{}
+	key := statusKey("Ingress", types.NamespacedName{Namespace: "default", Name: "app"})
+
+	reconcileIngress(t, r, "default", "app")
+	if status := r.SyncStatus.objects[key]; status == nil || status.LastError == "" {
+		t.Fatalf("status = %+v, want the Pi-hole error", status)
+	}
+
+	ph.err = nil
+	reconcileIngress(t, r, "default", "app")
+	status := r.SyncStatus.objects[key]
+	if status.LastError != "" || !slicesEqual(status.Domains, []string{"app.local"}) {
+		t.Errorf("status = %+v, want app.local synced without an error", status)
+	}
+
+	// Unregistering releases the records and drops the object from the status
+	ingress := getIngress(t, r, "default", "app")
+	delete(ingress.Annotations, AnnotationRegister)
+	if err := r.Update(context.Background(), ingress); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if _, ok := r.SyncStatus.objects[key]; ok {
+		t.Error("unregistered Ingress still listed in the sync status")
+	}
+}
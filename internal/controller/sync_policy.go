@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+)
+
+// AnnotationSyncPolicy overrides the operator-wide sync policy for one Ingress
+const AnnotationSyncPolicy = "pihole.io/sync-policy"
+
+// SyncPolicy limits which kinds of changes the operator may make to Pi-hole
+type SyncPolicy string
+
+const (
+	// SyncPolicySync creates, updates and deletes records
+	SyncPolicySync SyncPolicy = "sync"
+
+	// SyncPolicyUpsertOnly creates and updates records but never deletes them
+	SyncPolicyUpsertOnly SyncPolicy = "upsert-only"
+
+	// SyncPolicyCreateOnly only creates missing records
+	SyncPolicyCreateOnly SyncPolicy = "create-only"
+)
+
+// ParseSyncPolicy validates a sync policy name
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case SyncPolicySync, SyncPolicyUpsertOnly, SyncPolicyCreateOnly:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown sync policy %q", s)
+}
+
+// AllowsUpdate reports whether existing records may be changed
+func (p SyncPolicy) AllowsUpdate() bool {
+	return p != SyncPolicyCreateOnly
+}
+
+// AllowsDelete reports whether records may be removed. The zero value behaves as sync.
+func (p SyncPolicy) AllowsDelete() bool {
+	return p == "" || p == SyncPolicySync
+}
+
+// syncPolicy resolves the effective policy for an object
+func (r *IngressReconciler) syncPolicy(obj client.Object, logger *slog.Logger) SyncPolicy {
+	def := r.settings().SyncPolicy
+	if def == "" {
+		def = SyncPolicySync
+	}
+	value, ok := obj.GetAnnotations()[AnnotationSyncPolicy]
+	if !ok || value == "" {
+		return def
+	}
+	policy, err := ParseSyncPolicy(value)
+	if err != nil {
+		logger.Warn("invalid annotation", "annotation", AnnotationSyncPolicy, "value", value, "error", err)
+		r.Recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidAnnotation",
+			"%s=%q is not a valid sync policy; using %s", AnnotationSyncPolicy, value, def)
+		return def
+	}
+	return policy
+}
+
+// skipDeletions logs and counts deletions withheld by the sync policy
+func skipDeletions(policy SyncPolicy, hosts []string, logger *slog.Logger) {
+	if len(hosts) == 0 {
+		return
+	}
+	logger.Info("dns record deletion skipped", "hosts", strings.Join(hosts, ","), "sync_policy", string(policy))
+	metrics.DeletionsSkipped.WithLabelValues(string(policy)).Add(float64(len(hosts)))
+}
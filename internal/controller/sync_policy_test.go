@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+func TestParseSyncPolicy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    SyncPolicy
+		wantErr bool
+	}{
+		{input: "sync", want: SyncPolicySync},
+		{input: "Upsert-Only", want: SyncPolicyUpsertOnly},
+		{input: " create-only ", want: SyncPolicyCreateOnly},
+		{input: "delete-only", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSyncPolicy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSyncPolicy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSyncPolicy(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileSyncPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      SyncPolicy
+		annotation  string
+		wantMoved   bool
+		wantDeleted bool
+		wantManaged string
+	}{
+		{name: "sync", policy: SyncPolicySync, wantMoved: true, wantDeleted: true, wantManaged: "app.local,moved.local"},
+		{name: "upsert-only", policy: SyncPolicyUpsertOnly, wantMoved: true, wantManaged: "app.local,moved.local,old.local"},
+		{name: "create-only", policy: SyncPolicyCreateOnly, wantManaged: "app.local,moved.local,old.local"},
+		{name: "annotation override", policy: SyncPolicySync, annotation: "upsert-only", wantMoved: true, wantManaged: "app.local,moved.local,old.local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{
+				AnnotationRegister:     "true",
+				AnnotationManagedHosts: "moved.local,old.local",
+			}
+			if tt.annotation != "" {
+				annotations[AnnotationSyncPolicy] = tt.annotation
+			}
+			ph := newFakePiholeClient(
+				pihole.DNSRecord{Domain: "moved.local", IP: "10.0.0.1"},
+				pihole.DNSRecord{Domain: "old.local", IP: "192.168.1.100"},
+			)
+			r := newTestReconciler(ph, testIngress("app", annotations, "app.local", "moved.local"))
+			r.SyncPolicy = tt.policy
+
+			reconcileIngress(t, r, "default", "app")
+
+			if ph.ip("app.local") != "192.168.1.100" {
+				t.Error("missing record should always be created")
+			}
+			if moved := ph.ip("moved.local") == "192.168.1.100"; moved != tt.wantMoved {
+				t.Errorf("record updated = %v, want %v", moved, tt.wantMoved)
+			}
+			if deleted := ph.ip("old.local") == ""; deleted != tt.wantDeleted {
+				t.Errorf("record deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			current := getIngress(t, r, "default", "app")
+			if got := current.Annotations[AnnotationManagedHosts]; got != tt.wantManaged {
+				t.Errorf("managed hosts = %q, want %q", got, tt.wantManaged)
+			}
+			if hasFinalizer := controllerutil.ContainsFinalizer(current, FinalizerName); hasFinalizer != tt.wantDeleted {
+				t.Errorf("finalizer present = %v, want %v", hasFinalizer, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestHandleDeletionUpsertOnly(t *testing.T) {
+	ingress := testIngress("app", map[string]string{AnnotationManagedHosts: "app.local"}, "app.local")
+	ingress.Finalizers = []string{FinalizerName}
+	ingress.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	ph := newFakePiholeClient(pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.100"})
+	r := newTestReconciler(ph, ingress)
+	r.SyncPolicy = SyncPolicyUpsertOnly
+
+	if _, err := r.handleDeletion(context.Background(), ingress, r.Logger); err != nil {
+		t.Fatalf("handleDeletion() unexpected error: %v", err)
+	}
+	if controllerutil.ContainsFinalizer(ingress, FinalizerName) {
+		t.Error("finalizer should be released without deleting")
+	}
+	if ph.ip("app.local") == "" {
+		t.Error("record deleted although sync policy is upsert-only")
+	}
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// logSyncSummary logs the one line summing up the changes a reconcile made to Pi-hole:
+// at info when it changed anything, at debug when it did not, and at warn when a
+// change failed, counting those made before it. Alerts key off its message and fields,
+// so they must not be renamed.
+func (r *IngressReconciler) logSyncSummary(logger *slog.Logger, object string, applied Plan, unchanged int,
+	targetIP, targetIPv6 string, took time.Duration, err error) {
+	level, msg := slog.LevelDebug, "sync complete"
+	switch {
+	case err != nil:
+		level, msg = slog.LevelWarn, "sync failed"
+	case !applied.IsEmpty():
+		level = slog.LevelInfo
+	}
+	attrs := []slog.Attr{
+		slog.String("kind", r.src().kind()),
+		slog.String("object", object),
+		slog.Int("created", len(applied.Creates)),
+		slog.Int("updated", len(applied.Updates)),
+		slog.Int("deleted", len(applied.Deletes)),
+		slog.Int("unchanged", unchanged),
+		slog.String("target_ip", targetIP),
+		slog.String("target_ipv6", targetIPv6),
+		slog.Duration("duration", took.Round(time.Millisecond)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
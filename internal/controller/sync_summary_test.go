@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// syncSummaries returns the summary lines logged to buf
+func syncSummaries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if msg := entry["msg"]; msg == "sync complete" || msg == "sync failed" {
+			lines = append(lines, entry)
+		}
+	}
+	buf.Reset()
+	return lines
+}
+
+func TestSyncSummary(t *testing.T) {
+	ph := newFakePiholeClient(
+		pihole.DNSRecord{Domain: "moved.local", IP: "10.0.0.1"},
+		pihole.DNSRecord{Domain: "old.local", IP: "192.168.1.100"},
+		pihole.DNSRecord{Domain: "same.local", IP: "192.168.1.100"},
+	)
+	r := newTestReconciler(ph, testIngress("app", map[string]string{
+		AnnotationRegister:     "true",
+		AnnotationManagedHosts: "moved.local,old.local,same.local",
+	}, "app.local", "moved.local", "same.local"))
+	var buf bytes.Buffer
+	r.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	reconcileIngress(t, r, "default", "app")
+	summaries := syncSummaries(t, &buf)
+	if len(summaries) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(summaries))
+	}
+	want := map[string]any{
+		"level":       "INFO",
+		"msg":         "sync complete",
+		"kind":        "Ingress",
+		"object":      "default/app",
+		"created":     1.0,
+		"updated":     1.0,
+		"deleted":     1.0,
+		"unchanged":   1.0,
+		"target_ip":   "192.168.1.100",
+		"target_ipv6": "",
+	}
+	for key, value := range want {
+		if got := summaries[0][key]; got != value {
+			t.Errorf("%s = %v, want %v", key, got, value)
+		}
+	}
+	if _, ok := summaries[0]["duration"]; !ok {
+		t.Error("summary has no duration")
+	}
+
+	// Nothing to change is logged at debug
+	reconcileIngress(t, r, "default", "app")
+	summaries = syncSummaries(t, &buf)
+	if len(summaries) != 1 || summaries[0]["level"] != "DEBUG" || summaries[0]["unchanged"] != 3.0 ||
+		summaries[0]["created"] != 0.0 {
+		t.Errorf("no-op summary = %v, want one at debug with 3 unchanged", summaries)
+	}
+}
+
+func TestSyncSummaryFailure(t *testing.T) {
+	ph := newFakePiholeClient()
+	ph.err = errors.New("pihole down")
+	r := newTestReconciler(ph, testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"))
+	var buf bytes.Buffer
+	r.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "app"},
+	}); err != nil {
+		t.Fatalf("Reconcile() unexpected error: %v", err)
+	}
+	summaries := syncSummaries(t, &buf)
+	if len(summaries) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(summaries))
+	}
+	if s := summaries[0]; s["level"] != "WARN" || s["msg"] != "sync failed" || s["created"] != 0.0 ||
+		s["error"] != "pihole down" {
+		t.Errorf("failure summary = %v", s)
+	}
+}
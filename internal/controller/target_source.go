@@ -0,0 +1,38 @@
+package controller
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TargetSource selects where an object's target IP comes from when it has no
+// pihole.io/target-ip annotation
+type TargetSource string
+
+const (
+	// TargetSourceStatic uses DefaultTargetIP for every object
+	TargetSourceStatic TargetSource = "static"
+
+	// TargetSourceStatus uses the first IPv4 address in the object's load-balancer
+	// status, falling back to DefaultTargetIP while there is none
+	TargetSourceStatus TargetSource = "status"
+)
+
+// loadBalancerIPv4 returns the first IPv4 address in an Ingress's load-balancer status
+func loadBalancerIPv4(ingress *networkingv1.Ingress) string {
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if isValidIPv4(lb.IP) {
+			return lb.IP
+		}
+	}
+	return ""
+}
+
+// statusTargetIP returns the target IP the object reports itself, or "" when the
+// target source is static or the object has none
+func (r *IngressReconciler) statusTargetIP(obj client.Object) string {
+	if r.settings().TargetSource != TargetSourceStatus {
+		return ""
+	}
+	return r.src().statusIP(obj)
+}
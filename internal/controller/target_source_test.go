@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// withLoadBalancer sets the load-balancer status of an Ingress
+func withLoadBalancer(ingress *networkingv1.Ingress, addresses ...networkingv1.IngressLoadBalancerIngress) *networkingv1.Ingress {
+	ingress.Status.LoadBalancer.Ingress = addresses
+	return ingress
+}
+
+func TestLoadBalancerIPv4(t *testing.T) {
+	ingress := withLoadBalancer(testIngress("app", nil, "app.local"),
+		networkingv1.IngressLoadBalancerIngress{Hostname: "lb.example.com"},
+		networkingv1.IngressLoadBalancerIngress{IP: "fd00::1"},
+		networkingv1.IngressLoadBalancerIngress{IP: "192.168.1.50"},
+	)
+	if got := loadBalancerIPv4(ingress); got != "192.168.1.50" {
+		t.Errorf("loadBalancerIPv4() = %q, want 192.168.1.50", got)
+	}
+}
+
+func TestReconcileTargetSourceStatus(t *testing.T) {
+	ctx := context.Background()
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"),
+		withLoadBalancer(testIngress("pinned", map[string]string{AnnotationRegister: "true", AnnotationTargetIP: "10.0.0.9"}, "pinned.local"),
+			networkingv1.IngressLoadBalancerIngress{IP: "192.168.1.50"}),
+	)
+	r.DefaultTargetIP = ""
+	r.TargetSource = TargetSourceStatus
+	events := r.Recorder.(*record.FakeRecorder).Events
+
+	// No default and no address yet: warn and wait
+	res := reconcileIngress(t, r, "default", "app")
+	if ph.ip("app.local") != "" {
+		t.Fatal("record created without a target IP")
+	}
+	if res.RequeueAfter != notReadyRequeue {
+		t.Errorf("RequeueAfter = %v, want %v", res.RequeueAfter, notReadyRequeue)
+	}
+	select {
+	case event := <-events:
+		if !strings.Contains(event, "NoTargetIP") {
+			t.Errorf("event = %q, want NoTargetIP", event)
+		}
+	default:
+		t.Error("no event for the missing target IP")
+	}
+
+	ingress := withLoadBalancer(getIngress(t, r, "default", "app"), networkingv1.IngressLoadBalancerIngress{IP: "192.168.1.50"})
+	if err := r.Status().Update(ctx, ingress); err != nil {
+		t.Fatalf("Status().Update() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "app")
+	if got := ph.ip("app.local"); got != "192.168.1.50" {
+		t.Errorf("app.local = %q, want the load-balancer address", got)
+	}
+
+	// The annotation still wins over the status
+	reconcileIngress(t, r, "default", "pinned")
+	if got := ph.ip("pinned.local"); got != "10.0.0.9" {
+		t.Errorf("pinned.local = %q, want the annotated 10.0.0.9", got)
+	}
+}
+
+func TestReconcileTargetSourceStatic(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph, withLoadBalancer(testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local"),
+		networkingv1.IngressLoadBalancerIngress{IP: "192.168.1.50"}))
+
+	reconcileIngress(t, r, "default", "app")
+	if got := ph.ip("app.local"); got != r.DefaultTargetIP {
+		t.Errorf("app.local = %q, want the default %q while the target source is static", got, r.DefaultTargetIP)
+	}
+}
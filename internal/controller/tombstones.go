@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// tombstones keeps the last observed state of objects deleted while running without
+// finalizers. The delete event is the only place their managed hosts are still known,
+// so cleanup relies on it; tombstones are in-memory and lost if the operator restarts
+// before the cleanup succeeds.
+type tombstones struct {
+	mu   sync.Mutex
+	objs map[types.NamespacedName]client.Object
+}
+
+// record stores a copy of obj if it had records to clean up
+func (t *tombstones) record(obj client.Object) {
+	if obj.GetAnnotations()[AnnotationManagedHosts] == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.objs == nil {
+		t.objs = make(map[types.NamespacedName]client.Object)
+	}
+	t.objs[client.ObjectKeyFromObject(obj)] = obj.DeepCopyObject().(client.Object)
+}
+
+// get returns the tombstone for key, or nil
+func (t *tombstones) get(key types.NamespacedName) client.Object {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.objs[key]
+}
+
+// forget drops the tombstone for key once its cleanup is done
+func (t *tombstones) forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.objs, key)
+}
+
+// tombstoneHandler enqueues events like handler.EnqueueRequestForObject and
+// additionally captures deleted objects into tombstones
+type tombstoneHandler struct {
+	handler.EnqueueRequestForObject
+	tombstones *tombstones
+}
+
+// Delete records the deleted object before enqueueing it
+func (h *tombstoneHandler) Delete(ctx context.Context, evt event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	if evt.Object != nil {
+		h.tombstones.record(evt.Object)
+	}
+	h.EnqueueRequestForObject.Delete(ctx, evt, q)
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func testIngress(name string, annotations map[string]string, hosts ...string) *networkingv1.Ingress {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: annotations,
+		},
+	}
+	for _, h := range hosts {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{Host: h})
+	}
+	return ingress
+}
+
+func TestReconcileWithoutFinalizers(t *testing.T) {
+	ctx := context.Background()
+	ph := newFakePiholeClient()
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local")
+	r := newTestReconciler(ph, ingress)
+	r.DisableFinalizers = true
+
+	reconcileIngress(t, r, "default", "app")
+
+	current := getIngress(t, r, "default", "app")
+	if controllerutil.ContainsFinalizer(current, FinalizerName) {
+		t.Error("finalizer added although finalizers are disabled")
+	}
+	if ph.ip("app.local") != "192.168.1.100" {
+		t.Fatalf("record not created: %v", ph.records)
+	}
+
+	// The delete event captures the last known state before the object disappears
+	r.tombstones.record(current)
+	if err := r.Delete(ctx, current); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "app")
+
+	if ph.ip("app.local") != "" {
+		t.Error("record not cleaned up after delete event")
+	}
+	if r.tombstones.get(types.NamespacedName{Namespace: "default", Name: "app"}) != nil {
+		t.Error("tombstone not forgotten after cleanup")
+	}
+}
+
+func TestReconcileAnnotationRemovedWithoutFinalizers(t *testing.T) {
+	ph := newFakePiholeClient()
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local")
+	r := newTestReconciler(ph, ingress)
+	r.DisableFinalizers = true
+
+	reconcileIngress(t, r, "default", "app")
+
+	current := getIngress(t, r, "default", "app")
+	delete(current.Annotations, AnnotationRegister)
+	if err := r.Update(context.Background(), current); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	reconcileIngress(t, r, "default", "app")
+
+	if ph.ip("app.local") != "" {
+		t.Error("record not cleaned up after annotation removal")
+	}
+	if _, ok := getIngress(t, r, "default", "app").Annotations[AnnotationManagedHosts]; ok {
+		t.Error("managed-hosts annotation should be cleared")
+	}
+}
+
+func TestReconcileStripsFinalizers(t *testing.T) {
+	ph := newFakePiholeClient()
+	ingress := testIngress("app", map[string]string{AnnotationRegister: "true"}, "app.local")
+	ingress.Finalizers = []string{FinalizerName}
+	r := newTestReconciler(ph, ingress)
+	r.DisableFinalizers = true
+	r.StripFinalizers = true
+
+	reconcileIngress(t, r, "default", "app")
+
+	if controllerutil.ContainsFinalizer(getIngress(t, r, "default", "app"), FinalizerName) {
+		t.Error("finalizer should have been stripped")
+	}
+	if ph.ip("app.local") == "" {
+		t.Error("record should still be registered")
+	}
+}
+
+func TestTombstonesIgnoreUnmanaged(t *testing.T) {
+	var ts tombstones
+	ts.record(testIngress("app", nil, "app.local"))
+	if ts.get(types.NamespacedName{Namespace: "default", Name: "app"}) != nil {
+		t.Error("Ingress without managed hosts should not leave a tombstone")
+	}
+}
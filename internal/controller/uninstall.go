@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// FinalizerRelease is what ReleaseFinalizer did, or would do, to one object
+type FinalizerRelease struct {
+	Owner       string
+	Terminating bool
+
+	// Deleted are the tracked records removed from Pi-hole; Kept those left in place,
+	// because cleanup was off or the sync policy forbids deletions
+	Deleted []string
+	Kept    []string
+
+	// Gone is set when the object disappeared before the finalizer was patched off
+	Gone bool
+}
+
+// FinalizerHolders lists the watched objects carrying the cleanup finalizer, across
+// the given namespaces or every namespace when none are given
+func (r *IngressReconciler) FinalizerHolders(ctx context.Context, namespaces []string) ([]client.Object, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	var holders []client.Object
+	for _, ns := range namespaces {
+		list := r.src().newList()
+		if err := r.List(ctx, list, client.InNamespace(ns)); err != nil {
+			return nil, fmt.Errorf("listing %s objects: %w", r.src().kind(), err)
+		}
+		for _, obj := range r.src().items(list) {
+			if controllerutil.ContainsFinalizer(obj, FinalizerName) {
+				holders = append(holders, obj)
+			}
+		}
+	}
+	return holders, nil
+}
+
+// ReleaseFinalizer removes the cleanup finalizer from obj, for uninstalling the
+// operator. With cleanup, the records the object tracks are first deleted from
+// Pi-hole as its deletion would, unless its sync policy forbids it. The finalizer is
+// removed with a patch, so a concurrent change to the object causes no conflict, and
+// an object already terminating is then deleted by the API server. With dryRun
+// nothing is changed and the report says what would be done.
+func (r *IngressReconciler) ReleaseFinalizer(ctx context.Context, obj client.Object, cleanup, dryRun bool) (FinalizerRelease, error) {
+	release := FinalizerRelease{Owner: r.ownerOf(obj), Terminating: !obj.GetDeletionTimestamp().IsZero()}
+	hosts := r.getManagedHosts(obj)
+	if !cleanup || r.PiholeClient == nil || !r.uninstallPolicy(obj).AllowsDelete() {
+		release.Kept = hosts
+		hosts = nil
+	}
+	for _, key := range hosts {
+		if dryRun {
+			release.Deleted = append(release.Deleted, key)
+			continue
+		}
+		if err := r.deleteRecordKey(ctx, key); err != nil {
+			return release, fmt.Errorf("deleting %s: %w", key, err)
+		}
+		release.Deleted = append(release.Deleted, key)
+	}
+	if dryRun {
+		return release, nil
+	}
+
+	base := obj.DeepCopyObject().(client.Object)
+	controllerutil.RemoveFinalizer(obj, FinalizerName)
+	// Records no longer in Pi-hole are no longer tracked, in case the object lives on
+	if !release.Terminating && len(release.Kept) == 0 && len(release.Deleted) > 0 {
+		annotations := obj.GetAnnotations()
+		delete(annotations, AnnotationManagedHosts)
+		obj.SetAnnotations(annotations)
+	}
+	if err := r.Patch(ctx, obj, client.MergeFrom(base)); err != nil {
+		if apierrors.IsNotFound(err) {
+			release.Gone = true
+			return release, nil
+		}
+		return release, fmt.Errorf("removing finalizer: %w", err)
+	}
+	return release, nil
+}
+
+// uninstallPolicy is the sync policy of obj without reporting an invalid annotation,
+// which the operator has already done
+func (r *IngressReconciler) uninstallPolicy(obj client.Object) SyncPolicy {
+	if value := obj.GetAnnotations()[AnnotationSyncPolicy]; value != "" {
+		if policy, err := ParseSyncPolicy(value); err == nil {
+			return policy
+		}
+	}
+	return r.settings().SyncPolicy
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// finalizedIngress is a registered Ingress holding the cleanup finalizer and tracking hosts
+func finalizedIngress(name string, annotations map[string]string, hosts ...string) *networkingv1.Ingress {
+	ingress := testIngress(name, annotations, hosts...)
+	ingress.Finalizers = []string{FinalizerName}
+	return ingress
+}
+
+func TestFinalizerHolders(t *testing.T) {
+	other := finalizedIngress("other", nil, "other.local")
+	other.Namespace = "apps"
+	r := newTestReconciler(newFakePiholeClient(),
+		finalizedIngress("web", nil, "web.local"),
+		testIngress("plain", nil, "plain.local"),
+		other,
+	)
+
+	holders, err := r.FinalizerHolders(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("FinalizerHolders() unexpected error: %v", err)
+	}
+	if len(holders) != 2 {
+		t.Errorf("FinalizerHolders() = %d objects, want 2", len(holders))
+	}
+	holders, err = r.FinalizerHolders(context.Background(), []string{"apps"})
+	if err != nil {
+		t.Fatalf("FinalizerHolders(apps) unexpected error: %v", err)
+	}
+	if len(holders) != 1 || holders[0].GetName() != "other" {
+		t.Errorf("FinalizerHolders(apps) = %v, want only apps/other", holders)
+	}
+}
+
+func TestReleaseFinalizer(t *testing.T) {
+	ctx := context.Background()
+	terminating := finalizedIngress("gone", map[string]string{AnnotationManagedHosts: "gone.local"}, "gone.local")
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	ph := newFakePiholeClient(
+		pihole.DNSRecord{Domain: "web.local", IP: "192.168.1.100"},
+		pihole.DNSRecord{Domain: "gone.local", IP: "192.168.1.100"},
+		pihole.DNSRecord{Domain: "kept.local", IP: "192.168.1.100"},
+	)
+	r := newTestReconciler(ph,
+		finalizedIngress("web", map[string]string{AnnotationManagedHosts: "web.local"}, "web.local"),
+		finalizedIngress("kept", map[string]string{
+			AnnotationManagedHosts: "kept.local",
+			AnnotationSyncPolicy:   string(SyncPolicyUpsertOnly),
+		}, "kept.local"),
+		terminating,
+	)
+
+	// A dry run reports the deletions and changes nothing
+	web := getIngress(t, r, "default", "web")
+	release, err := r.ReleaseFinalizer(ctx, web, true, true)
+	if err != nil {
+		t.Fatalf("ReleaseFinalizer(dry run) unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(release.Deleted, []string{"web.local"}) || len(ph.records) != 3 {
+		t.Errorf("dry run = %+v with %d records left, want web.local to be reported only", release, len(ph.records))
+	}
+	if web = getIngress(t, r, "default", "web"); len(web.Finalizers) != 1 {
+		t.Errorf("dry run removed the finalizer: %v", web.Finalizers)
+	}
+
+	release, err = r.ReleaseFinalizer(ctx, web, true, false)
+	if err != nil {
+		t.Fatalf("ReleaseFinalizer() unexpected error: %v", err)
+	}
+	if _, ok := ph.records["web.local"]; ok || release.Owner != "Ingress default/web" {
+		t.Errorf("release = %+v, want web.local deleted from pi-hole", release)
+	}
+	web = getIngress(t, r, "default", "web")
+	if len(web.Finalizers) != 0 || web.Annotations[AnnotationManagedHosts] != "" {
+		t.Errorf("web = finalizers %v, annotations %v, want both cleared", web.Finalizers, web.Annotations)
+	}
+
+	// The sync policy still forbids deletions
+	release, err = r.ReleaseFinalizer(ctx, getIngress(t, r, "default", "kept"), true, false)
+	if err != nil {
+		t.Fatalf("ReleaseFinalizer(kept) unexpected error: %v", err)
+	}
+	if _, ok := ph.records["kept.local"]; !ok || !reflect.DeepEqual(release.Kept, []string{"kept.local"}) {
+		t.Errorf("release = %+v, want kept.local left in pi-hole", release)
+	}
+	if kept := getIngress(t, r, "default", "kept"); kept.Annotations[AnnotationManagedHosts] != "kept.local" {
+		t.Errorf("managed hosts = %q, want kept.local still tracked", kept.Annotations[AnnotationManagedHosts])
+	}
+
+	// A terminating object is deleted once its finalizer is gone
+	release, err = r.ReleaseFinalizer(ctx, getIngress(t, r, "default", "gone"), false, false)
+	if err != nil {
+		t.Fatalf("ReleaseFinalizer(gone) unexpected error: %v", err)
+	}
+	if !release.Terminating || !reflect.DeepEqual(release.Kept, []string{"gone.local"}) {
+		t.Errorf("release = %+v, want a terminating object with gone.local kept", release)
+	}
+	err = r.Get(ctx, types.NamespacedName{Namespace: "default", Name: "gone"}, &networkingv1.Ingress{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Get(gone) error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+const (
+	// DefaultWipeThreshold is the fraction of managed records that must vanish at once
+	DefaultWipeThreshold = 0.5
+
+	// DefaultWipeMinRecords is how many managed records must have been present for a
+	// listing to be judged
+	DefaultWipeMinRecords = 5
+
+	// DefaultRestoresPerMinute caps how many records a restore creates per minute
+	DefaultRestoresPerMinute = 30
+)
+
+// WipeDetector notices when Pi-hole loses most of the operator's records at once,
+// e.g. when its container is recreated with an empty config volume, and restores
+// them without waiting for every object to reconcile. Reconciles pass each listing
+// to Observe, which compares it with the managed records present in the previous
+// one; records an object has only just asked for were never present, so in-flight
+// creates are not mistaken for losses. A restore recreates every record in the
+// desired index whose owners agree on the IP, at most MaxPerMinute a minute so a
+// recovering Pi-hole is not flooded. A nil *WipeDetector detects nothing.
+type WipeDetector struct {
+	PiholeClient pihole.Client
+	Index        *DesiredIndex
+	Logger       *slog.Logger
+
+	// Threshold is the fraction of previously present records whose loss counts as a
+	// wipe; MinRecords skips the check while fewer were present. Zero values use the
+	// defaults.
+	Threshold  float64
+	MinRecords int
+
+	// MaxPerMinute caps the records created per minute; zero uses the default
+	MaxPerMinute int
+
+	// Audit receives every restored record; nil disables auditing
+	Audit audit.Sink
+
+	// Locks serializes restores with reconciles writing the same domain; nil disables it
+	Locks *DomainLocks
+
+	mu      sync.Mutex
+	present map[string]bool
+	restore chan struct{}
+
+	// window is the period MaxPerMinute applies to; zero means a minute
+	window time.Duration
+}
+
+// Observe checks a listing for a wipe and starts a restore when it finds one. It
+// reports whether a wipe was detected, so the caller can flag the object it reconciles.
+func (w *WipeDetector) Observe(records []pihole.DNSRecord) bool {
+	if w == nil {
+		return false
+	}
+	listed := make(map[string]bool, len(records))
+	for _, rec := range records {
+		listed[rec.Key()] = true
+	}
+
+	w.mu.Lock()
+	var expected, missing int
+	present := make(map[string]bool)
+	for _, rec := range w.Index.Records() {
+		key := pihole.RecordKey(rec.Domain, rec.Type)
+		if w.present[key] {
+			expected++
+			if !listed[key] {
+				missing++
+			}
+		}
+		if listed[key] {
+			present[key] = true
+		}
+	}
+	w.present = present
+	w.mu.Unlock()
+
+	if expected < w.minRecords() || float64(missing) < w.threshold()*float64(expected) {
+		return false
+	}
+
+	metrics.WipesDetected.Inc()
+	w.Logger.Error("pi-hole lost most managed dns records, restoring", "missing", missing, "expected", expected)
+	select {
+	case w.restoreCh() <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Start restores records whenever Observe detects a wipe, until ctx is cancelled;
+// it implements manager.Runnable
+func (w *WipeDetector) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.restoreCh():
+		}
+		w.restoreAll(ctx)
+	}
+}
+
+// restoreAll recreates missing records one rate-limited round at a time until none
+// are missing. A failed round leaves the rest to the reconciles and their backoff.
+func (w *WipeDetector) restoreAll(ctx context.Context) {
+	window := w.window
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	restored := 0
+	for {
+		done, n, err := w.restoreRound(ctx)
+		restored += n
+		if err != nil {
+			w.Logger.Error("restore stopped", "restored", restored, "error", err)
+			return
+		}
+		if done {
+			w.Logger.Info("restore complete", "restored", restored)
+			return
+		}
+		w.Logger.Info("restore rate limit reached, waiting", "restored", restored, "retry_in", window.String())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(window):
+		}
+	}
+}
+
+// restoreRound creates up to MaxPerMinute missing records. done reports that no
+// record was left missing.
+func (w *WipeDetector) restoreRound(ctx context.Context) (done bool, restored int, err error) {
+	current, err := w.PiholeClient.ListRecords(ctx)
+	if err != nil {
+		w.Logger.Error("pihole api error", "operation", "list", "error", err)
+		return false, 0, err
+	}
+	missing := computePlan(current, w.restorable(), nil).Creates
+
+	limit := w.MaxPerMinute
+	if limit <= 0 {
+		limit = DefaultRestoresPerMinute
+	}
+	for i, rec := range missing {
+		if i == limit {
+			return false, restored, nil
+		}
+		unlock := w.Locks.Lock(rec.Domain)
+		err := w.PiholeClient.CreateRecord(ctx, rec)
+		unlock()
+		if err != nil {
+			w.Logger.Error("pihole api error", "operation", "create", "host", rec.Domain, "error", err)
+			return false, restored, err
+		}
+		w.Logger.Info("dns record restored", "host", rec.Key(), "ip", rec.IP)
+		metrics.RecordsRestored.Inc()
+		restored++
+		recordAudit(ctx, w.Audit, audit.Entry{Action: audit.ActionCreate, Domain: rec.Domain, Type: rec.Type(), NewIP: rec.IP, Owner: "wipe restore"}, w.Logger)
+	}
+	return true, restored, nil
+}
+
+// restorable returns the desired records whose owners agree on the IP; conflicting
+// ones are left to the reconciles, which settle who wins
+func (w *WipeDetector) restorable() []pihole.DNSRecord {
+	var records []pihole.DNSRecord
+	for _, rec := range w.Index.Records() {
+		if rec.Conflicting() {
+			continue
+		}
+		for _, ip := range rec.Targets {
+			records = append(records, pihole.DNSRecord{Domain: rec.Domain, IP: ip})
+			break
+		}
+	}
+	return records
+}
+
+func (w *WipeDetector) threshold() float64 {
+	if w.Threshold <= 0 {
+		return DefaultWipeThreshold
+	}
+	return w.Threshold
+}
+
+func (w *WipeDetector) minRecords() int {
+	if w.MinRecords <= 0 {
+		return DefaultWipeMinRecords
+	}
+	return w.MinRecords
+}
+
+// restoreCh returns the channel requesting a restore
+func (w *WipeDetector) restoreCh() chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.restore == nil {
+		w.restore = make(chan struct{}, 1)
+	}
+	return w.restore
+}
+
+// NeedLeaderElection ensures only the leader writes to Pi-hole
+func (w *WipeDetector) NeedLeaderElection() bool {
+	return true
+}
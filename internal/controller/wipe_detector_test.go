@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+)
+
+// indexedRecords adds n records wanted by the named Ingress to x and returns them
+func indexedRecords(x *DesiredIndex, name string, n int) []pihole.DNSRecord {
+	records := make([]pihole.DNSRecord, 0, n)
+	for i := range n {
+		records = append(records, pihole.DNSRecord{Domain: fmt.Sprintf("%s-%d.local", name, i), IP: "10.0.0.1"})
+	}
+	x.Set("Ingress default/"+name, records)
+	return records
+}
+
+func TestWipeDetectorObserve(t *testing.T) {
+	x := &DesiredIndex{}
+	w := &WipeDetector{Index: x, Logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+	records := indexedRecords(x, "a", 6)
+
+	// Nothing was present before the first listing
+	if w.Observe(nil) {
+		t.Error("empty first listing detected as a wipe")
+	}
+	if w.Observe(records) {
+		t.Error("complete listing detected as a wipe")
+	}
+	// Losing a third of the records is below the threshold
+	if w.Observe(records[2:]) {
+		t.Error("listing missing 2 of 6 records detected as a wipe")
+	}
+
+	// Records only just wanted were never present, so they are not missing
+	indexedRecords(x, "b", 10)
+	if w.Observe(records) {
+		t.Error("records not yet created counted as lost")
+	}
+
+	if !w.Observe(records[:2]) {
+		t.Fatal("listing missing 4 of 6 records not detected as a wipe")
+	}
+	select {
+	case <-w.restoreCh():
+	default:
+		t.Error("wipe did not request a restore")
+	}
+	// The remaining records are the new baseline, too few to judge
+	if w.Observe(nil) {
+		t.Error("wipe detected twice")
+	}
+}
+
+func TestWipeDetectorRestore(t *testing.T) {
+	x := &DesiredIndex{}
+	records := indexedRecords(x, "a", 5)
+	x.Set("Ingress default/b", []pihole.DNSRecord{{Domain: "shared.local", IP: "10.0.0.1"}})
+	x.Set("Ingress default/c", []pihole.DNSRecord{{Domain: "shared.local", IP: "10.0.0.2"}})
+
+	ph := newFakePiholeClient(records[0])
+	w := &WipeDetector{
+		PiholeClient: ph,
+		Index:        x,
+		Logger:       slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		MaxPerMinute: 2,
+		window:       time.Millisecond,
+	}
+	w.restoreAll(context.Background())
+
+	for _, rec := range records {
+		if ph.ip(rec.Domain) != rec.IP {
+			t.Errorf("%s not restored", rec.Domain)
+		}
+	}
+	if ph.ip("shared.local") != "" {
+		t.Error("record with conflicting owners restored")
+	}
+	// 4 creates at 2 per round
+	lists := 0
+	for _, call := range ph.calls {
+		if call == "list" {
+			lists++
+		}
+	}
+	if lists != 2 {
+		t.Errorf("restore listed %d times, want 2 rounds: %v", lists, ph.calls)
+	}
+}
+
+func TestReconcileWipeDetected(t *testing.T) {
+	ph := newFakePiholeClient()
+	r := newTestReconciler(ph,
+		testIngress("a", map[string]string{AnnotationRegister: "true"}, "a1.local", "a2.local", "a3.local"),
+		testIngress("b", map[string]string{AnnotationRegister: "true"}, "b1.local", "b2.local", "b3.local"),
+	)
+	r.DisableFinalizers = true
+	r.Index = &DesiredIndex{}
+	r.Wipes = &WipeDetector{Index: r.Index, Logger: r.Logger}
+
+	reconcileIngress(t, r, "default", "a")
+	reconcileIngress(t, r, "default", "b")
+	// The next listing sees every record, making them the baseline
+	reconcileIngress(t, r, "default", "a")
+
+	ph.records = make(map[string]string)
+	reconcileIngress(t, r, "default", "b")
+
+	events := r.Recorder.(*record.FakeRecorder).Events
+	select {
+	case event := <-events:
+		if !strings.Contains(event, "PiholeWipeDetected") {
+			t.Errorf("event = %q, want PiholeWipeDetected", event)
+		}
+	default:
+		t.Error("no event for the wipe")
+	}
+}
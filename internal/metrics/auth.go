@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-logr/logr"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	authenticationclient "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	authorizationclient "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/rest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// WithAuthenticationAndAuthorization is a metrics server FilterProvider that only lets
+// through requests whose bearer token the API server accepts and whose user may get the
+// requested non-resource URL, such as the metrics-reader ClusterRole grants for /metrics.
+// It does what controller-runtime's filters package does without depending on
+// k8s.io/apiserver.
+func WithAuthenticationAndAuthorization(c *rest.Config, httpClient *http.Client) (metricsserver.Filter, error) {
+	clientset, err := kubernetes.NewForConfigAndClient(c, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return authFilter(clientset.AuthenticationV1().TokenReviews(), clientset.AuthorizationV1().SubjectAccessReviews()), nil
+}
+
+// authFilter checks each request with a TokenReview and a SubjectAccessReview
+func authFilter(tokens authenticationclient.TokenReviewInterface,
+	access authorizationclient.SubjectAccessReviewInterface) metricsserver.Filter {
+	return func(log logr.Logger, handler http.Handler) (http.Handler, error) {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			review, err := tokens.Create(req.Context(), &authenticationv1.TokenReview{
+				Spec: authenticationv1.TokenReviewSpec{Token: token},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				log.Error(err, "token review failed")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !review.Status.Authenticated {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			user := review.Status.User
+			extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+			for k, v := range user.Extra {
+				extra[k] = authorizationv1.ExtraValue(v)
+			}
+			decision, err := access.Create(req.Context(), &authorizationv1.SubjectAccessReview{
+				Spec: authorizationv1.SubjectAccessReviewSpec{
+					User:   user.Username,
+					UID:    user.UID,
+					Groups: user.Groups,
+					Extra:  extra,
+					NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+						Path: req.URL.Path,
+						Verb: strings.ToLower(req.Method),
+					},
+				},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				log.Error(err, "subject access review failed")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !decision.Status.Allowed {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			handler.ServeHTTP(w, req)
+		}), nil
+	}
+}
@@ -0,0 +1,256 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/version"
+)
+
+// DefaultPrefix is the prefix applied to every operator metric unless configured otherwise
+const DefaultPrefix = "pihole"
+
+// The operator's collectors. They are created with DefaultPrefix when the package is
+// loaded and replaced by Configure.
+var (
+	// DeletionsBlocked counts reconciles whose record deletions were refused by the deletion guard
+	DeletionsBlocked *prometheus.CounterVec
+
+	// HostsSkipped counts extracted hosts dropped by the host filter
+	HostsSkipped *prometheus.CounterVec
+
+	// DeletionsSkipped counts record deletions withheld by a non-sync sync policy
+	DeletionsSkipped *prometheus.CounterVec
+
+	// NotificationFailures counts change notifications that could not be delivered
+	NotificationFailures *prometheus.CounterVec
+
+	// FinalizerTimeouts counts deletions whose finalizer was removed before DNS cleanup succeeded
+	FinalizerTimeouts prometheus.Counter
+
+	// WipesDetected counts listings in which most previously present managed records had vanished
+	WipesDetected prometheus.Counter
+
+	// RecordsRestored counts records recreated after a wipe
+	RecordsRestored prometheus.Counter
+
+	// RecordsAdopted counts pre-existing Pi-hole records taken over without a write, by kind
+	RecordsAdopted *prometheus.CounterVec
+
+	// ReconcileDuration times the Pi-hole-coupled phases of a reconcile, by kind and phase
+	ReconcileDuration *prometheus.HistogramVec
+
+	// ReconcileOutcomes counts finished reconciles by kind and outcome
+	ReconcileOutcomes *prometheus.CounterVec
+
+	// RecordDrift is the number of desired records Pi-hole lacks or holds with another IP, by kind
+	RecordDrift *prometheus.GaugeVec
+
+	// ForeignRecords is the number of Pi-hole records holding a managed domain with an unwanted IP
+	ForeignRecords prometheus.Gauge
+
+	// PendingDeletions is the number of deleted objects whose finalizer waits for DNS cleanup, by kind
+	PendingDeletions *prometheus.GaugeVec
+
+	// OldestPendingDeletion is how long the longest-waiting of those objects has been deleted
+	OldestPendingDeletion prometheus.Gauge
+
+	// PiholeUp is 1 when the last call to a Pi-hole instance reached it, and 0 when it did not
+	PiholeUp *prometheus.GaugeVec
+
+	// LastSuccessfulOperation is when each kind of Pi-hole operation last succeeded, by instance
+	LastSuccessfulOperation *prometheus.GaugeVec
+
+	// PiholeRecords is the number of local DNS records each Pi-hole held at its last
+	// listing, managed or not
+	PiholeRecords *prometheus.GaugeVec
+
+	// PiholeSessionValidUntil is when each Pi-hole session expires unless renewed; 0 after logout
+	PiholeSessionValidUntil *prometheus.GaugeVec
+
+	// BuildInfo is always 1, labelled with the version of the running operator
+	BuildInfo *prometheus.GaugeVec
+)
+
+// ReconcileBuckets are the buckets of ReconcileDuration, from a fast local Pi-hole up
+// to a slow one near the client timeout
+var ReconcileBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30}
+
+// Factory creates collectors named after Prefix and carrying ConstLabels, and registers
+// them with Registerer. Registration is idempotent: asking again for a collector that
+// is already registered returns the existing one, with its counts.
+type Factory struct {
+	Registerer  prometheus.Registerer
+	Prefix      string
+	ConstLabels prometheus.Labels
+
+	collectors []prometheus.Collector
+	errs       []error
+}
+
+// CounterVec returns the counter vector name, partitioned by labels
+func (f *Factory) CounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	return register(f, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   f.Prefix,
+		Name:        name,
+		Help:        help,
+		ConstLabels: f.ConstLabels,
+	}, labels))
+}
+
+// Counter returns the counter name
+func (f *Factory) Counter(name, help string) prometheus.Counter {
+	return register(f, prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   f.Prefix,
+		Name:        name,
+		Help:        help,
+		ConstLabels: f.ConstLabels,
+	}))
+}
+
+// Gauge returns the gauge name
+func (f *Factory) Gauge(name, help string) prometheus.Gauge {
+	return register(f, prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   f.Prefix,
+		Name:        name,
+		Help:        help,
+		ConstLabels: f.ConstLabels,
+	}))
+}
+
+// GaugeVec returns the gauge vector name, partitioned by labels
+func (f *Factory) GaugeVec(name, help string, labels ...string) *prometheus.GaugeVec {
+	return register(f, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   f.Prefix,
+		Name:        name,
+		Help:        help,
+		ConstLabels: f.ConstLabels,
+	}, labels))
+}
+
+// HistogramVec returns the histogram vector name with the given buckets, partitioned by labels
+func (f *Factory) HistogramVec(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	return register(f, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   f.Prefix,
+		Name:        name,
+		Help:        help,
+		ConstLabels: f.ConstLabels,
+		Buckets:     buckets,
+	}, labels))
+}
+
+// Collectors returns the collectors created so far
+func (f *Factory) Collectors() []prometheus.Collector {
+	return f.collectors
+}
+
+// Err returns the registration failures, such as a constant label clashing with a
+// collector's own labels
+func (f *Factory) Err() error {
+	return errors.Join(f.errs...)
+}
+
+// register registers c, returning the collector already registered in its place if any
+func register[C prometheus.Collector](f *Factory, c C) C {
+	if err := f.Registerer.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			f.errs = append(f.errs, err)
+			return c
+		}
+		existing, ok := are.ExistingCollector.(C)
+		if !ok {
+			f.errs = append(f.errs, err)
+			return c
+		}
+		c = existing
+	}
+	f.collectors = append(f.collectors, c)
+	return c
+}
+
+var (
+	mu          sync.Mutex
+	prefix      string
+	constLabels prometheus.Labels
+	current     []prometheus.Collector
+)
+
+func init() {
+	if err := Configure(DefaultPrefix, nil); err != nil {
+		panic(err)
+	}
+}
+
+// Configure recreates the operator's collectors in the controller-runtime registry with
+// the given prefix and constant labels, unregistering the previous ones. Configuring the
+// same prefix and labels again keeps the existing collectors. It must be called before
+// the controllers start.
+func Configure(metricsPrefix string, labels map[string]string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if current != nil && metricsPrefix == prefix && maps.Equal(labels, constLabels) {
+		return nil
+	}
+	for _, c := range current {
+		ctrlmetrics.Registry.Unregister(c)
+	}
+
+	f := &Factory{Registerer: ctrlmetrics.Registry, Prefix: metricsPrefix, ConstLabels: labels}
+	DeletionsBlocked = f.CounterVec("deletions_blocked_total",
+		"Number of reconciles whose DNS record deletions were blocked by a safety threshold.", "scope")
+	HostsSkipped = f.CounterVec("hosts_skipped_total",
+		"Number of extracted hostnames skipped because they are IP literals or cluster-internal names.", "reason")
+	DeletionsSkipped = f.CounterVec("deletions_skipped_total",
+		"Number of DNS record deletions not executed because of the sync policy.", "policy")
+	NotificationFailures = f.CounterVec("notification_failures_total",
+		"Number of DNS change notifications dropped or not delivered after retries.", "reason")
+	FinalizerTimeouts = f.Counter("finalizer_timeouts_total",
+		"Number of Ingress deletions released before their DNS records could be removed.")
+	WipesDetected = f.Counter("wipes_detected_total",
+		"Number of times Pi-hole was found to have lost most of the operator's DNS records.")
+	RecordsRestored = f.Counter("records_restored_total",
+		"Number of DNS records recreated after Pi-hole lost them.")
+	RecordsAdopted = f.CounterVec("records_adopted_total",
+		"Number of DNS records that already existed in Pi-hole with the desired IP and were adopted without a write.",
+		"kind")
+	ReconcileDuration = f.HistogramVec("reconcile_duration_seconds",
+		"Duration of the Pi-hole-coupled phases of a reconcile: list, sync, prune and annotate.",
+		ReconcileBuckets, "kind", "phase")
+	ReconcileOutcomes = f.CounterVec("reconcile_outcomes_total",
+		"Number of finished reconciles by outcome: success, requeue, error, skipped-no-hosts or skipped-invalid-ip.",
+		"kind", "outcome")
+	RecordDrift = f.GaugeVec("record_drift",
+		"Number of desired DNS records missing from Pi-hole or present with an IP no object wants.", "kind")
+	ForeignRecords = f.Gauge("foreign_records",
+		"Number of Pi-hole records for a managed domain and type with an IP no object wants.")
+	PendingDeletions = f.GaugeVec("pending_deletions",
+		"Number of deleted objects still held by the finalizer while their DNS cleanup is retried.", "kind")
+	OldestPendingDeletion = f.Gauge("oldest_pending_deletion_seconds",
+		"Seconds since the longest-waiting deleted object held by the finalizer was deleted; 0 when there is none.")
+	PiholeUp = f.GaugeVec("up",
+		"Whether the last call to the Pi-hole instance reached a working API (1) or not (0).", "instance")
+	LastSuccessfulOperation = f.GaugeVec("last_successful_operation_timestamp_seconds",
+		"Unix time of the last successful Pi-hole operation: list, create, delete, batch or health.",
+		"instance", "operation")
+	PiholeRecords = f.GaugeVec("total_records",
+		"Local DNS records Pi-hole held at its last listing, managed by the operator or not.", "instance")
+	PiholeSessionValidUntil = f.GaugeVec("session_valid_until_timestamp_seconds",
+		"Unix time at which the operator's Pi-hole session expires unless renewed.", "instance")
+	BuildInfo = f.GaugeVec("operator_build_info",
+		"Always 1, labelled with the version, commit and Go version of the running operator.",
+		"version", "commit", "go_version")
+	BuildInfo.WithLabelValues(version.Version, version.Commit, runtime.Version()).Set(1)
+
+	prefix, constLabels, current = metricsPrefix, labels, f.Collectors()
+	if err := f.Err(); err != nil {
+		return fmt.Errorf("registering metrics: %w", err)
+	}
+	return nil
+}
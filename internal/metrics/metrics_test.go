@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/version"
+)
+
+// gathered returns the names of the metric families in reg starting with prefix, and
+// the labels of their first series
+func gathered(t *testing.T, reg prometheus.Gatherer, prefix string) ([]string, map[string]string) {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() unexpected error: %v", err)
+	}
+	var names []string
+	labels := map[string]string{}
+	for _, mf := range families {
+		if !strings.HasPrefix(mf.GetName(), prefix) {
+			continue
+		}
+		names = append(names, mf.GetName())
+		for _, lp := range mf.GetMetric()[0].GetLabel() {
+			labels[lp.GetName()] = lp.GetValue()
+		}
+	}
+	return names, labels
+}
+
+func TestFactory(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	f := &Factory{Registerer: reg, Prefix: "homelab_dns", ConstLabels: prometheus.Labels{"cluster": "home"}}
+	counter := f.Counter("wipes_detected_total", "Wipes.")
+	vec := f.CounterVec("hosts_skipped_total", "Skipped hosts.", "reason")
+	counter.Inc()
+	vec.WithLabelValues("internal").Inc()
+	if err := f.Err(); err != nil {
+		t.Fatalf("Err() = %v, want nil", err)
+	}
+
+	names, labels := gathered(t, reg, "")
+	want := []string{"homelab_dns_hosts_skipped_total", "homelab_dns_wipes_detected_total"}
+	if !slices.Equal(names, want) {
+		t.Errorf("metric names = %v, want %v", names, want)
+	}
+	if labels["cluster"] != "home" {
+		t.Errorf("cluster label = %q, want %q", labels["cluster"], "home")
+	}
+
+	// A second factory on the same registry gets the registered collectors back
+	again := &Factory{Registerer: reg, Prefix: "homelab_dns", ConstLabels: prometheus.Labels{"cluster": "home"}}
+	if got := again.Counter("wipes_detected_total", "Wipes."); got != counter {
+		t.Error("Counter() registered a second collector, want the existing one")
+	}
+	if got := again.CounterVec("hosts_skipped_total", "Skipped hosts.", "reason"); got != vec {
+		t.Error("CounterVec() registered a second collector, want the existing one")
+	}
+	if err := again.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}
+
+func TestFactoryLabelClash(t *testing.T) {
+	f := &Factory{Registerer: prometheus.NewRegistry(), Prefix: "pihole", ConstLabels: prometheus.Labels{"reason": "x"}}
+	f.CounterVec("hosts_skipped_total", "Skipped hosts.", "reason")
+	if f.Err() == nil {
+		t.Error("Err() = nil, want an error for a constant label named like a variable one")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	t.Cleanup(func() {
+		if err := Configure(DefaultPrefix, nil); err != nil {
+			t.Errorf("Configure() restoring defaults: %v", err)
+		}
+	})
+
+	if err := Configure("homelab_dns", map[string]string{"cluster": "home"}); err != nil {
+		t.Fatalf("Configure() unexpected error: %v", err)
+	}
+	WipesDetected.Inc()
+	before := WipesDetected
+
+	// Configuring the same settings again keeps the collectors and their counts
+	if err := Configure("homelab_dns", map[string]string{"cluster": "home"}); err != nil {
+		t.Fatalf("Configure() unexpected error: %v", err)
+	}
+	if WipesDetected != before {
+		t.Error("Configure() with unchanged settings replaced the collectors")
+	}
+
+	names, labels := gathered(t, ctrlmetrics.Registry, "homelab_dns_")
+	if !slices.Contains(names, "homelab_dns_wipes_detected_total") || labels["cluster"] != "home" {
+		t.Errorf("gathered %v with labels %v, want homelab_dns_wipes_detected_total{cluster=home}", names, labels)
+	}
+	if old, _ := gathered(t, ctrlmetrics.Registry, DefaultPrefix+"_"); len(old) != 0 {
+		t.Errorf("metrics with the previous prefix still registered: %v", old)
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	families, err := ctrlmetrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() unexpected error: %v", err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != "pihole_operator_build_info" {
+			continue
+		}
+		m := mf.GetMetric()[0]
+		labels := map[string]string{}
+		for _, lp := range m.GetLabel() {
+			labels[lp.GetName()] = lp.GetValue()
+		}
+		if m.GetGauge().GetValue() != 1 || labels["version"] != version.Version || labels["commit"] != version.Commit ||
+			labels["go_version"] != runtime.Version() {
+			t.Errorf("build info = %v with labels %v, want 1 with the build's version", m.GetGauge().GetValue(), labels)
+		}
+		return
+	}
+	t.Error("pihole_operator_build_info is not registered")
+}
@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+)
+
+const (
+	// DefaultQueueSize bounds the number of summaries waiting for delivery
+	DefaultQueueSize = 100
+
+	// deliveryAttempts is how many times a summary is sent before giving up
+	deliveryAttempts = 3
+
+	// retryDelay is the wait before the first retry; it doubles on each attempt
+	retryDelay = 2 * time.Second
+)
+
+// Dispatcher delivers summaries asynchronously so a slow endpoint can't stall
+// reconciliation. Summaries are dropped when the queue is full.
+type Dispatcher struct {
+	notifier Notifier
+	events   map[string]bool
+	queue    chan Summary
+	logger   *slog.Logger
+
+	// retryDelay is overridden in tests
+	retryDelay time.Duration
+}
+
+// NewDispatcher creates a Dispatcher forwarding changes whose action is in events
+func NewDispatcher(n Notifier, events []string, queueSize int, logger *slog.Logger) *Dispatcher {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	allowed := make(map[string]bool, len(events))
+	for _, e := range events {
+		allowed[e] = true
+	}
+	return &Dispatcher{
+		notifier:   n,
+		events:     allowed,
+		queue:      make(chan Summary, queueSize),
+		logger:     logger,
+		retryDelay: retryDelay,
+	}
+}
+
+// Enqueue queues summary for delivery, keeping only the configured event types.
+// It never blocks and is a no-op on a nil Dispatcher.
+func (d *Dispatcher) Enqueue(summary Summary) {
+	if d == nil {
+		return
+	}
+
+	changes := make([]Change, 0, len(summary.Changes))
+	for _, c := range summary.Changes {
+		if d.events[c.Action] {
+			changes = append(changes, c)
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	summary.Changes = changes
+	if summary.Time.IsZero() {
+		summary.Time = time.Now().UTC()
+	}
+
+	select {
+	case d.queue <- summary:
+	default:
+		d.logger.Warn("notification queue full, dropping notification", "owner", summary.Owner)
+		metrics.NotificationFailures.WithLabelValues("queue-full").Inc()
+	}
+}
+
+// Start delivers queued summaries until ctx is cancelled; it implements manager.Runnable
+func (d *Dispatcher) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case summary := <-d.queue:
+			d.deliver(ctx, summary)
+		}
+	}
+}
+
+// Flush delivers the summaries still queued and returns once the queue is empty, for
+// a process about to exit after Start has returned. It is a no-op on a nil Dispatcher.
+func (d *Dispatcher) Flush(ctx context.Context) {
+	if d == nil {
+		return
+	}
+	for {
+		select {
+		case summary := <-d.queue:
+			d.deliver(ctx, summary)
+		default:
+			return
+		}
+	}
+}
+
+// NeedLeaderElection lets every replica drain its own queue
+func (d *Dispatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// deliver sends summary, retrying with a doubling delay
+func (d *Dispatcher) deliver(ctx context.Context, summary Summary) {
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		err := d.notifier.Notify(ctx, summary)
+		if err == nil {
+			return
+		}
+		if attempt >= deliveryAttempts {
+			d.logger.Error("notification failed", "owner", summary.Owner, "attempts", attempt, "error", err)
+			metrics.NotificationFailures.WithLabelValues("delivery").Inc()
+			return
+		}
+		d.logger.Debug("notification failed, retrying", "owner", summary.Owner, "attempt", attempt, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/audit"
+)
+
+// Supported notification formats
+const (
+	FormatJSON = "json"
+	FormatNtfy = "ntfy"
+)
+
+// defaultTimeout bounds a single delivery attempt
+const defaultTimeout = 10 * time.Second
+
+// WebhookNotifier POSTs the summary as JSON
+type WebhookNotifier struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier creates a WebhookNotifier posting to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, httpClient: &http.Client{Timeout: defaultTimeout}}
+}
+
+// Notify posts summary to the webhook
+func (n *WebhookNotifier) Notify(ctx context.Context, summary Summary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("encoding notification: %w", err)
+	}
+	return post(ctx, n.httpClient, n.url, "application/json", body, nil)
+}
+
+// NtfyNotifier POSTs a plain-text message in the style of ntfy.sh
+type NtfyNotifier struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewNtfyNotifier creates an NtfyNotifier posting to a topic url
+func NewNtfyNotifier(url string) *NtfyNotifier {
+	return &NtfyNotifier{url: url, httpClient: &http.Client{Timeout: defaultTimeout}}
+}
+
+// Notify posts summary as one line per change
+func (n *NtfyNotifier) Notify(ctx context.Context, summary Summary) error {
+	var b strings.Builder
+	deleted := false
+	for _, c := range summary.Changes {
+		switch c.Action {
+		case audit.ActionCreate:
+			fmt.Fprintf(&b, "created %s -> %s\n", c.Domain, c.NewIP)
+		case audit.ActionUpdate:
+			fmt.Fprintf(&b, "updated %s %s -> %s\n", c.Domain, c.OldIP, c.NewIP)
+		case audit.ActionDelete:
+			deleted = true
+			fmt.Fprintf(&b, "deleted %s\n", c.Domain)
+		}
+	}
+
+	headers := map[string]string{"Title": "Pi-hole DNS changes: " + summary.Owner}
+	if deleted {
+		headers["Tags"] = "warning"
+	}
+	return post(ctx, n.httpClient, n.url, "text/plain", []byte(b.String()), headers)
+}
+
+// post sends body to url and treats any non-2xx response as an error
+func post(ctx context.Context, c *http.Client, url, contentType string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending notification: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification endpoint returned %s", resp.Status)
+	}
+	return nil
+}
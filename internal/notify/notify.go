@@ -0,0 +1,27 @@
+// Package notify delivers summaries of DNS changes to external endpoints
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// Change is a single DNS mutation. Action is one of the audit actions.
+type Change struct {
+	Action string `json:"action"`
+	Domain string `json:"domain"`
+	OldIP  string `json:"oldIP,omitempty"`
+	NewIP  string `json:"newIP,omitempty"`
+}
+
+// Summary batches the changes made by one reconcile
+type Summary struct {
+	Owner   string    `json:"owner"`
+	Time    time.Time `json:"time"`
+	Changes []Change  `json:"changes"`
+}
+
+// Notifier delivers a summary to an external endpoint
+type Notifier interface {
+	Notify(ctx context.Context, summary Summary) error
+}
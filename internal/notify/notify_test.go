@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var got Summary
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	want := Summary{
+		Owner:   "Ingress default/app",
+		Time:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Changes: []Change{{Action: "delete", Domain: "app.local", OldIP: "192.168.1.100"}},
+	}
+	if err := NewWebhookNotifier(srv.URL).Notify(context.Background(), want); err != nil {
+		t.Fatalf("Notify() unexpected error: %v", err)
+	}
+	if got.Owner != want.Owner || len(got.Changes) != 1 || got.Changes[0] != want.Changes[0] {
+		t.Errorf("received %+v, want %+v", got, want)
+	}
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	var body, title, tags string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		body, title, tags = string(raw), r.Header.Get("Title"), r.Header.Get("Tags")
+	}))
+	defer srv.Close()
+
+	summary := Summary{
+		Owner: "Ingress default/app",
+		Changes: []Change{
+			{Action: "create", Domain: "new.local", NewIP: "10.0.0.1"},
+			{Action: "update", Domain: "nas.local", OldIP: "10.0.0.2", NewIP: "10.0.0.3"},
+			{Action: "delete", Domain: "old.local"},
+		},
+	}
+	if err := NewNtfyNotifier(srv.URL).Notify(context.Background(), summary); err != nil {
+		t.Fatalf("Notify() unexpected error: %v", err)
+	}
+
+	wantBody := "created new.local -> 10.0.0.1\nupdated nas.local 10.0.0.2 -> 10.0.0.3\ndeleted old.local\n"
+	if body != wantBody {
+		t.Errorf("body = %q, want %q", body, wantBody)
+	}
+	if title != "Pi-hole DNS changes: Ingress default/app" {
+		t.Errorf("Title = %q", title)
+	}
+	if tags != "warning" {
+		t.Errorf("Tags = %q, want warning for deletions", tags)
+	}
+}
+
+func TestNotifierErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	if err := NewWebhookNotifier(srv.URL).Notify(context.Background(), Summary{}); err == nil {
+		t.Error("Notify() expected error for 502, got nil")
+	}
+}
+
+// recordingNotifier fails the first failures calls and records delivered summaries
+type recordingNotifier struct {
+	mu        sync.Mutex
+	failures  int
+	calls     int
+	delivered []Summary
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, s Summary) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls++
+	if n.calls <= n.failures {
+		return errors.New("unavailable")
+	}
+	n.delivered = append(n.delivered, s)
+	return nil
+}
+
+func TestDispatcherFiltersEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := NewDispatcher(&recordingNotifier{}, []string{"delete"}, 10, logger)
+
+	d.Enqueue(Summary{Owner: "a", Changes: []Change{{Action: "create", Domain: "a.local"}}})
+	d.Enqueue(Summary{Owner: "b", Changes: []Change{
+		{Action: "create", Domain: "b.local"},
+		{Action: "delete", Domain: "c.local"},
+	}})
+
+	if len(d.queue) != 1 {
+		t.Fatalf("queued %d summaries, want 1", len(d.queue))
+	}
+	got := <-d.queue
+	if got.Owner != "b" || len(got.Changes) != 1 || got.Changes[0].Domain != "c.local" {
+		t.Errorf("queued %+v, want only the deletion of c.local", got)
+	}
+	if got.Time.IsZero() {
+		t.Error("queued summary should be timestamped")
+	}
+}
+
+func TestDispatcherDropsWhenFull(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := NewDispatcher(&recordingNotifier{}, []string{"create"}, 1, logger)
+
+	for i := 0; i < 3; i++ {
+		d.Enqueue(Summary{Changes: []Change{{Action: "create", Domain: "a.local"}}})
+	}
+	if len(d.queue) != 1 {
+		t.Errorf("queued %d summaries, want 1", len(d.queue))
+	}
+
+	var nilDispatcher *Dispatcher
+	nilDispatcher.Enqueue(Summary{Changes: []Change{{Action: "create"}}})
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name          string
+		failures      int
+		wantCalls     int
+		wantDelivered int
+	}{
+		{name: "first attempt succeeds", failures: 0, wantCalls: 1, wantDelivered: 1},
+		{name: "succeeds after retry", failures: 2, wantCalls: 3, wantDelivered: 1},
+		{name: "gives up", failures: 5, wantCalls: deliveryAttempts, wantDelivered: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &recordingNotifier{failures: tt.failures}
+			d := NewDispatcher(n, []string{"create"}, 1, logger)
+			d.retryDelay = time.Millisecond
+
+			d.deliver(context.Background(), Summary{Changes: []Change{{Action: "create", Domain: "a.local"}}})
+
+			if n.calls != tt.wantCalls || len(n.delivered) != tt.wantDelivered {
+				t.Errorf("calls = %d delivered = %d, want %d/%d", n.calls, len(n.delivered), tt.wantCalls, tt.wantDelivered)
+			}
+		})
+	}
+}
+
+func TestDispatcherFlush(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	n := &recordingNotifier{}
+	d := NewDispatcher(n, []string{"create"}, 10, logger)
+
+	d.Enqueue(Summary{Owner: "a", Changes: []Change{{Action: "create", Domain: "a.local"}}})
+	d.Enqueue(Summary{Owner: "b", Changes: []Change{{Action: "create", Domain: "b.local"}}})
+	d.Flush(context.Background())
+
+	if len(n.delivered) != 2 || len(d.queue) != 0 {
+		t.Errorf("delivered %d summaries with %d left queued, want 2 and none", len(n.delivered), len(d.queue))
+	}
+
+	var nilDispatcher *Dispatcher
+	nilDispatcher.Flush(context.Background())
+}
@@ -0,0 +1,163 @@
+package pihole
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+// Batch is a set of record changes applied together. Deletes are applied before
+// creates, so a batch can move a record to a new IP.
+type Batch struct {
+	// Deletes holds the RecordKey of each record to remove
+	Deletes []string
+	Creates []DNSRecord
+}
+
+// Len returns the number of changes in the batch
+func (b Batch) Len() int {
+	return len(b.Deletes) + len(b.Creates)
+}
+
+// Merge appends the changes of other to b
+func (b *Batch) Merge(other Batch) {
+	b.Deletes = append(b.Deletes, other.Deletes...)
+	b.Creates = append(b.Creates, other.Creates...)
+}
+
+// apply returns records with the batch applied. A create whose key is still listed
+// replaces that entry in place, so the hosts list never holds a key twice.
+func (b Batch) apply(records []DNSRecord) []DNSRecord {
+	out := make([]DNSRecord, 0, len(records)+len(b.Creates))
+	index := make(map[string]int, len(records)+len(b.Creates))
+	for _, r := range records {
+		if !slices.Contains(b.Deletes, r.Key()) {
+			index[r.Key()] = len(out)
+			out = append(out, r)
+		}
+	}
+	for _, r := range b.Creates {
+		if i, ok := index[r.Key()]; ok {
+			out[i] = r
+			continue
+		}
+		index[r.Key()] = len(out)
+		out = append(out, r)
+	}
+	return out
+}
+
+// BatchWriter is implemented by clients that can apply a batch in one request
+type BatchWriter interface {
+	ApplyBatch(ctx context.Context, batch Batch) error
+}
+
+// ApplyBatch applies batch through c, in one request if c is a BatchWriter and
+// record by record otherwise
+func ApplyBatch(ctx context.Context, c Client, batch Batch) error {
+	if bw, ok := c.(BatchWriter); ok {
+		return bw.ApplyBatch(ctx, batch)
+	}
+	for _, key := range batch.Deletes {
+		domain, recordType := ParseRecordKey(key)
+		if err := c.DeleteRecord(ctx, domain, recordType); err != nil {
+			return err
+		}
+	}
+	for _, record := range batch.Creates {
+		if err := c.CreateRecord(ctx, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyBatch replaces the hosts list with a single PATCH. The list is read and written
+// while holding the client's write lock so its own record calls cannot be lost.
+func (c *HTTPClient) ApplyBatch(ctx context.Context, batch Batch) error {
+	ctx, release := c.callContext(ctx)
+	defer release()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	err := c.applyBatch(ctx, batch)
+	c.observe(ctx, OperationBatch, err)
+	return err
+}
+
+func (c *HTTPClient) applyBatch(ctx context.Context, batch Batch) error {
+	records, err := c.listRecords(ctx)
+	if err != nil {
+		return fmt.Errorf("listing records for batch: %w", err)
+	}
+
+	// Records of other clusters are kept whatever the batch deletes
+	cluster := c.clusterID()
+	var own, others []DNSRecord
+	for _, r := range records {
+		if r.OtherCluster(cluster) {
+			others = append(others, r)
+		} else {
+			own = append(own, r)
+		}
+	}
+	creates := make([]DNSRecord, 0, len(batch.Creates))
+	for _, r := range batch.Creates {
+		r.Cluster = cluster
+		creates = append(creates, r)
+	}
+	batch.Creates = creates
+
+	records = append(batch.apply(own), others...)
+	hosts := make([]string, 0, len(records))
+	for _, r := range records {
+		hosts = append(hosts, hostsEntry(r))
+	}
+	return c.patchHosts(ctx, hosts)
+}
+
+// patchHosts sets the full hosts list through PATCH /api/config
+func (c *HTTPClient) patchHosts(ctx context.Context, hosts []string) error {
+	if err := c.ensureAuthenticated(ctx); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+
+	var payload configResponse
+	payload.Config.DNS.Hosts = hosts
+	body, err := json.Marshal(map[string]any{"config": payload.Config})
+	if err != nil {
+		return fmt.Errorf("marshaling hosts: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, c.baseURL+"/api/config", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	sid := c.setAuthHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Session expired, try to re-authenticate once
+		if err := c.reauthenticate(ctx, sid); err != nil {
+			return fmt.Errorf("re-authentication failed: %w", err)
+		}
+		return c.patchHosts(ctx, hosts)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Message: string(respBody)}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package pihole
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestApplyBatch(t *testing.T) {
+	hosts := []string{"192.168.1.100 app.local", "fd00::10 app.local", "192.168.1.100 old.local"}
+	mock := mockAuthServer(t, hosts, true)
+	defer mock.Close()
+
+	var patched []string
+	patches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/config" && r.Method == http.MethodPatch {
+			patches++
+			var body configResponse
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			patched = body.Config.DNS.Hosts
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		mock.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, testPassword)
+	err := client.ApplyBatch(context.Background(), Batch{
+		Deletes: []string{"app.local", "old.local"},
+		Creates: []DNSRecord{{Domain: "app.local", IP: "192.168.1.200"}, {Domain: "new.local", IP: "192.168.1.100"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch() unexpected error: %v", err)
+	}
+
+	want := []string{"fd00::10 app.local", "192.168.1.200 app.local", "192.168.1.100 new.local"}
+	if patches != 1 || !slices.Equal(patched, want) {
+		t.Errorf("%d patches with hosts %q, want 1 with %q", patches, patched, want)
+	}
+}
+
+func TestApplyBatchError(t *testing.T) {
+	// The mock server does not know PATCH /api/config
+	server := mockAuthServer(t, nil, true)
+	defer server.Close()
+
+	err := NewClient(server.URL, testPassword).ApplyBatch(context.Background(), Batch{
+		Creates: []DNSRecord{{Domain: "app.local", IP: "192.168.1.100"}},
+	})
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("ApplyBatch() error = %v, want a 404 APIError", err)
+	}
+}
+
+func TestBatchApplyReplacesListedKey(t *testing.T) {
+	records := []DNSRecord{
+		{Domain: "app.local", IP: "192.168.1.100"},
+		{Domain: "other.local", IP: "192.168.1.100"},
+	}
+	batch := Batch{Creates: []DNSRecord{
+		{Domain: "app.local", IP: "192.168.1.200"},
+		{Domain: "new.local", IP: "192.168.1.100"},
+		{Domain: "new.local", IP: "192.168.1.101"},
+	}}
+
+	want := []DNSRecord{
+		{Domain: "app.local", IP: "192.168.1.200"},
+		{Domain: "other.local", IP: "192.168.1.100"},
+		{Domain: "new.local", IP: "192.168.1.101"},
+	}
+	if got := batch.apply(records); !slices.Equal(got, want) {
+		t.Errorf("apply() = %+v, want %+v", got, want)
+	}
+}
@@ -3,7 +3,9 @@ package pihole
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,13 +13,15 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
 )
 
 // Client interface for Pi-hole API operations
 type Client interface {
 	ListRecords(ctx context.Context) ([]DNSRecord, error)
 	CreateRecord(ctx context.Context, record DNSRecord) error
-	DeleteRecord(ctx context.Context, domain string) error
+	DeleteRecord(ctx context.Context, domain, recordType string) error
 	Healthy(ctx context.Context) bool
 }
 
@@ -27,11 +31,30 @@ type HTTPClient struct {
 	password   string
 	httpClient *http.Client
 
-	// Session management
+	// Session management; mu also guards password, the shutdown settings and the
+	// metrics state
 	mu    sync.RWMutex
 	sid   string
 	csrf  string
 	valid time.Time
+
+	// name labels the client's metrics; lastCall is when the last operation finished
+	name     string
+	lastCall time.Time
+
+	// cluster marks the records the client creates; see SetClusterID
+	cluster string
+
+	// stopping and shutdownGrace are set by SetShutdown
+	stopping      context.Context
+	shutdownGrace time.Duration
+
+	// writeMu keeps record calls from landing between the read and write of a batch
+	writeMu sync.Mutex
+
+	// authMu lets one call log in at a time, so calls that find the session gone
+	// together share the login of the first
+	authMu sync.Mutex
 }
 
 // NewClient creates a new Pi-hole API client
@@ -45,6 +68,27 @@ func NewClient(baseURL, password string) *HTTPClient {
 	}
 }
 
+// SetPassword replaces the password and drops the current session, so the next call
+// authenticates with it. It reports whether the password changed; setting the same
+// password keeps the session.
+func (c *HTTPClient) SetPassword(password string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if password == c.password {
+		return false
+	}
+	c.password = password
+	c.sid, c.csrf, c.valid = "", "", time.Time{}
+	return true
+}
+
+// SetTLSConfig verifies the Pi-hole server with cfg instead of the default settings
+func (c *HTTPClient) SetTLSConfig(cfg *tls.Config) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	c.httpClient.Transport = transport
+}
+
 // authResponse represents the Pi-hole v6 auth response
 type authResponse struct {
 	Session struct {
@@ -68,7 +112,9 @@ type configResponse struct {
 func (c *HTTPClient) authenticate(ctx context.Context) error {
 	reqURL := fmt.Sprintf("%s/api/auth", c.baseURL)
 
+	c.mu.RLock()
 	payload := map[string]string{"password": c.password}
+	c.mu.RUnlock()
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("marshaling auth request: %w", err)
@@ -99,38 +145,72 @@ func (c *HTTPClient) authenticate(ctx context.Context) error {
 		return fmt.Errorf("decoding auth response: %w", err)
 	}
 
+	now := time.Now()
 	c.mu.Lock()
 	c.sid = authResp.Session.SID
 	c.csrf = authResp.Session.CSRF
 	// Set validity with some buffer (use 80% of the timeout)
-	c.valid = time.Now().Add(time.Duration(authResp.Session.Validity*80/100) * time.Second)
+	c.valid = now.Add(time.Duration(authResp.Session.Validity) * time.Second * 80 / 100)
 	c.mu.Unlock()
 
+	expiry := now.Add(time.Duration(authResp.Session.Validity) * time.Second)
+	metrics.PiholeSessionValidUntil.WithLabelValues(c.instance()).Set(float64(expiry.Unix()))
 	return nil
 }
 
 // ensureAuthenticated checks if we have a valid session, authenticates if not
 func (c *HTTPClient) ensureAuthenticated(ctx context.Context) error {
-	c.mu.RLock()
-	valid := c.valid.After(time.Now()) && c.sid != ""
-	c.mu.RUnlock()
+	if _, valid := c.session(); valid {
+		return nil
+	}
 
-	if valid {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	// Another call may have logged in while this one waited
+	if _, valid := c.session(); valid {
 		return nil
 	}
+	return c.authenticate(ctx)
+}
 
+// reauthenticate replaces the session Pi-hole refused a request with. A call refused
+// with a session another call has already replaced reuses the new one instead of
+// logging in again.
+func (c *HTTPClient) reauthenticate(ctx context.Context, refused string) error {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if sid, valid := c.session(); valid && sid != refused {
+		return nil
+	}
 	return c.authenticate(ctx)
 }
 
-// setAuthHeaders adds authentication headers to a request
-func (c *HTTPClient) setAuthHeaders(req *http.Request) {
+// session returns the current session ID and whether it is still trusted
+func (c *HTTPClient) session() (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sid, c.sid != "" && c.valid.After(time.Now())
+}
+
+// setAuthHeaders adds authentication headers to a request and returns the session
+// ID it used
+func (c *HTTPClient) setAuthHeaders(req *http.Request) string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	req.Header.Set("X-FTL-SID", c.sid)
+	return c.sid
 }
 
 // ListRecords fetches all local DNS records from Pi-hole
 func (c *HTTPClient) ListRecords(ctx context.Context) ([]DNSRecord, error) {
+	ctx, release := c.callContext(ctx)
+	defer release()
+	records, err := c.listRecords(ctx)
+	c.observe(ctx, OperationList, err)
+	return records, err
+}
+
+func (c *HTTPClient) listRecords(ctx context.Context) ([]DNSRecord, error) {
 	if err := c.ensureAuthenticated(ctx); err != nil {
 		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
@@ -142,7 +222,7 @@ func (c *HTTPClient) ListRecords(ctx context.Context) ([]DNSRecord, error) {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	c.setAuthHeaders(req)
+	sid := c.setAuthHeaders(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -152,10 +232,10 @@ func (c *HTTPClient) ListRecords(ctx context.Context) ([]DNSRecord, error) {
 
 	if resp.StatusCode == http.StatusUnauthorized {
 		// Session expired, try to re-authenticate once
-		if err := c.authenticate(ctx); err != nil {
+		if err := c.reauthenticate(ctx, sid); err != nil {
 			return nil, fmt.Errorf("re-authentication failed: %w", err)
 		}
-		return c.ListRecords(ctx)
+		return c.listRecords(ctx)
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -168,29 +248,37 @@ func (c *HTTPClient) ListRecords(ctx context.Context) ([]DNSRecord, error) {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
-	// Parse the hosts array (format: "IP DOMAIN")
+	// Parse the hosts array (format: "IP DOMAIN [MARKER]")
 	var records []DNSRecord
 	for _, entry := range configResp.Config.DNS.Hosts {
-		parts := strings.Fields(entry)
-		if len(parts) >= 2 {
-			records = append(records, DNSRecord{
-				IP:     parts[0],
-				Domain: parts[1],
-			})
+		if record, ok := parseHostsEntry(entry); ok {
+			records = append(records, record)
 		}
 	}
 
+	metrics.PiholeRecords.WithLabelValues(c.instance()).Set(float64(len(records)))
 	return records, nil
 }
 
-// CreateRecord creates a new DNS A record in Pi-hole
+// CreateRecord creates a new DNS A or AAAA record in Pi-hole
 func (c *HTTPClient) CreateRecord(ctx context.Context, record DNSRecord) error {
+	ctx, release := c.callContext(ctx)
+	defer release()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	err := c.createRecord(ctx, record)
+	c.observe(ctx, OperationCreate, err)
+	return err
+}
+
+func (c *HTTPClient) createRecord(ctx context.Context, record DNSRecord) error {
 	if err := c.ensureAuthenticated(ctx); err != nil {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
 
-	// Format: "IP DOMAIN" URL-encoded
-	entry := fmt.Sprintf("%s %s", record.IP, record.Domain)
+	// Format: "IP DOMAIN [MARKER]" URL-encoded
+	record.Cluster = c.clusterID()
+	entry := hostsEntry(record)
 	reqURL := fmt.Sprintf("%s/api/config/dns/hosts/%s", c.baseURL, url.PathEscape(entry))
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPut, reqURL, nil)
@@ -198,7 +286,7 @@ func (c *HTTPClient) CreateRecord(ctx context.Context, record DNSRecord) error {
 		return fmt.Errorf("creating request: %w", err)
 	}
 
-	c.setAuthHeaders(req)
+	sid := c.setAuthHeaders(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -208,10 +296,10 @@ func (c *HTTPClient) CreateRecord(ctx context.Context, record DNSRecord) error {
 
 	if resp.StatusCode == http.StatusUnauthorized {
 		// Session expired, try to re-authenticate once
-		if err := c.authenticate(ctx); err != nil {
+		if err := c.reauthenticate(ctx, sid); err != nil {
 			return fmt.Errorf("re-authentication failed: %w", err)
 		}
-		return c.CreateRecord(ctx, record)
+		return c.createRecord(ctx, record)
 	}
 
 	// Accept 200, 201, 204 as success
@@ -223,28 +311,39 @@ func (c *HTTPClient) CreateRecord(ctx context.Context, record DNSRecord) error {
 	return nil
 }
 
-// DeleteRecord deletes a DNS record from Pi-hole
-func (c *HTTPClient) DeleteRecord(ctx context.Context, domain string) error {
+// DeleteRecord deletes the record of the given type for domain from Pi-hole. A record
+// marked by another cluster is left alone, as if it did not exist.
+func (c *HTTPClient) DeleteRecord(ctx context.Context, domain, recordType string) error {
+	ctx, release := c.callContext(ctx)
+	defer release()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	err := c.deleteRecord(ctx, domain, recordType)
+	c.observe(ctx, OperationDelete, err)
+	return err
+}
+
+func (c *HTTPClient) deleteRecord(ctx context.Context, domain, recordType string) error {
 	if err := c.ensureAuthenticated(ctx); err != nil {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
 
-	// First, we need to find the record to get the full "IP DOMAIN" entry
-	records, err := c.ListRecords(ctx)
+	// First, we need to find the record to get the full "IP DOMAIN [MARKER]" entry
+	records, err := c.listRecords(ctx)
 	if err != nil {
 		return fmt.Errorf("listing records to find entry: %w", err)
 	}
 
 	var entryToDelete string
 	for _, r := range records {
-		if r.Domain == domain {
-			entryToDelete = fmt.Sprintf("%s %s", r.IP, r.Domain)
+		if r.Domain == domain && r.Type() == recordType && !r.OtherCluster(c.clusterID()) {
+			entryToDelete = hostsEntry(r)
 			break
 		}
 	}
 
 	if entryToDelete == "" {
-		// Record doesn't exist, consider it a success
+		// Record doesn't exist, or belongs to another cluster; consider it a success
 		return nil
 	}
 
@@ -255,7 +354,7 @@ func (c *HTTPClient) DeleteRecord(ctx context.Context, domain string) error {
 		return fmt.Errorf("creating request: %w", err)
 	}
 
-	c.setAuthHeaders(req)
+	sid := c.setAuthHeaders(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -265,10 +364,10 @@ func (c *HTTPClient) DeleteRecord(ctx context.Context, domain string) error {
 
 	if resp.StatusCode == http.StatusUnauthorized {
 		// Session expired, try to re-authenticate once
-		if err := c.authenticate(ctx); err != nil {
+		if err := c.reauthenticate(ctx, sid); err != nil {
 			return fmt.Errorf("re-authentication failed: %w", err)
 		}
-		return c.DeleteRecord(ctx, domain)
+		return c.deleteRecord(ctx, domain, recordType)
 	}
 
 	// Accept 200, 204, 404 as success
@@ -282,10 +381,10 @@ func (c *HTTPClient) DeleteRecord(ctx context.Context, domain string) error {
 
 // Healthy checks if the Pi-hole API is reachable and authentication works
 func (c *HTTPClient) Healthy(ctx context.Context) bool {
-	if err := c.ensureAuthenticated(ctx); err != nil {
-		return false
-	}
-	_, err := c.ListRecords(ctx)
+	ctx, release := c.callContext(ctx)
+	defer release()
+	_, err := c.listRecords(ctx)
+	c.observe(ctx, OperationHealth, err)
 	return err == nil
 }
 
@@ -299,6 +398,14 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("pihole api error (status %d): %s", e.StatusCode, e.Message)
 }
 
+// AsAPIError finds an APIError in err's chain, such as one wrapped with the name of
+// the instance that returned it
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	ok := errors.As(err, &apiErr)
+	return apiErr, ok
+}
+
 // IsRetryable returns true if the error should trigger a retry
 func (e *APIError) IsRetryable() bool {
 	// 400 Bad Request should not retry (invalid request)
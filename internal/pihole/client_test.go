@@ -7,6 +7,12 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
 )
 
 const (
@@ -122,6 +128,35 @@ func TestAuthenticate(t *testing.T) {
 	}
 }
 
+func TestSetPassword(t *testing.T) {
+	server := mockAuthServer(t, nil, true)
+	defer server.Close()
+
+	client := NewClient(server.URL, "old-password")
+	if _, err := client.ListRecords(context.Background()); err == nil {
+		t.Fatal("ListRecords() with the old password expected error, got nil")
+	}
+
+	if !client.SetPassword(testPassword) {
+		t.Error("SetPassword() = false for a new password, want true")
+	}
+	if _, err := client.ListRecords(context.Background()); err != nil {
+		t.Fatalf("ListRecords() after rotation unexpected error: %v", err)
+	}
+
+	if client.SetPassword(testPassword) {
+		t.Error("SetPassword() = true for the same password, want false")
+	}
+	if client.sid != testSID {
+		t.Error("setting the same password dropped the session")
+	}
+
+	client.SetPassword("rotated-password")
+	if client.sid != "" || !client.valid.IsZero() {
+		t.Error("session kept after the password changed")
+	}
+}
+
 func TestListRecords(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -178,6 +213,45 @@ func TestListRecords(t *testing.T) {
 	}
 }
 
+func gaugeValue(t *testing.T, g prometheus.Gauge) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := g.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetGauge().GetValue()
+}
+
+func TestTotalsMetrics(t *testing.T) {
+	server := mockAuthServer(t, []string{"192.168.1.100 app.local", "10.0.0.5 router.lan", "192.168.1.100 api.local"}, true)
+	defer server.Close()
+
+	client := NewClient(server.URL, testPassword)
+	client.SetName("totals")
+	before := time.Now()
+	if _, err := client.ListRecords(context.Background()); err != nil {
+		t.Fatalf("ListRecords() unexpected error: %v", err)
+	}
+
+	if got := gaugeValue(t, metrics.PiholeRecords.WithLabelValues("totals")); got != 3 {
+		t.Errorf("pihole_total_records = %v, want 3", got)
+	}
+	// The mock grants a 300 second session
+	validUntil := gaugeValue(t, metrics.PiholeSessionValidUntil.WithLabelValues("totals"))
+	if earliest := float64(before.Add(300 * time.Second).Unix()); validUntil < earliest || validUntil > earliest+5 {
+		t.Errorf("pihole_session_valid_until_timestamp_seconds = %v, want about %v", validUntil, earliest)
+	}
+
+	// A failed listing keeps the last count
+	server.Close()
+	if _, err := client.ListRecords(context.Background()); err == nil {
+		t.Fatal("expected listing a closed server to fail")
+	}
+	if got := gaugeValue(t, metrics.PiholeRecords.WithLabelValues("totals")); got != 3 {
+		t.Errorf("pihole_total_records = %v after a failure, want 3 kept", got)
+	}
+}
+
 func TestCreateRecord(t *testing.T) {
 	server := mockAuthServer(t, nil, true)
 	defer server.Close()
@@ -199,18 +273,73 @@ func TestDeleteRecord(t *testing.T) {
 	client := NewClient(server.URL, testPassword)
 
 	// Delete existing record
-	err := client.DeleteRecord(context.Background(), "app.local")
+	err := client.DeleteRecord(context.Background(), "app.local", TypeA)
 	if err != nil {
 		t.Errorf("DeleteRecord() unexpected error: %v", err)
 	}
 
 	// Delete non-existing record should succeed (no-op)
-	err = client.DeleteRecord(context.Background(), "nonexistent.local")
+	err = client.DeleteRecord(context.Background(), "nonexistent.local", TypeA)
 	if err != nil {
 		t.Errorf("DeleteRecord() for non-existent should not error: %v", err)
 	}
 }
 
+func TestDeleteRecordByType(t *testing.T) {
+	hosts := []string{"fd00::10 app.local", "192.168.1.100 app.local"}
+	mock := mockAuthServer(t, hosts, true)
+	defer mock.Close()
+
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/api/config/dns/hosts/"))
+		}
+		mock.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, testPassword)
+	if err := client.DeleteRecord(context.Background(), "app.local", TypeA); err != nil {
+		t.Fatalf("DeleteRecord(A) unexpected error: %v", err)
+	}
+	if err := client.DeleteRecord(context.Background(), "app.local", TypeAAAA); err != nil {
+		t.Fatalf("DeleteRecord(AAAA) unexpected error: %v", err)
+	}
+
+	want := []string{"192.168.1.100 app.local", "fd00::10 app.local"}
+	if len(deleted) != len(want) || deleted[0] != want[0] || deleted[1] != want[1] {
+		t.Errorf("deleted entries = %q, want %q", deleted, want)
+	}
+}
+
+func TestRecordKey(t *testing.T) {
+	tests := []struct {
+		record DNSRecord
+		typ    string
+		key    string
+	}{
+		{DNSRecord{Domain: "app.local", IP: "192.168.1.100"}, TypeA, "app.local"},
+		{DNSRecord{Domain: "app.local", IP: "fd00::10"}, TypeAAAA, "app.local/AAAA"},
+		{DNSRecord{Domain: "app.local", IP: "::ffff:192.168.1.100"}, TypeA, "app.local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := tt.record.Type(); got != tt.typ {
+				t.Errorf("Type() = %q, want %q", got, tt.typ)
+			}
+			if got := tt.record.Key(); got != tt.key {
+				t.Errorf("Key() = %q, want %q", got, tt.key)
+			}
+			domain, typ := ParseRecordKey(tt.key)
+			if domain != tt.record.Domain || typ != tt.typ {
+				t.Errorf("ParseRecordKey(%q) = %q, %q, want %q, %q", tt.key, domain, typ, tt.record.Domain, tt.typ)
+			}
+		})
+	}
+}
+
 func TestHealthy(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -0,0 +1,132 @@
+package pihole
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseHostsEntry(t *testing.T) {
+	tests := []struct {
+		entry  string
+		want   DNSRecord
+		wantOK bool
+	}{
+		{entry: "192.168.1.100 app.local", want: DNSRecord{IP: "192.168.1.100", Domain: "app.local"}, wantOK: true},
+		{entry: "192.168.1.100 app.local prod.cluster.pihole-operator.invalid",
+			want: DNSRecord{IP: "192.168.1.100", Domain: "app.local", Cluster: "prod"}, wantOK: true},
+		{entry: "192.168.1.100 app.local alias.local", want: DNSRecord{IP: "192.168.1.100", Domain: "app.local"}, wantOK: true},
+		{entry: "192.168.1.100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			got, ok := parseHostsEntry(tt.entry)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseHostsEntry(%q) = %+v, %v, want %+v, %v", tt.entry, got, ok, tt.want, tt.wantOK)
+			}
+			if ok && tt.want.Cluster != "" && hostsEntry(got) != tt.entry {
+				t.Errorf("hostsEntry() = %q, want %q", hostsEntry(got), tt.entry)
+			}
+		})
+	}
+}
+
+func TestClusterMarkers(t *testing.T) {
+	hosts := []string{
+		"192.168.1.100 lab.local lab.cluster.pihole-operator.invalid",
+		"10.0.0.1 shared.local prod.cluster.pihole-operator.invalid",
+		"192.168.1.100 legacy.local",
+	}
+	mock := mockAuthServer(t, hosts, true)
+	defer mock.Close()
+
+	var created, deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entry := strings.TrimPrefix(r.URL.Path, "/api/config/dns/hosts/")
+		switch r.Method {
+		case http.MethodPut:
+			created = append(created, entry)
+		case http.MethodDelete:
+			deleted = append(deleted, entry)
+		}
+		mock.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, testPassword)
+	client.SetClusterID("lab")
+	ctx := context.Background()
+
+	records, err := client.ListRecords(ctx)
+	if err != nil {
+		t.Fatalf("ListRecords() unexpected error: %v", err)
+	}
+	if len(records) != 3 || records[0].Cluster != "lab" || records[1].Cluster != "prod" || records[2].Cluster != "" {
+		t.Errorf("ListRecords() = %+v, want the cluster of each marker", records)
+	}
+
+	if err := client.CreateRecord(ctx, DNSRecord{IP: "192.168.1.100", Domain: "new.local"}); err != nil {
+		t.Fatalf("CreateRecord() unexpected error: %v", err)
+	}
+	// Another cluster's record is left alone; an unmarked one is deleted
+	for _, domain := range []string{"lab.local", "shared.local", "legacy.local"} {
+		if err := client.DeleteRecord(ctx, domain, TypeA); err != nil {
+			t.Fatalf("DeleteRecord(%s) unexpected error: %v", domain, err)
+		}
+	}
+
+	if want := []string{"192.168.1.100 new.local lab.cluster.pihole-operator.invalid"}; !slices.Equal(created, want) {
+		t.Errorf("created entries = %q, want %q", created, want)
+	}
+	want := []string{"192.168.1.100 lab.local lab.cluster.pihole-operator.invalid", "192.168.1.100 legacy.local"}
+	if !slices.Equal(deleted, want) {
+		t.Errorf("deleted entries = %q, want %q", deleted, want)
+	}
+}
+
+func TestApplyBatchKeepsOtherClusters(t *testing.T) {
+	hosts := []string{
+		"10.0.0.1 shared.local prod.cluster.pihole-operator.invalid",
+		"192.168.1.100 old.local lab.cluster.pihole-operator.invalid",
+	}
+	mock := mockAuthServer(t, hosts, true)
+	defer mock.Close()
+
+	var patched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/config" && r.Method == http.MethodPatch {
+			var body configResponse
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			patched = body.Config.DNS.Hosts
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		mock.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, testPassword)
+	client.SetClusterID("lab")
+	err := client.ApplyBatch(context.Background(), Batch{
+		Deletes: []string{"shared.local", "old.local"},
+		Creates: []DNSRecord{{Domain: "new.local", IP: "192.168.1.100"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch() unexpected error: %v", err)
+	}
+
+	want := []string{
+		"192.168.1.100 new.local lab.cluster.pihole-operator.invalid",
+		"10.0.0.1 shared.local prod.cluster.pihole-operator.invalid",
+	}
+	if !slices.Equal(patched, want) {
+		t.Errorf("patched hosts = %q, want %q", patched, want)
+	}
+}
@@ -0,0 +1,529 @@
+// Package fakeserver is an in-memory Pi-hole v6 API for tests and local development.
+// It keeps sessions, the local DNS hosts and the CNAME records the way Pi-hole does,
+// logs every request, and can be told to fail, slow down or garble requests, expire
+// sessions, or stop keeping writes.
+package fakeserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultValidity is how long a session lasts without use, as in Pi-hole
+const DefaultValidity = 300 * time.Second
+
+// The config lists served under /api/config/dns
+const (
+	ListHosts  = "hosts"
+	ListCNAMEs = "cnameRecords"
+)
+
+// Server is a fake Pi-hole. The zero value is not usable; create one with New. It is
+// an http.Handler, so it can be served by httptest or a plain http.Server.
+type Server struct {
+	// Logger, when set, logs every request
+	Logger *slog.Logger
+
+	mu       sync.Mutex
+	password string
+	validity time.Duration
+	sessions map[string]time.Time
+	lists    map[string][]string
+	faults   []*Fault
+	readOnly bool
+	requests []Request
+	now      func() time.Time
+}
+
+// Request is a request the server answered
+type Request struct {
+	Method string
+	Path   string
+	Status int
+}
+
+// Fault makes matching requests misbehave instead of being served normally
+type Fault struct {
+	// Method and Path select the requests; an empty Method matches any, and Path
+	// matches as a prefix of the unescaped path, so "/api/config" covers every list
+	Method string
+	Path   string
+
+	// Mode is how the requests misbehave; the zero value fails them
+	Mode FaultMode
+
+	// Status is answered by FaultFail with a Pi-hole error body; 0 drops the
+	// connection instead
+	Status int
+
+	// Times is how many requests misbehave; 0 keeps going until ClearFaults
+	Times int
+
+	// Delay holds each matching request before answering, plus a random part of up
+	// to Jitter
+	Delay  time.Duration
+	Jitter time.Duration
+}
+
+// FaultMode is how a Fault makes a request misbehave
+type FaultMode int
+
+const (
+	// FaultFail answers Status, or drops the connection when Status is 0
+	FaultFail FaultMode = iota
+	// FaultSlow serves the request normally once its delay has passed
+	FaultSlow
+	// FaultExpireSession ends the request's session before serving it, so it is
+	// refused with 401 as if the session had run out in flight
+	FaultExpireSession
+	// FaultTruncate serves the request but cuts its body in half, leaving invalid
+	// JSON; a write still takes effect
+	FaultTruncate
+)
+
+// delay returns how long a request matching f is held
+func (f *Fault) delay() time.Duration {
+	if f.Jitter <= 0 {
+		return f.Delay
+	}
+	return f.Delay + mathrand.N(f.Jitter)
+}
+
+// New returns a server accepting password, with sessions valid for DefaultValidity
+// and no records
+func New(password string) *Server {
+	return &Server{
+		password: password,
+		validity: DefaultValidity,
+		sessions: map[string]time.Time{},
+		lists:    map[string][]string{ListHosts: {}, ListCNAMEs: {}},
+		now:      time.Now,
+	}
+}
+
+// Start serves s on a local port until the returned server is closed
+func (s *Server) Start() *httptest.Server {
+	return httptest.NewServer(s)
+}
+
+// SetPassword changes the accepted password; existing sessions stay valid
+func (s *Server) SetPassword(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.password = password
+}
+
+// SetValidity sets how long new and renewed sessions last
+func (s *Server) SetValidity(validity time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validity = validity
+}
+
+// SetClock replaces the clock sessions expire by, so tests can move time forward
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// ExpireSessions ends every session, as Pi-hole does when it restarts
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.sessions)
+}
+
+// Sessions returns the number of sessions that have not expired
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, expiry := range s.sessions {
+		if s.now().Before(expiry) {
+			n++
+		}
+	}
+	return n
+}
+
+// SetHosts replaces the local DNS records, each "IP DOMAIN"
+func (s *Server) SetHosts(hosts ...string) {
+	s.setList(ListHosts, hosts)
+}
+
+// Hosts returns the local DNS records
+func (s *Server) Hosts() []string {
+	return s.list(ListHosts)
+}
+
+// SetCNAMEs replaces the CNAME records, each "DOMAIN,TARGET"
+func (s *Server) SetCNAMEs(cnames ...string) {
+	s.setList(ListCNAMEs, cnames)
+}
+
+// CNAMEs returns the CNAME records
+func (s *Server) CNAMEs() []string {
+	return s.list(ListCNAMEs)
+}
+
+func (s *Server) setList(name string, entries []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lists[name] = append([]string{}, entries...)
+}
+
+func (s *Server) list(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.lists[name]...)
+}
+
+// SetReadOnly makes writes succeed without changing any record, as when Pi-hole
+// answers but cannot save its configuration
+func (s *Server) SetReadOnly(readOnly bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readOnly = readOnly
+}
+
+// Inject adds a fault; faults are tried in the order they were added
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every fault
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests answered so far, oldest first
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+// ServeHTTP answers a request the way Pi-hole v6 would
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	fault := s.fault(r)
+	if fault == nil {
+		s.serve(rec, r)
+		s.record(r, rec.status)
+		return
+	}
+
+	time.Sleep(fault.delay())
+	switch fault.Mode {
+	case FaultSlow:
+		s.serve(rec, r)
+	case FaultExpireSession:
+		s.mu.Lock()
+		delete(s.sessions, sessionID(r))
+		s.mu.Unlock()
+		s.serve(rec, r)
+	case FaultTruncate:
+		s.truncate(rec, r)
+	default:
+		if fault.Status == 0 {
+			s.record(r, 0)
+			panic(http.ErrAbortHandler)
+		}
+		writeError(rec, fault.Status, "injected", "injected fault")
+	}
+	s.record(r, rec.status)
+}
+
+// truncate serves r and answers with the first half of the body
+func (s *Server) truncate(w http.ResponseWriter, r *http.Request) {
+	full := httptest.NewRecorder()
+	s.serve(full, r)
+	maps.Copy(w.Header(), full.Header())
+	w.WriteHeader(full.Code)
+	body := full.Body.Bytes()
+	_, _ = w.Write(body[:len(body)/2])
+}
+
+// fault returns the first fault matching r, using up one of its failures
+func (s *Server) fault(r *http.Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if (f.Method != "" && f.Method != r.Method) || !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.faults = slices.Delete(s.faults, i, i+1)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (s *Server) record(r *http.Request, status int) {
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Status: status})
+	s.mu.Unlock()
+	if s.Logger != nil {
+		s.Logger.Info("request", "method", r.Method, "path", r.URL.Path, "status", status)
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/auth" {
+		switch r.Method {
+		case http.MethodPost:
+			s.login(w, r)
+		case http.MethodDelete:
+			s.logout(w, r)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		}
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	switch path := r.URL.Path; {
+	case path == "/api/config" && r.Method == http.MethodGet,
+		path == "/api/config/dns" && r.Method == http.MethodGet:
+		s.writeConfig(w, ListHosts, ListCNAMEs)
+	case path == "/api/config" && r.Method == http.MethodPatch:
+		s.patch(w, r)
+	case strings.HasPrefix(path, "/api/config/dns/"):
+		s.serveList(w, r, strings.TrimPrefix(r.URL.EscapedPath(), "/api/config/dns/"))
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+	}
+}
+
+// login opens a session when the password matches
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON payload")
+		return
+	}
+
+	s.mu.Lock()
+	if payload.Password != s.password {
+		s.mu.Unlock()
+		writeJSON(w, http.StatusUnauthorized, map[string]any{
+			"session": map[string]any{"valid": false, "sid": nil, "validity": -1, "message": "password incorrect"},
+		})
+		return
+	}
+	sid := randomToken()
+	validity := s.validity
+	s.sessions[sid] = s.now().Add(validity)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"session": map[string]any{
+			"valid":    true,
+			"sid":      sid,
+			"csrf":     randomToken(),
+			"validity": int(validity.Seconds()),
+			"message":  "password correct",
+		},
+	})
+}
+
+// logout ends the session of the request
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+	s.mu.Lock()
+	delete(s.sessions, sessionID(r))
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorized reports whether r carries a live session, extending it as Pi-hole does
+func (s *Server) authorized(r *http.Request) bool {
+	sid := sessionID(r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiry, ok := s.sessions[sid]
+	if !ok || !s.now().Before(expiry) {
+		delete(s.sessions, sid)
+		return false
+	}
+	s.sessions[sid] = s.now().Add(s.validity)
+	return true
+}
+
+// sessionID returns the session a request carries in its header or query
+func sessionID(r *http.Request) string {
+	if sid := r.Header.Get("X-FTL-SID"); sid != "" {
+		return sid
+	}
+	return r.URL.Query().Get("sid")
+}
+
+// serveList answers /api/config/dns/{list} and /api/config/dns/{list}/{entry}, given
+// the escaped path after /api/config/dns/
+func (s *Server) serveList(w http.ResponseWriter, r *http.Request, rest string) {
+	name, escaped, hasEntry := strings.Cut(rest, "/")
+	if name != ListHosts && name != ListCNAMEs {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return
+	}
+	if !hasEntry {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+			return
+		}
+		s.writeConfig(w, name)
+		return
+	}
+
+	entry, err := url.PathUnescape(escaped)
+	if err != nil || !validEntry(name, entry) {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid "+name+" entry")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.lists[name]
+	switch r.Method {
+	case http.MethodPut:
+		if slices.Contains(entries, entry) {
+			writeError(w, http.StatusBadRequest, "bad_request", "Item already present")
+			return
+		}
+		entries = append(slices.Clone(entries), entry)
+		if !s.readOnly {
+			s.lists[name] = entries
+		}
+		writeJSON(w, http.StatusCreated, configBody(map[string][]string{name: entries}))
+	case http.MethodDelete:
+		i := slices.Index(entries, entry)
+		if i < 0 {
+			writeError(w, http.StatusNotFound, "not_found", "Item not found")
+			return
+		}
+		if !s.readOnly {
+			s.lists[name] = slices.Delete(entries, i, i+1)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+	}
+}
+
+// patch replaces the lists present in a PATCH /api/config body
+func (s *Server) patch(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Config struct {
+			DNS map[string][]string `json:"dns"`
+		} `json:"config"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON payload")
+		return
+	}
+	for name, entries := range payload.Config.DNS {
+		if name != ListHosts && name != ListCNAMEs {
+			writeError(w, http.StatusBadRequest, "bad_request", "Unknown config item dns."+name)
+			return
+		}
+		for _, entry := range entries {
+			if !validEntry(name, entry) {
+				writeError(w, http.StatusBadRequest, "bad_request", "Invalid "+name+" entry")
+				return
+			}
+		}
+	}
+
+	s.mu.Lock()
+	lists := map[string][]string{ListHosts: slices.Clone(s.lists[ListHosts]), ListCNAMEs: slices.Clone(s.lists[ListCNAMEs])}
+	for name, entries := range payload.Config.DNS {
+		lists[name] = append([]string{}, entries...)
+	}
+	if !s.readOnly {
+		maps.Copy(s.lists, lists)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, configBody(lists))
+}
+
+// writeConfig answers with the named lists in Pi-hole's config layout
+func (s *Server) writeConfig(w http.ResponseWriter, names ...string) {
+	s.mu.Lock()
+	lists := map[string][]string{}
+	for _, name := range names {
+		lists[name] = append([]string{}, s.lists[name]...)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, configBody(lists))
+}
+
+func configBody(lists map[string][]string) map[string]any {
+	return map[string]any{"config": map[string]any{"dns": lists}, "took": 0.001}
+}
+
+// validEntry checks a hosts entry is "IP DOMAIN..." and a CNAME entry "DOMAIN,TARGET[,TTL]"
+func validEntry(name, entry string) bool {
+	if name == ListHosts {
+		return len(strings.Fields(entry)) >= 2
+	}
+	parts := strings.Split(entry, ",")
+	return len(parts) >= 2 && len(parts) <= 3 && parts[0] != "" && parts[1] != ""
+}
+
+func writeError(w http.ResponseWriter, status int, key, message string) {
+	writeJSON(w, status, map[string]any{"error": map[string]any{"key": key, "message": message, "hint": nil}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder remembers the status a handler answered with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
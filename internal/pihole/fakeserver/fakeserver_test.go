@@ -0,0 +1,291 @@
+package fakeserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole/fakeserver"
+)
+
+const password = "secret"
+
+func start(t *testing.T) (*fakeserver.Server, *pihole.HTTPClient) {
+	t.Helper()
+	fake := fakeserver.New(password)
+	srv := fake.Start()
+	t.Cleanup(srv.Close)
+	return fake, pihole.NewClient(srv.URL, password)
+}
+
+func TestRecords(t *testing.T) {
+	ctx := context.Background()
+	fake, client := start(t)
+	fake.SetHosts("10.0.0.1 router.lan")
+
+	if err := client.CreateRecord(ctx, pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.100"}); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if err := client.CreateRecord(ctx, pihole.DNSRecord{Domain: "app.local", IP: "fd00::1"}); err != nil {
+		t.Fatalf("CreateRecord AAAA: %v", err)
+	}
+	if err := client.DeleteRecord(ctx, "router.lan", pihole.TypeA); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	want := []string{"192.168.1.100 app.local", "fd00::1 app.local"}
+	if got := fake.Hosts(); !slices.Equal(got, want) {
+		t.Errorf("hosts = %v, want %v", got, want)
+	}
+
+	records, err := client.ListRecords(ctx)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("ListRecords = %v, %v", records, err)
+	}
+
+	// A duplicate is refused like Pi-hole does
+	err = client.CreateRecord(ctx, pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.100"})
+	if apiErr, ok := pihole.AsAPIError(err); !ok || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("duplicate create error = %v, want a 400", err)
+	}
+
+	batch := pihole.Batch{
+		Creates: []pihole.DNSRecord{{Domain: "api.local", IP: "192.168.1.101"}},
+		Deletes: []string{pihole.RecordKey("app.local", pihole.TypeAAAA)},
+	}
+	if err := client.ApplyBatch(ctx, batch); err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	want = []string{"192.168.1.100 app.local", "192.168.1.101 api.local"}
+	if got := fake.Hosts(); !slices.Equal(got, want) {
+		t.Errorf("hosts after batch = %v, want %v", got, want)
+	}
+}
+
+func TestCNAMEs(t *testing.T) {
+	fake := fakeserver.New(password)
+	srv := fake.Start()
+	defer srv.Close()
+
+	sid := login(t, srv.URL)
+	do := func(method, path string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, nil)
+		req.Header.Set("X-FTL-SID", sid)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	entry := "/api/config/dns/cnameRecords/" + url.PathEscape("www.local,app.local")
+	if status := do(http.MethodPut, entry); status != http.StatusCreated {
+		t.Errorf("PUT status = %d, want 201", status)
+	}
+	if got := fake.CNAMEs(); !slices.Equal(got, []string{"www.local,app.local"}) {
+		t.Errorf("cnames = %v", got)
+	}
+	if status := do(http.MethodPut, "/api/config/dns/cnameRecords/"+url.PathEscape("no-target")); status != http.StatusBadRequest {
+		t.Errorf("invalid entry status = %d, want 400", status)
+	}
+	if status := do(http.MethodDelete, entry); status != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, want 204", status)
+	}
+	if status := do(http.MethodDelete, entry); status != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d, want 404", status)
+	}
+}
+
+// login opens a session and returns its ID
+func login(t *testing.T, base string) string {
+	t.Helper()
+	resp, err := http.Post(base+"/api/auth", "application/json", strings.NewReader(`{"password":"`+password+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var body struct {
+		Session struct {
+			SID      string `json:"sid"`
+			Validity int    `json:"validity"`
+		} `json:"session"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Session.SID == "" {
+		t.Fatalf("login failed: %v", err)
+	}
+	return body.Session.SID
+}
+
+func TestAuthentication(t *testing.T) {
+	ctx := context.Background()
+	fake := fakeserver.New(password)
+	srv := fake.Start()
+	defer srv.Close()
+
+	if pihole.NewClient(srv.URL, "wrong").Healthy(ctx) {
+		t.Error("a wrong password was accepted")
+	}
+	client := pihole.NewClient(srv.URL, password)
+	if !client.Healthy(ctx) {
+		t.Fatal("the right password was refused")
+	}
+	if fake.Sessions() != 1 {
+		t.Errorf("sessions = %d, want 1", fake.Sessions())
+	}
+	if err := client.Logout(ctx); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if fake.Sessions() != 0 {
+		t.Errorf("sessions after logout = %d, want 0", fake.Sessions())
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	fake, client := start(t)
+	fake.SetValidity(time.Minute)
+	fake.SetClock(func() time.Time { return now })
+
+	if _, err := client.ListRecords(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client still trusts its session, so the first call is refused and retried
+	// after logging in again
+	now = now.Add(2 * time.Minute)
+	if _, err := client.ListRecords(ctx); err != nil {
+		t.Fatalf("ListRecords after expiry: %v", err)
+	}
+	fake.ExpireSessions()
+	if _, err := client.ListRecords(ctx); err != nil {
+		t.Fatalf("ListRecords after ExpireSessions: %v", err)
+	}
+
+	var logins, refused int
+	for _, req := range fake.Requests() {
+		if req.Path == "/api/auth" {
+			logins++
+		}
+		if req.Status == http.StatusUnauthorized {
+			refused++
+		}
+	}
+	if logins != 3 || refused != 2 {
+		t.Errorf("logins = %d, refused = %d, want 3 and 2", logins, refused)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+	fake, client := start(t)
+
+	fake.Inject(fakeserver.Fault{Method: http.MethodGet, Path: "/api/config/dns/hosts", Status: http.StatusInternalServerError, Times: 1})
+	_, err := client.ListRecords(ctx)
+	if apiErr, ok := pihole.AsAPIError(err); !ok || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("ListRecords error = %v, want the injected 500", err)
+	}
+	if _, err := client.ListRecords(ctx); err != nil {
+		t.Errorf("the fault should fail one request only: %v", err)
+	}
+
+	fake.Inject(fakeserver.Fault{Path: "/api/"})
+	if _, err := client.ListRecords(ctx); err == nil {
+		t.Error("expected a dropped connection to fail")
+	}
+	fake.ClearFaults()
+	if _, err := client.ListRecords(ctx); err != nil {
+		t.Errorf("ListRecords after ClearFaults: %v", err)
+	}
+
+	requests := fake.Requests()
+	if last := requests[len(requests)-1]; last.Method != http.MethodGet || last.Path != "/api/config/dns/hosts" ||
+		last.Status != http.StatusOK {
+		t.Errorf("last request = %+v", last)
+	}
+}
+
+func TestFaultModes(t *testing.T) {
+	ctx := context.Background()
+	fake, client := start(t)
+	fake.SetHosts("10.0.0.1 router.lan")
+	if _, err := client.ListRecords(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("slow", func(t *testing.T) {
+		fake.Inject(fakeserver.Fault{Mode: fakeserver.FaultSlow, Path: "/api/config", Delay: 50 * time.Millisecond,
+			Jitter: 50 * time.Millisecond, Times: 1})
+		began := time.Now()
+		records, err := client.ListRecords(ctx)
+		if err != nil || len(records) != 1 {
+			t.Fatalf("ListRecords = %v, %v; a slow request is still served", records, err)
+		}
+		if took := time.Since(began); took < 50*time.Millisecond || took > time.Second {
+			t.Errorf("slow request took %v, want between the delay and delay plus jitter", took)
+		}
+	})
+
+	t.Run("expire session", func(t *testing.T) {
+		before := len(fake.Requests())
+		fake.Inject(fakeserver.Fault{Mode: fakeserver.FaultExpireSession, Path: "/api/config", Times: 1})
+		if _, err := client.ListRecords(ctx); err != nil {
+			t.Fatalf("ListRecords: %v", err)
+		}
+		var statuses []int
+		for _, req := range fake.Requests()[before:] {
+			statuses = append(statuses, req.Status)
+		}
+		if want := []int{http.StatusUnauthorized, http.StatusOK, http.StatusOK}; !slices.Equal(statuses, want) {
+			t.Errorf("statuses = %v, want the refusal, a login and the retry %v", statuses, want)
+		}
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		fake.Inject(fakeserver.Fault{Mode: fakeserver.FaultTruncate, Method: http.MethodGet, Path: "/api/config", Times: 1})
+		if _, err := client.ListRecords(ctx); err == nil || !strings.Contains(err.Error(), "decoding") {
+			t.Errorf("ListRecords error = %v, want a decoding error", err)
+		}
+
+		// A write answered with a truncated body still happens
+		fake.Inject(fakeserver.Fault{Mode: fakeserver.FaultTruncate, Method: http.MethodPut, Path: "/api/config", Times: 1})
+		if err := client.CreateRecord(ctx, pihole.DNSRecord{Domain: "app.local", IP: "192.168.1.100"}); err != nil {
+			t.Fatalf("CreateRecord: %v", err)
+		}
+		if !slices.Contains(fake.Hosts(), "192.168.1.100 app.local") {
+			t.Errorf("hosts = %v, want the truncated write kept", fake.Hosts())
+		}
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		fake.SetReadOnly(true)
+		before := fake.Hosts()
+		if err := client.CreateRecord(ctx, pihole.DNSRecord{Domain: "lost.local", IP: "192.168.1.101"}); err != nil {
+			t.Fatalf("CreateRecord: %v", err)
+		}
+		if err := client.DeleteRecord(ctx, "router.lan", pihole.TypeA); err != nil {
+			t.Fatalf("DeleteRecord: %v", err)
+		}
+		if err := client.ApplyBatch(ctx, pihole.Batch{Creates: []pihole.DNSRecord{{Domain: "batch.local", IP: "192.168.1.102"}}}); err != nil {
+			t.Fatalf("ApplyBatch: %v", err)
+		}
+		if got := fake.Hosts(); !slices.Equal(got, before) {
+			t.Errorf("hosts = %v, want %v unchanged while read-only", got, before)
+		}
+
+		fake.SetReadOnly(false)
+		if err := client.CreateRecord(ctx, pihole.DNSRecord{Domain: "kept.local", IP: "192.168.1.103"}); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(fake.Hosts(), "192.168.1.103 kept.local") {
+			t.Errorf("hosts = %v, want writes kept again", fake.Hosts())
+		}
+	})
+}
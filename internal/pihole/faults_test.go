@@ -0,0 +1,100 @@
+package pihole
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole/fakeserver"
+)
+
+// TestHTTPClient_RecoversFromFaults checks every call after a misbehaving Pi-hole
+// recovers succeeds again, and which faults the client absorbs on its own
+func TestHTTPClient_RecoversFromFaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault fakeserver.Fault
+		// failures is how many calls fail before one succeeds
+		failures int
+		// retryable is whether the failed calls report an APIError worth retrying
+		retryable bool
+	}{
+		{
+			name:      "consecutive server errors",
+			fault:     fakeserver.Fault{Path: "/api/config", Status: http.StatusInternalServerError, Times: 3},
+			failures:  3,
+			retryable: true,
+		},
+		{
+			// Dropped GETs are retried by net/http itself, so drop the writes
+			name:     "dropped connections",
+			fault:    fakeserver.Fault{Method: http.MethodPut, Path: "/api/config", Times: 2},
+			failures: 2,
+		},
+		{
+			name:     "latency beyond the deadline",
+			fault:    fakeserver.Fault{Mode: fakeserver.FaultSlow, Path: "/api/config", Delay: 300 * time.Millisecond, Times: 1},
+			failures: 1,
+		},
+		{
+			name:     "random latency within the deadline",
+			fault:    fakeserver.Fault{Mode: fakeserver.FaultSlow, Path: "/api/", Jitter: 20 * time.Millisecond},
+			failures: 0,
+		},
+		{
+			name:     "session invalidated in flight",
+			fault:    fakeserver.Fault{Mode: fakeserver.FaultExpireSession, Path: "/api/config", Times: 2},
+			failures: 0,
+		},
+		{
+			name:     "truncated body",
+			fault:    fakeserver.Fault{Mode: fakeserver.FaultTruncate, Method: http.MethodGet, Path: "/api/config", Times: 1},
+			failures: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, url := flakyServer(t)
+			client := NewClient(url, testPassword)
+			if !client.Healthy(context.Background()) {
+				t.Fatal("expected Pi-hole to be healthy before the fault")
+			}
+			fake.Inject(tt.fault)
+
+			// Like a reconcile, list and create the record when it is missing, so a
+			// write that landed before its answer was lost is not repeated
+			call := func() error {
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+				records, err := client.ListRecords(ctx)
+				if err != nil {
+					return err
+				}
+				if slices.ContainsFunc(records, func(r DNSRecord) bool { return r.Domain == "new.example.com" }) {
+					return nil
+				}
+				return client.CreateRecord(ctx, DNSRecord{Domain: "new.example.com", IP: "192.168.1.101"})
+			}
+			for i := range tt.failures {
+				err := call()
+				if err == nil {
+					t.Fatalf("call %d succeeded, want %d failures", i+1, tt.failures)
+				}
+				var apiErr *APIError
+				if got := errors.As(err, &apiErr) && apiErr.IsRetryable(); got != tt.retryable {
+					t.Errorf("call %d error %v: retryable APIError = %v, want %v", i+1, err, got, tt.retryable)
+				}
+			}
+			if err := call(); err != nil {
+				t.Fatalf("call after %d failures: %v", tt.failures, err)
+			}
+			if !slices.Contains(fake.Hosts(), "192.168.1.101 new.example.com") {
+				t.Errorf("hosts = %v, want the record created", fake.Hosts())
+			}
+		})
+	}
+}
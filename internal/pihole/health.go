@@ -0,0 +1,108 @@
+package pihole
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+)
+
+// DefaultIdleProbeInterval is how long a client may go without a call before
+// ProbeIdle checks that Pi-hole is still reachable
+const DefaultIdleProbeInterval = 2 * time.Minute
+
+// The operations labelling the last successful operation timestamp
+const (
+	OperationList   = "list"
+	OperationCreate = "create"
+	OperationDelete = "delete"
+	OperationBatch  = "batch"
+	OperationHealth = "health"
+)
+
+// SetName sets the instance label of the client's metrics; without one the URL is used
+func (c *HTTPClient) SetName(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.name = name
+}
+
+// SetClusterID sets the cluster ID marked on the records the client creates. Records
+// marked by another cluster are never deleted, so several clusters can share one
+// Pi-hole. Without a cluster ID records are created unmarked.
+func (c *HTTPClient) SetClusterID(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cluster = id
+}
+
+// clusterID returns the cluster ID set by SetClusterID
+func (c *HTTPClient) clusterID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cluster
+}
+
+// instance returns the instance label of the client's metrics
+func (c *HTTPClient) instance() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.name != "" {
+		return c.name
+	}
+	return c.baseURL
+}
+
+// observe updates the reachability metrics after an operation. Pi-hole is up when the
+// operation succeeded or Pi-hole rejected the request itself, and down when it could
+// not be reached, failed or refused the password. An operation abandoned because its
+// context ended says nothing either way.
+func (c *HTTPClient) observe(ctx context.Context, operation string, err error) {
+	c.mu.Lock()
+	c.lastCall = time.Now()
+	c.mu.Unlock()
+
+	instance := c.instance()
+	if err == nil {
+		metrics.PiholeUp.WithLabelValues(instance).Set(1)
+		metrics.LastSuccessfulOperation.WithLabelValues(instance, operation).SetToCurrentTime()
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	up := 0.0
+	if apiErr, ok := AsAPIError(err); ok && apiErr.StatusCode < 500 &&
+		apiErr.StatusCode != http.StatusUnauthorized && apiErr.StatusCode != http.StatusForbidden {
+		up = 1
+	}
+	metrics.PiholeUp.WithLabelValues(instance).Set(up)
+}
+
+// ProbeIdle checks Pi-hole with Healthy whenever no call was made for interval, so
+// the reachability metrics stay current while nothing changes. It returns when ctx
+// is cancelled.
+func (c *HTTPClient) ProbeIdle(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultIdleProbeInterval
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		c.mu.RLock()
+		idle := time.Since(c.lastCall)
+		c.mu.RUnlock()
+		if idle >= interval {
+			c.Healthy(ctx)
+			idle = 0
+		}
+		timer.Reset(interval - idle)
+	}
+}
@@ -0,0 +1,145 @@
+package pihole
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole/fakeserver"
+)
+
+// flakyServer returns a fake Pi-hole and its URL
+func flakyServer(t *testing.T) (fake *fakeserver.Server, url string) {
+	fake = fakeserver.New(testPassword)
+	fake.SetHosts("192.168.1.100 app.example.com")
+	srv := fake.Start()
+	t.Cleanup(srv.Close)
+	return fake, srv.URL
+}
+
+// fail makes fake answer 500 until its faults are cleared
+func fail(fake *fakeserver.Server) {
+	fake.Inject(fakeserver.Fault{Path: "/api/", Status: http.StatusInternalServerError})
+}
+
+func piholeUp(t *testing.T, instance string) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.PiholeUp.WithLabelValues(instance).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetGauge().GetValue()
+}
+
+func lastSuccess(t *testing.T, instance, operation string) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.LastSuccessfulOperation.WithLabelValues(instance, operation).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetGauge().GetValue()
+}
+
+func TestHTTPClient_UpFollowsReachability(t *testing.T) {
+	fake, url := flakyServer(t)
+	client := NewClient(url, testPassword)
+	client.SetName("flips")
+	ctx := context.Background()
+
+	if !client.Healthy(ctx) {
+		t.Fatal("expected Pi-hole to be healthy")
+	}
+	if got := piholeUp(t, "flips"); got != 1 {
+		t.Errorf("pihole_up = %v after a healthy probe, want 1", got)
+	}
+	healthAt := lastSuccess(t, "flips", OperationHealth)
+	if healthAt == 0 {
+		t.Error("expected the health timestamp to be set")
+	}
+
+	fail(fake)
+	if _, err := client.ListRecords(ctx); err == nil {
+		t.Fatal("expected listing to fail")
+	}
+	if got := piholeUp(t, "flips"); got != 0 {
+		t.Errorf("pihole_up = %v after a 500, want 0", got)
+	}
+	if got := lastSuccess(t, "flips", OperationList); got != 0 {
+		t.Errorf("list timestamp = %v after a failed list, want unset", got)
+	}
+
+	fake.ClearFaults()
+	if _, err := client.ListRecords(ctx); err != nil {
+		t.Fatalf("ListRecords: %v", err)
+	}
+	if got := piholeUp(t, "flips"); got != 1 {
+		t.Errorf("pihole_up = %v after recovering, want 1", got)
+	}
+	if got := lastSuccess(t, "flips", OperationList); got < healthAt {
+		t.Errorf("list timestamp = %v, want at least %v", got, healthAt)
+	}
+	if got := lastSuccess(t, "flips", OperationHealth); got != healthAt {
+		t.Errorf("health timestamp moved from %v to %v without a probe", healthAt, got)
+	}
+}
+
+func TestHTTPClient_UpIgnoresRejectedRequests(t *testing.T) {
+	client := &HTTPClient{baseURL: "http://rejects.example"}
+	ctx := context.Background()
+
+	client.observe(ctx, OperationCreate, &APIError{StatusCode: http.StatusBadRequest, Message: "invalid"})
+	if got := piholeUp(t, "http://rejects.example"); got != 1 {
+		t.Errorf("pihole_up = %v after a 400, want 1", got)
+	}
+
+	client.observe(ctx, OperationCreate, &APIError{StatusCode: http.StatusUnauthorized, Message: "unauthorized"})
+	if got := piholeUp(t, "http://rejects.example"); got != 0 {
+		t.Errorf("pihole_up = %v after a 401, want 0", got)
+	}
+
+	// An operation abandoned with its context leaves the gauge alone
+	client.observe(ctx, OperationCreate, nil)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	client.observe(cancelled, OperationCreate, context.Canceled)
+	if got := piholeUp(t, "http://rejects.example"); got != 1 {
+		t.Errorf("pihole_up = %v after a cancelled call, want 1", got)
+	}
+}
+
+func TestHTTPClient_ProbeIdle(t *testing.T) {
+	fake, url := flakyServer(t)
+	client := NewClient(url, testPassword)
+	client.SetName("idle")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.ProbeIdle(ctx, 20*time.Millisecond)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	waitForUp := func(want float64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for piholeUp(t, "idle") != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("pihole_up never became %v", want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitForUp(1)
+	fail(fake)
+	waitForUp(0)
+	fake.ClearFaults()
+	waitForUp(1)
+}
@@ -0,0 +1,166 @@
+package pihole
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ListingCache wraps a Client and remembers the most recent record listing, kept
+// up to date with later writes, so it can be inspected without calling Pi-hole.
+// With MaxAge set, ListRecords also answers from the cache while the listing is
+// younger than MaxAge, so the reconciles of a burst of changes share one listing
+// instead of each listing Pi-hole. Writes always go to the wrapped client; a failed
+// write may have been applied in part, so the next ListRecords lists again.
+// Records are keyed by RecordKey.
+type ListingCache struct {
+	Client
+
+	// MaxAge is how long a listing answers ListRecords; zero lists on every call
+	MaxAge time.Duration
+
+	// Cluster is the cluster ID the wrapped client marks created records with and
+	// scopes deletes to; see HTTPClient.SetClusterID
+	Cluster string
+
+	// listMu serializes listings, so callers finding the cache stale wait for one
+	// listing rather than each making their own
+	listMu sync.Mutex
+
+	mu      sync.RWMutex
+	records map[string]DNSRecord
+	listed  time.Time
+	stale   bool
+
+	// writes counts the writes applied, so a listing that raced a write is not reused
+	writes uint64
+	now    func() time.Time
+}
+
+// NewListingCache wraps c
+func NewListingCache(c Client) *ListingCache {
+	return &ListingCache{Client: c}
+}
+
+// ListRecords returns the cached listing while it is fresh and otherwise lists
+// records and refreshes the cache
+func (c *ListingCache) ListRecords(ctx context.Context) ([]DNSRecord, error) {
+	if c.MaxAge > 0 {
+		c.listMu.Lock()
+		defer c.listMu.Unlock()
+		if records, ok := c.fresh(); ok {
+			return records, nil
+		}
+	}
+
+	c.mu.RLock()
+	started, writes := c.clock(), c.writes
+	c.mu.RUnlock()
+	records, err := c.Client.ListRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]DNSRecord, len(records))
+	for _, r := range records {
+		byKey[r.Key()] = r
+	}
+	c.mu.Lock()
+	c.records = byKey
+	c.listed = started
+	c.stale = c.writes != writes
+	c.mu.Unlock()
+	return records, nil
+}
+
+// fresh returns the cached records if they were listed within MaxAge
+func (c *ListingCache) fresh() ([]DNSRecord, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.records == nil || c.stale || c.clock().Sub(c.listed) >= c.MaxAge {
+		return nil, false
+	}
+	records := make([]DNSRecord, 0, len(c.records))
+	for _, r := range c.records {
+		records = append(records, r)
+	}
+	slices.SortFunc(records, func(a, b DNSRecord) int { return strings.Compare(a.Key(), b.Key()) })
+	return records, true
+}
+
+// CreateRecord creates a record and adds it to the cache
+func (c *ListingCache) CreateRecord(ctx context.Context, record DNSRecord) error {
+	err := c.Client.CreateRecord(ctx, record)
+	c.update(Batch{Creates: []DNSRecord{record}}, err)
+	return err
+}
+
+// DeleteRecord deletes a record and removes it from the cache
+func (c *ListingCache) DeleteRecord(ctx context.Context, domain, recordType string) error {
+	err := c.Client.DeleteRecord(ctx, domain, recordType)
+	c.update(Batch{Deletes: []string{RecordKey(domain, recordType)}}, err)
+	return err
+}
+
+// ApplyBatch applies a batch, in one request if the wrapped client supports it, and
+// updates the cache to match
+func (c *ListingCache) ApplyBatch(ctx context.Context, batch Batch) error {
+	err := ApplyBatch(ctx, c.Client, batch)
+	c.update(batch, err)
+	return err
+}
+
+// update brings the cache in line with a write, the way the wrapped client applies it:
+// created records are marked with Cluster and another cluster's records are not
+// deleted. After a failed write the cache is marked stale.
+func (c *ListingCache) update(batch Batch, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes++
+	if err != nil {
+		c.stale = true
+		return
+	}
+	if c.records == nil {
+		return
+	}
+	for _, key := range batch.Deletes {
+		if !c.records[key].OtherCluster(c.Cluster) {
+			delete(c.records, key)
+		}
+	}
+	for _, record := range batch.Creates {
+		record.Cluster = c.Cluster
+		c.records[record.Key()] = record
+	}
+}
+
+// PartialRecords returns the records the wrapped client left out of its listing because
+// only some of its servers hold them; see PartialLister
+func (c *ListingCache) PartialRecords() []DNSRecord {
+	return PartialRecords(c.Client)
+}
+
+// Snapshot returns a copy of the cached record key to IP map and when it was last listed.
+// The map is nil until the first successful listing.
+func (c *ListingCache) Snapshot() (map[string]string, time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.records == nil {
+		return nil, time.Time{}
+	}
+	out := make(map[string]string, len(c.records))
+	for k, r := range c.records {
+		out[k] = r.IP
+	}
+	return out, c.listed
+}
+
+func (c *ListingCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,99 @@
+package pihole
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestListingCache(t *testing.T) {
+	hosts := []string{"192.168.1.100 app.local", "fd00::10 app.local", "192.168.1.20 nas.local"}
+	server := mockAuthServer(t, hosts, true)
+	defer server.Close()
+
+	ctx := context.Background()
+	cache := NewListingCache(NewClient(server.URL, testPassword))
+
+	if snapshot, _ := cache.Snapshot(); snapshot != nil {
+		t.Fatalf("Snapshot() before listing = %v, want nil", snapshot)
+	}
+
+	if _, err := cache.ListRecords(ctx); err != nil {
+		t.Fatalf("ListRecords() unexpected error: %v", err)
+	}
+	if err := cache.CreateRecord(ctx, DNSRecord{Domain: "new.local", IP: "192.168.1.100"}); err != nil {
+		t.Fatalf("CreateRecord() unexpected error: %v", err)
+	}
+	if err := cache.DeleteRecord(ctx, "app.local", TypeA); err != nil {
+		t.Fatalf("DeleteRecord() unexpected error: %v", err)
+	}
+
+	snapshot, listed := cache.Snapshot()
+	if listed.IsZero() {
+		t.Error("Snapshot() listing time is zero")
+	}
+	want := map[string]string{"app.local/AAAA": "fd00::10", "nas.local": "192.168.1.20", "new.local": "192.168.1.100"}
+	if len(snapshot) != len(want) {
+		t.Fatalf("Snapshot() = %v, want %v", snapshot, want)
+	}
+	for key, ip := range want {
+		if snapshot[key] != ip {
+			t.Errorf("Snapshot()[%q] = %q, want %q", key, snapshot[key], ip)
+		}
+	}
+}
+
+func TestListingCacheMaxAge(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryClient(DNSRecord{Domain: "app.local", IP: "10.0.0.1"}, DNSRecord{Domain: "old.local", IP: "10.0.0.1"})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := &ListingCache{Client: backend, MaxAge: 5 * time.Second, Cluster: "lab", now: func() time.Time { return now }}
+
+	list := func() []DNSRecord {
+		t.Helper()
+		records, err := cache.ListRecords(ctx)
+		if err != nil {
+			t.Fatalf("ListRecords() unexpected error: %v", err)
+		}
+		return records
+	}
+
+	list()
+	if err := cache.CreateRecord(ctx, DNSRecord{Domain: "new.local", IP: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.DeleteRecord(ctx, "old.local", TypeA); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh listing is shared and includes the writes made since
+	want := []DNSRecord{{Domain: "app.local", IP: "10.0.0.1"}, {Domain: "new.local", IP: "10.0.0.1", Cluster: "lab"}}
+	if got := list(); !slices.Equal(got, want) || backend.lists != 1 {
+		t.Errorf("ListRecords() = %v after %d listings, want %v from the first listing", got, backend.lists, want)
+	}
+
+	now = now.Add(5 * time.Second)
+	list()
+	if backend.lists != 2 {
+		t.Errorf("%d listings, want Pi-hole listed again once MaxAge passed", backend.lists)
+	}
+
+	// A failed write may have been applied in part, so the next call lists again
+	backend.err = errors.New("pihole unreachable")
+	if err := cache.CreateRecord(ctx, DNSRecord{Domain: "other.local", IP: "10.0.0.1"}); err == nil {
+		t.Fatal("CreateRecord() error = nil, want the backend error")
+	}
+	backend.err = nil
+	list()
+	if backend.lists != 3 {
+		t.Errorf("%d listings, want Pi-hole listed again after a failed write", backend.lists)
+	}
+
+	cache.MaxAge = 0
+	list()
+	if backend.lists != 4 {
+		t.Errorf("%d listings, want every call to list without MaxAge", backend.lists)
+	}
+}
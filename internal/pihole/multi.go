@@ -0,0 +1,205 @@
+package pihole
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Instance is a named Pi-hole server of a MultiClient
+type Instance struct {
+	Name   string
+	Client Client
+}
+
+// PartialLister is implemented by clients spanning several Pi-hole servers whose
+// listing leaves out records only some servers hold
+type PartialLister interface {
+	PartialRecords() []DNSRecord
+}
+
+// PartialRecords returns the records c left out of its last listing because only some
+// of its servers hold them, or nil if c is not a PartialLister
+func PartialRecords(c Client) []DNSRecord {
+	if pl, ok := c.(PartialLister); ok {
+		return pl.PartialRecords()
+	}
+	return nil
+}
+
+// MultiClient keeps the same records on several Pi-hole servers, e.g. a primary and a
+// secondary resolver. Writes go to every instance; an instance failing a write does not
+// stop the others, and the errors are joined with the instance names.
+//
+// ListRecords reports only the records every instance holds with the same IP, so a
+// record missing or different on one instance is written again by the caller. The
+// records only some instances hold are reported by PartialRecords, so a record whose
+// delete failed on one instance is still deleted there by a later sync. Writes
+// use the per-instance listing to leave instances that already hold the record alone
+// and to replace a different IP rather than add a second one.
+type MultiClient struct {
+	instances []Instance
+
+	mu sync.Mutex
+	// listed holds the records of each instance by RecordKey, from the last listing
+	// and kept up to date with later writes; nil until listed
+	listed []map[string]string
+}
+
+// NewMultiClient returns a client writing to all instances
+func NewMultiClient(instances ...Instance) *MultiClient {
+	return &MultiClient{instances: instances, listed: make([]map[string]string, len(instances))}
+}
+
+// ListRecords lists every instance and returns the records they agree on
+func (m *MultiClient) ListRecords(ctx context.Context) ([]DNSRecord, error) {
+	listed := make([]map[string]string, len(m.instances))
+	var first []DNSRecord
+	for i, inst := range m.instances {
+		records, err := inst.Client.ListRecords(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("pihole %s: %w", inst.Name, err)
+		}
+		listed[i] = make(map[string]string, len(records))
+		for _, r := range records {
+			listed[i][r.Key()] = r.IP
+		}
+		if i == 0 {
+			first = records
+		}
+	}
+
+	m.mu.Lock()
+	m.listed = listed
+	m.mu.Unlock()
+
+	agreed := make([]DNSRecord, 0, len(first))
+	for _, r := range first {
+		if !slices.ContainsFunc(listed[1:], func(byKey map[string]string) bool { return byKey[r.Key()] != r.IP }) {
+			agreed = append(agreed, r)
+		}
+	}
+	return agreed, nil
+}
+
+// PartialRecords returns the records that, at the last listing, some instances held but
+// not all with the same IP, and so are left out of ListRecords. Each is reported with
+// the IP of the first instance holding it.
+func (m *MultiClient) PartialRecords() []DNSRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]bool)
+	var partial []DNSRecord
+	for _, byKey := range m.listed {
+		for key, ip := range byKey {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if slices.ContainsFunc(m.listed, func(other map[string]string) bool { return other[key] != ip }) {
+				domain, _ := ParseRecordKey(key)
+				partial = append(partial, DNSRecord{Domain: domain, IP: ip})
+			}
+		}
+	}
+	slices.SortFunc(partial, func(a, b DNSRecord) int { return strings.Compare(a.Key(), b.Key()) })
+	return partial
+}
+
+// CreateRecord creates the record on every instance that does not hold it yet
+func (m *MultiClient) CreateRecord(ctx context.Context, record DNSRecord) error {
+	return m.each(ctx, Batch{Creates: []DNSRecord{record}}, func(inst Instance, batch Batch) error {
+		for _, key := range batch.Deletes {
+			domain, recordType := ParseRecordKey(key)
+			if err := inst.Client.DeleteRecord(ctx, domain, recordType); err != nil {
+				return err
+			}
+		}
+		if len(batch.Creates) == 0 {
+			return nil
+		}
+		return inst.Client.CreateRecord(ctx, record)
+	})
+}
+
+// DeleteRecord deletes the record from every instance
+func (m *MultiClient) DeleteRecord(ctx context.Context, domain, recordType string) error {
+	return m.each(ctx, Batch{Deletes: []string{RecordKey(domain, recordType)}}, func(inst Instance, _ Batch) error {
+		return inst.Client.DeleteRecord(ctx, domain, recordType)
+	})
+}
+
+// ApplyBatch applies the batch to every instance, in one request where supported
+func (m *MultiClient) ApplyBatch(ctx context.Context, batch Batch) error {
+	return m.each(ctx, batch, func(inst Instance, instBatch Batch) error {
+		if instBatch.Len() == 0 {
+			return nil
+		}
+		return ApplyBatch(ctx, inst.Client, instBatch)
+	})
+}
+
+// Healthy reports whether every instance is healthy
+func (m *MultiClient) Healthy(ctx context.Context) bool {
+	for _, inst := range m.instances {
+		if !inst.Client.Healthy(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// each applies batch to every instance through write, after fitting it to what the
+// instance is known to hold, and records the outcome in the listing
+func (m *MultiClient) each(ctx context.Context, batch Batch, write func(Instance, Batch) error) error {
+	var errs []error
+	for i, inst := range m.instances {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		instBatch := m.fit(i, batch)
+		if err := write(inst, instBatch); err != nil {
+			errs = append(errs, fmt.Errorf("pihole %s: %w", inst.Name, err))
+			continue
+		}
+		m.mu.Lock()
+		if byKey := m.listed[i]; byKey != nil {
+			for _, key := range instBatch.Deletes {
+				delete(byKey, key)
+			}
+			for _, record := range instBatch.Creates {
+				byKey[record.Key()] = record.IP
+			}
+		}
+		m.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// fit drops the creates instance i already holds and deletes a different IP before
+// creating the record. Without a listing the batch is used as it is.
+func (m *MultiClient) fit(i int, batch Batch) Batch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	byKey := m.listed[i]
+	if byKey == nil {
+		return batch
+	}
+
+	fitted := Batch{Deletes: slices.Clone(batch.Deletes)}
+	for _, record := range batch.Creates {
+		ip, ok := byKey[record.Key()]
+		deleted := slices.Contains(batch.Deletes, record.Key())
+		switch {
+		case ok && ip == record.IP && !deleted:
+			continue
+		case ok && !deleted:
+			fitted.Deletes = append(fitted.Deletes, record.Key())
+		}
+		fitted.Creates = append(fitted.Creates, record)
+	}
+	return fitted
+}
@@ -0,0 +1,154 @@
+package pihole
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// memoryClient is a Client holding records in memory
+type memoryClient struct {
+	records map[string]string
+	lists   int
+	writes  int
+	err     error
+}
+
+func newMemoryClient(records ...DNSRecord) *memoryClient {
+	c := &memoryClient{records: make(map[string]string)}
+	for _, r := range records {
+		c.records[r.Key()] = r.IP
+	}
+	return c
+}
+
+func (c *memoryClient) ListRecords(context.Context) ([]DNSRecord, error) {
+	c.lists++
+	var records []DNSRecord
+	for key, ip := range c.records {
+		domain, _ := ParseRecordKey(key)
+		records = append(records, DNSRecord{Domain: domain, IP: ip})
+	}
+	return records, nil
+}
+
+func (c *memoryClient) CreateRecord(_ context.Context, record DNSRecord) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.writes++
+	c.records[record.Key()] = record.IP
+	return nil
+}
+
+func (c *memoryClient) DeleteRecord(_ context.Context, domain, recordType string) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.writes++
+	delete(c.records, RecordKey(domain, recordType))
+	return nil
+}
+
+func (c *memoryClient) Healthy(context.Context) bool {
+	return c.err == nil
+}
+
+func TestMultiClientListRecords(t *testing.T) {
+	primary := newMemoryClient(
+		DNSRecord{Domain: "app.local", IP: "10.0.0.1"},
+		DNSRecord{Domain: "drift.local", IP: "10.0.0.1"},
+		DNSRecord{Domain: "missing.local", IP: "10.0.0.1"},
+	)
+	secondary := newMemoryClient(
+		DNSRecord{Domain: "app.local", IP: "10.0.0.1"},
+		DNSRecord{Domain: "drift.local", IP: "10.0.0.2"},
+	)
+	m := NewMultiClient(Instance{Name: "primary", Client: primary}, Instance{Name: "secondary", Client: secondary})
+
+	records, err := m.ListRecords(context.Background())
+	if err != nil {
+		t.Fatalf("ListRecords() unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Domain != "app.local" {
+		t.Errorf("ListRecords() = %v, want only the record both instances agree on", records)
+	}
+	want := []DNSRecord{{Domain: "drift.local", IP: "10.0.0.1"}, {Domain: "missing.local", IP: "10.0.0.1"}}
+	if got := PartialRecords(NewListingCache(m)); !slices.Equal(got, want) {
+		t.Errorf("PartialRecords() = %v, want %v", got, want)
+	}
+}
+
+func TestMultiClientCreateRecord(t *testing.T) {
+	ctx := context.Background()
+	primary := newMemoryClient(DNSRecord{Domain: "app.local", IP: "10.0.0.1"})
+	secondary := newMemoryClient(DNSRecord{Domain: "app.local", IP: "10.0.0.2"})
+	third := newMemoryClient()
+	m := NewMultiClient(
+		Instance{Name: "primary", Client: primary},
+		Instance{Name: "secondary", Client: secondary},
+		Instance{Name: "third", Client: third},
+	)
+	if _, err := m.ListRecords(ctx); err != nil {
+		t.Fatalf("ListRecords() unexpected error: %v", err)
+	}
+
+	if err := m.CreateRecord(ctx, DNSRecord{Domain: "app.local", IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("CreateRecord() unexpected error: %v", err)
+	}
+	if primary.writes != 0 {
+		t.Errorf("primary written %d times though it held the record", primary.writes)
+	}
+	if secondary.records["app.local"] != "10.0.0.1" || secondary.writes != 2 {
+		t.Errorf("secondary = %v after %d writes, want the IP replaced", secondary.records, secondary.writes)
+	}
+	if third.records["app.local"] != "10.0.0.1" {
+		t.Errorf("third = %v, want the record created", third.records)
+	}
+}
+
+func TestMultiClientApplyBatch(t *testing.T) {
+	ctx := context.Background()
+	primary := newMemoryClient(DNSRecord{Domain: "old.local", IP: "10.0.0.1"}, DNSRecord{Domain: "app.local", IP: "10.0.0.1"})
+	secondary := newMemoryClient(DNSRecord{Domain: "old.local", IP: "10.0.0.1"})
+	m := NewMultiClient(Instance{Name: "primary", Client: primary}, Instance{Name: "secondary", Client: secondary})
+	if _, err := m.ListRecords(ctx); err != nil {
+		t.Fatalf("ListRecords() unexpected error: %v", err)
+	}
+
+	err := m.ApplyBatch(ctx, Batch{Deletes: []string{"old.local"}, Creates: []DNSRecord{{Domain: "app.local", IP: "10.0.0.1"}}})
+	if err != nil {
+		t.Fatalf("ApplyBatch() unexpected error: %v", err)
+	}
+	for name, c := range map[string]*memoryClient{"primary": primary, "secondary": secondary} {
+		if len(c.records) != 1 || c.records["app.local"] != "10.0.0.1" {
+			t.Errorf("%s = %v, want app.local only", name, c.records)
+		}
+	}
+	if primary.writes != 1 {
+		t.Errorf("primary written %d times, want the delete only", primary.writes)
+	}
+}
+
+func TestMultiClientPartialFailure(t *testing.T) {
+	failing := newMemoryClient()
+	failing.err = &APIError{StatusCode: http.StatusBadRequest, Message: "bad request"}
+	healthy := newMemoryClient()
+	m := NewMultiClient(Instance{Name: "broken", Client: failing}, Instance{Name: "healthy", Client: healthy})
+
+	err := m.CreateRecord(context.Background(), DNSRecord{Domain: "app.local", IP: "10.0.0.1"})
+	if err == nil || !strings.Contains(err.Error(), "pihole broken") {
+		t.Fatalf("CreateRecord() error = %v, want one naming the broken instance", err)
+	}
+	if apiErr, ok := AsAPIError(err); !ok || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("AsAPIError() = %v, %v, want the instance's 400", apiErr, ok)
+	}
+	if healthy.records["app.local"] != "10.0.0.1" {
+		t.Error("healthy instance skipped after another failed")
+	}
+	if m.Healthy(context.Background()) {
+		t.Error("Healthy() = true with a failing instance")
+	}
+}
@@ -0,0 +1,103 @@
+package pihole
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/pihole/fakeserver"
+)
+
+// TestHTTPClient_SessionStress runs many concurrent operations while the server keeps
+// ending sessions and answers logins slowly; run it with -race. Every operation must
+// succeed, and concurrent calls refused with the same session must share one login.
+func TestHTTPClient_SessionStress(t *testing.T) {
+	const workers, opsEach = 32, 21
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+
+	fake := fakeserver.New(testPassword)
+	fake.SetValidity(2 * time.Second)
+	fake.Inject(fakeserver.Fault{Mode: fakeserver.FaultSlow, Method: http.MethodPost, Path: "/api/auth",
+		Jitter: 5 * time.Millisecond})
+	srv := fake.Start()
+	defer srv.Close()
+	client := NewClient(srv.URL, testPassword)
+
+	// End every session now and then, as a restarting Pi-hole does
+	var expirations atomic.Int64
+	stop := make(chan struct{})
+	chaos := sync.WaitGroup{}
+	chaos.Add(1)
+	go func() {
+		defer chaos.Done()
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				fake.ExpireSessions()
+				expirations.Add(1)
+			}
+		}
+	}()
+
+	began := time.Now()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*opsEach)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range opsEach {
+				domain := fmt.Sprintf("w%d-%d.example.com", w, i)
+				var err error
+				switch i % 3 {
+				case 0:
+					_, err = client.ListRecords(ctx)
+				case 1:
+					err = client.CreateRecord(ctx, DNSRecord{Domain: domain, IP: "192.168.1.100"})
+				case 2:
+					prev := fmt.Sprintf("w%d-%d.example.com", w, i-1)
+					err = client.DeleteRecord(ctx, prev, TypeA)
+				}
+				if err != nil {
+					errs <- fmt.Errorf("worker %d op %d: %w", w, i, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	chaos.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if hosts := fake.Hosts(); len(hosts) != 0 {
+		t.Errorf("records left after every create was deleted: %d", len(hosts))
+	}
+
+	// A login is needed at most once per expiry, plus the sessions the client lets go
+	// of itself before the server's validity runs out
+	logins := 0
+	for _, req := range fake.Requests() {
+		if req.Method == http.MethodPost && req.Path == "/api/auth" {
+			logins++
+		}
+	}
+	renewals := int(time.Since(began)/(2*time.Second*8/10)) + 1
+	if bound := int(expirations.Load()) + renewals; logins > bound {
+		t.Errorf("%d logins for %d expirations, want at most %d", logins, expirations.Load(), bound)
+	}
+	t.Logf("%d operations, %d logins, %d expirations", workers*opsEach, logins, expirations.Load())
+}
@@ -0,0 +1,86 @@
+package pihole
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rsJames-ttrpg/pihole-ingress-operator/internal/metrics"
+)
+
+// DefaultShutdownGrace is how long a call in flight when the operator shuts down is
+// given to finish
+const DefaultShutdownGrace = 5 * time.Second
+
+// SetShutdown gives calls whose context is cancelled because stopping is done up to
+// grace more to finish, instead of abandoning a write half way. Cancellation for any
+// other reason, such as a timeout, still takes effect at once. A grace of 0 disables it.
+func (c *HTTPClient) SetShutdown(stopping context.Context, grace time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopping, c.shutdownGrace = stopping, grace
+}
+
+// callContext returns the context a call runs under: ctx, but outliving a shutdown
+// by the grace set with SetShutdown. The returned function releases it.
+func (c *HTTPClient) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	c.mu.RLock()
+	stopping, grace := c.stopping, c.shutdownGrace
+	c.mu.RUnlock()
+	if stopping == nil || grace <= 0 {
+		return ctx, func() {}
+	}
+
+	call, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		if stopping.Err() == nil {
+			cancel(context.Cause(ctx))
+			return
+		}
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel(context.Cause(ctx))
+		case <-call.Done():
+		}
+	})
+	return call, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// Logout ends the current session, so it doesn't count against Pi-hole's session
+// limit until it expires. It does nothing without a session.
+func (c *HTTPClient) Logout(ctx context.Context) error {
+	c.mu.Lock()
+	sid := c.sid
+	c.sid, c.csrf, c.valid = "", "", time.Time{}
+	c.mu.Unlock()
+	if sid == "" {
+		return nil
+	}
+	metrics.PiholeSessionValidUntil.WithLabelValues(c.instance()).Set(0)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.baseURL+"/api/auth", nil)
+	if err != nil {
+		return fmt.Errorf("creating logout request: %w", err)
+	}
+	req.Header.Set("X-FTL-SID", sid)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing logout request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// An expired session is as good as ended
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusUnauthorized {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Message: string(respBody)}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package pihole
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowCreateServer authenticates like Pi-hole and holds each create until release is
+// closed or the client gives up, signalling started when a create arrives
+func slowCreateServer(t *testing.T, started chan<- struct{}, release <-chan struct{}) *httptest.Server {
+	t.Helper()
+	auth := mockAuthServer(t, nil, true)
+	t.Cleanup(auth.Close)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/config/dns/hosts/") || r.Method != http.MethodPut {
+			auth.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		started <- struct{}{}
+		select {
+		case <-release:
+			w.WriteHeader(http.StatusCreated)
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestShutdownGrace(t *testing.T) {
+	record := DNSRecord{Domain: "app.local", IP: "192.168.1.100"}
+
+	t.Run("finishes within the grace", func(t *testing.T) {
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		client := NewClient(slowCreateServer(t, started, release).URL, testPassword)
+		stopping, stop := context.WithCancel(context.Background())
+		client.SetShutdown(stopping, time.Minute)
+
+		done := make(chan error, 1)
+		go func() { done <- client.CreateRecord(stopping, record) }()
+		<-started
+		stop()
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		if err := <-done; err != nil {
+			t.Errorf("CreateRecord() error = %v, want the call to finish after the shutdown", err)
+		}
+	})
+
+	t.Run("abandoned after the grace", func(t *testing.T) {
+		started := make(chan struct{}, 1)
+		client := NewClient(slowCreateServer(t, started, nil).URL, testPassword)
+		stopping, stop := context.WithCancel(context.Background())
+		client.SetShutdown(stopping, 100*time.Millisecond)
+
+		done := make(chan error, 1)
+		go func() { done <- client.CreateRecord(stopping, record) }()
+		<-started
+		start := time.Now()
+		stop()
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Error("CreateRecord() succeeded against a hung Pi-hole")
+			}
+			if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+				t.Errorf("CreateRecord() returned after %v, before the grace", elapsed)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("CreateRecord() still running long after the grace")
+		}
+	})
+
+	t.Run("other cancellation is immediate", func(t *testing.T) {
+		started := make(chan struct{}, 1)
+		client := NewClient(slowCreateServer(t, started, nil).URL, testPassword)
+		client.SetShutdown(context.Background(), time.Minute)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() { done <- client.CreateRecord(ctx, record) }()
+		<-started
+		cancel()
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Error("CreateRecord() succeeded after its context was cancelled")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("CreateRecord() waited out the shutdown grace without a shutdown")
+		}
+	})
+}
+
+func TestLogout(t *testing.T) {
+	var logouts []string
+	auth := mockAuthServer(t, nil, true)
+	defer auth.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/auth" && r.Method == http.MethodDelete {
+			logouts = append(logouts, r.Header.Get("X-FTL-SID"))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		auth.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, testPassword)
+
+	// Without a session there is nothing to end
+	if err := client.Logout(context.Background()); err != nil || len(logouts) != 0 {
+		t.Fatalf("Logout() without a session = %v after %d logouts, want nil after none", err, len(logouts))
+	}
+
+	if _, err := client.ListRecords(context.Background()); err != nil {
+		t.Fatalf("ListRecords() unexpected error: %v", err)
+	}
+	if err := client.Logout(context.Background()); err != nil {
+		t.Fatalf("Logout() unexpected error: %v", err)
+	}
+	if err := client.Logout(context.Background()); err != nil {
+		t.Fatalf("second Logout() unexpected error: %v", err)
+	}
+	if len(logouts) != 1 || logouts[0] != testSID {
+		t.Errorf("logouts = %v, want one with the session %s", logouts, testSID)
+	}
+}
@@ -1,7 +1,89 @@
 package pihole
 
+import (
+	"net"
+	"strings"
+)
+
+// Record types, derived from the address family of the record's IP
+const (
+	TypeA    = "A"
+	TypeAAAA = "AAAA"
+)
+
+// clusterMarkerSuffix ends the extra hostname that marks a hosts entry with the cluster
+// that wrote it, as in "10.0.0.1 app.home.lan prod.cluster.pihole-operator.invalid".
+// The reserved .invalid TLD keeps the marker from shadowing a real name, though Pi-hole
+// does resolve it, to the IPs of every record of the cluster.
+const clusterMarkerSuffix = ".cluster.pihole-operator.invalid"
+
 // DNSRecord represents a Pi-hole local DNS record
 type DNSRecord struct {
 	IP     string
 	Domain string
+
+	// Cluster is the cluster ID marked on the record in Pi-hole, empty for records
+	// without a marker. Clients mark the records they create with their own cluster
+	// ID; see HTTPClient.SetClusterID.
+	Cluster string
+}
+
+// OtherCluster reports whether the record is marked by a cluster other than clusterID
+func (r DNSRecord) OtherCluster(clusterID string) bool {
+	return r.Cluster != "" && r.Cluster != clusterID
+}
+
+// Type returns TypeAAAA for IPv6 addresses and TypeA otherwise
+func (r DNSRecord) Type() string {
+	if ip := net.ParseIP(r.IP); ip != nil && ip.To4() == nil {
+		return TypeAAAA
+	}
+	return TypeA
+}
+
+// Key identifies the record by domain and type; see RecordKey
+func (r DNSRecord) Key() string {
+	return RecordKey(r.Domain, r.Type())
+}
+
+// RecordKey identifies a record by domain and type. A records are keyed by the bare
+// domain, so keys written before AAAA support remain valid; AAAA records are keyed
+// as "domain/AAAA".
+func RecordKey(domain, recordType string) string {
+	if recordType == TypeAAAA {
+		return domain + "/" + TypeAAAA
+	}
+	return domain
+}
+
+// hostsEntry formats rec as a Pi-hole hosts entry, followed by its cluster marker
+func hostsEntry(rec DNSRecord) string {
+	if rec.Cluster == "" {
+		return rec.IP + " " + rec.Domain
+	}
+	return rec.IP + " " + rec.Domain + " " + rec.Cluster + clusterMarkerSuffix
+}
+
+// parseHostsEntry parses a hosts entry of the form "IP DOMAIN [NAME...]", reading the
+// cluster from a marker among the extra names; ok is false for a malformed entry
+func parseHostsEntry(entry string) (rec DNSRecord, ok bool) {
+	parts := strings.Fields(entry)
+	if len(parts) < 2 {
+		return DNSRecord{}, false
+	}
+	rec = DNSRecord{IP: parts[0], Domain: parts[1]}
+	for _, name := range parts[2:] {
+		if cluster, found := strings.CutSuffix(name, clusterMarkerSuffix); found {
+			rec.Cluster = cluster
+		}
+	}
+	return rec, true
+}
+
+// ParseRecordKey splits a key built by RecordKey into domain and type
+func ParseRecordKey(key string) (string, string) {
+	if domain, ok := strings.CutSuffix(key, "/"+TypeAAAA); ok {
+		return domain, TypeAAAA
+	}
+	return key, TypeA
 }
@@ -0,0 +1,386 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// stateKey is the ConfigMap data key holding the serialized State
+	stateKey = "state.json"
+
+	// maxConflictRetries bounds the read-modify-write retries on update conflicts
+	maxConflictRetries = 5
+)
+
+const (
+	// ReasonGracePeriod marks deletions postponed by a deletion grace period
+	ReasonGracePeriod = "grace-period"
+
+	// ReasonCleanupTimeout marks records left behind when finalizer cleanup gave up
+	ReasonCleanupTimeout = "cleanup-timeout"
+)
+
+// Expiry is a record whose pihole.io/expire-after elapsed; its owner doesn't create it
+// again while the annotation keeps the value it expired under
+type Expiry struct {
+	Owner     string    `json:"owner"`
+	UID       string    `json:"uid,omitempty"`
+	After     string    `json:"after"`
+	ExpiredAt time.Time `json:"expiredAt"`
+}
+
+// PendingDeletion is a DNS record scheduled for removal at a later time
+type PendingDeletion struct {
+	Owner       string    `json:"owner"`
+	DeleteAfter time.Time `json:"deleteAfter"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// Ownership records which object owns a DNS record and since when
+type Ownership struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	UID       string `json:"uid,omitempty"`
+
+	// ClusterID is the cluster of the owning object; empty for entries written before
+	// cluster IDs were recorded, or by an operator without one
+	ClusterID string `json:"clusterID,omitempty"`
+
+	// TargetIP is the IP the record pointed at when it was created
+	TargetIP string `json:"targetIP,omitempty"`
+
+	// CreatedAt is zero for records created before ownership was recorded, or by
+	// something else and adopted
+	CreatedAt    time.Time `json:"createdAt,omitzero"`
+	AdoptedAt    time.Time `json:"adoptedAt,omitzero"`
+	LastSyncedAt time.Time `json:"lastSyncedAt,omitzero"`
+
+	// TrackedSince is when the owner took the record on: created, adopted or, for a
+	// record without an entry, first synced
+	TrackedSince time.Time `json:"trackedSince,omitzero"`
+}
+
+// Owner formats the owning object as "kind namespace/name", e.g. "ingress media/jellyfin"
+func (o Ownership) Owner() string {
+	name := o.Name
+	if o.Namespace != "" {
+		name = o.Namespace + "/" + o.Name
+	}
+	return strings.ToLower(o.Kind) + " " + name
+}
+
+// String describes where the record came from, e.g. "created 2024-03-02 by ingress
+// media/jellyfin"
+func (o Ownership) String() string {
+	if o.CreatedAt.IsZero() {
+		return "created by " + o.Owner()
+	}
+	return "created " + o.CreatedAt.UTC().Format(time.DateOnly) + " by " + o.Owner()
+}
+
+// SameObject reports whether o and other name the same object
+func (o Ownership) SameObject(other Ownership) bool {
+	return o.Kind == other.Kind && o.Namespace == other.Namespace && o.Name == other.Name && o.UID == other.UID
+}
+
+// State is the operator state persisted across restarts
+type State struct {
+	// PendingDeletions maps the pihole.RecordKey of a record to its scheduled deletion
+	PendingDeletions map[string]PendingDeletion `json:"pendingDeletions,omitempty"`
+
+	// Records maps the pihole.RecordKey of every managed record to its owner. State
+	// written before ownership was recorded has none; records are added as their
+	// owners next sync them.
+	Records map[string]Ownership `json:"records,omitempty"`
+
+	// Expired maps the pihole.RecordKey of every expired record to its expiry
+	Expired map[string]Expiry `json:"expired,omitempty"`
+}
+
+// Store persists State in a ConfigMap. The state is cached in memory after the
+// first read; the operator is the only writer, so the cache is refreshed only
+// when an update conflicts.
+type Store struct {
+	client client.Client
+	reader client.Reader
+	key    types.NamespacedName
+
+	mu     sync.Mutex
+	cached *State
+	rv     string
+}
+
+// NewStore creates a Store backed by the named ConfigMap. reader should be an
+// uncached reader so the operator doesn't need to watch ConfigMaps.
+func NewStore(c client.Client, reader client.Reader, namespace, name string) *Store {
+	return &Store{
+		client: c,
+		reader: reader,
+		key:    types.NamespacedName{Namespace: namespace, Name: name},
+	}
+}
+
+// Get returns a copy of the current state
+func (s *Store) Get(ctx context.Context) (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached == nil {
+		if err := s.load(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s.cached.clone(), nil
+}
+
+// Update applies fn to a copy of the state and persists it when fn reports a change
+func (s *Store) Update(ctx context.Context, fn func(*State) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if s.cached == nil {
+			if err := s.load(ctx); err != nil {
+				return err
+			}
+		}
+
+		next := s.cached.clone()
+		if !fn(next) {
+			return nil
+		}
+
+		err := s.save(ctx, next)
+		if err == nil {
+			return nil
+		}
+		if (!errors.IsConflict(err) && !errors.IsAlreadyExists(err)) || attempt >= maxConflictRetries {
+			return err
+		}
+		s.cached = nil
+	}
+}
+
+// load reads the ConfigMap into the cache; a missing ConfigMap yields an empty state
+func (s *Store) load(ctx context.Context) error {
+	var cm corev1.ConfigMap
+	if err := s.reader.Get(ctx, s.key, &cm); err != nil {
+		if errors.IsNotFound(err) {
+			s.cached = &State{}
+			s.rv = ""
+			return nil
+		}
+		return fmt.Errorf("reading registry configmap: %w", err)
+	}
+
+	state := &State{}
+	if raw := cm.Data[stateKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), state); err != nil {
+			return fmt.Errorf("decoding registry state: %w", err)
+		}
+	}
+	s.cached = state
+	s.rv = cm.ResourceVersion
+	return nil
+}
+
+// save writes state to the ConfigMap, creating it if needed
+func (s *Store) save(ctx context.Context, state *State) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding registry state: %w", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            s.key.Name,
+			Namespace:       s.key.Namespace,
+			ResourceVersion: s.rv,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "pihole-ingress-operator",
+				"app.kubernetes.io/managed-by": "pihole-ingress-operator",
+			},
+		},
+		Data: map[string]string{stateKey: string(raw)},
+	}
+
+	if s.rv == "" {
+		err = s.client.Create(ctx, cm)
+	} else {
+		err = s.client.Update(ctx, cm)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.cached = state
+	s.rv = cm.ResourceVersion
+	return nil
+}
+
+// clone returns a deep copy of the state
+func (st *State) clone() *State {
+	out := &State{}
+	if st.PendingDeletions != nil {
+		out.PendingDeletions = make(map[string]PendingDeletion, len(st.PendingDeletions))
+		for k, v := range st.PendingDeletions {
+			out.PendingDeletions[k] = v
+		}
+	}
+	if st.Records != nil {
+		out.Records = make(map[string]Ownership, len(st.Records))
+		for k, v := range st.Records {
+			out.Records[k] = v
+		}
+	}
+	if st.Expired != nil {
+		out.Expired = make(map[string]Expiry, len(st.Expired))
+		for k, v := range st.Expired {
+			out.Expired[k] = v
+		}
+	}
+	return out
+}
+
+// SchedulePendingDeletion records that domain should be deleted after the given time
+func (st *State) SchedulePendingDeletion(domain, owner string, after time.Time) {
+	st.AddPendingDeletion(domain, PendingDeletion{Owner: owner, DeleteAfter: after, Reason: ReasonGracePeriod})
+}
+
+// AddPendingDeletion records a pending deletion for domain, replacing any existing entry
+func (st *State) AddPendingDeletion(domain string, p PendingDeletion) {
+	if st.PendingDeletions == nil {
+		st.PendingDeletions = make(map[string]PendingDeletion)
+	}
+	st.PendingDeletions[domain] = p
+}
+
+// CancelPendingDeletions removes any scheduled deletions for the given domains,
+// reporting whether anything changed
+func (st *State) CancelPendingDeletions(domains []string) bool {
+	changed := false
+	for _, d := range domains {
+		if _, ok := st.PendingDeletions[d]; ok {
+			delete(st.PendingDeletions, d)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// DuePendingDeletions returns the domains whose scheduled deletion time has passed
+func (st *State) DuePendingDeletions(now time.Time) []string {
+	var due []string
+	for domain, p := range st.PendingDeletions {
+		if !now.Before(p.DeleteAfter) {
+			due = append(due, domain)
+		}
+	}
+	return due
+}
+
+// RecordCreated records that owner created the record key, replacing whatever was
+// recorded for it before
+func (st *State) RecordCreated(key string, owner Ownership, now time.Time) {
+	if st.Records == nil {
+		st.Records = make(map[string]Ownership)
+	}
+	owner.CreatedAt = now
+	owner.LastSyncedAt = now
+	owner.TrackedSince = now
+	st.Records[key] = owner
+}
+
+// RecordAdopted records that owner adopted the record key, which already existed in
+// Pi-hole pointing at owner.TargetIP, keeping any creation time recorded for it
+func (st *State) RecordAdopted(key string, owner Ownership, now time.Time) {
+	if st.Records == nil {
+		st.Records = make(map[string]Ownership)
+	}
+	owner.CreatedAt = st.Records[key].CreatedAt
+	owner.AdoptedAt = now
+	owner.LastSyncedAt = now
+	owner.TrackedSince = now
+	st.Records[key] = owner
+}
+
+// RecordSynced records that owner synced the record key, which points at ip, at now.
+// A record without an entry, e.g. one created by an older version, gains one with no
+// creation time, and an entry without owner's cluster ID gains it. LastSyncedAt only
+// moves once it is older than resolution, so a resync doesn't rewrite the state for
+// every record. It reports whether anything changed.
+func (st *State) RecordSynced(key string, owner Ownership, ip string, now time.Time, resolution time.Duration) bool {
+	current, ok := st.Records[key]
+	if !ok {
+		if st.Records == nil {
+			st.Records = make(map[string]Ownership)
+		}
+		owner.TargetIP = ip
+		owner.LastSyncedAt = now
+		owner.TrackedSince = now
+		st.Records[key] = owner
+		return true
+	}
+	same := current.SameObject(owner)
+	if same && current.ClusterID == owner.ClusterID && now.Sub(current.LastSyncedAt) < resolution {
+		return false
+	}
+	// A record handed over to another object keeps its creation history, but the new
+	// owner only tracks it from now
+	owner.TargetIP = current.TargetIP
+	owner.CreatedAt = current.CreatedAt
+	owner.AdoptedAt = current.AdoptedAt
+	owner.LastSyncedAt = now
+	owner.TrackedSince = current.TrackedSince
+	if !same {
+		owner.TrackedSince = now
+	}
+	st.Records[key] = owner
+	return true
+}
+
+// ForgetRecords removes the ownership of the given record keys, reporting whether
+// anything changed
+func (st *State) ForgetRecords(keys []string) bool {
+	changed := false
+	for _, key := range keys {
+		if _, ok := st.Records[key]; ok {
+			delete(st.Records, key)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Expire records that the record key expired, replacing any earlier expiry
+func (st *State) Expire(key string, e Expiry) {
+	if st.Expired == nil {
+		st.Expired = make(map[string]Expiry)
+	}
+	st.Expired[key] = e
+}
+
+// ForgetExpired removes the expiry of the given record keys, reporting whether
+// anything changed
+func (st *State) ForgetExpired(keys []string) bool {
+	changed := false
+	for _, key := range keys {
+		if _, ok := st.Expired[key]; ok {
+			delete(st.Expired, key)
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,260 @@
+package registry
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStoreUpdatePersists(t *testing.T) {
+	ctx := context.Background()
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	store := NewStore(k8s, k8s, "pihole-operator", "registry")
+
+	after := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.Update(ctx, func(st *State) bool {
+		st.SchedulePendingDeletion("app.local", "Ingress default/app", after)
+		return true
+	}); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+
+	var cm corev1.ConfigMap
+	if err := k8s.Get(ctx, types.NamespacedName{Namespace: "pihole-operator", Name: "registry"}, &cm); err != nil {
+		t.Fatalf("registry configmap not created: %v", err)
+	}
+
+	// A fresh store (as after a restart) sees the persisted state
+	reloaded := NewStore(k8s, k8s, "pihole-operator", "registry")
+	state, err := reloaded.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	got, ok := state.PendingDeletions["app.local"]
+	if !ok {
+		t.Fatalf("pending deletion not persisted: %+v", state)
+	}
+	if got.Owner != "Ingress default/app" || !got.DeleteAfter.Equal(after) {
+		t.Errorf("pending deletion = %+v", got)
+	}
+}
+
+func TestStoreUpdateNoChangeSkipsWrite(t *testing.T) {
+	ctx := context.Background()
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	store := NewStore(k8s, k8s, "pihole-operator", "registry")
+
+	if err := store.Update(ctx, func(*State) bool { return false }); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+
+	var cms corev1.ConfigMapList
+	if err := k8s.List(ctx, &cms); err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if len(cms.Items) != 0 {
+		t.Errorf("expected no configmap to be written, found %d", len(cms.Items))
+	}
+}
+
+func TestStoreUpdateRecoversFromConflict(t *testing.T) {
+	ctx := context.Background()
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	first := NewStore(k8s, k8s, "pihole-operator", "registry")
+	second := NewStore(k8s, k8s, "pihole-operator", "registry")
+	after := time.Now()
+
+	if err := first.Update(ctx, func(st *State) bool {
+		st.SchedulePendingDeletion("a.local", "x", after)
+		return true
+	}); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	// second has never loaded; prime its cache, then let first write again so second is stale
+	if _, err := second.Get(ctx); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if err := first.Update(ctx, func(st *State) bool {
+		st.SchedulePendingDeletion("b.local", "x", after)
+		return true
+	}); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+
+	if err := second.Update(ctx, func(st *State) bool {
+		st.SchedulePendingDeletion("c.local", "x", after)
+		return true
+	}); err != nil {
+		t.Fatalf("Update() on stale store unexpected error: %v", err)
+	}
+
+	state, err := NewStore(k8s, k8s, "pihole-operator", "registry").Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if len(state.PendingDeletions) != 3 {
+		t.Errorf("pending deletions = %v, want a, b and c", state.PendingDeletions)
+	}
+}
+
+func TestStatePendingDeletions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	st := &State{}
+	st.SchedulePendingDeletion("past.local", "x", now.Add(-time.Minute))
+	st.SchedulePendingDeletion("now.local", "x", now)
+	st.SchedulePendingDeletion("future.local", "x", now.Add(time.Minute))
+
+	due := st.DuePendingDeletions(now)
+	sort.Strings(due)
+	if len(due) != 2 || due[0] != "now.local" || due[1] != "past.local" {
+		t.Errorf("DuePendingDeletions() = %v, want [now.local past.local]", due)
+	}
+
+	if !st.CancelPendingDeletions([]string{"future.local", "unknown.local"}) {
+		t.Error("CancelPendingDeletions() = false, want true")
+	}
+	if st.CancelPendingDeletions([]string{"future.local"}) {
+		t.Error("CancelPendingDeletions() second call = true, want false")
+	}
+	if _, ok := st.PendingDeletions["future.local"]; ok {
+		t.Error("future.local still pending after cancel")
+	}
+}
+
+func TestStateRecordOwnership(t *testing.T) {
+	created := time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)
+	jellyfin := Ownership{Kind: "Ingress", Namespace: "media", Name: "jellyfin", UID: "uid-1", TargetIP: "192.168.1.100"}
+	st := &State{}
+	st.RecordCreated("jellyfin.home.lan", jellyfin, created)
+
+	got := st.Records["jellyfin.home.lan"]
+	if !got.CreatedAt.Equal(created) || !got.LastSyncedAt.Equal(created) || got.TargetIP != "192.168.1.100" {
+		t.Errorf("created record = %+v", got)
+	}
+	if want := "created 2024-03-02 by ingress media/jellyfin"; got.String() != want {
+		t.Errorf("String() = %q, want %q", got.String(), want)
+	}
+
+	// Syncs within the resolution leave the state alone
+	if st.RecordSynced("jellyfin.home.lan", jellyfin, "192.168.1.100", created.Add(time.Minute), time.Hour) {
+		t.Error("RecordSynced() within the resolution = true, want false")
+	}
+	later := created.Add(2 * time.Hour)
+	if !st.RecordSynced("jellyfin.home.lan", jellyfin, "192.168.1.100", later, time.Hour) {
+		t.Error("RecordSynced() past the resolution = false, want true")
+	}
+	if got := st.Records["jellyfin.home.lan"]; !got.LastSyncedAt.Equal(later) || !got.CreatedAt.Equal(created) {
+		t.Errorf("synced record = %+v", got)
+	}
+
+	// A handed-over record names its new owner but keeps its history
+	other := Ownership{Kind: "Ingress", Namespace: "media", Name: "jellyfin-v2", UID: "uid-2"}
+	if !st.RecordSynced("jellyfin.home.lan", other, "192.168.1.100", later, time.Hour) {
+		t.Error("RecordSynced() by another owner = false, want true")
+	}
+	if got := st.Records["jellyfin.home.lan"]; got.Name != "jellyfin-v2" || !got.CreatedAt.Equal(created) ||
+		got.TargetIP != "192.168.1.100" {
+		t.Errorf("handed-over record = %+v", got)
+	}
+
+	// A record tracked before ownership was recorded is upgraded without a creation time
+	if !st.RecordSynced("old.home.lan", jellyfin, "192.168.1.50", later, time.Hour) {
+		t.Error("RecordSynced() of an untracked record = false, want true")
+	}
+	old := st.Records["old.home.lan"]
+	if !old.CreatedAt.IsZero() || old.TargetIP != "192.168.1.50" {
+		t.Errorf("upgraded record = %+v", old)
+	}
+	if want := "created by ingress media/jellyfin"; old.String() != want {
+		t.Errorf("String() = %q, want %q", old.String(), want)
+	}
+
+	// A record synced before the cluster id was set is stamped with it straight away
+	lab := jellyfin
+	lab.ClusterID = "lab"
+	if !st.RecordSynced("old.home.lan", lab, "192.168.1.50", later.Add(time.Minute), time.Hour) {
+		t.Error("RecordSynced() with a new cluster id = false, want true")
+	}
+	if got := st.Records["old.home.lan"].ClusterID; got != "lab" {
+		t.Errorf("cluster id = %q, want lab", got)
+	}
+
+	if !st.ForgetRecords([]string{"old.home.lan", "unknown.home.lan"}) {
+		t.Error("ForgetRecords() = false, want true")
+	}
+	if st.ForgetRecords([]string{"old.home.lan"}) {
+		t.Error("ForgetRecords() second call = true, want false")
+	}
+}
+
+func TestStateExpiry(t *testing.T) {
+	created := time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)
+	app := Ownership{Kind: "Ingress", Namespace: "preview", Name: "app", UID: "uid-1"}
+	st := &State{}
+	st.RecordCreated("app.home.lan", app, created)
+
+	// Syncs by the same object keep the time it took the record on
+	st.RecordSynced("app.home.lan", app, "192.168.1.100", created.Add(2*time.Hour), time.Hour)
+	if got := st.Records["app.home.lan"].TrackedSince; !got.Equal(created) {
+		t.Errorf("TrackedSince after a sync = %v, want %v", got, created)
+	}
+	// A new owner starts its own lifetime
+	handover := created.Add(3 * time.Hour)
+	st.RecordSynced("app.home.lan", Ownership{Kind: "Ingress", Namespace: "preview", Name: "app-v2"}, "192.168.1.100",
+		handover, time.Hour)
+	if got := st.Records["app.home.lan"].TrackedSince; !got.Equal(handover) {
+		t.Errorf("TrackedSince after a handover = %v, want %v", got, handover)
+	}
+
+	st.Expire("app.home.lan", Expiry{Owner: "Ingress preview/app", UID: "uid-1", After: "72h", ExpiredAt: handover})
+	clone := st.clone()
+	if got := clone.Expired["app.home.lan"]; got.After != "72h" || !got.ExpiredAt.Equal(handover) {
+		t.Errorf("cloned expiry = %+v", got)
+	}
+	if !st.ForgetExpired([]string{"app.home.lan", "unknown.home.lan"}) {
+		t.Error("ForgetExpired() = false, want true")
+	}
+	if st.ForgetExpired([]string{"app.home.lan"}) {
+		t.Error("ForgetExpired() second call = true, want false")
+	}
+	if _, ok := clone.Expired["app.home.lan"]; !ok {
+		t.Error("clone shares the expiry map")
+	}
+}
+
+func TestStoreReadsStateWithoutRecords(t *testing.T) {
+	ctx := context.Background()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "pihole-operator", Name: "registry"},
+		Data: map[string]string{stateKey: `{"pendingDeletions":{"app.local":{"owner":"Ingress default/app",` +
+			`"deleteAfter":"2024-01-01T12:00:00Z"}}}`},
+	}
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cm).Build()
+	store := NewStore(k8s, k8s, "pihole-operator", "registry")
+
+	now := time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)
+	if err := store.Update(ctx, func(st *State) bool {
+		return st.RecordSynced("app.local", Ownership{Kind: "Ingress", Namespace: "default", Name: "app"},
+			"192.168.1.100", now, time.Hour)
+	}); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+
+	state, err := NewStore(k8s, k8s, "pihole-operator", "registry").Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if _, ok := state.PendingDeletions["app.local"]; !ok {
+		t.Errorf("pending deletion lost in the upgrade: %+v", state)
+	}
+	if got := state.Records["app.local"]; got.Name != "app" || !got.LastSyncedAt.Equal(now) {
+		t.Errorf("upgraded record = %+v", got)
+	}
+}
@@ -0,0 +1,20 @@
+// Package version reports the build of the operator, set at link time with
+// -ldflags "-X github.com/rsJames-ttrpg/pihole-ingress-operator/internal/version.Version=..."
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Build details; the Makefile and Dockerfile set them from git
+var (
+	Version = "dev"
+	Commit  = "unknown"
+	Date    = "unknown"
+)
+
+// String describes the build in one line
+func String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", Version, Commit, Date, runtime.Version())
+}
@@ -25,6 +25,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -45,6 +46,16 @@ const metricsServiceName = "pihole-ingress-operator-controller-manager-metrics-s
 // metricsRoleBindingName is the name of the RBAC that will be created to allow get the metrics data
 const metricsRoleBindingName = "pihole-ingress-operator-metrics-binding"
 
+// piholeNamespace holds the Pi-hole the operator is tested against; Pi-hole runs as
+// root, so it cannot share the restricted manager namespace
+const piholeNamespace = "pihole-e2e"
+
+// piholeName is the name of the Pi-hole Deployment and Service
+const piholeName = "pihole"
+
+// piholePassword is the password of the Pi-hole API
+const piholePassword = "test-password"
+
 var _ = Describe("Manager", Ordered, func() {
 	var controllerPodName string
 
@@ -63,10 +74,19 @@ var _ = Describe("Manager", Ordered, func() {
 		_, err = utils.Run(cmd)
 		Expect(err).NotTo(HaveOccurred(), "Failed to label namespace with restricted policy")
 
+		By("deploying Pi-hole")
+		cmd = exec.Command("kubectl", "create", "ns", piholeNamespace)
+		_, err = utils.Run(cmd)
+		Expect(err).NotTo(HaveOccurred(), "Failed to create Pi-hole namespace")
+		err = utils.DeployPihole(piholeNamespace, piholeName, piholePassword)
+		Expect(err).NotTo(HaveOccurred(), "Failed to deploy Pi-hole")
+		err = utils.WaitForPihole(piholeNamespace, piholeName, piholePassword, 5*time.Minute)
+		Expect(err).NotTo(HaveOccurred(), "Pi-hole did not become ready")
+
 		By("creating the pihole-operator-config ConfigMap")
 		cmd = exec.Command("kubectl", "create", "configmap", "pihole-operator-config",
 			"--namespace", namespace,
-			"--from-literal=PIHOLE_URL=http://localhost:8080",
+			fmt.Sprintf("--from-literal=PIHOLE_URL=http://%s.%s.svc.cluster.local", piholeName, piholeNamespace),
 			"--from-literal=DEFAULT_TARGET_IP=192.168.1.100",
 			"--from-literal=LOG_LEVEL=debug")
 		_, err = utils.Run(cmd)
@@ -75,7 +95,7 @@ var _ = Describe("Manager", Ordered, func() {
 		By("creating the pihole-operator-secret Secret")
 		cmd = exec.Command("kubectl", "create", "secret", "generic", "pihole-operator-secret",
 			"--namespace", namespace,
-			"--from-literal=PIHOLE_PASSWORD=test-password")
+			"--from-literal=PIHOLE_PASSWORD="+piholePassword)
 		_, err = utils.Run(cmd)
 		Expect(err).NotTo(HaveOccurred(), "Failed to create Secret")
 
@@ -93,8 +113,13 @@ var _ = Describe("Manager", Ordered, func() {
 	// After all tests have been executed, clean up by undeploying the controller, uninstalling CRDs,
 	// and deleting the namespace.
 	AfterAll(func() {
-		By("cleaning up the curl pod for metrics")
-		cmd := exec.Command("kubectl", "delete", "pod", "curl-metrics", "-n", namespace)
+		By("cleaning up the curl pods for metrics and the --once pod")
+		cmd := exec.Command("kubectl", "delete", "pod", "curl-metrics", "curl-metrics-anonymous", "pihole-once",
+			"-n", namespace)
+		_, _ = utils.Run(cmd)
+
+		By("deleting the test Ingress")
+		cmd = exec.Command("kubectl", "delete", "ingress", "e2e-app", "-n", piholeNamespace, "--ignore-not-found")
 		_, _ = utils.Run(cmd)
 
 		By("undeploying the controller-manager")
@@ -108,6 +133,10 @@ var _ = Describe("Manager", Ordered, func() {
 		By("removing manager namespace")
 		cmd = exec.Command("kubectl", "delete", "ns", namespace)
 		_, _ = utils.Run(cmd)
+
+		By("removing Pi-hole")
+		cmd = exec.Command("kubectl", "delete", "ns", piholeNamespace)
+		_, _ = utils.Run(cmd)
 	})
 
 	// After each test, check for failures and collect logs, events,
@@ -133,6 +162,15 @@ var _ = Describe("Manager", Ordered, func() {
 				_, _ = fmt.Fprintf(GinkgoWriter, "Failed to get Kubernetes events: %s", err)
 			}
 
+			By("Fetching Pi-hole logs")
+			cmd = exec.Command("kubectl", "logs", "deployment/"+piholeName, "-n", piholeNamespace)
+			piholeLogs, err := utils.Run(cmd)
+			if err == nil {
+				_, _ = fmt.Fprintf(GinkgoWriter, "Pi-hole logs:\n %s", piholeLogs)
+			} else {
+				_, _ = fmt.Fprintf(GinkgoWriter, "Failed to get Pi-hole logs: %s", err)
+			}
+
 			By("Fetching curl-metrics logs")
 			cmd = exec.Command("kubectl", "logs", "curl-metrics", "-n", namespace)
 			metricsOutput, err := utils.Run(cmd)
@@ -231,34 +269,8 @@ var _ = Describe("Manager", Ordered, func() {
 			// +kubebuilder:scaffold:e2e-metrics-webhooks-readiness
 
 			By("creating the curl-metrics pod to access the metrics endpoint")
-			cmd = exec.Command("kubectl", "run", "curl-metrics", "--restart=Never",
-				"--namespace", namespace,
-				"--image=curlimages/curl:latest",
-				"--overrides",
-				fmt.Sprintf(`{
-					"spec": {
-						"containers": [{
-							"name": "curl",
-							"image": "curlimages/curl:latest",
-							"command": ["/bin/sh", "-c"],
-							"args": ["curl -v -H 'Authorization: Bearer %s' http://%s.%s.svc.cluster.local:8443/metrics"],
-							"securityContext": {
-								"readOnlyRootFilesystem": true,
-								"allowPrivilegeEscalation": false,
-								"capabilities": {
-									"drop": ["ALL"]
-								},
-								"runAsNonRoot": true,
-								"runAsUser": 1000,
-								"seccompProfile": {
-									"type": "RuntimeDefault"
-								}
-							}
-						}],
-						"serviceAccountName": "%s"
-					}
-				}`, token, metricsServiceName, namespace, serviceAccountName))
-			_, err = utils.Run(cmd)
+			metricsURL := fmt.Sprintf("https://%s.%s.svc.cluster.local:8443/metrics", metricsServiceName, namespace)
+			err = utils.CurlMetrics(namespace, "curl-metrics", serviceAccountName, token, metricsURL)
 			Expect(err).NotTo(HaveOccurred(), "Failed to create curl-metrics pod")
 
 			By("waiting for the curl-metrics pod to complete.")
@@ -280,6 +292,104 @@ var _ = Describe("Manager", Ordered, func() {
 				g.Expect(metricsOutput).To(ContainSubstring("< HTTP/1.1 200 OK"))
 			}
 			Eventually(verifyMetricsAvailable, 2*time.Minute).Should(Succeed())
+
+			By("checking that the metrics endpoint refuses requests without a token")
+			err = utils.CurlMetrics(namespace, "curl-metrics-anonymous", serviceAccountName, "", metricsURL)
+			Expect(err).NotTo(HaveOccurred(), "Failed to create curl-metrics-anonymous pod")
+			verifyUnauthorized := func(g Gomega) {
+				cmd := exec.Command("kubectl", "logs", "curl-metrics-anonymous", "-n", namespace)
+				output, err := utils.Run(cmd)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(output).To(ContainSubstring("< HTTP/1.1 401 Unauthorized"))
+			}
+			Eventually(verifyUnauthorized, 5*time.Minute).Should(Succeed())
+		})
+
+		It("should reconcile every object once and exit with --once", func() {
+			By("running the operator image with --once")
+			err := utils.RunOnce(namespace, "pihole-once", projectImage, serviceAccountName,
+				"pihole-operator-config", "pihole-operator-secret")
+			Expect(err).NotTo(HaveOccurred(), "Failed to create pihole-once pod")
+
+			By("waiting for the pass to complete")
+			verifyCompleted := func(g Gomega) {
+				cmd := exec.Command("kubectl", "get", "pods", "pihole-once",
+					"-o", "jsonpath={.status.phase}",
+					"-n", namespace)
+				output, err := utils.Run(cmd)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(output).To(Equal("Succeeded"), "pihole-once pod in wrong status")
+			}
+			Eventually(verifyCompleted, 2*time.Minute).Should(Succeed())
+
+			By("checking the pass summary")
+			cmd := exec.Command("kubectl", "logs", "pihole-once", "-n", namespace)
+			output, err := utils.Run(cmd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(ContainSubstring("Ingress: 0 reconciled, 0 pending, 0 failed"))
+		})
+
+		It("should register an annotated Ingress in Pi-hole and remove it on deletion", func() {
+			const host = "app.e2e.example.com"
+			const record = "192.168.1.100 " + host
+			piholeServer := fmt.Sprintf("%s.%s.svc.cluster.local", piholeName, piholeNamespace)
+
+			By("starting a pod to resolve names through Pi-hole")
+			err := utils.StartDNSUtils(piholeNamespace, "dnsutils")
+			Expect(err).NotTo(HaveOccurred(), "Failed to start the dnsutils pod")
+
+			By("creating an annotated Ingress")
+			cmd := exec.Command("kubectl", "apply", "-f", "-")
+			cmd.Stdin = strings.NewReader(fmt.Sprintf(`apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: e2e-app
+  namespace: %s
+  annotations:
+    pihole.io/register: "true"
+spec:
+  rules:
+  - host: %s
+`, piholeNamespace, host))
+			_, err = utils.Run(cmd)
+			Expect(err).NotTo(HaveOccurred(), "Failed to create the Ingress")
+
+			By("checking the record through the Pi-hole API")
+			verifyRecord := func(g Gomega) {
+				hosts, err := utils.PiholeHosts(piholeNamespace, piholeName, piholePassword)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(hosts).To(ContainElement(record))
+			}
+			Eventually(verifyRecord).Should(Succeed())
+
+			By("resolving the host through Pi-hole")
+			verifyResolves := func(g Gomega) {
+				answers, err := utils.Dig(piholeNamespace, "dnsutils", piholeServer, host)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(answers).To(Equal([]string{"192.168.1.100"}))
+			}
+			Eventually(verifyResolves).Should(Succeed())
+
+			By("deleting the Ingress")
+			cmd = exec.Command("kubectl", "delete", "ingress", "e2e-app", "-n", piholeNamespace, "--timeout=2m")
+			_, err = utils.Run(cmd)
+			Expect(err).NotTo(HaveOccurred(), "Failed to delete the Ingress")
+
+			By("checking the record is gone from Pi-hole")
+			verifyRemoved := func(g Gomega) {
+				hosts, err := utils.PiholeHosts(piholeNamespace, piholeName, piholePassword)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(hosts).NotTo(ContainElement(record))
+			}
+			Eventually(verifyRemoved).Should(Succeed())
+
+			By("checking the host no longer resolves")
+			verifyUnresolved := func(g Gomega) {
+				answers, err := utils.Dig(piholeNamespace, "dnsutils", piholeServer, host)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(answers).To(BeEmpty())
+			}
+			Eventually(verifyUnresolved).Should(Succeed())
 		})
 
 		// +kubebuilder:scaffold:e2e-webhooks-checks
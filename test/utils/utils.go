@@ -19,10 +19,12 @@ package utils
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2" // nolint:revive,staticcheck
 )
@@ -33,6 +35,9 @@ const (
 
 	defaultKindBinary  = "kind"
 	defaultKindCluster = "kind"
+
+	piholeImage   = "pihole/pihole:2025.08.0"
+	dnsutilsImage = "registry.k8s.io/e2e-test-images/jessie-dnsutils:1.7"
 )
 
 func warnError(err error) {
@@ -224,3 +229,264 @@ func UncommentCode(filename, target, prefix string) error {
 
 	return nil
 }
+
+// SetManagerEnv sets environment variables on the operator deployment, e.g. SOURCES to
+// run a reduced set of sources, and waits for the rollout
+func SetManagerEnv(namespace, deployment string, env map[string]string) error {
+	args := []string{"set", "env", "deployment/" + deployment, "-n", namespace}
+	for name, value := range env {
+		args = append(args, name+"="+value)
+	}
+	if _, err := Run(exec.Command("kubectl", args...)); err != nil {
+		return err
+	}
+	_, err := Run(exec.Command("kubectl", "rollout", "status", "deployment/"+deployment,
+		"-n", namespace, "--timeout=2m"))
+	return err
+}
+
+// CurlMetrics starts a pod named pod running as serviceAccount that fetches url with
+// curl, sending token as a bearer token when it is not empty. The metrics endpoint
+// serves a self-signed certificate by default, so it is not verified. The response
+// headers end up in the pod's logs once it has completed.
+func CurlMetrics(namespace, pod, serviceAccount, token, url string) error {
+	auth := ""
+	if token != "" {
+		auth = fmt.Sprintf("-H 'Authorization: Bearer %s' ", token)
+	}
+	overrides := fmt.Sprintf(`{
+		"spec": {
+			"containers": [{
+				"name": "curl",
+				"image": "curlimages/curl:latest",
+				"command": ["/bin/sh", "-c"],
+				"args": ["curl -v -k %s%s"],
+				"securityContext": {
+					"readOnlyRootFilesystem": true,
+					"allowPrivilegeEscalation": false,
+					"capabilities": {
+						"drop": ["ALL"]
+					},
+					"runAsNonRoot": true,
+					"runAsUser": 1000,
+					"seccompProfile": {
+						"type": "RuntimeDefault"
+					}
+				}
+			}],
+			"serviceAccountName": "%s"
+		}
+	}`, auth, url, serviceAccount)
+	_, err := Run(exec.Command("kubectl", "run", pod, "--restart=Never",
+		"--namespace", namespace,
+		"--image=curlimages/curl:latest",
+		"--overrides", overrides))
+	return err
+}
+
+// RunOnce starts a pod named pod that runs image with --once as serviceAccount, taking
+// its settings from the ConfigMap and Secret named in envFrom, like the deployment.
+// The pass summary ends up in the pod's logs and its phase reflects the exit code.
+func RunOnce(namespace, pod, image, serviceAccount, configMap, secret string) error {
+	overrides := fmt.Sprintf(`{
+		"spec": {
+			"containers": [{
+				"name": "once",
+				"image": "%s",
+				"args": ["--once"],
+				"envFrom": [
+					{"configMapRef": {"name": "%s"}},
+					{"secretRef": {"name": "%s"}}
+				],
+				"securityContext": {
+					"readOnlyRootFilesystem": true,
+					"allowPrivilegeEscalation": false,
+					"capabilities": {
+						"drop": ["ALL"]
+					},
+					"runAsNonRoot": true,
+					"seccompProfile": {
+						"type": "RuntimeDefault"
+					}
+				}
+			}],
+			"serviceAccountName": "%s"
+		}
+	}`, image, configMap, secret, serviceAccount)
+	_, err := Run(exec.Command("kubectl", "run", pod, "--restart=Never",
+		"--namespace", namespace,
+		"--image", image,
+		"--overrides", overrides))
+	return err
+}
+
+// DeployPihole runs a Pi-hole v6 Deployment and Service named name in namespace, with
+// password for its API. The Service answers DNS on port 53 and the API on port 80.
+// Pi-hole runs as root, so namespace must not enforce the restricted security policy.
+func DeployPihole(namespace, name, password string) error {
+	manifest := fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: %[1]s
+  template:
+    metadata:
+      labels:
+        app: %[1]s
+    spec:
+      containers:
+      - name: pihole
+        image: %[4]s
+        env:
+        - name: FTLCONF_webserver_api_password
+          value: %[3]q
+        - name: FTLCONF_dns_listeningMode
+          value: all
+        ports:
+        - containerPort: 53
+          protocol: UDP
+        - containerPort: 53
+          protocol: TCP
+        - containerPort: 80
+        readinessProbe:
+          tcpSocket:
+            port: 80
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+spec:
+  selector:
+    app: %[1]s
+  ports:
+  - name: dns
+    port: 53
+    protocol: UDP
+  - name: dns-tcp
+    port: 53
+    protocol: TCP
+  - name: http
+    port: 80
+`, name, namespace, password, piholeImage)
+	cmd := exec.Command("kubectl", "apply", "-f", "-")
+	cmd.Stdin = strings.NewReader(manifest)
+	_, err := Run(cmd)
+	return err
+}
+
+// WaitForPihole waits for the Pi-hole deployed by DeployPihole to roll out and for its
+// API to accept password. FTL serves the web port a while before it accepts logins.
+func WaitForPihole(namespace, name, password string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	if _, err := Run(exec.Command("kubectl", "rollout", "status", "deployment/"+name,
+		"-n", namespace, "--timeout="+timeout.String())); err != nil {
+		return err
+	}
+	for {
+		sid, err := piholeLogin(namespace, name, password)
+		if err == nil {
+			piholeLogout(namespace, name, sid)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pihole %s/%s not ready after %s: %w", namespace, name, timeout, err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// PiholeHosts returns Pi-hole's local DNS records as "IP hostname" entries, read
+// through its API from inside the Pi-hole pod
+func PiholeHosts(namespace, name, password string) ([]string, error) {
+	sid, err := piholeLogin(namespace, name, password)
+	if err != nil {
+		return nil, err
+	}
+	defer piholeLogout(namespace, name, sid)
+
+	output, err := piholeCurl(namespace, name, "-H", "X-FTL-SID: "+sid, "http://localhost/api/config/dns/hosts")
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Config struct {
+			DNS struct {
+				Hosts []string `json:"hosts"`
+			} `json:"dns"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(output), &body); err != nil {
+		return nil, fmt.Errorf("decoding hosts %q: %w", output, err)
+	}
+	return body.Config.DNS.Hosts, nil
+}
+
+// piholeLogin opens an API session on the Pi-hole and returns its ID
+func piholeLogin(namespace, name, password string) (string, error) {
+	payload, err := json.Marshal(map[string]string{"password": password})
+	if err != nil {
+		return "", err
+	}
+	output, err := piholeCurl(namespace, name, "-X", "POST", "--data", string(payload), "http://localhost/api/auth")
+	if err != nil {
+		return "", err
+	}
+	var body struct {
+		Session struct {
+			Valid bool   `json:"valid"`
+			SID   string `json:"sid"`
+		} `json:"session"`
+	}
+	if err := json.Unmarshal([]byte(output), &body); err != nil {
+		return "", fmt.Errorf("decoding login %q: %w", output, err)
+	}
+	if !body.Session.Valid {
+		return "", fmt.Errorf("pihole refused the password")
+	}
+	return body.Session.SID, nil
+}
+
+// piholeLogout ends a session, as Pi-hole only allows a few at a time
+func piholeLogout(namespace, name, sid string) {
+	if _, err := piholeCurl(namespace, name, "-X", "DELETE", "-H", "X-FTL-SID: "+sid,
+		"http://localhost/api/auth"); err != nil {
+		warnError(err)
+	}
+}
+
+// piholeCurl runs curl with args inside the Pi-hole pod and returns the response body
+func piholeCurl(namespace, name string, args ...string) (string, error) {
+	cmdArgs := append([]string{"exec", "-n", namespace, "deployment/" + name, "--", "curl", "-sS", "-f"}, args...)
+	return Run(exec.Command("kubectl", cmdArgs...))
+}
+
+// StartDNSUtils starts a pod named pod with dig in namespace and waits for it to be ready
+func StartDNSUtils(namespace, pod string) error {
+	if _, err := Run(exec.Command("kubectl", "run", pod, "--restart=Never",
+		"--namespace", namespace,
+		"--image", dnsutilsImage,
+		"--command", "--", "sleep", "3600")); err != nil {
+		return err
+	}
+	_, err := Run(exec.Command("kubectl", "wait", "pod/"+pod, "-n", namespace,
+		"--for=condition=Ready", "--timeout=2m"))
+	return err
+}
+
+// Dig resolves host against the DNS server at server from the pod started by
+// StartDNSUtils and returns the answers, one per line
+func Dig(namespace, pod, server, host string) ([]string, error) {
+	output, err := Run(exec.Command("kubectl", "exec", "-n", namespace, pod, "--",
+		"dig", "+short", "@"+server, host))
+	if err != nil {
+		return nil, err
+	}
+	return GetNonEmptyLines(output), nil
+}